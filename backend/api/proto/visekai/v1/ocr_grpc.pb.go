@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: visekai/v1/ocr.proto
+
+package visekaiv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OCRService_UploadDocument_FullMethodName = "/visekai.v1.OCRService/UploadDocument"
+	OCRService_SubmitJob_FullMethodName      = "/visekai.v1.OCRService/SubmitJob"
+	OCRService_WatchJob_FullMethodName       = "/visekai.v1.OCRService/WatchJob"
+	OCRService_GetResult_FullMethodName      = "/visekai.v1.OCRService/GetResult"
+)
+
+// OCRServiceClient is the client API for OCRService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// OCRService exposes the core OCR operations to internal service consumers.
+// Calls authenticate like the REST API: a JWT or an API key in the
+// authorization metadata ("Bearer <token>"), or an API key in x-api-key.
+type OCRServiceClient interface {
+	// UploadDocument stores a document sent as a stream: a metadata message
+	// first, then the file's content in chunks. It is handled as the REST
+	// upload is: a file with the same content as one of the user's documents is
+	// handled by the duplicate policy, and one a teammate already uploaded
+	// reuses their file and result when the organization allows it.
+	UploadDocument(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadDocumentRequest, UploadDocumentResponse], error)
+	// SubmitJob submits an OCR job for a document
+	SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// WatchJob streams a job's current state, then its events as they happen,
+	// and ends once the job is completed, failed or cancelled
+	WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobEvent], error)
+	// GetResult fetches the result of a job, or a result by its ID
+	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Result, error)
+}
+
+type oCRServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOCRServiceClient(cc grpc.ClientConnInterface) OCRServiceClient {
+	return &oCRServiceClient{cc}
+}
+
+func (c *oCRServiceClient) UploadDocument(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadDocumentRequest, UploadDocumentResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OCRService_ServiceDesc.Streams[0], OCRService_UploadDocument_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadDocumentRequest, UploadDocumentResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_UploadDocumentClient = grpc.ClientStreamingClient[UploadDocumentRequest, UploadDocumentResponse]
+
+func (c *oCRServiceClient) SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, OCRService_SubmitJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oCRServiceClient) WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OCRService_ServiceDesc.Streams[1], OCRService_WatchJob_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchJobRequest, JobEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_WatchJobClient = grpc.ServerStreamingClient[JobEvent]
+
+func (c *oCRServiceClient) GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, OCRService_GetResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OCRServiceServer is the server API for OCRService service.
+// All implementations must embed UnimplementedOCRServiceServer
+// for forward compatibility.
+//
+// OCRService exposes the core OCR operations to internal service consumers.
+// Calls authenticate like the REST API: a JWT or an API key in the
+// authorization metadata ("Bearer <token>"), or an API key in x-api-key.
+type OCRServiceServer interface {
+	// UploadDocument stores a document sent as a stream: a metadata message
+	// first, then the file's content in chunks. It is handled as the REST
+	// upload is: a file with the same content as one of the user's documents is
+	// handled by the duplicate policy, and one a teammate already uploaded
+	// reuses their file and result when the organization allows it.
+	UploadDocument(grpc.ClientStreamingServer[UploadDocumentRequest, UploadDocumentResponse]) error
+	// SubmitJob submits an OCR job for a document
+	SubmitJob(context.Context, *SubmitJobRequest) (*Job, error)
+	// WatchJob streams a job's current state, then its events as they happen,
+	// and ends once the job is completed, failed or cancelled
+	WatchJob(*WatchJobRequest, grpc.ServerStreamingServer[JobEvent]) error
+	// GetResult fetches the result of a job, or a result by its ID
+	GetResult(context.Context, *GetResultRequest) (*Result, error)
+	mustEmbedUnimplementedOCRServiceServer()
+}
+
+// UnimplementedOCRServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOCRServiceServer struct{}
+
+func (UnimplementedOCRServiceServer) UploadDocument(grpc.ClientStreamingServer[UploadDocumentRequest, UploadDocumentResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadDocument not implemented")
+}
+func (UnimplementedOCRServiceServer) SubmitJob(context.Context, *SubmitJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitJob not implemented")
+}
+func (UnimplementedOCRServiceServer) WatchJob(*WatchJobRequest, grpc.ServerStreamingServer[JobEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchJob not implemented")
+}
+func (UnimplementedOCRServiceServer) GetResult(context.Context, *GetResultRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
+}
+func (UnimplementedOCRServiceServer) mustEmbedUnimplementedOCRServiceServer() {}
+func (UnimplementedOCRServiceServer) testEmbeddedByValue()                    {}
+
+// UnsafeOCRServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OCRServiceServer will
+// result in compilation errors.
+type UnsafeOCRServiceServer interface {
+	mustEmbedUnimplementedOCRServiceServer()
+}
+
+func RegisterOCRServiceServer(s grpc.ServiceRegistrar, srv OCRServiceServer) {
+	// If the following call pancis, it indicates UnimplementedOCRServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OCRService_ServiceDesc, srv)
+}
+
+func _OCRService_UploadDocument_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OCRServiceServer).UploadDocument(&grpc.GenericServerStream[UploadDocumentRequest, UploadDocumentResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_UploadDocumentServer = grpc.ClientStreamingServer[UploadDocumentRequest, UploadDocumentResponse]
+
+func _OCRService_SubmitJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCRServiceServer).SubmitJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCRService_SubmitJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCRServiceServer).SubmitJob(ctx, req.(*SubmitJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OCRService_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OCRServiceServer).WatchJob(m, &grpc.GenericServerStream[WatchJobRequest, JobEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCRService_WatchJobServer = grpc.ServerStreamingServer[JobEvent]
+
+func _OCRService_GetResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCRServiceServer).GetResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCRService_GetResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCRServiceServer).GetResult(ctx, req.(*GetResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OCRService_ServiceDesc is the grpc.ServiceDesc for OCRService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OCRService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "visekai.v1.OCRService",
+	HandlerType: (*OCRServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitJob",
+			Handler:    _OCRService_SubmitJob_Handler,
+		},
+		{
+			MethodName: "GetResult",
+			Handler:    _OCRService_GetResult_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadDocument",
+			Handler:       _OCRService_UploadDocument_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchJob",
+			Handler:       _OCRService_WatchJob_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "visekai/v1/ocr.proto",
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"visekai/backend/internal/config"
+)
+
+// formOverheadBytes allows for the form fields and multipart boundaries sent
+// along with files
+const formOverheadBytes = 1 << 20
+
+// bodyLimits returns the body size limits of the routes taking files, above
+// the MaxBodySize of every other route
+func bodyLimits(cfg *config.Config) map[string]int64 {
+	attachments := int64(max(cfg.InboundEmailMaxAttachments, 1))
+	limits := make(map[string]int64)
+	for _, prefix := range []string{"/api/v1", "/api/v2"} {
+		limits[prefix+"/documents/upload"] = cfg.MaxFileSize + formOverheadBytes
+		limits[prefix+"/documents/metadata-import"] = 10<<20 + formOverheadBytes
+		limits[prefix+"/inbound-email/mailgun"] = attachments*cfg.MaxFileSize + formOverheadBytes
+	}
+	return limits
+}
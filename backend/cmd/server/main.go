@@ -2,25 +2,43 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
+	_ "time/tzdata" // time zone names for user settings, even on images without zoneinfo
 
+	"visekai/backend/internal/alerts"
+	"visekai/backend/internal/billing"
+	"visekai/backend/internal/classifier"
 	"visekai/backend/internal/config"
+	"visekai/backend/internal/connectors"
 	"visekai/backend/internal/database"
+	"visekai/backend/internal/delivery"
+	"visekai/backend/internal/entities"
+	"visekai/backend/internal/events"
+	"visekai/backend/internal/grpcapi"
 	"visekai/backend/internal/handlers"
+	"visekai/backend/internal/mail"
 	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
 	"visekai/backend/internal/ocr"
 	"visekai/backend/internal/repository"
+	"visekai/backend/internal/scanner"
 	"visekai/backend/internal/services"
+	"visekai/backend/internal/sso"
+	"visekai/backend/internal/tasks"
 	"visekai/backend/pkg/logger"
-	"visekai/backend/pkg/storage"
+	"visekai/backend/pkg/metrics"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -31,128 +49,912 @@ func main() {
 	}
 
 	// Initialize logger
-	logger.Init(cfg.LogLevel)
+	err = logger.Setup(logger.Config{
+		Level:   cfg.LogLevel,
+		Format:  cfg.LogFormat,
+		Outputs: cfg.LogOutputs,
+		File: logger.FileConfig{
+			Path:       cfg.LogFilePath,
+			MaxSizeMB:  cfg.LogFileMaxSizeMB,
+			MaxBackups: cfg.LogFileMaxBackups,
+			MaxAge:     cfg.LogFileMaxAge,
+		},
+		SyslogNetwork: cfg.LogSyslogNetwork,
+		SyslogAddress: cfg.LogSyslogAddress,
+		SyslogTag:     cfg.LogSyslogTag,
+		Loki: logger.LokiConfig{
+			URL:       cfg.LogLokiURL,
+			Labels:    cfg.LogLokiLabels,
+			TenantID:  cfg.LogLokiTenantID,
+			Username:  cfg.LogLokiUsername,
+			Password:  cfg.LogLokiPassword,
+			BatchWait: cfg.LogLokiBatchWait,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+
+	// Keep timestamps in UTC whatever the host time zone; this also covers values
+	// read back from the database, which pgx returns in the local time zone
+	time.Local = time.UTC
+
+	// Run maintenance commands instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rotate-keys":
+			os.Exit(runRotateKeys(cfg, os.Args[2:]))
+		case "migrate":
+			os.Exit(runMigrate(cfg, os.Args[2:]))
+		default:
+			log.Fatalf("Unknown command: %s", os.Args[1])
+		}
+	}
+
+	// Set Gin mode
+	gin.SetMode(cfg.GinMode)
+
+	// Create HTTP server. In degraded mode it starts listening right away and
+	// answers /healthz while dependencies come up; the router is swapped in later.
+	startup := newStartupState()
+	handler := &switchHandler{}
+	handler.set(startingHandler(startup))
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+	redirectSrv, err := configureTLS(srv, cfg)
+	if err != nil {
+		logger.Fatal("Failed to configure TLS", "error", err)
+	}
+	if redirectSrv != nil {
+		go serve(redirectSrv, cfg.TLSRedirectPort)
+	}
+	if cfg.StartupDegradedMode {
+		go serve(srv, cfg.Port)
+	}
 
-	// Initialize database
-	db, err := database.New(cfg)
+	// Initialize the OCR client and the Redis client used for rate limiting
+	ocrClient, err := ocr.NewClient(cfg.OCRServiceURL, ocr.ClientConfigFrom(cfg))
 	if err != nil {
-		logger.Fatal("Failed to connect to database", "error", err)
+		logger.Fatal("Failed to initialize OCR client", "error", err)
+	}
+	ocrClient.UseBreaker(ocr.NewBreaker(ocr.BreakerConfig{
+		Threshold:   cfg.OCRBreakerThreshold,
+		Cooldown:    cfg.OCRBreakerCooldown,
+		MaxCooldown: cfg.OCRBreakerMaxCooldown,
+	}))
+	redisClient, err := newRedisClient(cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize Redis client", "error", err)
+	}
+	if redisClient != nil {
+		defer redisClient.Close()
+	}
+
+	// Wait for dependencies, so the server survives starting before them
+	var db *database.DB
+	deps := []dependency{
+		{name: "database", required: true, check: func(ctx context.Context) error {
+			conn, err := database.New(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			db = conn
+			return nil
+		}},
+		{name: "ocr", check: ocrClient.HealthCheck},
+	}
+	if redisClient != nil {
+		deps = append(deps, dependency{name: "redis", check: redisClient.Ping})
+	}
+
+	waitCtx, stopWaiting := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err = waitForDependencies(waitCtx, startup, cfg.StartupMaxWait, deps)
+	stopWaiting()
+	if err != nil {
+		logger.Fatal("Dependencies unavailable", "error", err, "max_wait", cfg.StartupMaxWait)
 	}
 	defer db.Close()
 
+	// Bring the schema up to date before anything touches it
+	if cfg.DBAutoMigrate {
+		applied, err := db.Migrate(context.Background())
+		for _, m := range applied {
+			logger.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			logger.Fatal("Failed to migrate database", "error", err)
+		}
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db.Pool)
-	documentRepo := repository.NewDocumentRepository(db.Pool)
-	jobRepo := repository.NewJobRepository(db.Pool)
+	documentRepo := repository.NewDocumentRepository(db.Pool, db.Replica)
+	jobRepo := repository.NewJobRepository(db.Pool, db.Replica)
+	approvalRepo := repository.NewApprovalRepository(db.Pool)
 	resultRepo := repository.NewResultRepository(db.Pool)
+	regionRepo := repository.NewRegionRepository(db.Pool)
+	warehouseRepo := repository.NewWarehouseRepository(db.Pool)
+	outboxRepo := repository.NewOutboxRepository(db.Pool)
+	settingsRepo := repository.NewInstanceSettingsRepository(db.Pool)
+	auditRepo := repository.NewAuditRepository(db.Pool, db.Replica)
+	userSettingsRepo := repository.NewUserSettingsRepository(db.Pool)
+	apiKeyRepo := repository.NewAPIKeyRepository(db.Pool)
+	accessGrantRepo := repository.NewAccessGrantRepository(db.Pool)
+	orgRepo := repository.NewOrganizationRepository(db.Pool)
+	documentLinkRepo := repository.NewDocumentLinkRepository(db.Pool)
+	shareLinkRepo := repository.NewShareLinkRepository(db.Pool)
+	ssoRepo := repository.NewSSORepository(db.Pool)
+	dataExportRepo := repository.NewDataExportRepository(db.Pool)
+	retentionRepo := repository.NewRetentionRepository(db.Pool)
+	emailNotificationRepo := repository.NewEmailNotificationRepository(db.Pool)
+	templateRepo := repository.NewTemplateRepository(db.Pool)
+	integrationRepo := repository.NewIntegrationRepository(db.Pool)
+	notificationRepo := repository.NewNotificationRepository(db.Pool)
+	deliveryRepo := repository.NewDeliveryRepository(db.Pool)
+	connectorRepo := repository.NewConnectorRepository(db.Pool)
+	inboundEmailRepo := repository.NewInboundEmailRepository(db.Pool)
+	usageReportRepo := repository.NewUsageReportRepository(db.Pool, db.Replica)
+	storageStatsRepo := repository.NewStorageStatsRepository(db.Pool, db.Replica)
+	statsRepo := repository.NewStatsRepository(db.Pool, db.Replica)
+	backfillRepo := repository.NewBackfillRepository(db.Pool)
+	creditRepo := repository.NewCreditRepository(db.Pool)
+	jwtKeyRepo := repository.NewJWTKeyRepository(db.Pool)
+	transactor := repository.NewTransactor(db.Pool)
 
 	// Initialize storage
-	fileStorage, err := storage.NewStorage(cfg.StoragePath)
+	fileStorage, err := openStorage(cfg)
 	if err != nil {
 		logger.Fatal("Failed to initialize storage", "error", err)
 	}
+	fileStorage.UseReferenceChecker(documentRepo.ReferencedPaths)
+
+	// Initialize malware scanner
+	fileScanner, err := scanner.NewScanner(scanner.Config{
+		Driver:  cfg.AntivirusDriver,
+		Address: cfg.AntivirusAddress,
+		Timeout: cfg.AntivirusTimeout,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize antivirus scanner", "error", err)
+	}
+
+	// File types accepted as documents, uploaded or imported
+	allowedExts := []string{".jpg", ".jpeg", ".png", ".pdf", ".tiff", ".tif", ".gif", ".bmp", ".webp"}
+
+	// Initialize entity extraction
+	entityExtractor, err := entities.NewExtractor(entities.Config{
+		Providers: cfg.EntityProviders,
+		Types:     cfg.EntityTypes,
+		URL:       cfg.EntityRemoteURL,
+		Token:     cfg.EntityRemoteToken,
+		Timeout:   cfg.EntityRemoteTimeout,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize entity extraction", "error", err)
+	}
+
+	// Initialize document classification
+	documentClassifier, err := classifier.NewClassifier(classifier.Config{
+		Driver:  cfg.ClassifierDriver,
+		URL:     cfg.ClassifierURL,
+		Token:   cfg.ClassifierToken,
+		Timeout: cfg.ClassifierTimeout,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize document classifier", "error", err)
+	}
+
+	// Initialize event bus publisher
+	eventPublisher, err := events.NewPublisher(events.Config{
+		Driver:        cfg.EventBusDriver,
+		URL:           cfg.EventBusURL,
+		Prefix:        cfg.EventBusSubjectPrefix,
+		WebhookURL:    cfg.WebhookURL,
+		WebhookSecret: cfg.WebhookSecret,
+		Timeout:       5 * time.Second,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize event bus", "error", err)
+	}
 
-	// Initialize OCR client
-	ocrClient := ocr.NewClient(cfg.OCRServiceURL)
+	// Mail server used for alerts, user notifications and replies to inbound
+	// email
+	smtpConfig := mail.Config{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.SMTPFrom,
+	}
+
+	// Initialize alerting
+	hostname, _ := os.Hostname()
+	alerter, err := alerts.New(alerts.Config{
+		WebhookURL:      cfg.AlertWebhookURL,
+		WebhookSecret:   cfg.AlertWebhookSecret,
+		SlackWebhookURL: cfg.AlertSlackWebhookURL,
+		EmailTo:         cfg.AlertEmailTo,
+		SMTP:            smtpConfig,
+		Cooldown:        cfg.AlertCooldown,
+		Timeout:         10 * time.Second,
+		Instance:        hostname,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize alerting", "error", err)
+	}
+
+	// Initialize the OCR job worker pool
+	jobRunner := tasks.NewRunner("ocr_jobs", cfg.OCRWorkers, cfg.OCRQueueSize)
 
 	// Initialize services
-	authService := services.NewAuthService(userRepo, cfg)
-	jobService := services.NewJobService(jobRepo, resultRepo, documentRepo, ocrClient)
+	auditService := services.NewAuditService(auditRepo)
+	eventService := services.NewEventService(outboxRepo, transactor, eventPublisher, cfg.EventRelayInterval)
+	// Sign tokens with a ring of asymmetric keys, unless they are signed with
+	// the shared secret. Retired keys are kept as long as a token can live.
+	var jwtKeyService *services.JWTKeyService
+	if cfg.JWTAlgorithm != "HS256" {
+		jwtKeySealer, err := delivery.NewSealer(cfg.JWTKeyEncryptionKey)
+		if err != nil {
+			logger.Fatal("Invalid JWT key encryption key", "error", err)
+		}
+		accessExpiry, _ := time.ParseDuration(cfg.JWTExpiry)
+		refreshExpiry, _ := time.ParseDuration(cfg.RefreshTokenExpiry)
+		jwtKeyService = services.NewJWTKeyService(jwtKeyRepo, jwtKeySealer, cfg.JWTAlgorithm, cfg.JWTKeyRotationInterval, max(accessExpiry, refreshExpiry))
+		if err := jwtKeyService.Init(context.Background()); err != nil {
+			logger.Fatal("Failed to load JWT signing keys", "error", err)
+		}
+	}
+	authService := services.NewAuthService(userRepo, transactor, eventService, jwtKeyService, cfg)
+	ocrPolicyService := services.NewOCRPolicyService(settingsRepo, orgRepo)
+	brandingService := services.NewBrandingService(settingsRepo, orgRepo)
+	sharedUploadService := services.NewSharedUploadService(documentRepo, jobRepo, resultRepo, regionRepo, userRepo, eventService, ocrPolicyService)
+	creditCosts := make(map[models.ResolutionMode]int)
+	for resolution, cost := range cfg.CreditsPerPage {
+		creditCosts[models.ResolutionMode(resolution)] = cost
+	}
+	creditService := services.NewCreditService(creditRepo, transactor, creditCosts, cfg.CreditsMonthlyAllotment, cfg.CreditsEnforced)
+	var billingService *services.BillingService
+	if cfg.BillingEnabled {
+		creditPacks := make(map[int]int)
+		for credits, price := range cfg.BillingCreditPacks {
+			n, _ := strconv.Atoi(credits)
+			creditPacks[n] = price
+		}
+		stripe := billing.NewStripe(cfg.StripeAPIURL, cfg.StripeSecretKey, cfg.StripeWebhookSecret, 10*time.Second)
+		billingService = services.NewBillingService(stripe, creditService, userRepo, creditPacks, cfg.BillingCurrency, cfg.BillingSuccessURL, cfg.BillingCancelURL)
+	}
+	orientationService := services.NewOrientationService(documentRepo, fileStorage)
+	entityService := services.NewEntityService(entityExtractor)
+	classificationService := services.NewClassificationService(documentClassifier)
+	templateService := services.NewTemplateService(templateRepo)
+	jobService := services.NewJobService(jobRepo, approvalRepo, resultRepo, regionRepo, documentRepo, ocrClient, fileStorage, transactor, eventService, ocrPolicyService, creditService, entityService, classificationService, templateService, orientationService, jobRunner)
+	approvalService := services.NewApprovalService(approvalRepo, jobRepo, userRepo, orgRepo, transactor, eventService, ocrPolicyService, jobService)
+	resultService := services.NewResultService(resultRepo, regionRepo, jobRepo, documentRepo, entityService, orientationService, fileStorage)
 
-	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService, userRepo)
-	documentHandler := handlers.NewDocumentHandler(documentRepo, fileStorage, cfg.MaxFileSize, []string{".jpg", ".jpeg", ".png", ".pdf", ".tiff", ".tif", ".gif", ".bmp", ".webp"})
-	jobHandler := handlers.NewJobHandler(jobService)
-	healthCheckHandler := handlers.NewHealthCheckHandler(db.Pool)
+	// Credentials of delivery targets are stored sealed
+	var deliverySealer *delivery.Sealer
+	if cfg.DeliveryEnabled {
+		deliverySealer, err = delivery.NewSealer(cfg.DeliveryEncryptionKey)
+		if err != nil {
+			logger.Fatal("Invalid delivery encryption key", "error", err)
+		}
+	}
+	deliveryService := services.NewDeliveryService(deliveryRepo, resultRepo, documentRepo, resultService, transactor, deliverySealer, cfg.DeliveryInterval, cfg.DeliveryTimeout, cfg.DeliveryMaxAttempts, cfg.DeliveryAllowPrivate)
 
-	// Set Gin mode
-	gin.SetMode(cfg.GinMode)
+	integrationService := services.NewIntegrationService(integrationRepo, resultRepo, transactor, cfg.IntegrationHookInterval, cfg.IntegrationHookTimeout, cfg.IntegrationHookAllowPrivate)
+	notificationService := services.NewNotificationService(notificationRepo, transactor, cfg.NotificationDocumentURL, cfg.NotificationInterval, cfg.NotificationTimeout, cfg.NotificationAllowPrivate)
+	importProviders := connectors.NewProviders(connectors.Config{
+		GoogleClientID:      cfg.ConnectorGoogleClientID,
+		GoogleClientSecret:  cfg.ConnectorGoogleClientSecret,
+		DropboxClientID:     cfg.ConnectorDropboxAppKey,
+		DropboxClientSecret: cfg.ConnectorDropboxAppSecret,
+		Timeout:             cfg.ConnectorTimeout,
+	})
+	replySender := mail.NewSender(smtpConfig, 10*time.Second)
+	emailNotificationService := services.NewEmailNotificationService(emailNotificationRepo, transactor, replySender, cfg.NotificationDocumentURL, cfg.EmailNotificationInterval)
+	inboundEmailService := services.NewInboundEmailService(inboundEmailRepo, userRepo, userSettingsRepo, jobRepo, documentRepo, transactor, fileStorage, eventService, jobService, fileScanner, cfg.AntivirusFailOpen, replySender, cfg.InboundEmailDomain, cfg.InboundEmailSigningKey, cfg.InboundEmailDocumentURL, cfg.InboundEmailMaxAttachments, cfg.MaxFileSize, allowedExts)
+	importService := services.NewImportService(connectorRepo, documentRepo, transactor, fileStorage, eventService, jobService, fileScanner, cfg.AntivirusFailOpen, importProviders, cfg.JWTSecret, cfg.ConnectorCallbackURL, cfg.ConnectorSyncInterval, cfg.MaxFileSize, allowedExts)
+	usageReportService := services.NewUsageReportService(usageReportRepo, fileStorage, cfg.UsageReportInterval)
+	statsService := services.NewStatsService(statsRepo, userSettingsRepo)
+	storageStatsService := services.NewStorageStatsService(storageStatsRepo, documentRepo, fileStorage, cfg.StorageStatsInterval)
+	backfillService := services.NewBackfillService(backfillRepo, cfg.BackfillBatchSize)
+	backfillService.Register(services.NewPageCountBackfill(documentRepo, fileStorage))
+	warehouseTarget := services.NewLocalExportTarget(fileStorage)
+	if cfg.WarehouseExportDestination == "s3" {
+		warehouseTarget = delivery.NewS3(delivery.S3Config{
+			Endpoint:        cfg.WarehouseS3Endpoint,
+			Region:          cfg.WarehouseS3Region,
+			Bucket:          cfg.WarehouseS3Bucket,
+			Prefix:          cfg.WarehouseS3Prefix,
+			AccessKeyID:     cfg.WarehouseS3AccessKeyID,
+			SecretAccessKey: cfg.WarehouseS3SecretKey,
+		}, &http.Client{Timeout: 2 * time.Minute})
+	}
+	warehouseExportService := services.NewWarehouseExportService(warehouseRepo, warehouseTarget, cfg.WarehouseExportFormat, cfg.WarehouseExportInterval, cfg.WarehouseExportLag, cfg.WarehouseExportBatchSize)
+	trashService := services.NewTrashService(documentRepo, jobRepo, transactor, fileStorage, cfg.TrashRetention)
+	accountService := services.NewAccountService(userRepo, documentRepo, orgRepo, auditRepo, dataExportRepo, transactor, fileStorage, cfg.AccountDeletionGrace)
+	dataExportService := services.NewDataExportService(dataExportRepo, resultRepo, retentionRepo, accountService, fileStorage, cfg.ShareLinkSecret, cfg.DataExportTTL)
+	jobTrashService := services.NewJobTrashService(jobRepo, userSettingsRepo, cfg.JobTrashRetention)
+	retentionService := services.NewRetentionService(retentionRepo, userRepo, userSettingsRepo, trashService, notificationService, replySender, cfg.RetentionInterval, cfg.RetentionWarningPeriod)
+	janitorService := services.NewJanitorService(documentRepo, trashService, jobTrashService, accountService, fileStorage, cfg.JanitorInterval, cfg.OrphanFileGrace)
+	jobExpiryService := services.NewJobExpiryService(jobRepo, transactor, eventService, cfg.PendingJobCheckInterval, cfg.PendingJobLifetime)
+	jobReaperService := services.NewJobReaperService(jobRepo, jobService, cfg.StuckJobCheckInterval, cfg.StuckJobTimeout)
+	accessGrantService := services.NewAccessGrantService(accessGrantRepo, documentRepo, resultRepo, userRepo, transactor, eventService, auditService, cfg.AccessGrantCheckInterval, cfg.AccessGrantReminderLead)
+	jobEventStream := services.NewJobEventStream(jobRepo)
+	alertService := services.NewAlertService(alerter, jobRepo, fileStorage, ocrClient, cfg.AlertCheckInterval, services.AlertThresholds{
+		StorageMinFreePercent: cfg.AlertStorageFreePercent,
+		QueueWaitSLO:          cfg.AlertQueueWaitSLO,
+		FailureThreshold:      cfg.AlertFailureThreshold,
+		Canary:                cfg.AlertCanaryEnabled,
+	})
+	queueAdminService := services.NewQueueAdminService(jobRepo, jobService, janitorService, jobExpiryService, ocrClient)
+	requestStats := services.NewRequestStats()
+	systemStatsService := services.NewSystemStatsService(statsRepo, queueAdminService, requestStats)
+	var apiKeyService *services.APIKeyService
+	if cfg.EnableAPIKeys {
+		apiKeyService = services.NewAPIKeyService(apiKeyRepo)
+	}
+	orgService := services.NewOrganizationService(orgRepo, documentRepo, jobRepo, resultRepo, userRepo, transactor, eventService)
+	documentLinkService := services.NewDocumentLinkService(documentLinkRepo, documentRepo, resultRepo, fileStorage)
+	shareLinkService := services.NewShareLinkService(shareLinkRepo, documentRepo, resultRepo, brandingService, fileStorage, cfg.ShareLinkSecret, cfg.ShareLinkMaxTTL)
+	// Client secrets of organizations' identity providers are stored sealed
+	var ssoSealer *delivery.Sealer
+	if cfg.SSOEncryptionKey != "" {
+		ssoSealer, err = delivery.NewSealer(cfg.SSOEncryptionKey)
+		if err != nil {
+			logger.Fatal("Invalid SSO encryption key", "error", err)
+		}
+	}
+	ssoService := services.NewSSOService(ssoRepo, orgRepo, userRepo, transactor, authService, sso.NewOIDC(10*time.Second), ssoSealer, cfg.JWTSecret, cfg.SSOCallbackURL)
+	if err := ssoService.SealSecrets(context.Background()); err != nil {
+		logger.Fatal("Failed to seal SSO client secrets", "error", err)
+	}
+	uploadService := services.NewUploadService(documentRepo, userSettingsRepo, fileStorage, transactor, eventService, trashService, sharedUploadService, orgService, fileScanner, cfg.AntivirusFailOpen, cfg.MaxFileSize, allowedExts)
+	metadataImportService := services.NewMetadataImportService(documentRepo, transactor, fileStorage)
+	setupService := services.NewSetupService(settingsRepo, userRepo, transactor, authService, ocrClient)
 
-	// Create router
+	// Apply settings saved by the setup wizard
+	if err := setupService.ApplyStoredSettings(context.Background()); err != nil {
+		logger.Warn("Failed to apply stored instance settings", "error", err)
+	}
+
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	if cfg.WarehouseExportEnabled {
+		go warehouseExportService.Start(workerCtx)
+	}
+	if cfg.UsageReportsEnabled {
+		go usageReportService.Start(workerCtx)
+	}
+	if jwtKeyService != nil {
+		go jwtKeyService.Start(workerCtx)
+	}
+	go config.RenewVaultSecrets(workerCtx)
+	go eventService.Start(workerCtx)
+	go janitorService.Start(workerCtx)
+	go retentionService.Start(workerCtx)
+	go storageStatsService.Start(workerCtx)
+	go backfillService.Start(workerCtx)
+	go dataExportService.Start(workerCtx)
+	go jobExpiryService.Start(workerCtx)
+	go jobReaperService.Start(workerCtx)
+	go accessGrantService.Start(workerCtx)
+	go jobEventStream.Start(workerCtx)
+	go alertService.Start(workerCtx)
+	go integrationService.Start(workerCtx)
+	go notificationService.Start(workerCtx)
+	if cfg.DeliveryEnabled {
+		go deliveryService.Start(workerCtx)
+	}
+	if len(importProviders) > 0 {
+		go importService.Start(workerCtx)
+	}
+	if replySender.Configured() {
+		go emailNotificationService.Start(workerCtx)
+	}
+	if cfg.InboundEmailDomain != "" && replySender.Configured() {
+		go inboundEmailService.Start(workerCtx)
+	}
+
+	// Resume jobs left pending by a previous run
+	if queued, err := jobService.EnqueuePending(workerCtx); err != nil {
+		logger.Warn("Failed to queue pending jobs", "error", err)
+	} else if queued > 0 {
+		logger.Info("Queued pending jobs", "count", queued)
+	}
+
+	// Initialize rate limiters
+	rateLimits := newRateLimits(cfg, newLimiterFactory(cfg, redisClient))
+
+	authRateLimit := middleware.RateLimit(rateLimits.limiter("auth"), middleware.KeyByIP)
+	apiRateLimit := middleware.RateLimit(rateLimits.limiter("api"), middleware.KeyByUser)
+	uploadRateLimit := middleware.RateLimit(rateLimits.limiter("upload"), middleware.KeyByUser)
+	ocrRateLimit := middleware.RateLimit(rateLimits.limiter("ocr"), middleware.KeyByUser)
+	sharedRateLimit := middleware.RateLimit(rateLimits.limiter("shared"), middleware.KeyByIP)
+
+	// Reload the log level, rate limits and OCR workers on SIGHUP
+	go reloadOnHangup(workerCtx, rateLimits, jobRunner)
+
+	// Initialize the cache of responses shared by all callers
+	responseCache := newResponseCache(cfg, redisClient)
+
+	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(authService, auditService, userRepo)
+	accountHandler := handlers.NewAccountHandler(accountService, auditService)
+	documentHandler := handlers.NewDocumentHandler(documentRepo, uploadService, trashService, auditService, orientationService)
+	documentLinkHandler := handlers.NewDocumentLinkHandler(documentLinkService)
+	shareLinkHandler := handlers.NewShareLinkHandler(shareLinkService, auditService)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService, auditService)
+	templateHandler := handlers.NewTemplateHandler(templateService)
+	integrationHandler := handlers.NewIntegrationHandler(integrationService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	deliveryHandler := handlers.NewDeliveryHandler(deliveryService)
+	connectorHandler := handlers.NewConnectorHandler(importService, cfg.ConnectorSuccessURL)
+	inboundEmailHandler := handlers.NewInboundEmailHandler(inboundEmailService)
+	ssoHandler := handlers.NewSSOHandler(ssoService, auditService, cfg.SSOSuccessURL)
+	metadataImportHandler := handlers.NewMetadataImportHandler(metadataImportService, auditService)
+	jobHandler := handlers.NewJobHandler(jobService, jobTrashService, jobEventStream, auditService)
+	approvalHandler := handlers.NewApprovalHandler(approvalService, auditService)
+	accessGrantHandler := handlers.NewAccessGrantHandler(accessGrantService, auditService)
+	orgHandler := handlers.NewOrganizationHandler(orgService, auditService)
+	creditHandler := handlers.NewCreditHandler(creditService)
+	billingHandler := handlers.NewBillingHandler(billingService)
+	brandingHandler := handlers.NewBrandingHandler(brandingService, auditService)
+	resultHandler := handlers.NewResultHandler(resultService, brandingService)
+	errorCatalogHandler := handlers.NewErrorCatalogHandler()
+	usageReportHandler := handlers.NewUsageReportHandler(usageReportService, auditService)
+	storageStatsHandler := handlers.NewStorageStatsHandler(storageStatsService)
+	statsHandler := handlers.NewStatsHandler(statsService)
+	systemStatsHandler := handlers.NewSystemStatsHandler(systemStatsService)
+	backfillHandler := handlers.NewBackfillHandler(backfillService, auditService)
+	warehouseExportHandler := handlers.NewWarehouseExportHandler(warehouseExportService, cfg.WarehouseExportEnabled, cfg.WarehouseExportFormat, cfg.WarehouseExportDestination)
+	jobReaperHandler := handlers.NewJobReaperHandler(jobReaperService)
+	queueAdminHandler := handlers.NewQueueAdminHandler(queueAdminService, auditService)
+	ocrPolicyHandler := handlers.NewOCRPolicyHandler(ocrPolicyService, auditService)
+	eventHandler := handlers.NewEventHandler(eventService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	jwtKeyHandler := handlers.NewJWTKeyHandler(jwtKeyService, auditService)
+	settingsHandler := handlers.NewSettingsHandler(userSettingsRepo, retentionService, emailNotificationService, auditService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	setupHandler := handlers.NewSetupHandler(setupService, authService, auditService, responseCache)
+	healthCheckHandler := handlers.NewHealthCheckHandler(db.Pool, db.Replica)
+	healthCheckHandler.AddCheck("storage", handlers.HealthCheckFunc(func(context.Context) error {
+		return fileStorage.CheckWritable()
+	}), true)
+	healthCheckHandler.AddCheck("ocr", handlers.HealthCheckFunc(ocrClient.HealthCheck), false)
+	healthCheckHandler.AddCheck("ocr_circuit_breaker", ocrClient.Breaker(), false)
+	if redisClient != nil {
+		healthCheckHandler.AddCheck("redis", handlers.HealthCheckFunc(redisClient.Ping), false)
+	}
+
+	// Create router. Forwarded client IPs are only believed from the
+	// configured proxies, so clients cannot pick their own rate limit key.
 	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Fatal("Invalid trusted proxies", "error", err)
+	}
 
 	// Global middleware
 	router.Use(gin.Recovery())
 	router.Use(middleware.RequestID())
-	router.Use(middleware.Logger())
-	router.Use(middleware.CORS())
+	router.Use(middleware.ProblemDetails(cfg.ProblemTypeBaseURL))
+	router.Use(middleware.CountRequests(requestStats))
+	router.Use(middleware.Logger(middleware.LoggerConfig{
+		ErrorBodies:   cfg.LogErrorBodies,
+		MaxBodyBytes:  cfg.LogBodyMaxBytes,
+		SamplePercent: cfg.LogBodySamplePercent,
+		RedactFields:  cfg.LogRedactFields,
+	}))
+	router.Use(middleware.CORS(middleware.CORSConfig{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}))
+	router.Use(middleware.BodyLimit(int64(cfg.MaxBodySize), bodyLimits(cfg)))
 
-	// Health check endpoint with database verification
+	// Liveness (/healthz) only tells the process is serving; readiness
+	// (/readyz) checks every dependency
 	router.GET("/api/v1/health", healthCheckHandler.Handle)
+	router.GET("/readyz", healthCheckHandler.Handle)
+	router.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+			"status": "running",
+			"checks": startup.snapshot(),
+		}, "Service is running"))
+	})
+
+	// Public keys verifying tokens, for other services
+	router.GET("/.well-known/jwks.json", jwtKeyHandler.JWKS)
 
-	// API v1 routes
-	v1 := router.Group("/api/v1")
-	{
-		// Auth routes with rate limiting
-		authRateLimiter := middleware.NewRateLimiter(10, 1*time.Minute) // 10 requests per minute
-		auth := v1.Group("/auth")
-		auth.Use(authRateLimiter.RateLimit())
+	// Prometheus metrics
+	if cfg.EnableMetrics {
+		router.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+	}
+
+	// API routes, served as v1 and, with the v2 envelope, as v2
+	registerAPI := func(api *gin.RouterGroup) {
+		// Account changes need the settings permission, so API keys narrowed to
+		// other permissions cannot make them
+		accountPermission := middleware.RequirePermission(userRepo, models.ResourceSettings)
+
+		// Auth routes with rate limiting per client IP
+		auth := api.Group("/auth")
+		auth.Use(authRateLimit)
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/logout", authHandler.Logout)
 			auth.POST("/refresh", authHandler.RefreshToken)
-			auth.GET("/me", middleware.AuthRequired(authService), authHandler.GetCurrentUser)
+			auth.GET("/me", middleware.AuthRequired(authService, apiKeyService), authHandler.GetCurrentUser)
+			auth.PATCH("/me", middleware.AuthRequired(authService, apiKeyService), accountPermission, accountHandler.UpdateProfile)
+			auth.DELETE("/me", middleware.AuthRequired(authService, apiKeyService), accountPermission, accountHandler.Delete)
+			auth.POST("/me/restore", middleware.AuthRequired(authService, apiKeyService), accountPermission, accountHandler.CancelDeletion)
+			auth.GET("/me/export", middleware.AuthRequired(authService, apiKeyService), accountPermission, accountHandler.Export)
+			auth.GET("/sso/login", ssoHandler.Login)
+			auth.GET("/sso/callback", ssoHandler.Callback)
+		}
+
+		// First-run setup wizard, locked once completed
+		setup := api.Group("/setup")
+		setup.Use(authRateLimit)
+		{
+			setup.GET("", middleware.CacheResponse(responseCache, handlers.CacheSetupStatus, "public, max-age=30"), setupHandler.GetStatus)
+			setup.POST("", setupHandler.Complete)
+		}
+
+		// Branding for the host of the request, shown on pages for signed-out viewers:
+		// the organization's whose custom domain it is, or the instance's
+		api.GET("/branding", brandingHandler.GetPublic)
+
+		// Catalog of the error codes responses carry, for client developers
+		api.GET("/errors", errorCatalogHandler.List)
+
+		// Documents shared through signed links, rate limited per client IP
+		shared := api.Group("/shared")
+		shared.Use(sharedRateLimit)
+		{
+			shared.GET("/:id", shareLinkHandler.Get)
+			shared.GET("/:id/file", shareLinkHandler.GetFile)
+		}
+
+		// Cloud storage accounts redirect back here once access is granted
+		api.GET("/connectors/callback", authRateLimit, connectorHandler.Callback)
+
+		// Emails received by the Mailgun inbound route, verified by their signature
+		if cfg.InboundEmailDomain != "" {
+			api.POST("/inbound-email/mailgun", sharedRateLimit, inboundEmailHandler.Mailgun)
+		}
+
+		// Data export archives, downloaded through signed links
+		api.GET("/export/:id/download", sharedRateLimit, dataExportHandler.Download)
+
+		// Stripe payment events, verified by their signature
+		if cfg.BillingEnabled {
+			api.POST("/billing/webhook", billingHandler.Webhook)
 		}
 
 		// Protected routes
-		protected := v1.Group("")
-		protected.Use(middleware.AuthRequired(authService))
+		protected := api.Group("")
+		protected.Use(middleware.AuthRequired(authService, apiKeyService), apiRateLimit)
 		{
 			// Document routes
 			documents := protected.Group("/documents")
+			documents.Use(middleware.RequirePermission(userRepo, models.ResourceDocuments))
 			{
-				documents.POST("/upload", documentHandler.Upload)
+				documents.POST("/upload", uploadRateLimit, documentHandler.Upload)
 				documents.GET("", documentHandler.List)
+				documents.GET("/trash", documentHandler.ListTrash)
+				documents.POST("/metadata-import", metadataImportHandler.Import)
+				documents.GET("/metadata-import/:id/errors", metadataImportHandler.DownloadErrors)
 				documents.GET("/:id", documentHandler.Get)
+				documents.GET("/:id/preview", documentHandler.Preview)
+				documents.POST("/:id/reocr", ocrRateLimit, jobHandler.ReOCRDocument)
 				documents.DELETE("/:id", documentHandler.Delete)
+				documents.POST("/:id/restore", documentHandler.Restore)
+				documents.GET("/:id/links", documentLinkHandler.List)
+				documents.POST("/:id/links", documentLinkHandler.Create)
+				documents.PUT("/:id/links/:link_id", documentLinkHandler.Update)
+				documents.DELETE("/:id/links/:link_id", documentLinkHandler.Delete)
+				documents.GET("/:id/related", documentLinkHandler.Related)
+				documents.GET("/:id/related/export", documentLinkHandler.ExportRelated)
+				documents.POST("/:id/share", shareLinkHandler.Create)
+				documents.GET("/:id/share", shareLinkHandler.List)
+				documents.DELETE("/:id/share/:link_id", shareLinkHandler.Revoke)
+			}
+
+			// Data export routes
+			export := protected.Group("/export")
+			export.Use(accountPermission)
+			{
+				export.POST("", dataExportHandler.Request)
+				export.GET("", dataExportHandler.List)
+				export.GET("/:id", dataExportHandler.Get)
 			}
 
 			// OCR routes
 			ocr := protected.Group("/ocr")
+			ocr.Use(middleware.RequirePermission(userRepo, models.ResourceJobs))
 			{
-				ocr.POST("/submit", jobHandler.SubmitJob)
-				ocr.POST("/batch", jobHandler.SubmitBatchJob)
+				ocr.POST("/submit", ocrRateLimit, jobHandler.SubmitJob)
+				ocr.POST("/batch", ocrRateLimit, jobHandler.SubmitBatchJob)
+				ocr.GET("/policy", ocrPolicyHandler.GetOwn)
+				ocr.GET("/events", jobHandler.StreamEvents)
 				ocr.GET("/jobs", jobHandler.ListJobs)
+				ocr.GET("/jobs/trash", jobHandler.ListTrash)
 				ocr.GET("/jobs/:id", jobHandler.GetJob)
+				ocr.GET("/jobs/:id/events", jobHandler.GetJobEvents)
 				ocr.GET("/jobs/:id/result", jobHandler.GetJobResult)
+				if cfg.DeliveryEnabled {
+					ocr.GET("/jobs/:id/deliveries", deliveryHandler.ListJobDeliveries)
+				}
 				ocr.PUT("/jobs/:id/cancel", jobHandler.CancelJob)
 				ocr.DELETE("/jobs/:id", jobHandler.DeleteJob)
+				ocr.POST("/jobs/:id/restore", jobHandler.RestoreJob)
+				ocr.PUT("/jobs/:id/archive", jobHandler.ArchiveJob)
+				ocr.DELETE("/jobs/:id/archive", jobHandler.UnarchiveJob)
+				ocr.GET("/approvals", approvalHandler.List)
+				ocr.POST("/approvals/:id/approve", approvalHandler.Approve)
+				ocr.POST("/approvals/:id/reject", approvalHandler.Reject)
 			}
 
 			// Results routes
 			results := protected.Group("/results")
+			results.Use(middleware.RequirePermission(userRepo, models.ResourceResults))
 			{
-				results.GET("/:id", handlers.GetResult)
-				results.GET("/:id/download", handlers.DownloadResult)
+				results.GET("/:id", resultHandler.Get)
+				results.GET("/:id/download", resultHandler.Download)
 				results.GET("/:id/preview", handlers.PreviewResult)
+				results.GET("/:id/regions", resultHandler.GetRegions)
+				results.GET("/:id/breakdown", resultHandler.GetBreakdown)
+				results.GET("/:id/entities", resultHandler.GetEntities)
+				results.GET("/:id/fields", resultHandler.GetFields)
+				results.GET("/:id/pages", resultHandler.GetPages)
+				results.GET("/:id/pages/:page", resultHandler.GetPage)
+				results.POST("/:id/pages/:page/reprocess", ocrRateLimit, jobHandler.ReprocessPage)
+			}
+
+			// Export routes
+			exports := protected.Group("/exports")
+			exports.Use(middleware.RequirePermission(userRepo, models.ResourceResults))
+			{
+				exports.POST("/fields", resultHandler.ExportFields)
+			}
+
+			// Extraction template routes
+			templates := protected.Group("/templates")
+			templates.Use(middleware.RequirePermission(userRepo, models.ResourceResults))
+			{
+				templates.POST("", templateHandler.Create)
+				templates.GET("", templateHandler.List)
+				templates.GET("/:id", templateHandler.Get)
+				templates.PUT("/:id", templateHandler.Update)
+				templates.DELETE("/:id", templateHandler.Delete)
+			}
+
+			// No-code integration routes: polling trigger and REST hooks
+			integrations := protected.Group("/integrations")
+			integrations.Use(middleware.RequirePermission(userRepo, models.ResourceResults))
+			{
+				integrations.GET("/results", integrationHandler.PollResults)
+				integrations.POST("/hooks", integrationHandler.Subscribe)
+				integrations.GET("/hooks", integrationHandler.ListHooks)
+				integrations.DELETE("/hooks/:id", integrationHandler.Unsubscribe)
+			}
+
+			// Result delivery target routes
+			if cfg.DeliveryEnabled {
+				deliveryTargets := protected.Group("/delivery-targets")
+				deliveryTargets.Use(middleware.RequirePermission(userRepo, models.ResourceResults))
+				{
+					deliveryTargets.GET("", deliveryHandler.List)
+					deliveryTargets.POST("", deliveryHandler.Create)
+					deliveryTargets.PUT("/:id", deliveryHandler.Update)
+					deliveryTargets.DELETE("/:id", deliveryHandler.Delete)
+					deliveryTargets.POST("/:id/test", deliveryHandler.Test)
+					deliveryTargets.GET("/:id/deliveries", deliveryHandler.ListDeliveries)
+				}
+			}
+
+			// Inbound email address routes
+			if cfg.InboundEmailDomain != "" {
+				inboundEmail := protected.Group("/inbound-email")
+				inboundEmail.Use(middleware.RequirePermission(userRepo, models.ResourceDocuments))
+				{
+					inboundEmail.GET("", inboundEmailHandler.GetAddress)
+					inboundEmail.POST("/rotate", inboundEmailHandler.RotateAddress)
+				}
+			}
+
+			// Google Drive and Dropbox import connector routes
+			connectorRoutes := protected.Group("/connectors")
+			connectorRoutes.Use(middleware.RequirePermission(userRepo, models.ResourceDocuments))
+			{
+				connectorRoutes.POST("", connectorHandler.Authorize)
+				connectorRoutes.GET("", connectorHandler.List)
+				connectorRoutes.GET("/:id/folders", connectorHandler.ListFolders)
+				connectorRoutes.PUT("/:id", connectorHandler.Update)
+				connectorRoutes.DELETE("/:id", connectorHandler.Delete)
+				connectorRoutes.POST("/:id/sync", connectorHandler.Sync)
+			}
+
+			// Access grant routes
+			grants := protected.Group("/grants")
+			grants.Use(middleware.RequirePermission(userRepo, models.ResourceGrants))
+			{
+				grants.POST("", accessGrantHandler.Create)
+				grants.GET("", accessGrantHandler.List)
+				grants.GET("/received", accessGrantHandler.ListReceived)
+				grants.DELETE("/:id", accessGrantHandler.Revoke)
+				grants.GET("/:id/activity", accessGrantHandler.Activity)
+				grants.GET("/:id/documents", accessGrantHandler.ListDocuments)
+				grants.GET("/:id/documents/:document_id", accessGrantHandler.GetDocument)
+			}
+
+			// Organization routes
+			orgs := protected.Group("/orgs")
+			orgs.Use(middleware.RequirePermission(userRepo, models.ResourceOrgs))
+			{
+				orgs.POST("", orgHandler.Create)
+				orgs.GET("", orgHandler.List)
+				orgs.GET("/invitations", orgHandler.ListReceivedInvitations)
+				orgs.POST("/invitations/:id/accept", orgHandler.AcceptInvitation)
+				orgs.POST("/invitations/:id/decline", orgHandler.DeclineInvitation)
+				orgs.GET("/:id", orgHandler.Get)
+				orgs.DELETE("/:id", orgHandler.Delete)
+				orgs.POST("/:id/invitations", orgHandler.Invite)
+				orgs.GET("/:id/invitations", orgHandler.ListInvitations)
+				orgs.DELETE("/:id/invitations/:invitation_id", orgHandler.RevokeInvitation)
+				orgs.PUT("/:id/members/:user_id", orgHandler.UpdateMember)
+				orgs.DELETE("/:id/members/:user_id", orgHandler.RemoveMember)
+				orgs.GET("/:id/documents", orgHandler.ListDocuments)
+				orgs.POST("/:id/documents", orgHandler.ShareDocuments)
+				orgs.GET("/:id/documents/:document_id", orgHandler.GetDocument)
+				orgs.DELETE("/:id/documents/:document_id", orgHandler.UnshareDocument)
+				orgs.GET("/:id/jobs", orgHandler.ListJobs)
+				orgs.GET("/:id/ocr-policy", ocrPolicyHandler.GetForOrg)
+				orgs.PUT("/:id/ocr-policy", ocrPolicyHandler.UpdateForOrg)
+				orgs.GET("/:id/branding", brandingHandler.GetForOrg)
+				orgs.PUT("/:id/branding", brandingHandler.UpdateForOrg)
+				orgs.POST("/:id/branding/domain/verify", brandingHandler.VerifyDomainForOrg)
+				orgs.GET("/:id/sso", ssoHandler.GetConfig)
+				orgs.PUT("/:id/sso", ssoHandler.Configure)
+				orgs.DELETE("/:id/sso", ssoHandler.DeleteConfig)
+				orgs.POST("/:id/sso/domains/:domain/verify", ssoHandler.VerifyDomain)
+			}
+
+			// Usage routes
+			usage := protected.Group("/usage")
+			usage.Use(middleware.RequirePermission(userRepo, models.ResourceUsage))
+			{
+				usage.GET("/credits", creditHandler.GetCredits)
+				usage.GET("/pages", resultHandler.GetPageUsage)
+			}
+
+			// Job statistics
+			stats := protected.Group("/stats")
+			stats.Use(middleware.RequirePermission(userRepo, models.ResourceUsage))
+			{
+				stats.GET("", statsHandler.Get)
+			}
+
+			// Billing routes
+			if cfg.BillingEnabled {
+				billingRoutes := protected.Group("/billing")
+				billingRoutes.Use(middleware.RequirePermission(userRepo, models.ResourceBilling))
+				{
+					billingRoutes.GET("/packs", billingHandler.ListPacks)
+					billingRoutes.POST("/checkout", billingHandler.Checkout)
+				}
 			}
 
 			// Settings routes
 			settings := protected.Group("/settings")
+			settings.Use(middleware.RequirePermission(userRepo, models.ResourceSettings))
+			{
+				settings.GET("", settingsHandler.Get)
+				settings.PUT("", settingsHandler.Update)
+				settings.GET("/retention", settingsHandler.GetRetention)
+				settings.PUT("/retention", settingsHandler.UpdateRetention)
+				settings.GET("/notifications", notificationHandler.List)
+				settings.POST("/notifications", notificationHandler.Create)
+				settings.PUT("/notifications/:id", notificationHandler.Update)
+				settings.DELETE("/notifications/:id", notificationHandler.Delete)
+				settings.POST("/notifications/:id/test", notificationHandler.Test)
+			}
+
+			// API key routes
+			if cfg.EnableAPIKeys {
+				apiKeys := protected.Group("/api-keys")
+				apiKeys.Use(middleware.RequirePermission(userRepo, models.ResourceAPIKeys))
+				{
+					apiKeys.POST("", apiKeyHandler.Create)
+					apiKeys.GET("", apiKeyHandler.List)
+					apiKeys.DELETE("/:id", apiKeyHandler.Revoke)
+				}
+			}
+
+			// Admin routes
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequirePermission(userRepo, models.ResourceAdmin))
 			{
-				settings.GET("", handlers.GetSettings)
-				settings.PUT("", handlers.UpdateSettings)
+				admin.GET("/exports/warehouse", warehouseExportHandler.Status)
+				admin.POST("/exports/warehouse/run", warehouseExportHandler.Run)
+				admin.GET("/reports", usageReportHandler.List)
+				admin.POST("/reports", usageReportHandler.Generate)
+				admin.GET("/reports/:id", usageReportHandler.Get)
+				admin.GET("/reports/:id/download", usageReportHandler.Download)
+				admin.GET("/stats", systemStatsHandler.Get)
+				admin.GET("/storage", storageStatsHandler.Get)
+				admin.GET("/tasks", backfillHandler.List)
+				admin.GET("/tasks/:name", backfillHandler.Get)
+				admin.POST("/tasks/:name", backfillHandler.Run)
+				admin.POST("/tasks/:name/cancel", backfillHandler.Cancel)
+				admin.GET("/events", eventHandler.List)
+				admin.POST("/events/replay", eventHandler.Replay)
+				admin.GET("/audit", auditHandler.List)
+				admin.GET("/jwt-keys", jwtKeyHandler.List)
+				admin.POST("/jwt-keys/rotate", jwtKeyHandler.Rotate)
+				admin.GET("/jobs", queueAdminHandler.ListJobs)
+				admin.GET("/jobs/queue", queueAdminHandler.QueueStats)
+				admin.POST("/jobs/requeue", queueAdminHandler.Requeue)
+				admin.POST("/jobs/cancel", queueAdminHandler.Cancel)
+				admin.POST("/jobs/reap", jobReaperHandler.Sweep)
+				admin.POST("/maintenance/cleanup", queueAdminHandler.Cleanup)
+				admin.GET("/ocr/health", queueAdminHandler.OCRHealth)
+				admin.GET("/ocr-policy", ocrPolicyHandler.Get)
+				admin.PUT("/ocr-policy", ocrPolicyHandler.Update)
+				admin.GET("/branding", brandingHandler.Get)
+				admin.PUT("/branding", brandingHandler.Update)
+				admin.POST("/branding/domain/verify", brandingHandler.VerifyDomain)
+
+				// Runtime profiling, disabled unless explicitly enabled
+				if cfg.EnableProfiling {
+					handlers.RegisterProfilingRoutes(admin.Group("/debug/pprof"))
+				}
 			}
 		}
 	}
 
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", cfg.Port),
-		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	// v1 answers in the v1 envelope unless clients ask for v2 responses in
+	// Accept, and warns of its deprecation once scheduled
+	v1 := router.Group("/api/v1", middleware.V2Envelope(cfg.ProblemTypeBaseURL, true))
+	if !cfg.APIV1DeprecatedAt.IsZero() {
+		v1.Use(middleware.Deprecation(cfg.APIV1DeprecatedAt, cfg.APIV1SunsetAt, "/api/v1", "/api/v2", cfg.APIV1DeprecationURL))
 	}
+	registerAPI(v1)
+	registerAPI(router.Group("/api/v2", middleware.V2Envelope(cfg.ProblemTypeBaseURL, false)))
 
-	// Start server in goroutine
-	go func() {
-		logger.Info("Starting server", "port", cfg.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatal("Failed to start server", "error", err)
+	// Serve the API, starting the server unless degraded mode already did
+	handler.set(router)
+	if !cfg.StartupDegradedMode {
+		go serve(srv, cfg.Port)
+	}
+
+	// Serve the gRPC API to internal service consumers, over TLS with the
+	// API's certificate when TLS is configured
+	var grpcSrv *grpc.Server
+	if cfg.GRPCEnabled {
+		var grpcTLS *tls.Config
+		if srv.TLSConfig != nil {
+			grpcTLS = &tls.Config{
+				MinVersion:     tls.VersionTLS12,
+				GetCertificate: srv.TLSConfig.GetCertificate,
+			}
 		}
-	}()
+		grpcSrv = grpcapi.NewServer(authService, apiKeyService, userRepo, uploadService, jobService, resultService, jobEventStream, auditService).GRPCServer(grpcTLS)
+		go serveGRPC(grpcSrv, cfg.GRPCPort, grpcTLS != nil)
+	}
 
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
@@ -161,6 +963,9 @@ func main() {
 
 	logger.Info("Shutting down server...")
 
+	// Stop background workers
+	stopWorkers()
+
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -168,6 +973,66 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal("Server forced to shutdown", "error", err)
 	}
+	if redirectSrv != nil {
+		if err := redirectSrv.Shutdown(ctx); err != nil {
+			logger.Warn("HTTPS redirect server forced to shutdown", "error", err)
+		}
+	}
+	if grpcSrv != nil {
+		stopGRPC(ctx, grpcSrv)
+	}
+
+	// Let running OCR jobs finish; jobs still queued stay pending in the database
+	if err := jobRunner.Shutdown(ctx); err != nil {
+		logger.Warn("OCR workers did not finish in time", "error", err)
+	}
 
 	logger.Info("Server exited")
+	logger.Sync()
+}
+
+// serve runs the HTTP server until it is shut down, over TLS when it has a
+// TLS configuration
+func serve(srv *http.Server, port string) {
+	logger.Info("Starting server", "port", port, "tls", srv.TLSConfig != nil)
+
+	var err error
+	if srv.TLSConfig != nil {
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		logger.Fatal("Failed to start server", "error", err)
+	}
+}
+
+// serveGRPC runs the gRPC server until it is stopped
+func serveGRPC(srv *grpc.Server, port string, useTLS bool) {
+	logger.Info("Starting gRPC server", "port", port, "tls", useTLS)
+
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		logger.Fatal("Failed to start gRPC server", "error", err)
+	}
+	if err := srv.Serve(lis); err != nil {
+		logger.Fatal("Failed to start gRPC server", "error", err)
+	}
+}
+
+// stopGRPC lets running gRPC calls finish, and cuts off those still running
+// once ctx is done
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Warn("gRPC server forced to shutdown", "error", ctx.Err())
+		srv.Stop()
+	}
 }
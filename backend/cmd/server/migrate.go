@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"visekai/backend/internal/config"
+	"visekai/backend/internal/database"
+	"visekai/backend/pkg/logger"
+)
+
+// runMigrate manages the database schema:
+//
+//	migrate up          apply pending migrations
+//	migrate status      list migrations and when they were applied
+//	migrate baseline N  mark migrations up to N as applied without running them
+func runMigrate(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: migrate up | status | baseline <version>")
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Wait for the database like the server does, so CI can run this as soon
+	// as the database container has been started
+	var db *database.DB
+	err := waitForDependencies(ctx, newStartupState(), cfg.StartupMaxWait, []dependency{
+		{name: "database", required: true, check: func(ctx context.Context) error {
+			conn, err := database.New(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			db = conn
+			return nil
+		}},
+	})
+	if err != nil {
+		logger.Error("Database unavailable", "error", err)
+		return 1
+	}
+	defer db.Close()
+
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := db.Migrate(ctx)
+		for _, m := range applied {
+			logger.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			logger.Error("Migration failed", "error", err)
+			return 1
+		}
+		logger.Info("Database schema is up to date", "applied", len(applied))
+
+	case "status":
+		status, err := db.MigrationStatus(ctx)
+		if err != nil {
+			logger.Error("Failed to get migration status", "error", err)
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+
+	case "baseline":
+		if flags.NArg() != 2 {
+			flags.Usage()
+			return 2
+		}
+		version, err := strconv.Atoi(flags.Arg(1))
+		if err != nil {
+			flags.Usage()
+			return 2
+		}
+		if err := db.Baseline(ctx, version); err != nil {
+			logger.Error("Baseline failed", "error", err)
+			return 1
+		}
+		logger.Info("Database baselined", "version", version)
+
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	return 0
+}
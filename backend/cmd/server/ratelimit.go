@@ -0,0 +1,121 @@
+package main
+
+import (
+	"sort"
+
+	"visekai/backend/internal/config"
+	"visekai/backend/internal/middleware"
+	"visekai/backend/pkg/redis"
+)
+
+// limiterFactory creates the named rate limiter for a quota, or nil when the quota is disabled
+type limiterFactory func(name string, limit config.RateLimit) middleware.Limiter
+
+// newRedisClient returns the Redis client backing rate limits and the response
+// cache, or nil when neither uses Redis. Connections are opened lazily.
+func newRedisClient(cfg *config.Config) (*redis.Client, error) {
+	if cfg.RateLimitBackend != "redis" && cfg.ResponseCacheBackend != "redis" {
+		return nil, nil
+	}
+
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RedisPassword != "" {
+		opts.Password = cfg.RedisPassword
+	}
+
+	return redis.NewClient(opts), nil
+}
+
+// newLimiterFactory returns a factory for the configured rate limit backend.
+// Requests are let through while Redis is down.
+func newLimiterFactory(cfg *config.Config, client *redis.Client) limiterFactory {
+	if cfg.RateLimitBackend != "redis" {
+		return func(name string, limit config.RateLimit) middleware.Limiter {
+			if limit.Requests <= 0 {
+				return nil
+			}
+			return middleware.NewRateLimiter(limit.Requests, limit.Window)
+		}
+	}
+
+	return func(name string, limit config.RateLimit) middleware.Limiter {
+		if limit.Requests <= 0 {
+			return nil
+		}
+		return middleware.NewRedisLimiter(client, name, limit.Requests, limit.Window)
+	}
+}
+
+// newResponseCache returns the configured response cache, or nil when disabled.
+// Handlers run uncached while Redis is down.
+func newResponseCache(cfg *config.Config, client *redis.Client) middleware.ResponseCache {
+	if cfg.ResponseCacheTTL <= 0 {
+		return nil
+	}
+
+	switch cfg.ResponseCacheBackend {
+	case "memory":
+		return middleware.NewMemoryResponseCache(cfg.ResponseCacheTTL)
+	case "redis":
+		return middleware.NewRedisResponseCache(client, cfg.ResponseCacheTTL)
+	default:
+		return nil
+	}
+}
+
+// rateLimits holds the named rate limiters of the API, so a reload can replace
+// the ones whose quota changed
+type rateLimits struct {
+	newLimiter limiterFactory
+	limiters   map[string]*middleware.ReloadableLimiter
+	quotas     map[string]config.RateLimit
+}
+
+// newRateLimits creates the rate limiters of the configured quotas
+func newRateLimits(cfg *config.Config, newLimiter limiterFactory) *rateLimits {
+	r := &rateLimits{
+		newLimiter: newLimiter,
+		limiters:   map[string]*middleware.ReloadableLimiter{},
+		quotas:     rateLimitQuotas(cfg),
+	}
+	for name, quota := range r.quotas {
+		r.limiters[name] = middleware.NewReloadableLimiter(newLimiter(name, quota))
+	}
+	return r
+}
+
+// limiter returns the named rate limiter
+func (r *rateLimits) limiter(name string) middleware.Limiter {
+	return r.limiters[name]
+}
+
+// reload replaces the limiters whose quota changed, returning their names.
+// Requests counted by a replaced limiter are forgotten.
+func (r *rateLimits) reload(cfg *config.Config) []string {
+	var changed []string
+	for name, quota := range rateLimitQuotas(cfg) {
+		if quota == r.quotas[name] {
+			continue
+		}
+		r.limiters[name].Set(r.newLimiter(name, quota))
+		r.quotas[name] = quota
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// rateLimitQuotas returns the quota of each named rate limiter
+func rateLimitQuotas(cfg *config.Config) map[string]config.RateLimit {
+	general := config.RateLimit{Requests: cfg.RateLimitRequests, Window: cfg.RateLimitWindow}
+	return map[string]config.RateLimit{
+		"auth":   cfg.RateLimitAuth,
+		"api":    general,
+		"upload": cfg.RateLimitUpload,
+		"ocr":    cfg.RateLimitOCR,
+		"shared": general,
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"visekai/backend/internal/config"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+)
+
+// reloadOnHangup loads the configuration again on every SIGHUP until ctx is
+// done, applying the log level, rate limits and OCR worker count. Other
+// settings take effect on restart. An invalid configuration is logged and
+// the current one kept.
+func reloadOnHangup(ctx context.Context, rateLimits *rateLimits, jobRunner *tasks.Runner) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			logger.Error("Failed to reload configuration, keeping the current one", "error", err)
+			continue
+		}
+
+		logger.SetLevel(cfg.LogLevel)
+		changed := rateLimits.reload(cfg)
+		jobRunner.Resize(cfg.OCRWorkers)
+
+		logger.Info("Configuration reloaded",
+			"log_level", cfg.LogLevel,
+			"ocr_workers", cfg.OCRWorkers,
+			"rate_limits_changed", changed,
+		)
+	}
+}
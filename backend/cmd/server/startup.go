@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// startupAttemptTimeout bounds a single connectivity check
+	startupAttemptTimeout = 5 * time.Second
+	// startupMinBackoff and startupMaxBackoff bound the delay between checks
+	startupMinBackoff = 500 * time.Millisecond
+	startupMaxBackoff = 15 * time.Second
+)
+
+// dependency is an external service the server connects to at boot
+type dependency struct {
+	name string
+	// required dependencies abort startup when still unavailable after the
+	// maximum wait; optional ones only log a warning
+	required bool
+	check    func(ctx context.Context) error
+}
+
+// startupState tracks the state of each dependency while the server boots
+type startupState struct {
+	mu     sync.Mutex
+	status map[string]string
+}
+
+func newStartupState() *startupState {
+	return &startupState{status: make(map[string]string)}
+}
+
+func (s *startupState) set(name, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status[name] = status
+}
+
+func (s *startupState) snapshot() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := make(map[string]string, len(s.status))
+	for name, st := range s.status {
+		status[name] = st
+	}
+	return status
+}
+
+// waitForDependencies checks all dependencies concurrently, retrying each with
+// exponential backoff until it succeeds or maxWait elapses. It returns an error
+// when a required dependency did not come up or ctx was cancelled.
+func waitForDependencies(ctx context.Context, state *startupState, maxWait time.Duration, deps []dependency) error {
+	ctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+
+	errs := make([]error, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		state.set(dep.name, "waiting")
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = waitForDependency(ctx, state, dep)
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, dep := range deps {
+		if errs[i] == nil {
+			continue
+		}
+		if dep.required {
+			failed = append(failed, errs[i])
+			continue
+		}
+		logger.Warn("Optional dependency unavailable, starting without it", "dependency", dep.name, "error", errs[i])
+	}
+
+	return errors.Join(failed...)
+}
+
+// waitForDependency retries a single dependency check until it succeeds or ctx is done
+func waitForDependency(ctx context.Context, state *startupState, dep dependency) error {
+	backoff := startupMinBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, startupAttemptTimeout)
+		err := dep.check(attemptCtx)
+		cancel()
+		if err == nil {
+			state.set(dep.name, "ready")
+			if attempt > 1 {
+				logger.Info("Dependency is ready", "dependency", dep.name, "attempts", attempt)
+			}
+			return nil
+		}
+
+		state.set(dep.name, "unavailable: "+err.Error())
+		logger.Warn("Dependency not ready, retrying", "dependency", dep.name, "attempt", attempt, "retry_in", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return errors.New(dep.name + " unavailable: " + err.Error())
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, startupMaxBackoff)
+	}
+}
+
+// switchHandler serves HTTP requests with a handler that can be replaced at
+// runtime, so the listener can start before the router is built
+type switchHandler struct {
+	handler atomic.Pointer[http.Handler]
+}
+
+func (h *switchHandler) set(handler http.Handler) {
+	h.handler.Store(&handler)
+}
+
+func (h *switchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.handler.Load()).ServeHTTP(w, r)
+}
+
+// startingHandler answers /healthz with the dependency states while the server
+// boots and rejects every other request, /readyz included, as unavailable
+func startingHandler(state *startupState) http.Handler {
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	router.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(gin.H{
+			"status": "starting",
+			"checks": state.snapshot(),
+		}, "Service is starting"))
+	})
+	router.NoRoute(func(c *gin.Context) {
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, models.NewErrorResponse(
+			"SYS_018",
+			"Service is starting, please retry shortly",
+			nil,
+		))
+	})
+
+	return router
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"visekai/backend/internal/config"
+	"visekai/backend/pkg/storage"
+)
+
+// openStorage creates the file storage with encryption at rest configured
+func openStorage(cfg *config.Config) (*storage.Storage, error) {
+	fileStorage, err := storage.NewStorage(cfg.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.StorageContentAddressed {
+		fileStorage.EnableContentAddressing()
+	}
+
+	switch cfg.StorageEncryption {
+	case "", "none":
+	case "local":
+		keyring, err := storage.ParseKeyring(cfg.StorageEncryptionKeys, cfg.StorageEncryptionKeyID)
+		if err != nil {
+			return nil, err
+		}
+		fileStorage.EnableEncryption(keyring)
+	case "vault-transit":
+		transit, err := storage.NewVaultTransit(cfg.VaultAddr, cfg.VaultToken, cfg.VaultTransitKey, cfg.VaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+		fileStorage.EnableEncryption(transit)
+	default:
+		return nil, fmt.Errorf("unknown storage encryption: %s", cfg.StorageEncryption)
+	}
+
+	return fileStorage, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"visekai/backend/internal/config"
+	"visekai/backend/pkg/keypair"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// configureTLS serves srv over TLS when a certificate or autocert domains are
+// configured, negotiating HTTP/2 unless it is disabled. It returns the plain
+// HTTP server redirecting to HTTPS and answering ACME challenges, or nil when
+// there is none to run.
+func configureTLS(srv *http.Server, cfg *config.Config) (*http.Server, error) {
+	nextProtos := []string{"h2", "http/1.1"}
+	if !cfg.HTTP2Enabled {
+		nextProtos = []string{"http/1.1"}
+		// A non-nil map keeps net/http from enabling HTTP/2 itself
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+
+	var manager *autocert.Manager
+	switch {
+	case len(cfg.TLSAutocertDomains) > 0:
+		manager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+			Email:      cfg.TLSAutocertEmail,
+		}
+		if cfg.TLSAutocertDirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: cfg.TLSAutocertDirectoryURL}
+		}
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: manager.GetCertificate,
+			// The TLS-ALPN challenge lets certificates be obtained without
+			// the redirect server
+			NextProtos: append(nextProtos, acme.ALPNProto),
+		}
+	case cfg.TLSCertFile != "":
+		cert, err := keypair.Load(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("TLS certificate: %w", err)
+		}
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: cert.GetCertificate,
+			NextProtos:     nextProtos,
+		}
+	default:
+		return nil, nil
+	}
+
+	if cfg.TLSRedirectPort == "" {
+		return nil, nil
+	}
+	var handler http.Handler = redirectToHTTPS(cfg.Port)
+	if manager != nil {
+		handler = manager.HTTPHandler(handler)
+	}
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.TLSRedirectPort),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}, nil
+}
+
+// redirectToHTTPS redirects requests to the same URL over HTTPS on port.
+// Methods other than GET and HEAD keep their method and body with a 308.
+func redirectToHTTPS(port string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// apiBackend carries out commands through the admin API
+type apiBackend struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+func newAPIBackend(baseURL, token string) (*apiBackend, error) {
+	if token == "" {
+		return nil, errors.New("an admin token is required; set VISEKAI_API_TOKEN or pass -token, or use -direct")
+	}
+	return &apiBackend{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api/v1/admin",
+		token:      token,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (b *apiBackend) Close() {}
+
+func (b *apiBackend) QueueStats(ctx context.Context) (*models.QueueStats, error) {
+	var stats models.QueueStats
+	return &stats, b.do(ctx, http.MethodGet, "/jobs/queue", nil, &stats)
+}
+
+func (b *apiBackend) ListJobs(ctx context.Context, filter models.JobFilter) ([]*models.OCRJob, error) {
+	query := url.Values{}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		query.Set("status", strings.Join(statuses, ","))
+	}
+	if filter.UserID != nil {
+		query.Set("user_id", filter.UserID.String())
+	}
+	if filter.DocumentID != nil {
+		query.Set("document_id", filter.DocumentID.String())
+	}
+	if filter.CreatedBefore != nil {
+		query.Set("created_before", filter.CreatedBefore.Format(time.RFC3339))
+	}
+	if filter.StartedBefore != nil {
+		query.Set("started_before", filter.StartedBefore.Format(time.RFC3339))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	var jobs []*models.OCRJob
+	if err := b.do(ctx, http.MethodGet, "/jobs?"+query.Encode(), nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (b *apiBackend) Requeue(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error) {
+	var result models.JobBulkResult
+	if err := b.do(ctx, http.MethodPost, "/jobs/requeue", filter, &result); err != nil {
+		return nil, err
+	}
+	return result.Jobs, nil
+}
+
+func (b *apiBackend) Cancel(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error) {
+	var result models.JobBulkResult
+	if err := b.do(ctx, http.MethodPost, "/jobs/cancel", filter, &result); err != nil {
+		return nil, err
+	}
+	return result.Jobs, nil
+}
+
+func (b *apiBackend) Reap(ctx context.Context) (*models.StuckJobSweep, error) {
+	var sweep models.StuckJobSweep
+	return &sweep, b.do(ctx, http.MethodPost, "/jobs/reap", nil, &sweep)
+}
+
+func (b *apiBackend) Cleanup(ctx context.Context) (*services.CleanupReport, error) {
+	var report services.CleanupReport
+	return &report, b.do(ctx, http.MethodPost, "/maintenance/cleanup", nil, &report)
+}
+
+func (b *apiBackend) OCRHealth(ctx context.Context) (*services.OCRHealthReport, error) {
+	var report services.OCRHealthReport
+	return &report, b.do(ctx, http.MethodGet, "/ocr/health", nil, &report)
+}
+
+// do sends a request to the admin API and decodes the data of the response
+// envelope into out. Successful responses are decoded whatever their status,
+// as the OCR health check answers 503 when the backend is down.
+func (b *apiBackend) do(ctx context.Context, method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool               `json:"success"`
+		Data    json.RawMessage    `json:"data"`
+		Error   models.ErrorDetail `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("unexpected response from %s (status %d): %w", path, resp.StatusCode, err)
+	}
+	if !envelope.Success {
+		return fmt.Errorf("%s: %s (status %d)", envelope.Error.Code, envelope.Error.Message, resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+
+	"visekai/backend/internal/config"
+	"visekai/backend/internal/database"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/ocr"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// directBackend carries out commands against the database, configured from the
+// same environment as the server. Bulk changes are recorded in the audit log.
+type directBackend struct {
+	db           *database.DB
+	queueService *services.QueueAdminService
+	auditService *services.AuditService
+}
+
+func newDirectBackend(ctx context.Context) (*directBackend, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	logger.Init(cfg.LogLevel)
+
+	// The primary alone is enough, and never behind
+	cfg.DBReplicaDSN = ""
+
+	db, err := database.New(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the OCR URL saved by the setup wizard, as the server does
+	ocrClient, err := ocr.NewClient(cfg.OCRServiceURL, ocr.ClientConfigFrom(cfg))
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	settingsRepo := repository.NewInstanceSettingsRepository(db.Pool)
+	if ocrURL, ok, err := settingsRepo.Get(ctx, models.SettingOCRServiceURL); err == nil && ok {
+		ocrClient.SetBaseURL(ocrURL)
+	}
+
+	// Without the server's worker pool, janitor and expiry service, requeued jobs
+	// stay pending until the server restarts and cleanup is unavailable
+	jobRepo := repository.NewJobRepository(db.Pool, nil)
+	return &directBackend{
+		db:           db,
+		queueService: services.NewQueueAdminService(jobRepo, nil, nil, nil, ocrClient),
+		auditService: services.NewAuditService(repository.NewAuditRepository(db.Pool, nil)),
+	}, nil
+}
+
+func (b *directBackend) Close() {
+	b.db.Close()
+}
+
+func (b *directBackend) QueueStats(ctx context.Context) (*models.QueueStats, error) {
+	return b.queueService.QueueStats(ctx)
+}
+
+func (b *directBackend) ListJobs(ctx context.Context, filter models.JobFilter) ([]*models.OCRJob, error) {
+	return b.queueService.ListJobs(ctx, filter)
+}
+
+func (b *directBackend) Requeue(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error) {
+	ids, err := b.queueService.Requeue(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	b.audit(ctx, models.AuditJobsRequeued, filter, ids)
+	return ids, nil
+}
+
+func (b *directBackend) Cancel(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error) {
+	ids, err := b.queueService.Cancel(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	b.audit(ctx, models.AuditJobsCancelled, filter, ids)
+	return ids, nil
+}
+
+func (b *directBackend) Reap(ctx context.Context) (*models.StuckJobSweep, error) {
+	return nil, fmt.Errorf("reap: %w; use \"requeue -status processing -started-before <duration>\" instead", services.ErrServerOnly)
+}
+
+func (b *directBackend) Cleanup(ctx context.Context) (*services.CleanupReport, error) {
+	return b.queueService.Cleanup(ctx)
+}
+
+func (b *directBackend) OCRHealth(ctx context.Context) (*services.OCRHealthReport, error) {
+	return b.queueService.OCRHealth(ctx), nil
+}
+
+// audit records a bulk change made outside the API, attributed to the local
+// operating system user since there is no authenticated account
+func (b *directBackend) audit(ctx context.Context, action models.AuditAction, filter models.JobFilter, ids []uuid.UUID) {
+	actor := "unknown"
+	if u, err := user.Current(); err == nil {
+		actor = u.Username
+	}
+	host, _ := os.Hostname()
+
+	b.auditService.Record(ctx, &models.AuditEvent{
+		Action:     action,
+		ActorEmail: "visekai-admin:" + actor + "@" + host,
+		TargetType: "job",
+		UserAgent:  "visekai-admin",
+		Metadata: map[string]any{
+			"filter": filter,
+			"jobs":   len(ids),
+			"direct": true,
+		},
+	})
+}
@@ -0,0 +1,369 @@
+// Command visekai-admin inspects and manipulates the OCR job queue. It talks to
+// the admin API by default; with -direct it connects to the database using the
+// server's environment, for when the API is down.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/google/uuid"
+)
+
+const usage = `Usage: visekai-admin [global flags] <command> [flags]
+
+Commands:
+  queue        show queue depth by status
+  jobs         list jobs matching filters
+  stuck        list jobs processing for longer than -older-than
+  requeue      reset matching failed, cancelled or processing jobs to pending
+  cancel       cancel matching pending or processing jobs
+  reap         requeue jobs stuck in processing now (API only)
+  cleanup      run the janitor and expire overdue pending jobs (API only)
+  ocr-health   check the OCR backend
+
+Global flags:
+`
+
+// backend carries out commands through the admin API or the database
+type backend interface {
+	QueueStats(ctx context.Context) (*models.QueueStats, error)
+	ListJobs(ctx context.Context, filter models.JobFilter) ([]*models.OCRJob, error)
+	Requeue(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error)
+	Cancel(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error)
+	Reap(ctx context.Context) (*models.StuckJobSweep, error)
+	Cleanup(ctx context.Context) (*services.CleanupReport, error)
+	OCRHealth(ctx context.Context) (*services.OCRHealthReport, error)
+	Close()
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	global := flag.NewFlagSet("visekai-admin", flag.ContinueOnError)
+	global.Usage = func() {
+		fmt.Fprint(global.Output(), usage)
+		global.PrintDefaults()
+	}
+	apiURL := global.String("api", envOr("VISEKAI_API_URL", "http://localhost:8080"), "base URL of the API server (VISEKAI_API_URL)")
+	token := global.String("token", os.Getenv("VISEKAI_API_TOKEN"), "admin access token or API key (VISEKAI_API_TOKEN)")
+	direct := global.Bool("direct", false, "connect to the database directly, configured like the server (break-glass)")
+	asJSON := global.Bool("json", false, "print JSON instead of tables")
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var b backend
+	var err error
+	if *direct {
+		b, err = newDirectBackend(ctx)
+	} else {
+		b, err = newAPIBackend(*apiURL, *token)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "visekai-admin:", err)
+		return 1
+	}
+	defer b.Close()
+
+	out := &printer{json: *asJSON}
+	command, cmdArgs := global.Arg(0), global.Args()[1:]
+
+	switch command {
+	case "queue":
+		err = runQueue(ctx, b, out)
+	case "jobs":
+		err = runJobs(ctx, b, out, cmdArgs)
+	case "stuck":
+		err = runStuck(ctx, b, out, cmdArgs)
+	case "requeue":
+		err = runBulk(ctx, "requeue", b.Requeue, out, cmdArgs, *direct)
+	case "cancel":
+		err = runBulk(ctx, "cancel", b.Cancel, out, cmdArgs, *direct)
+	case "reap":
+		var sweep *models.StuckJobSweep
+		if sweep, err = b.Reap(ctx); err == nil {
+			out.print(sweep, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "Requeued:\t%d\nFailed:\t%d\n", len(sweep.Requeued), len(sweep.Failed))
+			})
+		}
+	case "cleanup":
+		var report *services.CleanupReport
+		if report, err = b.Cleanup(ctx); err == nil {
+			out.print(report, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "Documents purged:\t%d\n", report.Janitor.DocumentsPurged)
+				fmt.Fprintf(w, "Orphaned files removed:\t%d\n", report.Janitor.OrphanedFilesRemoved)
+				fmt.Fprintf(w, "Import reports removed:\t%d\n", report.Janitor.ImportReportsRemoved)
+				fmt.Fprintf(w, "Janitor errors:\t%d\n", report.Janitor.Errors)
+				fmt.Fprintf(w, "Pending jobs expired:\t%d\n", report.ExpiredJobs)
+			})
+		}
+	case "ocr-health":
+		var report *services.OCRHealthReport
+		if report, err = b.OCRHealth(ctx); err == nil {
+			out.print(report, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "URL:\t%s\nHealthy:\t%t\nLatency:\t%dms\n", report.URL, report.Healthy, report.LatencyMS)
+				if report.Error != "" {
+					fmt.Fprintf(w, "Error:\t%s\n", report.Error)
+				}
+			})
+			if !report.Healthy {
+				return 1
+			}
+		}
+	default:
+		global.Usage()
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if errors.Is(err, errUsage) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "visekai-admin:", err)
+		return 1
+	}
+	return 0
+}
+
+func runQueue(ctx context.Context, b backend, out *printer) error {
+	stats, err := b.QueueStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	out.print(stats, func(w *tabwriter.Writer) {
+		for _, status := range []models.JobStatus{
+			models.JobStatusPending,
+			models.JobStatusProcessing,
+			models.JobStatusCompleted,
+			models.JobStatusFailed,
+			models.JobStatusCancelled,
+		} {
+			fmt.Fprintf(w, "%s:\t%d\n", status, stats.Counts[status])
+		}
+		if stats.OldestPendingAt != nil {
+			fmt.Fprintf(w, "Oldest pending:\t%s ago\n", since(*stats.OldestPendingAt))
+		}
+		if stats.OldestProcessingAt != nil {
+			fmt.Fprintf(w, "Longest processing:\t%s\n", since(*stats.OldestProcessingAt))
+		}
+		if stats.Workers > 0 {
+			fmt.Fprintf(w, "Workers:\t%d\nQueued in memory:\t%d\n", stats.Workers, stats.Queued)
+		}
+	})
+	return nil
+}
+
+func runJobs(ctx context.Context, b backend, out *printer, args []string) error {
+	flags := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	filter := addFilterFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	f, err := filter.build()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := b.ListJobs(ctx, f)
+	if err != nil {
+		return err
+	}
+	out.jobs(jobs)
+	return nil
+}
+
+func runStuck(ctx context.Context, b backend, out *printer, args []string) error {
+	flags := flag.NewFlagSet("stuck", flag.ContinueOnError)
+	olderThan := flags.Duration("older-than", 30*time.Minute, "minimum time spent processing")
+	limit := flags.Int("limit", 100, "maximum number of jobs")
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	startedBefore := time.Now().UTC().Add(-*olderThan)
+	jobs, err := b.ListJobs(ctx, models.JobFilter{
+		Statuses:      []models.JobStatus{models.JobStatusProcessing},
+		StartedBefore: &startedBefore,
+		Limit:         *limit,
+	})
+	if err != nil {
+		return err
+	}
+	out.jobs(jobs)
+	return nil
+}
+
+// runBulk requeues or cancels the jobs matching the filter flags. Without any
+// filter it refuses to run unless -all is given.
+func runBulk(ctx context.Context, verb string, apply func(context.Context, models.JobFilter) ([]uuid.UUID, error), out *printer, args []string, direct bool) error {
+	flags := flag.NewFlagSet(verb, flag.ContinueOnError)
+	filter := addFilterFlags(flags)
+	all := flags.Bool("all", false, "allow running without a filter")
+	if err := flags.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	f, err := filter.build()
+	if err != nil {
+		return err
+	}
+	if !*all && len(f.Statuses) == 0 && f.UserID == nil && f.DocumentID == nil && f.CreatedBefore == nil && f.StartedBefore == nil {
+		fmt.Fprintf(os.Stderr, "%s: no filter given; pass -all to %s every matching job\n", verb, verb)
+		return errUsage
+	}
+
+	ids, err := apply(ctx, f)
+	if err != nil {
+		return err
+	}
+
+	out.print(models.JobBulkResult{Jobs: ids}, func(w *tabwriter.Writer) {
+		for _, id := range ids {
+			fmt.Fprintln(w, id)
+		}
+		fmt.Fprintf(w, "%d jobs affected\n", len(ids))
+		if direct && verb == "requeue" && len(ids) > 0 {
+			fmt.Fprintln(w, "Jobs requeued directly are processed once the API server restarts")
+		}
+	})
+	return nil
+}
+
+// errUsage reports invalid command line arguments, already explained to the user
+var errUsage = errors.New("usage")
+
+func parseError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+	return errUsage
+}
+
+// filterFlags holds the job filter flags shared by several commands
+type filterFlags struct {
+	status        *string
+	user          *string
+	document      *string
+	olderThan     *time.Duration
+	startedBefore *time.Duration
+	limit         *int
+}
+
+func addFilterFlags(flags *flag.FlagSet) *filterFlags {
+	return &filterFlags{
+		status:        flags.String("status", "", "comma-separated job statuses"),
+		user:          flags.String("user", "", "user ID"),
+		document:      flags.String("document", "", "document ID"),
+		olderThan:     flags.Duration("older-than", 0, "only jobs created at least this long ago"),
+		startedBefore: flags.Duration("started-before", 0, "only jobs started at least this long ago"),
+		limit:         flags.Int("limit", 100, "maximum number of jobs (up to 1000)"),
+	}
+}
+
+// build turns the flags into a filter. An invalid ID is an error rather than
+// ignored, which would widen the filter.
+func (f *filterFlags) build() (models.JobFilter, error) {
+	filter := models.JobFilter{Limit: *f.limit}
+
+	if *f.status != "" {
+		for _, status := range strings.Split(*f.status, ",") {
+			filter.Statuses = append(filter.Statuses, models.JobStatus(strings.TrimSpace(status)))
+		}
+	}
+	if *f.user != "" {
+		id, err := uuid.Parse(*f.user)
+		if err != nil {
+			return filter, fmt.Errorf("invalid user ID: %w", err)
+		}
+		filter.UserID = &id
+	}
+	if *f.document != "" {
+		id, err := uuid.Parse(*f.document)
+		if err != nil {
+			return filter, fmt.Errorf("invalid document ID: %w", err)
+		}
+		filter.DocumentID = &id
+	}
+	if *f.olderThan > 0 {
+		t := time.Now().UTC().Add(-*f.olderThan)
+		filter.CreatedBefore = &t
+	}
+	if *f.startedBefore > 0 {
+		t := time.Now().UTC().Add(-*f.startedBefore)
+		filter.StartedBefore = &t
+	}
+
+	return filter, nil
+}
+
+// printer writes results as aligned tables or JSON
+type printer struct {
+	json bool
+}
+
+func (p *printer) print(data any, table func(w *tabwriter.Writer)) {
+	if p.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(data)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	table(w)
+	w.Flush()
+}
+
+func (p *printer) jobs(jobs []*models.OCRJob) {
+	p.print(jobs, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tSTATUS\tUSER\tDOCUMENT\tAGE\tRETRIES\tERROR")
+		for _, job := range jobs {
+			errorMessage := ""
+			if job.ErrorMessage != nil {
+				errorMessage = *job.ErrorMessage
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d/%d\t%s\n",
+				job.ID, job.Status, job.UserID, job.DocumentID, since(job.CreatedAt),
+				job.RetryCount, job.MaxRetries, errorMessage)
+		}
+	})
+}
+
+// since formats the time elapsed since t, rounded to the second
+func since(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
+
+func envOr(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
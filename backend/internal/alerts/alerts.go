@@ -0,0 +1,198 @@
+// Package alerts notifies operators of conditions that need attention, such as
+// storage running out or the OCR backend failing, by email, Slack or webhook.
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"visekai/backend/internal/mail"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/metrics"
+)
+
+// Severity is how urgent an alert is
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Alert describes a condition. Alerts with the same Key describe the same
+// condition and are deduplicated.
+type Alert struct {
+	Key      string         `json:"key"`
+	Severity Severity       `json:"severity"`
+	Summary  string         `json:"summary"`
+	Details  map[string]any `json:"details,omitempty"`
+	// Resolved is set on the notice sent when a condition clears
+	Resolved bool      `json:"resolved"`
+	FiredAt  time.Time `json:"fired_at"`
+	// Instance is the host that raised the alert
+	Instance string `json:"instance,omitempty"`
+}
+
+// Notifier delivers alerts to a target
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+	Name() string
+}
+
+// Config holds the alert targets and the cool-down between repeated alerts
+type Config struct {
+	WebhookURL      string
+	WebhookSecret   string
+	SlackWebhookURL string
+	EmailTo         []string
+	SMTP            mail.Config
+	// Cooldown is the time before a condition that is still firing is alerted
+	// again; within it repeated firings are suppressed
+	Cooldown time.Duration
+	Timeout  time.Duration
+	Instance string
+}
+
+var alertsSent = metrics.NewCounterVec(
+	"visekai_alerts_total",
+	"Alerts raised, by condition and outcome (sent, suppressed or failed).",
+	"key", "outcome",
+)
+
+// Alerter raises alerts on its targets, deduplicating them: a condition is
+// alerted when it starts firing, again once per cool-down while it keeps
+// firing, and once more when it resolves. State is kept per instance.
+type Alerter struct {
+	notifiers []Notifier
+	cooldown  time.Duration
+	instance  string
+
+	mu     sync.Mutex
+	active map[string]time.Time // key -> last notified
+}
+
+// New creates an alerter for the configured targets. Without targets alerts
+// are only logged.
+func New(cfg Config) (*Alerter, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Minute
+	}
+
+	a := &Alerter{
+		cooldown: cfg.Cooldown,
+		instance: cfg.Instance,
+		active:   make(map[string]time.Time),
+	}
+
+	if cfg.WebhookURL != "" {
+		a.notifiers = append(a.notifiers, NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret, cfg.Timeout))
+	}
+	if cfg.SlackWebhookURL != "" {
+		a.notifiers = append(a.notifiers, NewSlackNotifier(cfg.SlackWebhookURL, cfg.Timeout))
+	}
+	if len(cfg.EmailTo) > 0 {
+		if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+			return nil, fmt.Errorf("email alerts need an SMTP host and sender")
+		}
+		a.notifiers = append(a.notifiers, NewEmailNotifier(cfg.SMTP, cfg.EmailTo, cfg.Timeout))
+	}
+
+	return a, nil
+}
+
+// Enabled reports whether any target is configured
+func (a *Alerter) Enabled() bool {
+	return a != nil && len(a.notifiers) > 0
+}
+
+// Fire raises an alert unless the same condition was alerted within the
+// cool-down. It reports whether the alert was sent.
+func (a *Alerter) Fire(ctx context.Context, alert Alert) bool {
+	if a == nil {
+		return false
+	}
+
+	now := time.Now().UTC()
+	a.mu.Lock()
+	last, firing := a.active[alert.Key]
+	if firing && now.Sub(last) < a.cooldown {
+		a.mu.Unlock()
+		alertsSent.Inc(alert.Key, "suppressed")
+		return false
+	}
+	a.active[alert.Key] = now
+	a.mu.Unlock()
+
+	alert.FiredAt = now
+	alert.Resolved = false
+	logger.Warn("Alert firing", "key", alert.Key, "severity", alert.Severity, "summary", alert.Summary)
+
+	if !a.notify(ctx, &alert) {
+		// Forget the alert so the next firing retries it rather than waiting out the cool-down
+		a.mu.Lock()
+		if a.active[alert.Key] == now {
+			delete(a.active, alert.Key)
+		}
+		a.mu.Unlock()
+		return false
+	}
+	return true
+}
+
+// Resolve sends a resolution notice if the condition was firing
+func (a *Alerter) Resolve(ctx context.Context, alert Alert) bool {
+	if a == nil {
+		return false
+	}
+
+	a.mu.Lock()
+	_, firing := a.active[alert.Key]
+	delete(a.active, alert.Key)
+	a.mu.Unlock()
+	if !firing {
+		return false
+	}
+
+	alert.FiredAt = time.Now().UTC()
+	alert.Resolved = true
+	logger.Info("Alert resolved", "key", alert.Key, "summary", alert.Summary)
+
+	return a.notify(ctx, &alert)
+}
+
+// notify delivers an alert to every target
+func (a *Alerter) notify(ctx context.Context, alert *Alert) bool {
+	if !a.Enabled() {
+		return false
+	}
+	alert.Instance = a.instance
+
+	var errs []error
+	for _, n := range a.notifiers {
+		if err := n.Notify(ctx, alert); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		alertsSent.Inc(alert.Key, "failed")
+		logger.Error("Failed to send alert", "key", alert.Key, "error", err)
+		return false
+	}
+
+	alertsSent.Inc(alert.Key, "sent")
+	return true
+}
+
+// title is the one-line form of an alert used by chat and email targets
+func title(alert *Alert) string {
+	if alert.Resolved {
+		return "[RESOLVED] " + alert.Summary
+	}
+	return fmt.Sprintf("[%s] %s", alert.Severity, alert.Summary)
+}
@@ -0,0 +1,44 @@
+package alerts
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/mail"
+)
+
+// EmailNotifier mails alerts through an SMTP server
+type EmailNotifier struct {
+	sender *mail.Sender
+	to     []string
+}
+
+// NewEmailNotifier creates a new email notifier
+func NewEmailNotifier(cfg mail.Config, to []string, timeout time.Duration) *EmailNotifier {
+	return &EmailNotifier{sender: mail.NewSender(cfg, timeout), to: to}
+}
+
+// Name identifies the target in logs
+func (n *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Notify mails the alert to every recipient
+func (n *EmailNotifier) Notify(ctx context.Context, alert *Alert) error {
+	return n.sender.Send(ctx, mail.Message{
+		To:      n.to,
+		Subject: title(alert),
+		Body:    message(alert),
+	})
+}
+
+// message builds the plain text body of an alert email
+func message(alert *Alert) string {
+	var b strings.Builder
+	b.WriteString(alert.Summary + "\n\n")
+	for _, line := range detailLines(alert) {
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
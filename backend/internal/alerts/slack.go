@@ -0,0 +1,76 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SlackNotifier posts alerts to a Slack incoming webhook
+type SlackNotifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewSlackNotifier creates a new Slack notifier
+func NewSlackNotifier(url string, timeout time.Duration) *SlackNotifier {
+	return &SlackNotifier{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name identifies the target in logs
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// Notify posts the alert as a message
+func (n *SlackNotifier) Notify(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(map[string]string{"text": slackText(alert)})
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return send(n.httpClient, req)
+}
+
+// slackText formats an alert as mrkdwn: the title in bold, then its details
+func slackText(alert *Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*", title(alert))
+	for _, line := range detailLines(alert) {
+		fmt.Fprintf(&b, "\n• %s", line)
+	}
+	return b.String()
+}
+
+// detailLines lists an alert's details as key: value lines, sorted by key,
+// followed by the instance that raised it
+func detailLines(alert *Alert) []string {
+	keys := make([]string, 0, len(alert.Details))
+	for key := range alert.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %v", key, alert.Details[key]))
+	}
+	if alert.Instance != "" {
+		lines = append(lines, "instance: "+alert.Instance)
+	}
+	return lines
+}
@@ -0,0 +1,75 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"visekai/backend/internal/events"
+)
+
+// WebhookNotifier posts alerts as JSON to an HTTP endpoint, signed like event
+// webhooks when a secret is configured
+type WebhookNotifier struct {
+	url        string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a new webhook notifier
+func NewWebhookNotifier(url, secret string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:        url,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name identifies the target in logs
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify delivers the alert; any non-2xx response is treated as a failure
+func (n *WebhookNotifier) Notify(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Visekai-Alert", alert.Key)
+	req.Header.Set("X-Visekai-Timestamp", timestamp)
+	if n.secret != "" {
+		req.Header.Set("X-Visekai-Signature", "sha256="+events.Sign(n.secret, timestamp, body))
+	}
+
+	return send(n.httpClient, req)
+}
+
+// send performs a request and fails on any non-2xx response
+func send(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert target returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
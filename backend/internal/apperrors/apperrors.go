@@ -0,0 +1,91 @@
+// Package apperrors defines the kinds of errors repositories and services
+// return, so handlers can pick the response status with errors.Is instead of
+// comparing error strings.
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kinds of errors. Match them with errors.Is; an *Error of a kind and anything
+// wrapping it with %w match the kind.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrForbidden  = errors.New("forbidden")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrPayment    = errors.New("payment required")
+)
+
+// statuses maps each kind to the HTTP status it is reported with
+var statuses = map[error]int{
+	ErrNotFound:   http.StatusNotFound,
+	ErrForbidden:  http.StatusForbidden,
+	ErrConflict:   http.StatusConflict,
+	ErrValidation: http.StatusBadRequest,
+	ErrPayment:    http.StatusPaymentRequired,
+}
+
+// Error is an error of one kind with the error code API clients see. Its
+// message is safe to show to them.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is the kind of the error
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// NotFound returns an error for a missing resource
+func NotFound(code, message string) *Error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: message}
+}
+
+// Forbidden returns an error for an action the caller may not take
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: message}
+}
+
+// Conflict returns an error for an action the resource's current state rules out
+func Conflict(code, message string) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message}
+}
+
+// Validation returns an error for invalid input
+func Validation(code, message string) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Message: message}
+}
+
+// PaymentRequired returns an error for an action the caller has not paid for
+func PaymentRequired(code, message string) *Error {
+	return &Error{Kind: ErrPayment, Code: code, Message: message}
+}
+
+// HTTPStatus returns the status err is reported with: that of its kind, or 500
+// for errors of no kind
+func HTTPStatus(err error) int {
+	for kind, status := range statuses {
+		if errors.Is(err, kind) {
+			return status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Code returns the error code of the first *Error in err's chain, or an empty
+// string when there is none
+func Code(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,422 @@
+package apperrors
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// CatalogEntry describes an error code API clients may see
+type CatalogEntry struct {
+	Code     string `json:"code"`
+	Status   int    `json:"status"`
+	Category string `json:"category"`
+	// Description tells what the code means; the message of a response
+	// carrying it may be more specific
+	Description string `json:"description"`
+}
+
+// categories names the category, in snake case, of the codes of each prefix
+var categories = map[string]string{
+	"ACCT":     "accounts",
+	"APPROVAL": "approvals",
+	"AUTH":     "auth",
+	"BILLING":  "billing",
+	"BRAND":    "branding",
+	"CONN":     "import_connectors",
+	"CREDIT":   "credits",
+	"DLV":      "result_delivery",
+	"DOC":      "documents",
+	"EVT":      "domain_events",
+	"EXP":      "exports",
+	"GRANT":    "access_grants",
+	"HOOK":     "no_code_integrations",
+	"INB":      "inbound_email",
+	"JOB":      "ocr_jobs",
+	"LINK":     "document_links",
+	"NOTIF":    "notifications",
+	"ORG":      "organizations",
+	"RATE":     "rate_limiting",
+	"REPORT":   "usage_reports",
+	"RES":      "resources",
+	"SEC":      "security",
+	"SETUP":    "setup",
+	"SHARE":    "share_links",
+	"SSO":      "single_sign_on",
+	"SYS":      "system",
+	"TASK":     "backfill_tasks",
+	"TPL":      "extraction_templates",
+	"VAL":      "validation",
+}
+
+// catalog lists every error code with the status it is usually reported
+// with. Codes sent with another status in some cases say so in their
+// description. Add new codes here as they are introduced.
+var catalog = []CatalogEntry{
+	// Accounts
+	{Code: "ACCT_001", Status: http.StatusConflict, Description: "The last admin account cannot be deleted"},
+	{Code: "ACCT_002", Status: http.StatusConflict, Description: "The account is not scheduled for deletion"},
+
+	// Approvals
+	{Code: "APPROVAL_001", Status: http.StatusForbidden, Description: "Only approvers other than the submitter may decide on jobs awaiting approval"},
+	{Code: "APPROVAL_002", Status: http.StatusNotFound, Description: "The job is not awaiting approval"},
+	{Code: "APPROVAL_003", Status: http.StatusConflict, Description: "The job is no longer awaiting approval"},
+
+	// Authentication and authorization
+	{Code: "AUTH_001", Status: http.StatusUnauthorized, Description: "Missing or malformed Authorization header, or invalid login credentials; 409 when registering an email that already has an account"},
+	{Code: "AUTH_002", Status: http.StatusUnauthorized, Description: "Invalid or expired token, refresh token or API key"},
+	{Code: "AUTH_003", Status: http.StatusUnauthorized, Description: "The request is not authenticated"},
+	{Code: "AUTH_004", Status: http.StatusForbidden, Description: "The resource belongs to another user"},
+	{Code: "AUTH_005", Status: http.StatusForbidden, Description: "Admin access required"},
+	{Code: "AUTH_006", Status: http.StatusForbidden, Description: "API keys cannot manage API keys"},
+	{Code: "AUTH_007", Status: http.StatusForbidden, Description: "The API key may not use this label"},
+	{Code: "AUTH_008", Status: http.StatusForbidden, Description: "The caller lacks the permission the route requires"},
+	{Code: "AUTH_009", Status: http.StatusForbidden, Description: "The current password is incorrect"},
+	{Code: "AUTH_010", Status: http.StatusConflict, Description: "Tokens are signed with the shared secret, so there are no signing keys to manage"},
+
+	// Billing
+	{Code: "BILLING_001", Status: http.StatusBadRequest, Description: "The payment webhook signature is invalid"},
+
+	// Branding
+	{Code: "BRAND_001", Status: http.StatusUnprocessableEntity, Description: "The custom domain is not verified"},
+	{Code: "BRAND_002", Status: http.StatusConflict, Description: "The custom domain is already used by another organization"},
+
+	// Import connectors
+	{Code: "CONN_001", Status: http.StatusNotFound, Description: "Import connection not found"},
+	{Code: "CONN_002", Status: http.StatusForbidden, Description: "The account linking attempt is invalid, expired or was refused"},
+	{Code: "CONN_003", Status: http.StatusConflict, Description: "Access to the linked account was revoked or has expired; link it again"},
+
+	// Credits
+	{Code: "CREDIT_001", Status: http.StatusPaymentRequired, Description: "The job costs more credits than are available"},
+
+	// Result delivery
+	{Code: "DLV_001", Status: http.StatusNotFound, Description: "Delivery target not found"},
+	{Code: "DLV_002", Status: http.StatusBadRequest, Description: "The delivery target rejected the test file"},
+
+	// Documents
+	{Code: "DOC_001", Status: http.StatusUnprocessableEntity, Description: "Previews are not available for this document type"},
+
+	// Domain events
+	{Code: "EVT_001", Status: http.StatusBadRequest, Description: "The event replay request is invalid"},
+
+	// Exports
+	{Code: "EXP_001", Status: http.StatusUnprocessableEntity, Description: "Searchable PDFs are not available for this document type"},
+	{Code: "EXP_002", Status: http.StatusNotFound, Description: "Data export not found"},
+	{Code: "EXP_003", Status: http.StatusForbidden, Description: "The download link is invalid or has expired"},
+
+	// Access grants
+	{Code: "GRANT_001", Status: http.StatusNotFound, Description: "Access grant not found"},
+	{Code: "GRANT_002", Status: http.StatusForbidden, Description: "The access grant has expired or was revoked"},
+
+	// No-code integrations
+	{Code: "HOOK_001", Status: http.StatusNotFound, Description: "Hook not found"},
+
+	// Inbound email
+	{Code: "INB_001", Status: http.StatusNotFound, Description: "Inbound email address not found; 406 when mail is sent to an unknown address"},
+	{Code: "INB_002", Status: http.StatusForbidden, Description: "The inbound email signature is invalid"},
+
+	// OCR jobs
+	{Code: "JOB_001", Status: http.StatusInternalServerError, Description: "Failed to submit the OCR job"},
+	{Code: "JOB_002", Status: http.StatusConflict, Description: "The job cannot be cancelled in its current status"},
+	{Code: "JOB_003", Status: http.StatusConflict, Description: "Active jobs cannot be deleted; cancel them first"},
+	{Code: "JOB_004", Status: http.StatusConflict, Description: "The document has pending or processing OCR jobs"},
+	{Code: "JOB_005", Status: http.StatusUnprocessableEntity, Description: "The job violates the organization's OCR policy"},
+	{Code: "JOB_006", Status: http.StatusConflict, Description: "The result has no per-page text; reprocess the whole document instead"},
+	{Code: "JOB_007", Status: http.StatusConflict, Description: "Invalid job status transition"},
+	{Code: "JOB_008", Status: http.StatusConflict, Description: "The document has no result to patch; submit an OCR job first"},
+
+	// Document links
+	{Code: "LINK_001", Status: http.StatusNotFound, Description: "Document link not found"},
+	{Code: "LINK_002", Status: http.StatusConflict, Description: "The documents are already linked with this relation"},
+
+	// Notifications
+	{Code: "NOTIF_001", Status: http.StatusNotFound, Description: "Notification channel not found"},
+	{Code: "NOTIF_002", Status: http.StatusBadRequest, Description: "The webhook rejected the test message"},
+
+	// Organizations
+	{Code: "ORG_001", Status: http.StatusNotFound, Description: "Organization not found"},
+	{Code: "ORG_002", Status: http.StatusNotFound, Description: "Organization member not found"},
+	{Code: "ORG_003", Status: http.StatusNotFound, Description: "Invitation not found"},
+	{Code: "ORG_004", Status: http.StatusForbidden, Description: "The organization role does not allow this"},
+	{Code: "ORG_005", Status: http.StatusConflict, Description: "An organization must keep at least one owner"},
+	{Code: "ORG_006", Status: http.StatusConflict, Description: "The user is already a member of the organization"},
+	{Code: "ORG_007", Status: http.StatusConflict, Description: "The invitation has expired or was already answered"},
+	{Code: "ORG_008", Status: http.StatusConflict, Description: "An invitation to this email is already pending"},
+
+	// Rate limiting
+	{Code: "RATE_001", Status: http.StatusTooManyRequests, Description: "Too many requests; retry after the Retry-After delay"},
+
+	// Usage reports
+	{Code: "REPORT_001", Status: http.StatusNotFound, Description: "Usage report not found"},
+
+	// Resources
+	{Code: "RES_001", Status: http.StatusNotFound, Description: "User not found"},
+	{Code: "RES_002", Status: http.StatusNotFound, Description: "Document not found"},
+	{Code: "RES_003", Status: http.StatusNotFound, Description: "Job not found"},
+	{Code: "RES_004", Status: http.StatusNotFound, Description: "Result not found"},
+	{Code: "RES_005", Status: http.StatusConflict, Description: "A document with the same content already exists"},
+	{Code: "RES_006", Status: http.StatusNotFound, Description: "API key not found"},
+	{Code: "RES_007", Status: http.StatusNotFound, Description: "Error report not found"},
+	{Code: "RES_008", Status: http.StatusNotFound, Description: "Result page not found"},
+
+	// Security
+	{Code: "SEC_001", Status: http.StatusUnprocessableEntity, Description: "The file was rejected because malware was detected"},
+
+	// Setup
+	{Code: "SETUP_001", Status: http.StatusConflict, Description: "Setup has already been completed"},
+
+	// Share links
+	{Code: "SHARE_001", Status: http.StatusForbidden, Description: "The share link is invalid or has expired"},
+	{Code: "SHARE_002", Status: http.StatusForbidden, Description: "The share link was revoked"},
+	{Code: "SHARE_003", Status: http.StatusNotFound, Description: "Share link not found"},
+
+	// Single sign-on
+	{Code: "SSO_001", Status: http.StatusNotFound, Description: "Single sign-on is not configured for this organization"},
+	{Code: "SSO_002", Status: http.StatusForbidden, Description: "The single sign-on attempt is invalid, expired or was refused by the identity provider"},
+	{Code: "SSO_003", Status: http.StatusForbidden, Description: "The identity provider groups do not grant access to this organization"},
+	{Code: "SSO_004", Status: http.StatusConflict, Description: "An account with this email already exists; it must be invited before signing in with SSO"},
+	{Code: "SSO_005", Status: http.StatusForbidden, Description: "The email is not in a domain of this organization"},
+	{Code: "SSO_006", Status: http.StatusConflict, Description: "An email domain is already used by another organization's SSO"},
+	{Code: "SSO_007", Status: http.StatusConflict, Description: "Single sign-on is disabled until an encryption key for client secrets is configured"},
+	{Code: "SSO_008", Status: http.StatusNotFound, Description: "The email domain is not configured for the organization's SSO"},
+	{Code: "SSO_009", Status: http.StatusUnprocessableEntity, Description: "The DNS verification record of the email domain was not found"},
+
+	// System
+	{Code: "SYS_001", Status: http.StatusInternalServerError, Description: "Failed to register the user or issue tokens"},
+	{Code: "SYS_002", Status: http.StatusInternalServerError, Description: "Failed to save or read the uploaded file"},
+	{Code: "SYS_003", Status: http.StatusInternalServerError, Description: "Failed to create the document record"},
+	{Code: "SYS_004", Status: http.StatusInternalServerError, Description: "Failed to list documents"},
+	{Code: "SYS_005", Status: http.StatusInternalServerError, Description: "Failed to delete or replace the document"},
+	{Code: "SYS_006", Status: http.StatusInternalServerError, Description: "Failed to list jobs"},
+	{Code: "SYS_007", Status: http.StatusInternalServerError, Description: "Failed to get the warehouse export status"},
+	{Code: "SYS_008", Status: http.StatusInternalServerError, Description: "Failed to run the warehouse export"},
+	{Code: "SYS_009", Status: http.StatusInternalServerError, Description: "Failed to generate the searchable PDF"},
+	{Code: "SYS_010", Status: http.StatusInternalServerError, Description: "Failed to list domain events"},
+	{Code: "SYS_011", Status: http.StatusInternalServerError, Description: "Failed to get the setup status"},
+	{Code: "SYS_012", Status: http.StatusInternalServerError, Description: "Failed to complete setup"},
+	{Code: "SYS_013", Status: http.StatusServiceUnavailable, Description: "The virus scanner is unavailable; retry later"},
+	{Code: "SYS_014", Status: http.StatusInternalServerError, Description: "Failed to list audit events"},
+	{Code: "SYS_015", Status: http.StatusInternalServerError, Description: "Failed to get settings"},
+	{Code: "SYS_016", Status: http.StatusInternalServerError, Description: "Failed to update settings"},
+	{Code: "SYS_017", Status: http.StatusInternalServerError, Description: "Failed to list API keys"},
+	{Code: "SYS_018", Status: http.StatusServiceUnavailable, Description: "The service is starting; retry shortly"},
+	{Code: "SYS_019", Status: http.StatusInternalServerError, Description: "Failed to sweep stuck jobs"},
+	{Code: "SYS_020", Status: http.StatusInternalServerError, Description: "Failed to get the OCR policy"},
+	{Code: "SYS_021", Status: http.StatusInternalServerError, Description: "Failed to update the OCR policy"},
+	{Code: "SYS_022", Status: http.StatusInternalServerError, Description: "Failed to get queue statistics"},
+	{Code: "SYS_023", Status: http.StatusInternalServerError, Description: "Failed to list jobs for admins"},
+	{Code: "SYS_024", Status: http.StatusInternalServerError, Description: "Failed to requeue jobs"},
+	{Code: "SYS_025", Status: http.StatusInternalServerError, Description: "Failed to cancel jobs"},
+	{Code: "SYS_026", Status: http.StatusInternalServerError, Description: "Failed to run cleanup"},
+	{Code: "SYS_027", Status: http.StatusInternalServerError, Description: "Failed to get branding"},
+	{Code: "SYS_028", Status: http.StatusInternalServerError, Description: "Failed to update branding"},
+	{Code: "SYS_029", Status: http.StatusBadGateway, Description: "Failed to look up the domain verification record"},
+	{Code: "SYS_030", Status: http.StatusInternalServerError, Description: "Failed to list jobs awaiting approval"},
+	{Code: "SYS_031", Status: http.StatusInternalServerError, Description: "Failed to record the approval decision"},
+	{Code: "SYS_032", Status: http.StatusInternalServerError, Description: "Failed to get the job"},
+	{Code: "SYS_033", Status: http.StatusInternalServerError, Description: "Failed to get the result"},
+	{Code: "SYS_034", Status: http.StatusInternalServerError, Description: "Failed to get the document"},
+	{Code: "SYS_035", Status: http.StatusInternalServerError, Description: "Failed to restore the document"},
+	{Code: "SYS_036", Status: http.StatusInternalServerError, Description: "Failed to revoke the API key"},
+	{Code: "SYS_037", Status: http.StatusInternalServerError, Description: "Failed to get job events"},
+	{Code: "SYS_038", Status: http.StatusInternalServerError, Description: "Failed to grant access"},
+	{Code: "SYS_039", Status: http.StatusInternalServerError, Description: "Failed to list access grants"},
+	{Code: "SYS_040", Status: http.StatusInternalServerError, Description: "Failed to revoke the access grant"},
+	{Code: "SYS_041", Status: http.StatusInternalServerError, Description: "Failed to list access grant activity"},
+	{Code: "SYS_042", Status: http.StatusInternalServerError, Description: "Failed to get shared documents"},
+	{Code: "SYS_043", Status: http.StatusInternalServerError, Description: "Failed to stream job events"},
+	{Code: "SYS_044", Status: http.StatusInternalServerError, Description: "Failed to get credit usage"},
+	{Code: "SYS_045", Status: http.StatusInternalServerError, Description: "Failed to get the result breakdown"},
+	{Code: "SYS_046", Status: http.StatusInternalServerError, Description: "Failed to get page usage"},
+	{Code: "SYS_047", Status: http.StatusInternalServerError, Description: "Failed to start checkout"},
+	{Code: "SYS_048", Status: http.StatusInternalServerError, Description: "Failed to process the billing event"},
+	{Code: "SYS_049", Status: http.StatusInternalServerError, Description: "Failed to import document metadata"},
+	{Code: "SYS_050", Status: http.StatusInternalServerError, Description: "Failed to get the error report"},
+	{Code: "SYS_051", Status: http.StatusInternalServerError, Description: "Failed to create the organization"},
+	{Code: "SYS_052", Status: http.StatusInternalServerError, Description: "Failed to list organizations"},
+	{Code: "SYS_053", Status: http.StatusInternalServerError, Description: "Failed to get the organization"},
+	{Code: "SYS_054", Status: http.StatusInternalServerError, Description: "Failed to delete the organization"},
+	{Code: "SYS_055", Status: http.StatusInternalServerError, Description: "Failed to invite the user"},
+	{Code: "SYS_056", Status: http.StatusInternalServerError, Description: "Failed to list invitations"},
+	{Code: "SYS_057", Status: http.StatusInternalServerError, Description: "Failed to answer or revoke the invitation"},
+	{Code: "SYS_058", Status: http.StatusInternalServerError, Description: "Failed to update or remove the organization member"},
+	{Code: "SYS_059", Status: http.StatusInternalServerError, Description: "Failed to share or unshare documents with the organization"},
+	{Code: "SYS_060", Status: http.StatusInternalServerError, Description: "Failed to get organization documents"},
+	{Code: "SYS_061", Status: http.StatusInternalServerError, Description: "Failed to list organization jobs"},
+	{Code: "SYS_062", Status: http.StatusInternalServerError, Description: "Failed to link documents"},
+	{Code: "SYS_063", Status: http.StatusInternalServerError, Description: "Failed to list document links"},
+	{Code: "SYS_064", Status: http.StatusInternalServerError, Description: "Failed to update the document link"},
+	{Code: "SYS_065", Status: http.StatusInternalServerError, Description: "Failed to delete the document link"},
+	{Code: "SYS_066", Status: http.StatusInternalServerError, Description: "Failed to get related documents"},
+	{Code: "SYS_067", Status: http.StatusInternalServerError, Description: "Failed to export related documents"},
+	{Code: "SYS_068", Status: http.StatusInternalServerError, Description: "Failed to create the share link"},
+	{Code: "SYS_069", Status: http.StatusInternalServerError, Description: "Failed to list share links"},
+	{Code: "SYS_070", Status: http.StatusInternalServerError, Description: "Failed to revoke the share link"},
+	{Code: "SYS_071", Status: http.StatusInternalServerError, Description: "Failed to open the share link"},
+	{Code: "SYS_072", Status: http.StatusInternalServerError, Description: "Failed to list usage reports"},
+	{Code: "SYS_073", Status: http.StatusInternalServerError, Description: "Failed to get the usage report"},
+	{Code: "SYS_074", Status: http.StatusInternalServerError, Description: "Failed to generate the usage report"},
+	{Code: "SYS_075", Status: http.StatusInternalServerError, Description: "Failed to start single sign-on"},
+	{Code: "SYS_076", Status: http.StatusInternalServerError, Description: "Failed to complete single sign-on"},
+	{Code: "SYS_077", Status: http.StatusInternalServerError, Description: "Failed to get the SSO configuration"},
+	{Code: "SYS_078", Status: http.StatusInternalServerError, Description: "Failed to configure SSO"},
+	{Code: "SYS_079", Status: http.StatusInternalServerError, Description: "Failed to delete the SSO configuration"},
+	{Code: "SYS_080", Status: http.StatusInternalServerError, Description: "Failed to update the profile"},
+	{Code: "SYS_081", Status: http.StatusInternalServerError, Description: "Failed to delete the account"},
+	{Code: "SYS_082", Status: http.StatusInternalServerError, Description: "Failed to cancel the account deletion"},
+	{Code: "SYS_083", Status: http.StatusInternalServerError, Description: "Failed to export the account"},
+	{Code: "SYS_084", Status: http.StatusInternalServerError, Description: "Failed to request a data export"},
+	{Code: "SYS_085", Status: http.StatusInternalServerError, Description: "Failed to list data exports"},
+	{Code: "SYS_086", Status: http.StatusInternalServerError, Description: "Failed to get the data export"},
+	{Code: "SYS_087", Status: http.StatusInternalServerError, Description: "Failed to download the data export"},
+	{Code: "SYS_088", Status: http.StatusInternalServerError, Description: "Failed to render the preview"},
+	{Code: "SYS_089", Status: http.StatusInternalServerError, Description: "Failed to get result pages"},
+	{Code: "SYS_090", Status: http.StatusInternalServerError, Description: "Failed to get the result page"},
+	{Code: "SYS_091", Status: http.StatusInternalServerError, Description: "Failed to get result entities"},
+	{Code: "SYS_092", Status: http.StatusInternalServerError, Description: "Failed to create the extraction template"},
+	{Code: "SYS_093", Status: http.StatusInternalServerError, Description: "Failed to list extraction templates"},
+	{Code: "SYS_094", Status: http.StatusInternalServerError, Description: "Failed to get the extraction template"},
+	{Code: "SYS_095", Status: http.StatusInternalServerError, Description: "Failed to update the extraction template"},
+	{Code: "SYS_096", Status: http.StatusInternalServerError, Description: "Failed to delete the extraction template"},
+	{Code: "SYS_097", Status: http.StatusInternalServerError, Description: "Failed to get result fields"},
+	{Code: "SYS_098", Status: http.StatusInternalServerError, Description: "Failed to export fields"},
+	{Code: "SYS_099", Status: http.StatusInternalServerError, Description: "Failed to poll results"},
+	{Code: "SYS_100", Status: http.StatusInternalServerError, Description: "Failed to subscribe the hook"},
+	{Code: "SYS_101", Status: http.StatusInternalServerError, Description: "Failed to list hooks"},
+	{Code: "SYS_102", Status: http.StatusInternalServerError, Description: "Failed to unsubscribe the hook"},
+	{Code: "SYS_103", Status: http.StatusInternalServerError, Description: "Failed to start linking the account"},
+	{Code: "SYS_104", Status: http.StatusInternalServerError, Description: "Failed to link the account"},
+	{Code: "SYS_105", Status: http.StatusInternalServerError, Description: "Failed to list import connections"},
+	{Code: "SYS_106", Status: http.StatusInternalServerError, Description: "Failed to list folders"},
+	{Code: "SYS_107", Status: http.StatusInternalServerError, Description: "Failed to update the import connection"},
+	{Code: "SYS_108", Status: http.StatusInternalServerError, Description: "Failed to delete the import connection"},
+	{Code: "SYS_109", Status: http.StatusInternalServerError, Description: "Failed to sync the import connection"},
+	{Code: "SYS_110", Status: http.StatusInternalServerError, Description: "Failed to get the inbound email address"},
+	{Code: "SYS_111", Status: http.StatusInternalServerError, Description: "Failed to rotate the inbound email address"},
+	{Code: "SYS_112", Status: http.StatusInternalServerError, Description: "Failed to receive the email"},
+	{Code: "SYS_113", Status: http.StatusInternalServerError, Description: "Failed to list notification channels"},
+	{Code: "SYS_114", Status: http.StatusInternalServerError, Description: "Failed to create the notification channel"},
+	{Code: "SYS_115", Status: http.StatusInternalServerError, Description: "Failed to update the notification channel"},
+	{Code: "SYS_116", Status: http.StatusInternalServerError, Description: "Failed to delete the notification channel"},
+	{Code: "SYS_117", Status: http.StatusInternalServerError, Description: "Failed to send the test notification"},
+	{Code: "SYS_118", Status: http.StatusInternalServerError, Description: "Failed to list delivery targets"},
+	{Code: "SYS_119", Status: http.StatusInternalServerError, Description: "Failed to create the delivery target"},
+	{Code: "SYS_120", Status: http.StatusInternalServerError, Description: "Failed to update the delivery target"},
+	{Code: "SYS_121", Status: http.StatusInternalServerError, Description: "Failed to delete the delivery target"},
+	{Code: "SYS_122", Status: http.StatusInternalServerError, Description: "Failed to test the delivery target"},
+	{Code: "SYS_123", Status: http.StatusInternalServerError, Description: "Failed to list deliveries"},
+	{Code: "SYS_124", Status: http.StatusInternalServerError, Description: "Failed to list job deliveries"},
+	{Code: "SYS_125", Status: http.StatusInternalServerError, Description: "Failed to get storage statistics"},
+	{Code: "SYS_126", Status: http.StatusInternalServerError, Description: "Failed to get backfill tasks"},
+	{Code: "SYS_127", Status: http.StatusInternalServerError, Description: "Failed to start a backfill task"},
+	{Code: "SYS_128", Status: http.StatusInternalServerError, Description: "Failed to cancel a backfill task"},
+	{Code: "SYS_129", Status: http.StatusInternalServerError, Description: "Failed to list deleted jobs"},
+	{Code: "SYS_130", Status: http.StatusInternalServerError, Description: "Failed to restore the job"},
+	{Code: "SYS_131", Status: http.StatusInternalServerError, Description: "Failed to archive or unarchive the job"},
+	{Code: "SYS_132", Status: http.StatusInternalServerError, Description: "Failed to get retention policies"},
+	{Code: "SYS_133", Status: http.StatusInternalServerError, Description: "Failed to update retention policies"},
+	{Code: "SYS_134", Status: http.StatusInternalServerError, Description: "Failed to get job statistics"},
+	{Code: "SYS_135", Status: http.StatusInternalServerError, Description: "Failed to get system statistics"},
+	{Code: "SYS_136", Status: http.StatusInternalServerError, Description: "Failed to rotate the JWT signing key"},
+	{Code: "SYS_137", Status: http.StatusInternalServerError, Description: "Failed to verify the SSO email domain"},
+
+	// Backfill tasks
+	{Code: "TASK_001", Status: http.StatusNotFound, Description: "Backfill task not found"},
+	{Code: "TASK_002", Status: http.StatusConflict, Description: "The backfill task is already pending or running"},
+	{Code: "TASK_003", Status: http.StatusConflict, Description: "The backfill task is not pending or running"},
+
+	// Extraction templates
+	{Code: "TPL_001", Status: http.StatusNotFound, Description: "Extraction template not found"},
+
+	// Validation
+	{Code: "VAL_001", Status: http.StatusBadRequest, Description: "The request body or a query parameter is invalid"},
+	{Code: "VAL_002", Status: http.StatusBadRequest, Description: "The password is too weak"},
+	{Code: "VAL_003", Status: http.StatusBadRequest, Description: "Invalid multipart form, or the file is too large"},
+	{Code: "VAL_004", Status: http.StatusBadRequest, Description: "No file uploaded"},
+	{Code: "VAL_005", Status: http.StatusBadRequest, Description: "The file exceeds the maximum allowed size"},
+	{Code: "VAL_006", Status: http.StatusBadRequest, Description: "The file type is not allowed"},
+	{Code: "VAL_007", Status: http.StatusBadRequest, Description: "Invalid document ID"},
+	{Code: "VAL_008", Status: http.StatusBadRequest, Description: "Invalid job ID"},
+	{Code: "VAL_009", Status: http.StatusBadRequest, Description: "Invalid result ID"},
+	{Code: "VAL_010", Status: http.StatusBadRequest, Description: "The page must be a positive integer"},
+	{Code: "VAL_011", Status: http.StatusBadRequest, Description: "Unsupported export format"},
+	{Code: "VAL_012", Status: http.StatusBadRequest, Description: "The OCR service URL must be an absolute http or https URL"},
+	{Code: "VAL_013", Status: http.StatusBadRequest, Description: "The file content does not match its type"},
+	{Code: "VAL_014", Status: http.StatusBadRequest, Description: "Encrypted PDFs are not supported"},
+	{Code: "VAL_015", Status: http.StatusBadRequest, Description: "The PDF is corrupt"},
+	{Code: "VAL_016", Status: http.StatusBadRequest, Description: "Invalid checksum; expected a hex-encoded SHA-256 digest"},
+	{Code: "VAL_017", Status: http.StatusUnprocessableEntity, Description: "The uploaded file does not match the supplied SHA-256 digest"},
+	{Code: "VAL_018", Status: http.StatusBadRequest, Description: "Invalid duplicate policy"},
+	{Code: "VAL_019", Status: http.StatusBadRequest, Description: "Invalid API key ID"},
+	{Code: "VAL_020", Status: http.StatusBadRequest, Description: "Invalid time zone"},
+	{Code: "VAL_021", Status: http.StatusBadRequest, Description: "Invalid or out-of-range page number"},
+	{Code: "VAL_022", Status: http.StatusBadRequest, Description: "No custom domain is configured"},
+	{Code: "VAL_023", Status: http.StatusBadRequest, Description: "Invalid labels"},
+	{Code: "VAL_024", Status: http.StatusBadRequest, Description: "Access cannot be granted to yourself"},
+	{Code: "VAL_025", Status: http.StatusBadRequest, Description: "Invalid access grant ID"},
+	{Code: "VAL_026", Status: http.StatusBadRequest, Description: "Invalid last event ID"},
+	{Code: "VAL_027", Status: http.StatusBadRequest, Description: "Unknown credit pack"},
+	{Code: "VAL_028", Status: http.StatusBadRequest, Description: "Invalid metadata CSV"},
+	{Code: "VAL_029", Status: http.StatusBadRequest, Description: "Invalid organization ID"},
+	{Code: "VAL_030", Status: http.StatusBadRequest, Description: "Invalid invitation ID"},
+	{Code: "VAL_031", Status: http.StatusBadRequest, Description: "Invalid user ID"},
+	{Code: "VAL_032", Status: http.StatusBadRequest, Description: "A document cannot be linked to itself"},
+	{Code: "VAL_033", Status: http.StatusBadRequest, Description: "Invalid document link ID"},
+	{Code: "VAL_034", Status: http.StatusBadRequest, Description: "The share link lasts longer than allowed"},
+	{Code: "VAL_035", Status: http.StatusBadRequest, Description: "Invalid share link ID"},
+	{Code: "VAL_036", Status: http.StatusBadRequest, Description: "Usage reports can only be generated for months that are over"},
+	{Code: "VAL_037", Status: http.StatusBadRequest, Description: "Invalid usage report ID"},
+	{Code: "VAL_038", Status: http.StatusBadRequest, Description: "A client secret is required to configure SSO"},
+	{Code: "VAL_039", Status: http.StatusBadRequest, Description: "An organization ID or email is required"},
+	{Code: "VAL_040", Status: http.StatusBadRequest, Description: "The current password is required to change the email"},
+	{Code: "VAL_041", Status: http.StatusBadRequest, Description: "Invalid data export ID"},
+	{Code: "VAL_042", Status: http.StatusBadRequest, Description: "Invalid preview size"},
+	{Code: "VAL_043", Status: http.StatusBadRequest, Description: "The version must be a positive integer"},
+	{Code: "VAL_044", Status: http.StatusBadRequest, Description: "Regions can only be reprocessed on JPEG, PNG and GIF documents"},
+	{Code: "VAL_045", Status: http.StatusBadRequest, Description: "The region is outside the page"},
+	{Code: "VAL_046", Status: http.StatusBadRequest, Description: "Invalid region"},
+	{Code: "VAL_047", Status: http.StatusBadRequest, Description: "Invalid entity type"},
+	{Code: "VAL_048", Status: http.StatusBadRequest, Description: "Invalid extraction template"},
+	{Code: "VAL_049", Status: http.StatusBadRequest, Description: "Invalid extraction template ID"},
+	{Code: "VAL_050", Status: http.StatusBadRequest, Description: "Invalid fields export filter"},
+	{Code: "VAL_051", Status: http.StatusBadRequest, Description: "Invalid cursor"},
+	{Code: "VAL_052", Status: http.StatusBadRequest, Description: "The hook target URL must be an http or https URL"},
+	{Code: "VAL_053", Status: http.StatusBadRequest, Description: "Invalid hook ID"},
+	{Code: "VAL_054", Status: http.StatusBadRequest, Description: "The storage provider is not available"},
+	{Code: "VAL_055", Status: http.StatusBadRequest, Description: "Invalid import connection settings"},
+	{Code: "VAL_056", Status: http.StatusBadRequest, Description: "Invalid import connection ID"},
+	{Code: "VAL_057", Status: http.StatusBadRequest, Description: "Invalid notification channel ID"},
+	{Code: "VAL_058", Status: http.StatusBadRequest, Description: "Invalid delivery target ID"},
+	{Code: "VAL_059", Status: http.StatusBadRequest, Description: "Invalid delivery target settings"},
+	{Code: "VAL_060", Status: http.StatusRequestEntityTooLarge, Description: "Request body exceeds the size limit of the route"},
+}
+
+// Catalog returns every error code, sorted by code
+func Catalog() []CatalogEntry {
+	entries := make([]CatalogEntry, len(catalog))
+	for i, entry := range catalog {
+		entry.Category = categoryOf(entry.Code)
+		entries[i] = entry
+	}
+	slices.SortFunc(entries, func(a, b CatalogEntry) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return entries
+}
+
+// Lookup returns the catalog entry of a code
+func Lookup(code string) (CatalogEntry, bool) {
+	for _, entry := range catalog {
+		if entry.Code == code {
+			entry.Category = categoryOf(entry.Code)
+			return entry, true
+		}
+	}
+	return CatalogEntry{}, false
+}
+
+// categoryOf returns the category of a code from its prefix
+func categoryOf(code string) string {
+	prefix, _, _ := strings.Cut(code, "_")
+	return categories[prefix]
+}
@@ -0,0 +1,216 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/events"
+)
+
+// DefaultStripeAPIURL is the base URL of the Stripe API
+const DefaultStripeAPIURL = "https://api.stripe.com"
+
+// signatureTolerance is how old a webhook event's signature may be, so
+// captured events cannot be replayed later
+const signatureTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned for webhook events whose Stripe-Signature
+// header is missing, stale or does not match the payload
+var ErrInvalidSignature = errors.New("invalid Stripe signature")
+
+// Stripe talks to the Stripe API over its form-encoded REST interface and
+// verifies the signatures of the webhook events it sends
+type Stripe struct {
+	apiURL        string
+	secretKey     string
+	webhookSecret string
+	httpClient    *http.Client
+}
+
+// NewStripe creates a new Stripe client. An empty apiURL uses the Stripe API.
+func NewStripe(apiURL, secretKey, webhookSecret string, timeout time.Duration) *Stripe {
+	if apiURL == "" {
+		apiURL = DefaultStripeAPIURL
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Stripe{
+		apiURL:        strings.TrimSuffix(apiURL, "/"),
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		httpClient:    &http.Client{Timeout: timeout},
+	}
+}
+
+// CheckoutParams describes a one-off payment for a single product
+type CheckoutParams struct {
+	ProductName string
+	// UnitAmount is the price in the currency's smallest unit, such as cents
+	UnitAmount        int
+	Currency          string
+	CustomerEmail     string
+	ClientReferenceID string
+	SuccessURL        string
+	CancelURL         string
+	Metadata          map[string]string
+	// IdempotencyKey makes retried requests return the same session
+	IdempotencyKey string
+}
+
+// CheckoutSession is the part of a Stripe checkout session the billing
+// service uses
+type CheckoutSession struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	Status            string            `json:"status"`
+	PaymentStatus     string            `json:"payment_status"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	AmountTotal       int               `json:"amount_total"`
+	Currency          string            `json:"currency"`
+	Metadata          map[string]string `json:"metadata"`
+	ExpiresAt         int64             `json:"expires_at"`
+}
+
+// Event is a Stripe webhook event. Data.Object holds the object the event is
+// about, whose type depends on Type.
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// APIError is an error response of the Stripe API
+type APIError struct {
+	StatusCode int
+	Type       string `json:"type"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("stripe returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// CreateCheckoutSession creates a hosted checkout page for a payment
+func (s *Stripe) CreateCheckoutSession(ctx context.Context, params CheckoutParams) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", params.SuccessURL)
+	form.Set("cancel_url", params.CancelURL)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", params.Currency)
+	form.Set("line_items[0][price_data][unit_amount]", strconv.Itoa(params.UnitAmount))
+	form.Set("line_items[0][price_data][product_data][name]", params.ProductName)
+	if params.CustomerEmail != "" {
+		form.Set("customer_email", params.CustomerEmail)
+	}
+	if params.ClientReferenceID != "" {
+		form.Set("client_reference_id", params.ClientReferenceID)
+	}
+	for key, value := range params.Metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+
+	var session CheckoutSession
+	if err := s.post(ctx, "/v1/checkout/sessions", form, params.IdempotencyKey, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// post sends a form-encoded API request and decodes the response into out
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.secretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errResp struct {
+			Error APIError `json:"error"`
+		}
+		_ = json.Unmarshal(body, &errResp)
+		errResp.Error.StatusCode = resp.StatusCode
+		return &errResp.Error
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// ParseEvent verifies a webhook payload against its Stripe-Signature header
+// and decodes it. The header carries a timestamp t and one or more v1
+// signatures, each an HMAC-SHA256 of "<t>.<payload>" with the endpoint's
+// signing secret.
+func (s *Stripe) ParseEvent(payload []byte, signatureHeader string, now time.Time) (*Event, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return nil, ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(signedAt, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return nil, ErrInvalidSignature
+	}
+
+	expected := []byte(events.Sign(s.webhookSecret, timestamp, payload))
+	valid := false
+	for _, signature := range signatures {
+		if hmac.Equal(expected, []byte(signature)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse event: %w", err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,47 @@
+package classifier
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+)
+
+// Result is the type a classifier detected for a document
+type Result struct {
+	Type       models.DocumentType
+	Confidence float64
+}
+
+// Classifier detects the type of a document from its recognized text
+type Classifier interface {
+	Name() string
+	Classify(ctx context.Context, text string) (*Result, error)
+}
+
+// Config holds the document classifier settings
+type Config struct {
+	Driver  string // none, rules, remote
+	URL     string
+	Token   string
+	Timeout time.Duration
+}
+
+// NewClassifier creates the configured classifier.
+// It returns nil when classification is disabled.
+func NewClassifier(cfg Config) (Classifier, error) {
+	switch cfg.Driver {
+	case "", "none":
+		return nil, nil
+	case "rules":
+		return NewRulesClassifier(), nil
+	case "remote":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("remote classifier requires a URL")
+		}
+		return NewRemoteClassifier(cfg.URL, cfg.Token, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown classifier driver: %s", cfg.Driver)
+	}
+}
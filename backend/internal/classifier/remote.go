@@ -0,0 +1,77 @@
+package classifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"visekai/backend/internal/models"
+)
+
+// RemoteClassifier sends text to an HTTP endpoint, such as a model server, as
+// {"text": "..."} and reads back {"type": "invoice", "confidence": 0.93}.
+// Types it does not know are recorded as other.
+type RemoteClassifier struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewRemoteClassifier creates a new remote classifier
+func NewRemoteClassifier(url, token string, timeout time.Duration) *RemoteClassifier {
+	return &RemoteClassifier{
+		url:        url,
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name identifies the classifier on the classifications it makes
+func (c *RemoteClassifier) Name() string {
+	return "remote"
+}
+
+// Classify asks the remote endpoint for the type of the document
+func (c *RemoteClassifier) Classify(ctx context.Context, text string) (*Result, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach classifier: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("classifier returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Type       models.DocumentType `json:"type"`
+		Confidence float64             `json:"confidence"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode classifier response: %w", err)
+	}
+
+	if !result.Type.Valid() {
+		result.Type = models.DocumentTypeOther
+	}
+	return &Result{Type: result.Type, Confidence: min(max(result.Confidence, 0), 1)}, nil
+}
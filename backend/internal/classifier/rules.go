@@ -0,0 +1,92 @@
+package classifier
+
+import (
+	"context"
+	"strings"
+
+	"visekai/backend/internal/models"
+)
+
+// rulesThreshold is the score a type needs before the rules classifier picks it
+// over other
+const rulesThreshold = 3
+
+// rule is a phrase hinting at a document type, and how strongly
+type rule struct {
+	phrase string
+	weight int
+}
+
+// rules lists the phrases, in lower case, that hint at each document type. Each
+// phrase counts once however often it appears.
+var rules = map[models.DocumentType][]rule{
+	models.DocumentTypeInvoice: {
+		{"invoice", 3}, {"rechnung", 3}, {"facture", 3}, {"factura", 3},
+		{"bill to", 2}, {"amount due", 2}, {"due date", 2}, {"payment terms", 2},
+		{"vat", 1}, {"iban", 1}, {"subtotal", 1},
+	},
+	models.DocumentTypeReceipt: {
+		{"receipt", 3}, {"kassenbon", 3}, {"quittung", 3}, {"ticket de caisse", 3},
+		{"cash", 1}, {"change", 1}, {"tendered", 2}, {"thank you", 1},
+		{"card", 1}, {"total", 1}, {"cashier", 2},
+	},
+	models.DocumentTypeContract: {
+		{"agreement", 3}, {"contract", 3}, {"vertrag", 3}, {"contrat", 3},
+		{"hereinafter", 3}, {"whereas", 3}, {"parties", 2}, {"witness", 2},
+		{"shall", 1}, {"terms and conditions", 1}, {"signature", 1},
+	},
+	models.DocumentTypeID: {
+		{"passport", 3}, {"identity card", 3}, {"personalausweis", 3}, {"carte d'identité", 3},
+		{"driving licence", 3}, {"driver license", 3}, {"<<<", 3},
+		{"date of birth", 2}, {"place of birth", 2}, {"nationality", 2},
+		{"surname", 1}, {"given names", 1}, {"date of expiry", 1},
+	},
+}
+
+// RulesClassifier detects document types from weighted phrases. It needs no
+// setup and suits common documents; a remote classifier does better on others.
+type RulesClassifier struct{}
+
+// NewRulesClassifier creates a new rules classifier
+func NewRulesClassifier() *RulesClassifier {
+	return &RulesClassifier{}
+}
+
+// Name identifies the classifier on the classifications it makes
+func (c *RulesClassifier) Name() string {
+	return "rules"
+}
+
+// Classify scores each type by the phrases found in text. The best type wins
+// once it reaches the threshold, with its share of all scores as confidence;
+// otherwise the document is other, less surely the closer the best came.
+func (c *RulesClassifier) Classify(ctx context.Context, text string) (*Result, error) {
+	lower := strings.ToLower(text)
+
+	best, bestScore, total := models.DocumentTypeOther, 0, 0
+	for documentType, phrases := range rules {
+		score := 0
+		for _, r := range phrases {
+			if strings.Contains(lower, r.phrase) {
+				score += r.weight
+			}
+		}
+		total += score
+		// Break ties by name so the outcome does not depend on map order
+		if score > bestScore || (score == bestScore && score > 0 && documentType < best) {
+			best, bestScore = documentType, score
+		}
+	}
+
+	if bestScore < rulesThreshold {
+		return &Result{
+			Type:       models.DocumentTypeOther,
+			Confidence: 1 - float64(bestScore)/rulesThreshold,
+		}, nil
+	}
+
+	return &Result{
+		Type:       best,
+		Confidence: float64(bestScore) / float64(total),
+	}, nil
+}
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
-
-	"github.com/joho/godotenv"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -13,6 +15,78 @@ type Config struct {
 	GinMode  string
 	LogLevel string
 
+	// Log format and outputs (stdout, file, syslog, loki)
+	LogFormat         string
+	LogOutputs        []string
+	LogFilePath       string
+	LogFileMaxSizeMB  int
+	LogFileMaxBackups int
+	LogFileMaxAge     time.Duration
+	LogSyslogNetwork  string
+	LogSyslogAddress  string
+	LogSyslogTag      string
+	LogLokiURL        string
+	LogLokiLabels     map[string]string
+	LogLokiTenantID   string
+	LogLokiUsername   string
+	LogLokiPassword   string
+	LogLokiBatchWait  time.Duration
+
+	// Request log bodies of error responses
+	LogErrorBodies       bool
+	LogBodyMaxBytes      int
+	LogBodySamplePercent int
+	LogRedactFields      []string
+
+	// CORS
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+
+	// ProblemTypeBaseURL prefixes error codes to form the type URI of
+	// application/problem+json error responses
+	ProblemTypeBaseURL string
+
+	// Deprecation of the v1 API: when it was deprecated (unset while it is
+	// not), when it stops being served, and where the deprecation is
+	// documented
+	APIV1DeprecatedAt   time.Time
+	APIV1SunsetAt       time.Time
+	APIV1DeprecationURL string
+
+	// Native TLS, for deployments without a reverse proxy: a certificate and
+	// key file, read again when they change, or certificates obtained from
+	// Let's Encrypt (or TLSAutocertDirectoryURL) for TLSAutocertDomains
+	TLSCertFile             string
+	TLSKeyFile              string
+	TLSAutocertDomains      []string
+	TLSAutocertEmail        string
+	TLSAutocertCacheDir     string
+	TLSAutocertDirectoryURL string
+	// TLSRedirectPort serves plain HTTP redirecting to HTTPS and answering
+	// ACME challenges; empty disables it
+	TLSRedirectPort string
+	// HTTP2Enabled negotiates HTTP/2 with TLS clients
+	HTTP2Enabled bool
+	// HTTPReadHeaderTimeout bounds the time clients take to send request
+	// headers, so slow clients cannot hold connections open
+	HTTPReadHeaderTimeout time.Duration
+	// MaxBodySize bounds request bodies of routes not taking files; uploads
+	// are bounded by MaxFileSize instead
+	MaxBodySize int
+	// TrustedProxies lists the addresses and CIDR ranges of the reverse
+	// proxies whose X-Forwarded-For and X-Real-IP headers give the client IP;
+	// with none, the client IP is the connection's peer address
+	TrustedProxies []string
+
+	// gRPC API for internal service consumers, served on its own port over
+	// TLS with the API's certificate when TLS is configured
+	GRPCEnabled bool
+	GRPCPort    string
+
 	// Database
 	DBHost     string
 	DBPort     string
@@ -20,11 +94,42 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBSSLMode  string
+	// DBAutoMigrate applies pending schema migrations at startup
+	DBAutoMigrate bool
+	// Connection pool tuning, applied to the primary and the replica
+	DBMaxConns          int
+	DBMinConns          int
+	DBMaxConnLifetime   time.Duration
+	DBMaxConnIdleTime   time.Duration
+	DBHealthCheckPeriod time.Duration
+	// DBStatementTimeout aborts statements running longer; zero disables it
+	DBStatementTimeout time.Duration
+	// DBReplicaDSN is an optional read replica serving list queries
+	DBReplicaDSN string
 
 	// JWT
 	JWTSecret          string
 	JWTExpiry          string
 	RefreshTokenExpiry string
+	// JWTAlgorithm signs tokens with JWTSecret (HS256) or with a ring of
+	// generated keys published as JWKS (RS256, EdDSA)
+	JWTAlgorithm string
+	// JWTKeyRotationInterval rotates the signing key once it is that old;
+	// zero rotates only on demand
+	JWTKeyRotationInterval time.Duration
+	// JWTKeyEncryptionKey seals the stored signing keys (32 bytes, base64),
+	// required with signing keys and independent of JWTSecret
+	JWTKeyEncryptionKey string
+
+	// Secrets read from Vault at startup (VaultAddr, VaultToken) instead of
+	// the environment. VaultNamespace selects a Vault Enterprise namespace.
+	VaultNamespace string
+	// VaultJWTSecretPath is a KV secret holding the JWT secret as jwt_secret,
+	// e.g. secret/data/visekai
+	VaultJWTSecretPath string
+	// VaultDBCredsPath issues leased database credentials, e.g.
+	// database/creds/visekai, renewed while the server runs
+	VaultDBCredsPath string
 
 	// Redis
 	RedisURL      string
@@ -32,73 +137,662 @@ type Config struct {
 
 	// OCR Service
 	OCRServiceURL string
+	OCRWorkers    int
+	OCRQueueSize  int
+	// OCRBreakerThreshold consecutive failures open the circuit breaker in
+	// front of the OCR service (0 disables it); it probes the service after
+	// OCRBreakerCooldown, doubled after each failed probe up to
+	// OCRBreakerMaxCooldown
+	OCRBreakerThreshold   int
+	OCRBreakerCooldown    time.Duration
+	OCRBreakerMaxCooldown time.Duration
+	// OCR requests time out after OCRTimeout plus OCRTimeoutPerPage per page
+	// and OCRTimeoutPerMB per megabyte, at most OCRMaxTimeout, and failed
+	// attempts are retried OCRRetries times after OCRRetryBackoff, doubled
+	// every attempt
+	OCRTimeout        time.Duration
+	OCRTimeoutPerPage time.Duration
+	OCRTimeoutPerMB   time.Duration
+	OCRMaxTimeout     time.Duration
+	OCRRetries        int
+	OCRRetryBackoff   time.Duration
+	// Idle connections kept open to the OCR service
+	OCRMaxIdleConns        int
+	OCRMaxIdleConnsPerHost int
+	OCRIdleConnTimeout     time.Duration
+	// TLS of an https OCR service: trusted CA file, server name to verify, or
+	// no verification at all
+	OCRTLSCAFile             string
+	OCRTLSServerName         string
+	OCRTLSInsecureSkipVerify bool
+	// Client certificate presented to an OCR service requiring mutual TLS,
+	// reloaded when the files change
+	OCRTLSCertFile string
+	OCRTLSKeyFile  string
 
-	// Storage
-	StoragePath       string
-	MaxFileSize       int64
-	AllowedExtensions []string
+	// Storage; content-addressed storage keeps one file per distinct content
+	StoragePath             string
+	StorageContentAddressed bool
+	MaxFileSize             int64
+	AllowedExtensions       []string
 
-	// Rate Limiting
+	// Rate Limiting (requests per window; the general limit applies per user
+	// to all authenticated routes)
+	RateLimitBackend  string
 	RateLimitRequests int
-	RateLimitWindow   string
+	RateLimitWindow   time.Duration
+	RateLimitAuth     RateLimit
+	RateLimitUpload   RateLimit
+	RateLimitOCR      RateLimit
+
+	// Response caching of endpoints shared by all callers (none, memory or redis)
+	ResponseCacheBackend string
+	ResponseCacheTTL     time.Duration
 
 	// Features
 	EnableRegistration      bool
 	EnableEmailVerification bool
 	EnableAPIKeys           bool
+	EnableProfiling         bool
+	EnableMetrics           bool
+
+	// Warehouse export of job metadata as ndjson or parquet, to local storage or
+	// an S3 bucket. Jobs finished within WarehouseExportLag are left for the
+	// next run so that transactions still committing are not skipped.
+	WarehouseExportEnabled     bool
+	WarehouseExportInterval    time.Duration
+	WarehouseExportFormat      string
+	WarehouseExportBatchSize   int
+	WarehouseExportLag         time.Duration
+	WarehouseExportDestination string
+	WarehouseS3Endpoint        string
+	WarehouseS3Region          string
+	WarehouseS3Bucket          string
+	WarehouseS3Prefix          string
+	WarehouseS3AccessKeyID     string
+	WarehouseS3SecretKey       string
+
+	// Event bus
+	EventBusDriver        string
+	EventBusURL           string
+	EventBusSubjectPrefix string
+	EventRelayInterval    time.Duration
+	WebhookURL            string
+	WebhookSecret         string
+
+	// Encryption at rest
+	StorageEncryption      string
+	StorageEncryptionKeys  string
+	StorageEncryptionKeyID string
+	VaultAddr              string
+	VaultToken             string
+	VaultTransitKey        string
+	VaultTimeout           time.Duration
+
+	// Antivirus
+	AntivirusDriver   string
+	AntivirusAddress  string
+	AntivirusTimeout  time.Duration
+	AntivirusFailOpen bool
+
+	// Entity extraction
+	EntityProviders     []string
+	EntityTypes         []string
+	EntityRemoteURL     string
+	EntityRemoteToken   string
+	EntityRemoteTimeout time.Duration
+
+	// Document classification
+	ClassifierDriver  string
+	ClassifierURL     string
+	ClassifierToken   string
+	ClassifierTimeout time.Duration
+
+	// Janitor
+	JanitorInterval      time.Duration
+	TrashRetention       time.Duration
+	JobTrashRetention    time.Duration
+	OrphanFileGrace      time.Duration
+	AccountDeletionGrace time.Duration
+
+	// Retention policies
+	RetentionInterval      time.Duration
+	RetentionWarningPeriod time.Duration
+
+	// Pending job expiry
+	PendingJobLifetime      time.Duration
+	PendingJobCheckInterval time.Duration
+
+	// Stuck job reaper
+	StuckJobTimeout       time.Duration
+	StuckJobCheckInterval time.Duration
+
+	// Access grant expiry reminders
+	AccessGrantReminderLead  time.Duration
+	AccessGrantCheckInterval time.Duration
+
+	// Alerting
+	AlertWebhookURL         string
+	AlertWebhookSecret      string
+	AlertSlackWebhookURL    string
+	AlertEmailTo            []string
+	SMTPHost                string
+	SMTPPort                int
+	SMTPUsername            string
+	SMTPPassword            string
+	SMTPFrom                string
+	AlertCheckInterval      time.Duration
+	AlertCooldown           time.Duration
+	AlertStorageFreePercent int
+	AlertQueueWaitSLO       time.Duration
+	AlertFailureThreshold   int
+	AlertCanaryEnabled      bool
+
+	// Credits
+	CreditsEnforced         bool
+	CreditsMonthlyAllotment int
+	CreditsPerPage          map[string]int
+
+	// Billing
+	BillingEnabled      bool
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	StripeAPIURL        string
+	BillingCurrency     string
+	BillingCreditPacks  map[string]int
+	BillingSuccessURL   string
+	BillingCancelURL    string
+
+	// Share links and data export downloads are signed with ShareLinkSecret,
+	// which must differ from JWTSecret
+	ShareLinkSecret string
+	ShareLinkMaxTTL time.Duration
+
+	// Data exports of users' documents and results
+	DataExportTTL time.Duration
+
+	// Single sign-on through organizations' identity providers, whose client
+	// secrets are sealed with SSOEncryptionKey (32 bytes, base64); SSO is
+	// disabled without it
+	SSOCallbackURL   string
+	SSOSuccessURL    string
+	SSOEncryptionKey string
+
+	// Usage reports
+	UsageReportsEnabled bool
+	UsageReportInterval time.Duration
+
+	// Storage stats for the admin dashboard
+	StorageStatsInterval time.Duration
+
+	// Backfill tasks run by admins over existing data
+	BackfillBatchSize int
+
+	// REST hooks of no-code tools
+	IntegrationHookInterval     time.Duration
+	IntegrationHookTimeout      time.Duration
+	IntegrationHookAllowPrivate bool
+
+	// Slack and Teams job notifications
+	NotificationInterval     time.Duration
+	NotificationTimeout      time.Duration
+	NotificationAllowPrivate bool
+	NotificationDocumentURL  string
+
+	// Failure emails and daily digests
+	EmailNotificationInterval time.Duration
+
+	// Result delivery to S3 buckets and SFTP servers, whose credentials are
+	// sealed with DeliveryEncryptionKey (32 bytes, base64), required when enabled
+	DeliveryEnabled       bool
+	DeliveryEncryptionKey string
+	DeliveryInterval      time.Duration
+	DeliveryTimeout       time.Duration
+	DeliveryMaxAttempts   int
+	DeliveryAllowPrivate  bool
+
+	// Google Drive and Dropbox import connectors
+	ConnectorGoogleClientID     string
+	ConnectorGoogleClientSecret string
+	ConnectorDropboxAppKey      string
+	ConnectorDropboxAppSecret   string
+	ConnectorCallbackURL        string
+	ConnectorSuccessURL         string
+	ConnectorSyncInterval       time.Duration
+	ConnectorTimeout            time.Duration
+
+	// Email-to-OCR ingestion
+	InboundEmailDomain         string
+	InboundEmailSigningKey     string
+	InboundEmailDocumentURL    string
+	InboundEmailMaxAttachments int
+
+	// Startup
+	StartupMaxWait      time.Duration
+	StartupDegradedMode bool
+}
+
+// RateLimit is a request quota per window. A zero quota disables the limit.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
 }
 
+// Load reads the configuration from the environment and the .env file, if
+// there is one, whose values do not override the environment's. Every value
+// that cannot be parsed or is out of range is reported, in one error. Called
+// again, it reads the .env file again, to reload the configuration.
 func Load() (*Config, error) {
-	// Load .env file if it exists
-	_ = godotenv.Load()
+	loadDotenv()
 
+	l := &loader{}
 	cfg := &Config{
-		Port:                    getEnv("PORT", "8080"),
-		GinMode:                 getEnv("GIN_MODE", "debug"),
-		LogLevel:                getEnv("LOG_LEVEL", "info"),
-		DBHost:                  getEnv("DB_HOST", "localhost"),
-		DBPort:                  getEnv("DB_PORT", "5432"),
-		DBName:                  getEnv("POSTGRES_DB", "ocr_db"),
-		DBUser:                  getEnv("POSTGRES_USER", "ocr_user"),
-		DBPassword:              getEnv("POSTGRES_PASSWORD", ""),
-		DBSSLMode:               getEnv("DB_SSLMODE", "disable"),
-		JWTSecret:               getEnv("JWT_SECRET", ""),
-		JWTExpiry:               getEnv("JWT_EXPIRY", "24h"),
-		RefreshTokenExpiry:      getEnv("REFRESH_TOKEN_EXPIRY", "168h"),
-		RedisURL:                getEnv("REDIS_URL", "redis://localhost:6379"),
-		RedisPassword:           getEnv("REDIS_PASSWORD", ""),
-		OCRServiceURL:           getEnv("OCR_SERVICE_URL", "http://localhost:8000"),
-		StoragePath:             getEnv("STORAGE_PATH", "./storage"),
-		MaxFileSize:             52428800, // 50MB default
-		EnableRegistration:      getEnvBool("ENABLE_REGISTRATION", true),
-		EnableEmailVerification: getEnvBool("ENABLE_EMAIL_VERIFICATION", false),
-		EnableAPIKeys:           getEnvBool("ENABLE_API_KEYS", true),
-	}
-
-	// Validate required fields
-	if cfg.JWTSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET is required")
-	}
-
-	if cfg.DBPassword == "" {
-		return nil, fmt.Errorf("POSTGRES_PASSWORD is required")
+		Port:                        l.getEnv("PORT", "8080"),
+		GinMode:                     l.getEnv("GIN_MODE", "debug"),
+		LogLevel:                    l.getEnv("LOG_LEVEL", "info"),
+		LogFormat:                   l.getEnv("LOG_FORMAT", "json"),
+		LogOutputs:                  l.getEnvList("LOG_OUTPUTS", []string{"stdout"}),
+		LogFilePath:                 l.getEnv("LOG_FILE_PATH", "./logs/backend.log"),
+		LogFileMaxSizeMB:            l.getEnvInt("LOG_FILE_MAX_SIZE_MB", 100),
+		LogFileMaxBackups:           l.getEnvInt("LOG_FILE_MAX_BACKUPS", 7),
+		LogFileMaxAge:               l.getEnvDuration("LOG_FILE_MAX_AGE", 30*24*time.Hour),
+		LogSyslogNetwork:            l.getEnv("LOG_SYSLOG_NETWORK", ""),
+		LogSyslogAddress:            l.getEnv("LOG_SYSLOG_ADDRESS", ""),
+		LogSyslogTag:                l.getEnv("LOG_SYSLOG_TAG", "visekai-backend"),
+		LogLokiURL:                  l.getEnv("LOG_LOKI_URL", ""),
+		LogLokiLabels:               l.getEnvStringMap("LOG_LOKI_LABELS", map[string]string{"service": "visekai-backend"}),
+		LogLokiTenantID:             l.getEnv("LOG_LOKI_TENANT_ID", ""),
+		LogLokiUsername:             l.getEnv("LOG_LOKI_USERNAME", ""),
+		LogLokiPassword:             l.getEnv("LOG_LOKI_PASSWORD", ""),
+		LogLokiBatchWait:            l.getEnvDuration("LOG_LOKI_BATCH_WAIT", time.Second),
+		LogErrorBodies:              l.getEnvBool("LOG_ERROR_BODIES", false),
+		LogBodyMaxBytes:             l.getEnvInt("LOG_BODY_MAX_BYTES", 4096),
+		LogBodySamplePercent:        l.getEnvInt("LOG_BODY_SAMPLE_PERCENT", 100),
+		LogRedactFields:             l.getEnvList("LOG_REDACT_FIELDS", nil),
+		CORSAllowedOrigins:          l.getEnvList("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+		CORSAllowedMethods:          l.getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders:          l.getEnvList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "Accept", "Cache-Control", "X-Requested-With", "X-Request-ID", "X-Content-SHA256", "X-API-Key", "If-None-Match", "If-Modified-Since"}),
+		CORSExposedHeaders:          l.getEnvList("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "Content-Disposition", "X-RateLimit-Limit", "X-RateLimit-Remaining", "Retry-After", "ETag", "Last-Modified", "Deprecation", "Sunset", "Link", "API-Version"}),
+		CORSAllowCredentials:        l.getEnvBool("CORS_ALLOW_CREDENTIALS", true),
+		CORSMaxAge:                  l.getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+		ProblemTypeBaseURL:          l.getEnv("PROBLEM_TYPE_BASE_URL", "urn:visekai:error:"),
+		APIV1DeprecationURL:         l.getEnv("API_V1_DEPRECATION_URL", ""),
+		TLSCertFile:                 l.getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:                  l.getEnv("TLS_KEY_FILE", ""),
+		TLSAutocertDomains:          l.getEnvList("TLS_AUTOCERT_DOMAINS", nil),
+		TLSAutocertEmail:            l.getEnv("TLS_AUTOCERT_EMAIL", ""),
+		TLSAutocertCacheDir:         l.getEnv("TLS_AUTOCERT_CACHE_DIR", "./storage/autocert"),
+		TLSAutocertDirectoryURL:     l.getEnv("TLS_AUTOCERT_DIRECTORY_URL", ""),
+		TLSRedirectPort:             l.getEnv("TLS_REDIRECT_PORT", ""),
+		HTTP2Enabled:                l.getEnvBool("HTTP2_ENABLED", true),
+		HTTPReadHeaderTimeout:       l.getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+		MaxBodySize:                 l.getEnvInt("MAX_BODY_SIZE", 1<<20),
+		TrustedProxies:              l.getEnvList("TRUSTED_PROXIES", nil),
+		GRPCEnabled:                 l.getEnvBool("GRPC_ENABLED", false),
+		GRPCPort:                    l.getEnv("GRPC_PORT", "9090"),
+		DBHost:                      l.getEnv("DB_HOST", "localhost"),
+		DBPort:                      l.getEnv("DB_PORT", "5432"),
+		DBName:                      l.getEnv("POSTGRES_DB", "ocr_db"),
+		DBUser:                      l.getEnv("POSTGRES_USER", "ocr_user"),
+		DBPassword:                  l.getEnv("POSTGRES_PASSWORD", ""),
+		DBSSLMode:                   l.getEnv("DB_SSLMODE", "disable"),
+		DBAutoMigrate:               l.getEnvBool("DB_AUTO_MIGRATE", false),
+		DBMaxConns:                  l.getEnvInt("DB_MAX_CONNS", 25),
+		DBMinConns:                  l.getEnvInt("DB_MIN_CONNS", 5),
+		DBMaxConnLifetime:           l.getEnvDuration("DB_MAX_CONN_LIFETIME", time.Hour),
+		DBMaxConnIdleTime:           l.getEnvDuration("DB_MAX_CONN_IDLE_TIME", 30*time.Minute),
+		DBHealthCheckPeriod:         l.getEnvDuration("DB_HEALTH_CHECK_PERIOD", time.Minute),
+		DBStatementTimeout:          l.getEnvDuration("DB_STATEMENT_TIMEOUT", 0),
+		DBReplicaDSN:                l.getEnv("DB_REPLICA_DSN", ""),
+		JWTSecret:                   l.getEnv("JWT_SECRET", ""),
+		JWTExpiry:                   l.getEnv("JWT_EXPIRY", "24h"),
+		RefreshTokenExpiry:          l.getEnv("REFRESH_TOKEN_EXPIRY", "168h"),
+		JWTAlgorithm:                l.getEnv("JWT_ALGORITHM", "HS256"),
+		JWTKeyRotationInterval:      l.getEnvDuration("JWT_KEY_ROTATION_INTERVAL", 0),
+		JWTKeyEncryptionKey:         l.getEnv("JWT_KEY_ENCRYPTION_KEY", ""),
+		VaultNamespace:              l.getEnv("VAULT_NAMESPACE", ""),
+		VaultJWTSecretPath:          l.getEnv("VAULT_JWT_SECRET_PATH", ""),
+		VaultDBCredsPath:            l.getEnv("VAULT_DB_CREDS_PATH", ""),
+		RedisURL:                    l.getEnv("REDIS_URL", "redis://localhost:6379"),
+		RedisPassword:               l.getEnv("REDIS_PASSWORD", ""),
+		RateLimitBackend:            l.getEnv("RATE_LIMIT_BACKEND", "memory"),
+		ResponseCacheBackend:        l.getEnv("RESPONSE_CACHE_BACKEND", "memory"),
+		ResponseCacheTTL:            l.getEnvDuration("RESPONSE_CACHE_TTL", time.Minute),
+		RateLimitRequests:           l.getEnvInt("RATE_LIMIT_REQUESTS", 100),
+		RateLimitWindow:             l.getEnvDuration("RATE_LIMIT_WINDOW", time.Minute),
+		RateLimitAuth:               l.getEnvRateLimit("RATE_LIMIT_AUTH", RateLimit{10, time.Minute}),
+		RateLimitUpload:             l.getEnvRateLimit("RATE_LIMIT_UPLOAD", RateLimit{30, time.Minute}),
+		RateLimitOCR:                l.getEnvRateLimit("RATE_LIMIT_OCR", RateLimit{60, time.Minute}),
+		OCRServiceURL:               l.getEnv("OCR_SERVICE_URL", "http://localhost:8000"),
+		StoragePath:                 l.getEnv("STORAGE_PATH", "./storage"),
+		StorageContentAddressed:     l.getEnvBool("STORAGE_CONTENT_ADDRESSED", true),
+		MaxFileSize:                 52428800, // 50MB default
+		EnableRegistration:          l.getEnvBool("ENABLE_REGISTRATION", true),
+		EnableEmailVerification:     l.getEnvBool("ENABLE_EMAIL_VERIFICATION", false),
+		EnableAPIKeys:               l.getEnvBool("ENABLE_API_KEYS", true),
+		EnableProfiling:             l.getEnvBool("ENABLE_PROFILING", false),
+		EnableMetrics:               l.getEnvBool("ENABLE_METRICS", false),
+		OCRWorkers:                  l.getEnvInt("OCR_WORKERS", 4),
+		OCRQueueSize:                l.getEnvInt("OCR_QUEUE_SIZE", 1000),
+		OCRBreakerThreshold:         l.getEnvInt("OCR_BREAKER_THRESHOLD", 5),
+		OCRBreakerCooldown:          l.getEnvDuration("OCR_BREAKER_COOLDOWN", 15*time.Second),
+		OCRBreakerMaxCooldown:       l.getEnvDuration("OCR_BREAKER_MAX_COOLDOWN", 5*time.Minute),
+		OCRTimeout:                  l.getEnvDuration("OCR_TIMEOUT", 2*time.Minute),
+		OCRTimeoutPerPage:           l.getEnvDuration("OCR_TIMEOUT_PER_PAGE", 30*time.Second),
+		OCRTimeoutPerMB:             l.getEnvDuration("OCR_TIMEOUT_PER_MB", 5*time.Second),
+		OCRMaxTimeout:               l.getEnvDuration("OCR_MAX_TIMEOUT", 30*time.Minute),
+		OCRRetries:                  l.getEnvInt("OCR_RETRIES", 2),
+		OCRRetryBackoff:             l.getEnvDuration("OCR_RETRY_BACKOFF", time.Second),
+		OCRMaxIdleConns:             l.getEnvInt("OCR_MAX_IDLE_CONNS", 100),
+		OCRMaxIdleConnsPerHost:      l.getEnvInt("OCR_MAX_IDLE_CONNS_PER_HOST", 16),
+		OCRIdleConnTimeout:          l.getEnvDuration("OCR_IDLE_CONN_TIMEOUT", 90*time.Second),
+		OCRTLSCAFile:                l.getEnv("OCR_TLS_CA_FILE", ""),
+		OCRTLSServerName:            l.getEnv("OCR_TLS_SERVER_NAME", ""),
+		OCRTLSInsecureSkipVerify:    l.getEnvBool("OCR_TLS_INSECURE_SKIP_VERIFY", false),
+		OCRTLSCertFile:              l.getEnv("OCR_TLS_CERT_FILE", ""),
+		OCRTLSKeyFile:               l.getEnv("OCR_TLS_KEY_FILE", ""),
+		WarehouseExportEnabled:      l.getEnvBool("WAREHOUSE_EXPORT_ENABLED", false),
+		WarehouseExportInterval:     l.getEnvDuration("WAREHOUSE_EXPORT_INTERVAL", 24*time.Hour),
+		WarehouseExportFormat:       l.getEnv("WAREHOUSE_EXPORT_FORMAT", "ndjson"),
+		WarehouseExportBatchSize:    l.getEnvInt("WAREHOUSE_EXPORT_BATCH_SIZE", 5000),
+		WarehouseExportLag:          l.getEnvDuration("WAREHOUSE_EXPORT_LAG", 15*time.Minute),
+		WarehouseExportDestination:  l.getEnv("WAREHOUSE_EXPORT_DESTINATION", "local"),
+		WarehouseS3Endpoint:         l.getEnv("WAREHOUSE_EXPORT_S3_ENDPOINT", ""),
+		WarehouseS3Region:           l.getEnv("WAREHOUSE_EXPORT_S3_REGION", ""),
+		WarehouseS3Bucket:           l.getEnv("WAREHOUSE_EXPORT_S3_BUCKET", ""),
+		WarehouseS3Prefix:           l.getEnv("WAREHOUSE_EXPORT_S3_PREFIX", ""),
+		WarehouseS3AccessKeyID:      l.getEnv("WAREHOUSE_EXPORT_S3_ACCESS_KEY_ID", ""),
+		WarehouseS3SecretKey:        l.getEnv("WAREHOUSE_EXPORT_S3_SECRET_ACCESS_KEY", ""),
+		EventBusDriver:              l.getEnv("EVENT_BUS_DRIVER", "none"),
+		EventBusURL:                 l.getEnv("EVENT_BUS_URL", ""),
+		EventBusSubjectPrefix:       l.getEnv("EVENT_BUS_SUBJECT_PREFIX", "visekai"),
+		EventRelayInterval:          l.getEnvDuration("EVENT_RELAY_INTERVAL", 5*time.Second),
+		WebhookURL:                  l.getEnv("WEBHOOK_URL", ""),
+		WebhookSecret:               l.getEnv("WEBHOOK_SECRET", ""),
+		StorageEncryption:           l.getEnv("STORAGE_ENCRYPTION", "none"),
+		StorageEncryptionKeys:       l.getEnv("STORAGE_ENCRYPTION_KEYS", ""),
+		StorageEncryptionKeyID:      l.getEnv("STORAGE_ENCRYPTION_KEY_ID", ""),
+		VaultAddr:                   l.getEnv("VAULT_ADDR", ""),
+		VaultToken:                  l.getEnv("VAULT_TOKEN", ""),
+		VaultTransitKey:             l.getEnv("VAULT_TRANSIT_KEY", "visekai-storage"),
+		VaultTimeout:                l.getEnvDuration("VAULT_TIMEOUT", 10*time.Second),
+		AntivirusDriver:             l.getEnv("ANTIVIRUS_DRIVER", "none"),
+		AntivirusAddress:            l.getEnv("ANTIVIRUS_ADDRESS", "tcp://localhost:3310"),
+		AntivirusTimeout:            l.getEnvDuration("ANTIVIRUS_TIMEOUT", 30*time.Second),
+		AntivirusFailOpen:           l.getEnvBool("ANTIVIRUS_FAIL_OPEN", false),
+		EntityProviders:             l.getEnvList("ENTITY_PROVIDERS", []string{"none"}),
+		EntityTypes:                 l.getEnvList("ENTITY_TYPES", nil),
+		EntityRemoteURL:             l.getEnv("ENTITY_REMOTE_URL", ""),
+		EntityRemoteToken:           l.getEnv("ENTITY_REMOTE_TOKEN", ""),
+		EntityRemoteTimeout:         l.getEnvDuration("ENTITY_REMOTE_TIMEOUT", 10*time.Second),
+		ClassifierDriver:            l.getEnv("CLASSIFIER_DRIVER", "none"),
+		ClassifierURL:               l.getEnv("CLASSIFIER_URL", ""),
+		ClassifierToken:             l.getEnv("CLASSIFIER_TOKEN", ""),
+		ClassifierTimeout:           l.getEnvDuration("CLASSIFIER_TIMEOUT", 10*time.Second),
+		JanitorInterval:             l.getEnvDuration("JANITOR_INTERVAL", time.Hour),
+		TrashRetention:              l.getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		JobTrashRetention:           l.getEnvDuration("JOB_TRASH_RETENTION", 30*24*time.Hour),
+		AccountDeletionGrace:        l.getEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+		OrphanFileGrace:             l.getEnvDuration("ORPHAN_FILE_GRACE", 24*time.Hour),
+		RetentionInterval:           l.getEnvDuration("RETENTION_INTERVAL", time.Hour),
+		RetentionWarningPeriod:      l.getEnvDuration("RETENTION_WARNING_PERIOD", 7*24*time.Hour),
+		PendingJobLifetime:          l.getEnvDuration("PENDING_JOB_LIFETIME", 24*time.Hour),
+		PendingJobCheckInterval:     l.getEnvDuration("PENDING_JOB_CHECK_INTERVAL", 5*time.Minute),
+		StuckJobTimeout:             l.getEnvDuration("STUCK_JOB_TIMEOUT", 30*time.Minute),
+		StuckJobCheckInterval:       l.getEnvDuration("STUCK_JOB_CHECK_INTERVAL", 5*time.Minute),
+		AccessGrantReminderLead:     l.getEnvDuration("ACCESS_GRANT_REMINDER_LEAD", 24*time.Hour),
+		AccessGrantCheckInterval:    l.getEnvDuration("ACCESS_GRANT_CHECK_INTERVAL", 15*time.Minute),
+		AlertWebhookURL:             l.getEnv("ALERT_WEBHOOK_URL", ""),
+		AlertWebhookSecret:          l.getEnv("ALERT_WEBHOOK_SECRET", ""),
+		AlertSlackWebhookURL:        l.getEnv("ALERT_SLACK_WEBHOOK_URL", ""),
+		AlertEmailTo:                l.getEnvList("ALERT_EMAIL_TO", nil),
+		SMTPHost:                    l.getEnv("SMTP_HOST", ""),
+		SMTPPort:                    l.getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:                l.getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:                l.getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                    l.getEnv("SMTP_FROM", ""),
+		AlertCheckInterval:          l.getEnvDuration("ALERT_CHECK_INTERVAL", time.Minute),
+		AlertCooldown:               l.getEnvDuration("ALERT_COOLDOWN", 30*time.Minute),
+		AlertStorageFreePercent:     l.getEnvInt("ALERT_STORAGE_MIN_FREE_PERCENT", 10),
+		AlertQueueWaitSLO:           l.getEnvDuration("ALERT_QUEUE_WAIT_SLO", 10*time.Minute),
+		AlertFailureThreshold:       l.getEnvInt("ALERT_FAILURE_THRESHOLD", 3),
+		AlertCanaryEnabled:          l.getEnvBool("ALERT_CANARY_ENABLED", false),
+		CreditsEnforced:             l.getEnvBool("CREDITS_ENFORCED", false),
+		CreditsMonthlyAllotment:     l.getEnvInt("CREDITS_MONTHLY_ALLOTMENT", 1000),
+		CreditsPerPage:              l.getEnvIntMap("CREDITS_PER_PAGE", map[string]int{"tiny": 1, "small": 1, "base": 2, "large": 4, "gundam": 6}),
+		BillingEnabled:              l.getEnvBool("BILLING_ENABLED", false),
+		StripeSecretKey:             l.getEnv("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret:         l.getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		StripeAPIURL:                l.getEnv("STRIPE_API_URL", "https://api.stripe.com"),
+		BillingCurrency:             l.getEnv("BILLING_CURRENCY", "usd"),
+		BillingCreditPacks:          l.getEnvIntMap("BILLING_CREDIT_PACKS", map[string]int{"1000": 1000, "5000": 4500, "20000": 16000}),
+		BillingSuccessURL:           l.getEnv("BILLING_SUCCESS_URL", "http://localhost:3000/billing/success"),
+		BillingCancelURL:            l.getEnv("BILLING_CANCEL_URL", "http://localhost:3000/billing"),
+		ShareLinkSecret:             l.getEnv("SHARE_LINK_SECRET", ""),
+		ShareLinkMaxTTL:             l.getEnvDuration("SHARE_LINK_MAX_TTL", 30*24*time.Hour),
+		DataExportTTL:               l.getEnvDuration("DATA_EXPORT_TTL", 48*time.Hour),
+		SSOCallbackURL:              l.getEnv("SSO_CALLBACK_URL", ""),
+		SSOSuccessURL:               l.getEnv("SSO_SUCCESS_URL", ""),
+		SSOEncryptionKey:            l.getEnv("SSO_ENCRYPTION_KEY", ""),
+		UsageReportsEnabled:         l.getEnvBool("USAGE_REPORTS_ENABLED", true),
+		UsageReportInterval:         l.getEnvDuration("USAGE_REPORT_CHECK_INTERVAL", time.Hour),
+		StorageStatsInterval:        l.getEnvDuration("STORAGE_STATS_INTERVAL", time.Hour),
+		BackfillBatchSize:           l.getEnvInt("BACKFILL_BATCH_SIZE", 100),
+		IntegrationHookInterval:     l.getEnvDuration("INTEGRATION_HOOK_INTERVAL", 30*time.Second),
+		IntegrationHookTimeout:      l.getEnvDuration("INTEGRATION_HOOK_TIMEOUT", 10*time.Second),
+		IntegrationHookAllowPrivate: l.getEnvBool("INTEGRATION_HOOK_ALLOW_PRIVATE", false),
+		NotificationInterval:        l.getEnvDuration("NOTIFICATION_INTERVAL", 30*time.Second),
+		NotificationTimeout:         l.getEnvDuration("NOTIFICATION_TIMEOUT", 10*time.Second),
+		NotificationAllowPrivate:    l.getEnvBool("NOTIFICATION_ALLOW_PRIVATE", false),
+		NotificationDocumentURL:     l.getEnv("NOTIFICATION_DOCUMENT_URL", "http://localhost:3000/documents/"),
+		EmailNotificationInterval:   l.getEnvDuration("EMAIL_NOTIFICATION_INTERVAL", time.Minute),
+		DeliveryEnabled:             l.getEnvBool("DELIVERY_ENABLED", false),
+		DeliveryEncryptionKey:       l.getEnv("DELIVERY_ENCRYPTION_KEY", ""),
+		DeliveryInterval:            l.getEnvDuration("DELIVERY_INTERVAL", time.Minute),
+		DeliveryTimeout:             l.getEnvDuration("DELIVERY_TIMEOUT", time.Minute),
+		DeliveryMaxAttempts:         l.getEnvInt("DELIVERY_MAX_ATTEMPTS", 10),
+		DeliveryAllowPrivate:        l.getEnvBool("DELIVERY_ALLOW_PRIVATE", false),
+		ConnectorGoogleClientID:     l.getEnv("CONNECTOR_GOOGLE_CLIENT_ID", ""),
+		ConnectorGoogleClientSecret: l.getEnv("CONNECTOR_GOOGLE_CLIENT_SECRET", ""),
+		ConnectorDropboxAppKey:      l.getEnv("CONNECTOR_DROPBOX_APP_KEY", ""),
+		ConnectorDropboxAppSecret:   l.getEnv("CONNECTOR_DROPBOX_APP_SECRET", ""),
+		ConnectorCallbackURL:        l.getEnv("CONNECTOR_CALLBACK_URL", ""),
+		ConnectorSuccessURL:         l.getEnv("CONNECTOR_SUCCESS_URL", ""),
+		ConnectorSyncInterval:       l.getEnvDuration("CONNECTOR_SYNC_INTERVAL", 15*time.Minute),
+		ConnectorTimeout:            l.getEnvDuration("CONNECTOR_TIMEOUT", 30*time.Second),
+		InboundEmailDomain:          l.getEnv("INBOUND_EMAIL_DOMAIN", ""),
+		InboundEmailSigningKey:      l.getEnv("INBOUND_EMAIL_MAILGUN_SIGNING_KEY", ""),
+		InboundEmailDocumentURL:     l.getEnv("INBOUND_EMAIL_DOCUMENT_URL", "http://localhost:3000/documents/"),
+		InboundEmailMaxAttachments:  l.getEnvInt("INBOUND_EMAIL_MAX_ATTACHMENTS", 10),
+		StartupMaxWait:              l.getEnvDuration("STARTUP_MAX_WAIT", 2*time.Minute),
+		StartupDegradedMode:         l.getEnvBool("STARTUP_DEGRADED_MODE", false),
+	}
+
+	// Vault secrets take precedence over the environment
+	l.loadVault(cfg)
+
+	cfg.APIV1DeprecatedAt = l.getEnvDate("API_V1_DEPRECATED_AT")
+	cfg.APIV1SunsetAt = l.getEnvDate("API_V1_SUNSET_AT")
+
+	if errs := append(l.errs, cfg.validate()...); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
 	}
 
 	return cfg, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	value := os.Getenv(key)
+// loader reads settings from the environment. Values that cannot be parsed
+// are collected rather than replaced by defaults, so a typo is reported
+// instead of silently ignored.
+type loader struct {
+	errs []error
+}
+
+// invalid records a value that cannot be parsed
+func (l *loader) invalid(key, value, want string) {
+	l.errs = append(l.errs, fmt.Errorf("%s %q must be %s", key, value, want))
+}
+
+// lookup returns a setting, or the contents of the file named by its _FILE
+// variant, such as a Docker secret, with surrounding whitespace trimmed.
+// Setting both is reported.
+func (l *loader) lookup(key string) string {
+	value, path := os.Getenv(key), os.Getenv(key+"_FILE")
+	if path == "" {
+		return value
+	}
+	if value != "" {
+		l.errs = append(l.errs, fmt.Errorf("%s and %s_FILE are both set", key, key))
+		return value
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s_FILE: %w", key, err))
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+func (l *loader) getEnv(key, defaultValue string) string {
+	value := l.lookup(key)
 	if value == "" {
 		return defaultValue
 	}
 	return value
 }
 
-func getEnvBool(key string, defaultValue bool) bool {
-	value := os.Getenv(key)
+func (l *loader) getEnvList(key string, defaultValue []string) []string {
+	value := l.lookup(key)
 	if value == "" {
 		return defaultValue
 	}
-	return value == "true" || value == "1"
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (l *loader) getEnvBool(key string, defaultValue bool) bool {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.invalid(key, value, "true or false")
+		return defaultValue
+	}
+	return parsed
+}
+
+func (l *loader) getEnvInt(key string, defaultValue int) int {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		l.invalid(key, value, "a whole number")
+		return defaultValue
+	}
+	return parsed
+}
+
+func (l *loader) getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		l.invalid(key, value, "a duration such as 30s, 5m or 24h")
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvDate parses a date (2006-01-02) or an RFC 3339 time, returning the
+// zero time when the variable is unset
+func (l *loader) getEnvDate(key string) time.Time {
+	value := l.lookup(key)
+	if value == "" {
+		return time.Time{}
+	}
+	if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		return parsed
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		l.invalid(key, value, "a date (2006-01-02) or an RFC 3339 time")
+		return time.Time{}
+	}
+	return parsed
+}
+
+// getEnvIntMap parses a comma-separated list of name:number pairs
+func (l *loader) getEnvIntMap(key string, defaultValue map[string]int) map[string]int {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed := make(map[string]int)
+	for _, item := range strings.Split(value, ",") {
+		name, number, ok := strings.Cut(item, ":")
+		if !ok {
+			l.invalid(key, value, "a comma-separated list of name:number pairs")
+			return defaultValue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil || n < 0 {
+			l.invalid(key, value, "a comma-separated list of name:number pairs with numbers of 0 or more")
+			return defaultValue
+		}
+		parsed[strings.TrimSpace(name)] = n
+	}
+	return parsed
+}
+
+// getEnvStringMap parses a comma-separated list of name:value pairs
+func (l *loader) getEnvStringMap(key string, defaultValue map[string]string) map[string]string {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		name, v, ok := strings.Cut(item, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			l.invalid(key, value, "a comma-separated list of name:value pairs")
+			return defaultValue
+		}
+		parsed[strings.TrimSpace(name)] = strings.TrimSpace(v)
+	}
+	return parsed
+}
+
+// getEnvRateLimit parses a quota of the form "requests/window", e.g. "10/1m"
+func (l *loader) getEnvRateLimit(key string, defaultValue RateLimit) RateLimit {
+	value := l.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		l.invalid(key, value, "a quota of the form requests/window, e.g. 10/1m")
+		return defaultValue
+	}
+	parsedRequests, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil {
+		l.invalid(key, value, "a quota of the form requests/window, e.g. 10/1m")
+		return defaultValue
+	}
+	parsedWindow, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil {
+		l.invalid(key, value, "a quota of the form requests/window, e.g. 10/1m")
+		return defaultValue
+	}
+	return RateLimit{Requests: parsedRequests, Window: parsedWindow}
 }
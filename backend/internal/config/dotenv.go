@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// dotenvFile holds settings added to the process environment
+const dotenvFile = ".env"
+
+var (
+	dotenvMu sync.Mutex
+	// dotenvKeys are the variables set from the .env file, which loading it
+	// again may change or unset
+	dotenvKeys = map[string]bool{}
+)
+
+// loadDotenv sets the variables of the .env file, if there is one, that the
+// process environment does not set. Loaded again, it applies the changes to
+// the file and unsets the variables removed from it.
+func loadDotenv() {
+	values, err := godotenv.Read(dotenvFile)
+	if err != nil {
+		values = nil
+	}
+
+	dotenvMu.Lock()
+	defer dotenvMu.Unlock()
+
+	for key := range dotenvKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(dotenvKeys, key)
+		}
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !dotenvKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		dotenvKeys[key] = true
+	}
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validator collects the problems of a configuration, so all of them are
+// reported at once
+type validator struct {
+	errs []error
+}
+
+func (v *validator) fail(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// required checks that a setting is set
+func (v *validator) required(key, value string) {
+	if value == "" {
+		v.fail("%s is required", key)
+	}
+}
+
+// oneOf checks that a setting is one of the supported values
+func (v *validator) oneOf(key, value string, supported ...string) {
+	if !slices.Contains(supported, value) {
+		v.fail("%s %q is not supported (supported: %s)", key, value, strings.Join(supported, ", "))
+	}
+}
+
+// url checks that a setting, when set, is an absolute URL with one of the
+// schemes
+func (v *validator) url(key, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" || !slices.Contains(schemes, parsed.Scheme) {
+		v.fail("%s %q must be an absolute %s URL", key, value, strings.Join(schemes, " or "))
+	}
+}
+
+// port checks that a setting is a TCP port
+func (v *validator) port(key, value string) {
+	if n, err := strconv.Atoi(value); err != nil || n < 1 || n > 65535 {
+		v.fail("%s %q must be a port between 1 and 65535", key, value)
+	}
+}
+
+// ipOrCIDR checks that a setting is an IP address or a CIDR range
+func (v *validator) ipOrCIDR(key, value string) {
+	if _, err := netip.ParseAddr(value); err == nil {
+		return
+	}
+	if _, err := netip.ParsePrefix(value); err != nil {
+		v.fail("%s %q must be an IP address or CIDR range", key, value)
+	}
+}
+
+// encryptionKey checks that a setting, when set, is a 32-byte key, base64
+// encoded
+func (v *validator) encryptionKey(key, value string) {
+	if value == "" {
+		return
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(value); err != nil || len(decoded) != 32 {
+		v.fail("%s must be 32 bytes, base64 encoded (generate one with: openssl rand -base64 32)", key)
+	}
+}
+
+// atLeast checks that a number is at least min
+func (v *validator) atLeast(key string, value, min int) {
+	if value < min {
+		v.fail("%s (%d) must be at least %d", key, value, min)
+	}
+}
+
+// between checks that a number is within [min, max]
+func (v *validator) between(key string, value, min, max int) {
+	if value < min || value > max {
+		v.fail("%s (%d) must be between %d and %d", key, value, min, max)
+	}
+}
+
+// positive checks that durations are above zero
+func (v *validator) positive(durations map[string]time.Duration) {
+	for _, key := range sortedKeys(durations) {
+		if durations[key] <= 0 {
+			v.fail("%s (%s) must be positive", key, durations[key])
+		}
+	}
+}
+
+// nonNegative checks that durations are zero or more
+func (v *validator) nonNegative(durations map[string]time.Duration) {
+	for _, key := range sortedKeys(durations) {
+		if durations[key] < 0 {
+			v.fail("%s (%s) must not be negative", key, durations[key])
+		}
+	}
+}
+
+// rateLimit checks that a quota has a window unless it is disabled
+func (v *validator) rateLimit(key string, limit RateLimit) {
+	if limit.Requests < 0 {
+		v.fail("%s (%d requests) must not be negative", key, limit.Requests)
+	}
+	if limit.Requests > 0 && limit.Window <= 0 {
+		v.fail("%s window (%s) must be positive", key, limit.Window)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// validate returns every problem of the configuration
+func (c *Config) validate() []error {
+	var v validator
+
+	v.required("JWT_SECRET", c.JWTSecret)
+	v.required("SHARE_LINK_SECRET", c.ShareLinkSecret)
+	if c.ShareLinkSecret != "" && c.ShareLinkSecret == c.JWTSecret {
+		v.fail("SHARE_LINK_SECRET must differ from JWT_SECRET")
+	}
+	v.required("POSTGRES_PASSWORD", c.DBPassword)
+
+	// Server and logging
+	v.port("PORT", c.Port)
+	if c.GRPCEnabled {
+		v.port("GRPC_PORT", c.GRPCPort)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		v.fail("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.TLSCertFile != "" && len(c.TLSAutocertDomains) > 0 {
+		v.fail("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS cannot both be set")
+	}
+	if c.TLSRedirectPort != "" {
+		if c.TLSCertFile == "" && len(c.TLSAutocertDomains) == 0 {
+			v.fail("TLS_REDIRECT_PORT requires TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+		}
+		v.port("TLS_REDIRECT_PORT", c.TLSRedirectPort)
+	}
+	v.positive(map[string]time.Duration{"HTTP_READ_HEADER_TIMEOUT": c.HTTPReadHeaderTimeout})
+	v.atLeast("MAX_BODY_SIZE", c.MaxBodySize, 1024)
+	for _, proxy := range c.TrustedProxies {
+		v.ipOrCIDR("TRUSTED_PROXIES entry", proxy)
+	}
+	v.oneOf("GIN_MODE", c.GinMode, "debug", "release", "test")
+	v.oneOf("LOG_LEVEL", c.LogLevel, "debug", "info", "warn", "error")
+	v.oneOf("LOG_FORMAT", c.LogFormat, "json", "console")
+	for _, output := range c.LogOutputs {
+		v.oneOf("LOG_OUTPUTS entry", output, "stdout", "file", "syslog", "loki")
+		if output == "loki" {
+			v.required("LOG_LOKI_URL", c.LogLokiURL)
+		}
+	}
+	v.url("LOG_LOKI_URL", c.LogLokiURL, "http", "https")
+	v.atLeast("LOG_FILE_MAX_SIZE_MB", c.LogFileMaxSizeMB, 1)
+	v.atLeast("LOG_FILE_MAX_BACKUPS", c.LogFileMaxBackups, 0)
+	v.atLeast("LOG_BODY_MAX_BYTES", c.LogBodyMaxBytes, 1)
+	v.between("LOG_BODY_SAMPLE_PERCENT", c.LogBodySamplePercent, 0, 100)
+	v.url("API_V1_DEPRECATION_URL", c.APIV1DeprecationURL, "http", "https")
+	if !c.APIV1SunsetAt.IsZero() && c.APIV1DeprecatedAt.IsZero() {
+		v.fail("API_V1_SUNSET_AT requires API_V1_DEPRECATED_AT")
+	}
+
+	// Database
+	v.port("DB_PORT", c.DBPort)
+	v.oneOf("DB_SSLMODE", c.DBSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	if c.DBMaxConns < 1 || c.DBMinConns < 0 || c.DBMinConns > c.DBMaxConns {
+		v.fail("DB_MIN_CONNS (%d) must be between 0 and DB_MAX_CONNS (%d), which must be positive", c.DBMinConns, c.DBMaxConns)
+	}
+
+	// Tokens
+	for _, expiry := range []struct{ name, value string }{
+		{"JWT_EXPIRY", c.JWTExpiry},
+		{"REFRESH_TOKEN_EXPIRY", c.RefreshTokenExpiry},
+	} {
+		if d, err := time.ParseDuration(expiry.value); err != nil || d <= 0 {
+			v.fail("%s %q must be a positive duration such as 24h", expiry.name, expiry.value)
+		}
+	}
+	v.oneOf("JWT_ALGORITHM", c.JWTAlgorithm, "HS256", "RS256", "EdDSA")
+	if c.JWTAlgorithm != "HS256" {
+		// Sealing the keys with the secret they replace would protect nothing
+		v.required("JWT_KEY_ENCRYPTION_KEY", c.JWTKeyEncryptionKey)
+	}
+	v.encryptionKey("JWT_KEY_ENCRYPTION_KEY", c.JWTKeyEncryptionKey)
+
+	// OCR service
+	v.required("OCR_SERVICE_URL", c.OCRServiceURL)
+	v.url("OCR_SERVICE_URL", c.OCRServiceURL, "http", "https")
+	if (c.OCRTLSCertFile == "") != (c.OCRTLSKeyFile == "") {
+		v.fail("OCR_TLS_CERT_FILE and OCR_TLS_KEY_FILE must be set together")
+	}
+	if (c.OCRTLSCertFile != "" || c.OCRTLSCAFile != "") && !strings.HasPrefix(c.OCRServiceURL, "https://") {
+		v.fail("OCR_TLS_CA_FILE and OCR_TLS_CERT_FILE require an https OCR_SERVICE_URL")
+	}
+	v.atLeast("OCR_WORKERS", c.OCRWorkers, 1)
+	v.atLeast("OCR_QUEUE_SIZE", c.OCRQueueSize, 0)
+	v.atLeast("OCR_BREAKER_THRESHOLD", c.OCRBreakerThreshold, 0)
+	v.atLeast("OCR_RETRIES", c.OCRRetries, 0)
+	v.atLeast("OCR_MAX_IDLE_CONNS", c.OCRMaxIdleConns, 0)
+	v.atLeast("OCR_MAX_IDLE_CONNS_PER_HOST", c.OCRMaxIdleConnsPerHost, 0)
+	if c.OCRTimeout <= 0 || c.OCRMaxTimeout < c.OCRTimeout {
+		v.fail("OCR_TIMEOUT (%s) must be positive and at most OCR_MAX_TIMEOUT (%s)", c.OCRTimeout, c.OCRMaxTimeout)
+	}
+	if c.OCRBreakerMaxCooldown < c.OCRBreakerCooldown {
+		v.fail("OCR_BREAKER_COOLDOWN (%s) must be at most OCR_BREAKER_MAX_COOLDOWN (%s)", c.OCRBreakerCooldown, c.OCRBreakerMaxCooldown)
+	}
+
+	// Rate limits and caching
+	v.oneOf("RATE_LIMIT_BACKEND", c.RateLimitBackend, "memory", "redis")
+	v.oneOf("RESPONSE_CACHE_BACKEND", c.ResponseCacheBackend, "none", "memory", "redis")
+	if c.RateLimitBackend == "redis" || c.ResponseCacheBackend == "redis" {
+		v.url("REDIS_URL", c.RedisURL, "redis", "rediss")
+	}
+	v.rateLimit("RATE_LIMIT_REQUESTS", RateLimit{Requests: c.RateLimitRequests, Window: c.RateLimitWindow})
+	v.rateLimit("RATE_LIMIT_AUTH", c.RateLimitAuth)
+	v.rateLimit("RATE_LIMIT_UPLOAD", c.RateLimitUpload)
+	v.rateLimit("RATE_LIMIT_OCR", c.RateLimitOCR)
+
+	v.oneOf("WAREHOUSE_EXPORT_FORMAT", c.WarehouseExportFormat, "ndjson", "parquet")
+	v.atLeast("WAREHOUSE_EXPORT_BATCH_SIZE", c.WarehouseExportBatchSize, 1)
+	if c.WarehouseExportLag < 0 {
+		v.fail("WAREHOUSE_EXPORT_LAG (%s) must not be negative", c.WarehouseExportLag)
+	}
+	v.oneOf("WAREHOUSE_EXPORT_DESTINATION", c.WarehouseExportDestination, "local", "s3")
+	if c.WarehouseExportDestination == "s3" {
+		v.required("WAREHOUSE_EXPORT_S3_REGION", c.WarehouseS3Region)
+		v.required("WAREHOUSE_EXPORT_S3_BUCKET", c.WarehouseS3Bucket)
+		v.required("WAREHOUSE_EXPORT_S3_ACCESS_KEY_ID", c.WarehouseS3AccessKeyID)
+		v.required("WAREHOUSE_EXPORT_S3_SECRET_ACCESS_KEY", c.WarehouseS3SecretKey)
+	}
+	v.atLeast("BACKFILL_BATCH_SIZE", c.BackfillBatchSize, 1)
+
+	// Outgoing calls and links
+	for _, setting := range []struct{ name, value string }{
+		{"WEBHOOK_URL", c.WebhookURL},
+		{"ALERT_WEBHOOK_URL", c.AlertWebhookURL},
+		{"ALERT_SLACK_WEBHOOK_URL", c.AlertSlackWebhookURL},
+		{"STRIPE_API_URL", c.StripeAPIURL},
+		{"BILLING_SUCCESS_URL", c.BillingSuccessURL},
+		{"BILLING_CANCEL_URL", c.BillingCancelURL},
+		{"SSO_CALLBACK_URL", c.SSOCallbackURL},
+		{"SSO_SUCCESS_URL", c.SSOSuccessURL},
+		{"NOTIFICATION_DOCUMENT_URL", c.NotificationDocumentURL},
+		{"INBOUND_EMAIL_DOCUMENT_URL", c.InboundEmailDocumentURL},
+		{"CONNECTOR_CALLBACK_URL", c.ConnectorCallbackURL},
+		{"CONNECTOR_SUCCESS_URL", c.ConnectorSuccessURL},
+		{"ENTITY_REMOTE_URL", c.EntityRemoteURL},
+		{"CLASSIFIER_URL", c.ClassifierURL},
+		{"VAULT_ADDR", c.VaultAddr},
+		{"TLS_AUTOCERT_DIRECTORY_URL", c.TLSAutocertDirectoryURL},
+		{"WAREHOUSE_EXPORT_S3_ENDPOINT", c.WarehouseS3Endpoint},
+	} {
+		v.url(setting.name, setting.value, "http", "https")
+	}
+
+	// Single sign-on
+	v.encryptionKey("SSO_ENCRYPTION_KEY", c.SSOEncryptionKey)
+
+	// Alerts, credits and deliveries
+	v.between("SMTP_PORT", c.SMTPPort, 1, 65535)
+	v.between("ALERT_STORAGE_MIN_FREE_PERCENT", c.AlertStorageFreePercent, 0, 100)
+	v.atLeast("ALERT_FAILURE_THRESHOLD", c.AlertFailureThreshold, 1)
+	v.atLeast("CREDITS_MONTHLY_ALLOTMENT", c.CreditsMonthlyAllotment, 0)
+	v.atLeast("DELIVERY_MAX_ATTEMPTS", c.DeliveryMaxAttempts, 1)
+	if c.DeliveryEnabled {
+		v.required("DELIVERY_ENCRYPTION_KEY", c.DeliveryEncryptionKey)
+	}
+	v.encryptionKey("DELIVERY_ENCRYPTION_KEY", c.DeliveryEncryptionKey)
+	v.atLeast("INBOUND_EMAIL_MAX_ATTACHMENTS", c.InboundEmailMaxAttachments, 1)
+	if c.BillingEnabled {
+		if c.StripeSecretKey == "" || c.StripeWebhookSecret == "" {
+			v.fail("STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET are required when BILLING_ENABLED is set")
+		}
+		for _, credits := range sortedKeys(c.BillingCreditPacks) {
+			price := c.BillingCreditPacks[credits]
+			if n, err := strconv.Atoi(credits); err != nil || n < 1 || price < 1 {
+				v.fail("BILLING_CREDIT_PACKS entry %s:%d must be a positive number of credits and a positive price", credits, price)
+			}
+		}
+	}
+
+	// Worker intervals and timeouts; a zero interval would stop a worker
+	v.positive(map[string]time.Duration{
+		"RATE_LIMIT_WINDOW":           c.RateLimitWindow,
+		"LOG_LOKI_BATCH_WAIT":         c.LogLokiBatchWait,
+		"OCR_IDLE_CONN_TIMEOUT":       c.OCRIdleConnTimeout,
+		"WAREHOUSE_EXPORT_INTERVAL":   c.WarehouseExportInterval,
+		"EVENT_RELAY_INTERVAL":        c.EventRelayInterval,
+		"VAULT_TIMEOUT":               c.VaultTimeout,
+		"ANTIVIRUS_TIMEOUT":           c.AntivirusTimeout,
+		"ENTITY_REMOTE_TIMEOUT":       c.EntityRemoteTimeout,
+		"CLASSIFIER_TIMEOUT":          c.ClassifierTimeout,
+		"JANITOR_INTERVAL":            c.JanitorInterval,
+		"RETENTION_INTERVAL":          c.RetentionInterval,
+		"PENDING_JOB_CHECK_INTERVAL":  c.PendingJobCheckInterval,
+		"STUCK_JOB_CHECK_INTERVAL":    c.StuckJobCheckInterval,
+		"ACCESS_GRANT_CHECK_INTERVAL": c.AccessGrantCheckInterval,
+		"ALERT_CHECK_INTERVAL":        c.AlertCheckInterval,
+		"USAGE_REPORT_CHECK_INTERVAL": c.UsageReportInterval,
+		"STORAGE_STATS_INTERVAL":      c.StorageStatsInterval,
+		"INTEGRATION_HOOK_INTERVAL":   c.IntegrationHookInterval,
+		"INTEGRATION_HOOK_TIMEOUT":    c.IntegrationHookTimeout,
+		"NOTIFICATION_INTERVAL":       c.NotificationInterval,
+		"NOTIFICATION_TIMEOUT":        c.NotificationTimeout,
+		"EMAIL_NOTIFICATION_INTERVAL": c.EmailNotificationInterval,
+		"DELIVERY_INTERVAL":           c.DeliveryInterval,
+		"DELIVERY_TIMEOUT":            c.DeliveryTimeout,
+		"CONNECTOR_SYNC_INTERVAL":     c.ConnectorSyncInterval,
+		"CONNECTOR_TIMEOUT":           c.ConnectorTimeout,
+		"STARTUP_MAX_WAIT":            c.StartupMaxWait,
+	})
+
+	// Ages, grace periods and optional timeouts, where zero has a meaning
+	v.nonNegative(map[string]time.Duration{
+		"CORS_MAX_AGE":               c.CORSMaxAge,
+		"LOG_FILE_MAX_AGE":           c.LogFileMaxAge,
+		"JWT_KEY_ROTATION_INTERVAL":  c.JWTKeyRotationInterval,
+		"DB_MAX_CONN_LIFETIME":       c.DBMaxConnLifetime,
+		"DB_MAX_CONN_IDLE_TIME":      c.DBMaxConnIdleTime,
+		"DB_HEALTH_CHECK_PERIOD":     c.DBHealthCheckPeriod,
+		"DB_STATEMENT_TIMEOUT":       c.DBStatementTimeout,
+		"RESPONSE_CACHE_TTL":         c.ResponseCacheTTL,
+		"OCR_BREAKER_COOLDOWN":       c.OCRBreakerCooldown,
+		"OCR_TIMEOUT_PER_PAGE":       c.OCRTimeoutPerPage,
+		"OCR_TIMEOUT_PER_MB":         c.OCRTimeoutPerMB,
+		"OCR_RETRY_BACKOFF":          c.OCRRetryBackoff,
+		"TRASH_RETENTION":            c.TrashRetention,
+		"JOB_TRASH_RETENTION":        c.JobTrashRetention,
+		"ORPHAN_FILE_GRACE":          c.OrphanFileGrace,
+		"ACCOUNT_DELETION_GRACE":     c.AccountDeletionGrace,
+		"RETENTION_WARNING_PERIOD":   c.RetentionWarningPeriod,
+		"PENDING_JOB_LIFETIME":       c.PendingJobLifetime,
+		"STUCK_JOB_TIMEOUT":          c.StuckJobTimeout,
+		"ACCESS_GRANT_REMINDER_LEAD": c.AccessGrantReminderLead,
+		"ALERT_COOLDOWN":             c.AlertCooldown,
+		"ALERT_QUEUE_WAIT_SLO":       c.AlertQueueWaitSLO,
+		"SHARE_LINK_MAX_TTL":         c.ShareLinkMaxTTL,
+		"DATA_EXPORT_TTL":            c.DataExportTTL,
+	})
+
+	return v.errs
+}
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"visekai/backend/pkg/logger"
+)
+
+const (
+	// vaultRetryInterval is the delay before retrying a failed renewal
+	vaultRetryInterval = 30 * time.Second
+	// vaultMinInterval keeps short leases from renewing in a tight loop
+	vaultMinInterval = 5 * time.Second
+)
+
+// vaultClient calls the HTTP API of HashiCorp Vault with a token
+type vaultClient struct {
+	addr      string
+	token     string
+	namespace string
+	http      *http.Client
+}
+
+// vaultSecret is Vault's response to reads and renewals
+type vaultSecret struct {
+	LeaseID       string         `json:"lease_id"`
+	LeaseDuration int            `json:"lease_duration"`
+	Renewable     bool           `json:"renewable"`
+	Data          map[string]any `json:"data"`
+	Auth          *struct {
+		LeaseDuration int  `json:"lease_duration"`
+		Renewable     bool `json:"renewable"`
+	} `json:"auth"`
+}
+
+// field returns a string field of a secret, unwrapping version 2 KV secrets
+func (s *vaultSecret) field(name string) string {
+	data := s.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		data = nested
+	}
+	value, _ := data[name].(string)
+	return value
+}
+
+// do sends a request to the API path and decodes the secret it returns
+func (v *vaultClient) do(ctx context.Context, method, path string, body any) (*vaultSecret, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+"/v1/"+strings.TrimPrefix(path, "/"), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+		if len(failure.Errors) > 0 {
+			return nil, fmt.Errorf("vault responded with status %d: %s", resp.StatusCode, strings.Join(failure.Errors, "; "))
+		}
+		return nil, fmt.Errorf("vault responded with status %d", resp.StatusCode)
+	}
+
+	var secret vaultSecret
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return &secret, nil
+}
+
+// vaultLease is the lease of the token or of a secret, renewed once half of
+// it has passed
+type vaultLease struct {
+	id        string
+	renewable bool
+	ttl       time.Duration
+	renewedAt time.Time
+}
+
+func (l vaultLease) renewAt() time.Time {
+	return l.renewedAt.Add(l.ttl / 2)
+}
+
+func (l vaultLease) expiresAt() time.Time {
+	return l.renewedAt.Add(l.ttl)
+}
+
+// vaultSessionState is the Vault client with the leases it keeps alive
+type vaultSessionState struct {
+	sync.Mutex
+	client     *vaultClient
+	token      vaultLease
+	dbPath     string
+	dbUser     string
+	dbPassword string
+	dbLease    vaultLease
+}
+
+// vaultSession is shared by every Load, so reloading the configuration reuses
+// the leased database credentials rather than requesting new ones
+var vaultSession vaultSessionState
+
+// loadVault reads the JWT secret and the database credentials from Vault when
+// their paths are set
+func (l *loader) loadVault(cfg *Config) {
+	if cfg.VaultJWTSecretPath == "" && cfg.VaultDBCredsPath == "" {
+		return
+	}
+	if cfg.VaultAddr == "" || cfg.VaultToken == "" {
+		l.errs = append(l.errs, errors.New("VAULT_ADDR and VAULT_TOKEN are required to read secrets from Vault"))
+		return
+	}
+
+	ctx := context.Background()
+	s := &vaultSession
+	s.Lock()
+	defer s.Unlock()
+
+	addr := strings.TrimSuffix(cfg.VaultAddr, "/")
+	if s.client == nil || s.client.addr != addr || s.client.token != cfg.VaultToken || s.client.namespace != cfg.VaultNamespace {
+		client := &vaultClient{addr: addr, token: cfg.VaultToken, namespace: cfg.VaultNamespace, http: &http.Client{Timeout: cfg.VaultTimeout}}
+		token, err := client.do(ctx, http.MethodGet, "auth/token/lookup-self", nil)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("VAULT_TOKEN: %w", err))
+			return
+		}
+		ttl, _ := token.Data["ttl"].(float64)
+		renewable, _ := token.Data["renewable"].(bool)
+		s.client = client
+		s.token = vaultLease{renewable: renewable, ttl: time.Duration(ttl) * time.Second, renewedAt: time.Now()}
+		s.dbPath = ""
+	}
+
+	if cfg.VaultJWTSecretPath != "" {
+		secret, err := s.client.do(ctx, http.MethodGet, cfg.VaultJWTSecretPath, nil)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("VAULT_JWT_SECRET_PATH: %w", err))
+		} else if value := secret.field("jwt_secret"); value == "" {
+			l.errs = append(l.errs, fmt.Errorf("VAULT_JWT_SECRET_PATH %q has no jwt_secret field", cfg.VaultJWTSecretPath))
+		} else {
+			cfg.JWTSecret = value
+		}
+	}
+
+	if cfg.VaultDBCredsPath != "" {
+		if s.dbPath != cfg.VaultDBCredsPath || time.Now().After(s.dbLease.renewAt()) {
+			if err := s.readDBCredentials(ctx, cfg.VaultDBCredsPath); err != nil {
+				l.errs = append(l.errs, fmt.Errorf("VAULT_DB_CREDS_PATH: %w", err))
+				return
+			}
+		}
+		cfg.DBUser, cfg.DBPassword = s.dbUser, s.dbPassword
+	}
+}
+
+// readDBCredentials requests new database credentials; the session must be
+// locked
+func (s *vaultSessionState) readDBCredentials(ctx context.Context, path string) error {
+	secret, err := s.client.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	user, password := secret.field("username"), secret.field("password")
+	if user == "" || password == "" {
+		return fmt.Errorf("%q has no username and password", path)
+	}
+
+	s.dbPath, s.dbUser, s.dbPassword = path, user, password
+	s.dbLease = vaultLease{
+		id:        secret.LeaseID,
+		renewable: secret.Renewable,
+		ttl:       time.Duration(secret.LeaseDuration) * time.Second,
+		renewedAt: time.Now(),
+	}
+	return nil
+}
+
+// DatabaseCredentials returns the user and password of new database
+// connections, which change when Vault issues new credentials
+func (c *Config) DatabaseCredentials() (user, password string) {
+	if c.VaultDBCredsPath != "" {
+		vaultSession.Lock()
+		defer vaultSession.Unlock()
+		if vaultSession.dbPath == c.VaultDBCredsPath {
+			return vaultSession.dbUser, vaultSession.dbPassword
+		}
+	}
+	return c.DBUser, c.DBPassword
+}
+
+// RenewVaultSecrets keeps the Vault token and the database credentials alive
+// until ctx is done, requesting new credentials once their lease cannot be
+// renewed any more. It returns at once when nothing needs renewing.
+func RenewVaultSecrets(ctx context.Context) {
+	for {
+		wait, ok := vaultSession.renew(ctx)
+		if !ok {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// renew renews the leases past half their time, returning how long to wait
+// before the next renewal, or false when nothing needs renewing
+func (s *vaultSessionState) renew(ctx context.Context) (time.Duration, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	renewToken := s.client != nil && s.token.renewable && s.token.ttl > 0
+	renewDB := s.client != nil && s.dbPath != "" && s.dbLease.ttl > 0
+	if !renewToken && !renewDB {
+		return 0, false
+	}
+
+	now := time.Now()
+	next := now.Add(24 * time.Hour)
+	failed := false
+
+	if renewToken {
+		if !now.Before(s.token.renewAt()) {
+			secret, err := s.client.do(ctx, http.MethodPost, "auth/token/renew-self", nil)
+			if err != nil || secret.Auth == nil {
+				logger.Error("Failed to renew Vault token", "error", err, "expires_at", s.token.expiresAt())
+				failed = true
+			} else {
+				s.token = vaultLease{renewable: secret.Auth.Renewable, ttl: time.Duration(secret.Auth.LeaseDuration) * time.Second, renewedAt: now}
+			}
+		}
+		if s.token.renewable && s.token.renewAt().Before(next) {
+			next = s.token.renewAt()
+		}
+	}
+
+	if renewDB {
+		if !now.Before(s.dbLease.renewAt()) {
+			if err := s.renewDBCredentials(ctx, now); err != nil {
+				logger.Error("Failed to renew database credentials from Vault", "error", err, "expires_at", s.dbLease.expiresAt())
+				failed = true
+			}
+		}
+		if s.dbLease.renewAt().Before(next) {
+			next = s.dbLease.renewAt()
+		}
+	}
+
+	wait := time.Until(next)
+	if failed {
+		wait = min(wait, vaultRetryInterval)
+	}
+	return max(wait, vaultMinInterval), true
+}
+
+// renewDBCredentials extends the lease of the database credentials. Vault
+// caps a lease at its maximum TTL; once it grants less than asked, the
+// credentials are about to expire and new ones are requested. Connections
+// opened before keep the old credentials until the pool recycles them.
+func (s *vaultSessionState) renewDBCredentials(ctx context.Context, now time.Time) error {
+	if s.dbLease.renewable {
+		secret, err := s.client.do(ctx, http.MethodPut, "sys/leases/renew", map[string]any{
+			"lease_id":  s.dbLease.id,
+			"increment": int(s.dbLease.ttl.Seconds()),
+		})
+		if err == nil && time.Duration(secret.LeaseDuration)*time.Second >= s.dbLease.ttl {
+			s.dbLease.renewedAt = now
+			return nil
+		}
+	}
+
+	if err := s.readDBCredentials(ctx, s.dbPath); err != nil {
+		return err
+	}
+	logger.Info("Obtained new database credentials from Vault", "user", s.dbUser, "lease_ttl", s.dbLease.ttl)
+	return nil
+}
@@ -0,0 +1,175 @@
+// Package connectors reads files from users' cloud storage accounts, linked
+// with OAuth, so they can be imported as documents
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Provider names
+const (
+	ProviderGoogleDrive = "google_drive"
+	ProviderDropbox     = "dropbox"
+)
+
+// responseLimit caps the size of the JSON responses read from a provider
+const responseLimit = 8 << 20
+
+// ErrUnauthorized is returned when a provider rejects the account's tokens,
+// for instance because the user revoked the access
+var ErrUnauthorized = errors.New("access to the account was revoked or has expired")
+
+// Provider is a cloud storage service files are imported from
+type Provider interface {
+	// AuthCodeURL returns where to send the user to grant read access
+	AuthCodeURL(redirectURL, state string) string
+	// Exchange trades the code the user came back with for tokens
+	Exchange(ctx context.Context, redirectURL, code string) (*Token, error)
+	// Refresh gets a new access token with a refresh token
+	Refresh(ctx context.Context, refreshToken string) (*Token, error)
+	// Account returns the email address of the account, for display
+	Account(ctx context.Context, accessToken string) (string, error)
+	// ListFolders lists the folders directly inside a folder; an empty parent
+	// is the root of the account
+	ListFolders(ctx context.Context, accessToken, parent string) ([]Entry, error)
+	// ListFiles lists the files directly inside a folder, skipping those that
+	// cannot be downloaded as they are, such as Google Docs
+	ListFiles(ctx context.Context, accessToken, folder string) ([]Entry, error)
+	// Download opens the content of a file
+	Download(ctx context.Context, accessToken, fileID string) (io.ReadCloser, error)
+}
+
+// Token is an OAuth access token with the refresh token renewing it
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// Entry is a file or folder of a provider
+type Entry struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Size       int64     `json:"size,omitempty"`
+	ModifiedAt time.Time `json:"modified_at,omitempty"`
+}
+
+// Config holds the OAuth apps registered with each provider. A provider
+// without a client ID is not offered.
+type Config struct {
+	GoogleClientID      string
+	GoogleClientSecret  string
+	DropboxClientID     string
+	DropboxClientSecret string
+	Timeout             time.Duration
+}
+
+// NewProviders creates the configured providers, by name
+func NewProviders(cfg Config) map[string]Provider {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+
+	providers := map[string]Provider{}
+	if cfg.GoogleClientID != "" {
+		providers[ProviderGoogleDrive] = NewGoogleDrive(cfg.GoogleClientID, cfg.GoogleClientSecret, httpClient)
+	}
+	if cfg.DropboxClientID != "" {
+		providers[ProviderDropbox] = NewDropbox(cfg.DropboxClientID, cfg.DropboxClientSecret, httpClient)
+	}
+	return providers
+}
+
+// oauthApp is an OAuth client registered with a provider
+type oauthApp struct {
+	clientID     string
+	clientSecret string
+	tokenURL     string
+	httpClient   *http.Client
+}
+
+// token posts a token request and reads the token from the response
+func (a *oauthApp) token(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", a.clientID)
+	form.Set("client_secret", a.clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var body struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := doJSON(a.httpClient, req, &body); err != nil {
+		// Token endpoints answer 400 invalid_grant for revoked refresh tokens
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.status == http.StatusBadRequest {
+			return nil, ErrUnauthorized
+		}
+		return nil, err
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access token")
+	}
+
+	token := &Token{AccessToken: body.AccessToken, RefreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// statusError is a non-2xx response of a provider
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("provider returned status %d", e.status)
+}
+
+// do sends a request and returns the response when it is a success; 401
+// responses are ErrUnauthorized
+func do(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach provider: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrUnauthorized
+	}
+	return nil, &statusError{status: resp.StatusCode}
+}
+
+// doJSON sends a request and decodes its JSON response into out
+func doJSON(httpClient *http.Client, req *http.Request, out any) error {
+	resp, err := do(httpClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, responseLimit)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode provider response: %w", err)
+	}
+	return nil
+}
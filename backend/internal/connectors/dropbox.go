@@ -0,0 +1,166 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	dropboxAuthURL    = "https://www.dropbox.com/oauth2/authorize"
+	dropboxTokenURL   = "https://api.dropboxapi.com/oauth2/token"
+	dropboxAPI        = "https://api.dropboxapi.com/2"
+	dropboxContentAPI = "https://content.dropboxapi.com/2"
+)
+
+// Dropbox imports files from Dropbox through its v2 API. Folders are
+// addressed by their "id:..." identifiers, which survive renames and moves.
+type Dropbox struct {
+	app oauthApp
+}
+
+// NewDropbox creates a new Dropbox provider
+func NewDropbox(appKey, appSecret string, httpClient *http.Client) *Dropbox {
+	return &Dropbox{app: oauthApp{
+		clientID:     appKey,
+		clientSecret: appSecret,
+		tokenURL:     dropboxTokenURL,
+		httpClient:   httpClient,
+	}}
+}
+
+// AuthCodeURL asks for offline access, so a refresh token is issued
+func (d *Dropbox) AuthCodeURL(redirectURL, state string) string {
+	params := url.Values{}
+	params.Set("client_id", d.app.clientID)
+	params.Set("redirect_uri", redirectURL)
+	params.Set("response_type", "code")
+	params.Set("token_access_type", "offline")
+	params.Set("state", state)
+	return dropboxAuthURL + "?" + params.Encode()
+}
+
+// Exchange trades an authorization code for tokens
+func (d *Dropbox) Exchange(ctx context.Context, redirectURL, code string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	return d.app.token(ctx, form)
+}
+
+// Refresh gets a new short-lived access token
+func (d *Dropbox) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	return d.app.token(ctx, form)
+}
+
+// Account returns the email address of the account
+func (d *Dropbox) Account(ctx context.Context, accessToken string) (string, error) {
+	var account struct {
+		Email string `json:"email"`
+	}
+	if err := d.call(ctx, accessToken, "/users/get_current_account", nil, &account); err != nil {
+		return "", err
+	}
+	return account.Email, nil
+}
+
+// ListFolders lists the folders inside parent, or inside the root
+func (d *Dropbox) ListFolders(ctx context.Context, accessToken, parent string) ([]Entry, error) {
+	return d.list(ctx, accessToken, parent, "folder")
+}
+
+// ListFiles lists the files inside folder
+func (d *Dropbox) ListFiles(ctx context.Context, accessToken, folder string) ([]Entry, error) {
+	return d.list(ctx, accessToken, folder, "file")
+}
+
+// Download opens the content of a file
+func (d *Dropbox) Download(ctx context.Context, accessToken, fileID string) (io.ReadCloser, error) {
+	arg, err := json.Marshal(map[string]string{"path": fileID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", dropboxContentAPI+"/files/download", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Dropbox-API-Arg", string(arg))
+
+	resp, err := do(d.app.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// list pages through the entries of a folder with the given tag
+func (d *Dropbox) list(ctx context.Context, accessToken, folder, tag string) ([]Entry, error) {
+	type page struct {
+		Entries []struct {
+			Tag            string    `json:".tag"`
+			ID             string    `json:"id"`
+			Name           string    `json:"name"`
+			Size           int64     `json:"size"`
+			ServerModified time.Time `json:"server_modified"`
+		} `json:"entries"`
+		Cursor  string `json:"cursor"`
+		HasMore bool   `json:"has_more"`
+	}
+
+	var current page
+	if err := d.call(ctx, accessToken, "/files/list_folder", map[string]any{"path": folder, "limit": 2000}, &current); err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for {
+		for _, entry := range current.Entries {
+			if entry.Tag == tag {
+				entries = append(entries, Entry{ID: entry.ID, Name: entry.Name, Size: entry.Size, ModifiedAt: entry.ServerModified})
+			}
+		}
+		if !current.HasMore {
+			return entries, nil
+		}
+
+		cursor := current.Cursor
+		current = page{}
+		if err := d.call(ctx, accessToken, "/files/list_folder/continue", map[string]any{"cursor": cursor}, &current); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// call posts a JSON RPC request to the API; a nil body sends none, as
+// endpoints without arguments require
+func (d *Dropbox) call(ctx context.Context, accessToken, endpoint string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", dropboxAPI+endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return doJSON(d.app.httpClient, req, out)
+}
@@ -0,0 +1,161 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	googleDriveAPI = "https://www.googleapis.com/drive/v3"
+	// googleScope grants read access to the files of the account
+	googleScope = "https://www.googleapis.com/auth/drive.readonly"
+	// googleFolderType is the MIME type Drive gives folders; other
+	// application/vnd.google-apps.* types are native documents with no content
+	// to download
+	googleFolderType = "application/vnd.google-apps.folder"
+	googleNativeType = "application/vnd.google-apps."
+)
+
+// GoogleDrive imports files from Google Drive through the Drive v3 API
+type GoogleDrive struct {
+	app oauthApp
+}
+
+// NewGoogleDrive creates a new Google Drive provider
+func NewGoogleDrive(clientID, clientSecret string, httpClient *http.Client) *GoogleDrive {
+	return &GoogleDrive{app: oauthApp{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		tokenURL:     googleTokenURL,
+		httpClient:   httpClient,
+	}}
+}
+
+// AuthCodeURL asks for offline access, so a refresh token is issued, and for
+// consent every time, so it is issued again when the account is relinked
+func (g *GoogleDrive) AuthCodeURL(redirectURL, state string) string {
+	params := url.Values{}
+	params.Set("client_id", g.app.clientID)
+	params.Set("redirect_uri", redirectURL)
+	params.Set("response_type", "code")
+	params.Set("scope", googleScope)
+	params.Set("access_type", "offline")
+	params.Set("prompt", "consent")
+	params.Set("state", state)
+	return googleAuthURL + "?" + params.Encode()
+}
+
+// Exchange trades an authorization code for tokens
+func (g *GoogleDrive) Exchange(ctx context.Context, redirectURL, code string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	return g.app.token(ctx, form)
+}
+
+// Refresh gets a new access token; Google keeps the refresh token unchanged
+func (g *GoogleDrive) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	return g.app.token(ctx, form)
+}
+
+// Account returns the email address of the Drive's owner
+func (g *GoogleDrive) Account(ctx context.Context, accessToken string) (string, error) {
+	var about struct {
+		User struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"user"`
+	}
+	if err := g.get(ctx, accessToken, googleDriveAPI+"/about?fields=user(emailAddress)", &about); err != nil {
+		return "", err
+	}
+	return about.User.EmailAddress, nil
+}
+
+// ListFolders lists the folders inside parent, or inside My Drive
+func (g *GoogleDrive) ListFolders(ctx context.Context, accessToken, parent string) ([]Entry, error) {
+	return g.list(ctx, accessToken, parent, "mimeType = '"+googleFolderType+"'")
+}
+
+// ListFiles lists the files inside folder that have content to download
+func (g *GoogleDrive) ListFiles(ctx context.Context, accessToken, folder string) ([]Entry, error) {
+	return g.list(ctx, accessToken, folder, "not mimeType contains '"+googleNativeType+"'")
+}
+
+// Download opens the content of a file
+func (g *GoogleDrive) Download(ctx context.Context, accessToken, fileID string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", googleDriveAPI+"/files/"+url.PathEscape(fileID)+"?alt=media", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := do(g.app.httpClient, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// list pages through the children of a folder matching a Drive query
+func (g *GoogleDrive) list(ctx context.Context, accessToken, parent, filter string) ([]Entry, error) {
+	if parent == "" {
+		parent = "root"
+	}
+	query := fmt.Sprintf("'%s' in parents and trashed = false and %s", strings.ReplaceAll(parent, "'", `\'`), filter)
+
+	entries := []Entry{}
+	pageToken := ""
+	for {
+		params := url.Values{}
+		params.Set("q", query)
+		params.Set("fields", "nextPageToken,files(id,name,size,modifiedTime)")
+		params.Set("pageSize", "1000")
+		params.Set("orderBy", "createdTime")
+		if pageToken != "" {
+			params.Set("pageToken", pageToken)
+		}
+
+		var page struct {
+			NextPageToken string `json:"nextPageToken"`
+			Files         []struct {
+				ID           string    `json:"id"`
+				Name         string    `json:"name"`
+				Size         string    `json:"size"`
+				ModifiedTime time.Time `json:"modifiedTime"`
+			} `json:"files"`
+		}
+		if err := g.get(ctx, accessToken, googleDriveAPI+"/files?"+params.Encode(), &page); err != nil {
+			return nil, err
+		}
+
+		for _, file := range page.Files {
+			size, _ := strconv.ParseInt(file.Size, 10, 64)
+			entries = append(entries, Entry{ID: file.ID, Name: file.Name, Size: size, ModifiedAt: file.ModifiedTime})
+		}
+		if page.NextPageToken == "" {
+			return entries, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (g *GoogleDrive) get(ctx context.Context, accessToken, endpoint string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return doJSON(g.app.httpClient, req, out)
+}
@@ -0,0 +1,18 @@
+-- Word and line-level bounding boxes for OCR results
+
+CREATE TABLE IF NOT EXISTS result_regions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    result_id UUID REFERENCES ocr_results(id) ON DELETE CASCADE,
+    page_number INTEGER NOT NULL DEFAULT 1,
+    level VARCHAR(20) NOT NULL CHECK (level IN ('word', 'line')),
+    sequence INTEGER NOT NULL DEFAULT 0,
+    text TEXT,
+    x0 FLOAT NOT NULL,
+    y0 FLOAT NOT NULL,
+    x1 FLOAT NOT NULL,
+    y1 FLOAT NOT NULL,
+    confidence FLOAT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_result_regions_result_page ON result_regions(result_id, page_number);
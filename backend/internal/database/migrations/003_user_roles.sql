@@ -0,0 +1,6 @@
+-- User roles for administrative access
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'admin'));
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
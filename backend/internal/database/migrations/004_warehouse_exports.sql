@@ -0,0 +1,12 @@
+-- Incremental cursors for the data warehouse export of job/result metadata
+
+CREATE TABLE IF NOT EXISTS warehouse_export_cursors (
+    name VARCHAR(100) PRIMARY KEY,
+    schema_version INTEGER NOT NULL,
+    cursor_at TIMESTAMP,
+    cursor_id UUID,
+    rows_exported BIGINT NOT NULL DEFAULT 0,
+    last_file VARCHAR(500),
+    last_run_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,18 @@
+-- Outbox of domain events awaiting publication to the message bus
+
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    user_id UUID,
+    data JSONB,
+    occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_event_outbox_pending ON event_outbox(next_attempt_at) WHERE published_at IS NULL;
+CREATE INDEX idx_event_outbox_occurred_at ON event_outbox(occurred_at DESC);
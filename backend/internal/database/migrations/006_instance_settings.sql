@@ -0,0 +1,7 @@
+-- Instance-wide settings configured through the setup wizard
+
+CREATE TABLE IF NOT EXISTS instance_settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+-- Free-form document metadata, such as the filename exactly as the client sent
+-- it when original_filename holds a normalized version
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+
+-- Speeds up finding filename collisions within a user's documents
+CREATE INDEX IF NOT EXISTS idx_documents_user_original_filename ON documents(user_id, original_filename);
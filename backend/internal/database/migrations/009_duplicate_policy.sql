@@ -0,0 +1,4 @@
+-- Per-user choice of what uploading an already stored file does
+
+ALTER TABLE user_settings
+    ADD COLUMN IF NOT EXISTS duplicate_policy VARCHAR(20) NOT NULL DEFAULT 'return_existing';
@@ -0,0 +1,51 @@
+-- Store every timestamp as TIMESTAMPTZ so values no longer depend on the time
+-- zone of the host that wrote them. Existing values are read as UTC; if the API
+-- ran with another local time zone, run
+--   ALTER DATABASE ocr_db SET visekai.legacy_timezone = 'Europe/Berlin';
+-- before this migration to convert from that zone instead.
+
+DO $$
+DECLARE
+    legacy_tz TEXT := COALESCE(NULLIF(current_setting('visekai.legacy_timezone', TRUE), ''), 'UTC');
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name
+        FROM information_schema.columns
+        WHERE table_schema = current_schema()
+          AND data_type = 'timestamp without time zone'
+          AND (table_name, column_name) IN (
+              ('users', 'created_at'),
+              ('users', 'updated_at'),
+              ('documents', 'uploaded_at'),
+              ('documents', 'deleted_at'),
+              ('ocr_jobs', 'created_at'),
+              ('ocr_jobs', 'started_at'),
+              ('ocr_jobs', 'completed_at'),
+              ('ocr_results', 'created_at'),
+              ('job_logs', 'created_at'),
+              ('user_settings', 'created_at'),
+              ('user_settings', 'updated_at'),
+              ('api_keys', 'last_used_at'),
+              ('api_keys', 'expires_at'),
+              ('api_keys', 'created_at'),
+              ('result_regions', 'created_at'),
+              ('warehouse_export_cursors', 'cursor_at'),
+              ('warehouse_export_cursors', 'last_run_at'),
+              ('warehouse_export_cursors', 'updated_at'),
+              ('event_outbox', 'occurred_at'),
+              ('event_outbox', 'published_at'),
+              ('event_outbox', 'next_attempt_at'),
+              ('instance_settings', 'updated_at'),
+              ('audit_events', 'created_at')
+          )
+    LOOP
+        EXECUTE format(
+            'ALTER TABLE %I ALTER COLUMN %I TYPE TIMESTAMPTZ USING %I AT TIME ZONE %L',
+            col.table_name, col.column_name, col.column_name, legacy_tz
+        );
+    END LOOP;
+END $$;
+
+-- User time zone (IANA name) for digest emails and exports; API timestamps stay UTC
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,19 @@
+-- Per-page OCR text, so single pages can be reprocessed and spliced into a
+-- result. Every reprocess adds a row with the next version; the highest
+-- version of each page is current and earlier ones are kept as history.
+
+ALTER TABLE ocr_results ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE ocr_results ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS result_pages (
+    result_id UUID NOT NULL REFERENCES ocr_results(id) ON DELETE CASCADE,
+    page_number INTEGER NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    job_id UUID REFERENCES ocr_jobs(id) ON DELETE SET NULL,
+    resolution_mode VARCHAR(20),
+    raw_text TEXT NOT NULL DEFAULT '',
+    markdown_text TEXT NOT NULL DEFAULT '',
+    confidence_score FLOAT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (result_id, page_number, version)
+);
@@ -0,0 +1,18 @@
+-- Jobs the organization's OCR policy holds for an approver before they are
+-- queued. Each held job has one approval request, decided at most once.
+
+ALTER TABLE ocr_jobs DROP CONSTRAINT IF EXISTS ocr_jobs_status_check;
+ALTER TABLE ocr_jobs ADD CONSTRAINT ocr_jobs_status_check
+    CHECK (status IN ('awaiting_approval', 'pending', 'processing', 'completed', 'failed', 'cancelled'));
+
+CREATE TABLE IF NOT EXISTS job_approvals (
+    job_id UUID PRIMARY KEY REFERENCES ocr_jobs(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    decision VARCHAR(20) CHECK (decision IN ('approved', 'rejected')),
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMPTZ,
+    comment TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_approvals_undecided ON job_approvals(requested_at) WHERE decision IS NULL;
@@ -0,0 +1,9 @@
+-- Labels on documents and jobs, and the labels an API key is restricted to.
+-- A key with labels only sees documents and jobs carrying at least one of them.
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE ocr_jobs ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_documents_labels ON documents USING GIN (labels);
+CREATE INDEX IF NOT EXISTS idx_ocr_jobs_labels ON ocr_jobs USING GIN (labels);
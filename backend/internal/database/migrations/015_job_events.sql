@@ -0,0 +1,54 @@
+-- Timeline of each job's lifecycle. A trigger records the events, so every
+-- change to a job is captured whichever code path makes it.
+
+CREATE TABLE IF NOT EXISTS job_events (
+    id BIGSERIAL PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES ocr_jobs(id) ON DELETE CASCADE,
+    event_type VARCHAR(20) NOT NULL
+        CHECK (event_type IN ('created', 'queued', 'started', 'progress', 'retried', 'completed', 'failed', 'cancelled')),
+    status VARCHAR(50) NOT NULL,
+    progress_percentage INTEGER NOT NULL DEFAULT 0,
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    message TEXT,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_events_job_id ON job_events(job_id, id);
+
+CREATE OR REPLACE FUNCTION record_job_event()
+RETURNS TRIGGER AS $$
+DECLARE
+    new_event VARCHAR(20);
+    new_message TEXT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        new_event := 'created';
+    ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+        new_event := CASE NEW.status
+            WHEN 'processing' THEN 'started'
+            WHEN 'completed' THEN 'completed'
+            WHEN 'failed' THEN 'failed'
+            WHEN 'cancelled' THEN 'cancelled'
+            WHEN 'pending' THEN CASE WHEN OLD.status = 'awaiting_approval' THEN 'queued' ELSE 'retried' END
+        END;
+        -- Failures and cancellations carry their reason, retries the error of
+        -- the attempt that failed
+        IF new_event IN ('failed', 'cancelled', 'retried') THEN
+            new_message := NEW.error_message;
+        END IF;
+    ELSIF NEW.progress_percentage IS DISTINCT FROM OLD.progress_percentage THEN
+        new_event := 'progress';
+    END IF;
+
+    IF new_event IS NOT NULL THEN
+        INSERT INTO job_events (job_id, event_type, status, progress_percentage, retry_count, message)
+        VALUES (NEW.id, new_event, NEW.status, COALESCE(NEW.progress_percentage, 0), COALESCE(NEW.retry_count, 0), new_message);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS record_ocr_jobs_event ON ocr_jobs;
+CREATE TRIGGER record_ocr_jobs_event AFTER INSERT OR UPDATE ON ocr_jobs
+    FOR EACH ROW EXECUTE FUNCTION record_job_event();
@@ -0,0 +1,19 @@
+-- Temporary access a user grants another user to a set of their documents,
+-- e.g. for a review. Grants stop working at expires_at or when revoked.
+
+CREATE TABLE IF NOT EXISTS access_grants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    grantee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_ids UUID[] NOT NULL,
+    note TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMPTZ,
+    reminder_sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_access_grants_owner_id ON access_grants(owner_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_access_grants_grantee_id ON access_grants(grantee_id, expires_at);
+CREATE INDEX IF NOT EXISTS idx_access_grants_reminder ON access_grants(expires_at)
+    WHERE revoked_at IS NULL AND reminder_sent_at IS NULL;
@@ -0,0 +1,8 @@
+-- Quality assessment of each document's latest full OCR run, with the score
+-- in its own column for filtering and sorting
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS quality JSONB;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS quality_score SMALLINT;
+
+CREATE INDEX IF NOT EXISTS idx_documents_quality_score ON documents(user_id, quality_score)
+    WHERE deleted_at IS NULL;
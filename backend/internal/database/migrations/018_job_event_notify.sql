@@ -0,0 +1,42 @@
+-- Announce each job event on the job_events channel so API instances can
+-- stream them to clients. The payload carries only the event ID and the job's
+-- owner; listeners read the event itself. Notifications are delivered on
+-- commit, so listeners never see events of rolled back changes.
+
+CREATE OR REPLACE FUNCTION record_job_event()
+RETURNS TRIGGER AS $$
+DECLARE
+    new_event VARCHAR(20);
+    new_message TEXT;
+    new_id BIGINT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        new_event := 'created';
+    ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+        new_event := CASE NEW.status
+            WHEN 'processing' THEN 'started'
+            WHEN 'completed' THEN 'completed'
+            WHEN 'failed' THEN 'failed'
+            WHEN 'cancelled' THEN 'cancelled'
+            WHEN 'pending' THEN CASE WHEN OLD.status = 'awaiting_approval' THEN 'queued' ELSE 'retried' END
+        END;
+        -- Failures and cancellations carry their reason, retries the error of
+        -- the attempt that failed
+        IF new_event IN ('failed', 'cancelled', 'retried') THEN
+            new_message := NEW.error_message;
+        END IF;
+    ELSIF NEW.progress_percentage IS DISTINCT FROM OLD.progress_percentage THEN
+        new_event := 'progress';
+    END IF;
+
+    IF new_event IS NOT NULL THEN
+        INSERT INTO job_events (job_id, event_type, status, progress_percentage, retry_count, message)
+        VALUES (NEW.id, new_event, NEW.status, COALESCE(NEW.progress_percentage, 0), COALESCE(NEW.retry_count, 0), new_message)
+        RETURNING id INTO new_id;
+
+        PERFORM pg_notify('job_events', json_build_object('id', new_id, 'user_id', NEW.user_id)::text);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
@@ -0,0 +1,45 @@
+-- Ledger of each user's OCR credits. The balance is the sum of the amounts:
+-- monthly allotments add credits, jobs are charged when submitted and
+-- adjusted to the pages actually processed, and failed or cancelled jobs are
+-- refunded.
+
+CREATE TABLE IF NOT EXISTS credit_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('allotment', 'charge', 'adjustment', 'refund')),
+    amount INTEGER NOT NULL,
+    job_id UUID REFERENCES ocr_jobs(id) ON DELETE SET NULL,
+    -- Month an allotment is for
+    period DATE,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_transactions_user_id ON credit_transactions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_credit_transactions_job_id ON credit_transactions(job_id) WHERE job_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_transactions_allotment ON credit_transactions(user_id, period)
+    WHERE kind = 'allotment';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_transactions_refund ON credit_transactions(job_id)
+    WHERE kind = 'refund';
+
+-- Refund what a job was charged when it fails or is cancelled, whichever code
+-- path fails it. A job is refunded once, even if it is requeued and fails again.
+CREATE OR REPLACE FUNCTION refund_job_credits()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.status IN ('failed', 'cancelled') AND NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO credit_transactions (user_id, kind, amount, job_id, description)
+        SELECT NEW.user_id, 'refund', -SUM(amount), NEW.id, 'Job ' || NEW.status
+        FROM credit_transactions
+        WHERE job_id = NEW.id AND kind IN ('charge', 'adjustment')
+        HAVING SUM(amount) < 0
+        ON CONFLICT DO NOTHING;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS refund_ocr_jobs_credits ON ocr_jobs;
+CREATE TRIGGER refund_ocr_jobs_credits AFTER UPDATE OF status ON ocr_jobs
+    FOR EACH ROW EXECUTE FUNCTION refund_job_credits();
@@ -0,0 +1,10 @@
+-- What each page version cost: how long the OCR service took on it, which
+-- backend processed it and the credits charged for it. Rows written before
+-- these were recorded, and pages copied from a teammate's result, leave them
+-- NULL.
+
+ALTER TABLE result_pages ADD COLUMN IF NOT EXISTS processing_time_ms INTEGER;
+ALTER TABLE result_pages ADD COLUMN IF NOT EXISTS backend VARCHAR(255);
+ALTER TABLE result_pages ADD COLUMN IF NOT EXISTS credits INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_result_pages_job_id ON result_pages(job_id);
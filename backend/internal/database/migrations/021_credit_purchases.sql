@@ -0,0 +1,11 @@
+-- Credits bought through the billing provider. A purchase references the
+-- provider's checkout session, so a payment delivered twice is credited once.
+
+ALTER TABLE credit_transactions DROP CONSTRAINT IF EXISTS credit_transactions_kind_check;
+ALTER TABLE credit_transactions ADD CONSTRAINT credit_transactions_kind_check
+    CHECK (kind IN ('allotment', 'charge', 'adjustment', 'refund', 'purchase'));
+
+ALTER TABLE credit_transactions ADD COLUMN IF NOT EXISTS reference VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_credit_transactions_purchase ON credit_transactions(reference)
+    WHERE kind = 'purchase';
@@ -0,0 +1,19 @@
+-- Typed links between a user's documents, such as a delivery note that
+-- references an invoice or a scan that is part of a larger document. Links
+-- are directed from source to target; position orders the parts of a whole.
+
+CREATE TABLE IF NOT EXISTS document_links (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    relation VARCHAR(50) NOT NULL,
+    position INTEGER,
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (source_id <> target_id),
+    UNIQUE (source_id, target_id, relation)
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_links_source_id ON document_links(source_id);
+CREATE INDEX IF NOT EXISTS idx_document_links_target_id ON document_links(target_id);
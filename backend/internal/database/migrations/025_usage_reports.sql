@@ -0,0 +1,14 @@
+-- Monthly usage reports for capacity planning. The full report, with a row
+-- per organization, is stored as a JSON file; this table indexes the reports
+-- and keeps the instance totals so they can be listed without reading files.
+
+CREATE TABLE IF NOT EXISTS usage_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    period_start TIMESTAMPTZ NOT NULL UNIQUE,
+    period_end TIMESTAMPTZ NOT NULL,
+    totals JSONB NOT NULL,
+    peak_queue_depth INTEGER NOT NULL DEFAULT 0,
+    peak_queue_at TIMESTAMPTZ,
+    file_path VARCHAR(500) NOT NULL,
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
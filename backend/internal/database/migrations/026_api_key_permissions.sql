@@ -0,0 +1,4 @@
+-- Permissions an API key is narrowed to, such as documents:read or jobs:*.
+-- A key without permissions holds every permission of its user's role.
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS permissions TEXT[] NOT NULL DEFAULT '{}';
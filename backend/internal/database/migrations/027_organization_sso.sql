@@ -0,0 +1,32 @@
+-- Single sign-on through an organization's OpenID Connect identity provider.
+-- Users signing in for the first time get an account and a membership whose
+-- role is mapped from their IdP groups; sso_identities remembers which IdP
+-- subject is which user.
+
+CREATE TABLE IF NOT EXISTS organization_sso (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    issuer VARCHAR(500) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    client_secret TEXT NOT NULL,
+    email_domains TEXT[] NOT NULL DEFAULT '{}',
+    group_claim VARCHAR(100) NOT NULL DEFAULT 'groups',
+    role_mapping JSONB NOT NULL DEFAULT '{}',
+    default_role VARCHAR(20) CHECK (default_role IN ('owner', 'member', 'viewer')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_sso_email_domains ON organization_sso USING GIN (email_domains);
+
+CREATE TABLE IF NOT EXISTS sso_identities (
+    issuer VARCHAR(500) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMPTZ,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sso_identities_user_id ON sso_identities(user_id);
@@ -0,0 +1,7 @@
+-- Users deleting their account keep it for a grace period, during which they
+-- can export their data or cancel; the janitor deletes it afterwards.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users(deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
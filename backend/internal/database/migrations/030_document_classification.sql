@@ -0,0 +1,8 @@
+-- Type detected for each document after OCR, with the type in its own column
+-- for filtering
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS classification JSONB;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS document_type VARCHAR(32);
+
+CREATE INDEX IF NOT EXISTS idx_documents_document_type ON documents(user_id, document_type)
+    WHERE deleted_at IS NULL;
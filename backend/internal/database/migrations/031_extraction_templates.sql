@@ -0,0 +1,14 @@
+-- Users' rules for extracting named fields from the results of a type of
+-- document, applied by the worker when a job completes
+
+CREATE TABLE IF NOT EXISTS extraction_templates (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    document_type VARCHAR(32),
+    fields JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_extraction_templates_user_id ON extraction_templates(user_id, created_at DESC);
@@ -0,0 +1,24 @@
+-- REST hook subscriptions of no-code tools such as Zapier or Make. Each hook
+-- remembers the last result delivered to it, so results are sent in order and
+-- none is skipped while its target is down.
+
+CREATE TABLE IF NOT EXISTS integration_hooks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    target_url TEXT NOT NULL,
+    labels TEXT[],
+    cursor_at TIMESTAMPTZ NOT NULL,
+    cursor_id UUID NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_delivered_at TIMESTAMPTZ,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_integration_hooks_user_id ON integration_hooks(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_integration_hooks_next_attempt_at ON integration_hooks(next_attempt_at);
+
+-- Results are read in (created_at, id) order by polling triggers and hooks
+CREATE INDEX IF NOT EXISTS idx_ocr_results_created_at_id ON ocr_results(created_at, id);
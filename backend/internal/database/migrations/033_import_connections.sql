@@ -0,0 +1,36 @@
+-- Cloud storage accounts users linked to import files from, and the remote
+-- files already imported from each, so no file is imported twice
+
+CREATE TABLE IF NOT EXISTS import_connections (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(32) NOT NULL,
+    account VARCHAR(255) NOT NULL DEFAULT '',
+    access_token TEXT NOT NULL,
+    refresh_token TEXT NOT NULL DEFAULT '',
+    token_expiry TIMESTAMPTZ,
+    folder_id TEXT,
+    folder_name TEXT,
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_sync_at TIMESTAMPTZ,
+    last_error TEXT,
+    next_sync_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_connections_user_id ON import_connections(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_import_connections_next_sync_at ON import_connections(next_sync_at)
+    WHERE enabled AND folder_id IS NOT NULL;
+
+-- A file is recorded once it is imported, or once it is skipped for good
+-- because it is not a supported document
+CREATE TABLE IF NOT EXISTS imported_files (
+    connection_id UUID NOT NULL REFERENCES import_connections(id) ON DELETE CASCADE,
+    remote_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    document_id UUID REFERENCES documents(id) ON DELETE SET NULL,
+    skipped_reason TEXT,
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (connection_id, remote_id)
+);
@@ -0,0 +1,26 @@
+-- Email-to-OCR ingestion: each user's secret inbound address, and the emails
+-- received at them, with the documents and jobs they created so the sender
+-- can be replied to once processing finishes
+
+CREATE TABLE IF NOT EXISTS inbound_email_addresses (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS inbound_emails (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_id TEXT NOT NULL DEFAULT '',
+    sender TEXT NOT NULL DEFAULT '',
+    subject TEXT NOT NULL DEFAULT '',
+    document_ids UUID[] NOT NULL DEFAULT '{}',
+    job_ids UUID[] NOT NULL DEFAULT '{}',
+    skipped TEXT[] NOT NULL DEFAULT '{}',
+    replied_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Mail providers retry deliveries; a message is ingested once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inbound_emails_message_id ON inbound_emails(user_id, message_id) WHERE message_id <> '';
+CREATE INDEX IF NOT EXISTS idx_inbound_emails_unreplied ON inbound_emails(created_at) WHERE replied_at IS NULL;
@@ -0,0 +1,27 @@
+-- Slack and Microsoft Teams channels users are notified in when their jobs
+-- complete or fail. Like REST hooks, each channel remembers the last job it
+-- was notified of, so no job is skipped while its webhook is down.
+
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    webhook_url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    labels TEXT[],
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    cursor_at TIMESTAMPTZ NOT NULL,
+    cursor_id UUID NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_delivered_at TIMESTAMPTZ,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_channels_user_id ON notification_channels(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notification_channels_next_attempt_at ON notification_channels(next_attempt_at) WHERE enabled;
+
+-- Finished jobs are read in (completed_at, id) order per user
+CREATE INDEX IF NOT EXISTS idx_ocr_jobs_user_completed_at ON ocr_jobs(user_id, completed_at, id) WHERE completed_at IS NOT NULL;
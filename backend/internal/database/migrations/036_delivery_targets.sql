@@ -0,0 +1,40 @@
+-- Buckets and SFTP servers users have completed results pushed to. Each target
+-- remembers the last job it was given, and each job it is given gets a
+-- delivery tracking whether its files arrived.
+
+CREATE TABLE IF NOT EXISTS delivery_targets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    settings JSONB NOT NULL DEFAULT '{}',
+    -- Passwords and secret keys, sealed with DELIVERY_ENCRYPTION_KEY
+    credentials TEXT NOT NULL DEFAULT '',
+    formats TEXT[] NOT NULL,
+    labels TEXT[],
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    cursor_at TIMESTAMPTZ NOT NULL,
+    cursor_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_targets_user_id ON delivery_targets(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS result_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    target_id UUID NOT NULL REFERENCES delivery_targets(id) ON DELETE CASCADE,
+    job_id UUID NOT NULL REFERENCES ocr_jobs(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    files TEXT[],
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (target_id, job_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_result_deliveries_job_id ON result_deliveries(job_id);
+CREATE INDEX IF NOT EXISTS idx_result_deliveries_pending ON result_deliveries(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,6 @@
+-- Content-addressed files are shared by every document with the same content,
+-- so a file is only deleted once no document refers to its path any more.
+-- Index the paths so those reference checks stay cheap.
+
+CREATE INDEX IF NOT EXISTS idx_documents_file_path ON documents(file_path);
+CREATE INDEX IF NOT EXISTS idx_documents_thumbnail_path ON documents(thumbnail_path) WHERE thumbnail_path IS NOT NULL;
@@ -0,0 +1,24 @@
+-- Storage used by each user and by the instance, recomputed periodically so
+-- the admin storage dashboard never walks the disk or scans every document
+-- on a request.
+
+CREATE TABLE IF NOT EXISTS storage_stats (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    documents INTEGER NOT NULL DEFAULT 0,
+    trashed_documents INTEGER NOT NULL DEFAULT 0,
+    files INTEGER NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    oldest_uploaded_at TIMESTAMPTZ,
+    computed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_storage_stats_bytes ON storage_stats(bytes DESC);
+
+-- The instance totals of the last computation; a single row
+CREATE TABLE IF NOT EXISTS storage_stats_summary (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    totals JSONB NOT NULL,
+    oldest_files JSONB NOT NULL DEFAULT '[]',
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    computed_at TIMESTAMPTZ NOT NULL
+);
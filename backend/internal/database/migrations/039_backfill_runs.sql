@@ -0,0 +1,26 @@
+-- Runs of backfill tasks, data migrations admins start over existing data.
+-- A run goes through the data in batches, saving its cursor and counts after
+-- each one, so a run interrupted by a restart or a failure resumes where it
+-- stopped. Each task has at most one pending or running run at a time.
+
+CREATE TABLE IF NOT EXISTS backfill_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    cursor TEXT NOT NULL DEFAULT '',
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMPTZ,
+    heartbeat_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_backfill_runs_task ON backfill_runs(task, created_at DESC);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_backfill_runs_active ON backfill_runs(task)
+    WHERE status IN ('pending', 'running');
@@ -0,0 +1,18 @@
+-- Deleted jobs and their results are kept in the trash until their user's
+-- retention elapses instead of being deleted at once, and jobs can be
+-- archived to hide them from the default job listings. Users may set their
+-- own trash retention and have finished jobs archived after a number of days.
+
+ALTER TABLE ocr_jobs
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+ALTER TABLE ocr_results
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_ocr_jobs_deleted_at ON ocr_jobs(deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE user_settings
+    ADD COLUMN IF NOT EXISTS job_trash_retention_days INTEGER,
+    ADD COLUMN IF NOT EXISTS archive_jobs_after_days INTEGER;
@@ -0,0 +1,23 @@
+-- Per-user retention policies: how many days each kind of data is kept
+-- before the retention worker deletes it. Users are warned ahead of every
+-- deletion; warned_until is how far ahead items were already warned about,
+-- so each item is announced once.
+
+CREATE TABLE IF NOT EXISTS retention_policies (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target VARCHAR(30) NOT NULL,
+    days INTEGER NOT NULL CHECK (days > 0),
+    warned_until TIMESTAMPTZ,
+    warned_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, target)
+);
+
+-- Results whose text was deleted keep their metadata and extracted fields
+ALTER TABLE ocr_results ADD COLUMN IF NOT EXISTS text_deleted_at TIMESTAMPTZ;
+
+-- Existing notification channels are told about upcoming deletions too
+UPDATE notification_channels
+SET events = array_append(events, 'retention.warning')
+WHERE NOT 'retention.warning' = ANY(events);
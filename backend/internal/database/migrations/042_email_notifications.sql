@@ -0,0 +1,26 @@
+-- Email notifications: an email as soon as a job fails, and a daily digest of
+-- the jobs of the past day sent at the user's chosen hour
+
+ALTER TABLE user_settings
+    ADD COLUMN IF NOT EXISTS email_on_failure BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS email_digest BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS digest_hour SMALLINT NOT NULL DEFAULT 8 CHECK (digest_hour BETWEEN 0 AND 23);
+
+-- Set once a failure was emailed; a job failing again after a retry is
+-- emailed again
+ALTER TABLE ocr_jobs ADD COLUMN IF NOT EXISTS failure_emailed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_ocr_jobs_failed_unemailed
+    ON ocr_jobs(completed_at)
+    WHERE status = 'failed' AND deleted_at IS NULL;
+
+-- Users who get a digest, with the period the next one covers: from
+-- period_start until next_at, when it is sent
+CREATE TABLE IF NOT EXISTS email_digests (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    period_start TIMESTAMPTZ NOT NULL,
+    next_at TIMESTAMPTZ NOT NULL,
+    last_sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_digests_next_at ON email_digests(next_at);
@@ -0,0 +1,13 @@
+-- Asymmetric keys signing access and refresh tokens. The newest unretired key
+-- signs; retired keys keep verifying the tokens they signed until those
+-- expire. Private keys are stored sealed.
+
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    id TEXT PRIMARY KEY,
+    algorithm TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    retired_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_jwt_signing_keys_created_at ON jwt_signing_keys(created_at);
@@ -0,0 +1,29 @@
+-- Email domains route sign-ins to an organization's IdP only once the
+-- organization proves it controls them with a DNS TXT record, and a verified
+-- domain belongs to one organization. Domains configured before are kept
+-- unverified. Client secrets are sealed; those stored in plaintext are sealed
+-- at startup.
+
+CREATE TABLE IF NOT EXISTS organization_sso_domains (
+    organization_id UUID NOT NULL REFERENCES organization_sso(organization_id) ON DELETE CASCADE,
+    domain VARCHAR(255) NOT NULL,
+    verification_record VARCHAR(300) NOT NULL,
+    verification_value VARCHAR(100) NOT NULL,
+    verified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, domain)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_sso_domains_verified
+    ON organization_sso_domains(domain) WHERE verified_at IS NOT NULL;
+
+INSERT INTO organization_sso_domains (organization_id, domain, verification_record, verification_value)
+SELECT s.organization_id, d.domain, '_visekai-verification.' || d.domain,
+       'visekai-verification=' || md5(random()::text || clock_timestamp()::text || d.domain)
+FROM organization_sso s, unnest(s.email_domains) AS d(domain)
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_organization_sso_email_domains;
+ALTER TABLE organization_sso DROP COLUMN IF EXISTS email_domains;
+
+ALTER TABLE organization_sso ADD COLUMN IF NOT EXISTS client_secret_sealed BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,31 @@
+-- Each organization sets its own OCR policy. Jobs on documents shared with an
+-- organization, or submitted by its members, follow that organization's
+-- policy; users outside any organization keep the instance-wide policy.
+-- Existing organizations start from the instance-wide policy, keeping only
+-- the approvers who are members.
+
+CREATE TABLE IF NOT EXISTS organization_ocr_policies (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    policy JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO organization_ocr_policies (organization_id, policy)
+SELECT o.id,
+       s.value::jsonb || jsonb_build_object('approvers', COALESCE((
+           SELECT jsonb_agg(a.id)
+           FROM jsonb_array_elements_text(CASE
+               WHEN jsonb_typeof(s.value::jsonb -> 'approvers') = 'array' THEN s.value::jsonb -> 'approvers'
+               ELSE '[]'::jsonb
+           END) AS a(id)
+           JOIN organization_members m ON m.organization_id = o.id AND m.user_id::text = a.id
+       ), '[]'::jsonb))
+FROM organizations o
+JOIN instance_settings s ON s.key = 'ocr_policy'
+ON CONFLICT (organization_id) DO NOTHING;
+
+-- Held jobs are decided by the approvers of the organization whose policy held
+-- them; jobs held by the instance-wide policy have none
+ALTER TABLE job_approvals ADD COLUMN IF NOT EXISTS organization_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_job_approvals_organization_id ON job_approvals(organization_id) WHERE decision IS NULL;
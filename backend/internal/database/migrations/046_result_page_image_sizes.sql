@@ -0,0 +1,6 @@
+-- The size of the page image the OCR service read, which region boxes are
+-- measured in. Searchable PDFs scale the boxes by it onto the page; pages
+-- recorded before it was reported are taken as rendered at a fixed resolution.
+
+ALTER TABLE result_pages ADD COLUMN IF NOT EXISTS image_width INTEGER;
+ALTER TABLE result_pages ADD COLUMN IF NOT EXISTS image_height INTEGER;
@@ -0,0 +1,15 @@
+-- Each organization sets its own branding and custom domain. Shared documents
+-- and exports are branded by the organization the document is shared with, or
+-- the owner's; users outside any organization keep the instance-wide branding.
+-- A verified custom domain belongs to one organization, so links opened on it
+-- are branded by that organization.
+
+CREATE TABLE IF NOT EXISTS organization_branding (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    branding JSONB NOT NULL,
+    verified_domain VARCHAR(253),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_branding_verified_domain
+    ON organization_branding(verified_domain);
@@ -3,17 +3,23 @@ package database
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"visekai/backend/internal/config"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type DB struct {
 	Pool *pgxpool.Pool
+	// Replica is the read replica pool, or nil when none is configured
+	Replica *pgxpool.Pool
 }
 
-func New(cfg *config.Config) (*DB, error) {
+// New connects to the database and the read replica when one is configured,
+// giving up when ctx is done
+func New(ctx context.Context, cfg *config.Config) (*DB, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.DBHost,
@@ -24,28 +30,76 @@ func New(cfg *config.Config) (*DB, error) {
 		cfg.DBSSLMode,
 	)
 
+	pool, err := connect(ctx, cfg, dsn, cfg.DatabaseCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{Pool: pool}
+	if cfg.DBReplicaDSN != "" {
+		db.Replica, err = connect(ctx, cfg, cfg.DBReplicaDSN, nil)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("read replica: %w", err)
+		}
+	}
+
+	return db, nil
+}
+
+// connect opens a connection pool tuned by the configuration and checks it
+// works. New connections log in with credentials, when set, so they pick up
+// credentials renewed after the pool was opened.
+func connect(ctx context.Context, cfg *config.Config, dsn string, credentials func() (user, password string)) (*pgxpool.Pool, error) {
 	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database config: %w", err)
 	}
 
+	// Have the server interpret and render timestamps in UTC
+	poolConfig.ConnConfig.RuntimeParams["timezone"] = "UTC"
+	if cfg.DBStatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.DBStatementTimeout.Milliseconds(), 10)
+	}
+
+	if credentials != nil {
+		poolConfig.BeforeConnect = func(ctx context.Context, connConfig *pgx.ConnConfig) error {
+			connConfig.User, connConfig.Password = credentials()
+			return nil
+		}
+	}
+
 	// Connection pool settings
-	poolConfig.MaxConns = 25
-	poolConfig.MinConns = 5
+	poolConfig.MaxConns = int32(cfg.DBMaxConns)
+	poolConfig.MinConns = int32(cfg.DBMinConns)
+	// A zero duration keeps the pgx default
+	if cfg.DBMaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.DBMaxConnLifetime
+	}
+	if cfg.DBMaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.DBMaxConnIdleTime
+	}
+	if cfg.DBHealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.DBHealthCheckPeriod
+	}
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
 
 	// Test connection
-	if err := pool.Ping(context.Background()); err != nil {
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("unable to ping database: %w", err)
 	}
 
-	return &DB{Pool: pool}, nil
+	return pool, nil
 }
 
 func (db *DB) Close() {
 	db.Pool.Close()
+	if db.Replica != nil {
+		db.Replica.Close()
+	}
 }
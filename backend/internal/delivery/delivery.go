@@ -0,0 +1,78 @@
+// Package delivery pushes files to storage users own, such as an S3 bucket or
+// an SFTP server, so results reach their systems without polling the API
+package delivery
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Kinds of delivery target
+const (
+	KindS3   = "s3"
+	KindSFTP = "sftp"
+)
+
+// ErrCorruptSecret is returned when sealed credentials fail authentication
+var ErrCorruptSecret = errors.New("sealed credentials are corrupted or were sealed with another key")
+
+// Target is a place files are written to
+type Target interface {
+	// Put writes a file, replacing any file of the same name. Names are
+	// slash-separated and relative to the target's prefix or directory.
+	Put(ctx context.Context, name string, content []byte) error
+}
+
+// DialFunc opens network connections, letting callers refuse some addresses
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Sealer encrypts credentials stored in the database with AES-256-GCM
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer creates a sealer for a 32-byte key, base64 encoded
+func NewSealer(encodedKey string) (*Sealer, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("delivery encryption key must be 32 bytes, base64 encoded")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal encrypts plaintext bound to context, such as the ID of the row it is
+// stored in, and encodes it as base64
+func (s *Sealer) Seal(plaintext, context []byte) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, plaintext, context)), nil
+}
+
+// Open decrypts what Seal returned for the same context
+func (s *Sealer) Open(sealed string, context []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < s.aead.NonceSize() {
+		return nil, ErrCorruptSecret
+	}
+	plaintext, err := s.aead.Open(nil, raw[:s.aead.NonceSize()], raw[s.aead.NonceSize():], context)
+	if err != nil {
+		return nil, ErrCorruptSecret
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,146 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// S3Config is an S3 bucket, or a bucket of an S3-compatible service
+type S3Config struct {
+	// Endpoint is the URL of an S3-compatible service, addressed path-style;
+	// empty for AWS, addressed virtual-hosted-style
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3 writes objects to a bucket with requests signed with AWS Signature
+// Version 4
+type S3 struct {
+	cfg        S3Config
+	httpClient *http.Client
+}
+
+// NewS3 creates a target writing to a bucket
+func NewS3(cfg S3Config, httpClient *http.Client) *S3 {
+	return &S3{cfg: cfg, httpClient: httpClient}
+}
+
+// Put uploads an object under the prefix
+func (s *S3) Put(ctx context.Context, name string, content []byte) error {
+	key := path.Join(strings.Trim(s.cfg.Prefix, "/"), name)
+
+	var target string
+	if s.cfg.Endpoint == "" {
+		target = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.cfg.Bucket, s.cfg.Region, encodePath(key))
+	} else {
+		target = strings.TrimRight(s.cfg.Endpoint, "/") + "/" + encodePath(s.cfg.Bucket) + "/" + encodePath(key)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", target, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	s.sign(req, content, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("bucket returned status %d: %s", resp.StatusCode, s3ErrorCode(body))
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	return nil
+}
+
+// sign adds the Signature Version 4 headers to a request
+func (s *S3) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"content-type:" + req.Header.Get("Content-Type"),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// encodePath escapes an object key as Signature Version 4 expects: every byte
+// but unreserved characters and slashes is percent-encoded
+func encodePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// s3ErrorCode extracts the code of an S3 error response, such as AccessDenied
+func s3ErrorCode(body []byte) string {
+	start := bytes.Index(body, []byte("<Code>"))
+	end := bytes.Index(body, []byte("</Code>"))
+	if start < 0 || end < start {
+		return "unknown error"
+	}
+	return string(body[start+len("<Code>") : end])
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,329 @@
+package delivery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP packet types, from version 3 of the protocol
+const (
+	sftpInit    = 1
+	sftpVersion = 2
+	sftpOpen    = 3
+	sftpClose   = 4
+	sftpWrite   = 6
+	sftpMkdir   = 14
+	sftpStat    = 17
+	sftpStatus  = 101
+	sftpHandle  = 102
+	sftpAttrs   = 105
+)
+
+const (
+	// sftpOpenWrite opens a file for writing, creating or truncating it
+	sftpOpenWrite = 0x02 | 0x08 | 0x10
+	// sftpChunkSize is the size of the writes, which every server accepts
+	sftpChunkSize = 32 * 1024
+	// sftpMaxPacket caps the size of the packets read from the server
+	sftpMaxPacket = 256 * 1024
+)
+
+// ErrHostKeyMismatch is returned when an SFTP server presents another host key
+// than the one it was trusted with
+var ErrHostKeyMismatch = errors.New("server host key does not match the trusted host key")
+
+// SFTPConfig is an SFTP server files are written to over SSH, with a
+// password or a private key
+type SFTPConfig struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	PrivateKey string
+	// Directory is where files are written; created if missing
+	Directory string
+	// HostKey is the SHA256 fingerprint of the server's host key. When
+	// empty, the key presented first is trusted and can be read with HostKey.
+	HostKey string
+}
+
+// SFTP writes files to an SFTP server, one connection per file
+type SFTP struct {
+	cfg     SFTPConfig
+	dial    DialFunc
+	timeout time.Duration
+}
+
+// NewSFTP creates a target writing to an SFTP server
+func NewSFTP(cfg SFTPConfig, dial DialFunc, timeout time.Duration) *SFTP {
+	if cfg.Port == 0 {
+		cfg.Port = 22
+	}
+	return &SFTP{cfg: cfg, dial: dial, timeout: timeout}
+}
+
+// HostKey returns the fingerprint of the server's host key once trusted
+func (s *SFTP) HostKey() string {
+	return s.cfg.HostKey
+}
+
+// Put writes a file under the directory, creating missing directories
+func (s *SFTP) Put(ctx context.Context, name string, content []byte) error {
+	var auth []ssh.AuthMethod
+	if s.cfg.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(s.cfg.PrivateKey))
+		if err != nil {
+			return fmt.Errorf("invalid private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if s.cfg.Password != "" {
+		auth = append(auth, ssh.Password(s.cfg.Password))
+	}
+
+	address := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	conn, err := s.dial(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, &ssh.ClientConfig{
+		User:            s.cfg.Username,
+		Auth:            auth,
+		HostKeyCallback: s.checkHostKey,
+		Timeout:         s.timeout,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open SFTP channel: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open SFTP channel: %w", err)
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return fmt.Errorf("server does not offer SFTP: %w", err)
+	}
+
+	sftp := &sftpClient{w: stdin, r: stdout}
+	if err := sftp.init(); err != nil {
+		return err
+	}
+
+	target := path.Join(s.cfg.Directory, name)
+	if err := sftp.mkdirAll(path.Dir(target)); err != nil {
+		return err
+	}
+	return sftp.writeFile(target, content)
+}
+
+// checkHostKey accepts the server's host key if it matches the trusted one, or
+// trusts it when none is yet
+func (s *SFTP) checkHostKey(_ string, _ net.Addr, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+	if s.cfg.HostKey == "" {
+		s.cfg.HostKey = fingerprint
+		return nil
+	}
+	if fingerprint != s.cfg.HostKey {
+		return ErrHostKeyMismatch
+	}
+	return nil
+}
+
+// sftpClient speaks the parts of SFTP version 3 needed to write files, one
+// request at a time
+type sftpClient struct {
+	w      io.Writer
+	r      io.Reader
+	nextID uint32
+}
+
+// init negotiates the protocol version
+func (c *sftpClient) init() error {
+	if err := c.send(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return err
+	}
+	typ, _, err := c.recv()
+	if err != nil {
+		return err
+	}
+	if typ != sftpVersion {
+		return fmt.Errorf("unexpected SFTP packet %d", typ)
+	}
+	return nil
+}
+
+// mkdirAll creates a directory and its missing parents
+func (c *sftpClient) mkdirAll(dir string) error {
+	if dir == "" || dir == "." || dir == "/" {
+		return nil
+	}
+	if typ, _, err := c.request(sftpStat, sftpString(nil, dir)); err != nil {
+		return err
+	} else if typ == sftpAttrs {
+		return nil
+	}
+
+	if err := c.mkdirAll(path.Dir(dir)); err != nil {
+		return err
+	}
+	payload := binary.BigEndian.AppendUint32(sftpString(nil, dir), 0)
+	typ, body, err := c.request(sftpMkdir, payload)
+	if err != nil {
+		return err
+	}
+	return sftpStatusError(typ, body, "failed to create directory "+dir)
+}
+
+// writeFile creates or truncates a file and writes content to it
+func (c *sftpClient) writeFile(name string, content []byte) error {
+	payload := sftpString(nil, name)
+	payload = binary.BigEndian.AppendUint32(payload, sftpOpenWrite)
+	payload = binary.BigEndian.AppendUint32(payload, 0)
+	typ, body, err := c.request(sftpOpen, payload)
+	if err != nil {
+		return err
+	}
+	if typ != sftpHandle {
+		return sftpStatusError(typ, body, "failed to open "+name)
+	}
+	handle, _, ok := sftpReadString(body)
+	if !ok {
+		return fmt.Errorf("malformed SFTP handle")
+	}
+
+	for offset := 0; ; {
+		end := min(offset+sftpChunkSize, len(content))
+		payload := sftpString(nil, handle)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(offset))
+		payload = sftpString(payload, string(content[offset:end]))
+		typ, body, err := c.request(sftpWrite, payload)
+		if err != nil {
+			return err
+		}
+		if err := sftpStatusError(typ, body, "failed to write "+name); err != nil {
+			return err
+		}
+		if end == len(content) {
+			break
+		}
+		offset = end
+	}
+
+	typ, body, err = c.request(sftpClose, sftpString(nil, handle))
+	if err != nil {
+		return err
+	}
+	return sftpStatusError(typ, body, "failed to close "+name)
+}
+
+// request sends a request and returns the type and body of the response,
+// after its request ID
+func (c *sftpClient) request(typ byte, payload []byte) (byte, []byte, error) {
+	c.nextID++
+	id := c.nextID
+	if err := c.send(typ, append(binary.BigEndian.AppendUint32(nil, id), payload...)); err != nil {
+		return 0, nil, err
+	}
+
+	respType, body, err := c.recv()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(body) < 4 || binary.BigEndian.Uint32(body) != id {
+		return 0, nil, fmt.Errorf("unexpected SFTP response")
+	}
+	return respType, body[4:], nil
+}
+
+// send writes a packet
+func (c *sftpClient) send(typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, typ)
+	packet = append(packet, payload...)
+	if _, err := c.w.Write(packet); err != nil {
+		return fmt.Errorf("failed to send SFTP request: %w", err)
+	}
+	return nil
+}
+
+// recv reads a packet
+func (c *sftpClient) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("failed to read SFTP response: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("SFTP response too large")
+	}
+	body := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, fmt.Errorf("failed to read SFTP response: %w", err)
+	}
+	return header[4], body, nil
+}
+
+// sftpStatusError returns nil for a status response reporting success, and
+// an error describing any other response
+func sftpStatusError(typ byte, body []byte, action string) error {
+	if typ != sftpStatus || len(body) < 4 {
+		return fmt.Errorf("%s: unexpected SFTP response %d", action, typ)
+	}
+	code := binary.BigEndian.Uint32(body)
+	if code == 0 {
+		return nil
+	}
+	message, _, _ := sftpReadString(body[4:])
+	if strings.TrimSpace(message) == "" {
+		message = "status " + strconv.Itoa(int(code))
+	}
+	return fmt.Errorf("%s: %s", action, message)
+}
+
+// sftpString appends a length-prefixed string
+func sftpString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// sftpReadString reads a length-prefixed string, returning the rest
+func sftpReadString(b []byte) (string, []byte, bool) {
+	if len(b) < 4 {
+		return "", nil, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return "", nil, false
+	}
+	return string(b[4 : 4+n]), b[4+n:], true
+}
@@ -0,0 +1,101 @@
+package entities
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"visekai/backend/internal/models"
+)
+
+// Extractor finds entities in the raw text of a result
+type Extractor interface {
+	Extract(ctx context.Context, text string) ([]models.ResultEntity, error)
+}
+
+// Config holds the entity extraction settings
+type Config struct {
+	Providers []string // regex, remote; none or empty disables extraction
+	Types     []string // entity types to keep; empty keeps all
+	URL       string   // endpoint of the remote provider
+	Token     string   // bearer token sent to the remote provider
+	Timeout   time.Duration
+}
+
+// NewExtractor creates an extractor running the configured providers in order.
+// It returns nil when extraction is disabled.
+func NewExtractor(cfg Config) (Extractor, error) {
+	var providers []Extractor
+	for _, name := range cfg.Providers {
+		switch name {
+		case "", "none":
+		case "regex":
+			providers = append(providers, NewRegexExtractor())
+		case "remote":
+			if cfg.URL == "" {
+				return nil, fmt.Errorf("remote entity provider requires a URL")
+			}
+			providers = append(providers, NewRemoteExtractor(cfg.URL, cfg.Token, cfg.Timeout))
+		default:
+			return nil, fmt.Errorf("unknown entity provider: %s", name)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	types := make(map[models.EntityType]bool, len(cfg.Types))
+	for _, t := range cfg.Types {
+		entityType := models.EntityType(t)
+		if !entityType.Valid() {
+			return nil, fmt.Errorf("unknown entity type: %s", t)
+		}
+		types[entityType] = true
+	}
+
+	return &chain{providers: providers, types: types}, nil
+}
+
+// chain runs several providers and merges what they find. Where providers
+// find overlapping entities of the same type, the first provider wins.
+type chain struct {
+	providers []Extractor
+	types     map[models.EntityType]bool
+}
+
+func (c *chain) Extract(ctx context.Context, text string) ([]models.ResultEntity, error) {
+	entities := []models.ResultEntity{}
+	for _, provider := range c.providers {
+		found, err := provider.Extract(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		for _, entity := range found {
+			if len(c.types) > 0 && !c.types[entity.Type] {
+				continue
+			}
+			if entity.Start < 0 || entity.End > len(text) || entity.Start >= entity.End {
+				continue
+			}
+			if overlaps(entities, entity) {
+				continue
+			}
+			entities = append(entities, entity)
+		}
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool { return entities[i].Start < entities[j].Start })
+	return entities, nil
+}
+
+// overlaps reports whether an entity of the same type already covers part of
+// the text of entity
+func overlaps(entities []models.ResultEntity, entity models.ResultEntity) bool {
+	for _, e := range entities {
+		if e.Type == entity.Type && e.Start < entity.End && entity.Start < e.End {
+			return true
+		}
+	}
+	return false
+}
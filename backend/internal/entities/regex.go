@@ -0,0 +1,277 @@
+package entities
+
+import (
+	"context"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/models"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+\-]+@[a-z0-9\-]+(?:\.[a-z0-9\-]+)*\.[a-z]{2,}\b`)
+	ibanPattern  = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`)
+
+	isoDatePattern      = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	numericDatePattern  = regexp.MustCompile(`\b(\d{1,2})([./])(\d{1,2})([./])(\d{4})\b`)
+	dayMonthDatePattern = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\.?\s+([a-z]{3,9})\.?,?\s+(\d{4})\b`)
+	monthDayDatePattern = regexp.MustCompile(`(?i)\b([a-z]{3,9})\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+
+	amountNumber          = `(\d{1,3}(?:[ ,.'’]\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?)`
+	currencyBeforePattern = regexp.MustCompile(`(?:([€$£¥])|\b(EUR|USD|GBP|CHF|JPY|CAD|AUD)\b)\s?` + amountNumber)
+	currencyAfterPattern  = regexp.MustCompile(amountNumber + `\s?(?:([€$£¥])|(EUR|USD|GBP|CHF|JPY|CAD|AUD)\b)`)
+	decimalPart           = regexp.MustCompile(`[.,]\d{1,2}$`)
+
+	invoicePattern = regexp.MustCompile(`(?i)\b(?:invoice|inv|rechnung|facture|factura|fattura)\.?\s*(?:no\.?|nr\.?|n[°º]\.?|num(?:ber|ero|éro)?\.?|#)?\s*[:#]?\s*([A-Z0-9][A-Z0-9\-/.]*\d[A-Z0-9\-/]*)`)
+)
+
+// currencySymbols maps currency symbols to their ISO codes
+var currencySymbols = map[string]string{
+	"€": "EUR",
+	"$": "USD",
+	"£": "GBP",
+	"¥": "JPY",
+}
+
+// months maps English month names and their abbreviations to month numbers
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+// RegexExtractor finds entities with regular expressions, checking what can
+// be checked: calendar dates, IBAN check digits
+type RegexExtractor struct{}
+
+// NewRegexExtractor creates a new regex extractor
+func NewRegexExtractor() *RegexExtractor {
+	return &RegexExtractor{}
+}
+
+// Extract finds dates, amounts, emails, invoice numbers and IBANs in text
+func (e *RegexExtractor) Extract(ctx context.Context, text string) ([]models.ResultEntity, error) {
+	var entities []models.ResultEntity
+	add := func(entityType models.EntityType, start, end int, value string, confidence float64) {
+		entity := models.ResultEntity{
+			Type:       entityType,
+			Text:       text[start:end],
+			Start:      start,
+			End:        end,
+			Value:      value,
+			Confidence: confidence,
+			Source:     "regex",
+		}
+		if !overlaps(entities, entity) {
+			entities = append(entities, entity)
+		}
+	}
+
+	for _, m := range emailPattern.FindAllStringIndex(text, -1) {
+		add(models.EntityEmail, m[0], m[1], strings.ToLower(text[m[0]:m[1]]), 0.95)
+	}
+
+	for _, m := range ibanPattern.FindAllStringIndex(text, -1) {
+		if end, iban, ok := validIBAN(text, m[0], m[1]); ok {
+			add(models.EntityIBAN, m[0], end, iban, 0.99)
+		}
+	}
+
+	for _, m := range invoicePattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2], m[3]
+		for end > start && strings.ContainsRune("-/.", rune(text[end-1])) {
+			end--
+		}
+		add(models.EntityInvoiceNumber, start, end, strings.ToUpper(text[start:end]), 0.8)
+	}
+
+	e.extractDates(text, add)
+	e.extractAmounts(text, add)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// extractDates finds ISO, numeric and written-out dates. Numeric dates with a
+// slash are read month first unless the first number cannot be a month, and
+// are less certain when both readings are valid.
+func (e *RegexExtractor) extractDates(text string, add func(models.EntityType, int, int, string, float64)) {
+	for _, m := range isoDatePattern.FindAllStringSubmatchIndex(text, -1) {
+		if value, ok := isoDate(atoi(text, m[2], m[3]), atoi(text, m[4], m[5]), atoi(text, m[6], m[7])); ok {
+			add(models.EntityDate, m[0], m[1], value, 0.95)
+		}
+	}
+
+	for _, m := range numericDatePattern.FindAllStringSubmatchIndex(text, -1) {
+		if text[m[4]:m[5]] != text[m[8]:m[9]] {
+			continue
+		}
+		first, second, year := atoi(text, m[2], m[3]), atoi(text, m[6], m[7]), atoi(text, m[10], m[11])
+		day, month := first, second
+		if text[m[4]:m[5]] == "/" && first <= 12 {
+			day, month = second, first
+		}
+		value, ok := isoDate(year, month, day)
+		if !ok {
+			continue
+		}
+		confidence := 0.9
+		if first <= 12 && second <= 12 && first != second {
+			confidence = 0.6
+		}
+		add(models.EntityDate, m[0], m[1], value, confidence)
+	}
+
+	for _, m := range dayMonthDatePattern.FindAllStringSubmatchIndex(text, -1) {
+		month, ok := months[strings.ToLower(text[m[4]:m[5]])]
+		if !ok {
+			continue
+		}
+		if value, ok := isoDate(atoi(text, m[6], m[7]), int(month), atoi(text, m[2], m[3])); ok {
+			add(models.EntityDate, m[0], m[1], value, 0.9)
+		}
+	}
+
+	for _, m := range monthDayDatePattern.FindAllStringSubmatchIndex(text, -1) {
+		month, ok := months[strings.ToLower(text[m[2]:m[3]])]
+		if !ok {
+			continue
+		}
+		if value, ok := isoDate(atoi(text, m[6], m[7]), int(month), atoi(text, m[4], m[5])); ok {
+			add(models.EntityDate, m[0], m[1], value, 0.9)
+		}
+	}
+}
+
+// extractAmounts finds numbers written with a currency symbol or code on
+// either side. Amounts without a currency are too ambiguous to tell from
+// other numbers.
+func (e *RegexExtractor) extractAmounts(text string, add func(models.EntityType, int, int, string, float64)) {
+	for _, m := range currencyBeforePattern.FindAllStringSubmatchIndex(text, -1) {
+		currency := submatch(text, m, 1)
+		if currency == "" {
+			currency = submatch(text, m, 2)
+		}
+		if m[1] < len(text) && isDigit(text[m[1]]) {
+			continue
+		}
+		add(models.EntityAmount, m[0], m[1], amountValue(submatch(text, m, 3), currency), 0.9)
+	}
+
+	for _, m := range currencyAfterPattern.FindAllStringSubmatchIndex(text, -1) {
+		if m[0] > 0 && (isDigit(text[m[0]-1]) || text[m[0]-1] == '.' || text[m[0]-1] == ',') {
+			continue
+		}
+		currency := submatch(text, m, 2)
+		if currency == "" {
+			currency = submatch(text, m, 3)
+		}
+		add(models.EntityAmount, m[0], m[1], amountValue(submatch(text, m, 1), currency), 0.9)
+	}
+}
+
+// validIBAN checks the IBAN candidate text[start:end] against its check digits.
+// Candidates running into a following word are shortened a group at a time
+// until they check out. It returns where the IBAN ends and its compact form.
+func validIBAN(text string, start, end int) (int, string, bool) {
+	for end > start {
+		compact := strings.ReplaceAll(text[start:end], " ", "")
+		if len(compact) < 15 {
+			return 0, "", false
+		}
+		if len(compact) <= 34 && ibanChecksum(compact) {
+			return end, compact, true
+		}
+
+		// Drop the last group and the space before it
+		cut := strings.LastIndexByte(text[start:end], ' ')
+		if cut < 0 {
+			end -= 4
+		} else {
+			end = start + cut
+		}
+	}
+	return 0, "", false
+}
+
+// ibanChecksum verifies the ISO 7064 mod 97 check digits of a compact IBAN
+func ibanChecksum(iban string) bool {
+	rearranged := iban[4:] + iban[:4]
+	var digits strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// amountValue normalizes an amount to a decimal number followed by its
+// currency code
+func amountValue(number, currency string) string {
+	decimals := ""
+	if loc := decimalPart.FindStringIndex(number); loc != nil {
+		decimals = "." + number[loc[0]+1:]
+		number = number[:loc[0]]
+	}
+	number = strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+
+	if code, ok := currencySymbols[currency]; ok {
+		currency = code
+	}
+	return number + decimals + " " + currency
+}
+
+// isoDate formats a date as YYYY-MM-DD if it exists in the calendar
+func isoDate(year, month, day int) (string, bool) {
+	if year < 1000 || month < 1 || month > 12 || day < 1 {
+		return "", false
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day {
+		return "", false
+	}
+	return date.Format("2006-01-02"), true
+}
+
+func atoi(text string, start, end int) int {
+	n, _ := strconv.Atoi(text[start:end])
+	return n
+}
+
+func submatch(text string, m []int, group int) string {
+	if m[2*group] < 0 {
+		return ""
+	}
+	return text[m[2*group]:m[2*group+1]]
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
@@ -0,0 +1,82 @@
+package entities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"visekai/backend/internal/models"
+)
+
+// remoteResponseLimit caps the size of the response read from a remote provider
+const remoteResponseLimit = 8 << 20
+
+// RemoteExtractor sends text to an HTTP endpoint, such as an NER model server,
+// as {"text": "..."} and reads back {"entities": [...]} with the fields of
+// models.ResultEntity
+type RemoteExtractor struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewRemoteExtractor creates a new remote extractor
+func NewRemoteExtractor(url, token string, timeout time.Duration) *RemoteExtractor {
+	return &RemoteExtractor{
+		url:        url,
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Extract asks the remote provider for the entities in text
+func (e *RemoteExtractor) Extract(ctx context.Context, text string) ([]models.ResultEntity, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.token)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach entity provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("entity provider returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Entities []models.ResultEntity `json:"entities"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, remoteResponseLimit)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode entity provider response: %w", err)
+	}
+
+	for i := range result.Entities {
+		entity := &result.Entities[i]
+		entity.Source = "remote"
+		// Offsets are checked by the caller; keep the text consistent with them
+		if entity.Start >= 0 && entity.End <= len(text) && entity.Start < entity.End {
+			entity.Text = text[entity.Start:entity.End]
+		}
+		if entity.Value == "" {
+			entity.Value = entity.Text
+		}
+	}
+	return result.Entities, nil
+}
@@ -0,0 +1,72 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/models"
+)
+
+// KafkaRESTPublisher publishes events to Kafka through a Confluent-compatible REST proxy.
+// The aggregate ID is used as record key so events of one aggregate stay ordered.
+type KafkaRESTPublisher struct {
+	baseURL    string
+	prefix     string
+	httpClient *http.Client
+}
+
+// NewKafkaRESTPublisher creates a new Kafka REST proxy publisher
+func NewKafkaRESTPublisher(baseURL, prefix string, timeout time.Duration) *KafkaRESTPublisher {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &KafkaRESTPublisher{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		prefix:     prefix,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish sends an event as a single record to the topic derived from its type
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, event *models.DomainEvent) error {
+	body, err := json.Marshal(map[string]any{
+		"records": []map[string]any{
+			{"key": event.AggregateID.String(), "value": event},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/topics/%s", p.baseURL, Subject(p.prefix, event))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kafka rest proxy returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// Close is a no-op; the HTTP client holds no dedicated connection
+func (p *KafkaRESTPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,171 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"visekai/backend/internal/models"
+)
+
+// NATSPublisher publishes events to NATS core using the text protocol.
+// Every publish is followed by a PING so a returned nil error means the
+// server has accepted the message.
+type NATSPublisher struct {
+	url     string
+	prefix  string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSPublisher creates a new NATS publisher; the connection is opened lazily
+func NewNATSPublisher(natsURL, prefix string, timeout time.Duration) *NATSPublisher {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &NATSPublisher{
+		url:     natsURL,
+		prefix:  prefix,
+		timeout: timeout,
+	}
+}
+
+// Publish sends an event to the subject derived from its type
+func (p *NATSPublisher) Publish(ctx context.Context, event *models.DomainEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err = p.publish(ctx, Subject(p.prefix, event), payload)
+	if err != nil {
+		// The server drops idle connections that missed PINGs; retry once on a fresh one
+		p.closeConn()
+		err = p.publish(ctx, Subject(p.prefix, event), payload)
+		if err != nil {
+			p.closeConn()
+		}
+	}
+	return err
+}
+
+// Close closes the connection
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeConn()
+	return nil
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, subject string, payload []byte) error {
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = p.conn.SetDeadline(deadline)
+
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload)
+	if _, err := p.conn.Write([]byte(msg)); err != nil {
+		return fmt.Errorf("failed to write to nats: %w", err)
+	}
+
+	return p.waitForPong()
+}
+
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	u, err := url.Parse(p.url)
+	if err != nil {
+		return fmt.Errorf("invalid nats url: %w", err)
+	}
+
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(p.timeout))
+
+	reader := bufio.NewReader(conn)
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO") {
+		conn.Close()
+		return fmt.Errorf("unexpected nats greeting: %q", strings.TrimSpace(info))
+	}
+
+	options := map[string]any{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "visekai-backend",
+		"lang":     "go",
+	}
+	if u.User != nil {
+		options["user"] = u.User.Username()
+		if password, ok := u.User.Password(); ok {
+			options["pass"] = password
+		}
+	}
+	connectOpts, _ := json.Marshal(options)
+
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connectOpts); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send nats connect: %w", err)
+	}
+
+	p.conn = conn
+	p.reader = reader
+
+	if err := p.waitForPong(); err != nil {
+		p.closeConn()
+		return err
+	}
+
+	return nil
+}
+
+// waitForPong reads protocol lines until the server answers our PING
+func (p *NATSPublisher) waitForPong() error {
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read from nats: %w", err)
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("failed to write to nats: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (p *NATSPublisher) closeConn() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+		p.reader = nil
+	}
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/pkg/logger"
+)
+
+// Publisher delivers domain events to a message bus
+type Publisher interface {
+	Publish(ctx context.Context, event *models.DomainEvent) error
+	Close() error
+}
+
+// Config holds the message bus and webhook delivery settings
+type Config struct {
+	Driver        string // none, log, nats, kafka-rest
+	URL           string
+	Prefix        string
+	WebhookURL    string
+	WebhookSecret string
+	Timeout       time.Duration
+}
+
+// NewPublisher creates a publisher delivering to the configured bus and webhook.
+// It returns nil when no destination is configured.
+func NewPublisher(cfg Config) (Publisher, error) {
+	var publishers []Publisher
+
+	switch cfg.Driver {
+	case "", "none":
+	case "log":
+		publishers = append(publishers, &LogPublisher{})
+	case "nats":
+		publishers = append(publishers, NewNATSPublisher(cfg.URL, cfg.Prefix, cfg.Timeout))
+	case "kafka-rest":
+		publishers = append(publishers, NewKafkaRESTPublisher(cfg.URL, cfg.Prefix, cfg.Timeout))
+	default:
+		return nil, fmt.Errorf("unknown event bus driver: %s", cfg.Driver)
+	}
+
+	if cfg.WebhookURL != "" {
+		publishers = append(publishers, NewWebhookPublisher(cfg.WebhookURL, cfg.WebhookSecret, cfg.Timeout))
+	}
+
+	switch len(publishers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return publishers[0], nil
+	default:
+		return MultiPublisher(publishers), nil
+	}
+}
+
+// MultiPublisher fans an event out to several destinations.
+// A failure on any destination fails the publish, so the event is retried
+// everywhere; consumers must tolerate duplicates.
+type MultiPublisher []Publisher
+
+// Publish delivers the event to every destination
+func (m MultiPublisher) Publish(ctx context.Context, event *models.DomainEvent) error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every destination
+func (m MultiPublisher) Close() error {
+	var errs []error
+	for _, p := range m {
+		if err := p.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Subject returns the bus subject/topic of an event
+func Subject(prefix string, event *models.DomainEvent) string {
+	if prefix == "" {
+		return string(event.Type)
+	}
+	return prefix + "." + string(event.Type)
+}
+
+// LogPublisher writes events to the application log, useful for development
+type LogPublisher struct{}
+
+// Publish logs the event
+func (p *LogPublisher) Publish(ctx context.Context, event *models.DomainEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	logger.Info("Domain event", "type", event.Type, "event_id", event.ID, "payload", string(payload))
+	return nil
+}
+
+// Close is a no-op
+func (p *LogPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"visekai/backend/internal/models"
+)
+
+// WebhookPublisher posts events as JSON to an HTTP endpoint.
+// When a secret is configured the body is signed with HMAC-SHA256 over
+// "<timestamp>.<body>" and sent in the X-Visekai-Signature header.
+type WebhookPublisher struct {
+	url        string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewWebhookPublisher creates a new webhook publisher
+func NewWebhookPublisher(url, secret string, timeout time.Duration) *WebhookPublisher {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &WebhookPublisher{
+		url:        url,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish delivers the event; any non-2xx response is treated as a failure
+func (p *WebhookPublisher) Publish(ctx context.Context, event *models.DomainEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Visekai-Event", string(event.Type))
+	req.Header.Set("X-Visekai-Event-ID", event.ID.String())
+	req.Header.Set("X-Visekai-Timestamp", timestamp)
+	if p.secret != "" {
+		req.Header.Set("X-Visekai-Signature", "sha256="+Sign(p.secret, timestamp, body))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Close is a no-op
+func (p *WebhookPublisher) Close() error {
+	return nil
+}
+
+// Sign computes the hex HMAC-SHA256 signature of a webhook body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
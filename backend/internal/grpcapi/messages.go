@@ -0,0 +1,86 @@
+package grpcapi
+
+import (
+	"time"
+
+	visekaiv1 "visekai/backend/api/proto/visekai/v1"
+	"visekai/backend/internal/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The messages of api/proto/visekai/v1/ocr.proto the models are sent as
+
+func toDocument(document *models.Document) *visekaiv1.Document {
+	return &visekaiv1.Document{
+		Id:               document.ID.String(),
+		OriginalFilename: document.OriginalFilename,
+		FileSize:         document.FileSize,
+		MimeType:         document.MimeType,
+		FileHash:         document.FileHash,
+		NumPages:         int32(document.NumPages),
+		Labels:           document.Labels,
+		UploadedAt:       timestamp(&document.UploadedAt),
+	}
+}
+
+func toJob(job *models.OCRJob) *visekaiv1.Job {
+	return &visekaiv1.Job{
+		Id:                 job.ID.String(),
+		DocumentId:         job.DocumentID.String(),
+		Status:             string(job.Status),
+		OcrMode:            string(job.OCRMode),
+		ResolutionMode:     string(job.ResolutionMode),
+		Priority:           int32(job.Priority),
+		RetryCount:         int32(job.RetryCount),
+		ProgressPercentage: int32(job.ProgressPercentage),
+		ErrorMessage:       stringValue(job.ErrorMessage),
+		Labels:             job.Labels,
+		CreatedAt:          timestamp(&job.CreatedAt),
+		StartedAt:          timestamp(job.StartedAt),
+		CompletedAt:        timestamp(job.CompletedAt),
+	}
+}
+
+func toJobEvent(event *models.JobEvent) *visekaiv1.JobEvent {
+	return &visekaiv1.JobEvent{
+		Id:                 event.ID,
+		JobId:              event.JobID.String(),
+		Type:               string(event.Type),
+		Status:             string(event.Status),
+		ProgressPercentage: int32(event.ProgressPercentage),
+		RetryCount:         int32(event.RetryCount),
+		Message:            stringValue(event.Message),
+		OccurredAt:         timestamp(&event.OccurredAt),
+	}
+}
+
+func toResult(result *models.OCRResult) *visekaiv1.Result {
+	return &visekaiv1.Result{
+		Id:               result.ID.String(),
+		JobId:            result.JobID.String(),
+		DocumentId:       result.DocumentID.String(),
+		RawText:          result.RawText,
+		MarkdownText:     result.MarkdownText,
+		ConfidenceScore:  result.ConfidenceScore,
+		ProcessingTimeMs: int32(result.ProcessingTimeMs),
+		NumPages:         int32(result.NumPages),
+		Version:          int32(result.Version),
+		CreatedAt:        timestamp(&result.CreatedAt),
+	}
+}
+
+// timestamp converts an optional time, leaving the field unset when it is nil
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
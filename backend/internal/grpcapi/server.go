@@ -0,0 +1,288 @@
+// Package grpcapi serves the core OCR operations over gRPC for internal
+// service consumers, with the service definitions in
+// api/proto/visekai/v1/ocr.proto and the code generated from them in
+// visekaiv1. It calls the same services as the REST handlers.
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"time"
+
+	visekaiv1 "visekai/backend/api/proto/visekai/v1"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/validator"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// permissions maps each method of the OCR service to the permission its
+// callers need, the one the matching REST route requires
+var permissions = map[string]models.Permission{
+	visekaiv1.OCRService_UploadDocument_FullMethodName: models.NewPermission(models.ResourceDocuments, models.ActionWrite),
+	visekaiv1.OCRService_SubmitJob_FullMethodName:      models.NewPermission(models.ResourceJobs, models.ActionWrite),
+	visekaiv1.OCRService_WatchJob_FullMethodName:       models.NewPermission(models.ResourceJobs, models.ActionRead),
+	visekaiv1.OCRService_GetResult_FullMethodName:      models.NewPermission(models.ResourceResults, models.ActionRead),
+}
+
+// Server serves the OCR service. Callers authenticate like on the REST API,
+// with a JWT or an API key as a bearer token in the authorization metadata or
+// an API key in x-api-key, and need the permission the REST route requires.
+type Server struct {
+	visekaiv1.UnimplementedOCRServiceServer
+
+	authService   *services.AuthService
+	apiKeyService *services.APIKeyService
+	userRepo      *repository.UserRepository
+	uploadService *services.UploadService
+	jobService    *services.JobService
+	resultService *services.ResultService
+	eventStream   *services.JobEventStream
+	auditService  *services.AuditService
+	validator     *validator.Validator
+}
+
+// NewServer creates a new gRPC server. API keys are refused when
+// apiKeyService is nil.
+func NewServer(
+	authService *services.AuthService,
+	apiKeyService *services.APIKeyService,
+	userRepo *repository.UserRepository,
+	uploadService *services.UploadService,
+	jobService *services.JobService,
+	resultService *services.ResultService,
+	eventStream *services.JobEventStream,
+	auditService *services.AuditService,
+) *Server {
+	return &Server{
+		authService:   authService,
+		apiKeyService: apiKeyService,
+		userRepo:      userRepo,
+		uploadService: uploadService,
+		jobService:    jobService,
+		resultService: resultService,
+		eventStream:   eventStream,
+		auditService:  auditService,
+		validator:     validator.New(),
+	}
+}
+
+// GRPCServer returns a gRPC server serving the OCR service, over TLS with
+// tlsConfig unless it is nil
+func (s *Server) GRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+		grpc.KeepaliveParams(keepalive.ServerParameters{MaxConnectionIdle: 5 * time.Minute}),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(opts...)
+	visekaiv1.RegisterOCRServiceServer(server, s)
+	return server
+}
+
+// unaryInterceptor authenticates and logs unary calls
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var resp any
+	err := s.intercept(ctx, info.FullMethod, grpc.SetHeader, grpc.SetTrailer, func(ctx context.Context) error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// streamInterceptor authenticates and logs streaming calls
+func (s *Server) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	setHeader := func(_ context.Context, md metadata.MD) error { return ss.SetHeader(md) }
+	setTrailer := func(_ context.Context, md metadata.MD) error {
+		ss.SetTrailer(md)
+		return nil
+	}
+	return s.intercept(ss.Context(), info.FullMethod, setHeader, setTrailer, func(ctx context.Context) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// intercept runs a call under a request ID, as RequestID does for the REST
+// API, once its caller is authenticated and authorized, and reports its
+// error as a status carrying the REST error code in visekai-error-code
+func (s *Server) intercept(ctx context.Context, method string, setHeader, setTrailer func(context.Context, metadata.MD) error, call func(context.Context) error) error {
+	start := time.Now()
+
+	requestID := firstMetadata(ctx, "x-request-id")
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	_ = setHeader(ctx, metadata.Pairs("x-request-id", requestID))
+	ctx = logger.WithContext(ctx, "request_id", requestID)
+
+	ctx, err := s.authenticate(ctx)
+	if err == nil {
+		err = s.authorize(ctx, method)
+	}
+	if err == nil {
+		err = call(ctx)
+	}
+
+	st := statusOf(err)
+	if st.code == codes.Internal {
+		logger.FromContext(ctx).Error("gRPC call failed", "method", method, "error", err)
+	}
+	if st.errorCode != "" {
+		_ = setTrailer(ctx, metadata.Pairs("visekai-error-code", st.errorCode))
+	}
+
+	logger.FromContext(ctx).Info("gRPC Request",
+		"method", method,
+		"code", st.code.String(),
+		"latency", time.Since(start),
+		"ip", remoteIP(ctx),
+	)
+
+	if st.code == codes.OK {
+		return nil
+	}
+	return grpcstatus.Error(st.code, st.message)
+}
+
+// caller is the authenticated caller of a call
+type caller struct {
+	userID         uuid.UUID
+	email          string
+	keyPermissions []models.Permission
+}
+
+type callerKey struct{}
+
+// callerFrom returns the caller of the call ctx belongs to
+func callerFrom(ctx context.Context) *caller {
+	c, _ := ctx.Value(callerKey{}).(*caller)
+	return c
+}
+
+// authenticate resolves the caller's JWT or API key, as AuthRequired does for
+// the REST API
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	authorization := firstMetadata(ctx, "authorization")
+
+	apiKey := firstMetadata(ctx, "x-api-key")
+	if apiKey == "" && strings.HasPrefix(authorization, "Bearer "+models.APIKeyPrefix) {
+		apiKey = strings.TrimPrefix(authorization, "Bearer ")
+	}
+	if apiKey != "" {
+		if s.apiKeyService == nil {
+			return ctx, newStatus(codes.Unauthenticated, "API keys are disabled")
+		}
+		key, err := s.apiKeyService.Authenticate(ctx, apiKey)
+		if err != nil {
+			return ctx, newStatus(codes.Unauthenticated, "invalid or expired API key")
+		}
+
+		ctx = context.WithValue(ctx, callerKey{}, &caller{userID: key.UserID, keyPermissions: key.Permissions})
+		ctx = logger.WithContext(ctx, "user_id", key.UserID, "api_key_id", key.ID)
+
+		// Keys restricted to labels only see documents and jobs carrying them
+		if len(key.Labels) > 0 {
+			ctx = repository.WithLabelScope(ctx, key.Labels)
+		}
+		return ctx, nil
+	}
+
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return ctx, newStatus(codes.Unauthenticated, "authorization metadata must be in format: Bearer <token>")
+	}
+	claims, err := s.authService.ValidateToken(token)
+	if err != nil {
+		return ctx, newStatus(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	ctx = context.WithValue(ctx, callerKey{}, &caller{userID: claims.UserID, email: claims.Email})
+	return logger.WithContext(ctx, "user_id", claims.UserID), nil
+}
+
+// authorize checks the caller holds the permission the method needs, as
+// RequirePermission does for the REST API
+func (s *Server) authorize(ctx context.Context, method string) error {
+	permission, ok := permissions[method]
+	if !ok {
+		return newStatus(codes.Unimplemented, "unknown method "+method)
+	}
+
+	c := callerFrom(ctx)
+	allowed := false
+	if user, err := s.userRepo.GetByID(ctx, c.userID); err == nil {
+		allowed = models.HasPermission(models.RolePermissions[user.Role], permission)
+	}
+	if len(c.keyPermissions) > 0 {
+		allowed = allowed && models.HasPermission(c.keyPermissions, permission)
+	}
+
+	if !allowed {
+		return newStatus(codes.PermissionDenied, "missing permission "+string(permission))
+	}
+	return nil
+}
+
+// auditEvent returns an audit event of an action taken in a call
+func (s *Server) auditEvent(ctx context.Context, action models.AuditAction, targetType string, targetID *uuid.UUID, metadata map[string]any) *models.AuditEvent {
+	c := callerFrom(ctx)
+	return &models.AuditEvent{
+		Action:     action,
+		ActorID:    &c.userID,
+		ActorEmail: c.email,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IPAddress:  remoteIP(ctx),
+		UserAgent:  firstMetadata(ctx, "user-agent"),
+		Metadata:   metadata,
+	}
+}
+
+// serverStream is a stream of a call with the context the interceptor set up
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// firstMetadata returns the first value of a key of the incoming metadata
+func firstMetadata(ctx context.Context, key string) string {
+	values := metadata.ValueFromIncomingContext(ctx, key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// remoteIP returns the address of the client
+func remoteIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
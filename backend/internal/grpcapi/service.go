@@ -0,0 +1,252 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	visekaiv1 "visekai/backend/api/proto/visekai/v1"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/services"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// UploadDocument stores a document streamed as its metadata followed by
+// chunks of its content, through the same pipeline as the REST upload
+func (s *Server) UploadDocument(stream grpc.ClientStreamingServer[visekaiv1.UploadDocumentRequest, visekaiv1.UploadDocumentResponse]) error {
+	ctx := stream.Context()
+
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return newStatus(codes.InvalidArgument, "missing upload metadata")
+	}
+	if err != nil {
+		return err
+	}
+	meta := first.GetMetadata()
+	if meta == nil || meta.GetFilename() == "" {
+		return newStatus(codes.InvalidArgument, "the first message must carry the metadata and filename")
+	}
+
+	opts := services.UploadOptions{
+		Labels:   meta.GetLabels(),
+		Checksum: meta.GetSha256(),
+	}
+	if value := meta.GetOnDuplicate(); value != "" {
+		opts.DuplicatePolicy = models.ParseDuplicatePolicy(value)
+	}
+	if value := meta.GetOrgId(); value != "" {
+		orgID, err := uuid.Parse(value)
+		if err != nil {
+			return &status{code: codes.InvalidArgument, message: "Invalid organization ID", errorCode: "VAL_029"}
+		}
+		opts.OrgID = &orgID
+	}
+
+	outcome, err := s.uploadService.Upload(ctx, callerFrom(ctx).userID, meta.GetFilename(), &chunkReader{stream: stream}, opts)
+	if err != nil {
+		return err
+	}
+
+	resp := &visekaiv1.UploadDocumentResponse{
+		Document: toDocument(outcome.Document),
+		Existing: outcome.Existing,
+	}
+	if outcome.Duplicate != nil {
+		resp.DuplicateOfDocumentId = outcome.Duplicate.ExistingDocumentID.String()
+	}
+	if outcome.Shared != nil {
+		resp.SharedFromDocumentId = outcome.Shared.DocumentID.String()
+	}
+	if outcome.Existing {
+		return stream.SendAndClose(resp)
+	}
+
+	if replaced := outcome.Replaced; replaced != nil {
+		s.auditService.Record(ctx, s.auditEvent(ctx, models.AuditDocumentDeleted, "document", &replaced.ID, map[string]any{
+			"original_filename": replaced.OriginalFilename,
+			"replaced":          true,
+		}))
+	}
+	document := outcome.Document
+	auditMetadata := map[string]any{
+		"original_filename": document.OriginalFilename,
+		"file_size":         document.FileSize,
+	}
+	if outcome.Shared != nil {
+		auditMetadata["shared_from_document_id"] = outcome.Shared.DocumentID
+	}
+	s.auditService.Record(ctx, s.auditEvent(ctx, models.AuditDocumentUploaded, "document", &document.ID, auditMetadata))
+
+	return stream.SendAndClose(resp)
+}
+
+// SubmitJob submits an OCR job, as the REST API does
+func (s *Server) SubmitJob(ctx context.Context, msg *visekaiv1.SubmitJobRequest) (*visekaiv1.Job, error) {
+	documentID, err := uuid.Parse(msg.GetDocumentId())
+	if err != nil {
+		return nil, &status{code: codes.InvalidArgument, message: "Invalid document ID", errorCode: "VAL_001"}
+	}
+	req := models.OCRJobRequest{
+		DocumentID:     documentID,
+		OCRMode:        models.OCRMode(msg.GetOcrMode()),
+		ResolutionMode: models.ResolutionMode(msg.GetResolutionMode()),
+		Priority:       int(msg.GetPriority()),
+		PageEvents:     msg.GetPageEvents(),
+	}
+	if err := s.validator.Validate(req); err != nil {
+		return nil, &status{code: codes.InvalidArgument, message: err.Error(), errorCode: "VAL_001"}
+	}
+
+	submission := models.JobSubmissionRequest{
+		DocumentID:     req.DocumentID,
+		OCRMode:        req.OCRMode,
+		ResolutionMode: req.ResolutionMode,
+		Priority:       req.Priority,
+	}
+	if req.PageEvents {
+		submission.Metadata = map[string]any{models.JobMetaPageEvents: true}
+	}
+
+	job, err := s.jobService.SubmitJob(ctx, submission, callerFrom(ctx).userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, s.auditEvent(ctx, models.AuditJobSubmitted, "job", &job.ID, map[string]any{
+		"document_id": job.DocumentID,
+		"ocr_mode":    job.OCRMode,
+	}))
+
+	return toJob(job), nil
+}
+
+// WatchJob streams a job's current state and then its events, until the job
+// completes, fails or is cancelled. A watch cut off by the event stream ends
+// with UNAVAILABLE, and the client watches again.
+func (s *Server) WatchJob(msg *visekaiv1.WatchJobRequest, stream grpc.ServerStreamingServer[visekaiv1.JobEvent]) error {
+	ctx := stream.Context()
+	jobID, err := uuid.Parse(msg.GetJobId())
+	if err != nil {
+		return &status{code: codes.InvalidArgument, message: "Invalid job ID", errorCode: "VAL_008"}
+	}
+	userID := callerFrom(ctx).userID
+
+	// Subscribe before reading the job so no event falls between them
+	sub := s.eventStream.Subscribe(userID, repository.LabelScope(ctx))
+	defer sub.Close()
+
+	job, err := s.jobService.GetJob(ctx, jobID, userID)
+	if err != nil {
+		return err
+	}
+
+	// The current state is sent as an event without an ID
+	state := &models.JobEvent{
+		JobID:              job.ID,
+		DocumentID:         job.DocumentID,
+		Type:               "state",
+		Status:             job.Status,
+		ProgressPercentage: job.ProgressPercentage,
+		RetryCount:         job.RetryCount,
+		Message:            job.ErrorMessage,
+		OccurredAt:         job.CreatedAt,
+	}
+	if job.StartedAt != nil {
+		state.OccurredAt = *job.StartedAt
+	}
+	if job.CompletedAt != nil {
+		state.OccurredAt = *job.CompletedAt
+	}
+	if err := stream.Send(toJobEvent(state)); err != nil {
+		return err
+	}
+	if finished(job.Status) {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return newStatus(codes.Unavailable, "job event stream interrupted, watch again")
+			}
+			if event.JobID != jobID {
+				continue
+			}
+			if err := stream.Send(toJobEvent(event)); err != nil {
+				return err
+			}
+			switch event.Type {
+			case models.JobEventCompleted, models.JobEventFailed, models.JobEventCancelled:
+				return nil
+			}
+		}
+	}
+}
+
+// GetResult fetches the result of a job or a result by its ID
+func (s *Server) GetResult(ctx context.Context, msg *visekaiv1.GetResultRequest) (*visekaiv1.Result, error) {
+	userID := callerFrom(ctx).userID
+
+	var result *models.OCRResult
+	switch {
+	case msg.GetJobId() != "":
+		jobID, err := uuid.Parse(msg.GetJobId())
+		if err != nil {
+			return nil, &status{code: codes.InvalidArgument, message: "Invalid job ID", errorCode: "VAL_008"}
+		}
+		result, err = s.jobService.GetJobResult(ctx, jobID, userID)
+		if err != nil {
+			return nil, err
+		}
+	case msg.GetResultId() != "":
+		resultID, err := uuid.Parse(msg.GetResultId())
+		if err != nil {
+			return nil, &status{code: codes.InvalidArgument, message: "Invalid result ID", errorCode: "VAL_001"}
+		}
+		result, err = s.resultService.GetResult(ctx, resultID, userID)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newStatus(codes.InvalidArgument, "job_id or result_id is required")
+	}
+
+	return toResult(result), nil
+}
+
+// finished reports whether a job has completed, failed or been cancelled
+func finished(status models.JobStatus) bool {
+	return status == models.JobStatusCompleted || status == models.JobStatusFailed || status == models.JobStatusCancelled
+}
+
+// chunkReader reads the file of an UploadDocument stream from the chunks
+// following its metadata
+type chunkReader struct {
+	stream grpc.ClientStreamingServer[visekaiv1.UploadDocumentRequest, visekaiv1.UploadDocumentResponse]
+	chunk  []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		msg, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		if msg.GetMetadata() != nil {
+			return 0, newStatus(codes.InvalidArgument, "metadata may only be sent first")
+		}
+		r.chunk = msg.GetChunk()
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
@@ -0,0 +1,100 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/storage"
+
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// kindCodes maps each kind of application error to the status it is
+// reported with
+var kindCodes = map[error]codes.Code{
+	apperrors.ErrNotFound:   codes.NotFound,
+	apperrors.ErrForbidden:  codes.PermissionDenied,
+	apperrors.ErrConflict:   codes.FailedPrecondition,
+	apperrors.ErrValidation: codes.InvalidArgument,
+	apperrors.ErrPayment:    codes.ResourceExhausted,
+}
+
+// uploadErrorCodes maps errors rejecting the content of an upload to the error
+// code the REST upload reports
+var uploadErrorCodes = map[error]string{
+	storage.ErrFileTooLarge:    "VAL_005",
+	storage.ErrEncryptedPDF:    "VAL_014",
+	storage.ErrCorruptPDF:      "VAL_015",
+	storage.ErrContentMismatch: "VAL_013",
+}
+
+// status is the outcome of a call, sent in its trailers
+type status struct {
+	code    codes.Code
+	message string
+	// errorCode is the error code the REST API reports for the same error,
+	// such as RES_003
+	errorCode string
+}
+
+func (s *status) Error() string {
+	return fmt.Sprintf("gRPC status %s: %s", s.code, s.message)
+}
+
+// newStatus returns an error ending a call with a status
+func newStatus(code codes.Code, message string) *status {
+	return &status{code: code, message: message}
+}
+
+// statusOf returns the status a call ending with err reports. Application
+// errors keep their message and error code; other errors are internal.
+func statusOf(err error) *status {
+	if err == nil {
+		return &status{code: codes.OK}
+	}
+
+	var st *status
+	if errors.As(err, &st) {
+		return st
+	}
+	// Errors of the transport, such as a client cancelling its stream
+	if s, ok := grpcstatus.FromError(err); ok {
+		return newStatus(s.Code(), s.Message())
+	}
+
+	var violation *services.PolicyViolationError
+	if errors.As(err, &violation) {
+		return &status{code: codes.InvalidArgument, message: violation.Message, errorCode: "JOB_005"}
+	}
+	if errors.Is(err, services.ErrScanUnavailable) {
+		return &status{code: codes.Unavailable, message: "virus scan unavailable, please try again later", errorCode: "SYS_013"}
+	}
+	for uploadErr, code := range uploadErrorCodes {
+		if errors.Is(err, uploadErr) {
+			return &status{code: codes.InvalidArgument, message: uploadErr.Error(), errorCode: code}
+		}
+	}
+
+	for kind, code := range kindCodes {
+		if !errors.Is(err, kind) {
+			continue
+		}
+		var appErr *apperrors.Error
+		if errors.As(err, &appErr) {
+			return &status{code: code, message: appErr.Message, errorCode: appErr.Code}
+		}
+		return newStatus(code, kind.Error())
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return newStatus(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return newStatus(codes.Canceled, "call cancelled")
+	}
+	return newStatus(codes.Internal, "internal error")
+}
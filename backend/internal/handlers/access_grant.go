@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AccessGrantHandler handles temporary access to documents granted to other users
+type AccessGrantHandler struct {
+	grantService *services.AccessGrantService
+	auditService *services.AuditService
+	validator    *validator.Validator
+}
+
+// NewAccessGrantHandler creates a new access grant handler
+func NewAccessGrantHandler(grantService *services.AccessGrantService, auditService *services.AuditService) *AccessGrantHandler {
+	return &AccessGrantHandler{
+		grantService: grantService,
+		auditService: auditService,
+		validator:    validator.New(),
+	}
+}
+
+// Create grants another user temporary access to some of the user's documents
+func (h *AccessGrantHandler) Create(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.AccessGrantCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	grant, err := h.grantService.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_038", "Failed to grant access")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditGrantCreated, "access_grant", &grant.ID, map[string]any{
+		"grantee_id":   grant.GranteeID,
+		"document_ids": grant.DocumentIDs,
+		"expires_at":   grant.ExpiresAt,
+	}))
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		grant,
+		"Access granted successfully",
+	))
+}
+
+// List returns the grants the user has given
+func (h *AccessGrantHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	grants, err := h.grantService.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_039", "Failed to list access grants")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		grants,
+		"Access grants retrieved successfully",
+	))
+}
+
+// ListReceived returns the active grants the user has received
+func (h *AccessGrantHandler) ListReceived(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	grants, err := h.grantService.ListReceived(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_039", "Failed to list access grants")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		grants,
+		"Access grants retrieved successfully",
+	))
+}
+
+// Revoke ends one of the user's grants before it expires
+func (h *AccessGrantHandler) Revoke(c *gin.Context) {
+	userID, grantID, ok := h.grantRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.grantService.Revoke(c.Request.Context(), grantID, userID); err != nil {
+		respondError(c, err, "SYS_040", "Failed to revoke access grant")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditGrantRevoked, "access_grant", &grantID, nil))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Access grant revoked successfully",
+	))
+}
+
+// Activity returns the audit trail of one of the user's grants, including
+// every access made under it
+func (h *AccessGrantHandler) Activity(c *gin.Context) {
+	userID, grantID, ok := h.grantRequest(c)
+	if !ok {
+		return
+	}
+
+	page, perPage, ok := pageParams(c, 50)
+	if !ok {
+		return
+	}
+
+	events, total, err := h.grantService.Activity(c.Request.Context(), grantID, userID, page, perPage)
+	if err != nil {
+		respondError(c, err, "SYS_041", "Failed to list access grant activity")
+		return
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		models.PaginatedResponse{
+			Items: events,
+			Pagination: models.Pagination{
+				Page:       page,
+				PerPage:    perPage,
+				Total:      total,
+				TotalPages: totalPages,
+				HasNext:    page < totalPages,
+				HasPrev:    page > 1,
+			},
+		},
+		"Access grant activity retrieved successfully",
+	))
+}
+
+// ListDocuments returns the documents shared with the user under a grant
+func (h *AccessGrantHandler) ListDocuments(c *gin.Context) {
+	userID, grantID, ok := h.grantRequest(c)
+	if !ok {
+		return
+	}
+
+	grant, documents, err := h.grantService.ListDocuments(c.Request.Context(), grantID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_042", "Failed to get shared documents")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditGrantAccessed, "access_grant", &grant.ID, map[string]any{
+		"owner_id": grant.OwnerID,
+		"resource": "documents",
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		documents,
+		"Shared documents retrieved successfully",
+	))
+}
+
+// GetDocument returns a document shared with the user under a grant, with its
+// latest result
+func (h *AccessGrantHandler) GetDocument(c *gin.Context) {
+	userID, grantID, ok := h.grantRequest(c)
+	if !ok {
+		return
+	}
+
+	// Parse document ID
+	documentID, err := uuid.Parse(c.Param("document_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_007",
+			"Invalid document ID",
+			nil,
+		))
+		return
+	}
+
+	grant, granted, err := h.grantService.GetDocument(c.Request.Context(), grantID, userID, documentID)
+	if err != nil {
+		respondError(c, err, "SYS_042", "Failed to get shared documents")
+		return
+	}
+
+	metadata := map[string]any{
+		"owner_id":    grant.OwnerID,
+		"resource":    "document",
+		"document_id": documentID,
+	}
+	if granted.Result != nil {
+		metadata["result_id"] = granted.Result.ID
+	}
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditGrantAccessed, "access_grant", &grant.ID, metadata))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		granted,
+		"Shared document retrieved successfully",
+	))
+}
+
+// grantRequest returns the authenticated user and the grant named in the path
+func (h *AccessGrantHandler) grantRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	grantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_025",
+			"Invalid access grant ID",
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, grantID, true
+}
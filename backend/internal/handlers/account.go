@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler handles users managing their own account
+type AccountHandler struct {
+	accountService *services.AccountService
+	auditService   *services.AuditService
+	validator      *validator.Validator
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService *services.AccountService, auditService *services.AuditService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		auditService:   auditService,
+		validator:      validator.New(),
+	}
+}
+
+// UpdateProfile changes the user's name and email
+func (h *AccountHandler) UpdateProfile(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.UserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	user, err := h.accountService.UpdateProfile(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_080", "Failed to update profile")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditProfileUpdated, "user", &userID, map[string]any{
+		"name_changed":  req.Name != nil,
+		"email_changed": req.Email != nil,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		user.ToResponse(),
+		"Profile updated successfully",
+	))
+}
+
+// Delete schedules the deletion of the user's account after the grace
+// period, or deletes it right away when there is none
+func (h *AccountHandler) Delete(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request; users without a password may send no body
+	var req models.AccountDeletionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				"Invalid request body",
+				nil,
+			))
+			return
+		}
+	}
+
+	user, err := h.accountService.RequestDeletion(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		respondError(c, err, "SYS_081", "Failed to delete account")
+		return
+	}
+
+	if user == nil {
+		// The account is gone, so the event cannot name who deleted it
+		event := newAuditEvent(c, models.AuditDeletionRequested, "user", nil, nil)
+		event.ActorID = nil
+		event.ActorEmail = ""
+		event.IPAddress = ""
+		event.UserAgent = ""
+		h.auditService.Record(c.Request.Context(), event)
+
+		c.JSON(http.StatusOK, models.NewSuccessResponse(
+			nil,
+			"Account deleted successfully",
+		))
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditDeletionRequested, "user", &userID, map[string]any{
+		"scheduled_at": user.DeletionScheduledAt,
+	}))
+
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(
+		user.ToResponse(),
+		"Account deletion scheduled; export your data or cancel before it is deleted",
+	))
+}
+
+// CancelDeletion keeps an account whose deletion was requested
+func (h *AccountHandler) CancelDeletion(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	user, err := h.accountService.CancelDeletion(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_082", "Failed to cancel account deletion")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditDeletionCancelled, "user", &userID, nil))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		user.ToResponse(),
+		"Account deletion cancelled successfully",
+	))
+}
+
+// Export returns the user's personal data as a JSON file
+func (h *AccountHandler) Export(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	export, err := h.accountService.Export(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_083", "Failed to export account")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditAccountExported, "user", &userID, map[string]any{
+		"documents": len(export.Documents),
+	}))
+
+	setAttachment(c, "visekai-account-"+export.ExportedAt.Format("20060102")+".json")
+	c.JSON(http.StatusOK, export)
+}
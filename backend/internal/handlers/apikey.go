@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler handles management of the user's API keys
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+	validator     *validator.Validator
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		validator:     validator.New(),
+	}
+}
+
+// Create issues a new API key and returns its secret once
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	// Get authenticated user
+	userID, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.APIKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	key, err := h.apiKeyService.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		key,
+		"API key created successfully; store the key now, it will not be shown again",
+	))
+}
+
+// List returns the user's API keys without their secrets
+func (h *APIKeyHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_017",
+			"Failed to list API keys",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		keys,
+		"API keys retrieved successfully",
+	))
+}
+
+// Revoke deactivates one of the user's API keys
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	// Get authenticated user
+	userID, ok := h.sessionUser(c)
+	if !ok {
+		return
+	}
+
+	// Parse key ID
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_019",
+			"Invalid API key ID",
+			nil,
+		))
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(c.Request.Context(), keyID, userID); err != nil {
+		respondError(c, err, "SYS_036", "Failed to revoke API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"API key revoked successfully",
+	))
+}
+
+// sessionUser returns the authenticated user, rejecting requests made with an
+// API key so a leaked key cannot be used to mint further keys
+func (h *APIKeyHandler) sessionUser(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, false
+	}
+
+	if _, ok := middleware.GetAPIKeyID(c); ok {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(
+			"AUTH_006",
+			"API keys cannot manage API keys",
+			nil,
+		))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ApprovalHandler handles approving and rejecting jobs held by the OCR policy
+type ApprovalHandler struct {
+	approvalService *services.ApprovalService
+	auditService    *services.AuditService
+	validator       *validator.Validator
+}
+
+// NewApprovalHandler creates a new approval handler
+func NewApprovalHandler(approvalService *services.ApprovalService, auditService *services.AuditService) *ApprovalHandler {
+	return &ApprovalHandler{
+		approvalService: approvalService,
+		auditService:    auditService,
+		validator:       validator.New(),
+	}
+}
+
+// List returns the jobs awaiting approval, oldest first
+func (h *ApprovalHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	page, perPage, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	approvals, pagination, err := h.approvalService.List(c.Request.Context(), userID, page, perPage)
+	if err != nil {
+		respondError(c, err, "SYS_030", "Failed to list jobs awaiting approval")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		models.PaginatedResponse{
+			Items:      approvals,
+			Pagination: *pagination,
+		},
+		"Jobs awaiting approval retrieved successfully",
+	))
+}
+
+// Approve releases a held job into the queue
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	h.decide(c, models.ApprovalApproved)
+}
+
+// Reject cancels a held job
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	h.decide(c, models.ApprovalRejected)
+}
+
+func (h *ApprovalHandler) decide(c *gin.Context, decision models.ApprovalDecision) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse job ID
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_008",
+			"Invalid job ID",
+			nil,
+		))
+		return
+	}
+
+	// The comment is optional, so the body may be empty
+	var req models.ApprovalDecisionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				"Invalid request body",
+				nil,
+			))
+			return
+		}
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	var approval *models.JobApproval
+	action := models.AuditJobApproved
+	if decision == models.ApprovalApproved {
+		approval, err = h.approvalService.Approve(c.Request.Context(), jobID, userID, req.Comment)
+	} else {
+		action = models.AuditJobRejected
+		approval, err = h.approvalService.Reject(c.Request.Context(), jobID, userID, req.Comment)
+	}
+	if err != nil {
+		respondError(c, err, "SYS_031", "Failed to record approval decision")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, action, "job", &jobID, map[string]any{
+		"reason":  approval.Reason,
+		"comment": req.Comment,
+	}))
+
+	message := "Job approved successfully"
+	if decision == models.ApprovalRejected {
+		message = "Job rejected successfully"
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		approval,
+		message,
+	))
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AuditHandler handles admin requests for the audit log
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// List handles listing audit events with optional filters
+func (h *AuditHandler) List(c *gin.Context) {
+	filter := models.AuditFilter{
+		Action:  models.AuditAction(c.Query("action")),
+		Page:    1,
+		PerPage: 50,
+	}
+
+	for param, target := range map[string]**uuid.UUID{"actor_id": &filter.ActorID, "target_id": &filter.TargetID} {
+		if raw := c.Query(param); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					"VAL_001",
+					"Invalid "+param,
+					nil,
+				))
+				return
+			}
+			*target = &id
+		}
+	}
+
+	for param, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					"VAL_001",
+					param+" must be an RFC3339 timestamp",
+					nil,
+				))
+				return
+			}
+			*target = &t
+		}
+	}
+
+	page, perPage, ok := pageParams(c, filter.PerPage)
+	if !ok {
+		return
+	}
+	filter.Page, filter.PerPage = page, perPage
+
+	events, total, err := h.auditService.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_014",
+			"Failed to list audit events",
+			nil,
+		))
+		return
+	}
+
+	// Calculate pagination
+	totalPages := (total + filter.PerPage - 1) / filter.PerPage
+	pagination := models.Pagination{
+		Page:       filter.Page,
+		PerPage:    filter.PerPage,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    filter.Page < totalPages,
+		HasPrev:    filter.Page > 1,
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		models.PaginatedResponse{
+			Items:      events,
+			Pagination: pagination,
+		},
+		"Audit events retrieved successfully",
+	))
+}
+
+// newAuditEvent creates an audit event for the current request, attributed to the
+// authenticated user when there is one
+func newAuditEvent(c *gin.Context, action models.AuditAction, targetType string, targetID *uuid.UUID, metadata map[string]any) *models.AuditEvent {
+	event := &models.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		RequestID:  middleware.GetRequestID(c),
+		Metadata:   metadata,
+	}
+	if userID, err := middleware.GetUserID(c); err == nil {
+		event.ActorID = &userID
+	}
+	if email, ok := c.Get("user_email"); ok {
+		event.ActorEmail, _ = email.(string)
+	}
+	return event
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"visekai/backend/internal/middleware"
 	"visekai/backend/internal/models"
@@ -14,17 +15,19 @@ import (
 
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
-	authService *services.AuthService
-	userRepo    *repository.UserRepository
-	validator   *validator.Validator
+	authService  *services.AuthService
+	auditService *services.AuditService
+	userRepo     *repository.UserRepository
+	validator    *validator.Validator
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(authService *services.AuthService, userRepo *repository.UserRepository) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, auditService *services.AuditService, userRepo *repository.UserRepository) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		userRepo:    userRepo,
-		validator:   validator.New(),
+		authService:  authService,
+		auditService: auditService,
+		userRepo:     userRepo,
+		validator:    validator.New(),
 	}
 }
 
@@ -65,11 +68,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// Register user
 	_, err := h.authService.Register(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"AUTH_001",
-			err.Error(),
-			nil,
-		))
+		respondError(c, err, "SYS_001", "Failed to register user")
 		return
 	}
 
@@ -121,6 +120,10 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Login user
 	authResponse, err := h.authService.Login(c.Request.Context(), req)
 	if err != nil {
+		event := newAuditEvent(c, models.AuditLoginFailed, "", nil, nil)
+		event.ActorEmail = strings.ToLower(strings.TrimSpace(req.Email))
+		h.auditService.Record(c.Request.Context(), event)
+
 		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
 			"AUTH_001",
 			err.Error(),
@@ -129,6 +132,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	event := newAuditEvent(c, models.AuditLogin, "user", &authResponse.User.ID, nil)
+	event.ActorID = &authResponse.User.ID
+	event.ActorEmail = authResponse.User.Email
+	h.auditService.Record(c.Request.Context(), event)
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(
 		authResponse,
 		"Login successful",
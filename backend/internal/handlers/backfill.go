@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BackfillHandler handles the backfill tasks admins run over existing data
+type BackfillHandler struct {
+	backfillService *services.BackfillService
+	auditService    *services.AuditService
+}
+
+// NewBackfillHandler creates a new backfill handler
+func NewBackfillHandler(backfillService *services.BackfillService, auditService *services.AuditService) *BackfillHandler {
+	return &BackfillHandler{
+		backfillService: backfillService,
+		auditService:    auditService,
+	}
+}
+
+// List returns the backfill tasks with their latest runs
+func (h *BackfillHandler) List(c *gin.Context) {
+	backfillTasks, err := h.backfillService.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "SYS_126", "Failed to list backfill tasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		backfillTasks,
+		"Backfill tasks retrieved successfully",
+	))
+}
+
+// Get returns a backfill task with its most recent runs
+func (h *BackfillHandler) Get(c *gin.Context) {
+	task, runs, err := h.backfillService.Runs(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondError(c, err, "SYS_126", "Failed to get backfill task")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		gin.H{
+			"task": task,
+			"runs": runs,
+		},
+		"Backfill task retrieved successfully",
+	))
+}
+
+// Run starts a backfill task, resuming its failed or cancelled latest run
+// unless restart is set. The task runs in the background.
+func (h *BackfillHandler) Run(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	restart, err := strconv.ParseBool(c.DefaultQuery("restart", "false"))
+	if err != nil {
+		respondInvalidQuery(c, []models.ValidationError{{Field: "restart", Message: "must be true or false"}})
+		return
+	}
+
+	run, err := h.backfillService.Run(c.Request.Context(), c.Param("name"), userID, restart)
+	if err != nil {
+		respondError(c, err, "SYS_127", "Failed to start backfill task")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditBackfillStarted, "backfill_run", &run.ID, map[string]any{
+		"task":    run.Task,
+		"restart": restart,
+	}))
+
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(
+		run,
+		"Backfill task started",
+	))
+}
+
+// Cancel cancels the pending or running run of a backfill task
+func (h *BackfillHandler) Cancel(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	run, err := h.backfillService.Cancel(c.Request.Context(), c.Param("name"), userID)
+	if err != nil {
+		respondError(c, err, "SYS_128", "Failed to cancel backfill task")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditBackfillCancelled, "backfill_run", &run.ID, map[string]any{
+		"task": run.Task,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		run,
+		"Backfill task cancelled",
+	))
+}
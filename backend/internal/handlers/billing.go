@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBillingWebhookBytes bounds the Stripe webhook payloads read
+const maxBillingWebhookBytes = 1 << 20
+
+// BillingHandler handles credit purchases
+type BillingHandler struct {
+	billingService *services.BillingService
+	validator      *validator.Validator
+}
+
+// NewBillingHandler creates a new billing handler
+func NewBillingHandler(billingService *services.BillingService) *BillingHandler {
+	return &BillingHandler{
+		billingService: billingService,
+		validator:      validator.New(),
+	}
+}
+
+// ListPacks returns the credit packs for sale
+func (h *BillingHandler) ListPacks(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		h.billingService.Packs(),
+		"Credit packs retrieved successfully",
+	))
+}
+
+// Checkout starts the purchase of a credit pack and returns the payment page
+// to redirect the user to
+func (h *BillingHandler) Checkout(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	session, err := h.billingService.Checkout(c.Request.Context(), userID, req.PackID)
+	if err != nil {
+		respondError(c, err, "SYS_047", "Failed to start checkout")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		session,
+		"Checkout started successfully",
+	))
+}
+
+// Webhook receives Stripe's payment events. It is unauthenticated; events are
+// verified by their signature.
+func (h *BillingHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBillingWebhookBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	err = h.billingService.HandleWebhook(c.Request.Context(), payload, c.GetHeader("Stripe-Signature"))
+	if err != nil {
+		respondError(c, err, "SYS_048", "Failed to process billing event")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Event received",
+	))
+}
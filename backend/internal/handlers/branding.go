@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BrandingHandler handles the branding and custom domain of organizations and
+// the instance-wide branding of users outside them
+type BrandingHandler struct {
+	brandingService *services.BrandingService
+	auditService    *services.AuditService
+	validator       *validator.Validator
+}
+
+// NewBrandingHandler creates a new branding handler
+func NewBrandingHandler(brandingService *services.BrandingService, auditService *services.AuditService) *BrandingHandler {
+	return &BrandingHandler{
+		brandingService: brandingService,
+		auditService:    auditService,
+		validator:       validator.New(),
+	}
+}
+
+// GetPublic returns the branding for the host the request was made to, for
+// pages shown to viewers who are not signed in: that of the organization whose
+// custom domain the host is, otherwise the instance-wide branding
+func (h *BrandingHandler) GetPublic(c *gin.Context) {
+	branding, err := h.brandingService.ForHost(c.Request.Context(), c.Request.Host)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_027",
+			"Failed to get branding",
+			nil,
+		))
+		return
+	}
+
+	// The response depends on the host, so shared caches must key on it
+	c.Header("Vary", "Host")
+	c.Header("Cache-Control", "public, max-age=60")
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		branding,
+		"Branding retrieved successfully",
+	))
+}
+
+// Get returns the instance-wide branding with the custom domain's
+// verification details
+func (h *BrandingHandler) Get(c *gin.Context) {
+	branding, err := h.brandingService.Get(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_027",
+			"Failed to get branding",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		branding,
+		"Branding retrieved successfully",
+	))
+}
+
+// Update changes the fields of the instance-wide branding present in the request
+func (h *BrandingHandler) Update(c *gin.Context) {
+	// Get authenticated admin
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	req, ok := h.bindUpdate(c)
+	if !ok {
+		return
+	}
+
+	branding, err := h.brandingService.Update(c.Request.Context(), req, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_028",
+			"Failed to update branding",
+			nil,
+		))
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditBrandingUpdated, "branding", nil, brandingAuditMetadata(branding)))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		branding,
+		"Branding updated successfully",
+	))
+}
+
+// VerifyDomain checks the DNS verification record of the instance-wide custom
+// domain
+func (h *BrandingHandler) VerifyDomain(c *gin.Context) {
+	branding, err := h.brandingService.VerifyDomain(c.Request.Context())
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditDomainVerified, "branding", nil, map[string]any{
+		"custom_domain": branding.CustomDomain.Domain,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		branding,
+		"Custom domain verified successfully",
+	))
+}
+
+// GetForOrg returns the branding of an organization the user is a member of
+func (h *BrandingHandler) GetForOrg(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	branding, err := h.brandingService.GetForOrg(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_027", "Failed to get branding")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		branding,
+		"Branding retrieved successfully",
+	))
+}
+
+// UpdateForOrg changes the fields of the branding of an organization the user
+// owns present in the request
+func (h *BrandingHandler) UpdateForOrg(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	req, ok := h.bindUpdate(c)
+	if !ok {
+		return
+	}
+
+	branding, err := h.brandingService.UpdateForOrg(c.Request.Context(), orgID, userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_028", "Failed to update branding")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditBrandingUpdated, "organization", &orgID, brandingAuditMetadata(branding)))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		branding,
+		"Branding updated successfully",
+	))
+}
+
+// VerifyDomainForOrg checks the DNS verification record of the custom domain
+// of an organization the user owns
+func (h *BrandingHandler) VerifyDomainForOrg(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	branding, err := h.brandingService.VerifyDomainForOrg(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondDomainError(c, err)
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditDomainVerified, "organization", &orgID, map[string]any{
+		"custom_domain": branding.CustomDomain.Domain,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		branding,
+		"Custom domain verified successfully",
+	))
+}
+
+// bindUpdate parses and validates a branding update, having responded with an
+// error when it is invalid
+func (h *BrandingHandler) bindUpdate(c *gin.Context) (models.UpdateBrandingRequest, bool) {
+	// Parse request
+	var req models.UpdateBrandingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return req, false
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return req, false
+	}
+
+	return req, true
+}
+
+// orgRequest returns the authenticated user and the organization in the path,
+// having responded with an error when either is missing
+func (h *BrandingHandler) orgRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	orgID, ok := parseIDParam(c, "id", "VAL_029", "Invalid organization ID")
+	return userID, orgID, ok
+}
+
+// respondDomainError responds to a failed custom domain verification
+func respondDomainError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDomainNotVerified):
+		c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+			"BRAND_001",
+			err.Error(),
+			nil,
+		))
+	case apperrors.HTTPStatus(err) != http.StatusInternalServerError:
+		respondError(c, err, "SYS_029", "Failed to look up domain verification record")
+	default:
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+			"SYS_029",
+			"Failed to look up domain verification record",
+			nil,
+		))
+	}
+}
+
+// brandingAuditMetadata returns the branding fields recorded in audit events
+func brandingAuditMetadata(branding *models.Branding) map[string]any {
+	metadata := map[string]any{
+		"display_name":  branding.DisplayName,
+		"logo_url":      branding.LogoURL,
+		"brand_exports": branding.BrandExports,
+	}
+	if branding.CustomDomain != nil {
+		metadata["custom_domain"] = branding.CustomDomain.Domain
+	}
+	return metadata
+}
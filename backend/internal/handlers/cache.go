@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"visekai/backend/internal/middleware"
+	"visekai/backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Names of the response caches in front of endpoints shared by all callers
+const (
+	CacheSetupStatus = "setup_status"
+)
+
+// invalidateCache drops the cached responses of a cache after the data behind
+// them changed. A nil cache is ignored; on failure entries expire after the TTL.
+func invalidateCache(c *gin.Context, cache middleware.ResponseCache, name string) {
+	if cache == nil {
+		return
+	}
+	if err := cache.Invalidate(c.Request.Context(), name); err != nil {
+		logger.Warn("Failed to invalidate response cache", "cache", name, "error", err)
+	}
+}
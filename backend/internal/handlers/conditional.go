@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondConditional writes a success response holding data, or 304 Not Modified
+// when the client already has the same representation. The ETag covers data only,
+// not the envelope, whose timestamp changes on every request. A zero lastModified
+// omits Last-Modified, as for lists, where removing an item changes the content
+// without changing any timestamp.
+func respondConditional(c *gin.Context, data any, lastModified time.Time, message string) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(data, message))
+		return
+	}
+
+	if notModified(c, weakETag(body), lastModified) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(data, message))
+}
+
+// weakETag returns a weak entity tag derived from the given parts. Tags are weak
+// because the JSON encoding is not guaranteed to be byte-for-byte stable.
+func weakETag(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag, Last-Modified and Cache-Control headers and reports
+// whether the request's conditional headers match them, in which case a 304
+// response was written. If-None-Match takes precedence over If-Modified-Since.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if !etagMatches(inm, etag) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(ims) {
+			return false
+		}
+	}
+
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists the tag, using the
+// weak comparison RFC 9110 prescribes for If-None-Match
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// connectorStateCookie binds the linking of an account to the browser that
+	// started it, so that a callback cannot link an account to someone else
+	connectorStateCookie = "connector_state"
+	// connectorCallbackPath is the path of the callback the cookie is sent to
+	connectorCallbackPath = "/api/v1/connectors/callback"
+)
+
+// ConnectorHandler handles linking Google Drive and Dropbox accounts and
+// picking the folders documents are imported from
+type ConnectorHandler struct {
+	importService *services.ImportService
+	validator     *validator.Validator
+	successURL    string
+}
+
+// NewConnectorHandler creates a new connector handler. Once an account is
+// linked the user is redirected to successURL with the connection's ID in its
+// query, or gets the connection as JSON when it is empty.
+func NewConnectorHandler(importService *services.ImportService, successURL string) *ConnectorHandler {
+	return &ConnectorHandler{
+		importService: importService,
+		validator:     validator.New(),
+		successURL:    successURL,
+	}
+}
+
+// Authorize starts linking an account and returns where to send the user to
+// grant access to it
+func (h *ConnectorHandler) Authorize(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.ConnectorAuthorizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	origin := requestOrigin(c)
+	authorization, state, err := h.importService.Authorize(c.Request.Context(), userID, req.Provider, origin)
+	if err != nil {
+		respondError(c, err, "SYS_103", "Failed to start linking account")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(connectorStateCookie, state, 600, connectorCallbackPath, "", strings.HasPrefix(origin, "https://"), true)
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		authorization,
+		"Account linking started successfully",
+	))
+}
+
+// Callback links the account with the code the provider redirected back with
+func (h *ConnectorHandler) Callback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(
+			"CONN_002",
+			"Access to the account was not granted: "+providerError,
+			nil,
+		))
+		return
+	}
+
+	state := c.Query("state")
+	cookie, err := c.Cookie(connectorStateCookie)
+	if err != nil || state == "" || cookie != state || c.Query("code") == "" {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(
+			"CONN_002",
+			services.ErrConnectorStateInvalid.Message,
+			nil,
+		))
+		return
+	}
+	c.SetCookie(connectorStateCookie, "", -1, connectorCallbackPath, "", strings.HasPrefix(requestOrigin(c), "https://"), true)
+
+	connection, err := h.importService.Complete(c.Request.Context(), c.Query("code"), state)
+	if err != nil {
+		respondError(c, err, "SYS_104", "Failed to link account")
+		return
+	}
+
+	if h.successURL != "" {
+		query := url.Values{}
+		query.Set("connection_id", connection.ID.String())
+		c.Redirect(http.StatusFound, h.successURL+"?"+query.Encode())
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		connection,
+		"Account linked successfully",
+	))
+}
+
+// List returns the user's linked accounts
+func (h *ConnectorHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	connections, err := h.importService.ListConnections(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_105", "Failed to list connections")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		connections,
+		"Connections retrieved successfully",
+	))
+}
+
+// ListFolders lists the folders of a linked account inside ?parent=, or at its
+// root
+func (h *ConnectorHandler) ListFolders(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	connectionID, ok := parseIDParam(c, "id", "VAL_056", "Invalid connection ID")
+	if !ok {
+		return
+	}
+
+	folders, err := h.importService.ListFolders(c.Request.Context(), connectionID, userID, c.Query("parent"))
+	if err != nil {
+		respondError(c, err, "SYS_106", "Failed to list folders")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		folders,
+		"Folders retrieved successfully",
+	))
+}
+
+// Update picks the folder a linked account imports from
+func (h *ConnectorHandler) Update(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	connectionID, ok := parseIDParam(c, "id", "VAL_056", "Invalid connection ID")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.ImportConnectionUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	connection, err := h.importService.Update(c.Request.Context(), connectionID, userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_107", "Failed to update connection")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		connection,
+		"Connection updated successfully",
+	))
+}
+
+// Delete unlinks an account
+func (h *ConnectorHandler) Delete(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	connectionID, ok := parseIDParam(c, "id", "VAL_056", "Invalid connection ID")
+	if !ok {
+		return
+	}
+
+	if err := h.importService.Delete(c.Request.Context(), connectionID, userID); err != nil {
+		respondError(c, err, "SYS_108", "Failed to delete connection")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Connection deleted successfully",
+	))
+}
+
+// Sync imports the new files of a linked account's folder without waiting for
+// its next scheduled sync
+func (h *ConnectorHandler) Sync(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	connectionID, ok := parseIDParam(c, "id", "VAL_056", "Invalid connection ID")
+	if !ok {
+		return
+	}
+
+	if err := h.importService.SyncNow(c.Request.Context(), connectionID, userID); err != nil {
+		respondError(c, err, "SYS_109", "Failed to sync connection")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(
+		nil,
+		"Connection sync scheduled",
+	))
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreditHandler handles credit usage requests
+type CreditHandler struct {
+	creditService *services.CreditService
+}
+
+// NewCreditHandler creates a new credit handler
+func NewCreditHandler(creditService *services.CreditService) *CreditHandler {
+	return &CreditHandler{creditService: creditService}
+}
+
+// GetCredits returns the user's credit balance, the cost of a page at each
+// resolution and a page of their credit transactions
+func (h *CreditHandler) GetCredits(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	page, perPage, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	usage, err := h.creditService.Usage(c.Request.Context(), userID, page, perPage)
+	if err != nil {
+		respondError(c, err, "SYS_044", "Failed to get credit usage")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		usage,
+		"Credit usage retrieved successfully",
+	))
+}
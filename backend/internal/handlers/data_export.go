@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DataExportHandler handles users exporting all of their data
+type DataExportHandler struct {
+	dataExportService *services.DataExportService
+	auditService      *services.AuditService
+}
+
+// NewDataExportHandler creates a new data export handler
+func NewDataExportHandler(dataExportService *services.DataExportService, auditService *services.AuditService) *DataExportHandler {
+	return &DataExportHandler{
+		dataExportService: dataExportService,
+		auditService:      auditService,
+	}
+}
+
+// Request starts building an archive of the user's documents, results and
+// account data
+func (h *DataExportHandler) Request(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	export, err := h.dataExportService.Request(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_084", "Failed to request data export")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditTakeoutRequested, "data_export", &export.ID, nil))
+
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(
+		export,
+		"Data export requested successfully",
+	))
+}
+
+// List returns the user's data exports
+func (h *DataExportHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	exports, err := h.dataExportService.List(c.Request.Context(), userID, requestOrigin(c))
+	if err != nil {
+		respondError(c, err, "SYS_085", "Failed to list data exports")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		exports,
+		"Data exports retrieved successfully",
+	))
+}
+
+// Get returns the status of a data export, with its download link once ready
+func (h *DataExportHandler) Get(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	exportID, ok := parseIDParam(c, "id", "VAL_041", "Invalid data export ID")
+	if !ok {
+		return
+	}
+
+	export, err := h.dataExportService.Get(c.Request.Context(), exportID, userID, requestOrigin(c))
+	if err != nil {
+		respondError(c, err, "SYS_086", "Failed to get data export")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		export,
+		"Data export retrieved successfully",
+	))
+}
+
+// Download streams the archive of a data export through its signed link
+func (h *DataExportHandler) Download(c *gin.Context) {
+	exportID, ok := parseIDParam(c, "id", "VAL_041", "Invalid data export ID")
+	if !ok {
+		return
+	}
+
+	export, file, err := h.dataExportService.Open(c.Request.Context(), exportID, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		respondError(c, err, "SYS_087", "Failed to download data export")
+		return
+	}
+	defer file.Close()
+
+	event := newAuditEvent(c, models.AuditTakeoutDownloaded, "data_export", &export.ID, nil)
+	event.ActorID = &export.UserID
+	h.auditService.Record(c.Request.Context(), event)
+
+	c.Header("Cache-Control", "no-store")
+	setAttachment(c, "visekai-export-"+export.CreatedAt.Format("20060102")+".zip")
+	c.DataFromReader(http.StatusOK, export.FileSize, "application/zip", file, nil)
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeliveryHandler handles the S3 buckets and SFTP servers users have their
+// results pushed to, and the deliveries to them
+type DeliveryHandler struct {
+	deliveryService *services.DeliveryService
+	validator       *validator.Validator
+}
+
+// NewDeliveryHandler creates a new delivery handler
+func NewDeliveryHandler(deliveryService *services.DeliveryService) *DeliveryHandler {
+	return &DeliveryHandler{
+		deliveryService: deliveryService,
+		validator:       validator.New(),
+	}
+}
+
+// List returns the user's delivery targets
+func (h *DeliveryHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	targets, err := h.deliveryService.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_118", "Failed to list delivery targets")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		targets,
+		"Delivery targets retrieved successfully",
+	))
+}
+
+// Create adds an S3 bucket or SFTP server as a delivery target
+func (h *DeliveryHandler) Create(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.DeliveryTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	target, err := h.deliveryService.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_119", "Failed to create delivery target")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		target,
+		"Delivery target created successfully",
+	))
+}
+
+// Update changes a delivery target
+func (h *DeliveryHandler) Update(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	targetID, ok := parseIDParam(c, "id", "VAL_058", "Invalid delivery target ID")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.DeliveryTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	target, err := h.deliveryService.Update(c.Request.Context(), targetID, userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_120", "Failed to update delivery target")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		target,
+		"Delivery target updated successfully",
+	))
+}
+
+// Delete removes a delivery target
+func (h *DeliveryHandler) Delete(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	targetID, ok := parseIDParam(c, "id", "VAL_058", "Invalid delivery target ID")
+	if !ok {
+		return
+	}
+
+	if err := h.deliveryService.Delete(c.Request.Context(), targetID, userID); err != nil {
+		respondError(c, err, "SYS_121", "Failed to delete delivery target")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Delivery target deleted successfully",
+	))
+}
+
+// Test writes a test file to a delivery target
+func (h *DeliveryHandler) Test(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	targetID, ok := parseIDParam(c, "id", "VAL_058", "Invalid delivery target ID")
+	if !ok {
+		return
+	}
+
+	if err := h.deliveryService.Test(c.Request.Context(), targetID, userID); err != nil {
+		respondError(c, err, "SYS_122", "Failed to test delivery target")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Test file delivered successfully",
+	))
+}
+
+// ListDeliveries returns the latest deliveries to a target, at most ?limit=
+func (h *DeliveryHandler) ListDeliveries(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	targetID, ok := parseIDParam(c, "id", "VAL_058", "Invalid delivery target ID")
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	deliveries, err := h.deliveryService.ListDeliveries(c.Request.Context(), targetID, userID, limit)
+	if err != nil {
+		respondError(c, err, "SYS_123", "Failed to list deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		deliveries,
+		"Deliveries retrieved successfully",
+	))
+}
+
+// ListJobDeliveries returns the deliveries of a job's result to each target
+func (h *DeliveryHandler) ListJobDeliveries(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	jobID, ok := parseIDParam(c, "id", "VAL_008", "Invalid job ID")
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.deliveryService.ListJobDeliveries(c.Request.Context(), jobID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_124", "Failed to list job deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		deliveries,
+		"Deliveries retrieved successfully",
+	))
+}
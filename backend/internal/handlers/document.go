@@ -1,11 +1,21 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	"visekai/backend/internal/apperrors"
 	"visekai/backend/internal/middleware"
 	"visekai/backend/internal/models"
 	"visekai/backend/internal/repository"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/logger"
 	"visekai/backend/pkg/storage"
 	"visekai/backend/pkg/validator"
 
@@ -15,26 +25,29 @@ import (
 
 // DocumentHandler handles document-related requests
 type DocumentHandler struct {
-	documentRepo *repository.DocumentRepository
-	storage      *storage.Storage
-	validator    *validator.Validator
-	maxFileSize  int64
-	allowedExts  []string
+	documentRepo  *repository.DocumentRepository
+	uploadService *services.UploadService
+	trashService  *services.TrashService
+	auditService  *services.AuditService
+	orientation   *services.OrientationService
+	validator     *validator.Validator
 }
 
 // NewDocumentHandler creates a new document handler
 func NewDocumentHandler(
 	documentRepo *repository.DocumentRepository,
-	storage *storage.Storage,
-	maxFileSize int64,
-	allowedExts []string,
+	uploadService *services.UploadService,
+	trashService *services.TrashService,
+	auditService *services.AuditService,
+	orientationService *services.OrientationService,
 ) *DocumentHandler {
 	return &DocumentHandler{
-		documentRepo: documentRepo,
-		storage:      storage,
-		validator:    validator.New(),
-		maxFileSize:  maxFileSize,
-		allowedExts:  allowedExts,
+		documentRepo:  documentRepo,
+		uploadService: uploadService,
+		trashService:  trashService,
+		auditService:  auditService,
+		orientation:   orientationService,
+		validator:     validator.New(),
 	}
 }
 
@@ -51,101 +64,158 @@ func (h *DocumentHandler) Upload(c *gin.Context) {
 		return
 	}
 
-	// Parse multipart form
-	err = c.Request.ParseMultipartForm(h.maxFileSize)
+	// Stream the file to storage as it arrives, validating it on the way
+	file, err := h.receiveUpload(c, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"VAL_003",
-			"File too large or invalid multipart form",
-			nil,
-		))
+		respondUploadError(c, err)
 		return
 	}
 
-	// Get uploaded file
-	file, err := c.FormFile("file")
+	opts, err := uploadOptions(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"VAL_004",
-			"No file uploaded",
-			nil,
-		))
+		h.uploadService.Discard(c.Request.Context(), file)
+
+		respondError(c, err, "VAL_029", "Invalid organization ID")
 		return
 	}
 
-	// Validate file size
-	if file.Size > h.maxFileSize {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"VAL_005",
-			"File size exceeds maximum allowed size",
-			nil,
-		))
+	outcome, err := h.uploadService.Store(c.Request.Context(), file, opts)
+	if err != nil {
+		respondStoreError(c, err, file)
 		return
 	}
 
-	// Validate file type
-	if !storage.ValidateFileType(file.Filename, h.allowedExts) {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"VAL_006",
-			"File type not allowed",
-			nil,
+	if outcome.Duplicate != nil {
+		c.Header("Location", outcome.Duplicate.ExistingDocumentURL)
+	}
+	if outcome.Existing {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(
+			models.UploadResponse{Document: outcome.Document, Duplicate: outcome.Duplicate},
+			"File already exists (duplicate detected)",
 		))
 		return
 	}
 
-	// Save file
-	filePath, fileHash, err := h.storage.SaveFile(file, userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"SYS_002",
-			"Failed to save file",
-			nil,
-		))
-		return
+	if replaced := outcome.Replaced; replaced != nil {
+		h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditDocumentDeleted, "document", &replaced.ID, map[string]any{
+			"original_filename": replaced.OriginalFilename,
+			"replaced":          true,
+		}))
 	}
 
-	// Check for duplicate by hash
-	existingDoc, err := h.documentRepo.GetByHash(c.Request.Context(), fileHash, userID)
-	if err == nil && existingDoc != nil {
-		// Delete the newly uploaded file since it's a duplicate
-		_ = h.storage.DeleteFile(filePath)
+	document, shared := outcome.Document, outcome.Shared
+	auditMetadata := map[string]any{
+		"original_filename": document.OriginalFilename,
+		"file_size":         document.FileSize,
+	}
+	if shared != nil {
+		auditMetadata["shared_from_document_id"] = shared.DocumentID
+	}
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditDocumentUploaded, "document", &document.ID, auditMetadata))
+
+	message := "File uploaded successfully"
+	switch {
+	case outcome.Replaced != nil:
+		message = "File uploaded, replacing an existing document"
+	case outcome.Duplicate != nil:
+		message = "File uploaded as a copy of an existing document"
+	case shared != nil && shared.ResultID != nil:
+		message = "File already processed by teammate " + shared.UserName
+	case shared != nil:
+		message = "File already uploaded by teammate " + shared.UserName
+	}
 
-		c.JSON(http.StatusOK, models.NewSuccessResponse(
-			existingDoc,
-			"File already exists (duplicate detected)",
-		))
-		return
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		models.UploadResponse{Document: document, Duplicate: outcome.Duplicate, Shared: shared},
+		message,
+	))
+}
+
+// uploadOptions reads the options of an upload from its query and form
+// fields: the duplicate policy from dedupe or on_duplicate, the checksum from
+// the X-Content-SHA256 header or the sha256 field, the labels, given as a
+// comma-separated list or repeated, and the organization to share with
+func uploadOptions(c *gin.Context) (services.UploadOptions, error) {
+	var opts services.UploadOptions
+	for _, field := range []string{"dedupe", "on_duplicate"} {
+		value := c.Query(field)
+		if value == "" {
+			value = c.PostForm(field)
+		}
+		if value != "" {
+			opts.DuplicatePolicy = models.ParseDuplicatePolicy(value)
+			break
+		}
 	}
 
-	// Create document record
-	document := &models.Document{
-		UserID:           userID,
-		Filename:         filePath[len(h.storage.GetFilePath("")):], // Relative path
-		OriginalFilename: file.Filename,
-		FilePath:         filePath,
-		FileSize:         file.Size,
-		MimeType:         storage.GetMimeType(file.Filename),
-		FileHash:         fileHash,
-		NumPages:         1, // TODO: Extract actual page count for PDFs
+	opts.Checksum = c.GetHeader("X-Content-SHA256")
+	if opts.Checksum == "" {
+		opts.Checksum = c.PostForm("sha256")
 	}
 
-	err = h.documentRepo.Create(c.Request.Context(), document)
-	if err != nil {
-		// Clean up file on database error
-		_ = h.storage.DeleteFile(filePath)
+	for _, value := range c.PostFormArray("labels") {
+		opts.Labels = append(opts.Labels, strings.Split(value, ",")...)
+	}
 
+	if value := c.PostForm("org_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return opts, apperrors.Validation("VAL_029", "Invalid organization ID")
+		}
+		opts.OrgID = &id
+	}
+	return opts, nil
+}
+
+// respondStoreError answers an upload that could not be stored
+func respondStoreError(c *gin.Context, err error, file *services.ReceivedUpload) {
+	var duplicate *services.DuplicateUploadError
+	switch {
+	case errors.As(err, &duplicate):
+		c.Header("Location", duplicate.Duplicate.ExistingDocumentURL)
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			"RES_005",
+			"File already exists",
+			[]models.ValidationError{
+				{Field: "existing_document_id", Message: duplicate.Duplicate.ExistingDocumentID.String()},
+			},
+		))
+	case errors.Is(err, services.ErrChecksumMismatch):
+		c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+			"VAL_017",
+			services.ErrChecksumMismatch.Error(),
+			[]models.ValidationError{
+				{Field: "sha256", Message: "received content hashes to " + file.Hash},
+			},
+		))
+	case errors.Is(err, services.ErrMalwareDetected):
+		c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+			"SEC_001",
+			services.ErrMalwareDetected.Error(),
+			nil,
+		))
+	case errors.Is(err, services.ErrScanUnavailable):
+		c.JSON(http.StatusServiceUnavailable, models.NewErrorResponse(
+			"SYS_013",
+			"Virus scan unavailable, please try again later",
+			nil,
+		))
+	case errors.Is(err, services.ErrDocumentBusy):
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			"JOB_004",
+			"Existing document has pending or processing OCR jobs; cancel them first",
+			nil,
+		))
+	case errors.Is(err, services.ErrReplaceFailed):
+		logger.FromContext(c.Request.Context()).Error("Failed to replace existing document", "error", err)
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
-			"SYS_003",
-			"Failed to create document record",
+			"SYS_005",
+			"Failed to replace existing document",
 			nil,
 		))
-		return
+	default:
+		respondError(c, err, "SYS_003", "Failed to create document record")
 	}
-
-	c.JSON(http.StatusCreated, models.NewSuccessResponse(
-		document,
-		"File uploaded successfully",
-	))
 }
 
 // List handles listing user's documents
@@ -162,13 +232,13 @@ func (h *DocumentHandler) List(c *gin.Context) {
 	}
 
 	// Parse request
-	var req models.DocumentListRequest
-	if err := c.ShouldBindQuery(&req); err != nil {
-		req = models.DocumentListRequest{
-			Page:    1,
-			PerPage: 20,
-			SortBy:  "uploaded_at",
-		}
+	req := models.DocumentListRequest{
+		Page:    1,
+		PerPage: 20,
+		SortBy:  "uploaded_at",
+	}
+	if !bindQuery(c, &req) {
+		return
 	}
 
 	// Get documents
@@ -193,13 +263,10 @@ func (h *DocumentHandler) List(c *gin.Context) {
 		HasPrev:    req.Page > 1,
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(
-		models.PaginatedResponse{
-			Items:      documents,
-			Pagination: pagination,
-		},
-		"Documents retrieved successfully",
-	))
+	respondConditional(c, models.PaginatedResponse{
+		Items:      documents,
+		Pagination: pagination,
+	}, time.Time{}, "Documents retrieved successfully")
 }
 
 // Get handles getting a single document
@@ -229,14 +296,71 @@ func (h *DocumentHandler) Get(c *gin.Context) {
 	// Get document
 	document, err := h.documentRepo.GetByID(c.Request.Context(), documentID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.NewErrorResponse(
-			"RES_002",
-			"Document not found",
+		respondError(c, err, "SYS_034", "Failed to get document")
+		return
+	}
+
+	// Verify ownership
+	if document.UserID != userID {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(
+			"AUTH_004",
+			"Access denied",
+			nil,
+		))
+		return
+	}
+
+	lastModified := document.UploadedAt
+	if document.DeletedAt != nil && document.DeletedAt.After(lastModified) {
+		lastModified = *document.DeletedAt
+	}
+
+	respondConditional(c, document, lastModified, "Document retrieved successfully")
+}
+
+// previewSizes are the longest sides of the previews that can be requested
+var previewSizes = map[string]int{
+	"thumbnail": 256,
+	"full":      2048,
+}
+
+// Preview renders an image document upright, as a thumbnail with
+// ?size=thumbnail or at up to 2048 pixels a side by default
+func (h *DocumentHandler) Preview(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	documentID, ok := parseIDParam(c, "id", "VAL_007", "Invalid document ID")
+	if !ok {
+		return
+	}
+
+	size := c.DefaultQuery("size", "full")
+	maxSide, ok := previewSizes[size]
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_042",
+			"Invalid size: must be thumbnail or full",
 			nil,
 		))
 		return
 	}
 
+	// Get document
+	document, err := h.documentRepo.GetByID(c.Request.Context(), documentID)
+	if err != nil {
+		respondError(c, err, "SYS_034", "Failed to get document")
+		return
+	}
+
 	// Verify ownership
 	if document.UserID != userID {
 		c.JSON(http.StatusForbidden, models.NewErrorResponse(
@@ -247,10 +371,26 @@ func (h *DocumentHandler) Get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(
-		document,
-		"Document retrieved successfully",
-	))
+	// The stored file never changes, and neither does the orientation found for it
+	if notModified(c, weakETag([]byte(document.FileHash), []byte(size)), document.UploadedAt) {
+		return
+	}
+
+	preview, contentType, err := h.orientation.Preview(c.Request.Context(), document, maxSide)
+	if errors.Is(err, services.ErrPreviewUnsupported) {
+		c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+			"DOC_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+	if err != nil {
+		respondError(c, err, "SYS_088", "Failed to render preview")
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, preview)
 }
 
 // Delete handles deleting a document
@@ -280,11 +420,7 @@ func (h *DocumentHandler) Delete(c *gin.Context) {
 	// Get document
 	document, err := h.documentRepo.GetByID(c.Request.Context(), documentID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.NewErrorResponse(
-			"RES_002",
-			"Document not found",
-			nil,
-		))
+		respondError(c, err, "SYS_034", "Failed to get document")
 		return
 	}
 
@@ -298,8 +434,16 @@ func (h *DocumentHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	// Soft delete document
-	err = h.documentRepo.SoftDelete(c.Request.Context(), documentID)
+	// Soft delete document, cancelling its active jobs only when asked to
+	cancelled, err := h.trashService.Delete(c.Request.Context(), documentID, c.Query("cancel_jobs") == "true")
+	if errors.Is(err, services.ErrDocumentBusy) {
+		c.JSON(http.StatusConflict, models.NewErrorResponse(
+			"JOB_004",
+			"Document has pending or processing OCR jobs; cancel them first or pass cancel_jobs=true",
+			nil,
+		))
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			"SYS_005",
@@ -312,8 +456,226 @@ func (h *DocumentHandler) Delete(c *gin.Context) {
 	// Note: We don't delete the actual file immediately for safety
 	// A cleanup job can handle this later
 
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditDocumentDeleted, "document", &documentID, map[string]any{
+		"original_filename": document.OriginalFilename,
+		"cancelled_jobs":    cancelled,
+	}))
+	for _, jobID := range cancelled {
+		h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJobCancelled, "job", &jobID, map[string]any{
+			"reason": "document_deleted",
+		}))
+	}
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(
 		nil,
 		"Document deleted successfully",
 	))
 }
+
+// ListTrash handles listing the user's soft-deleted documents
+func (h *DocumentHandler) ListTrash(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	page, perPage, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	// Get deleted documents
+	documents, total, err := h.trashService.List(c.Request.Context(), userID, page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_004",
+			"Failed to list documents",
+			nil,
+		))
+		return
+	}
+
+	items := make([]models.TrashedDocument, 0, len(documents))
+	for _, document := range documents {
+		item := models.TrashedDocument{Document: document}
+		if retention := h.trashService.Retention(); retention > 0 && document.DeletedAt != nil {
+			purgeAt := document.DeletedAt.Add(retention)
+			item.PurgeAt = &purgeAt
+		}
+		items = append(items, item)
+	}
+
+	// Calculate pagination
+	totalPages := (total + perPage - 1) / perPage
+	pagination := models.Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+
+	respondConditional(c, models.PaginatedResponse{
+		Items:      items,
+		Pagination: pagination,
+	}, time.Time{}, "Deleted documents retrieved successfully")
+}
+
+// Restore handles moving a document out of the trash
+func (h *DocumentHandler) Restore(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse document ID
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_007",
+			"Invalid document ID",
+			nil,
+		))
+		return
+	}
+
+	// Restore document
+	document, err := h.trashService.Restore(c.Request.Context(), documentID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_035", "Failed to restore document")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditDocumentRestored, "document", &documentID, nil))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		document,
+		"Document restored successfully",
+	))
+}
+
+// maxUploadFormBytes bounds the form fields sent along with an uploaded file
+const maxUploadFormBytes = 1 << 20
+
+var (
+	// errInvalidUpload is returned for uploads that are not well-formed
+	// multipart forms, or that were cut off
+	errInvalidUpload = errors.New("invalid multipart form")
+	// errNoFile is returned for uploads without a file field
+	errNoFile = errors.New("no file uploaded")
+)
+
+// receiveUpload reads a multipart upload as it arrives instead of buffering
+// it. The file field is written straight to storage while it is hashed, its
+// size limited and its content validated, and the upload is aborted as soon
+// as either check fails. The other fields, sent before or after the file,
+// become the request's PostForm.
+func (h *DocumentHandler) receiveUpload(c *gin.Context, userID uuid.UUID) (*services.ReceivedUpload, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, errInvalidUpload
+	}
+
+	var file *services.ReceivedUpload
+	fields := url.Values{}
+	remaining := int64(maxUploadFormBytes)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if file != nil {
+				h.uploadService.Discard(c.Request.Context(), file)
+			}
+			return nil, errInvalidUpload
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, remaining+1))
+			remaining -= int64(len(value))
+			if err != nil || remaining < 0 {
+				if file != nil {
+					h.uploadService.Discard(c.Request.Context(), file)
+				}
+				return nil, errInvalidUpload
+			}
+			fields.Add(part.FormName(), string(value))
+			continue
+		}
+
+		// Only the first file field is stored; other files are skipped
+		if part.FormName() != "file" || file != nil {
+			continue
+		}
+		file, err = h.uploadService.Receive(c.Request.Context(), userID, part.FileName(), &uploadPart{part: part})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if file == nil {
+		return nil, errNoFile
+	}
+	c.Request.PostForm = fields
+	c.Request.MultipartForm = &multipart.Form{Value: fields}
+	return file, nil
+}
+
+// respondUploadError answers an upload that could not be received
+func respondUploadError(c *gin.Context, err error) {
+	status, code, message := http.StatusBadRequest, "VAL_013", err.Error()
+	switch {
+	case errors.Is(err, errInvalidUpload):
+		code, message = "VAL_003", "Invalid multipart form"
+	case errors.Is(err, errNoFile):
+		code, message = "VAL_004", "No file uploaded"
+	case errors.Is(err, storage.ErrFileTooLarge):
+		code, message = "VAL_005", "File size exceeds maximum allowed size"
+	case errors.Is(err, services.ErrUploadFileType):
+		code, message = "VAL_006", "File type not allowed"
+	case errors.Is(err, storage.ErrEncryptedPDF):
+		code, message = "VAL_014", storage.ErrEncryptedPDF.Error()
+	case errors.Is(err, storage.ErrCorruptPDF):
+		code, message = "VAL_015", storage.ErrCorruptPDF.Error()
+	case errors.Is(err, storage.ErrContentMismatch):
+		message = storage.ErrContentMismatch.Error()
+	default:
+		logger.FromContext(c.Request.Context()).Error("Failed to save uploaded file", "error", err)
+		status, code, message = http.StatusInternalServerError, "SYS_002", "Failed to save file"
+	}
+	c.JSON(status, models.NewErrorResponse(
+		code,
+		message,
+		nil,
+	))
+}
+
+// uploadPart reads the file field of an upload. Errors reading it are the
+// client's, cutting the upload off or malforming it, so they are reported as
+// errInvalidUpload rather than as failures to save.
+type uploadPart struct {
+	part *multipart.Part
+}
+
+func (r *uploadPart) Read(p []byte) (int, error) {
+	n, err := r.part.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, fmt.Errorf("%w: %v", errInvalidUpload, err)
+	}
+	return n, err
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// documentRelations are the relations a link may have
+var documentRelations = []models.DocumentRelation{
+	models.RelationRelated,
+	models.RelationPartOf,
+	models.RelationAttachmentOf,
+	models.RelationReferences,
+	models.RelationSupersedes,
+	models.RelationDuplicateOf,
+}
+
+// DocumentLinkHandler handles links between documents
+type DocumentLinkHandler struct {
+	linkService *services.DocumentLinkService
+	validator   *validator.Validator
+}
+
+// NewDocumentLinkHandler creates a new document link handler
+func NewDocumentLinkHandler(linkService *services.DocumentLinkService) *DocumentLinkHandler {
+	return &DocumentLinkHandler{
+		linkService: linkService,
+		validator:   validator.New(),
+	}
+}
+
+// Create links a document to another of the user's documents
+func (h *DocumentLinkHandler) Create(c *gin.Context) {
+	userID, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.DocumentLinkCreateRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	link, err := h.linkService.Create(c.Request.Context(), userID, documentID, req)
+	if err != nil {
+		respondError(c, err, "SYS_062", "Failed to link documents")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		link,
+		"Documents linked successfully",
+	))
+}
+
+// List returns the links from and to a document
+func (h *DocumentLinkHandler) List(c *gin.Context) {
+	userID, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.linkService.List(c.Request.Context(), userID, documentID)
+	if err != nil {
+		respondError(c, err, "SYS_063", "Failed to list document links")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		links,
+		"Document links retrieved successfully",
+	))
+}
+
+// Update changes the relation, position or note of a link
+func (h *DocumentLinkHandler) Update(c *gin.Context) {
+	userID, documentID, linkID, ok := h.linkRequest(c)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.DocumentLinkUpdateRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	link, err := h.linkService.Update(c.Request.Context(), userID, documentID, linkID, req)
+	if err != nil {
+		respondError(c, err, "SYS_064", "Failed to update document link")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		link,
+		"Document link updated successfully",
+	))
+}
+
+// Delete removes a link
+func (h *DocumentLinkHandler) Delete(c *gin.Context) {
+	userID, documentID, linkID, ok := h.linkRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.linkService.Delete(c.Request.Context(), userID, documentID, linkID); err != nil {
+		respondError(c, err, "SYS_065", "Failed to delete document link")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Document link deleted successfully",
+	))
+}
+
+// Related returns the documents reachable from a document by following links,
+// up to ?depth= links away (default 2), optionally only through the
+// comma-separated ?relation= types
+func (h *DocumentLinkHandler) Related(c *gin.Context) {
+	userID, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	depth, relations, ok := traversalParams(c)
+	if !ok {
+		return
+	}
+
+	graph, err := h.linkService.Related(c.Request.Context(), userID, documentID, depth, relations)
+	if err != nil {
+		respondError(c, err, "SYS_066", "Failed to get related documents")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		graph,
+		"Related documents retrieved successfully",
+	))
+}
+
+// ExportRelated downloads a document and the documents related to it as a zip
+// archive of their scans and latest results (?format=markdown or text)
+func (h *DocumentLinkHandler) ExportRelated(c *gin.Context) {
+	userID, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	depth, relations, ok := traversalParams(c)
+	if !ok {
+		return
+	}
+
+	format := models.ResultExportFormat(c.DefaultQuery("format", string(models.ExportFormatMarkdown)))
+	if format != models.ExportFormatMarkdown && format != models.ExportFormatText {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_011",
+			fmt.Sprintf("Unsupported export format: %s", format),
+			nil,
+		))
+		return
+	}
+
+	graph, err := h.linkService.Related(c.Request.Context(), userID, documentID, depth, relations)
+	if err != nil {
+		respondError(c, err, "SYS_067", "Failed to export related documents")
+		return
+	}
+
+	setAttachment(c, "related-"+documentID.String()+".zip")
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := h.linkService.Export(c.Request.Context(), graph, format, c.Writer); err != nil {
+		logger.Warn("Failed to stream related documents export", "document_id", documentID, "error", err)
+	}
+}
+
+// bind parses and validates a JSON request body
+func (h *DocumentLinkHandler) bind(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return false
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return false
+	}
+
+	return true
+}
+
+// documentRequest returns the authenticated user and the document named in the path
+func (h *DocumentLinkHandler) documentRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	documentID, ok := parseIDParam(c, "id", "VAL_007", "Invalid document ID")
+	return userID, documentID, ok
+}
+
+// linkRequest returns the authenticated user and the document and link named
+// in the path
+func (h *DocumentLinkHandler) linkRequest(c *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	userID, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	linkID, ok := parseIDParam(c, "link_id", "VAL_033", "Invalid link ID")
+	return userID, documentID, linkID, ok
+}
+
+// traversalParams returns the depth and relation query parameters of a traversal
+func traversalParams(c *gin.Context) (int, []string, bool) {
+	depth := 2
+	if value := c.Query("depth"); value != "" {
+		d, err := strconv.Atoi(value)
+		if err != nil || d < 1 || d > services.MaxRelatedDepth {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				fmt.Sprintf("depth must be between 1 and %d", services.MaxRelatedDepth),
+				nil,
+			))
+			return 0, nil, false
+		}
+		depth = d
+	}
+
+	var relations []string
+	for _, value := range strings.Split(c.Query("relation"), ",") {
+		relation := strings.TrimSpace(value)
+		if relation == "" {
+			continue
+		}
+		if !isDocumentRelation(relation) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				fmt.Sprintf("unknown relation %q", relation),
+				nil,
+			))
+			return 0, nil, false
+		}
+		relations = append(relations, relation)
+	}
+
+	return depth, relations, true
+}
+
+// isDocumentRelation reports whether a relation is one a link may have
+func isDocumentRelation(relation string) bool {
+	for _, known := range documentRelations {
+		if string(known) == relation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCatalogHandler handles the catalog of error codes
+type ErrorCatalogHandler struct{}
+
+// NewErrorCatalogHandler creates a new error catalog handler
+func NewErrorCatalogHandler() *ErrorCatalogHandler {
+	return &ErrorCatalogHandler{}
+}
+
+// List handles listing every error code the API reports, with its status,
+// category and description, so clients can build their error handling on it
+func (h *ErrorCatalogHandler) List(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		apperrors.Catalog(),
+		"Error catalog retrieved successfully",
+	))
+}
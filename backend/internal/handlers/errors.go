@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError writes the error response for err. Errors of an apperrors kind
+// are reported with the kind's status and their own code and message. Anything
+// else is logged and reported as a 500 with code and message, so internal
+// details do not reach clients.
+func respondError(c *gin.Context, err error, code, message string) {
+	status := apperrors.HTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		logger.Error("Request failed", "method", c.Request.Method, "path", c.FullPath(), "error", err)
+		c.JSON(status, models.NewErrorResponse(
+			code,
+			message,
+			nil,
+		))
+		return
+	}
+
+	if appCode := apperrors.Code(err); appCode != "" {
+		code = appCode
+	}
+	c.JSON(status, models.NewErrorResponse(
+		code,
+		err.Error(),
+		nil,
+	))
+}
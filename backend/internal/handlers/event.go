@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EventHandler handles admin requests for outbox inspection and replay
+type EventHandler struct {
+	eventService *services.EventService
+	validator    *validator.Validator
+}
+
+// NewEventHandler creates a new event handler
+func NewEventHandler(eventService *services.EventService) *EventHandler {
+	return &EventHandler{
+		eventService: eventService,
+		validator:    validator.New(),
+	}
+}
+
+// List handles listing outbox events with optional filters
+func (h *EventHandler) List(c *gin.Context) {
+	filter := models.EventFilter{
+		Type:   models.EventType(c.Query("type")),
+		Status: c.Query("status"),
+	}
+
+	if status := filter.Status; status != "" && status != "pending" && status != "published" && status != "failed" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"status must be one of: pending published failed",
+			nil,
+		))
+		return
+	}
+
+	if raw := c.Query("aggregate_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				"Invalid aggregate_id",
+				nil,
+			))
+			return
+		}
+		filter.AggregateID = &id
+	}
+
+	for param, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					"VAL_001",
+					param+" must be an RFC3339 timestamp",
+					nil,
+				))
+				return
+			}
+			*target = &t
+		}
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		filter.Limit, _ = strconv.Atoi(raw)
+	}
+
+	events, err := h.eventService.ListEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_010",
+			"Failed to list events",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		events,
+		"Events retrieved successfully",
+	))
+}
+
+// Replay handles scheduling outbox events for redelivery
+func (h *EventHandler) Replay(c *gin.Context) {
+	var req models.EventReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	replayed, err := h.eventService.Replay(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"EVT_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		gin.H{"replayed": replayed},
+		"Events scheduled for replay",
+	))
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"visekai/backend/internal/models"
@@ -16,6 +17,14 @@ type HealthChecker interface {
 	Check(ctx context.Context) error
 }
 
+// HealthCheckFunc adapts a function to the HealthChecker interface
+type HealthCheckFunc func(ctx context.Context) error
+
+// Check calls f
+func (f HealthCheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
 // DBHealthChecker implements database health check
 type DBHealthChecker struct {
 	db *pgxpool.Pool
@@ -31,34 +40,89 @@ func (h *DBHealthChecker) Check(ctx context.Context) error {
 	return h.db.Ping(ctx)
 }
 
+// dependencyCheck is a dependency verified by the readiness check
+type dependencyCheck struct {
+	name    string
+	checker HealthChecker
+	// required dependencies make the service unready when they fail; the
+	// others only degrade it
+	required bool
+}
+
+// dependencyStatus is the outcome of checking one dependency
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
 // HealthCheckHandler handles health check with dependencies
 type HealthCheckHandler struct {
-	dbChecker *DBHealthChecker
+	checks []dependencyCheck
 }
 
-// NewHealthCheckHandler creates a new health check handler
-func NewHealthCheckHandler(db *pgxpool.Pool) *HealthCheckHandler {
-	return &HealthCheckHandler{
-		dbChecker: NewDBHealthChecker(db),
+// NewHealthCheckHandler creates a new health check handler checking the
+// database and, unless it is nil, the read replica. Add the other
+// dependencies with AddCheck.
+func NewHealthCheckHandler(db, replica *pgxpool.Pool) *HealthCheckHandler {
+	h := &HealthCheckHandler{}
+	h.AddCheck("database", NewDBHealthChecker(db), true)
+	if replica != nil {
+		h.AddCheck("database_replica", NewDBHealthChecker(replica), true)
 	}
+	return h
+}
+
+// AddCheck adds a dependency to the readiness check. The service is unready
+// while a required dependency fails, and degraded while another one does.
+func (h *HealthCheckHandler) AddCheck(name string, checker HealthChecker, required bool) {
+	h.checks = append(h.checks, dependencyCheck{name: name, checker: checker, required: required})
 }
 
-// Handle performs the health check
+// Handle performs the readiness check: every dependency is checked
+// concurrently and reported with its latency. It answers 503 while a required
+// dependency fails, so load balancers stop routing to the instance.
 func (h *HealthCheckHandler) Handle(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
 	defer cancel()
 
+	results := make([]dependencyStatus, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.checker.Check(ctx)
+			results[i] = dependencyStatus{
+				Status:    "healthy",
+				Required:  check.required,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[i].Status = "unhealthy"
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
 	status := "healthy"
 	statusCode := http.StatusOK
-	checks := make(map[string]string)
-
-	// Check database
-	if err := h.dbChecker.Check(ctx); err != nil {
-		checks["database"] = "unhealthy: " + err.Error()
-		status = "degraded"
-		statusCode = http.StatusServiceUnavailable
-	} else {
-		checks["database"] = "healthy"
+	checks := make(map[string]dependencyStatus, len(h.checks))
+	for i, check := range h.checks {
+		checks[check.name] = results[i]
+		if results[i].Error == "" {
+			continue
+		}
+		if check.required {
+			status = "unavailable"
+			statusCode = http.StatusServiceUnavailable
+		} else if status == "healthy" {
+			status = "degraded"
+		}
 	}
 
 	c.JSON(statusCode, models.NewSuccessResponse(gin.H{
@@ -229,26 +293,6 @@ func DeleteJob(c *gin.Context) {
 	})
 }
 
-func GetResult(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"success": false,
-		"error": gin.H{
-			"code":    "NOT_IMPLEMENTED",
-			"message": "Get result endpoint not yet implemented",
-		},
-	})
-}
-
-func DownloadResult(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"success": false,
-		"error": gin.H{
-			"code":    "NOT_IMPLEMENTED",
-			"message": "Download result endpoint not yet implemented",
-		},
-	})
-}
-
 func PreviewResult(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{
 		"success": false,
@@ -258,23 +302,3 @@ func PreviewResult(c *gin.Context) {
 		},
 	})
 }
-
-func GetSettings(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"success": false,
-		"error": gin.H{
-			"code":    "NOT_IMPLEMENTED",
-			"message": "Get settings endpoint not yet implemented",
-		},
-	})
-}
-
-func UpdateSettings(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"success": false,
-		"error": gin.H{
-			"code":    "NOT_IMPLEMENTED",
-			"message": "Update settings endpoint not yet implemented",
-		},
-	})
-}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mailgunFormMemory is the part of a Mailgun webhook kept in memory; larger
+// attachments are buffered to temporary files
+const mailgunFormMemory = 32 << 20
+
+// InboundEmailHandler handles users' inbound email addresses and the webhooks
+// the mail provider posts received emails to
+type InboundEmailHandler struct {
+	inboundService *services.InboundEmailService
+}
+
+// NewInboundEmailHandler creates a new inbound email handler
+func NewInboundEmailHandler(inboundService *services.InboundEmailService) *InboundEmailHandler {
+	return &InboundEmailHandler{inboundService: inboundService}
+}
+
+// GetAddress returns the address the user can email documents to
+func (h *InboundEmailHandler) GetAddress(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	address, err := h.inboundService.GetAddress(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_110", "Failed to get inbound email address")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		address,
+		"Inbound email address retrieved successfully",
+	))
+}
+
+// RotateAddress replaces the user's inbound address with a new one
+func (h *InboundEmailHandler) RotateAddress(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	address, err := h.inboundService.RotateAddress(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_111", "Failed to rotate inbound email address")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		address,
+		"Inbound email address rotated successfully",
+	))
+}
+
+// Mailgun receives an email forwarded by a Mailgun inbound route. Mail to an
+// unknown address is answered 406, which Mailgun takes as a rejection not to
+// retry.
+func (h *InboundEmailHandler) Mailgun(c *gin.Context) {
+	if err := c.Request.ParseMultipartForm(mailgunFormMemory); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_003",
+			"Invalid multipart form",
+			nil,
+		))
+		return
+	}
+
+	err := h.inboundService.VerifyMailgun(c.PostForm("timestamp"), c.PostForm("token"), c.PostForm("signature"))
+	if err != nil {
+		respondError(c, err, "INB_002", "Invalid signature")
+		return
+	}
+
+	msg := models.InboundMessage{
+		Recipients:  strings.Split(c.PostForm("recipient"), ","),
+		Sender:      c.PostForm("sender"),
+		Subject:     c.PostForm("subject"),
+		MessageID:   c.PostForm("Message-Id"),
+		Attachments: mailgunAttachments(c.Request.MultipartForm),
+	}
+
+	email, err := h.inboundService.Receive(c.Request.Context(), msg)
+	if errors.Is(err, repository.ErrInboundAddressNotFound) {
+		c.JSON(http.StatusNotAcceptable, models.NewErrorResponse(
+			"INB_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+	if err != nil {
+		respondError(c, err, "SYS_112", "Failed to receive email")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		email,
+		"Email received successfully",
+	))
+}
+
+// mailgunAttachments returns the files of the attachment-1 to attachment-N
+// fields, in order
+func mailgunAttachments(form *multipart.Form) []*multipart.FileHeader {
+	type numbered struct {
+		n    int
+		file *multipart.FileHeader
+	}
+
+	var found []numbered
+	for field, files := range form.File {
+		n, err := strconv.Atoi(strings.TrimPrefix(field, "attachment-"))
+		if err != nil || !strings.HasPrefix(field, "attachment-") || len(files) == 0 {
+			continue
+		}
+		found = append(found, numbered{n: n, file: files[0]})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].n < found[j].n })
+
+	attachments := make([]*multipart.FileHeader, len(found))
+	for i, attachment := range found {
+		attachments[i] = attachment.file
+	}
+	return attachments
+}
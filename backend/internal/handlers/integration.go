@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrationHandler handles the endpoints of no-code tools such as Zapier
+// and Make: the polling trigger for new results and REST hook subscriptions
+type IntegrationHandler struct {
+	integrationService *services.IntegrationService
+	validator          *validator.Validator
+}
+
+// NewIntegrationHandler creates a new integration handler
+func NewIntegrationHandler(integrationService *services.IntegrationService) *IntegrationHandler {
+	return &IntegrationHandler{
+		integrationService: integrationService,
+		validator:          validator.New(),
+	}
+}
+
+// PollResults returns the results completed after ?since=, a cursor returned
+// by a previous call, oldest first and at most ?limit= of them
+func (h *IntegrationHandler) PollResults(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				"limit must be a number",
+				nil,
+			))
+			return
+		}
+	}
+
+	page, err := h.integrationService.PollResults(c.Request.Context(), userID, c.Query("since"), limit)
+	if err != nil {
+		respondError(c, err, "SYS_099", "Failed to poll results")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		page,
+		"Results retrieved successfully",
+	))
+}
+
+// Subscribe creates a REST hook
+func (h *IntegrationHandler) Subscribe(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.HookSubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	hook, err := h.integrationService.Subscribe(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_100", "Failed to subscribe hook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		hook,
+		"Hook subscribed successfully",
+	))
+}
+
+// ListHooks returns the user's REST hooks
+func (h *IntegrationHandler) ListHooks(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	hooks, err := h.integrationService.ListHooks(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_101", "Failed to list hooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		hooks,
+		"Hooks retrieved successfully",
+	))
+}
+
+// Unsubscribe deletes a REST hook
+func (h *IntegrationHandler) Unsubscribe(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	hookID, ok := parseIDParam(c, "id", "VAL_053", "Invalid hook ID")
+	if !ok {
+		return
+	}
+
+	if err := h.integrationService.Unsubscribe(c.Request.Context(), hookID, userID); err != nil {
+		respondError(c, err, "SYS_102", "Failed to unsubscribe hook")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Hook unsubscribed successfully",
+	))
+}
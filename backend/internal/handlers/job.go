@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"visekai/backend/internal/middleware"
 	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
 	"visekai/backend/internal/services"
 	"visekai/backend/pkg/validator"
 
@@ -12,17 +18,27 @@ import (
 	"github.com/google/uuid"
 )
 
+// jobStreamHeartbeat is how often an idle job event stream sends a comment, so
+// proxies and clients keep the connection open
+const jobStreamHeartbeat = 15 * time.Second
+
 // JobHandler handles OCR job-related requests
 type JobHandler struct {
-	jobService *services.JobService
-	validator  *validator.Validator
+	jobService   *services.JobService
+	jobTrash     *services.JobTrashService
+	eventStream  *services.JobEventStream
+	auditService *services.AuditService
+	validator    *validator.Validator
 }
 
 // NewJobHandler creates a new job handler
-func NewJobHandler(jobService *services.JobService) *JobHandler {
+func NewJobHandler(jobService *services.JobService, jobTrash *services.JobTrashService, eventStream *services.JobEventStream, auditService *services.AuditService) *JobHandler {
 	return &JobHandler{
-		jobService: jobService,
-		validator:  validator.New(),
+		jobService:   jobService,
+		jobTrash:     jobTrash,
+		eventStream:  eventStream,
+		auditService: auditService,
+		validator:    validator.New(),
 	}
 }
 
@@ -67,21 +83,38 @@ func (h *JobHandler) SubmitJob(c *gin.Context) {
 		ResolutionMode: req.ResolutionMode,
 		Priority:       req.Priority,
 	}
+	if req.PageEvents {
+		submission.Metadata = map[string]any{models.JobMetaPageEvents: true}
+	}
 
 	// Submit job
 	job, err := h.jobService.SubmitJob(c.Request.Context(), submission, userID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"JOB_001",
-			err.Error(),
-			nil,
+	var violation *services.PolicyViolationError
+	if errors.As(err, &violation) {
+		c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+			"JOB_005",
+			violation.Message,
+			[]models.ValidationError{{Field: violation.Field, Message: violation.Message}},
 		))
 		return
 	}
+	if err != nil {
+		respondError(c, err, "JOB_001", "Failed to submit OCR job")
+		return
+	}
 
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJobSubmitted, "job", &job.ID, map[string]any{
+		"document_id": job.DocumentID,
+		"ocr_mode":    job.OCRMode,
+	}))
+
+	message := "OCR job submitted successfully"
+	if job.Status == models.JobStatusAwaitingApproval {
+		message = "OCR job submitted and awaiting approval"
+	}
 	c.JSON(http.StatusCreated, models.NewSuccessResponse(
 		job,
-		"OCR job submitted successfully",
+		message,
 	))
 }
 
@@ -121,7 +154,7 @@ func (h *JobHandler) SubmitBatchJob(c *gin.Context) {
 
 	// Submit jobs for each document
 	var jobs []*models.OCRJob
-	var errors []string
+	var failures []string
 
 	for _, documentID := range req.DocumentIDs {
 		submission := models.JobSubmissionRequest{
@@ -130,23 +163,32 @@ func (h *JobHandler) SubmitBatchJob(c *gin.Context) {
 			ResolutionMode: req.ResolutionMode,
 			Priority:       0, // Batch jobs have default priority
 		}
+		if req.PageEvents {
+			submission.Metadata = map[string]any{models.JobMetaPageEvents: true}
+		}
 
 		job, err := h.jobService.SubmitJob(c.Request.Context(), submission, userID)
 		if err != nil {
-			errors = append(errors, err.Error())
+			failures = append(failures, err.Error())
 			continue
 		}
 		jobs = append(jobs, job)
+
+		h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJobSubmitted, "job", &job.ID, map[string]any{
+			"document_id": job.DocumentID,
+			"ocr_mode":    job.OCRMode,
+			"batch":       true,
+		}))
 	}
 
 	response := gin.H{
 		"jobs":    jobs,
 		"success": len(jobs),
-		"failed":  len(errors),
+		"failed":  len(failures),
 	}
 
-	if len(errors) > 0 {
-		response["errors"] = errors
+	if len(failures) > 0 {
+		response["errors"] = failures
 	}
 
 	c.JSON(http.StatusCreated, models.NewSuccessResponse(
@@ -168,25 +210,17 @@ func (h *JobHandler) ListJobs(c *gin.Context) {
 		return
 	}
 
-	// Parse pagination
-	var req models.JobListRequest
-	if err := c.ShouldBindQuery(&req); err != nil {
-		req = models.JobListRequest{
-			Page:    1,
-			PerPage: 20,
-		}
+	// Parse pagination and filters
+	req := models.JobListRequest{
+		Page:    1,
+		PerPage: 20,
 	}
-
-	// Set defaults
-	if req.Page < 1 {
-		req.Page = 1
-	}
-	if req.PerPage < 1 || req.PerPage > 100 {
-		req.PerPage = 20
+	if !bindQuery(c, &req) {
+		return
 	}
 
 	// Get jobs
-	jobs, pagination, err := h.jobService.ListJobs(c.Request.Context(), userID, req.Page, req.PerPage)
+	jobs, pagination, err := h.jobService.ListJobs(c.Request.Context(), userID, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			"SYS_006",
@@ -196,13 +230,10 @@ func (h *JobHandler) ListJobs(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(
-		models.PaginatedResponse{
-			Items:      jobs,
-			Pagination: *pagination,
-		},
-		"Jobs retrieved successfully",
-	))
+	respondConditional(c, models.PaginatedResponse{
+		Items:      jobs,
+		Pagination: *pagination,
+	}, time.Time{}, "Jobs retrieved successfully")
 }
 
 // GetJob handles getting a single OCR job
@@ -232,11 +263,7 @@ func (h *JobHandler) GetJob(c *gin.Context) {
 	// Get job
 	job, err := h.jobService.GetJob(c.Request.Context(), jobID, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.NewErrorResponse(
-			"RES_003",
-			"Job not found",
-			nil,
-		))
+		respondError(c, err, "SYS_032", "Failed to get job")
 		return
 	}
 
@@ -246,6 +273,148 @@ func (h *JobHandler) GetJob(c *gin.Context) {
 	))
 }
 
+// GetJobEvents handles retrieving the timeline of a job
+func (h *JobHandler) GetJobEvents(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse job ID
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_008",
+			"Invalid job ID",
+			nil,
+		))
+		return
+	}
+
+	events, err := h.jobService.GetJobEvents(c.Request.Context(), jobID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_037", "Failed to get job events")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		events,
+		"Job events retrieved successfully",
+	))
+}
+
+// StreamEvents streams the events of all the user's jobs as server-sent
+// events, so dashboards need not poll. Each event's id is the job event ID;
+// clients that reconnect with Last-Event-ID (or the last_event_id query
+// parameter) first get the events they missed.
+func (h *JobHandler) StreamEvents(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse the event to resume after
+	var lastID int64
+	resume := c.GetHeader("Last-Event-ID")
+	if resume == "" {
+		resume = c.Query("last_event_id")
+	}
+	if resume != "" {
+		lastID, err = strconv.ParseInt(resume, 10, 64)
+		if err != nil || lastID < 0 {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_026",
+				"Invalid last event ID",
+				nil,
+			))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	// Subscribe before reading the backlog so no event falls between them
+	sub := h.eventStream.Subscribe(userID, repository.LabelScope(ctx))
+	defer sub.Close()
+
+	var backlog []*models.JobEvent
+	if lastID > 0 {
+		backlog, err = h.eventStream.Backlog(ctx, userID, lastID)
+		if err != nil {
+			respondError(c, err, "SYS_043", "Failed to stream job events")
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// The stream outlives the server's write timeout, so each write extends it
+	controller := http.NewResponseController(c.Writer)
+	write := func(message string) bool {
+		if err := controller.SetWriteDeadline(time.Now().Add(2 * jobStreamHeartbeat)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return false
+		}
+		if _, err := c.Writer.WriteString(message); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+	send := func(event *models.JobEvent) bool {
+		if event.ID <= lastID {
+			return true
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return false
+		}
+		lastID = event.ID
+		return write(fmt.Sprintf("id: %d\ndata: %s\n\n", event.ID, data))
+	}
+
+	if !write(": connected\n\n") {
+		return
+	}
+	for _, event := range backlog {
+		if !send(event) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(jobStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok || !send(event) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": keepalive\n\n") {
+				return
+			}
+		}
+	}
+}
+
 // CancelJob handles cancelling an OCR job
 func (h *JobHandler) CancelJob(c *gin.Context) {
 	// Get authenticated user
@@ -273,14 +442,12 @@ func (h *JobHandler) CancelJob(c *gin.Context) {
 	// Cancel job
 	err = h.jobService.CancelJob(c.Request.Context(), jobID, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"JOB_002",
-			err.Error(),
-			nil,
-		))
+		respondError(c, err, "JOB_002", "Failed to cancel job")
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJobCancelled, "job", &jobID, nil))
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(
 		nil,
 		"Job cancelled successfully",
@@ -314,20 +481,134 @@ func (h *JobHandler) DeleteJob(c *gin.Context) {
 	// Delete job
 	err = h.jobService.DeleteJob(c.Request.Context(), jobID, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"JOB_003",
+		respondError(c, err, "JOB_003", "Failed to delete job")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJobDeleted, "job", &jobID, nil))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Job deleted successfully",
+	))
+}
+
+// ListTrash handles listing the user's deleted jobs, which are purged with
+// their results once the user's retention elapses
+func (h *JobHandler) ListTrash(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
 			err.Error(),
 			nil,
 		))
 		return
 	}
 
+	page, perPage, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	jobs, total, err := h.jobTrash.List(c.Request.Context(), userID, page, perPage)
+	if err != nil {
+		respondError(c, err, "SYS_129", "Failed to list deleted jobs")
+		return
+	}
+
 	c.JSON(http.StatusOK, models.NewSuccessResponse(
-		nil,
-		"Job deleted successfully",
+		paginated(jobs, page, perPage, total),
+		"Deleted jobs retrieved successfully",
 	))
 }
 
+// RestoreJob handles moving a job and its results out of the trash
+func (h *JobHandler) RestoreJob(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse job ID
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_008",
+			"Invalid job ID",
+			nil,
+		))
+		return
+	}
+
+	job, err := h.jobTrash.Restore(c.Request.Context(), jobID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_130", "Failed to restore job")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJobRestored, "job", &jobID, nil))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		job,
+		"Job restored successfully",
+	))
+}
+
+// ArchiveJob handles archiving a job, hiding it from the default job listings
+func (h *JobHandler) ArchiveJob(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// UnarchiveJob handles moving a job back to the default job listings
+func (h *JobHandler) UnarchiveJob(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+// setArchived archives or unarchives the job of the request
+func (h *JobHandler) setArchived(c *gin.Context, archived bool) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse job ID
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_008",
+			"Invalid job ID",
+			nil,
+		))
+		return
+	}
+
+	job, err := h.jobService.ArchiveJob(c.Request.Context(), jobID, userID, archived)
+	if err != nil {
+		respondError(c, err, "SYS_131", "Failed to archive job")
+		return
+	}
+
+	message := "Job archived successfully"
+	if !archived {
+		message = "Job unarchived successfully"
+	}
+	c.JSON(http.StatusOK, models.NewSuccessResponse(job, message))
+}
+
 // GetJobResult handles getting the result of an OCR job
 func (h *JobHandler) GetJobResult(c *gin.Context) {
 	// Get authenticated user
@@ -355,16 +636,178 @@ func (h *JobHandler) GetJobResult(c *gin.Context) {
 	// Get result
 	result, err := h.jobService.GetJobResult(c.Request.Context(), jobID, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.NewErrorResponse(
-			"RES_004",
-			"Result not found",
+		respondError(c, err, "SYS_033", "Failed to get result")
+		return
+	}
+
+	streamResult(c, result, "Result retrieved successfully")
+}
+
+// ReprocessPage handles re-running OCR on a single page of a result. The new
+// page text is spliced into the result as a new version once the job completes.
+func (h *JobHandler) ReprocessPage(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
 			nil,
 		))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccessResponse(
-		result,
-		"Result retrieved successfully",
+	// Parse result ID and page number
+	resultID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_009",
+			"Invalid result ID",
+			nil,
+		))
+		return
+	}
+
+	page, err := strconv.Atoi(c.Param("page"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_021",
+			"Invalid page number",
+			nil,
+		))
+		return
+	}
+
+	// Parse options; the body is optional
+	var req models.ReprocessPageRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				"Invalid request body",
+				nil,
+			))
+			return
+		}
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	job, err := h.jobService.SubmitPageReprocess(c.Request.Context(), resultID, page, req, userID)
+	var violation *services.PolicyViolationError
+	switch {
+	case errors.Is(err, services.ErrPageOutOfRange):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_021",
+			"Invalid page number",
+			[]models.ValidationError{{Field: "page", Message: err.Error()}},
+		))
+		return
+	case errors.As(err, &violation):
+		c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+			"JOB_005",
+			violation.Message,
+			[]models.ValidationError{{Field: violation.Field, Message: violation.Message}},
+		))
+		return
+	case err != nil:
+		respondError(c, err, "JOB_001", "Failed to submit page reprocessing")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJobSubmitted, "job", &job.ID, map[string]any{
+		"document_id": job.DocumentID,
+		"ocr_mode":    job.OCRMode,
+		"result_id":   resultID,
+		"page":        page,
+	}))
+
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(
+		job,
+		"Page reprocessing submitted successfully",
+	))
+}
+
+// ReOCRDocument handles re-running OCR on a page of a document, or a region of
+// it, patching the document's latest result as a new version once the job
+// completes
+func (h *JobHandler) ReOCRDocument(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	documentID, ok := parseIDParam(c, "id", "VAL_007", "Invalid document ID")
+	if !ok {
+		return
+	}
+
+	var req models.DocumentReOCRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	job, err := h.jobService.SubmitDocumentReOCR(c.Request.Context(), documentID, req, userID)
+	var violation *services.PolicyViolationError
+	switch {
+	case errors.Is(err, services.ErrPageOutOfRange):
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_021",
+			"Invalid page number",
+			[]models.ValidationError{{Field: "page", Message: err.Error()}},
+		))
+		return
+	case errors.As(err, &violation):
+		c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+			"JOB_005",
+			violation.Message,
+			[]models.ValidationError{{Field: violation.Field, Message: violation.Message}},
+		))
+		return
+	case err != nil:
+		respondError(c, err, "JOB_001", "Failed to submit re-OCR")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJobSubmitted, "job", &job.ID, map[string]any{
+		"document_id": job.DocumentID,
+		"ocr_mode":    job.OCRMode,
+		"result_id":   job.Metadata[models.JobMetaReprocessResultID],
+		"page":        req.Page,
+		"bbox":        req.BBox,
+	}))
+
+	c.JSON(http.StatusAccepted, models.NewSuccessResponse(
+		job,
+		"Re-OCR submitted successfully",
 	))
 }
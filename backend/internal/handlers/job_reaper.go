@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobReaperHandler handles admin requests for the stuck job reaper
+type JobReaperHandler struct {
+	reaperService *services.JobReaperService
+}
+
+// NewJobReaperHandler creates a new job reaper handler
+func NewJobReaperHandler(reaperService *services.JobReaperService) *JobReaperHandler {
+	return &JobReaperHandler{reaperService: reaperService}
+}
+
+// Sweep requeues jobs stuck in processing immediately
+func (h *JobReaperHandler) Sweep(c *gin.Context) {
+	sweep, err := h.reaperService.Sweep(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_019",
+			"Failed to sweep stuck jobs",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		sweep,
+		"Stuck job sweep completed successfully",
+	))
+}
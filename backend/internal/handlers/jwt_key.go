@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWTKeyHandler handles the keys signing access and refresh tokens
+type JWTKeyHandler struct {
+	keyService   *services.JWTKeyService
+	auditService *services.AuditService
+}
+
+// NewJWTKeyHandler creates a new JWT key handler. keyService is nil when
+// tokens are signed with the shared secret.
+func NewJWTKeyHandler(keyService *services.JWTKeyService, auditService *services.AuditService) *JWTKeyHandler {
+	return &JWTKeyHandler{keyService: keyService, auditService: auditService}
+}
+
+// JWKS publishes the public keys verifying tokens, as a JSON Web Key Set,
+// so other services can verify them. The set is empty when tokens are
+// signed with the shared secret.
+func (h *JWTKeyHandler) JWKS(c *gin.Context) {
+	set := models.JSONWebKeySet{Keys: []models.JSONWebKey{}}
+	if h.keyService != nil {
+		set = h.keyService.JWKS()
+	}
+
+	// Short enough for verifiers to pick up a rotated key before it signs
+	// many tokens
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
+
+// List returns the signing keys, retired ones included, without their
+// private parts
+func (h *JWTKeyHandler) List(c *gin.Context) {
+	if h.keyService == nil {
+		respondError(c, services.ErrJWTKeysDisabled, "AUTH_010", "JWT signing keys are not in use")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		h.keyService.List(),
+		"JWT signing keys retrieved successfully",
+	))
+}
+
+// Rotate generates a new signing key. The previous one keeps verifying the
+// tokens it signed until they expire.
+func (h *JWTKeyHandler) Rotate(c *gin.Context) {
+	if h.keyService == nil {
+		respondError(c, services.ErrJWTKeysDisabled, "AUTH_010", "JWT signing keys are not in use")
+		return
+	}
+
+	key, err := h.keyService.Rotate(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "SYS_136", "Failed to rotate the JWT signing key")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditJWTKeyRotated, "jwt_signing_key", nil, map[string]any{
+		"kid":       key.ID,
+		"algorithm": key.Algorithm,
+	}))
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		key,
+		"JWT signing key rotated successfully",
+	))
+}
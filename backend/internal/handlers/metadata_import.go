@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxMetadataImportBytes bounds the size of a metadata import CSV
+const maxMetadataImportBytes = 10 << 20
+
+// MetadataImportHandler handles bulk document metadata imports
+type MetadataImportHandler struct {
+	importService *services.MetadataImportService
+	auditService  *services.AuditService
+}
+
+// NewMetadataImportHandler creates a new metadata import handler
+func NewMetadataImportHandler(importService *services.MetadataImportService, auditService *services.AuditService) *MetadataImportHandler {
+	return &MetadataImportHandler{
+		importService: importService,
+		auditService:  auditService,
+	}
+}
+
+// Import applies a CSV of document tags, folders and custom fields, sent as
+// the file field of a multipart form or as a text/csv body. With
+// ?mode=replace the rows replace the documents' labels and custom fields
+// instead of adding to them; with ?dry_run=true the rows are only validated.
+func (h *MetadataImportHandler) Import(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	mode := models.MetadataImportMode(c.DefaultQuery("mode", string(models.MetadataImportMerge)))
+	if mode != models.MetadataImportMerge && mode != models.MetadataImportReplace {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"mode must be merge or replace",
+			nil,
+		))
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	// Read the CSV from the form's file field or the body
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMetadataImportBytes)
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_004",
+				"No file uploaded",
+				nil,
+			))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_004",
+				"No file uploaded",
+				nil,
+			))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := h.importService.Import(c.Request.Context(), userID, body, mode, dryRun)
+	if err != nil {
+		respondError(c, err, "SYS_049", "Failed to import document metadata")
+		return
+	}
+
+	if !dryRun {
+		h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditMetadataImported, "metadata_import", &result.ID, map[string]any{
+			"mode":    result.Mode,
+			"rows":    result.Rows,
+			"updated": result.Updated,
+			"failed":  result.Failed,
+		}))
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		result,
+		"Document metadata imported",
+	))
+}
+
+// DownloadErrors returns the rows of an import that failed, as CSV
+func (h *MetadataImportHandler) DownloadErrors(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	importID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid import ID",
+			nil,
+		))
+		return
+	}
+
+	report, err := h.importService.OpenErrorReport(userID, importID)
+	if err != nil {
+		respondError(c, err, "SYS_050", "Failed to get error report")
+		return
+	}
+	defer report.Close()
+
+	setAttachment(c, "metadata-import-"+importID.String()+"-errors.csv")
+	c.DataFromReader(http.StatusOK, -1, "text/csv; charset=utf-8", report, nil)
+}
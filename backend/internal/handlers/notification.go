@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles the Slack and Teams channels users are notified
+// in when their jobs finish
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+	validator           *validator.Validator
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		validator:           validator.New(),
+	}
+}
+
+// List returns the user's notification channels
+func (h *NotificationHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	channels, err := h.notificationService.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_113", "Failed to list notification channels")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		channels,
+		"Notification channels retrieved successfully",
+	))
+}
+
+// Create adds a Slack or Teams incoming webhook as a notification channel
+func (h *NotificationHandler) Create(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.NotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	channel, err := h.notificationService.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_114", "Failed to create notification channel")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		channel,
+		"Notification channel created successfully",
+	))
+}
+
+// Update changes a notification channel
+func (h *NotificationHandler) Update(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	channelID, ok := parseIDParam(c, "id", "VAL_057", "Invalid notification channel ID")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.NotificationChannelUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	channel, err := h.notificationService.Update(c.Request.Context(), channelID, userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_115", "Failed to update notification channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		channel,
+		"Notification channel updated successfully",
+	))
+}
+
+// Delete removes a notification channel
+func (h *NotificationHandler) Delete(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	channelID, ok := parseIDParam(c, "id", "VAL_057", "Invalid notification channel ID")
+	if !ok {
+		return
+	}
+
+	if err := h.notificationService.Delete(c.Request.Context(), channelID, userID); err != nil {
+		respondError(c, err, "SYS_116", "Failed to delete notification channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Notification channel deleted successfully",
+	))
+}
+
+// Test posts a sample message to a notification channel
+func (h *NotificationHandler) Test(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	channelID, ok := parseIDParam(c, "id", "VAL_057", "Invalid notification channel ID")
+	if !ok {
+		return
+	}
+
+	if err := h.notificationService.Test(c.Request.Context(), channelID, userID); err != nil {
+		respondError(c, err, "SYS_117", "Failed to send test notification")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Test notification sent successfully",
+	))
+}
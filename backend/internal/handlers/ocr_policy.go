@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OCRPolicyHandler handles the OCR defaults and limits of organizations and
+// the instance-wide policy of users outside them
+type OCRPolicyHandler struct {
+	policyService *services.OCRPolicyService
+	auditService  *services.AuditService
+	validator     *validator.Validator
+}
+
+// NewOCRPolicyHandler creates a new OCR policy handler
+func NewOCRPolicyHandler(policyService *services.OCRPolicyService, auditService *services.AuditService) *OCRPolicyHandler {
+	return &OCRPolicyHandler{
+		policyService: policyService,
+		auditService:  auditService,
+		validator:     validator.New(),
+	}
+}
+
+// GetOwn returns the OCR policy the user's jobs are subject to
+func (h *OCRPolicyHandler) GetOwn(c *gin.Context) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	policy, err := h.policyService.GetForUser(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_020",
+			"Failed to get OCR policy",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		policy,
+		"OCR policy retrieved successfully",
+	))
+}
+
+// Get returns the instance-wide OCR policy
+func (h *OCRPolicyHandler) Get(c *gin.Context) {
+	policy, err := h.policyService.Get(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_020",
+			"Failed to get OCR policy",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		policy,
+		"OCR policy retrieved successfully",
+	))
+}
+
+// Update changes the fields of the instance-wide policy present in the request
+func (h *OCRPolicyHandler) Update(c *gin.Context) {
+	// Get authenticated admin
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.UpdateOCRPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	policy, err := h.policyService.Update(c.Request.Context(), req, userID)
+	if err != nil {
+		respondPolicyError(c, err)
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOCRPolicyUpdated, "ocr_policy", nil, policyAuditMetadata(policy)))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		policy,
+		"OCR policy updated successfully",
+	))
+}
+
+// GetForOrg returns the OCR policy of an organization the user is a member of
+func (h *OCRPolicyHandler) GetForOrg(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	policy, err := h.policyService.GetForOrg(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_020", "Failed to get OCR policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		policy,
+		"OCR policy retrieved successfully",
+	))
+}
+
+// UpdateForOrg changes the fields of the policy of an organization the user
+// owns present in the request
+func (h *OCRPolicyHandler) UpdateForOrg(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.UpdateOCRPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	policy, err := h.policyService.UpdateForOrg(c.Request.Context(), orgID, userID, req)
+	if err != nil {
+		respondPolicyError(c, err)
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOCRPolicyUpdated, "organization", &orgID, policyAuditMetadata(policy)))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		policy,
+		"OCR policy updated successfully",
+	))
+}
+
+// orgRequest returns the authenticated user and the organization in the path,
+// having responded with an error when either is missing
+func (h *OCRPolicyHandler) orgRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	orgID, ok := parseIDParam(c, "id", "VAL_029", "Invalid organization ID")
+	return userID, orgID, ok
+}
+
+// respondPolicyError responds to a failed policy update, reporting policy
+// violations as validation errors
+func respondPolicyError(c *gin.Context, err error) {
+	var violation *services.PolicyViolationError
+	if errors.As(err, &violation) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			violation.Message,
+			[]models.ValidationError{{Field: violation.Field, Message: violation.Message}},
+		))
+		return
+	}
+	respondError(c, err, "SYS_021", "Failed to update OCR policy")
+}
+
+// policyAuditMetadata returns the policy fields recorded in audit events
+func policyAuditMetadata(policy *models.OCRPolicy) map[string]any {
+	return map[string]any{
+		"default_ocr_mode":        policy.DefaultOCRMode,
+		"default_resolution":      policy.DefaultResolution,
+		"allowed_resolutions":     policy.AllowedResolutions,
+		"max_pages_per_job":       policy.MaxPagesPerJob,
+		"share_duplicate_uploads": policy.ShareDuplicateUploads,
+		"approval_page_threshold": policy.ApprovalPageThreshold,
+		"approval_resolutions":    policy.ApprovalResolutions,
+		"approvers":               policy.Approvers,
+	}
+}
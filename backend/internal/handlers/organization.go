@@ -0,0 +1,544 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OrganizationHandler handles organizations, their members and the documents
+// shared with them
+type OrganizationHandler struct {
+	orgService   *services.OrganizationService
+	auditService *services.AuditService
+	validator    *validator.Validator
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(orgService *services.OrganizationService, auditService *services.AuditService) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService:   orgService,
+		auditService: auditService,
+		validator:    validator.New(),
+	}
+}
+
+// Create creates an organization owned by the user
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.OrgCreateRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	org, err := h.orgService.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_051", "Failed to create organization")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgCreated, "organization", &org.ID, map[string]any{
+		"name": org.Name,
+	}))
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		org,
+		"Organization created successfully",
+	))
+}
+
+// List returns the organizations the user is a member of
+func (h *OrganizationHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	orgs, err := h.orgService.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_052", "Failed to list organizations")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		orgs,
+		"Organizations retrieved successfully",
+	))
+}
+
+// Get returns one of the user's organizations with its members
+func (h *OrganizationHandler) Get(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	org, err := h.orgService.Get(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_053", "Failed to get organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		org,
+		"Organization retrieved successfully",
+	))
+}
+
+// Delete deletes an organization the user owns
+func (h *OrganizationHandler) Delete(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.Delete(c.Request.Context(), orgID, userID); err != nil {
+		respondError(c, err, "SYS_054", "Failed to delete organization")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgDeleted, "organization", &orgID, nil))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Organization deleted successfully",
+	))
+}
+
+// Invite invites a user by email to an organization the user owns
+func (h *OrganizationHandler) Invite(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.OrgInviteRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	invitation, err := h.orgService.Invite(c.Request.Context(), orgID, userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_055", "Failed to invite user")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgMemberInvited, "organization", &orgID, map[string]any{
+		"invitation_id": invitation.ID,
+		"email":         invitation.Email,
+		"role":          invitation.Role,
+	}))
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		invitation,
+		"Invitation sent successfully",
+	))
+}
+
+// ListInvitations returns the pending invitations of an organization the user owns
+func (h *OrganizationHandler) ListInvitations(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.orgService.ListInvitations(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_056", "Failed to list invitations")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		invitations,
+		"Invitations retrieved successfully",
+	))
+}
+
+// RevokeInvitation withdraws a pending invitation of an organization the user owns
+func (h *OrganizationHandler) RevokeInvitation(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	invitationID, ok := parseIDParam(c, "invitation_id", "VAL_030", "Invalid invitation ID")
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.RevokeInvitation(c.Request.Context(), orgID, invitationID, userID); err != nil {
+		respondError(c, err, "SYS_057", "Failed to revoke invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Invitation revoked successfully",
+	))
+}
+
+// ListReceivedInvitations returns the pending invitations sent to the user
+func (h *OrganizationHandler) ListReceivedInvitations(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	invitations, err := h.orgService.ListReceivedInvitations(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_056", "Failed to list invitations")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		invitations,
+		"Invitations retrieved successfully",
+	))
+}
+
+// AcceptInvitation joins the organization an invitation sent to the user is for
+func (h *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	userID, invitationID, ok := h.invitationRequest(c)
+	if !ok {
+		return
+	}
+
+	invitation, err := h.orgService.AcceptInvitation(c.Request.Context(), invitationID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_057", "Failed to answer invitation")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgMemberJoined, "organization", &invitation.OrganizationID, map[string]any{
+		"invitation_id": invitation.ID,
+		"role":          invitation.Role,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		invitation,
+		"Invitation accepted successfully",
+	))
+}
+
+// DeclineInvitation declines an invitation sent to the user
+func (h *OrganizationHandler) DeclineInvitation(c *gin.Context) {
+	userID, invitationID, ok := h.invitationRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.DeclineInvitation(c.Request.Context(), invitationID, userID); err != nil {
+		respondError(c, err, "SYS_057", "Failed to answer invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Invitation declined successfully",
+	))
+}
+
+// UpdateMember changes the role of a member of an organization the user owns
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	memberID, ok := parseIDParam(c, "user_id", "VAL_031", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.OrgMemberUpdateRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	if err := h.orgService.UpdateMember(c.Request.Context(), orgID, userID, memberID, req.Role); err != nil {
+		respondError(c, err, "SYS_058", "Failed to update organization member")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgMemberUpdated, "organization", &orgID, map[string]any{
+		"member_id": memberID,
+		"role":      req.Role,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Organization member updated successfully",
+	))
+}
+
+// RemoveMember removes a member from an organization, or lets the user leave it
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	memberID, ok := parseIDParam(c, "user_id", "VAL_031", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.RemoveMember(c.Request.Context(), orgID, userID, memberID); err != nil {
+		respondError(c, err, "SYS_058", "Failed to remove organization member")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgMemberRemoved, "organization", &orgID, map[string]any{
+		"member_id": memberID,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Organization member removed successfully",
+	))
+}
+
+// ShareDocuments shares some of the user's documents with an organization
+func (h *OrganizationHandler) ShareDocuments(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.OrgShareRequest
+	if !h.bind(c, &req) {
+		return
+	}
+
+	shared, err := h.orgService.ShareDocuments(c.Request.Context(), orgID, userID, req.DocumentIDs)
+	if err != nil {
+		respondError(c, err, "SYS_059", "Failed to share documents")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgDocsShared, "organization", &orgID, map[string]any{
+		"document_ids": shared,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		gin.H{"document_ids": shared},
+		"Documents shared successfully",
+	))
+}
+
+// UnshareDocument stops sharing a document with an organization
+func (h *OrganizationHandler) UnshareDocument(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	documentID, ok := parseIDParam(c, "document_id", "VAL_007", "Invalid document ID")
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.UnshareDocument(c.Request.Context(), orgID, userID, documentID); err != nil {
+		respondError(c, err, "SYS_059", "Failed to unshare document")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgDocUnshared, "organization", &orgID, map[string]any{
+		"document_id": documentID,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Document unshared successfully",
+	))
+}
+
+// ListDocuments returns the documents shared with one of the user's organizations
+func (h *OrganizationHandler) ListDocuments(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	page, perPage, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+	documents, total, err := h.orgService.ListDocuments(c.Request.Context(), orgID, userID, page, perPage)
+	if err != nil {
+		respondError(c, err, "SYS_060", "Failed to list organization documents")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		paginated(documents, page, perPage, total),
+		"Organization documents retrieved successfully",
+	))
+}
+
+// GetDocument returns a document shared with one of the user's organizations,
+// with its latest result
+func (h *OrganizationHandler) GetDocument(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	documentID, ok := parseIDParam(c, "document_id", "VAL_007", "Invalid document ID")
+	if !ok {
+		return
+	}
+
+	document, err := h.orgService.GetDocument(c.Request.Context(), orgID, userID, documentID)
+	if err != nil {
+		respondError(c, err, "SYS_060", "Failed to get organization document")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		document,
+		"Organization document retrieved successfully",
+	))
+}
+
+// ListJobs returns the jobs of the documents shared with one of the user's
+// organizations
+func (h *OrganizationHandler) ListJobs(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	page, perPage, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+	jobs, total, err := h.orgService.ListJobs(c.Request.Context(), orgID, userID, page, perPage)
+	if err != nil {
+		respondError(c, err, "SYS_061", "Failed to list organization jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		paginated(jobs, page, perPage, total),
+		"Organization jobs retrieved successfully",
+	))
+}
+
+// bind parses and validates a JSON request body
+func (h *OrganizationHandler) bind(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return false
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return false
+	}
+
+	return true
+}
+
+// orgRequest returns the authenticated user and the organization named in the path
+func (h *OrganizationHandler) orgRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	orgID, ok := parseIDParam(c, "id", "VAL_029", "Invalid organization ID")
+	return userID, orgID, ok
+}
+
+// invitationRequest returns the authenticated user and the invitation named in the path
+func (h *OrganizationHandler) invitationRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	invitationID, ok := parseIDParam(c, "id", "VAL_030", "Invalid invitation ID")
+	return userID, invitationID, ok
+}
+
+// parseIDParam parses a UUID path parameter, responding with the given error
+// code when it is invalid
+func parseIDParam(c *gin.Context, name, code, message string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			code,
+			message,
+			nil,
+		))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// paginated wraps a page of items with its pagination details
+func paginated(items any, page, perPage, total int) models.PaginatedResponse {
+	totalPages := (total + perPage - 1) / perPage
+	return models.PaginatedResponse{
+		Items: items,
+		Pagination: models.Pagination{
+			Page:       page,
+			PerPage:    perPage,
+			Total:      total,
+			TotalPages: totalPages,
+			HasNext:    page < totalPages,
+			HasPrev:    page > 1,
+		},
+	}
+}
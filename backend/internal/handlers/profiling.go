@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"time"
+
+	"visekai/backend/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxProfileSeconds caps the duration of CPU profiles and execution traces
+const maxProfileSeconds = 60
+
+// RegisterProfilingRoutes exposes the runtime profiling endpoints of net/http/pprof
+// on the given route group, which must already require admin access
+func RegisterProfilingRoutes(group *gin.RouterGroup) {
+	group.Use(extendWriteDeadline())
+
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", gin.WrapF(pprof.Profile))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+	for _, name := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+		group.GET("/"+name, gin.WrapH(pprof.Handler(name)))
+	}
+}
+
+// extendWriteDeadline lets CPU profiles and traces run longer than the server's
+// write timeout. The requested duration is capped at maxProfileSeconds.
+func extendWriteDeadline() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if raw := c.Query("seconds"); raw != "" {
+			seconds, err := strconv.Atoi(raw)
+			if err != nil || seconds < 1 {
+				seconds = 1
+			}
+			if seconds > maxProfileSeconds {
+				seconds = maxProfileSeconds
+			}
+
+			query := c.Request.URL.Query()
+			query.Set("seconds", strconv.Itoa(seconds))
+			c.Request.URL.RawQuery = query.Encode()
+
+			deadline := time.Now().Add(time.Duration(seconds)*time.Second + 10*time.Second)
+			if err := http.NewResponseController(c.Writer).SetWriteDeadline(deadline); err != nil {
+				logger.FromContext(c.Request.Context()).Warn("Failed to extend write deadline for profiling", "error", err)
+			}
+		}
+
+		logger.FromContext(c.Request.Context()).Info("Profiling endpoint accessed", "path", c.Request.URL.Path)
+
+		c.Next()
+	}
+}
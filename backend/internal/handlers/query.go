@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryValidator validates the query parameters of list requests
+var queryValidator = validator.New()
+
+// bindQuery binds the query parameters named by the form tags of req, a
+// pointer to a struct holding the defaults of absent parameters, and validates
+// them. Parameters that are malformed or invalid are answered with a 400
+// listing each of them, and bindQuery reports false.
+func bindQuery(c *gin.Context, req any) bool {
+	if fields := queryTypeErrors(c, req); len(fields) > 0 {
+		respondInvalidQuery(c, fields)
+		return false
+	}
+
+	if err := c.ShouldBindQuery(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid query parameters: "+err.Error(),
+			nil,
+		))
+		return false
+	}
+
+	if err := queryValidator.Validate(req); err != nil {
+		var fields []models.ValidationError
+		for _, field := range validator.FieldErrors(err) {
+			fields = append(fields, models.ValidationError{Field: field.Field, Message: field.Message})
+		}
+		if len(fields) == 0 {
+			fields = []models.ValidationError{{Field: "query", Message: err.Error()}}
+		}
+		respondInvalidQuery(c, fields)
+		return false
+	}
+	return true
+}
+
+// pageParams binds the page and per_page query parameters, defaulting to the
+// first page of perPage items. Invalid values are answered with a 400, and
+// pageParams reports false.
+func pageParams(c *gin.Context, perPage int) (int, int, bool) {
+	req := models.PageRequest{Page: 1, PerPage: perPage}
+	if !bindQuery(c, &req) {
+		return 0, 0, false
+	}
+	return req.Page, req.PerPage, true
+}
+
+// respondInvalidQuery answers a request with invalid query parameters
+func respondInvalidQuery(c *gin.Context, fields []models.ValidationError) {
+	c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+		"VAL_001",
+		"Invalid query parameters",
+		fields,
+	))
+}
+
+// queryTypeErrors returns the query parameters of req's numeric and boolean
+// fields whose values do not parse as such, which binding would only report
+// without naming the parameter
+func queryTypeErrors(c *gin.Context, req any) []models.ValidationError {
+	t := reflect.TypeOf(req).Elem()
+
+	var fields []models.ValidationError
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		kind := field.Type.Kind()
+		if kind == reflect.Pointer {
+			kind = field.Type.Elem().Kind()
+		}
+		for _, value := range c.QueryArray(name) {
+			if value == "" {
+				continue
+			}
+			var message string
+			switch kind {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+					message = "must be an integer"
+				}
+			case reflect.Bool:
+				if _, err := strconv.ParseBool(value); err != nil {
+					message = "must be true or false"
+				}
+			}
+			if message != "" {
+				fields = append(fields, models.ValidationError{Field: name, Message: message})
+				break
+			}
+		}
+	}
+	return fields
+}
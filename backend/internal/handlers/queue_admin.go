@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// QueueAdminHandler handles admin requests to inspect and manipulate the job queue
+type QueueAdminHandler struct {
+	queueService *services.QueueAdminService
+	auditService *services.AuditService
+	validator    *validator.Validator
+}
+
+// NewQueueAdminHandler creates a new queue admin handler
+func NewQueueAdminHandler(queueService *services.QueueAdminService, auditService *services.AuditService) *QueueAdminHandler {
+	return &QueueAdminHandler{
+		queueService: queueService,
+		auditService: auditService,
+		validator:    validator.New(),
+	}
+}
+
+// QueueStats returns the number of jobs by status and the worker pool state
+func (h *QueueAdminHandler) QueueStats(c *gin.Context) {
+	stats, err := h.queueService.QueueStats(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_022",
+			"Failed to get queue stats",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		stats,
+		"Queue stats retrieved successfully",
+	))
+}
+
+// ListJobs lists the jobs of every user matching the query filters, oldest first
+func (h *QueueAdminHandler) ListJobs(c *gin.Context) {
+	var filter models.JobFilter
+
+	if raw := c.Query("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			filter.Statuses = append(filter.Statuses, models.JobStatus(strings.TrimSpace(status)))
+		}
+	}
+
+	for param, target := range map[string]**uuid.UUID{"user_id": &filter.UserID, "document_id": &filter.DocumentID} {
+		if raw := c.Query(param); raw != "" {
+			id, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					"VAL_001",
+					"Invalid "+param,
+					nil,
+				))
+				return
+			}
+			*target = &id
+		}
+	}
+
+	for param, target := range map[string]**time.Time{"created_before": &filter.CreatedBefore, "started_before": &filter.StartedBefore} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					"VAL_001",
+					param+" must be an RFC3339 timestamp",
+					nil,
+				))
+				return
+			}
+			*target = &t
+		}
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				"limit must be a number",
+				nil,
+			))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	// Validate filter
+	if err := h.validator.Validate(filter); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	jobs, err := h.queueService.ListJobs(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_023",
+			"Failed to list jobs",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		jobs,
+		"Jobs retrieved successfully",
+	))
+}
+
+// Requeue resets the jobs matching the filter in the body to pending and queues them
+func (h *QueueAdminHandler) Requeue(c *gin.Context) {
+	h.bulkUpdate(c, h.queueService.Requeue, models.AuditJobsRequeued, "SYS_024", "requeue", "requeued")
+}
+
+// Cancel cancels the active jobs matching the filter in the body
+func (h *QueueAdminHandler) Cancel(c *gin.Context) {
+	h.bulkUpdate(c, h.queueService.Cancel, models.AuditJobsCancelled, "SYS_025", "cancel", "cancelled")
+}
+
+// bulkUpdate applies a bulk job operation to the filter in the request body
+func (h *QueueAdminHandler) bulkUpdate(
+	c *gin.Context,
+	apply func(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error),
+	action models.AuditAction,
+	errorCode, verb, pastTense string,
+) {
+	// Parse request
+	var filter models.JobFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(filter); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	ids, err := apply(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidJobFilter) {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				err.Error(),
+				[]models.ValidationError{{Field: "statuses", Message: err.Error()}},
+			))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			errorCode,
+			"Failed to "+verb+" jobs",
+			nil,
+		))
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, action, "job", nil, map[string]any{
+		"filter": filter,
+		"jobs":   len(ids),
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		models.JobBulkResult{Jobs: ids},
+		"Jobs "+pastTense+" successfully",
+	))
+}
+
+// Cleanup runs the janitor and expires overdue pending jobs immediately
+func (h *QueueAdminHandler) Cleanup(c *gin.Context) {
+	report, err := h.queueService.Cleanup(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_026",
+			"Failed to run cleanup",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		report,
+		"Cleanup completed successfully",
+	))
+}
+
+// OCRHealth checks the OCR backend from the API server's point of view
+func (h *QueueAdminHandler) OCRHealth(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	report := h.queueService.OCRHealth(ctx)
+
+	status := http.StatusOK
+	if !report.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, models.NewSuccessResponse(
+		report,
+		"OCR health check completed",
+	))
+}
@@ -0,0 +1,635 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/jsonstream"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+	"visekai/backend/pkg/validator"
+	"visekai/backend/pkg/xlsx"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ResultHandler handles OCR result-related requests
+type ResultHandler struct {
+	resultService   *services.ResultService
+	brandingService *services.BrandingService
+	validator       *validator.Validator
+}
+
+// NewResultHandler creates a new result handler
+func NewResultHandler(resultService *services.ResultService, brandingService *services.BrandingService) *ResultHandler {
+	return &ResultHandler{
+		resultService:   resultService,
+		brandingService: brandingService,
+		validator:       validator.New(),
+	}
+}
+
+// GetRegions handles getting the word and line bounding boxes of a result
+func (h *ResultHandler) GetRegions(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse result ID
+	resultID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_009",
+			"Invalid result ID",
+			nil,
+		))
+		return
+	}
+
+	// Parse optional page filter
+	page := 0
+	if raw := c.Query("page"); raw != "" {
+		page, err = strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_010",
+				"Page must be a positive integer",
+				nil,
+			))
+			return
+		}
+	}
+
+	// Get regions
+	regions, err := h.resultService.GetRegions(c.Request.Context(), resultID, userID, page)
+	if err != nil {
+		respondError(c, err, "SYS_033", "Failed to get result")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		gin.H{
+			"result_id": resultID,
+			"page":      page,
+			"regions":   regions,
+		},
+		"Regions retrieved successfully",
+	))
+}
+
+// GetEntities handles getting the entities extracted from a result's text,
+// optionally only those of the type given by ?type=
+func (h *ResultHandler) GetEntities(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse result ID
+	resultID, ok := parseIDParam(c, "id", "VAL_009", "Invalid result ID")
+	if !ok {
+		return
+	}
+
+	entityType := models.EntityType(c.Query("type"))
+	if entityType != "" && !entityType.Valid() {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_047",
+			"Invalid entity type",
+			nil,
+		))
+		return
+	}
+
+	entities, err := h.resultService.GetEntities(c.Request.Context(), resultID, userID, entityType)
+	if err != nil {
+		respondError(c, err, "SYS_091", "Failed to get result entities")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		gin.H{
+			"result_id": resultID,
+			"entities":  entities,
+		},
+		"Entities retrieved successfully",
+	))
+}
+
+// GetFields handles getting the fields extracted from a result with the
+// user's extraction templates
+func (h *ResultHandler) GetFields(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse result ID
+	resultID, ok := parseIDParam(c, "id", "VAL_009", "Invalid result ID")
+	if !ok {
+		return
+	}
+
+	fields, err := h.resultService.GetFields(c.Request.Context(), resultID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_097", "Failed to get result fields")
+		return
+	}
+
+	// Results no template found anything in have no fields
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		fields,
+		"Fields retrieved successfully",
+	))
+}
+
+// ExportFields handles downloading the fields extracted from many documents
+// as a CSV or XLSX spreadsheet with one row per document
+func (h *ResultHandler) ExportFields(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.FieldsExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+	if req.Format == "" {
+		req.Format = "csv"
+	}
+
+	var buf bytes.Buffer
+	if err := h.resultService.ExportFields(c.Request.Context(), userID, req, &buf); err != nil {
+		respondError(c, err, "SYS_098", "Failed to export fields")
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if req.Format == "xlsx" {
+		contentType = xlsx.MimeType
+	}
+	setAttachment(c, "fields-"+time.Now().UTC().Format("20060102")+"."+req.Format)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// GetPages handles getting the latest version of every page of a result
+func (h *ResultHandler) GetPages(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse result ID
+	resultID, ok := parseIDParam(c, "id", "VAL_009", "Invalid result ID")
+	if !ok {
+		return
+	}
+
+	pages, err := h.resultService.GetPages(c.Request.Context(), resultID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_089", "Failed to get result pages")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		pages,
+		"Result pages retrieved successfully",
+	))
+}
+
+// GetPage handles getting the text of a single page of a result, at its
+// latest version or the one given by ?version=
+func (h *ResultHandler) GetPage(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse result ID
+	resultID, ok := parseIDParam(c, "id", "VAL_009", "Invalid result ID")
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.Param("page"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_010",
+			"Page must be a positive integer",
+			nil,
+		))
+		return
+	}
+
+	version := 0
+	if raw := c.Query("version"); raw != "" {
+		version, err = strconv.Atoi(raw)
+		if err != nil || version < 1 {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_043",
+				"Version must be a positive integer",
+				nil,
+			))
+			return
+		}
+	}
+
+	resultPage, err := h.resultService.GetPage(c.Request.Context(), resultID, userID, page, version)
+	if err != nil {
+		respondError(c, err, "SYS_090", "Failed to get result page")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		resultPage,
+		"Result page retrieved successfully",
+	))
+}
+
+// GetBreakdown handles getting the processing time, backend and credits of
+// each page of a result
+func (h *ResultHandler) GetBreakdown(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse result ID
+	resultID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_009",
+			"Invalid result ID",
+			nil,
+		))
+		return
+	}
+
+	breakdown, err := h.resultService.GetBreakdown(c.Request.Context(), resultID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_045", "Failed to get result breakdown")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		breakdown,
+		"Result breakdown retrieved successfully",
+	))
+}
+
+// GetPageUsage handles summing the cost and processing time of the pages the
+// user had processed by resolution and backend. The period defaults to the
+// last 30 days.
+func (h *ResultHandler) GetPageUsage(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	until := time.Now().UTC()
+	since := until.AddDate(0, 0, -30)
+	for param, target := range map[string]*time.Time{"since": &since, "until": &until} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					"VAL_001",
+					param+" must be an RFC3339 timestamp",
+					nil,
+				))
+				return
+			}
+			*target = t
+		}
+	}
+	if !since.Before(until) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"since must be before until",
+			nil,
+		))
+		return
+	}
+
+	usage, err := h.resultService.PageUsage(c.Request.Context(), userID, since, until)
+	if err != nil {
+		respondError(c, err, "SYS_046", "Failed to get page usage")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		usage,
+		"Page usage retrieved successfully",
+	))
+}
+
+// Download handles downloading a result in the requested format
+func (h *ResultHandler) Download(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse result ID
+	resultID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_009",
+			"Invalid result ID",
+			nil,
+		))
+		return
+	}
+
+	// Get result
+	result, err := h.resultService.GetResult(c.Request.Context(), resultID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_033", "Failed to get result")
+		return
+	}
+
+	document, err := h.resultService.GetResultDocument(c.Request.Context(), result)
+	if err != nil {
+		respondError(c, err, "SYS_034", "Failed to get document")
+		return
+	}
+	// Documents uploaded before filenames were normalized may still carry unsafe names
+	filename := storage.NormalizeFilename(document.OriginalFilename)
+	baseName := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+	format := models.ResultExportFormat(c.DefaultQuery("format", string(models.ExportFormatMarkdown)))
+	switch format {
+	case models.ExportFormatText:
+		setAttachment(c, baseName+".txt")
+		writeText(c, "text/plain; charset=utf-8", h.exportHeader(c, document, format)+result.RawText)
+
+	case models.ExportFormatMarkdown:
+		setAttachment(c, baseName+".md")
+		writeText(c, "text/markdown; charset=utf-8", h.exportHeader(c, document, format)+result.MarkdownText)
+
+	case models.ExportFormatJSON:
+		setAttachment(c, baseName+".json")
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Status(http.StatusOK)
+		w := jsonstream.NewIndentWriter(c.Writer, "    ")
+		writeResultJSON(w, result)
+		if err := w.Flush(); err != nil {
+			logger.FromContext(c.Request.Context()).Warn("Failed to stream result export", "result_id", result.ID, "error", err)
+		}
+
+	case models.ExportFormatSearchablePDF:
+		var buf bytes.Buffer
+		err := h.resultService.WriteSearchablePDF(c.Request.Context(), result, &buf)
+		if errors.Is(err, services.ErrSearchablePDFUnsupported) {
+			c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+				"EXP_001",
+				err.Error(),
+				nil,
+			))
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				"SYS_009",
+				"Failed to generate searchable PDF",
+				nil,
+			))
+			return
+		}
+		setAttachment(c, baseName+".pdf")
+		c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+
+	default:
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_011",
+			fmt.Sprintf("Unsupported export format: %s", format),
+			nil,
+		))
+	}
+}
+
+// Get handles getting a single OCR result
+func (h *ResultHandler) Get(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse result ID
+	resultID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_009",
+			"Invalid result ID",
+			nil,
+		))
+		return
+	}
+
+	// Get result
+	result, err := h.resultService.GetResult(c.Request.Context(), resultID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_033", "Failed to get result")
+		return
+	}
+
+	streamResult(c, result, "Result retrieved successfully")
+}
+
+// streamResult writes a success response holding a result. The text fields can be
+// megabytes long, so the response is encoded incrementally rather than marshalled
+// up front the way c.JSON does. Results only change by getting a new version, so
+// clients revalidating with the ETag or Last-Modified get 304 Not Modified instead.
+func streamResult(c *gin.Context, result *models.OCRResult, message string) {
+	lastModified := result.CreatedAt
+	if result.UpdatedAt != nil {
+		lastModified = *result.UpdatedAt
+	}
+	if notModified(c, weakETag(result.ID[:], []byte(strconv.Itoa(result.Version))), lastModified) {
+		return
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := jsonstream.NewWriter(c.Writer)
+	w.BeginObject()
+	w.Key("success")
+	w.Value(true)
+	w.Key("data")
+	writeResultJSON(w, result)
+	w.Key("message")
+	w.String(message)
+	w.Key("timestamp")
+	w.Value(time.Now())
+	w.EndObject()
+
+	if err := w.Flush(); err != nil {
+		logger.FromContext(c.Request.Context()).Warn("Failed to stream result", "result_id", result.ID, "error", err)
+	}
+}
+
+// writeResultJSON writes a result with the same layout as its JSON encoding
+func writeResultJSON(w *jsonstream.Writer, result *models.OCRResult) {
+	w.BeginObject()
+	w.Key("id")
+	w.Value(result.ID)
+	w.Key("job_id")
+	w.Value(result.JobID)
+	w.Key("document_id")
+	w.Value(result.DocumentID)
+	w.Key("raw_text")
+	w.String(result.RawText)
+	w.Key("markdown_text")
+	w.String(result.MarkdownText)
+	if len(result.JSONData) > 0 {
+		w.Key("json_data")
+		w.Value(result.JSONData)
+	}
+	w.Key("confidence_score")
+	w.Value(result.ConfidenceScore)
+	w.Key("processing_time_ms")
+	w.Value(result.ProcessingTimeMs)
+	w.Key("num_pages")
+	w.Value(result.NumPages)
+	w.Key("version")
+	w.Value(result.Version)
+	w.Key("created_at")
+	w.Value(result.CreatedAt)
+	if result.UpdatedAt != nil {
+		w.Key("updated_at")
+		w.Value(result.UpdatedAt)
+	}
+	w.EndObject()
+}
+
+// exportHeader returns the header naming the organization that text and Markdown
+// exports of the document start with when its branding enables branded
+// exports, or an empty string
+func (h *ResultHandler) exportHeader(c *gin.Context, document *models.Document, format models.ResultExportFormat) string {
+	branding, err := h.brandingService.ForExports(c.Request.Context(), document)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Warn("Failed to get branding for export", "error", err)
+		return ""
+	}
+	if branding == nil || branding.DisplayName == "" {
+		return ""
+	}
+
+	if format == models.ExportFormatText {
+		return branding.DisplayName + "\n\n"
+	}
+	header := "**" + branding.DisplayName + "**"
+	if branding.LogoURL != "" {
+		header = "![" + branding.DisplayName + "](" + branding.LogoURL + ") " + header
+	}
+	return header + "\n\n---\n\n"
+}
+
+// writeText writes a text body in chunks without copying it into a byte slice
+func writeText(c *gin.Context, contentType, text string) {
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.Itoa(len(text)))
+	c.Status(http.StatusOK)
+
+	const chunkSize = 32 * 1024
+	for start := 0; start < len(text); start += chunkSize {
+		if _, err := c.Writer.WriteString(text[start:min(start+chunkSize, len(text))]); err != nil {
+			logger.FromContext(c.Request.Context()).Warn("Failed to write download", "error", err)
+			return
+		}
+	}
+}
+
+// setAttachment sets the Content-Disposition header for a file download
+func setAttachment(c *gin.Context, filename string) {
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+}
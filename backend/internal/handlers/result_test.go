@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// discardResponseWriter is a response writer that drops the body, so that
+// benchmarks measure encoding rather than buffering the response
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// largeResult returns a result with a few megabytes of text, as a long scanned
+// document produces
+func largeResult() *models.OCRResult {
+	page := strings.Repeat("Invoice 42 \"ACME & Co\" <total>\tÜber 10.00 €\n", 1_000)
+	return &models.OCRResult{
+		ID:               uuid.New(),
+		JobID:            uuid.New(),
+		DocumentID:       uuid.New(),
+		RawText:          strings.Repeat(page, 50),
+		MarkdownText:     strings.Repeat("## Page\n\n"+page, 50),
+		JSONData:         map[string]any{"pages": 50},
+		ConfidenceScore:  0.93,
+		ProcessingTimeMs: 120_000,
+		NumPages:         50,
+		Version:          1,
+		CreatedAt:        time.Now().UTC(),
+	}
+}
+
+func newResultContext(w http.ResponseWriter) *gin.Context {
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/results/id", nil)
+	return c
+}
+
+func TestStreamResultMatchesJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	result := largeResult()
+	now := time.Now()
+	result.UpdatedAt = &now
+
+	recorder := httptest.NewRecorder()
+	streamResult(newResultContext(recorder), result, "Result retrieved successfully")
+
+	var streamed struct {
+		Success bool             `json:"success"`
+		Data    models.OCRResult `json:"data"`
+		Message string           `json:"message"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &streamed); err != nil {
+		t.Fatalf("streamed response is not valid JSON: %v", err)
+	}
+	if !streamed.Success || streamed.Message != "Result retrieved successfully" {
+		t.Errorf("unexpected envelope: success %v, message %q", streamed.Success, streamed.Message)
+	}
+
+	got, _ := json.Marshal(streamed.Data)
+	want, _ := json.Marshal(result)
+	if !bytes.Equal(got, want) {
+		t.Error("streamed result differs from its JSON encoding")
+	}
+}
+
+// BenchmarkStreamResult writes a large result the way result endpoints do
+func BenchmarkStreamResult(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	result := largeResult()
+	b.SetBytes(int64(len(result.RawText) + len(result.MarkdownText)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		c := newResultContext(&discardResponseWriter{header: http.Header{}})
+		streamResult(c, result, "Result retrieved successfully")
+	}
+}
+
+// BenchmarkMarshalResult writes the same result with c.JSON, which marshals the
+// whole response into memory first, for comparison
+func BenchmarkMarshalResult(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	result := largeResult()
+	b.SetBytes(int64(len(result.RawText) + len(result.MarkdownText)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		c := newResultContext(&discardResponseWriter{header: http.Header{}})
+		c.JSON(http.StatusOK, models.NewSuccessResponse(result, "Result retrieved successfully"))
+	}
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandler handles the authenticated user's preferences
+type SettingsHandler struct {
+	settingsRepo      *repository.UserSettingsRepository
+	retentionService  *services.RetentionService
+	emailNotification *services.EmailNotificationService
+	auditService      *services.AuditService
+	validator         *validator.Validator
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(
+	settingsRepo *repository.UserSettingsRepository,
+	retentionService *services.RetentionService,
+	emailNotification *services.EmailNotificationService,
+	auditService *services.AuditService,
+) *SettingsHandler {
+	return &SettingsHandler{
+		settingsRepo:      settingsRepo,
+		retentionService:  retentionService,
+		emailNotification: emailNotification,
+		auditService:      auditService,
+		validator:         validator.New(),
+	}
+}
+
+// Get returns the user's settings
+func (h *SettingsHandler) Get(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	settings, err := h.settingsRepo.GetByUserID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_015",
+			"Failed to get settings",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		settings,
+		"Settings retrieved successfully",
+	))
+}
+
+// Update changes the settings present in the request and leaves the others alone
+func (h *SettingsHandler) Update(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Time zones must be IANA names such as "Europe/Berlin"
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_020",
+				"Invalid timezone",
+				[]models.ValidationError{{Field: "timezone", Message: "must be an IANA time zone name such as Europe/Berlin"}},
+			))
+			return
+		}
+	}
+
+	settings, err := h.settingsRepo.GetByUserID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_015",
+			"Failed to get settings",
+			nil,
+		))
+		return
+	}
+
+	// Apply the changes, remembering which fields changed for the audit log
+	changed := []string{}
+	if req.DefaultOCRMode != nil && *req.DefaultOCRMode != settings.DefaultOCRMode {
+		settings.DefaultOCRMode = *req.DefaultOCRMode
+		changed = append(changed, "default_ocr_mode")
+	}
+	if req.DefaultResolution != nil && *req.DefaultResolution != settings.DefaultResolution {
+		settings.DefaultResolution = *req.DefaultResolution
+		changed = append(changed, "default_resolution")
+	}
+	if req.EmailNotifications != nil && *req.EmailNotifications != settings.EmailNotifications {
+		settings.EmailNotifications = *req.EmailNotifications
+		changed = append(changed, "email_notifications")
+	}
+	if req.Language != nil && *req.Language != settings.Language {
+		settings.Language = *req.Language
+		changed = append(changed, "language")
+	}
+	if req.Theme != nil && *req.Theme != settings.Theme {
+		settings.Theme = *req.Theme
+		changed = append(changed, "theme")
+	}
+	if req.DuplicatePolicy != nil && *req.DuplicatePolicy != settings.DuplicatePolicy {
+		settings.DuplicatePolicy = *req.DuplicatePolicy
+		changed = append(changed, "duplicate_policy")
+	}
+	if req.Timezone != nil && *req.Timezone != settings.Timezone {
+		settings.Timezone = *req.Timezone
+		changed = append(changed, "timezone")
+	}
+	if req.JobTrashRetentionDays != nil && *req.JobTrashRetentionDays != settings.JobTrashRetentionDays {
+		settings.JobTrashRetentionDays = *req.JobTrashRetentionDays
+		changed = append(changed, "job_trash_retention_days")
+	}
+	if req.ArchiveJobsAfterDays != nil && *req.ArchiveJobsAfterDays != settings.ArchiveJobsAfterDays {
+		settings.ArchiveJobsAfterDays = *req.ArchiveJobsAfterDays
+		changed = append(changed, "archive_jobs_after_days")
+	}
+	if req.EmailOnFailure != nil && *req.EmailOnFailure != settings.EmailOnFailure {
+		settings.EmailOnFailure = *req.EmailOnFailure
+		changed = append(changed, "email_on_failure")
+	}
+	if req.EmailDigest != nil && *req.EmailDigest != settings.EmailDigest {
+		settings.EmailDigest = *req.EmailDigest
+		changed = append(changed, "email_digest")
+	}
+	if req.DigestHour != nil && *req.DigestHour != settings.DigestHour {
+		settings.DigestHour = *req.DigestHour
+		changed = append(changed, "digest_hour")
+	}
+
+	if err := h.settingsRepo.Upsert(c.Request.Context(), settings); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_016",
+			"Failed to update settings",
+			nil,
+		))
+		return
+	}
+
+	// The next digest is due at another time once any of these changed
+	if slices.ContainsFunc(changed, func(field string) bool {
+		return field == "email_notifications" || field == "email_digest" || field == "digest_hour" || field == "timezone"
+	}) {
+		if err := h.emailNotification.ScheduleDigest(c.Request.Context(), settings); err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+				"SYS_016",
+				"Failed to update settings",
+				nil,
+			))
+			return
+		}
+	}
+
+	if len(changed) > 0 {
+		h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditSettingsUpdated, "user", &userID, map[string]any{
+			"fields": changed,
+		}))
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		settings,
+		"Settings updated successfully",
+	))
+}
+
+// GetRetention returns the user's retention policy for every target
+func (h *SettingsHandler) GetRetention(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	policies, err := h.retentionService.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_132", "Failed to get retention policies")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		policies,
+		"Retention policies retrieved successfully",
+	))
+}
+
+// UpdateRetention sets the retention policies present in the request
+func (h *SettingsHandler) UpdateRetention(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Parse request
+	var req models.UpdateRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	policies, err := h.retentionService.Update(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_133", "Failed to update retention policies")
+		return
+	}
+
+	changes := map[string]int{}
+	for _, policy := range req.Policies {
+		changes[string(policy.Target)] = policy.Days
+	}
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditSettingsUpdated, "user", &userID, map[string]any{
+		"fields":    []string{"retention"},
+		"retention": changes,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		policies,
+		"Retention policies updated successfully",
+	))
+}
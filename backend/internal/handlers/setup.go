@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupHandler handles the first-run setup wizard
+type SetupHandler struct {
+	setupService *services.SetupService
+	authService  *services.AuthService
+	auditService *services.AuditService
+	cache        middleware.ResponseCache
+	validator    *validator.Validator
+}
+
+// NewSetupHandler creates a new setup handler. The cached setup status is
+// invalidated once setup completes; cache may be nil.
+func NewSetupHandler(setupService *services.SetupService, authService *services.AuthService, auditService *services.AuditService, cache middleware.ResponseCache) *SetupHandler {
+	return &SetupHandler{
+		setupService: setupService,
+		authService:  authService,
+		auditService: auditService,
+		cache:        cache,
+		validator:    validator.New(),
+	}
+}
+
+// GetStatus handles checking whether the instance still needs to be set up
+func (h *SetupHandler) GetStatus(c *gin.Context) {
+	status, err := h.setupService.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_011",
+			"Failed to get setup status",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		status,
+		"Setup status retrieved successfully",
+	))
+}
+
+// Complete handles the one-time creation of the initial admin account and instance settings
+func (h *SetupHandler) Complete(c *gin.Context) {
+	var req models.SetupRequest
+
+	// Bind JSON request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Validate password strength
+	if err := validator.ValidatePassword(req.AdminPassword, validator.DefaultPasswordStrength()); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_002",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	// Run setup
+	admin, err := h.setupService.Complete(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err, "SYS_012", "Failed to complete setup")
+		return
+	}
+
+	invalidateCache(c, h.cache, CacheSetupStatus)
+
+	event := newAuditEvent(c, models.AuditSetupCompleted, "user", &admin.ID, map[string]any{
+		"instance_name":   req.InstanceName,
+		"storage_backend": req.StorageBackend,
+		"ocr_service_url": req.OCRServiceURL,
+	})
+	event.ActorID = &admin.ID
+	event.ActorEmail = admin.Email
+	h.auditService.Record(c.Request.Context(), event)
+
+	// Generate tokens for the new admin
+	authResponse, err := h.authService.Login(c.Request.Context(), models.UserLogin{
+		Email:    req.AdminEmail,
+		Password: req.AdminPassword,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_001",
+			"Failed to generate tokens",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		authResponse,
+		"Setup completed successfully",
+	))
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ShareLinkHandler handles share links: signed, expiring URLs giving anyone
+// read-only access to a document without signing in
+type ShareLinkHandler struct {
+	shareLinkService *services.ShareLinkService
+	auditService     *services.AuditService
+	validator        *validator.Validator
+}
+
+// NewShareLinkHandler creates a new share link handler
+func NewShareLinkHandler(shareLinkService *services.ShareLinkService, auditService *services.AuditService) *ShareLinkHandler {
+	return &ShareLinkHandler{
+		shareLinkService: shareLinkService,
+		auditService:     auditService,
+		validator:        validator.New(),
+	}
+}
+
+// Create shares one of the user's documents through a new link
+func (h *ShareLinkHandler) Create(c *gin.Context) {
+	userID, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	// Parse request; the body is optional
+	var req models.ShareLinkCreateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				"Invalid request body",
+				nil,
+			))
+			return
+		}
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	link, err := h.shareLinkService.Create(c.Request.Context(), userID, documentID, req, requestOrigin(c))
+	if err != nil {
+		respondError(c, err, "SYS_068", "Failed to share document")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditShareCreated, "share_link", &link.ID, map[string]any{
+		"document_id": documentID,
+		"expires_at":  link.ExpiresAt,
+	}))
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		link,
+		"Share link created successfully",
+	))
+}
+
+// List returns the share links of one of the user's documents
+func (h *ShareLinkHandler) List(c *gin.Context) {
+	userID, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.shareLinkService.List(c.Request.Context(), userID, documentID)
+	if err != nil {
+		respondError(c, err, "SYS_069", "Failed to list share links")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		links,
+		"Share links retrieved successfully",
+	))
+}
+
+// Revoke ends a share link before it expires
+func (h *ShareLinkHandler) Revoke(c *gin.Context) {
+	userID, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	linkID, ok := parseIDParam(c, "link_id", "VAL_035", "Invalid share link ID")
+	if !ok {
+		return
+	}
+
+	if err := h.shareLinkService.Revoke(c.Request.Context(), userID, documentID, linkID); err != nil {
+		respondError(c, err, "SYS_070", "Failed to revoke share link")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditShareRevoked, "share_link", &linkID, map[string]any{
+		"document_id": documentID,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Share link revoked successfully",
+	))
+}
+
+// Get returns the document and latest result behind a share link. It needs no
+// authentication: the expires and signature query parameters are the credential.
+func (h *ShareLinkHandler) Get(c *gin.Context) {
+	linkID, ok := parseIDParam(c, "id", "VAL_035", "Invalid share link ID")
+	if !ok {
+		return
+	}
+
+	shared, err := h.shareLinkService.Open(c.Request.Context(), linkID, c.Query("expires"), c.Query("signature"), c.Request.Host)
+	if err != nil {
+		respondError(c, err, "SYS_071", "Failed to open share link")
+		return
+	}
+
+	metadata := map[string]any{"document_id": shared.ID}
+	if shared.Result != nil {
+		metadata["result_id"] = shared.Result.ID
+	}
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditShareAccessed, "share_link", &linkID, metadata))
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		shared,
+		"Shared document retrieved successfully",
+	))
+}
+
+// GetFile downloads the original file of the document behind a share link
+func (h *ShareLinkHandler) GetFile(c *gin.Context) {
+	linkID, ok := parseIDParam(c, "id", "VAL_035", "Invalid share link ID")
+	if !ok {
+		return
+	}
+
+	document, file, err := h.shareLinkService.OpenFile(c.Request.Context(), linkID, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		respondError(c, err, "SYS_071", "Failed to open share link")
+		return
+	}
+	defer file.Close()
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditShareAccessed, "share_link", &linkID, map[string]any{
+		"document_id": document.ID,
+		"resource":    "file",
+	}))
+
+	c.Header("Cache-Control", "no-store")
+	setAttachment(c, document.OriginalFilename)
+	c.DataFromReader(http.StatusOK, document.FileSize, document.MimeType, file, nil)
+}
+
+// documentRequest returns the authenticated user and the document named in the path
+func (h *ShareLinkHandler) documentRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	documentID, ok := parseIDParam(c, "id", "VAL_007", "Invalid document ID")
+	return userID, documentID, ok
+}
+
+// requestOrigin returns the scheme and host a request was made to, trusting
+// X-Forwarded-Proto from the reverse proxy in front of the server
+func requestOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
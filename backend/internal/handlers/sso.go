@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ssoStateCookie binds a sign-in to the browser that started it, so that a
+// callback cannot sign someone else's browser in
+const ssoStateCookie = "sso_state"
+
+// SSOHandler handles single sign-on through organizations' identity
+// providers and the owners' configuration of it
+type SSOHandler struct {
+	ssoService   *services.SSOService
+	auditService *services.AuditService
+	validator    *validator.Validator
+	successURL   string
+}
+
+// NewSSOHandler creates a new SSO handler. Signed-in users are redirected to
+// successURL with their tokens in its fragment, or get them as JSON when it
+// is empty.
+func NewSSOHandler(ssoService *services.SSOService, auditService *services.AuditService, successURL string) *SSOHandler {
+	return &SSOHandler{
+		ssoService:   ssoService,
+		auditService: auditService,
+		validator:    validator.New(),
+		successURL:   successURL,
+	}
+}
+
+// Login sends the user to their organization's IdP, found by ?org_id= or by
+// the domain of ?email=
+func (h *SSOHandler) Login(c *gin.Context) {
+	var orgID uuid.UUID
+	if raw := c.Query("org_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_029",
+				"Invalid organization ID",
+				nil,
+			))
+			return
+		}
+		orgID = parsed
+	} else if !strings.Contains(c.Query("email"), "@") {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_039",
+			"org_id or email is required",
+			nil,
+		))
+		return
+	}
+
+	// The state cookie and the callback belong to the API version the
+	// sign-in started from
+	origin, basePath := requestOrigin(c), path.Dir(c.FullPath())
+	login, state, err := h.ssoService.Begin(c.Request.Context(), orgID, c.Query("email"), origin+basePath+"/callback")
+	if err != nil {
+		respondError(c, err, "SYS_075", "Failed to start single sign-on")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, state, 600, basePath, "", strings.HasPrefix(origin, "https://"), true)
+
+	if c.Query("redirect") == "false" {
+		c.JSON(http.StatusOK, models.NewSuccessResponse(
+			login,
+			"Single sign-on started successfully",
+		))
+		return
+	}
+	c.Redirect(http.StatusFound, login.AuthorizationURL)
+}
+
+// Callback signs the user in with the code their IdP redirected back with
+func (h *SSOHandler) Callback(c *gin.Context) {
+	if idpError := c.Query("error"); idpError != "" {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"SSO_002",
+			"Identity provider refused the sign-in: "+idpError,
+			nil,
+		))
+		return
+	}
+
+	state := c.Query("state")
+	cookie, err := c.Cookie(ssoStateCookie)
+	if err != nil || state == "" || cookie != state || c.Query("code") == "" {
+		c.JSON(http.StatusForbidden, models.NewErrorResponse(
+			"SSO_002",
+			services.ErrSSOSignInInvalid.Message,
+			nil,
+		))
+		return
+	}
+	c.SetCookie(ssoStateCookie, "", -1, path.Dir(c.FullPath()), "", strings.HasPrefix(requestOrigin(c), "https://"), true)
+
+	authResponse, orgID, err := h.ssoService.Complete(c.Request.Context(), c.Query("code"), state)
+	if err != nil {
+		respondError(c, err, "SYS_076", "Failed to complete single sign-on")
+		return
+	}
+
+	event := newAuditEvent(c, models.AuditLogin, "user", &authResponse.User.ID, map[string]any{
+		"method":          "sso",
+		"organization_id": orgID,
+	})
+	event.ActorID = &authResponse.User.ID
+	event.ActorEmail = authResponse.User.Email
+	h.auditService.Record(c.Request.Context(), event)
+
+	if h.successURL != "" {
+		fragment := url.Values{}
+		fragment.Set("access_token", authResponse.AccessToken)
+		fragment.Set("refresh_token", authResponse.RefreshToken)
+		fragment.Set("expires_in", strconv.FormatInt(authResponse.ExpiresIn, 10))
+		c.Redirect(http.StatusFound, h.successURL+"#"+fragment.Encode())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		authResponse,
+		"Login successful",
+	))
+}
+
+// GetConfig returns the SSO configuration of an organization the user owns
+func (h *SSOHandler) GetConfig(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	config, err := h.ssoService.GetConfig(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_077", "Failed to get SSO configuration")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		config,
+		"SSO configuration retrieved successfully",
+	))
+}
+
+// Configure creates or replaces the SSO configuration of an organization the
+// user owns
+func (h *SSOHandler) Configure(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	// Parse request
+	var req models.OrgSSORequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	config, err := h.ssoService.Configure(c.Request.Context(), orgID, userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_078", "Failed to configure SSO")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgSSOUpdated, "organization", &orgID, map[string]any{
+		"issuer":  config.Issuer,
+		"enabled": config.Enabled,
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		config,
+		"SSO configured successfully",
+	))
+}
+
+// VerifyDomain checks the DNS verification record of an email domain of the
+// SSO configuration of an organization the user owns
+func (h *SSOHandler) VerifyDomain(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	config, err := h.ssoService.VerifyDomain(c.Request.Context(), orgID, userID, c.Param("domain"))
+	if err != nil {
+		if errors.Is(err, services.ErrDomainNotVerified) {
+			c.JSON(http.StatusUnprocessableEntity, models.NewErrorResponse(
+				"SSO_009",
+				err.Error(),
+				nil,
+			))
+			return
+		}
+		respondError(c, err, "SYS_137", "Failed to verify SSO domain")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgSSODomainVerified, "organization", &orgID, map[string]any{
+		"domain": strings.ToLower(c.Param("domain")),
+	}))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		config,
+		"SSO domain verified successfully",
+	))
+}
+
+// DeleteConfig removes the SSO configuration of an organization the user owns
+func (h *SSOHandler) DeleteConfig(c *gin.Context) {
+	userID, orgID, ok := h.orgRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.ssoService.DeleteConfig(c.Request.Context(), orgID, userID); err != nil {
+		respondError(c, err, "SYS_079", "Failed to delete SSO configuration")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditOrgSSODeleted, "organization", &orgID, nil))
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"SSO configuration deleted successfully",
+	))
+}
+
+// orgRequest returns the authenticated user and the organization named in the path
+func (h *SSOHandler) orgRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	orgID, ok := parseIDParam(c, "id", "VAL_029", "Invalid organization ID")
+	return userID, orgID, ok
+}
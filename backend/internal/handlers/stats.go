@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatsPeriod is the longest period job statistics cover, keeping the
+// daily series at most a year long
+const maxStatsPeriod = 366 * 24 * time.Hour
+
+// StatsHandler handles the statistics of the authenticated user's jobs
+type StatsHandler struct {
+	statsService *services.StatsService
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(statsService *services.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: statsService}
+}
+
+// Get returns the statistics of the jobs that finished between the since and
+// until query parameters, the last 30 days by default
+func (h *StatsHandler) Get(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	until := time.Now().UTC()
+	since := until.AddDate(0, 0, -30)
+	for param, target := range map[string]*time.Time{"since": &since, "until": &until} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+					"VAL_001",
+					param+" must be an RFC3339 timestamp",
+					nil,
+				))
+				return
+			}
+			*target = t
+		}
+	}
+	if !since.Before(until) {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"since must be before until",
+			nil,
+		))
+		return
+	}
+	if until.Sub(since) > maxStatsPeriod {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"since and until may be at most 366 days apart",
+			nil,
+		))
+		return
+	}
+
+	stats, err := h.statsService.JobStats(c.Request.Context(), userID, since, until)
+	if err != nil {
+		respondError(c, err, "SYS_134", "Failed to get job statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		stats,
+		"Job statistics retrieved successfully",
+	))
+}
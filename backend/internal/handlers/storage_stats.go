@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StorageStatsHandler handles the admin storage dashboard
+type StorageStatsHandler struct {
+	statsService *services.StorageStatsService
+}
+
+// NewStorageStatsHandler creates a new storage stats handler
+func NewStorageStatsHandler(statsService *services.StorageStatsService) *StorageStatsHandler {
+	return &StorageStatsHandler{statsService: statsService}
+}
+
+// Get returns the storage used by the instance, as last computed, with a page
+// of the users using the most
+func (h *StorageStatsHandler) Get(c *gin.Context) {
+	page, perPage, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	stats, users, total, err := h.statsService.Get(c.Request.Context(), page, perPage)
+	if err != nil {
+		respondError(c, err, "SYS_125", "Failed to get storage statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		models.StorageStatsResponse{
+			StorageStats: *stats,
+			Users:        paginated(users, page, perPage, total),
+		},
+		"Storage statistics retrieved successfully",
+	))
+}
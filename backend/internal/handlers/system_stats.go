@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSystemStatsWindow is the longest window of the admin dashboard, as long
+// as instances keep counting their requests
+const maxSystemStatsWindow = 24 * time.Hour
+
+// SystemStatsHandler handles the admin dashboard's instance statistics
+type SystemStatsHandler struct {
+	statsService *services.SystemStatsService
+}
+
+// NewSystemStatsHandler creates a new system stats handler
+func NewSystemStatsHandler(statsService *services.SystemStatsService) *SystemStatsHandler {
+	return &SystemStatsHandler{statsService: statsService}
+}
+
+// Get returns the statistics of the last ?window= (a duration such as 15m,
+// default 1h, at most 24h)
+func (h *SystemStatsHandler) Get(c *gin.Context) {
+	window := time.Hour
+	if raw := c.Query("window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < time.Minute || d > maxSystemStatsWindow {
+			c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+				"VAL_001",
+				"window must be a duration between 1m and 24h",
+				nil,
+			))
+			return
+		}
+		window = d
+	}
+
+	stats, err := h.statsService.Get(c.Request.Context(), window)
+	if err != nil {
+		respondError(c, err, "SYS_135", "Failed to get system statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		stats,
+		"System statistics retrieved successfully",
+	))
+}
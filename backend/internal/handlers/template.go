@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/middleware"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TemplateHandler handles extraction templates: users' rules for pulling named
+// fields out of the results of a type of document
+type TemplateHandler struct {
+	templateService *services.TemplateService
+	validator       *validator.Validator
+}
+
+// NewTemplateHandler creates a new template handler
+func NewTemplateHandler(templateService *services.TemplateService) *TemplateHandler {
+	return &TemplateHandler{
+		templateService: templateService,
+		validator:       validator.New(),
+	}
+}
+
+// Create adds an extraction template
+func (h *TemplateHandler) Create(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	req, ok := h.bindRequest(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.templateService.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_092", "Failed to create extraction template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		template,
+		"Extraction template created successfully",
+	))
+}
+
+// List returns the user's extraction templates
+func (h *TemplateHandler) List(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	templates, err := h.templateService.List(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "SYS_093", "Failed to list extraction templates")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		templates,
+		"Extraction templates retrieved successfully",
+	))
+}
+
+// Get returns one of the user's extraction templates
+func (h *TemplateHandler) Get(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	templateID, ok := parseIDParam(c, "id", "VAL_049", "Invalid extraction template ID")
+	if !ok {
+		return
+	}
+
+	template, err := h.templateService.Get(c.Request.Context(), templateID, userID)
+	if err != nil {
+		respondError(c, err, "SYS_094", "Failed to get extraction template")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		template,
+		"Extraction template retrieved successfully",
+	))
+}
+
+// Update replaces one of the user's extraction templates
+func (h *TemplateHandler) Update(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	templateID, ok := parseIDParam(c, "id", "VAL_049", "Invalid extraction template ID")
+	if !ok {
+		return
+	}
+
+	req, ok := h.bindRequest(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.templateService.Update(c.Request.Context(), templateID, userID, req)
+	if err != nil {
+		respondError(c, err, "SYS_095", "Failed to update extraction template")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		template,
+		"Extraction template updated successfully",
+	))
+}
+
+// Delete deletes one of the user's extraction templates
+func (h *TemplateHandler) Delete(c *gin.Context) {
+	// Get authenticated user
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+			"AUTH_003",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	templateID, ok := parseIDParam(c, "id", "VAL_049", "Invalid extraction template ID")
+	if !ok {
+		return
+	}
+
+	if err := h.templateService.Delete(c.Request.Context(), templateID, userID); err != nil {
+		respondError(c, err, "SYS_096", "Failed to delete extraction template")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		nil,
+		"Extraction template deleted successfully",
+	))
+}
+
+// bindRequest parses and validates the body of a request creating or
+// replacing a template
+func (h *TemplateHandler) bindRequest(c *gin.Context) (models.TemplateRequest, bool) {
+	var req models.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return req, false
+	}
+
+	if err := h.validator.Validate(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return req, false
+	}
+
+	return req, true
+}
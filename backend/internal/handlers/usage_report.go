@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageReportHandler handles admin requests for the monthly usage reports
+type UsageReportHandler struct {
+	reportService *services.UsageReportService
+	auditService  *services.AuditService
+	validator     *validator.Validator
+}
+
+// NewUsageReportHandler creates a new usage report handler
+func NewUsageReportHandler(reportService *services.UsageReportService, auditService *services.AuditService) *UsageReportHandler {
+	return &UsageReportHandler{
+		reportService: reportService,
+		auditService:  auditService,
+		validator:     validator.New(),
+	}
+}
+
+// List returns the usage reports, most recent month first
+func (h *UsageReportHandler) List(c *gin.Context) {
+	page, perPage, ok := pageParams(c, 20)
+	if !ok {
+		return
+	}
+
+	reports, total, err := h.reportService.List(c.Request.Context(), page, perPage)
+	if err != nil {
+		respondError(c, err, "SYS_072", "Failed to list usage reports")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		paginated(reports, page, perPage, total),
+		"Usage reports retrieved successfully",
+	))
+}
+
+// Get returns a usage report with its breakdown by organization
+func (h *UsageReportHandler) Get(c *gin.Context) {
+	reportID, ok := parseIDParam(c, "id", "VAL_037", "Invalid report ID")
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.Get(c.Request.Context(), reportID)
+	if err != nil {
+		respondError(c, err, "SYS_073", "Failed to get usage report")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		report,
+		"Usage report retrieved successfully",
+	))
+}
+
+// Download returns a usage report as a file (?format=json or csv)
+func (h *UsageReportHandler) Download(c *gin.Context) {
+	reportID, ok := parseIDParam(c, "id", "VAL_037", "Invalid report ID")
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_011",
+			fmt.Sprintf("Unsupported export format: %s", format),
+			nil,
+		))
+		return
+	}
+
+	report, err := h.reportService.Get(c.Request.Context(), reportID)
+	if err != nil {
+		respondError(c, err, "SYS_073", "Failed to get usage report")
+		return
+	}
+
+	baseName := "usage-" + report.PeriodStart.Format("2006-01")
+	if format == "json" {
+		setAttachment(c, baseName+".json")
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.reportService.WriteCSV(report, &buf); err != nil {
+		logger.Error("Failed to write usage report", "report_id", reportID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_073",
+			"Failed to get usage report",
+			nil,
+		))
+		return
+	}
+
+	setAttachment(c, baseName+".csv")
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// Generate computes the report of a month that is over, replacing the
+// existing report of that month
+func (h *UsageReportHandler) Generate(c *gin.Context) {
+	// Parse request
+	var req models.UsageReportGenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			"Invalid request body",
+			nil,
+		))
+		return
+	}
+
+	if err := h.validator.Validate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			"VAL_001",
+			err.Error(),
+			nil,
+		))
+		return
+	}
+
+	month, _ := time.Parse("2006-01", req.Month)
+	report, err := h.reportService.Generate(c.Request.Context(), month)
+	if err != nil {
+		respondError(c, err, "SYS_074", "Failed to generate usage report")
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), newAuditEvent(c, models.AuditReportGenerated, "usage_report", &report.ID, map[string]any{
+		"month": req.Month,
+	}))
+
+	c.JSON(http.StatusCreated, models.NewSuccessResponse(
+		report,
+		"Usage report generated successfully",
+	))
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WarehouseExportHandler handles admin requests for the data warehouse export
+type WarehouseExportHandler struct {
+	exportService *services.WarehouseExportService
+	enabled       bool
+	format        string
+	destination   string
+}
+
+// NewWarehouseExportHandler creates a new warehouse export handler
+func NewWarehouseExportHandler(exportService *services.WarehouseExportService, enabled bool, format, destination string) *WarehouseExportHandler {
+	return &WarehouseExportHandler{
+		exportService: exportService,
+		enabled:       enabled,
+		format:        format,
+		destination:   destination,
+	}
+}
+
+// Status returns the export cursor and configuration
+func (h *WarehouseExportHandler) Status(c *gin.Context) {
+	cursor, err := h.exportService.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_007",
+			"Failed to get export status",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		gin.H{
+			"enabled":     h.enabled,
+			"format":      h.format,
+			"destination": h.destination,
+			"cursor":      cursor,
+		},
+		"Export status retrieved successfully",
+	))
+}
+
+// Run triggers an export immediately
+func (h *WarehouseExportHandler) Run(c *gin.Context) {
+	run, err := h.exportService.Run(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"SYS_008",
+			"Failed to run export",
+			nil,
+		))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccessResponse(
+		run,
+		"Export completed successfully",
+	))
+}
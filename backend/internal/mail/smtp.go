@@ -0,0 +1,140 @@
+// Package mail sends plain text email through an SMTP server, rendering the
+// emails sent to users from templates
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Config holds the SMTP server mail is sent through
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+	// InReplyTo threads the message under the one with this Message-ID
+	InReplyTo string
+}
+
+// Sender sends mail through an SMTP server. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when the server offers it.
+type Sender struct {
+	smtp    Config
+	timeout time.Duration
+}
+
+// NewSender creates a new SMTP sender
+func NewSender(cfg Config, timeout time.Duration) *Sender {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Sender{smtp: cfg, timeout: timeout}
+}
+
+// Configured reports whether the sender has a server and a sender address
+func (s *Sender) Configured() bool {
+	return s.smtp.Host != "" && s.smtp.From != ""
+}
+
+// Send sends a message to all of its recipients
+func (s *Sender) Send(ctx context.Context, msg Message) error {
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	addr := net.JoinHostPort(s.smtp.Host, fmt.Sprint(s.smtp.Port))
+	dialer := &net.Dialer{Deadline: deadline}
+	tlsConfig := &tls.Config{ServerName: s.smtp.Host}
+
+	var conn net.Conn
+	var err error
+	if s.smtp.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.smtp.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && s.smtp.Port != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if s.smtp.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.smtp.Username, s.smtp.Password, s.smtp.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.smtp.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(s.message(msg)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// message builds the plain text email
+func (s *Sender) message(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.smtp.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	if msg.InReplyTo != "" {
+		fmt.Fprintf(&b, "In-Reply-To: %s\r\n", headerValue(msg.InReplyTo))
+		fmt.Fprintf(&b, "References: %s\r\n", headerValue(msg.InReplyTo))
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// headerValue keeps a value on one header line, so it cannot add headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
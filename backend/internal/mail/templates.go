@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.txt
+var templateFiles embed.FS
+
+// templates are the emails sent to users, named by their file. The first
+// line a template renders is the subject, the rest the body.
+var templates = template.Must(template.ParseFS(templateFiles, "templates/*.txt"))
+
+// Render renders the named template into a message to the recipients
+func Render(name string, data any, to ...string) (Message, error) {
+	var out bytes.Buffer
+	if err := templates.ExecuteTemplate(&out, name+".txt", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s email: %w", name, err)
+	}
+
+	subject, body, _ := strings.Cut(out.String(), "\n")
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject),
+		Body:    strings.TrimLeft(body, "\n"),
+	}, nil
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"visekai/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyReadAheadLimit is the largest limit up to which bodies of unknown
+// length are read before the handler runs, so they can be rejected whole
+const bodyReadAheadLimit = 1 << 20
+
+// BodyLimit limits request bodies to limit bytes, or to the limit of their
+// route in routes, keyed by full path, for the few routes taking large
+// bodies such as uploads. A body declaring a larger Content-Length is
+// rejected with 413 before it is read. A body of unknown length, sent
+// chunked, is read ahead when its limit is small and rejected the same way;
+// otherwise reading it past the limit fails with *http.MaxBytesError.
+func BodyLimit(limit int64, routes map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		maxBytes := limit
+		if routeLimit, ok := routes[c.FullPath()]; ok {
+			maxBytes = routeLimit
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			abortBodyTooLarge(c, maxBytes)
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		if c.Request.ContentLength < 0 && maxBytes <= bodyReadAheadLimit {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					abortBodyTooLarge(c, maxBytes)
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusBadRequest, models.NewErrorResponse(
+					"VAL_001",
+					"Invalid request body",
+					nil,
+				))
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			c.Request.ContentLength = int64(len(body))
+		}
+
+		c.Next()
+	}
+}
+
+// abortBodyTooLarge answers 413, closing the connection as the rest of the
+// body will not be read
+func abortBodyTooLarge(c *gin.Context, limit int64) {
+	c.Header("Connection", "close")
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, models.NewErrorResponse(
+		"VAL_060",
+		fmt.Sprintf("Request body exceeds the limit of %d bytes", limit),
+		nil,
+	))
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMemoryCacheEntries bounds the entries of a MemoryResponseCache
+const maxMemoryCacheEntries = 1000
+
+var responseCacheLookups = metrics.NewCounterVec(
+	"visekai_response_cache_lookups_total",
+	"Response cache lookups, by cache name and result (hit or miss).",
+	"cache", "result",
+)
+
+// CachedResponse is a response body stored in a ResponseCache
+type CachedResponse struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// ResponseCache stores responses under a cache name and key. Invalidate drops
+// every response stored under a name, e.g. after the data behind it changed.
+type ResponseCache interface {
+	Get(ctx context.Context, name, key string) (*CachedResponse, bool, error)
+	Set(ctx context.Context, name, key string, resp *CachedResponse) error
+	Invalidate(ctx context.Context, name string) error
+}
+
+// CacheResponse serves successful GET responses from the cache, which makes it
+// suitable only for responses that are the same for every caller allowed to make
+// the request. Responses are keyed by path; the query string is ignored so it
+// cannot be used to fill the cache. The Cache-Control header is set whether or
+// not a cache is configured. A nil cache disables server-side caching, and cache
+// failures fall back to running the handler.
+func CacheResponse(cache ResponseCache, name, cacheControl string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cacheControl != "" {
+			c.Header("Cache-Control", cacheControl)
+		}
+		if cache == nil || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		key := c.Request.URL.Path
+		cached, ok, err := cache.Get(c.Request.Context(), name, key)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Warn("Response cache unavailable", "cache", name, "error", err)
+		}
+		if ok {
+			responseCacheLookups.Inc(name, "hit")
+			c.Header("X-Cache", "HIT")
+			c.Data(http.StatusOK, cached.ContentType, cached.Body)
+			c.Abort()
+			return
+		}
+		responseCacheLookups.Inc(name, "miss")
+
+		writer := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Header("X-Cache", "MISS")
+		c.Next()
+
+		if writer.Status() != http.StatusOK || err != nil {
+			return
+		}
+		resp := &CachedResponse{
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		}
+		if err := cache.Set(c.Request.Context(), name, key, resp); err != nil {
+			logger.FromContext(c.Request.Context()).Warn("Failed to store cached response", "cache", name, "error", err)
+		}
+	}
+}
+
+// capturingWriter keeps a copy of the response body
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// MemoryResponseCache is a ResponseCache local to one API instance. Invalidation
+// does not reach other instances, whose entries expire after the TTL instead.
+type MemoryResponseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	resp    *CachedResponse
+	expires time.Time
+}
+
+// NewMemoryResponseCache creates an in-process cache keeping responses for ttl
+func NewMemoryResponseCache(ttl time.Duration) *MemoryResponseCache {
+	return &MemoryResponseCache{
+		ttl:     ttl,
+		entries: make(map[string]memoryCacheEntry),
+	}
+}
+
+// Get implements ResponseCache
+func (m *MemoryResponseCache) Get(ctx context.Context, name, key string) (*CachedResponse, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[name+"\x00"+key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false, nil
+	}
+	return entry.resp, true, nil
+}
+
+// Set implements ResponseCache
+func (m *MemoryResponseCache) Set(ctx context.Context, name, key string, resp *CachedResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if len(m.entries) >= maxMemoryCacheEntries {
+		for k, entry := range m.entries {
+			if now.After(entry.expires) {
+				delete(m.entries, k)
+			}
+		}
+		if len(m.entries) >= maxMemoryCacheEntries {
+			clear(m.entries)
+		}
+	}
+
+	m.entries[name+"\x00"+key] = memoryCacheEntry{resp: resp, expires: now.Add(m.ttl)}
+	return nil
+}
+
+// Invalidate implements ResponseCache
+func (m *MemoryResponseCache) Invalidate(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.entries {
+		if strings.HasPrefix(k, name+"\x00") {
+			delete(m.entries, k)
+		}
+	}
+	return nil
+}
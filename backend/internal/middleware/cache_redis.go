@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"visekai/backend/pkg/redis"
+)
+
+// Each cache name has a generation counter in Redis and entries are stored under
+// the current generation, so invalidating a name is a single INCR; entries of
+// older generations are never read again and expire on their own.
+const (
+	cacheGetScript = `
+local gen = redis.call('GET', KEYS[1]) or '0'
+return redis.call('GET', KEYS[1] .. ':' .. gen .. ':' .. ARGV[1])
+`
+	cacheSetScript = `
+local gen = redis.call('GET', KEYS[1]) or '0'
+redis.call('SET', KEYS[1] .. ':' .. gen .. ':' .. ARGV[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`
+)
+
+// RedisResponseCache is a ResponseCache shared by all API instances through Redis
+type RedisResponseCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisResponseCache creates a shared cache keeping responses for ttl
+func NewRedisResponseCache(client *redis.Client, ttl time.Duration) *RedisResponseCache {
+	return &RedisResponseCache{client: client, ttl: ttl}
+}
+
+// Get implements ResponseCache
+func (r *RedisResponseCache) Get(ctx context.Context, name, key string) (*CachedResponse, bool, error) {
+	reply, err := r.client.Do(ctx, "EVAL", cacheGetScript, 1, r.genKey(name), key)
+	if errors.Is(err, redis.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cached response: %w", err)
+	}
+
+	raw, ok := reply.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected cached response reply: %v", reply)
+	}
+
+	var resp CachedResponse
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cached response: %w", err)
+	}
+	return &resp, true, nil
+}
+
+// Set implements ResponseCache
+func (r *RedisResponseCache) Set(ctx context.Context, name, key string, resp *CachedResponse) error {
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode cached response: %w", err)
+	}
+
+	if _, err := r.client.Do(ctx, "EVAL", cacheSetScript, 1, r.genKey(name), key, payload, r.ttl.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to store cached response: %w", err)
+	}
+	return nil
+}
+
+// Invalidate implements ResponseCache
+func (r *RedisResponseCache) Invalidate(ctx context.Context, name string) error {
+	if _, err := r.client.Do(ctx, "INCR", r.genKey(name)); err != nil {
+		return fmt.Errorf("failed to invalidate cached responses: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisResponseCache) genKey(name string) string {
+	return "visekai:cache:" + name
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitedBuffer keeps the first max bytes written to it and drops the rest,
+// so it can copy a stream of any size
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.max - b.buf.Len(); n > room {
+		b.truncated = true
+		p = p[:max(room, 0)]
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// Bytes returns the bytes kept
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// teeBody copies what the handler reads of a request body to a buffer
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
+// captureRequestBody keeps the start of what handlers read of the request
+// body in the returned buffer
+func captureRequestBody(c *gin.Context, maxBytes int) *limitedBuffer {
+	buf := &limitedBuffer{max: maxBytes}
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		c.Request.Body = teeBody{Reader: io.TeeReader(c.Request.Body, buf), Closer: c.Request.Body}
+	}
+	return buf
+}
+
+// errorBodyWriter keeps the start of error response bodies while writing them
+// through
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	body *limitedBuffer
+}
+
+func newErrorBodyWriter(w gin.ResponseWriter, maxBytes int) *errorBodyWriter {
+	return &errorBodyWriter{ResponseWriter: w, body: &limitedBuffer{max: maxBytes}}
+}
+
+func (w *errorBodyWriter) Write(b []byte) (int, error) {
+	if w.Status() >= http.StatusBadRequest {
+		_, _ = w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *errorBodyWriter) WriteString(s string) (int, error) {
+	if w.Status() >= http.StatusBadRequest {
+		_, _ = w.body.Write([]byte(s))
+	}
+	return w.ResponseWriter.WriteString(s)
+}
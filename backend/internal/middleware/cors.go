@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig configures which cross-origin requests browsers may make
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as "https://app.example.com". An entry
+	// "https://*.example.com" matches any subdomain, and "*" matches any origin
+	// but never allows credentials.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// originPattern is a parsed allowed origin
+type originPattern struct {
+	scheme string
+	host   string // without the leading "*." for wildcard patterns
+	any    bool   // matches every origin
+	sub    bool   // matches subdomains of host
+}
+
+// CORS middleware answers preflight requests and sets CORS headers for allowed origins.
+// Requests from other origins get no CORS headers, so browsers block the response.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	patterns := make([]originPattern, 0, len(cfg.AllowedOrigins))
+	anyOrigin := false
+	for _, origin := range cfg.AllowedOrigins {
+		if pattern, ok := parseOriginPattern(origin); ok {
+			patterns = append(patterns, pattern)
+			anyOrigin = anyOrigin || pattern.any
+		}
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	exposed := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := ""
+	if cfg.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// Responses differ per origin, so caches must key on it
+		c.Writer.Header().Add("Vary", "Origin")
+
+		if origin == "" || !originAllowed(patterns, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		if anyOrigin {
+			// Browsers reject credentials with a wildcard origin, and reflecting
+			// arbitrary origins with credentials would expose users to any site
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials && !anyOrigin {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if exposed != "" {
+			header.Set("Access-Control-Expose-Headers", exposed)
+		}
+
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", methods)
+			header.Set("Access-Control-Allow-Headers", headers)
+			if maxAge != "" {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// parseOriginPattern parses an allowed origin of the form scheme://host[:port],
+// where host may start with "*." to match subdomains
+func parseOriginPattern(origin string) (originPattern, bool) {
+	origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+	if origin == "*" {
+		return originPattern{any: true}, true
+	}
+
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || scheme == "" || host == "" {
+		return originPattern{}, false
+	}
+
+	if rest, ok := strings.CutPrefix(host, "*."); ok {
+		return originPattern{scheme: scheme, host: rest, sub: true}, rest != ""
+	}
+	return originPattern{scheme: scheme, host: host}, true
+}
+
+// originAllowed reports whether an Origin header value matches one of the patterns
+func originAllowed(patterns []originPattern, origin string) bool {
+	scheme, host, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		switch {
+		case pattern.any:
+			return true
+		case pattern.scheme != scheme:
+			continue
+		case pattern.sub:
+			if strings.HasSuffix(host, "."+pattern.host) {
+				return true
+			}
+		case pattern.host == host:
+			return true
+		}
+	}
+	return false
+}
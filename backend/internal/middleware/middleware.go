@@ -2,11 +2,13 @@ package middleware
 
 import (
 	"fmt"
+	"math/rand/v2"
 	"net/http"
 	"strings"
 	"time"
 
 	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
 	"visekai/backend/internal/services"
 	"visekai/backend/pkg/logger"
 
@@ -14,16 +16,51 @@ import (
 	"github.com/google/uuid"
 )
 
-// Logger middleware logs HTTP requests
-func Logger() gin.HandlerFunc {
+// LoggerConfig decides what the request log holds besides each request's
+// method, path, status and latency
+type LoggerConfig struct {
+	// ErrorBodies logs the request and response bodies of error responses,
+	// with the values of sensitive fields redacted
+	ErrorBodies bool
+	// MaxBodyBytes is how much of each body is logged
+	MaxBodyBytes int
+	// SamplePercent is the share of error responses whose bodies are logged
+	SamplePercent int
+	// RedactFields are redacted besides passwords, tokens, secrets, keys and
+	// the like
+	RedactFields []string
+}
+
+// Logger middleware logs HTTP requests, with sensitive query parameters
+// redacted, under their request ID and, once authenticated, user ID. Error
+// responses are logged with their bodies when configured to.
+func Logger(cfg LoggerConfig) gin.HandlerFunc {
+	redactor := newRedactor(cfg.RedactFields)
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 4096
+	}
+
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		raw := c.Request.URL.RawQuery
 
+		// Keep the start of both bodies in case the response is an error
+		var requestBody *limitedBuffer
+		var writer *errorBodyWriter
+		if cfg.ErrorBodies {
+			requestBody = captureRequestBody(c, cfg.MaxBodyBytes)
+			writer = newErrorBodyWriter(c.Writer, cfg.MaxBodyBytes)
+			c.Writer = writer
+		}
+
 		// Process request
 		c.Next()
 
+		if writer != nil {
+			c.Writer = writer.ResponseWriter
+		}
+
 		// Calculate latency
 		latency := time.Since(start)
 
@@ -32,44 +69,66 @@ func Logger() gin.HandlerFunc {
 
 		// Build full path
 		if raw != "" {
-			path = path + "?" + raw
+			path = path + "?" + redactor.query(raw)
 		}
 
-		// Get request ID if available
-		requestID := GetRequestID(c)
-
-		// Log request
-		logger.Info("HTTP Request",
-			"request_id", requestID,
+		fields := []any{
 			"method", c.Request.Method,
 			"path", path,
 			"status", statusCode,
 			"latency", latency,
 			"ip", c.ClientIP(),
-		)
+		}
+		if writer != nil && statusCode >= http.StatusBadRequest && rand.IntN(100) < cfg.SamplePercent {
+			fields = append(fields,
+				"request_body", redactor.body(requestBody, c.Request.Header.Get("Content-Type")),
+				"response_body", redactor.body(writer.body, c.Writer.Header().Get("Content-Type")),
+			)
+		}
+
+		// Log request, under its request ID and user ID
+		logger.FromContext(c.Request.Context()).Info("HTTP Request", fields...)
 	}
 }
 
-// CORS middleware handles CORS
-func CORS() gin.HandlerFunc {
+// AuthRequired middleware validates JWT tokens, and API keys when apiKeyService
+// is not nil. API keys are sent in the X-API-Key header or as a bearer token.
+func AuthRequired(authService *services.AuthService, apiKeyService *services.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		// Authenticate with an API key if one is presented
+		if apiKeyService != nil {
+			apiKey := c.GetHeader("X-API-Key")
+			if apiKey == "" && strings.HasPrefix(c.GetHeader("Authorization"), "Bearer "+models.APIKeyPrefix) {
+				apiKey = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			}
+			if apiKey != "" {
+				key, err := apiKeyService.Authenticate(c.Request.Context(), apiKey)
+				if err != nil {
+					c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+						"AUTH_002",
+						"Invalid or expired API key",
+						nil,
+					))
+					c.Abort()
+					return
+				}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+				// Set user context
+				c.Set("user_id", key.UserID)
+				c.Set("api_key_id", key.ID)
+				c.Set("api_key_permissions", key.Permissions)
+				c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), "user_id", key.UserID, "api_key_id", key.ID))
 
-		c.Next()
-	}
-}
+				// Keys restricted to labels only see documents and jobs carrying them
+				if len(key.Labels) > 0 {
+					c.Request = c.Request.WithContext(repository.WithLabelScope(c.Request.Context(), key.Labels))
+				}
+
+				c.Next()
+				return
+			}
+		}
 
-// AuthRequired middleware validates JWT tokens
-func AuthRequired(authService *services.AuthService) gin.HandlerFunc {
-	return func(c *gin.Context) {
 		// Get authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -111,6 +170,66 @@ func AuthRequired(authService *services.AuthService) gin.HandlerFunc {
 		// Set user context
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), "user_id", claims.UserID))
+
+		c.Next()
+	}
+}
+
+// RequirePermission middleware restricts a route group to callers holding the
+// resource's read permission for safe requests (GET, HEAD) and its write
+// permission for all others. Users hold the permissions of their role; API
+// keys hold those of their user's role narrowed to their own, and keys
+// restricted to labels never reach admin routes. It must be used after
+// AuthRequired.
+func RequirePermission(userRepo *repository.UserRepository, resource models.Resource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, models.NewErrorResponse(
+				"AUTH_003",
+				err.Error(),
+				nil,
+			))
+			c.Abort()
+			return
+		}
+
+		action := models.ActionWrite
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			action = models.ActionRead
+		}
+		required := models.NewPermission(resource, action)
+
+		allowed := false
+		if user, err := userRepo.GetByID(c.Request.Context(), userID); err == nil {
+			allowed = models.HasPermission(models.RolePermissions[user.Role], required)
+		}
+		if keyPermissions := GetAPIKeyPermissions(c); len(keyPermissions) > 0 {
+			allowed = allowed && models.HasPermission(keyPermissions, required)
+		}
+
+		if resource == models.ResourceAdmin {
+			if !allowed || repository.LabelScope(c.Request.Context()) != nil {
+				c.JSON(http.StatusForbidden, models.NewErrorResponse(
+					"AUTH_005",
+					"Admin access required",
+					nil,
+				))
+				c.Abort()
+				return
+			}
+		}
+
+		if !allowed {
+			c.JSON(http.StatusForbidden, models.NewErrorResponse(
+				"AUTH_008",
+				"Missing permission "+string(required),
+				nil,
+			))
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -130,3 +249,26 @@ func GetUserID(c *gin.Context) (uuid.UUID, error) {
 
 	return id, nil
 }
+
+// GetAPIKeyID returns the ID of the API key that authenticated the request, if any
+func GetAPIKeyID(c *gin.Context) (uuid.UUID, bool) {
+	keyID, exists := c.Get("api_key_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+
+	id, ok := keyID.(uuid.UUID)
+	return id, ok
+}
+
+// GetAPIKeyPermissions returns the permissions the API key that authenticated
+// the request is narrowed to, if any
+func GetAPIKeyPermissions(c *gin.Context) []models.Permission {
+	permissions, exists := c.Get("api_key_permissions")
+	if !exists {
+		return nil
+	}
+
+	held, _ := permissions.([]models.Permission)
+	return held
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemJSON is the media type of RFC 7807 problem details
+const ProblemJSON = "application/problem+json"
+
+// ProblemDetails rewrites JSON error responses as RFC 7807 problem details for
+// clients that list application/problem+json in Accept. The problem type is
+// typeBase followed by the error code, e.g. urn:visekai:error:RES_003, and the
+// error code and field errors are kept as the code and errors members. Other
+// clients get the usual error envelope.
+func ProblemDetails(typeBase string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !acceptsProblemJSON(c.Request.Header.Values("Accept")) {
+			c.Next()
+			return
+		}
+
+		writer := &problemWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if !writer.buffered {
+			return
+		}
+
+		body := writer.body.Bytes()
+		var errResp models.ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err != nil || errResp.Success || errResp.Error.Code == "" {
+			// Not an error envelope; send it unchanged
+			_, _ = writer.ResponseWriter.Write(body)
+			return
+		}
+
+		problem := newProblem(c, writer.Status(), &errResp, typeBase)
+		body, err := json.Marshal(problem)
+		if err != nil {
+			_, _ = writer.ResponseWriter.Write(writer.body.Bytes())
+			return
+		}
+
+		writer.Header().Set("Content-Type", ProblemJSON)
+		_, _ = writer.ResponseWriter.Write(body)
+	}
+}
+
+// newProblem returns the problem details of an error envelope sent with status
+func newProblem(c *gin.Context, status int, errResp *models.ErrorResponse, typeBase string) models.ProblemDetails {
+	// The title of a code's type is its catalog description
+	title := http.StatusText(status)
+	if entry, ok := apperrors.Lookup(errResp.Error.Code); ok {
+		title = entry.Description
+	}
+
+	return models.ProblemDetails{
+		Type:      typeBase + errResp.Error.Code,
+		Title:     title,
+		Status:    status,
+		Detail:    errResp.Error.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      errResp.Error.Code,
+		Errors:    errResp.Error.Details,
+		RequestID: GetRequestID(c),
+		Timestamp: errResp.Timestamp,
+	}
+}
+
+// acceptsProblemJSON reports whether an Accept header lists problem details
+// with a non-zero quality
+func acceptsProblemJSON(accept []string) bool {
+	return accepts(accept, ProblemJSON)
+}
+
+// accepts reports whether an Accept header lists a media type with a non-zero
+// quality
+func accepts(accept []string, want string) bool {
+	for _, header := range accept {
+		for _, mediaRange := range strings.Split(header, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil || mediaType != want {
+				continue
+			}
+			if q, ok := params["q"]; ok {
+				if quality, err := strconv.ParseFloat(q, 64); err != nil || quality <= 0 {
+					continue
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// problemWriter holds back JSON bodies of error responses, and of successful
+// ones too when successes is set, so they can be rewritten once the handler is
+// done; everything else is written through
+type problemWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffered  bool
+	successes bool
+}
+
+func (w *problemWriter) holdBack() bool {
+	if !w.buffered && !w.Written() && (w.successes || w.Status() >= http.StatusBadRequest) {
+		contentType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		w.buffered = contentType == "application/json"
+	}
+	return w.buffered
+}
+
+func (w *problemWriter) Write(b []byte) (int, error) {
+	if w.holdBack() {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *problemWriter) WriteString(s string) (int, error) {
+	if w.holdBack() {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
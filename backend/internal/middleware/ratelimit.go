@@ -1,21 +1,156 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"visekai/backend/internal/models"
+	"visekai/backend/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Limiter decides whether a request identified by key may proceed
+type Limiter interface {
+	Allow(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+// ErrUnlimited is returned by limiters whose limit is disabled, letting
+// requests through without rate limit headers
+var ErrUnlimited = errors.New("rate limit is disabled")
+
+// RateLimitResult is the outcome of a rate limit check
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// KeyFunc derives the rate limit key of a request
+type KeyFunc func(c *gin.Context) string
+
+// KeyByIP limits requests per client IP
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser limits requests per API key for requests authenticated with one and
+// per user otherwise, falling back to the client IP for anonymous requests.
+// It must run after AuthRequired.
+func KeyByUser(c *gin.Context) string {
+	if keyID, ok := GetAPIKeyID(c); ok {
+		return "apikey:" + keyID.String()
+	}
+	if userID, err := GetUserID(c); err == nil {
+		return "user:" + userID.String()
+	}
+	return KeyByIP(c)
+}
+
+// RateLimit middleware rejects requests once the limiter's quota for their key is
+// used up and reports the quota in X-RateLimit-Limit and X-RateLimit-Remaining,
+// plus Retry-After when rejecting. A nil limiter disables limiting. When the
+// limiter itself fails the request is let through so an unavailable backend does
+// not take the API down.
+func RateLimit(limiter Limiter, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), key(c))
+		if errors.Is(err, ErrUnlimited) {
+			c.Next()
+			return
+		}
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Warn("Rate limiter unavailable, allowing request", "error", err, "path", c.FullPath())
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, result)
+
+		if !result.Allowed {
+			c.JSON(http.StatusTooManyRequests, models.NewErrorResponse(
+				"RATE_001",
+				"Too many requests. Please try again later.",
+				nil,
+			))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setRateLimitHeaders reports a rate limit result. When several limits apply to a
+// route the one with the fewest remaining requests is reported.
+func setRateLimitHeaders(c *gin.Context, result RateLimitResult) {
+	header := c.Writer.Header()
+	if current, err := strconv.Atoi(header.Get("X-RateLimit-Remaining")); err == nil && current < result.Remaining {
+		return
+	}
+
+	header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	if !result.Allowed {
+		// Round up so clients retrying on time are not rejected again
+		seconds := int(math.Ceil(result.RetryAfter.Seconds()))
+		header.Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	}
+}
+
+// ReloadableLimiter is a Limiter whose limiter is replaced when the rate limits
+// are reloaded. Requests are let through while it has none.
+type ReloadableLimiter struct {
+	limiter atomic.Pointer[Limiter]
+}
+
+// NewReloadableLimiter creates a reloadable limiter starting with limiter,
+// which may be nil
+func NewReloadableLimiter(limiter Limiter) *ReloadableLimiter {
+	l := &ReloadableLimiter{}
+	l.Set(limiter)
+	return l
+}
+
+// Set replaces the limiter, stopping the previous one if it has a Stop method
+func (l *ReloadableLimiter) Set(limiter Limiter) {
+	previous := l.limiter.Swap(&limiter)
+	if previous != nil {
+		if stopper, ok := (*previous).(interface{ Stop() }); ok {
+			stopper.Stop()
+		}
+	}
+}
+
+// Allow implements Limiter
+func (l *ReloadableLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	limiter := *l.limiter.Load()
+	if limiter == nil {
+		return RateLimitResult{}, ErrUnlimited
+	}
+	return limiter.Allow(ctx, key)
+}
+
 // RateLimiter implements a simple token bucket rate limiter
 type RateLimiter struct {
 	visitors map[string]*Visitor
 	mu       sync.RWMutex
 	rate     int
 	window   time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // Visitor represents a rate limit visitor
@@ -31,6 +166,7 @@ func NewRateLimiter(requests int, window time.Duration) *RateLimiter {
 		visitors: make(map[string]*Visitor),
 		rate:     requests,
 		window:   window,
+		stop:     make(chan struct{}),
 	}
 
 	// Cleanup old visitors every 5 minutes
@@ -41,38 +177,24 @@ func NewRateLimiter(requests int, window time.Duration) *RateLimiter {
 
 // RateLimit middleware limits requests per IP
 func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-
-		if !rl.allow(ip) {
-			c.JSON(http.StatusTooManyRequests, models.NewErrorResponse(
-				"RATE_001",
-				"Too many requests. Please try again later.",
-				nil,
-			))
-			c.Abort()
-			return
-		}
-
-		c.Next()
-	}
+	return RateLimit(rl, KeyByIP)
 }
 
-// allow checks if a request is allowed
-func (rl *RateLimiter) allow(ip string) bool {
+// Allow implements Limiter for a single API instance
+func (rl *RateLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
 	now := time.Now()
+	result := RateLimitResult{Limit: rl.rate}
 
-	v, exists := rl.visitors[ip]
+	v, exists := rl.visitors[key]
 	if !exists {
-		rl.visitors[ip] = &Visitor{
-			tokens:     rl.rate - 1,
-			lastSeen:   now,
+		v = &Visitor{
+			tokens:     rl.rate,
 			lastRefill: now,
 		}
-		return true
+		rl.visitors[key] = v
 	}
 
 	// Refill tokens based on time elapsed
@@ -86,10 +208,18 @@ func (rl *RateLimiter) allow(ip string) bool {
 
 	if v.tokens > 0 {
 		v.tokens--
-		return true
+		result.Allowed = true
+		result.Remaining = v.tokens
+		return result, nil
 	}
 
-	return false
+	result.RetryAfter = rl.window - now.Sub(v.lastRefill)
+	return result, nil
+}
+
+// Stop stops cleaning up visitors, once the limiter is no longer used
+func (rl *RateLimiter) Stop() {
+	rl.stopOnce.Do(func() { close(rl.stop) })
 }
 
 // cleanupVisitors removes old visitors
@@ -97,12 +227,18 @@ func (rl *RateLimiter) cleanupVisitors() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-ticker.C:
+		}
+
 		rl.mu.Lock()
 		now := time.Now()
-		for ip, v := range rl.visitors {
-			if now.Sub(v.lastSeen) > 10*time.Minute {
-				delete(rl.visitors, ip)
+		for key, v := range rl.visitors {
+			if now.Sub(v.lastSeen) > max(10*time.Minute, rl.window) {
+				delete(rl.visitors, key)
 			}
 		}
 		rl.mu.Unlock()
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/pkg/redis"
+
+	"github.com/google/uuid"
+)
+
+// slidingWindowScript keeps a sorted set of request timestamps per key. Entries
+// older than the window are dropped, and a request is admitted while fewer than
+// limit entries remain. It returns {allowed, remaining, retry_after_ms}.
+const slidingWindowScript = `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0}
+end
+
+local retry = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+return {0, 0, retry}
+`
+
+// RedisLimiter is a sliding-window Limiter shared by all API instances through Redis
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+	rate   int
+	window time.Duration
+}
+
+// NewRedisLimiter creates a limiter admitting requests per key within any window.
+// The name separates the counters of limiters sharing a Redis database.
+func NewRedisLimiter(client *redis.Client, name string, requests int, window time.Duration) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: "visekai:ratelimit:" + name + ":",
+		rate:   requests,
+		window: window,
+	}
+}
+
+// Allow implements Limiter
+func (l *RedisLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	now := time.Now().UnixMilli()
+
+	reply, err := l.client.Do(ctx, "EVAL", slidingWindowScript, 1, l.prefix+key,
+		now, l.window.Milliseconds(), l.rate, fmt.Sprintf("%d-%s", now, uuid.NewString()),
+	)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 3 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	retryAfter, _ := values[2].(int64)
+
+	return RateLimitResult{
+		Allowed:    allowed == 1,
+		Limit:      l.rate,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(retryAfter) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// redacted replaces the values of sensitive fields in logs
+const redacted = "[REDACTED]"
+
+// defaultRedactFields are the fields and query parameters never logged; a
+// name containing any of them, in any case, is redacted
+var defaultRedactFields = []string{
+	"password", "passwd", "token", "secret", "authorization", "api_key", "apikey",
+	"private_key", "credential", "cookie", "signature",
+}
+
+// redactedQueryParams are query parameters redacted by their exact name, as
+// they carry OAuth authorization codes and state
+var redactedQueryParams = []string{"code", "state"}
+
+// jsonMember matches a JSON member with a scalar value, or a string value cut
+// off by truncation, in JSON that does not parse
+var jsonMember = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+
+// redactor removes the values of sensitive fields from logged bodies and
+// query strings
+type redactor struct {
+	fields []string
+}
+
+// newRedactor creates a redactor of the default fields and extra ones
+func newRedactor(extra []string) *redactor {
+	fields := slices.Clone(defaultRedactFields)
+	for _, field := range extra {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return &redactor{fields: fields}
+}
+
+// sensitive reports whether a field's value must not be logged
+func (r *redactor) sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range r.fields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// query returns a raw query string with the values of sensitive parameters
+// redacted, keeping the parameters in order
+func (r *redactor) query(raw string) string {
+	params := strings.Split(raw, "&")
+	for i, param := range params {
+		key, _, hasValue := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if hasValue && (r.sensitive(name) || slices.Contains(redactedQueryParams, strings.ToLower(name))) {
+			params[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// body returns a captured body as logged: JSON and form bodies with sensitive
+// fields redacted, text as is, and only the media type of anything else
+func (r *redactor) body(b *limitedBuffer, contentType string) string {
+	if b == nil || len(b.Bytes()) == 0 {
+		return ""
+	}
+
+	suffix := ""
+	if b.truncated {
+		suffix = "...(truncated)"
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var value any
+		if err := json.Unmarshal(b.Bytes(), &value); err == nil {
+			if out, err := json.Marshal(r.redactJSON(value)); err == nil {
+				return string(out)
+			}
+		}
+		// Truncated or malformed JSON is redacted member by member
+		return jsonMember.ReplaceAllStringFunc(string(b.Bytes()), func(member string) string {
+			match := jsonMember.FindStringSubmatch(member)
+			if !r.sensitive(match[1]) {
+				return member
+			}
+			return `"` + match[1] + `":"` + redacted + `"`
+		}) + suffix
+	case mediaType == "application/x-www-form-urlencoded":
+		return r.query(string(b.Bytes())) + suffix
+	case strings.HasPrefix(mediaType, "text/"):
+		return string(b.Bytes()) + suffix
+	default:
+		return fmt.Sprintf("[%d bytes of %s not logged]", len(b.Bytes()), mediaType)
+	}
+}
+
+// redactJSON replaces the values of sensitive members in a decoded JSON value
+func (r *redactor) redactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, member := range v {
+			if r.sensitive(key) {
+				v[key] = redacted
+			} else {
+				v[key] = r.redactJSON(member)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = r.redactJSON(item)
+		}
+	}
+	return value
+}
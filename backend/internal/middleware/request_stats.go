@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxErrorBodyCapture is how much of an error response is read for its code;
+// error envelopes are far smaller
+const maxErrorBodyCapture = 8 << 10
+
+// CountRequests counts every request in stats with the error code of its
+// response. Error responses without an error envelope are counted as
+// HTTP_<status>. It must run inside ProblemDetails, which rewrites the
+// envelopes it reads.
+func CountRequests(stats *services.RequestStats) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := newErrorBodyWriter(c.Writer, maxErrorBodyCapture)
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		status := writer.Status()
+		code := ""
+		if status >= http.StatusBadRequest {
+			var errResp models.ErrorResponse
+			if err := json.Unmarshal(writer.body.Bytes(), &errResp); err == nil && errResp.Error.Code != "" {
+				code = errResp.Error.Code
+			} else {
+				code = "HTTP_" + strconv.Itoa(status)
+			}
+		}
+		stats.Record(time.Now(), code)
+	}
+}
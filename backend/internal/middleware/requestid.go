@@ -1,13 +1,16 @@
 package middleware
 
 import (
+	"visekai/backend/pkg/logger"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 const RequestIDKey = "X-Request-ID"
 
-// RequestID adds a unique request ID to each request
+// RequestID adds a unique request ID to each request, and to the logger of the
+// request's context
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if request ID already exists in header
@@ -20,6 +23,7 @@ func RequestID() gin.HandlerFunc {
 		// Set request ID in context and response header
 		c.Set("request_id", requestID)
 		c.Writer.Header().Set(RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), "request_id", requestID))
 
 		c.Next()
 	}
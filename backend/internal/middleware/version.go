@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// V2MediaType is the media type of version 2 responses. Clients of the v1
+// routes listing it in Accept get version 2 responses too.
+const V2MediaType = "application/vnd.visekai.v2+json"
+
+// V2Envelope sends responses in the version 2 envelope: a successful response
+// is its data alone, with no success flag, message or timestamp, and an error
+// is always RFC 7807 problem details, typed as ProblemDetails types them.
+// Successful responses without data become 204 No Content. With negotiate
+// set, only clients listing V2MediaType in Accept get version 2 responses;
+// otherwise every client does. Bodies other than JSON, such as downloads and
+// event streams, are sent unchanged.
+func V2Envelope(typeBase string, negotiate bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested := accepts(c.Request.Header.Values("Accept"), V2MediaType)
+		if negotiate {
+			c.Header("Vary", "Accept")
+			if !requested {
+				c.Next()
+				return
+			}
+		}
+
+		c.Header("API-Version", "2")
+
+		writer := &problemWriter{ResponseWriter: c.Writer, successes: true}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if !writer.buffered {
+			return
+		}
+
+		body := writer.body.Bytes()
+		status := writer.Status()
+
+		var errResp models.ErrorResponse
+		if status >= http.StatusBadRequest {
+			if err := json.Unmarshal(body, &errResp); err == nil && !errResp.Success && errResp.Error.Code != "" {
+				if problem, err := json.Marshal(newProblem(c, status, &errResp, typeBase)); err == nil {
+					writer.Header().Set("Content-Type", ProblemJSON)
+					_, _ = writer.ResponseWriter.Write(problem)
+					return
+				}
+			}
+			_, _ = writer.ResponseWriter.Write(body)
+			return
+		}
+
+		var resp struct {
+			Success bool            `json:"success"`
+			Data    json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil || !resp.Success {
+			// Not a success envelope; send it unchanged
+			_, _ = writer.ResponseWriter.Write(body)
+			return
+		}
+
+		if len(resp.Data) == 0 || string(resp.Data) == "null" {
+			writer.Header().Del("Content-Type")
+			if status == http.StatusOK {
+				status = http.StatusNoContent
+			}
+			writer.ResponseWriter.WriteHeader(status)
+			writer.ResponseWriter.WriteHeaderNow()
+			return
+		}
+
+		if requested {
+			writer.Header().Set("Content-Type", V2MediaType)
+		}
+		_, _ = writer.ResponseWriter.Write(resp.Data)
+	}
+}
+
+// Deprecation marks the responses of a deprecated API version. The
+// Deprecation header (RFC 9745) tells when the version was deprecated, Sunset
+// (RFC 8594) when it stops being served, unless sunset is zero, and Link the
+// same route in the successor version, found by replacing prefix with
+// successorPrefix, and the documentation of the deprecation, unless docsURL is
+// empty.
+func Deprecation(deprecatedAt, sunset time.Time, prefix, successorPrefix, docsURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+
+		if path, ok := strings.CutPrefix(c.Request.URL.Path, prefix); ok {
+			c.Writer.Header().Add("Link", "<"+successorPrefix+path+`>; rel="successor-version"`)
+		}
+		if docsURL != "" {
+			c.Writer.Header().Add("Link", "<"+docsURL+`>; rel="deprecation"; type="text/html"`)
+		}
+
+		c.Next()
+	}
+}
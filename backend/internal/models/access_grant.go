@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccessGrant gives another user, the grantee, read access to a set of the
+// owner's documents and their results until it expires or is revoked
+type AccessGrant struct {
+	ID             uuid.UUID   `json:"id"`
+	OwnerID        uuid.UUID   `json:"owner_id"`
+	OwnerEmail     string      `json:"owner_email,omitempty"`
+	GranteeID      uuid.UUID   `json:"grantee_id"`
+	GranteeEmail   string      `json:"grantee_email,omitempty"`
+	DocumentIDs    []uuid.UUID `json:"document_ids"`
+	Note           *string     `json:"note,omitempty"`
+	ExpiresAt      time.Time   `json:"expires_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	RevokedAt      *time.Time  `json:"revoked_at,omitempty"`
+	ReminderSentAt *time.Time  `json:"reminder_sent_at,omitempty"`
+}
+
+// Active reports whether the grant can be used at the given time
+func (g *AccessGrant) Active(now time.Time) bool {
+	return g.RevokedAt == nil && now.Before(g.ExpiresAt)
+}
+
+// AccessGrantCreateRequest represents the data needed to grant temporary access
+type AccessGrantCreateRequest struct {
+	GranteeEmail  string      `json:"grantee_email" validate:"required,email"`
+	DocumentIDs   []uuid.UUID `json:"document_ids" validate:"required,min=1,max=100"`
+	DurationHours int         `json:"duration_hours" validate:"required,min=1,max=720"`
+	Note          string      `json:"note" validate:"max=1000"`
+}
+
+// GrantedDocument is a document seen through an access grant, with its latest
+// result if it has one
+type GrantedDocument struct {
+	Document *Document  `json:"document"`
+	Result   *OCRResult `json:"result,omitempty"`
+}
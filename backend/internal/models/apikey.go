@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key so keys are recognizable in configs and logs
+const APIKeyPrefix = "vsk_"
+
+// APIKey represents a long-lived credential for automated clients.
+// Only a hash of the key is stored.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"key_prefix"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	IsActive   bool       `json:"is_active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Labels restrict the key to documents and jobs carrying at least one of
+	// them; a key without labels sees all of its user's documents and jobs
+	Labels []string `json:"labels"`
+	// Permissions narrow the key to some of its user's permissions; a key
+	// without permissions holds all of them
+	Permissions []Permission `json:"permissions"`
+}
+
+// APIKeyCreateRequest represents the data needed to create an API key
+type APIKeyCreateRequest struct {
+	Name        string       `json:"name" validate:"required,min=1,max=255"`
+	ExpiresAt   *time.Time   `json:"expires_at"`
+	Labels      []string     `json:"labels"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// APIKeyCreated is returned once when a key is created; the key itself cannot be retrieved later
+type APIKeyCreated struct {
+	*APIKey
+	Key string `json:"key"`
+}
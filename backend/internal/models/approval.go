@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ApprovalDecision is an approver's verdict on a held job
+type ApprovalDecision string
+
+const (
+	ApprovalApproved ApprovalDecision = "approved"
+	ApprovalRejected ApprovalDecision = "rejected"
+)
+
+// JobApproval is the approval request of a job held by the OCR policy
+type JobApproval struct {
+	JobID  uuid.UUID `json:"job_id"`
+	Reason string    `json:"reason"`
+	// OrgID is the organization whose OCR policy held the job, nil for the
+	// instance-wide policy
+	OrgID       *uuid.UUID        `json:"organization_id,omitempty"`
+	RequestedAt time.Time         `json:"requested_at"`
+	Decision    *ApprovalDecision `json:"decision,omitempty"`
+	DecidedBy   *uuid.UUID        `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time        `json:"decided_at,omitempty"`
+	Comment     *string           `json:"comment,omitempty"`
+	// Job is filled in when listing approvals
+	Job *OCRJob `json:"job,omitempty"`
+}
+
+// ApprovalDecisionRequest represents an approver's optional comment on a decision
+type ApprovalDecisionRequest struct {
+	Comment string `json:"comment" validate:"max=1000"`
+}
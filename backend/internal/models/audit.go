@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction represents a security-relevant action recorded in the audit log
+type AuditAction string
+
+const (
+	AuditLogin                AuditAction = "auth.login"
+	AuditLoginFailed          AuditAction = "auth.login_failed"
+	AuditPasswordChanged      AuditAction = "auth.password_changed"
+	AuditProfileUpdated       AuditAction = "auth.profile_updated"
+	AuditDeletionRequested    AuditAction = "auth.deletion_requested"
+	AuditDeletionCancelled    AuditAction = "auth.deletion_cancelled"
+	AuditAccountExported      AuditAction = "auth.account_exported"
+	AuditTakeoutRequested     AuditAction = "auth.takeout_requested"
+	AuditTakeoutDownloaded    AuditAction = "auth.takeout_downloaded"
+	AuditDocumentUploaded     AuditAction = "document.uploaded"
+	AuditDocumentDeleted      AuditAction = "document.deleted"
+	AuditDocumentRestored     AuditAction = "document.restored"
+	AuditMetadataImported     AuditAction = "document.metadata_imported"
+	AuditJobSubmitted         AuditAction = "job.submitted"
+	AuditJobCancelled         AuditAction = "job.cancelled"
+	AuditJobDeleted           AuditAction = "job.deleted"
+	AuditJobRestored          AuditAction = "job.restored"
+	AuditJobApproved          AuditAction = "job.approved"
+	AuditJobRejected          AuditAction = "job.rejected"
+	AuditSettingsUpdated      AuditAction = "settings.updated"
+	AuditSetupCompleted       AuditAction = "setup.completed"
+	AuditOCRPolicyUpdated     AuditAction = "ocr_policy.updated"
+	AuditJobsRequeued         AuditAction = "jobs.requeued"
+	AuditJobsCancelled        AuditAction = "jobs.cancelled"
+	AuditBrandingUpdated      AuditAction = "branding.updated"
+	AuditDomainVerified       AuditAction = "branding.domain_verified"
+	AuditGrantCreated         AuditAction = "access_grant.created"
+	AuditGrantRevoked         AuditAction = "access_grant.revoked"
+	AuditGrantAccessed        AuditAction = "access_grant.accessed"
+	AuditOrgCreated           AuditAction = "organization.created"
+	AuditOrgDeleted           AuditAction = "organization.deleted"
+	AuditOrgMemberInvited     AuditAction = "organization.member_invited"
+	AuditOrgMemberJoined      AuditAction = "organization.member_joined"
+	AuditOrgMemberUpdated     AuditAction = "organization.member_updated"
+	AuditOrgMemberRemoved     AuditAction = "organization.member_removed"
+	AuditOrgDocsShared        AuditAction = "organization.documents_shared"
+	AuditOrgDocUnshared       AuditAction = "organization.document_unshared"
+	AuditOrgSSOUpdated        AuditAction = "organization.sso_updated"
+	AuditOrgSSODeleted        AuditAction = "organization.sso_deleted"
+	AuditOrgSSODomainVerified AuditAction = "organization.sso_domain_verified"
+	AuditShareCreated         AuditAction = "share_link.created"
+	AuditShareRevoked         AuditAction = "share_link.revoked"
+	AuditShareAccessed        AuditAction = "share_link.accessed"
+	AuditReportGenerated      AuditAction = "usage_report.generated"
+	AuditBackfillStarted      AuditAction = "backfill.started"
+	AuditBackfillCancelled    AuditAction = "backfill.cancelled"
+	AuditJWTKeyRotated        AuditAction = "jwt_key.rotated"
+)
+
+// AuditEvent records who did what, from where
+type AuditEvent struct {
+	ID         uuid.UUID      `json:"id"`
+	Action     AuditAction    `json:"action"`
+	ActorID    *uuid.UUID     `json:"actor_id,omitempty"`
+	ActorEmail string         `json:"actor_email,omitempty"`
+	TargetType string         `json:"target_type,omitempty"`
+	TargetID   *uuid.UUID     `json:"target_id,omitempty"`
+	IPAddress  string         `json:"ip_address,omitempty"`
+	UserAgent  string         `json:"user_agent,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// AuditFilter selects audit events for listing
+type AuditFilter struct {
+	Action   AuditAction
+	ActorID  *uuid.UUID
+	TargetID *uuid.UUID
+	Since    *time.Time
+	Until    *time.Time
+	Page     int
+	PerPage  int
+}
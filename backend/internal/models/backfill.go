@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BackfillStatus is the status of a backfill run
+type BackfillStatus string
+
+// Backfill run statuses
+const (
+	// BackfillPending runs wait for a worker, including resumed runs
+	BackfillPending BackfillStatus = "pending"
+	// BackfillRunning runs are being worked on by an instance
+	BackfillRunning BackfillStatus = "running"
+	// BackfillCompleted runs went through all the data
+	BackfillCompleted BackfillStatus = "completed"
+	// BackfillFailed runs stopped on an error and can be resumed
+	BackfillFailed BackfillStatus = "failed"
+	// BackfillCancelled runs were stopped by an admin and can be resumed
+	BackfillCancelled BackfillStatus = "cancelled"
+)
+
+// BackfillRun is a run of a backfill task over existing data. Cursor is the
+// position of the last batch processed, which a resumed run continues from.
+type BackfillRun struct {
+	ID          uuid.UUID      `json:"id"`
+	Task        string         `json:"task"`
+	Status      BackfillStatus `json:"status"`
+	Cursor      string         `json:"cursor"`
+	Total       int            `json:"total"`
+	Processed   int            `json:"processed"`
+	Updated     int            `json:"updated"`
+	Failed      int            `json:"failed"`
+	Error       string         `json:"error,omitempty"`
+	StartedBy   *uuid.UUID     `json:"started_by,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	StartedAt   *time.Time     `json:"started_at,omitempty"`
+	HeartbeatAt *time.Time     `json:"heartbeat_at,omitempty"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+}
+
+// Active reports whether the run is pending or running
+func (r *BackfillRun) Active() bool {
+	return r.Status == BackfillPending || r.Status == BackfillRunning
+}
+
+// BackfillTask describes a backfill task admins can run, with its latest run
+type BackfillTask struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	LatestRun   *BackfillRun `json:"latest_run,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// CreditPack is a number of credits sold at a fixed price
+type CreditPack struct {
+	ID      string `json:"id"`
+	Credits int    `json:"credits"`
+	// Price is in the currency's smallest unit, such as cents
+	Price    int    `json:"price"`
+	Currency string `json:"currency"`
+}
+
+// CheckoutRequest represents a request to buy a credit pack
+type CheckoutRequest struct {
+	PackID string `json:"pack_id" validate:"required"`
+}
+
+// CheckoutSession is a hosted payment page the user is sent to. The credits
+// are added once the payment provider confirms the payment.
+type CheckoutSession struct {
+	ID        string     `json:"id"`
+	URL       string     `json:"url"`
+	Pack      CreditPack `json:"pack"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SettingBranding is the instance setting holding the instance-wide Branding
+const SettingBranding = "branding"
+
+// Branding is how an organization presents shared documents and exports: its
+// name, logo and colors, and the custom domain share links are served from.
+// Users outside any organization are presented with the instance-wide
+// branding, which organizations that never set their own also start from.
+type Branding struct {
+	// OrgID is the organization the branding belongs to, nil for the
+	// instance-wide branding
+	OrgID *uuid.UUID `json:"organization_id,omitempty"`
+	// DisplayName defaults to the instance name
+	DisplayName  string `json:"display_name,omitempty"`
+	LogoURL      string `json:"logo_url,omitempty"`
+	PrimaryColor string `json:"primary_color,omitempty"`
+	AccentColor  string `json:"accent_color,omitempty"`
+	// BrandExports adds a header naming the organization to text and Markdown exports
+	BrandExports bool          `json:"brand_exports"`
+	CustomDomain *CustomDomain `json:"custom_domain,omitempty"`
+	UpdatedAt    *time.Time    `json:"updated_at,omitempty"`
+	UpdatedBy    *uuid.UUID    `json:"updated_by,omitempty"`
+}
+
+// CustomDomain is a domain an organization serves share links from. It is only
+// used once verified, by publishing the token in a DNS TXT record.
+type CustomDomain struct {
+	Domain string `json:"domain"`
+	// VerificationRecord is the name of the TXT record that must hold
+	// VerificationValue
+	VerificationRecord string     `json:"verification_record"`
+	VerificationValue  string     `json:"verification_value"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+}
+
+// Verified reports whether ownership of the domain has been proven
+func (d *CustomDomain) Verified() bool {
+	return d != nil && d.VerifiedAt != nil
+}
+
+// PublicBranding is the branding shown to anyone, including viewers of shared
+// links who are not signed in, resolved for the host of the request
+type PublicBranding struct {
+	DisplayName  string `json:"display_name"`
+	LogoURL      string `json:"logo_url,omitempty"`
+	PrimaryColor string `json:"primary_color,omitempty"`
+	AccentColor  string `json:"accent_color,omitempty"`
+	// Domain is the verified custom domain, if any
+	Domain string `json:"domain,omitempty"`
+	// CustomDomain reports whether the request was made on the custom domain
+	CustomDomain bool `json:"custom_domain"`
+}
+
+// UpdateBrandingRequest represents a partial update of the branding. An empty
+// string clears a field; changing the custom domain requires verifying it again.
+type UpdateBrandingRequest struct {
+	DisplayName  *string `json:"display_name" validate:"omitempty,max=100"`
+	LogoURL      *string `json:"logo_url" validate:"omitempty,max=2048,eq=|startswith=https://"`
+	PrimaryColor *string `json:"primary_color" validate:"omitempty,eq=|hexcolor"`
+	AccentColor  *string `json:"accent_color" validate:"omitempty,eq=|hexcolor"`
+	BrandExports *bool   `json:"brand_exports"`
+	CustomDomain *string `json:"custom_domain" validate:"omitempty,max=253,eq=|fqdn"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DocumentType represents the kind of a document as recognized by a classifier
+type DocumentType string
+
+const (
+	DocumentTypeInvoice  DocumentType = "invoice"
+	DocumentTypeReceipt  DocumentType = "receipt"
+	DocumentTypeContract DocumentType = "contract"
+	DocumentTypeID       DocumentType = "id_document"
+	DocumentTypeOther    DocumentType = "other"
+)
+
+// Valid reports whether t is a known document type
+func (t DocumentType) Valid() bool {
+	switch t {
+	case DocumentTypeInvoice, DocumentTypeReceipt, DocumentTypeContract, DocumentTypeID, DocumentTypeOther:
+		return true
+	}
+	return false
+}
+
+// DocumentClassification is the type detected for a document from the text of
+// its latest full OCR run
+type DocumentClassification struct {
+	Type       DocumentType `json:"type"`
+	Confidence float64      `json:"confidence"`
+	// Classifier names the classifier that detected the type
+	Classifier   string    `json:"classifier"`
+	ClassifiedAt time.Time `json:"classified_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentMetaImportConnectionID is the metadata key holding the connection a
+// document was imported through
+const DocumentMetaImportConnectionID = "import_connection_id"
+
+// ImportConnection is a cloud storage account, such as a Google Drive or a
+// Dropbox, linked with OAuth. Once a folder is picked, new files in it are
+// imported as documents of the user and processed.
+type ImportConnection struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Provider string    `json:"provider"`
+	// Account is the email address of the linked account
+	Account      string     `json:"account"`
+	AccessToken  string     `json:"-"`
+	RefreshToken string     `json:"-"`
+	TokenExpiry  *time.Time `json:"-"`
+	FolderID     *string    `json:"folder_id,omitempty"`
+	FolderName   *string    `json:"folder_name,omitempty"`
+	// Labels are given to the imported documents
+	Labels     []string   `json:"labels"`
+	Enabled    bool       `json:"enabled"`
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
+	LastError  *string    `json:"last_error,omitempty"`
+	NextSyncAt time.Time  `json:"next_sync_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ConnectorAuthorizeRequest represents the body of a request linking a cloud
+// storage account
+type ConnectorAuthorizeRequest struct {
+	Provider string `json:"provider" validate:"required,oneof=google_drive dropbox"`
+}
+
+// ConnectorAuthorization is where to send the user to grant access to their
+// account
+type ConnectorAuthorization struct {
+	Provider         string `json:"provider"`
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// ImportConnectionUpdateRequest represents the body of a request picking the
+// folder a connection imports from and the labels of the imported documents
+type ImportConnectionUpdateRequest struct {
+	FolderID   string   `json:"folder_id" validate:"required,max=1024"`
+	FolderName string   `json:"folder_name" validate:"max=1024"`
+	Labels     []string `json:"labels"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// ImportedFile records a remote file a connection imported, or skipped
+// because it is not a supported document
+type ImportedFile struct {
+	ConnectionID  uuid.UUID  `json:"connection_id"`
+	RemoteID      string     `json:"remote_id"`
+	Name          string     `json:"name"`
+	DocumentID    *uuid.UUID `json:"document_id,omitempty"`
+	SkippedReason *string    `json:"skipped_reason,omitempty"`
+	ImportedAt    time.Time  `json:"imported_at"`
+}
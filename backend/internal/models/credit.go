@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreditTransactionKind identifies why a user's credits changed
+type CreditTransactionKind string
+
+const (
+	// CreditAllotment tops the balance up to the free monthly allotment
+	CreditAllotment CreditTransactionKind = "allotment"
+	// CreditCharge is what a job costs when submitted
+	CreditCharge CreditTransactionKind = "charge"
+	// CreditAdjustment corrects a job's charge to the pages it processed
+	CreditAdjustment CreditTransactionKind = "adjustment"
+	// CreditRefund returns a failed or cancelled job's charge
+	CreditRefund CreditTransactionKind = "refund"
+	// CreditPurchase adds credits the user paid for
+	CreditPurchase CreditTransactionKind = "purchase"
+)
+
+// CreditTransaction is an entry in a user's credit ledger. Amount is positive
+// for credits added and negative for credits used.
+type CreditTransaction struct {
+	ID          uuid.UUID             `json:"id"`
+	UserID      uuid.UUID             `json:"user_id"`
+	Kind        CreditTransactionKind `json:"kind"`
+	Amount      int                   `json:"amount"`
+	JobID       *uuid.UUID            `json:"job_id,omitempty"`
+	Period      *time.Time            `json:"period,omitempty"`
+	Description *string               `json:"description,omitempty"`
+	// Reference is the billing provider's ID of a purchase
+	Reference *string   `json:"reference,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreditUsage summarizes a user's credits with a page of their ledger
+type CreditUsage struct {
+	Balance int `json:"balance"`
+	// Enforced is false when credits are tracked but jobs are not refused for
+	// lack of them
+	Enforced         bool                   `json:"enforced"`
+	MonthlyAllotment int                    `json:"monthly_allotment"`
+	PeriodStart      time.Time              `json:"period_start"`
+	NextAllotmentAt  time.Time              `json:"next_allotment_at"`
+	UsedThisPeriod   int                    `json:"used_this_period"`
+	CostPerPage      map[ResolutionMode]int `json:"cost_per_page"`
+	Transactions     []*CreditTransaction   `json:"transactions"`
+	Pagination       *Pagination            `json:"pagination"`
+}
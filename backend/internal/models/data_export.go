@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataExportStatus is the progress of a data export
+type DataExportStatus string
+
+const (
+	DataExportPending    DataExportStatus = "pending"
+	DataExportProcessing DataExportStatus = "processing"
+	DataExportCompleted  DataExportStatus = "completed"
+	DataExportFailed     DataExportStatus = "failed"
+)
+
+// DataExport is a zip archive of everything a user stored: their documents,
+// the latest result of each as markdown and JSON, and their account data.
+// It is built in the background and can be downloaded until it expires.
+type DataExport struct {
+	ID          uuid.UUID        `json:"id"`
+	UserID      uuid.UUID        `json:"user_id"`
+	Status      DataExportStatus `json:"status"`
+	FilePath    string           `json:"-"`
+	FileSize    int64            `json:"file_size"`
+	Documents   int              `json:"documents"`
+	Error       *string          `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
+	// DownloadURL is the signed link to the archive, set once it is ready
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// Active reports whether the export is still being built
+func (e *DataExport) Active() bool {
+	return e.Status == DataExportPending || e.Status == DataExportProcessing
+}
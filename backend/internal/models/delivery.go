@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a result delivery
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// DeliveryTarget is an S3 bucket or SFTP server the user's completed results
+// are pushed to, in the chosen formats. Jobs are picked up in the order they
+// completed, starting after the last one given to the target.
+type DeliveryTarget struct {
+	ID       uuid.UUID              `json:"id"`
+	UserID   uuid.UUID              `json:"user_id"`
+	Kind     string                 `json:"kind"`
+	Name     string                 `json:"name"`
+	Settings DeliveryTargetSettings `json:"settings"`
+	// Credentials are the sealed DeliveryCredentials
+	Credentials string   `json:"-"`
+	Formats     []string `json:"formats"`
+	// Labels restricts the target to documents with one of them, set when it
+	// is created with an API key restricted to labels
+	Labels    []string  `json:"labels,omitempty"`
+	Enabled   bool      `json:"enabled"`
+	CursorAt  time.Time `json:"-"`
+	CursorID  uuid.UUID `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DeliveryTargetSettings locates a delivery target; secrets are kept apart in
+// DeliveryCredentials
+type DeliveryTargetSettings struct {
+	// Endpoint is the URL of an S3-compatible service, empty for AWS
+	Endpoint    string `json:"endpoint,omitempty" validate:"omitempty,url,max=2048"`
+	Region      string `json:"region,omitempty" validate:"max=50"`
+	Bucket      string `json:"bucket,omitempty" validate:"max=255"`
+	Prefix      string `json:"prefix,omitempty" validate:"max=1024"`
+	AccessKeyID string `json:"access_key_id,omitempty" validate:"max=255"`
+	Host        string `json:"host,omitempty" validate:"max=255"`
+	Port        int    `json:"port,omitempty" validate:"min=0,max=65535"`
+	Username    string `json:"username,omitempty" validate:"max=255"`
+	Directory   string `json:"directory,omitempty" validate:"max=1024"`
+	// HostKey is the SHA256 fingerprint of the SFTP server's host key; when
+	// not given, the key the server presents first is trusted
+	HostKey string `json:"host_key,omitempty" validate:"max=255"`
+}
+
+// DeliveryCredentials are the secrets of a delivery target, stored encrypted
+type DeliveryCredentials struct {
+	SecretAccessKey string `json:"secret_access_key,omitempty" validate:"max=255"`
+	Password        string `json:"password,omitempty" validate:"max=255"`
+	PrivateKey      string `json:"private_key,omitempty" validate:"max=16384"`
+}
+
+// DeliveryTargetRequest represents the body of a request adding or changing a
+// delivery target. Credentials left out of a change are kept.
+type DeliveryTargetRequest struct {
+	Kind        string                 `json:"kind" validate:"required,oneof=s3 sftp"`
+	Name        string                 `json:"name" validate:"max=100"`
+	Settings    DeliveryTargetSettings `json:"settings"`
+	Credentials *DeliveryCredentials   `json:"credentials"`
+	Formats     []string               `json:"formats" validate:"omitempty,max=4,dive,oneof=markdown json text searchable_pdf"`
+	Enabled     *bool                  `json:"enabled"`
+}
+
+// ResultDelivery tracks the push of one job's result to a delivery target
+type ResultDelivery struct {
+	ID       uuid.UUID `json:"id"`
+	TargetID uuid.UUID `json:"target_id"`
+	JobID    uuid.UUID `json:"job_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Status   string    `json:"status"`
+	// Files are the names the result was written under, once delivered
+	Files         []string   `json:"files,omitempty"`
+	Attempts      int        `json:"attempts"`
+	LastError     *string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"-"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -8,20 +8,94 @@ import (
 
 // Document represents a uploaded document
 type Document struct {
-	ID               uuid.UUID  `json:"id"`
-	UserID           uuid.UUID  `json:"user_id"`
-	Filename         string     `json:"filename"`
-	OriginalFilename string     `json:"original_filename"`
-	FilePath         string     `json:"file_path"`
-	FileSize         int64      `json:"file_size"`
-	MimeType         string     `json:"mime_type"`
-	FileHash         string     `json:"file_hash"`
-	NumPages         int        `json:"num_pages"`
-	ThumbnailPath    *string    `json:"thumbnail_path,omitempty"`
-	UploadedAt       time.Time  `json:"uploaded_at"`
-	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	ID               uuid.UUID      `json:"id"`
+	UserID           uuid.UUID      `json:"user_id"`
+	Filename         string         `json:"filename"`
+	OriginalFilename string         `json:"original_filename"`
+	FilePath         string         `json:"file_path"`
+	FileSize         int64          `json:"file_size"`
+	MimeType         string         `json:"mime_type"`
+	FileHash         string         `json:"file_hash"`
+	NumPages         int            `json:"num_pages"`
+	ThumbnailPath    *string        `json:"thumbnail_path,omitempty"`
+	UploadedAt       time.Time      `json:"uploaded_at"`
+	DeletedAt        *time.Time     `json:"deleted_at,omitempty"`
+	Metadata         map[string]any `json:"metadata,omitempty"`
+	Labels           []string       `json:"labels"`
+	// OrgID is the organization the document is shared with
+	OrgID *uuid.UUID `json:"org_id,omitempty"`
+	// Quality is assessed when a job over the whole document completes
+	Quality *DocumentQuality `json:"quality,omitempty"`
+	// Classification is detected when a job over the whole document completes
+	Classification *DocumentClassification `json:"classification,omitempty"`
 }
 
+// DocumentMetaSourceFilename is the metadata key holding the filename as uploaded
+// when it differs from the normalized OriginalFilename
+const DocumentMetaSourceFilename = "source_filename"
+
+// DocumentMetaAliases is the metadata key holding the other filenames the
+// document's file was uploaded under, recorded when a duplicate upload is
+// linked to it
+const DocumentMetaAliases = "aliases"
+
+// DocumentMetaOrientation is the metadata key holding how an image document is
+// turned upright for OCR and previews: its EXIF orientation, the rotation
+// detected from its text and the total clockwise rotation applied
+const DocumentMetaOrientation = "orientation"
+
+// Type returns the type detected for the document, or an empty type when it
+// was not classified
+func (d *Document) Type() DocumentType {
+	if d.Classification == nil {
+		return ""
+	}
+	return d.Classification.Type
+}
+
+// Aliases returns the other filenames the document's file was uploaded under
+func (d *Document) Aliases() []string {
+	var aliases []string
+	switch values := d.Metadata[DocumentMetaAliases].(type) {
+	case []string:
+		aliases = values
+	case []any:
+		for _, value := range values {
+			if alias, ok := value.(string); ok {
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	return aliases
+}
+
+// AddAlias records another filename the document's file was uploaded under.
+// It reports false when the name is the document's own or already recorded.
+func (d *Document) AddAlias(name string) bool {
+	if name == "" || name == d.OriginalFilename {
+		return false
+	}
+	aliases := d.Aliases()
+	for _, alias := range aliases {
+		if alias == name {
+			return false
+		}
+	}
+
+	if d.Metadata == nil {
+		d.Metadata = map[string]any{}
+	}
+	d.Metadata[DocumentMetaAliases] = append(aliases, name)
+	return true
+}
+
+// Document metadata keys of a document whose file is shared with the identical
+// upload of a teammate, naming that upload and its owner
+const (
+	DocumentMetaSharedFromDocumentID = "shared_from_document_id"
+	DocumentMetaSharedFromUserID     = "shared_from_user_id"
+)
+
 // DocumentUploadRequest represents the metadata for a document upload
 type DocumentUploadRequest struct {
 	OriginalFilename string `json:"original_filename"`
@@ -30,8 +104,20 @@ type DocumentUploadRequest struct {
 
 // DocumentListRequest represents pagination and filter parameters
 type DocumentListRequest struct {
-	Page     int    `json:"page" validate:"min=1"`
-	PerPage  int    `json:"per_page" validate:"min=1,max=100"`
-	SortBy   string `json:"sort_by" validate:"omitempty,oneof=uploaded_at filename file_size"`
-	SortDesc bool   `json:"sort_desc"`
+	Page     int    `json:"page" form:"page" validate:"min=1"`
+	PerPage  int    `json:"per_page" form:"per_page" validate:"min=1,max=100"`
+	SortBy   string `json:"sort_by" form:"sort_by" validate:"omitempty,oneof=uploaded_at filename file_size quality_score"`
+	SortDesc bool   `json:"sort_desc" form:"sort_desc"`
+	// MinQuality and MaxQuality filter on the quality score; documents not
+	// assessed yet are left out when either is set
+	MinQuality *int `json:"min_quality" form:"min_quality" validate:"omitempty,min=0,max=100"`
+	MaxQuality *int `json:"max_quality" form:"max_quality" validate:"omitempty,min=0,max=100"`
+	// Type filters on the detected document type
+	Type DocumentType `json:"type" form:"type" validate:"omitempty,oneof=invoice receipt contract id_document other"`
+}
+
+// TrashedDocument represents a soft-deleted document in the trash
+type TrashedDocument struct {
+	Document
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
 }
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentRelation is the type of a link between two documents, read as
+// "source <relation> target"
+type DocumentRelation string
+
+const (
+	// RelationRelated links documents that belong together without an order
+	RelationRelated DocumentRelation = "related"
+	// RelationPartOf links a part, such as a separately scanned page, to the
+	// whole; the link's position orders the parts
+	RelationPartOf DocumentRelation = "part_of"
+	// RelationAttachmentOf links an attachment to the document it came with
+	RelationAttachmentOf DocumentRelation = "attachment_of"
+	// RelationReferences links a document to one it refers to, such as a
+	// delivery note to its invoice
+	RelationReferences DocumentRelation = "references"
+	// RelationSupersedes links a document to the one it replaces
+	RelationSupersedes DocumentRelation = "supersedes"
+	// RelationDuplicateOf links a document to another scan of the same paper
+	RelationDuplicateOf DocumentRelation = "duplicate_of"
+)
+
+// Link directions seen from a document
+const (
+	LinkOutgoing = "outgoing"
+	LinkIncoming = "incoming"
+)
+
+// DocumentLink is a typed, directed link between two of a user's documents
+type DocumentLink struct {
+	ID        uuid.UUID        `json:"id"`
+	UserID    uuid.UUID        `json:"user_id"`
+	SourceID  uuid.UUID        `json:"source_id"`
+	TargetID  uuid.UUID        `json:"target_id"`
+	Relation  DocumentRelation `json:"relation"`
+	Position  *int             `json:"position,omitempty"`
+	Note      *string          `json:"note,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// LinkedDocument is a link seen from one of its documents, with the document
+// at its other end
+type LinkedDocument struct {
+	Link *DocumentLink `json:"link"`
+	// Direction is outgoing when the document is the link's source
+	Direction string    `json:"direction"`
+	Document  *Document `json:"document"`
+}
+
+// DocumentLinkCreateRequest represents the data needed to link a document to another
+type DocumentLinkCreateRequest struct {
+	TargetID uuid.UUID        `json:"target_id" validate:"required"`
+	Relation DocumentRelation `json:"relation" validate:"required,oneof=related part_of attachment_of references supersedes duplicate_of"`
+	Position *int             `json:"position" validate:"omitempty,min=0"`
+	Note     string           `json:"note" validate:"max=1000"`
+}
+
+// DocumentLinkUpdateRequest represents a change of a link. Fields left out
+// are unchanged.
+type DocumentLinkUpdateRequest struct {
+	Relation *DocumentRelation `json:"relation" validate:"omitempty,oneof=related part_of attachment_of references supersedes duplicate_of"`
+	Position *int              `json:"position" validate:"omitempty,min=0"`
+	Note     *string           `json:"note" validate:"omitempty,max=1000"`
+}
+
+// RelatedDocument is a document reached by following links, with the number
+// of links followed to reach it
+type RelatedDocument struct {
+	Document *Document `json:"document"`
+	Depth    int       `json:"depth"`
+}
+
+// RelatedGraph is the part of a user's link graph reachable from a document:
+// the documents, the root included at depth 0, and the links between them
+type RelatedGraph struct {
+	RootID    uuid.UUID          `json:"root_id"`
+	Depth     int                `json:"depth"`
+	Documents []*RelatedDocument `json:"documents"`
+	Links     []*DocumentLink    `json:"links"`
+	// Truncated is set when more documents were reachable than returned
+	Truncated bool `json:"truncated"`
+}
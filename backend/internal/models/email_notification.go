@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FailedJobEmail is a failed job to email its owner about
+type FailedJobEmail struct {
+	JobID        uuid.UUID
+	UserID       uuid.UUID
+	DocumentID   uuid.UUID
+	Filename     string
+	ErrorMessage *string
+	CompletedAt  time.Time
+	Email        string
+	Timezone     string
+}
+
+// EmailDigest is a user's daily digest, sent at NextAt about the jobs that
+// finished since PeriodStart
+type EmailDigest struct {
+	UserID      uuid.UUID
+	PeriodStart time.Time
+	NextAt      time.Time
+	LastSentAt  *time.Time
+	Email       string
+	Timezone    string
+	DigestHour  int
+}
+
+// DigestSummary sums up a user's jobs that finished in a period.
+// AverageConfidence is nil when no completed job has a confidence score.
+type DigestSummary struct {
+	Completed         int
+	Failed            int
+	Pages             int
+	AverageConfidence *float64
+	CreditsUsed       int
+	// FailedJobs are the most recent failures, at most a handful
+	FailedJobs []*JobNotification
+}
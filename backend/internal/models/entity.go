@@ -0,0 +1,42 @@
+package models
+
+// EntityType represents the kind of an entity extracted from result text
+type EntityType string
+
+const (
+	EntityDate          EntityType = "date"
+	EntityAmount        EntityType = "amount"
+	EntityEmail         EntityType = "email"
+	EntityInvoiceNumber EntityType = "invoice_number"
+	EntityIBAN          EntityType = "iban"
+)
+
+// Valid reports whether t is a known entity type
+func (t EntityType) Valid() bool {
+	switch t {
+	case EntityDate, EntityAmount, EntityEmail, EntityInvoiceNumber, EntityIBAN:
+		return true
+	}
+	return false
+}
+
+// ResultJSONEntities is the key of a result's JSON data holding the entities
+// extracted from its raw text
+const ResultJSONEntities = "entities"
+
+// ResultEntity represents a value found in the raw text of a result
+type ResultEntity struct {
+	Type EntityType `json:"type"`
+	// Text is the entity as it appears in the raw text, between the byte
+	// offsets Start and End
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	// Value is the entity in a normal form: dates as YYYY-MM-DD, amounts as a
+	// decimal number followed by the ISO currency code when known, emails in
+	// lower case and IBANs without spaces
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+	// Source names the extractor that found the entity
+	Source string `json:"source"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventType represents the type of a domain event
+type EventType string
+
+const (
+	EventAccessGrantCreated   EventType = "access_grant.created"
+	EventAccessGrantExpiring  EventType = "access_grant.expiring"
+	EventDocumentUploaded     EventType = "document.uploaded"
+	EventJobApprovalRequested EventType = "job.approval_requested"
+	EventJobApproved          EventType = "job.approved"
+	EventJobRejected          EventType = "job.rejected"
+	EventJobPageCompleted     EventType = "job.page_completed"
+	EventJobCompleted         EventType = "job.completed"
+	EventJobExpired           EventType = "job.expired"
+	EventOrgInvitationCreated EventType = "organization.invitation_created"
+	EventResultCreated        EventType = "result.created"
+	EventResultUpdated        EventType = "result.updated"
+	EventUserRegistered       EventType = "user.registered"
+)
+
+// DomainEvent represents a change other systems may react to.
+// Events are stored in the outbox and delivered at least once, so
+// consumers should deduplicate on ID.
+type DomainEvent struct {
+	ID            uuid.UUID      `json:"id"`
+	Type          EventType      `json:"type"`
+	AggregateType string         `json:"aggregate_type"`
+	AggregateID   uuid.UUID      `json:"aggregate_id"`
+	UserID        *uuid.UUID     `json:"user_id,omitempty"`
+	Data          map[string]any `json:"data,omitempty"`
+	OccurredAt    time.Time      `json:"occurred_at"`
+	PublishedAt   *time.Time     `json:"published_at,omitempty"`
+	Attempts      int            `json:"attempts,omitempty"`
+	LastError     *string        `json:"last_error,omitempty"`
+}
+
+// NewDomainEvent creates a new domain event for an aggregate
+func NewDomainEvent(eventType EventType, aggregateType string, aggregateID uuid.UUID, userID *uuid.UUID, data map[string]any) *DomainEvent {
+	return &DomainEvent{
+		ID:            uuid.New(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		UserID:        userID,
+		Data:          data,
+		OccurredAt:    time.Now().UTC(),
+	}
+}
+
+// EventFilter selects outbox events for listing and replay
+type EventFilter struct {
+	IDs         []uuid.UUID
+	Type        EventType
+	AggregateID *uuid.UUID
+	Since       *time.Time
+	Until       *time.Time
+	Status      string // pending, published, failed
+	Limit       int
+}
+
+// EventReplayRequest represents a request to redeliver outbox events.
+// At least one selector must be set so a replay never targets the whole outbox by accident.
+type EventReplayRequest struct {
+	EventIDs    []uuid.UUID `json:"event_ids" validate:"omitempty,max=1000"`
+	Type        EventType   `json:"type"`
+	AggregateID *uuid.UUID  `json:"aggregate_id"`
+	Since       *time.Time  `json:"since"`
+	Until       *time.Time  `json:"until"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"mime/multipart"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentMetaEmailSender is the metadata key holding who emailed a document
+// to the user's inbound address
+const DocumentMetaEmailSender = "email_sender"
+
+// InboundEmailAddress is the secret address documents can be emailed to,
+// which is all it takes to add documents to the user's account
+type InboundEmailAddress struct {
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InboundMessage is an email received by the mail provider, with the
+// attachments to ingest
+type InboundMessage struct {
+	Recipients  []string
+	Sender      string
+	Subject     string
+	MessageID   string
+	Attachments []*multipart.FileHeader
+}
+
+// InboundEmail records an email received at a user's inbound address, with
+// the documents and jobs its attachments created and those it skipped
+type InboundEmail struct {
+	ID          uuid.UUID   `json:"id"`
+	UserID      uuid.UUID   `json:"user_id"`
+	MessageID   string      `json:"message_id"`
+	Sender      string      `json:"sender"`
+	Subject     string      `json:"subject"`
+	DocumentIDs []uuid.UUID `json:"document_ids"`
+	JobIDs      []uuid.UUID `json:"job_ids"`
+	// Skipped lists the attachments that were not ingested, each as
+	// "filename: reason"
+	Skipped   []string   `json:"skipped"`
+	RepliedAt *time.Time `json:"replied_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
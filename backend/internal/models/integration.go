@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HookEventResultCompleted is the REST hook event fired for each new result
+// of a completed job
+const HookEventResultCompleted = "result.completed"
+
+// IntegrationHook is a REST hook subscription of a no-code tool such as
+// Zapier or Make. New results are posted to TargetURL in order, starting
+// after the last one delivered.
+type IntegrationHook struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Event     string    `json:"event"`
+	TargetURL string    `json:"target_url"`
+	// Labels restricts the hook to documents with one of them, set when it is
+	// created with an API key restricted to labels
+	Labels          []string   `json:"labels,omitempty"`
+	CursorAt        time.Time  `json:"-"`
+	CursorID        uuid.UUID  `json:"-"`
+	Failures        int        `json:"failures"`
+	LastError       *string    `json:"last_error,omitempty"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"`
+	NextAttemptAt   time.Time  `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// HookSubscribeRequest represents the body of a REST hook subscription
+type HookSubscribeRequest struct {
+	TargetURL string `json:"target_url" validate:"required,url,max=2048"`
+	Event     string `json:"event" validate:"omitempty,oneof=result.completed"`
+}
+
+// IntegrationResult is a completed result flattened for no-code tools: the
+// document it belongs to, its text and the fields extracted from it
+type IntegrationResult struct {
+	ID           uuid.UUID         `json:"id"`
+	JobID        uuid.UUID         `json:"job_id"`
+	DocumentID   uuid.UUID         `json:"document_id"`
+	Filename     string            `json:"filename"`
+	DocumentType DocumentType      `json:"document_type,omitempty"`
+	Text         string            `json:"text"`
+	Confidence   float64           `json:"confidence"`
+	NumPages     int               `json:"num_pages"`
+	Fields       map[string]string `json:"fields"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// IntegrationResultPage is a page of the polling trigger. Cursor is passed
+// back as ?since= to get the results that completed after this page.
+type IntegrationResultPage struct {
+	Results []*IntegrationResult `json:"results"`
+	Cursor  string               `json:"cursor"`
+	HasMore bool                 `json:"has_more"`
+}
@@ -10,13 +10,48 @@ import (
 type JobStatus string
 
 const (
-	JobStatusPending    JobStatus = "pending"
-	JobStatusProcessing JobStatus = "processing"
-	JobStatusCompleted  JobStatus = "completed"
-	JobStatusFailed     JobStatus = "failed"
-	JobStatusCancelled  JobStatus = "cancelled"
+	// JobStatusAwaitingApproval holds a job the OCR policy requires an approver
+	// to accept before it is queued
+	JobStatusAwaitingApproval JobStatus = "awaiting_approval"
+	JobStatusPending          JobStatus = "pending"
+	JobStatusProcessing       JobStatus = "processing"
+	JobStatusCompleted        JobStatus = "completed"
+	JobStatusFailed           JobStatus = "failed"
+	JobStatusCancelled        JobStatus = "cancelled"
 )
 
+// jobTransitions lists the statuses a job may move to from each status. Failed,
+// cancelled and stuck processing jobs can be put back in the queue; completed
+// jobs are final.
+var jobTransitions = map[JobStatus][]JobStatus{
+	JobStatusAwaitingApproval: {JobStatusPending, JobStatusCancelled},
+	JobStatusPending:          {JobStatusProcessing, JobStatusFailed, JobStatusCancelled},
+	JobStatusProcessing:       {JobStatusCompleted, JobStatusFailed, JobStatusCancelled, JobStatusPending},
+	JobStatusFailed:           {JobStatusPending},
+	JobStatusCancelled:        {JobStatusPending},
+}
+
+// CanTransitionTo reports whether a job may move from this status to next
+func (s JobStatus) CanTransitionTo(next JobStatus) bool {
+	for _, allowed := range jobTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// JobStatusesBefore returns the statuses a job may move to next from
+func JobStatusesBefore(next JobStatus) []JobStatus {
+	var statuses []JobStatus
+	for _, from := range []JobStatus{JobStatusAwaitingApproval, JobStatusPending, JobStatusProcessing, JobStatusCompleted, JobStatusFailed, JobStatusCancelled} {
+		if from.CanTransitionTo(next) {
+			statuses = append(statuses, from)
+		}
+	}
+	return statuses
+}
+
 // OCRMode represents the OCR processing mode
 type OCRMode string
 
@@ -55,14 +90,29 @@ type OCRJob struct {
 	CompletedAt        *time.Time     `json:"completed_at,omitempty"`
 	ErrorMessage       *string        `json:"error_message,omitempty"`
 	Metadata           map[string]any `json:"metadata,omitempty"`
+	// Labels are copied from the document when the job is submitted
+	Labels []string `json:"labels"`
+	// ArchivedAt is set on jobs hidden from the default job listings
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	// DeletedAt is set on jobs in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// TrashedJob represents a soft-deleted job in the trash
+type TrashedJob struct {
+	*OCRJob
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
 }
 
 // OCRJobRequest represents the data needed to submit an OCR job
 type OCRJobRequest struct {
 	DocumentID     uuid.UUID      `json:"document_id" validate:"required"`
-	OCRMode        OCRMode        `json:"ocr_mode" validate:"required,oneof=document handwritten general figure"`
-	ResolutionMode ResolutionMode `json:"resolution_mode" validate:"required,oneof=tiny small base large gundam"`
+	OCRMode        OCRMode        `json:"ocr_mode" validate:"omitempty,oneof=document handwritten general figure"`
+	ResolutionMode ResolutionMode `json:"resolution_mode" validate:"omitempty,oneof=tiny small base large gundam"`
 	Priority       int            `json:"priority" validate:"min=0,max=10"`
+	// PageEvents requests a job.page_completed event as each page of a
+	// multi-page document finishes
+	PageEvents bool `json:"page_events"`
 }
 
 // JobSubmissionRequest represents internal job submission data
@@ -74,18 +124,94 @@ type JobSubmissionRequest struct {
 	Metadata       map[string]any
 }
 
+// JobMetaPageEvents is the job metadata key set on jobs that record a
+// job.page_completed event per page
+const JobMetaPageEvents = "page_events"
+
 // BatchOCRJobRequest represents the data needed to submit batch OCR jobs
 type BatchOCRJobRequest struct {
 	DocumentIDs    []uuid.UUID    `json:"document_ids" validate:"required,min=1,max=50"`
-	OCRMode        OCRMode        `json:"ocr_mode" validate:"required"`
-	ResolutionMode ResolutionMode `json:"resolution_mode" validate:"required"`
+	OCRMode        OCRMode        `json:"ocr_mode" validate:"omitempty,oneof=document handwritten general figure"`
+	ResolutionMode ResolutionMode `json:"resolution_mode" validate:"omitempty,oneof=tiny small base large gundam"`
+	PageEvents     bool           `json:"page_events"`
 }
 
 // JobListRequest represents pagination and filter parameters for jobs
 type JobListRequest struct {
-	Page     int       `json:"page" validate:"min=1"`
-	PerPage  int       `json:"per_page" validate:"min=1,max=100"`
-	Status   JobStatus `json:"status" validate:"omitempty,oneof=pending processing completed failed cancelled"`
-	SortBy   string    `json:"sort_by" validate:"omitempty,oneof=created_at status priority"`
-	SortDesc bool      `json:"sort_desc"`
+	Page     int       `json:"page" form:"page" validate:"min=1"`
+	PerPage  int       `json:"per_page" form:"per_page" validate:"min=1,max=100"`
+	Status   JobStatus `json:"status" form:"status" validate:"omitempty,oneof=awaiting_approval pending processing completed failed cancelled"`
+	SortBy   string    `json:"sort_by" form:"sort_by" validate:"omitempty,oneof=created_at status priority"`
+	SortDesc bool      `json:"sort_desc" form:"sort_desc"`
+	// Archived lists the archived jobs instead of the others
+	Archived bool `json:"archived" form:"archived"`
+}
+
+// StuckJobSweep reports the outcome of a sweep for jobs stuck in processing
+type StuckJobSweep struct {
+	Requeued []uuid.UUID `json:"requeued"`
+	Failed   []uuid.UUID `json:"failed"`
+}
+
+// JobFilter selects jobs for admin listing and bulk requeue or cancel
+type JobFilter struct {
+	Statuses      []JobStatus `json:"statuses,omitempty" validate:"omitempty,dive,oneof=awaiting_approval pending processing completed failed cancelled"`
+	UserID        *uuid.UUID  `json:"user_id,omitempty"`
+	DocumentID    *uuid.UUID  `json:"document_id,omitempty"`
+	CreatedBefore *time.Time  `json:"created_before,omitempty"`
+	StartedBefore *time.Time  `json:"started_before,omitempty"`
+	Limit         int         `json:"limit,omitempty" validate:"omitempty,min=1,max=1000"`
+}
+
+// QueueStats summarizes the job queue
+type QueueStats struct {
+	Counts             map[JobStatus]int `json:"counts"`
+	OldestPendingAt    *time.Time        `json:"oldest_pending_at,omitempty"`
+	OldestProcessingAt *time.Time        `json:"oldest_processing_at,omitempty"`
+	// Workers and Queued describe the worker pool of the API instance that
+	// answered; they are omitted when read from the database directly
+	Workers int `json:"workers,omitempty"`
+	Queued  int `json:"queued,omitempty"`
+}
+
+// JobBulkResult lists the jobs affected by a bulk requeue or cancel
+type JobBulkResult struct {
+	Jobs []uuid.UUID `json:"jobs"`
+}
+
+// JobEventType identifies a step in a job's lifecycle
+type JobEventType string
+
+const (
+	JobEventCreated   JobEventType = "created"
+	JobEventQueued    JobEventType = "queued"
+	JobEventStarted   JobEventType = "started"
+	JobEventProgress  JobEventType = "progress"
+	JobEventRetried   JobEventType = "retried"
+	JobEventCompleted JobEventType = "completed"
+	JobEventFailed    JobEventType = "failed"
+	JobEventCancelled JobEventType = "cancelled"
+)
+
+// JobEvent is an entry in a job's timeline, with the job's status, progress and
+// retry count after the event. Message holds the reason a job failed or was
+// cancelled, or the error of the attempt a retry follows.
+type JobEvent struct {
+	ID                 int64        `json:"id"`
+	JobID              uuid.UUID    `json:"job_id"`
+	DocumentID         uuid.UUID    `json:"document_id"`
+	UserID             uuid.UUID    `json:"-"`
+	Labels             []string     `json:"-"`
+	Type               JobEventType `json:"type"`
+	Status             JobStatus    `json:"status"`
+	ProgressPercentage int          `json:"progress_percentage"`
+	RetryCount         int          `json:"retry_count"`
+	Message            *string      `json:"message,omitempty"`
+	OccurredAt         time.Time    `json:"occurred_at"`
+}
+
+// JobEventNotification announces a new job event to listening API instances
+type JobEventNotification struct {
+	ID     int64     `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// JWTSigningKey is a key of the ring signing access and refresh tokens
+type JWTSigningKey struct {
+	ID        string `json:"kid"`
+	Algorithm string `json:"algorithm"`
+	// PrivateKey is the PKCS #8 key, PEM encoded and sealed
+	PrivateKey string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
+	// Current is set on the key signing new tokens
+	Current bool `json:"current"`
+}
+
+// JSONWebKey is the public part of a signing key, as published in the JWKS
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA keys
+	Modulus  string `json:"n,omitempty"`
+	Exponent string `json:"e,omitempty"`
+	// Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet lists the keys verifying tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxLabels is the most labels a document, job or API key can carry
+const MaxLabels = 20
+
+// labelPattern is the form of a normalized label, e.g. integration-x
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,49}$`)
+
+// NormalizeLabels trims and lowercases labels and drops empty and repeated
+// ones. It returns an error naming the first invalid label.
+func NormalizeLabels(labels []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		if !labelPattern.MatchString(label) {
+			return nil, fmt.Errorf("invalid label %q: use up to 50 letters, digits, '.', '_' or '-'", label)
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+
+	if len(normalized) > MaxLabels {
+		return nil, fmt.Errorf("at most %d labels are allowed", MaxLabels)
+	}
+
+	return normalized, nil
+}
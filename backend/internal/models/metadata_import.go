@@ -0,0 +1,52 @@
+package models
+
+import "github.com/google/uuid"
+
+// Columns of a metadata import CSV. Each row names a document by ID or by
+// filename; every other column is a custom field.
+const (
+	ImportColumnDocumentID = "document_id"
+	ImportColumnFilename   = "filename"
+	ImportColumnTags       = "tags"
+	ImportColumnFolder     = "folder"
+)
+
+// Document metadata keys set by metadata imports
+const (
+	DocumentMetaFolder = "folder"
+	DocumentMetaFields = "fields"
+)
+
+// MetadataImportMode decides what happens to a document's existing labels and
+// custom fields
+type MetadataImportMode string
+
+const (
+	// MetadataImportMerge adds the row's tags and custom fields to the document's
+	MetadataImportMerge MetadataImportMode = "merge"
+	// MetadataImportReplace replaces the document's labels and custom fields with the row's
+	MetadataImportReplace MetadataImportMode = "replace"
+)
+
+// MetadataImportRowError describes a row that could not be applied. Row is
+// the line number in the CSV, counting the header as line 1.
+type MetadataImportRowError struct {
+	Row        int        `json:"row"`
+	DocumentID *uuid.UUID `json:"document_id,omitempty"`
+	Filename   string     `json:"filename,omitempty"`
+	Error      string     `json:"error"`
+}
+
+// MetadataImportResult summarizes a metadata import. Errors holds the first
+// rows that failed; the full list is in the error report.
+type MetadataImportResult struct {
+	ID             uuid.UUID                 `json:"id"`
+	Mode           MetadataImportMode        `json:"mode"`
+	DryRun         bool                      `json:"dry_run"`
+	Rows           int                       `json:"rows"`
+	Updated        int                       `json:"updated"`
+	Unchanged      int                       `json:"unchanged"`
+	Failed         int                       `json:"failed"`
+	Errors         []*MetadataImportRowError `json:"errors"`
+	ErrorReportURL *string                   `json:"error_report_url,omitempty"`
+}
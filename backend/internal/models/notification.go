@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of notification channel
+const (
+	NotificationKindSlack = "slack"
+	NotificationKindTeams = "teams"
+)
+
+// Events a notification channel can be notified of
+const (
+	NotificationEventJobCompleted = "job.completed"
+	NotificationEventJobFailed    = "job.failed"
+	// NotificationEventRetentionWarning announces data a retention policy
+	// is about to delete
+	NotificationEventRetentionWarning = "retention.warning"
+)
+
+// NotificationChannel is a Slack or Microsoft Teams incoming webhook the user
+// is notified in when their jobs complete or fail, and of the data their
+// retention policies are about to delete. Jobs are notified in the
+// order they finished, starting after the last one notified.
+type NotificationChannel struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	WebhookURL string    `json:"-"`
+	Events     []string  `json:"events"`
+	// Labels restricts the channel to documents with one of them, set when it
+	// is created with an API key restricted to labels
+	Labels          []string   `json:"labels,omitempty"`
+	Enabled         bool       `json:"enabled"`
+	CursorAt        time.Time  `json:"-"`
+	CursorID        uuid.UUID  `json:"-"`
+	Failures        int        `json:"failures"`
+	LastError       *string    `json:"last_error,omitempty"`
+	LastDeliveredAt *time.Time `json:"last_delivered_at,omitempty"`
+	NextAttemptAt   time.Time  `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// NotificationChannelRequest represents the body of a request adding a
+// notification channel
+type NotificationChannelRequest struct {
+	Kind       string   `json:"kind" validate:"required,oneof=slack teams"`
+	Name       string   `json:"name" validate:"max=100"`
+	WebhookURL string   `json:"webhook_url" validate:"required,url,startswith=https://,max=2048"`
+	Events     []string `json:"events" validate:"omitempty,max=3,dive,oneof=job.completed job.failed retention.warning"`
+}
+
+// NotificationChannelUpdateRequest represents the body of a request changing a
+// notification channel; omitted fields are left as they are
+type NotificationChannelUpdateRequest struct {
+	Name       *string  `json:"name" validate:"omitempty,max=100"`
+	WebhookURL *string  `json:"webhook_url" validate:"omitempty,url,startswith=https://,max=2048"`
+	Events     []string `json:"events" validate:"omitempty,max=3,dive,oneof=job.completed job.failed retention.warning"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// Notice is a message about something other than a job posted to users'
+// notification channels, with facts as title, value pairs and an optional
+// link
+type Notice struct {
+	Title string
+	Facts [][2]string
+	URL   string
+}
+
+// JobNotification is a finished job as it is posted to a notification
+// channel: its document, outcome and result
+type JobNotification struct {
+	JobID        uuid.UUID
+	DocumentID   uuid.UUID
+	Filename     string
+	Status       JobStatus
+	ErrorMessage *string
+	ResultID     *uuid.UUID
+	Confidence   *float64
+	CompletedAt  time.Time
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OCRPolicy holds the OCR defaults and limits an organization's owners set for
+// its members, or admins set for users outside any organization
+type OCRPolicy struct {
+	// OrgID is the organization the policy belongs to, nil for the
+	// instance-wide policy
+	OrgID *uuid.UUID `json:"organization_id,omitempty"`
+	// DefaultOCRMode and DefaultResolution apply to jobs submitted without them
+	DefaultOCRMode    OCRMode        `json:"default_ocr_mode"`
+	DefaultResolution ResolutionMode `json:"default_resolution"`
+	// AllowedResolutions restricts the resolution modes members may use, for
+	// example to keep "gundam" off for GPU cost. Empty allows all of them.
+	AllowedResolutions []ResolutionMode `json:"allowed_resolutions"`
+	// MaxPagesPerJob rejects documents with more pages. Zero means no limit.
+	MaxPagesPerJob int `json:"max_pages_per_job"`
+	// ShareDuplicateUploads lets a member uploading a file another member of
+	// the organization already uploaded reuse the stored file and the
+	// teammate's latest result instead of storing and processing it again.
+	// Each member keeps their own document. In the instance-wide policy it is
+	// only the default of organizations that never set their own.
+	ShareDuplicateUploads bool `json:"share_duplicate_uploads"`
+	// Jobs over ApprovalPageThreshold pages (zero means no threshold) or at one
+	// of ApprovalResolutions wait for an approver before they are queued
+	ApprovalPageThreshold int              `json:"approval_page_threshold"`
+	ApprovalResolutions   []ResolutionMode `json:"approval_resolutions"`
+	// Approvers may approve or reject held jobs. Empty lets every admin, or
+	// every owner of the organization, do so.
+	Approvers []uuid.UUID `json:"approvers"`
+	UpdatedAt *time.Time  `json:"updated_at,omitempty"`
+	UpdatedBy *uuid.UUID  `json:"updated_by,omitempty"`
+}
+
+// DefaultOCRPolicy returns the policy of an organization that never set one
+func DefaultOCRPolicy() *OCRPolicy {
+	return &OCRPolicy{
+		DefaultOCRMode:      OCRModeDocument,
+		DefaultResolution:   ResolutionBase,
+		AllowedResolutions:  []ResolutionMode{},
+		ApprovalResolutions: []ResolutionMode{},
+		Approvers:           []uuid.UUID{},
+	}
+}
+
+// AllowsResolution reports whether members may submit jobs with the resolution mode
+func (p *OCRPolicy) AllowsResolution(mode ResolutionMode) bool {
+	if len(p.AllowedResolutions) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowedResolutions {
+		if allowed == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ApprovalReason returns why a job at the resolution on a document with the
+// given number of pages needs approval, or an empty string when it does not
+func (p *OCRPolicy) ApprovalReason(pages int, resolution ResolutionMode) string {
+	if p.ApprovalPageThreshold > 0 && pages > p.ApprovalPageThreshold {
+		return fmt.Sprintf("document has %d pages, more than the %d pages jobs may have without approval", pages, p.ApprovalPageThreshold)
+	}
+	for _, mode := range p.ApprovalResolutions {
+		if mode == resolution {
+			return fmt.Sprintf("resolution %q requires approval", resolution)
+		}
+	}
+	return ""
+}
+
+// IsApprover reports whether the user may approve or reject jobs held by the
+// instance-wide policy
+func (p *OCRPolicy) IsApprover(user *User) bool {
+	if len(p.Approvers) == 0 {
+		return user.IsAdmin()
+	}
+	for _, id := range p.Approvers {
+		if id == user.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateOCRPolicyRequest represents a partial update of the OCR policy
+type UpdateOCRPolicyRequest struct {
+	DefaultOCRMode        *OCRMode          `json:"default_ocr_mode" validate:"omitempty,oneof=document handwritten general figure"`
+	DefaultResolution     *ResolutionMode   `json:"default_resolution" validate:"omitempty,oneof=tiny small base large gundam"`
+	AllowedResolutions    *[]ResolutionMode `json:"allowed_resolutions" validate:"omitempty,dive,oneof=tiny small base large gundam"`
+	MaxPagesPerJob        *int              `json:"max_pages_per_job" validate:"omitempty,min=0,max=100000"`
+	ShareDuplicateUploads *bool             `json:"share_duplicate_uploads"`
+	ApprovalPageThreshold *int              `json:"approval_page_threshold" validate:"omitempty,min=0,max=100000"`
+	ApprovalResolutions   *[]ResolutionMode `json:"approval_resolutions" validate:"omitempty,dive,oneof=tiny small base large gundam"`
+	Approvers             *[]uuid.UUID      `json:"approvers" validate:"omitempty,max=100"`
+}
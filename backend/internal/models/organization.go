@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrgRole is a member's role in an organization
+type OrgRole string
+
+const (
+	// OrgRoleOwner manages the organization, its members and invitations
+	OrgRoleOwner OrgRole = "owner"
+	// OrgRoleMember shares documents with the organization
+	OrgRoleMember OrgRole = "member"
+	// OrgRoleViewer reads the organization's documents and results
+	OrgRoleViewer OrgRole = "viewer"
+)
+
+// CanShare reports whether the role may share documents with the organization
+func (r OrgRole) CanShare() bool {
+	return r == OrgRoleOwner || r == OrgRoleMember
+}
+
+// Rank orders roles by what they allow, owners highest
+func (r OrgRole) Rank() int {
+	switch r {
+	case OrgRoleOwner:
+		return 3
+	case OrgRoleMember:
+		return 2
+	case OrgRoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Organization is a team sharing scanned documents and their results
+type Organization struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// Role is the requesting user's role in the organization
+	Role        OrgRole `json:"role,omitempty"`
+	MemberCount int     `json:"member_count"`
+}
+
+// OrgMember is a user's membership of an organization
+type OrgMember struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	Name     string    `json:"name"`
+	Role     OrgRole   `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// OrgInvitation invites the user with an email to join an organization. It
+// is pending until accepted, declined, revoked or expired.
+type OrgInvitation struct {
+	ID               uuid.UUID  `json:"id"`
+	OrganizationID   uuid.UUID  `json:"organization_id"`
+	OrganizationName string     `json:"organization_name,omitempty"`
+	Email            string     `json:"email"`
+	Role             OrgRole    `json:"role"`
+	InvitedBy        *uuid.UUID `json:"invited_by,omitempty"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	AcceptedAt       *time.Time `json:"accepted_at,omitempty"`
+	DeclinedAt       *time.Time `json:"declined_at,omitempty"`
+}
+
+// Pending reports whether the invitation can still be accepted at the given time
+func (i *OrgInvitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.DeclinedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// OrganizationDetail is an organization with its members
+type OrganizationDetail struct {
+	Organization
+	Members []*OrgMember `json:"members"`
+}
+
+// OrgCreateRequest represents the data needed to create an organization
+type OrgCreateRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=255"`
+}
+
+// OrgInviteRequest represents the data needed to invite a user to an organization
+type OrgInviteRequest struct {
+	Email string  `json:"email" validate:"required,email"`
+	Role  OrgRole `json:"role" validate:"required,oneof=owner member viewer"`
+}
+
+// OrgMemberUpdateRequest represents a change of a member's role
+type OrgMemberUpdateRequest struct {
+	Role OrgRole `json:"role" validate:"required,oneof=owner member viewer"`
+}
+
+// OrgShareRequest names documents to share with an organization
+type OrgShareRequest struct {
+	DocumentIDs []uuid.UUID `json:"document_ids" validate:"required,min=1,max=100"`
+}
+
+// OrgDocument is a document shared with an organization, with its latest
+// result if it has one
+type OrgDocument struct {
+	Document *Document  `json:"document"`
+	Result   *OCRResult `json:"result,omitempty"`
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Permission allows an action on a resource, written resource:action, such
+// as documents:read or jobs:write. resource:* allows every action on the
+// resource and * allows everything.
+type Permission string
+
+// Resource is a part of the API guarded by permissions, one per route group
+type Resource string
+
+const (
+	ResourceDocuments Resource = "documents"
+	ResourceJobs      Resource = "jobs"
+	ResourceResults   Resource = "results"
+	ResourceGrants    Resource = "grants"
+	ResourceOrgs      Resource = "orgs"
+	ResourceUsage     Resource = "usage"
+	ResourceBilling   Resource = "billing"
+	ResourceSettings  Resource = "settings"
+	ResourceAPIKeys   Resource = "api_keys"
+	ResourceAdmin     Resource = "admin"
+)
+
+// Actions on resources; reads are safe requests (GET, HEAD), writes all others
+const (
+	ActionRead  = "read"
+	ActionWrite = "write"
+)
+
+// PermissionAll allows everything
+const PermissionAll Permission = "*"
+
+// resources are the resources permissions may name
+var resources = []Resource{
+	ResourceDocuments,
+	ResourceJobs,
+	ResourceResults,
+	ResourceGrants,
+	ResourceOrgs,
+	ResourceUsage,
+	ResourceBilling,
+	ResourceSettings,
+	ResourceAPIKeys,
+	ResourceAdmin,
+}
+
+// RolePermissions is the permission matrix: what users of each role may do.
+// API keys hold the permissions of their user's role, narrowed to their own
+// permissions when they have any.
+var RolePermissions = map[UserRole][]Permission{
+	UserRoleUser: {
+		"documents:*",
+		"jobs:*",
+		"results:*",
+		"grants:*",
+		"orgs:*",
+		"usage:read",
+		"billing:*",
+		"settings:*",
+		"api_keys:*",
+	},
+	UserRoleAdmin: {PermissionAll},
+}
+
+// NewPermission returns the permission for an action on a resource
+func NewPermission(resource Resource, action string) Permission {
+	return Permission(string(resource) + ":" + action)
+}
+
+// Validate checks that the permission names a known resource and action
+func (p Permission) Validate() error {
+	if p == PermissionAll {
+		return nil
+	}
+	resource, action, ok := strings.Cut(string(p), ":")
+	if !ok || !slices.Contains(resources, Resource(resource)) {
+		return fmt.Errorf("unknown permission %q", p)
+	}
+	if action != ActionRead && action != ActionWrite && action != "*" {
+		return fmt.Errorf("unknown permission %q", p)
+	}
+	return nil
+}
+
+// Allows reports whether holding p allows the required permission
+func (p Permission) Allows(required Permission) bool {
+	if p == PermissionAll || p == required {
+		return true
+	}
+	resource, action, _ := strings.Cut(string(p), ":")
+	return action == "*" && strings.HasPrefix(string(required), resource+":")
+}
+
+// HasPermission reports whether any of the held permissions allows the
+// required one
+func HasPermission(held []Permission, required Permission) bool {
+	for _, p := range held {
+		if p.Allows(required) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DocumentQuality is how well a document could be recognized, with what the
+// owner can do to improve it. The score runs from 0 to 100 and combines the
+// OCR confidence with the resolution, sharpness and skew of image documents.
+type DocumentQuality struct {
+	Score              int     `json:"score"`
+	Confidence         float64 `json:"confidence"`
+	LowConfidenceWords float64 `json:"low_confidence_words"`
+	// Image metrics are only measured for image documents
+	DPI          *float64            `json:"dpi,omitempty"`
+	DPIEstimated bool                `json:"dpi_estimated,omitempty"`
+	Sharpness    *float64            `json:"sharpness,omitempty"`
+	SkewDegrees  *float64            `json:"skew_degrees,omitempty"`
+	Suggestions  []QualitySuggestion `json:"suggestions"`
+	JobID        uuid.UUID           `json:"job_id"`
+	AssessedAt   time.Time           `json:"assessed_at"`
+}
+
+// QualitySuggestion is an action likely to improve recognition of a document
+type QualitySuggestion struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Quality suggestion codes
+const (
+	SuggestRescanHigherDPI  = "rescan_higher_dpi"
+	SuggestRescanSharper    = "rescan_sharper"
+	SuggestStraighten       = "straighten_page"
+	SuggestHandwrittenMode  = "use_handwritten_mode"
+	SuggestLargerResolution = "use_larger_resolution"
+)
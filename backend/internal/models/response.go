@@ -24,6 +24,21 @@ type ErrorDetail struct {
 	Details []ValidationError `json:"details,omitempty"`
 }
 
+// ProblemDetails is an RFC 7807 error body, sent instead of ErrorResponse to
+// clients that accept application/problem+json. Code and Errors carry the
+// ErrorDetail fields as extension members.
+type ProblemDetails struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      string            `json:"code"`
+	Errors    []ValidationError `json:"errors,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
 // ValidationError represents a field validation error
 type ValidationError struct {
 	Field   string `json:"field"`
@@ -36,6 +51,12 @@ type PaginatedResponse struct {
 	Pagination Pagination  `json:"pagination"`
 }
 
+// PageRequest represents the page and per_page query parameters of a list
+type PageRequest struct {
+	Page    int `json:"page" form:"page" validate:"min=1"`
+	PerPage int `json:"per_page" form:"per_page" validate:"min=1,max=100"`
+}
+
 // Pagination contains pagination metadata
 type Pagination struct {
 	Page       int  `json:"page"`
@@ -60,7 +81,7 @@ func NewSuccessResponse(data interface{}, message string) APIResponse {
 		Success:   true,
 		Data:      data,
 		Message:   message,
-		Timestamp: time.Now(),
+		Timestamp: time.Now().UTC(),
 	}
 }
 
@@ -73,6 +94,6 @@ func NewErrorResponse(code, message string, details []ValidationError) ErrorResp
 			Message: message,
 			Details: details,
 		},
-		Timestamp: time.Now(),
+		Timestamp: time.Now().UTC(),
 	}
 }
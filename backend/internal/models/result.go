@@ -17,7 +17,86 @@ type OCRResult struct {
 	ConfidenceScore  float64        `json:"confidence_score"`
 	ProcessingTimeMs int            `json:"processing_time_ms"`
 	NumPages         int            `json:"num_pages"`
-	CreatedAt        time.Time      `json:"created_at"`
+	// Version starts at 1 and increases whenever a reprocessed page is spliced in
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// ResultPage holds one version of the text of a single result page
+type ResultPage struct {
+	ResultID        uuid.UUID       `json:"result_id"`
+	PageNumber      int             `json:"page_number"`
+	Version         int             `json:"version"`
+	JobID           *uuid.UUID      `json:"job_id,omitempty"`
+	ResolutionMode  *ResolutionMode `json:"resolution_mode,omitempty"`
+	RawText         string          `json:"raw_text"`
+	MarkdownText    string          `json:"markdown_text"`
+	ConfidenceScore float64         `json:"confidence_score"`
+	// ProcessingTimeMs, Backend and Credits are what processing this version
+	// took; they are unknown for versions recorded before they were tracked
+	// and empty for pages copied from another result
+	ProcessingTimeMs *int    `json:"processing_time_ms,omitempty"`
+	Backend          *string `json:"backend,omitempty"`
+	Credits          *int    `json:"credits,omitempty"`
+	// ImageWidth and ImageHeight are the size in pixels of the page image the
+	// OCR service read, which the page's region boxes are measured in; unknown
+	// when the service did not report it
+	ImageWidth  *int      `json:"image_width,omitempty"`
+	ImageHeight *int      `json:"image_height,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Job metadata keys of page reprocessing jobs, naming the result and page they
+// patch and, for jobs redoing part of a page, the region of it
+const (
+	JobMetaReprocessResultID = "reprocess_result_id"
+	JobMetaReprocessPage     = "reprocess_page"
+	JobMetaReprocessRegion   = "reprocess_region"
+)
+
+// JobMetaSharedFromResultID is the job metadata key of a job completed with a
+// copy of a teammate's result instead of running OCR
+const JobMetaSharedFromResultID = "shared_from_result_id"
+
+// ReprocessPageRequest represents the options for reprocessing a single result page
+type ReprocessPageRequest struct {
+	// ResolutionMode defaults to the resolution of the original job
+	ResolutionMode ResolutionMode `json:"resolution_mode" validate:"omitempty,oneof=tiny small base large gundam"`
+	// BBox limits reprocessing to a region of the page, given as x0, y0, x1, y1
+	// in the pixels of the upright page image the regions are reported in
+	BBox []float64 `json:"bbox,omitempty" validate:"omitempty,len=4,dive,min=0"`
+}
+
+// DocumentReOCRRequest represents a request to run OCR again on one page of a
+// document, or a region of it, patching the document's latest result
+type DocumentReOCRRequest struct {
+	Page int `json:"page" validate:"required,min=1"`
+	ReprocessPageRequest
+}
+
+// RegionLevel represents the granularity of a bounding box region
+type RegionLevel string
+
+const (
+	RegionLevelWord RegionLevel = "word"
+	RegionLevelLine RegionLevel = "line"
+)
+
+// ResultRegion represents a bounding box of recognized text on a page.
+// Coordinates are in pixels of the page image as seen by the OCR engine.
+type ResultRegion struct {
+	ID         uuid.UUID   `json:"id"`
+	ResultID   uuid.UUID   `json:"result_id"`
+	PageNumber int         `json:"page_number"`
+	Level      RegionLevel `json:"level"`
+	Sequence   int         `json:"sequence"`
+	Text       string      `json:"text"`
+	X0         float64     `json:"x0"`
+	Y0         float64     `json:"y0"`
+	X1         float64     `json:"x1"`
+	Y1         float64     `json:"y1"`
+	Confidence float64     `json:"confidence"`
 }
 
 // ResultExportFormat represents the export format for OCR results
@@ -29,9 +108,66 @@ const (
 	ExportFormatText     ResultExportFormat = "text"
 	ExportFormatPDF      ResultExportFormat = "pdf"
 	ExportFormatDOCX     ResultExportFormat = "docx"
+	// ExportFormatSearchablePDF overlays the recognized text as an invisible layer on the original pages
+	ExportFormatSearchablePDF ResultExportFormat = "searchable_pdf"
 )
 
 // ResultExportRequest represents the data needed to export a result
 type ResultExportRequest struct {
-	Format ResultExportFormat `json:"format" validate:"required,oneof=markdown json text pdf docx"`
+	Format ResultExportFormat `json:"format" validate:"required,oneof=markdown json text pdf docx searchable_pdf"`
+}
+
+// PageCost is what processing one page version took, without its text
+type PageCost struct {
+	ResultID         uuid.UUID       `json:"result_id"`
+	PageNumber       int             `json:"page_number"`
+	Version          int             `json:"version"`
+	JobID            *uuid.UUID      `json:"job_id,omitempty"`
+	ResolutionMode   *ResolutionMode `json:"resolution_mode,omitempty"`
+	Backend          *string         `json:"backend,omitempty"`
+	ProcessingTimeMs *int            `json:"processing_time_ms,omitempty"`
+	Credits          *int            `json:"credits,omitempty"`
+	// Current is whether the version is the page's latest
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PageCostGroup sums the page versions sharing a resolution or backend. The
+// shares are of the total processing time and credits of all groups.
+type PageCostGroup struct {
+	Key                 string  `json:"key"`
+	Pages               int     `json:"pages"`
+	ProcessingTimeMs    int64   `json:"processing_time_ms"`
+	AvgProcessingTimeMs float64 `json:"avg_processing_time_ms"`
+	MaxProcessingTimeMs int     `json:"max_processing_time_ms"`
+	Credits             int     `json:"credits"`
+	TimeShare           float64 `json:"time_share"`
+	CreditShare         float64 `json:"credit_share"`
+}
+
+// ResultBreakdown shows what each page of a result cost, including versions
+// replaced by reprocessing, and which resolutions and backends dominate
+type ResultBreakdown struct {
+	ResultID         uuid.UUID        `json:"result_id"`
+	JobID            uuid.UUID        `json:"job_id"`
+	NumPages         int              `json:"num_pages"`
+	Pages            []*PageCost      `json:"pages"`
+	ProcessingTimeMs int64            `json:"processing_time_ms"`
+	Credits          int              `json:"credits"`
+	ByResolution     []*PageCostGroup `json:"by_resolution"`
+	ByBackend        []*PageCostGroup `json:"by_backend"`
+}
+
+// PageUsage shows which resolutions and backends dominate the cost and
+// latency of the pages a user had processed over a period, with the slowest
+// pages
+type PageUsage struct {
+	Since            time.Time        `json:"since"`
+	Until            time.Time        `json:"until"`
+	Pages            int              `json:"pages"`
+	ProcessingTimeMs int64            `json:"processing_time_ms"`
+	Credits          int              `json:"credits"`
+	ByResolution     []*PageCostGroup `json:"by_resolution"`
+	ByBackend        []*PageCostGroup `json:"by_backend"`
+	SlowestPages     []*PageCost      `json:"slowest_pages"`
 }
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionTarget is a kind of data a retention policy deletes
+type RetentionTarget string
+
+// Retention targets
+const (
+	// RetentionResultText deletes the text of results, keeping their
+	// metadata and extracted fields
+	RetentionResultText RetentionTarget = "result_text"
+	// RetentionDocuments moves documents to the trash
+	RetentionDocuments RetentionTarget = "documents"
+	// RetentionDataExports sets how long data export archives are kept
+	RetentionDataExports RetentionTarget = "data_exports"
+)
+
+// RetentionTargets lists the targets in the order they are shown
+var RetentionTargets = []RetentionTarget{RetentionResultText, RetentionDocuments, RetentionDataExports}
+
+// RetentionPolicy keeps a user's data of a target for a number of days; a
+// target without a policy is kept until the user deletes it
+type RetentionPolicy struct {
+	UserID uuid.UUID       `json:"-"`
+	Target RetentionTarget `json:"target"`
+	// Days is 0 for targets without a policy
+	Days int `json:"days"`
+	// WarnedUntil is the end of the period whose deletions were warned about
+	WarnedUntil *time.Time `json:"-"`
+	WarnedAt    *time.Time `json:"warned_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// RetentionPolicyRequest sets the days a target is kept; 0 removes the policy
+type RetentionPolicyRequest struct {
+	Target RetentionTarget `json:"target" validate:"required,oneof=result_text documents data_exports"`
+	Days   int             `json:"days" validate:"min=0,max=3650"`
+}
+
+// UpdateRetentionRequest represents the body of a request changing retention
+// policies; targets not listed are left as they are
+type UpdateRetentionRequest struct {
+	Policies []RetentionPolicyRequest `json:"policies" validate:"required,min=1,max=3,dive"`
+}
+
+// RetentionWarning announces the data of a target a user's policy will
+// delete, Count items from DeleteFrom on
+type RetentionWarning struct {
+	UserID     uuid.UUID
+	Target     RetentionTarget
+	Days       int
+	Count      int
+	DeleteFrom time.Time
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DuplicatePolicy decides what an upload of a file the user already has does
+type DuplicatePolicy string
+
+const (
+	// DuplicateReject refuses the upload
+	DuplicateReject DuplicatePolicy = "reject"
+	// DuplicateReturnExisting discards the upload and returns the existing
+	// document, recording the upload's filename as one of its aliases
+	DuplicateReturnExisting DuplicatePolicy = "return_existing"
+	// DuplicateCreateCopy stores the upload as a separate document
+	DuplicateCreateCopy DuplicatePolicy = "create_copy"
+	// DuplicateReplace moves the existing document to the trash and stores the
+	// upload in its place
+	DuplicateReplace DuplicatePolicy = "replace"
+)
+
+// duplicatePolicyAliases are the dedupe spellings of the policies
+var duplicatePolicyAliases = map[string]DuplicatePolicy{
+	"link":  DuplicateReturnExisting,
+	"allow": DuplicateCreateCopy,
+}
+
+// ParseDuplicatePolicy returns the policy named by an on_duplicate or dedupe
+// value, accepting hyphenated spellings. Unknown values give an invalid policy.
+func ParseDuplicatePolicy(value string) DuplicatePolicy {
+	value = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(value)), "-", "_")
+	if policy, ok := duplicatePolicyAliases[value]; ok {
+		return policy
+	}
+	return DuplicatePolicy(value)
+}
+
+// Valid reports whether the policy is known
+func (p DuplicatePolicy) Valid() bool {
+	switch p {
+	case DuplicateReject, DuplicateReturnExisting, DuplicateCreateCopy, DuplicateReplace:
+		return true
+	}
+	return false
+}
+
+// UserSettings represents a user's preferences
+type UserSettings struct {
+	UserID             uuid.UUID       `json:"user_id"`
+	DefaultOCRMode     OCRMode         `json:"default_ocr_mode"`
+	DefaultResolution  ResolutionMode  `json:"default_resolution"`
+	EmailNotifications bool            `json:"email_notifications"`
+	Language           string          `json:"language"`
+	Theme              string          `json:"theme"`
+	DuplicatePolicy    DuplicatePolicy `json:"duplicate_policy"`
+	Timezone           string          `json:"timezone"`
+	// JobTrashRetentionDays is how long deleted jobs are kept before being
+	// purged, 0 for the instance's retention
+	JobTrashRetentionDays int `json:"job_trash_retention_days"`
+	// ArchiveJobsAfterDays archives finished jobs once they are this old, 0
+	// to never archive them automatically
+	ArchiveJobsAfterDays int `json:"archive_jobs_after_days"`
+	// EmailOnFailure emails the user as soon as one of their jobs fails
+	EmailOnFailure bool `json:"email_on_failure"`
+	// EmailDigest emails the user a summary of the past day's jobs every day
+	// at DigestHour in their time zone
+	EmailDigest bool       `json:"email_digest"`
+	DigestHour  int        `json:"digest_hour"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// DefaultUserSettings returns the settings of a user who never changed them
+func DefaultUserSettings(userID uuid.UUID) *UserSettings {
+	return &UserSettings{
+		UserID:             userID,
+		DefaultOCRMode:     "document",
+		DefaultResolution:  "base",
+		EmailNotifications: true,
+		Language:           "en",
+		Theme:              "light",
+		DuplicatePolicy:    DuplicateReturnExisting,
+		Timezone:           "UTC",
+		EmailOnFailure:     true,
+		DigestHour:         8,
+	}
+}
+
+// Location returns the user's time zone for digest emails and exports. API
+// responses always use UTC.
+func (s *UserSettings) Location() *time.Location {
+	return TimezoneLocation(s.Timezone)
+}
+
+// TimezoneLocation returns the location of a user's time zone setting, UTC
+// when it cannot be loaded
+func TimezoneLocation(timezone string) *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// UpdateSettingsRequest represents a partial update of a user's settings
+type UpdateSettingsRequest struct {
+	DefaultOCRMode     *OCRMode         `json:"default_ocr_mode" validate:"omitempty,oneof=document handwritten general figure"`
+	DefaultResolution  *ResolutionMode  `json:"default_resolution" validate:"omitempty,oneof=tiny small base large gundam"`
+	EmailNotifications *bool            `json:"email_notifications"`
+	Language           *string          `json:"language" validate:"omitempty,min=2,max=10"`
+	Theme              *string          `json:"theme" validate:"omitempty,oneof=light dark system"`
+	DuplicatePolicy    *DuplicatePolicy `json:"duplicate_policy" validate:"omitempty,oneof=reject return_existing create_copy replace"`
+	Timezone           *string          `json:"timezone" validate:"omitempty,max=64"`
+	// JobTrashRetentionDays and ArchiveJobsAfterDays are reset by 0
+	JobTrashRetentionDays *int  `json:"job_trash_retention_days" validate:"omitempty,min=0,max=3650"`
+	ArchiveJobsAfterDays  *int  `json:"archive_jobs_after_days" validate:"omitempty,min=0,max=3650"`
+	EmailOnFailure        *bool `json:"email_on_failure"`
+	EmailDigest           *bool `json:"email_digest"`
+	DigestHour            *int  `json:"digest_hour" validate:"omitempty,min=0,max=23"`
+}
+
+// DuplicateInfo describes how an upload matching an existing document was handled
+type DuplicateInfo struct {
+	Policy              DuplicatePolicy `json:"policy"`
+	ExistingDocumentID  uuid.UUID       `json:"existing_document_id"`
+	ExistingDocumentURL string          `json:"existing_document_url"`
+	// Alias is the upload's filename, recorded on the existing document when
+	// it differs from the document's name
+	Alias string `json:"alias,omitempty"`
+}
+
+// SharedUploadInfo describes the teammate's upload of the same file that an
+// upload reused. ResultID and JobID are set when the teammate's result was
+// copied, in which case the document needs no processing.
+type SharedUploadInfo struct {
+	DocumentID uuid.UUID  `json:"document_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	UserName   string     `json:"user_name"`
+	ResultID   *uuid.UUID `json:"result_id,omitempty"`
+	JobID      *uuid.UUID `json:"job_id,omitempty"`
+}
+
+// UploadResponse is the document returned by an upload, with details on
+// duplicate handling when the file was already uploaded
+type UploadResponse struct {
+	*Document
+	Duplicate *DuplicateInfo    `json:"duplicate,omitempty"`
+	Shared    *SharedUploadInfo `json:"shared,omitempty"`
+}
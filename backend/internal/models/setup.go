@@ -0,0 +1,29 @@
+package models
+
+// Instance setting keys
+const (
+	SettingInstanceName     = "instance_name"
+	SettingStorageBackend   = "storage_backend"
+	SettingOCRServiceURL    = "ocr_service_url"
+	SettingSetupCompletedAt = "setup_completed_at"
+	SettingOCRPolicy        = "ocr_policy"
+)
+
+// StorageBackendLocal stores files on the local filesystem
+const StorageBackendLocal = "local"
+
+// SetupRequest represents the data submitted by the first-run setup wizard
+type SetupRequest struct {
+	AdminEmail     string `json:"admin_email" validate:"required,email"`
+	AdminPassword  string `json:"admin_password" validate:"required,min=8"`
+	AdminName      string `json:"admin_name" validate:"required,min=2"`
+	InstanceName   string `json:"instance_name" validate:"required,min=2,max=100"`
+	StorageBackend string `json:"storage_backend" validate:"required,oneof=local"`
+	OCRServiceURL  string `json:"ocr_service_url" validate:"required"`
+}
+
+// SetupStatus reports whether the instance still needs to be set up
+type SetupStatus struct {
+	SetupRequired bool   `json:"setup_required"`
+	InstanceName  string `json:"instance_name,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShareLink gives anyone holding its signed URL read-only access to a
+// document and its latest result until it expires or is revoked
+type ShareLink struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	DocumentID     uuid.UUID  `json:"document_id"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	AccessCount    int        `json:"access_count"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	// URL is the signed link, set when the link is created
+	URL string `json:"url,omitempty"`
+}
+
+// Active reports whether the link can be used at the given time
+func (l *ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// ShareLinkCreateRequest represents the data needed to share a document. The
+// link expires after ExpiresInHours, a week by default.
+type ShareLinkCreateRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" validate:"omitempty,min=1"`
+}
+
+// SharedDocument is what the holder of a share link sees: the document
+// without its storage details, its latest result and the branding of the
+// host the link was opened on
+type SharedDocument struct {
+	ID         uuid.UUID       `json:"id"`
+	Filename   string          `json:"filename"`
+	MimeType   string          `json:"mime_type"`
+	FileSize   int64           `json:"file_size"`
+	NumPages   int             `json:"num_pages"`
+	UploadedAt time.Time       `json:"uploaded_at"`
+	Result     *OCRResult      `json:"result,omitempty"`
+	Branding   *PublicBranding `json:"branding,omitempty"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrgSSOConfig connects an organization to its OpenID Connect identity
+// provider. Users signing in through it join the organization with the role
+// their IdP groups map to, or DefaultRole when none does.
+type OrgSSOConfig struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Issuer         string    `json:"issuer"`
+	ClientID       string    `json:"client_id"`
+	ClientSecret   string    `json:"-"`
+	// ClientSecretSealed tells whether ClientSecret, as stored, is sealed
+	ClientSecretSealed bool `json:"-"`
+	// EmailDomains lets users find the organization's IdP from their email,
+	// and bounds the emails it signs in, once verified
+	EmailDomains []SSODomain `json:"email_domains"`
+	// GroupClaim names the ID token claim listing the user's groups
+	GroupClaim  string             `json:"group_claim"`
+	RoleMapping map[string]OrgRole `json:"role_mapping"`
+	DefaultRole *OrgRole           `json:"default_role,omitempty"`
+	Enabled     bool               `json:"enabled"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// SSODomain is an email domain of an organization's SSO. It only counts once
+// the organization proves it controls it by publishing VerificationValue in
+// the VerificationRecord TXT record, and then belongs to that organization
+// alone.
+type SSODomain struct {
+	Domain             string     `json:"domain"`
+	VerificationRecord string     `json:"verification_record"`
+	VerificationValue  string     `json:"verification_value"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+}
+
+// Verified reports whether control of the domain has been proven
+func (d SSODomain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// OrgSSORequest represents the data needed to configure an organization's
+// SSO. The client secret may be left out to keep the stored one.
+type OrgSSORequest struct {
+	Issuer       string             `json:"issuer" validate:"required,url,startswith=https://"`
+	ClientID     string             `json:"client_id" validate:"required,max=255"`
+	ClientSecret string             `json:"client_secret" validate:"omitempty,max=2048"`
+	EmailDomains []string           `json:"email_domains" validate:"omitempty,max=20,dive,fqdn"`
+	GroupClaim   string             `json:"group_claim" validate:"omitempty,max=100"`
+	RoleMapping  map[string]OrgRole `json:"role_mapping" validate:"omitempty,max=100,dive,keys,min=1,max=255,endkeys,oneof=owner member viewer"`
+	DefaultRole  *OrgRole           `json:"default_role" validate:"omitempty,oneof=owner member viewer"`
+	Enabled      *bool              `json:"enabled"`
+}
+
+// SSOLogin is where to send a user to sign in with their organization's IdP
+type SSOLogin struct {
+	OrganizationID   uuid.UUID `json:"organization_id"`
+	AuthorizationURL string    `json:"authorization_url"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// JobStats sums up the jobs a user had finish over a period. Averages are
+// nil when no job they cover finished.
+type JobStats struct {
+	Since    time.Time `json:"since"`
+	Until    time.Time `json:"until"`
+	Timezone string    `json:"timezone"`
+	JobStatsTotals
+	ByResolution []*ResolutionJobStats `json:"by_resolution"`
+	// Daily has a day for every date of the period in the user's time zone,
+	// including days without jobs
+	Daily []*DailyJobStats `json:"daily"`
+}
+
+// JobStatsTotals counts finished jobs by outcome. FailureRate is the share
+// of completed and failed jobs that failed; cancelled jobs are left out.
+type JobStatsTotals struct {
+	Jobs                int      `json:"jobs"`
+	Completed           int      `json:"completed"`
+	Failed              int      `json:"failed"`
+	Cancelled           int      `json:"cancelled"`
+	FailureRate         float64  `json:"failure_rate"`
+	Pages               int64    `json:"pages"`
+	AverageConfidence   *float64 `json:"average_confidence,omitempty"`
+	AverageProcessingMs *float64 `json:"average_processing_ms,omitempty"`
+}
+
+// ResolutionJobStats sums up the jobs run at one resolution mode
+type ResolutionJobStats struct {
+	ResolutionMode ResolutionMode `json:"resolution_mode"`
+	JobStatsTotals
+}
+
+// DailyJobStats counts the jobs that finished on a date
+type DailyJobStats struct {
+	Date      string `json:"date"`
+	Jobs      int    `json:"jobs"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	Pages     int64  `json:"pages"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserStorageStats is the storage used by a user's documents, counting those
+// in the trash. Bytes sums the documents' sizes, so a file shared with other
+// documents counts once per document.
+type UserStorageStats struct {
+	UserID           uuid.UUID  `json:"user_id"`
+	Email            string     `json:"email"`
+	Documents        int        `json:"documents"`
+	TrashedDocuments int        `json:"trashed_documents"`
+	Files            int        `json:"files"`
+	Bytes            int64      `json:"bytes"`
+	OldestUploadedAt *time.Time `json:"oldest_uploaded_at,omitempty"`
+}
+
+// StorageTotals is the storage used by the instance. Documents and
+// DocumentBytes sum the documents; Files and Bytes are the document files
+// actually on disk, fewer when documents share content. Orphans are files on
+// disk no document refers to, which the janitor removes once they are older
+// than its grace period.
+type StorageTotals struct {
+	Documents     int   `json:"documents"`
+	DocumentBytes int64 `json:"document_bytes"`
+	Files         int   `json:"files"`
+	Bytes         int64 `json:"bytes"`
+	OrphanFiles   int   `json:"orphan_files"`
+	OrphanBytes   int64 `json:"orphan_bytes"`
+}
+
+// StoredFile is a document file on disk, relative to the storage root
+type StoredFile struct {
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// StorageStats is the storage used by the instance when it was last computed
+type StorageStats struct {
+	Totals      StorageTotals `json:"totals"`
+	OldestFiles []StoredFile  `json:"oldest_files"`
+	DurationMs  int64         `json:"duration_ms"`
+	ComputedAt  time.Time     `json:"computed_at"`
+}
+
+// StorageStatsResponse is the admin storage dashboard: the instance totals
+// and a page of the users using the most storage
+type StorageStatsResponse struct {
+	StorageStats
+	Users PaginatedResponse `json:"users"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// SystemStats shows how the instance is doing over a recent window, for an
+// operations dashboard
+type SystemStats struct {
+	Since      time.Time     `json:"since"`
+	Until      time.Time     `json:"until"`
+	Throughput JobThroughput `json:"throughput"`
+	QueueWait  LatencyStats  `json:"queue_wait_ms"`
+	OCRLatency LatencyStats  `json:"ocr_latency_ms"`
+	Queue      *QueueStats   `json:"queue"`
+	API        APIErrorStats `json:"api"`
+	// ActiveUsers counts the users who uploaded documents or submitted jobs
+	ActiveUsers int `json:"active_users"`
+}
+
+// JobThroughput counts the jobs of all users that finished in a window.
+// FailureRate is the share of completed and failed jobs that failed.
+type JobThroughput struct {
+	Completed      int     `json:"completed"`
+	Failed         int     `json:"failed"`
+	Cancelled      int     `json:"cancelled"`
+	Pages          int64   `json:"pages"`
+	JobsPerMinute  float64 `json:"jobs_per_minute"`
+	PagesPerMinute float64 `json:"pages_per_minute"`
+	FailureRate    float64 `json:"failure_rate"`
+}
+
+// LatencyStats are percentiles of durations in milliseconds, nil when
+// nothing was measured
+type LatencyStats struct {
+	Count int      `json:"count"`
+	P50   *float64 `json:"p50,omitempty"`
+	P90   *float64 `json:"p90,omitempty"`
+	P99   *float64 `json:"p99,omitempty"`
+}
+
+// APIErrorStats counts the API requests the answering instance served and
+// its error responses by code. Since is later than the window's start when
+// the instance started within it.
+type APIErrorStats struct {
+	Since     time.Time         `json:"since"`
+	Requests  int               `json:"requests"`
+	Errors    int               `json:"errors"`
+	ErrorRate float64           `json:"error_rate"`
+	ByCode    []*ErrorCodeCount `json:"by_code"`
+}
+
+// ErrorCodeCount is how often an error code was answered, and its share of
+// all requests
+type ErrorCodeCount struct {
+	Code  string  `json:"code"`
+	Count int     `json:"count"`
+	Rate  float64 `json:"rate"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExtractionTemplate is a user's set of rules for pulling named fields, such
+// as an invoice number or a total, out of the results of a type of document
+type ExtractionTemplate struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	// DocumentType limits the template to documents classified as that type;
+	// templates without one apply to any document
+	DocumentType DocumentType    `json:"document_type,omitempty"`
+	Fields       []TemplateField `json:"fields"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// TemplateField is how to find one field. Each of Zone, Anchor and Pattern
+// narrows down the text the next one looks at: the zone picks the text inside
+// a box of a page, the anchor the text after a label such as "Invoice No", and
+// the pattern the first match of a regular expression, or its first group.
+// A field needs at least one of them.
+type TemplateField struct {
+	Name    string        `json:"name" validate:"required,max=64"`
+	Zone    *TemplateZone `json:"zone,omitempty"`
+	Anchor  string        `json:"anchor,omitempty" validate:"max=200"`
+	Pattern string        `json:"pattern,omitempty" validate:"max=500"`
+}
+
+// TemplateZone is a box on a page, given as x0, y0, x1, y1 in the pixels the
+// result's regions are reported in
+type TemplateZone struct {
+	Page int       `json:"page" validate:"min=1"`
+	BBox []float64 `json:"bbox" validate:"len=4,dive,min=0"`
+}
+
+// TemplateRequest represents the body of a request creating or replacing an
+// extraction template
+type TemplateRequest struct {
+	Name         string          `json:"name" validate:"required,max=100"`
+	DocumentType DocumentType    `json:"document_type" validate:"omitempty,oneof=invoice receipt contract id_document other"`
+	Fields       []TemplateField `json:"fields" validate:"required,min=1,max=50,dive"`
+}
+
+// ResultJSONFields is the key of a result's JSON data holding the fields
+// extracted from it with the best matching template
+const ResultJSONFields = "fields"
+
+// ResultFields represents the fields extracted from a result. Fields the
+// template's rules found nothing for are listed in Missing.
+type ResultFields struct {
+	TemplateID   uuid.UUID         `json:"template_id"`
+	TemplateName string            `json:"template_name"`
+	Fields       map[string]string `json:"fields"`
+	Missing      []string          `json:"missing"`
+}
+
+// FieldsExportRequest represents the body of a request exporting the fields
+// extracted from many documents as a spreadsheet. Every filter is optional:
+// Labels keeps documents with any of the labels (tags), From and Until bound
+// the upload time and DocumentType keeps documents classified as that type.
+type FieldsExportRequest struct {
+	Format       string       `json:"format" validate:"omitempty,oneof=csv xlsx"`
+	Labels       []string     `json:"labels" validate:"omitempty,max=20"`
+	From         *time.Time   `json:"from"`
+	Until        *time.Time   `json:"until"`
+	DocumentType DocumentType `json:"document_type" validate:"omitempty,oneof=invoice receipt contract id_document other"`
+}
+
+// DocumentFields is a document with the fields extracted from its latest
+// result; Fields is nil when the document has no result or no template
+// found any field in it
+type DocumentFields struct {
+	DocumentID   uuid.UUID
+	Filename     string
+	UploadedAt   time.Time
+	DocumentType DocumentType
+	Fields       *ResultFields
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageTotals sums the usage of a month, for the whole instance or one
+// organization. Latencies are nil when no job ran.
+type UsageTotals struct {
+	Jobs              int64    `json:"jobs"`
+	FailedJobs        int64    `json:"failed_jobs"`
+	Pages             int64    `json:"pages"`
+	DocumentsAdded    int64    `json:"documents_added"`
+	StorageAddedBytes int64    `json:"storage_added_bytes"`
+	StorageTotalBytes int64    `json:"storage_total_bytes"`
+	P95QueueWaitMs    *float64 `json:"p95_queue_wait_ms,omitempty"`
+	P95ProcessingMs   *float64 `json:"p95_processing_ms,omitempty"`
+}
+
+// OrgUsage is the usage of one organization. Documents outside any
+// organization are reported with a nil OrgID.
+type OrgUsage struct {
+	OrgID   *uuid.UUID `json:"org_id"`
+	OrgName string     `json:"org_name,omitempty"`
+	UsageTotals
+}
+
+// UsageReport is the usage of the instance over one calendar month (UTC),
+// generated once the month is over so operators can plan GPU and storage
+// capacity. Orgs is only set when the full report is read.
+type UsageReport struct {
+	ID             uuid.UUID   `json:"id"`
+	PeriodStart    time.Time   `json:"period_start"`
+	PeriodEnd      time.Time   `json:"period_end"`
+	Totals         UsageTotals `json:"totals"`
+	PeakQueueDepth int         `json:"peak_queue_depth"`
+	PeakQueueAt    *time.Time  `json:"peak_queue_at,omitempty"`
+	Orgs           []*OrgUsage `json:"orgs,omitempty"`
+	FilePath       string      `json:"-"`
+	GeneratedAt    time.Time   `json:"generated_at"`
+}
+
+// UsageReportGenerateRequest represents a request to generate, or generate
+// again, the report of a month that is over, as YYYY-MM
+type UsageReportGenerateRequest struct {
+	Month string `json:"month" validate:"required,datetime=2006-01"`
+}
@@ -6,14 +6,31 @@ import (
 	"github.com/google/uuid"
 )
 
+// UserRole represents the role of a user
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
 // User represents a user in the system
 type User struct {
 	ID           uuid.UUID `json:"id"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"` // Never send password hash in JSON
 	Name         string    `json:"name"`
+	Role         UserRole  `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	// DeletionScheduledAt is when the account will be deleted, if its
+	// deletion was requested
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+// IsAdmin reports whether the user has administrative access
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
 }
 
 // UserRegistration represents the data needed for user registration
@@ -29,20 +46,47 @@ type UserLogin struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// UserUpdateRequest represents the changes a user makes to their profile.
+// Changing the email requires the current password.
+type UserUpdateRequest struct {
+	Name            *string `json:"name" validate:"omitempty,min=2,max=255"`
+	Email           *string `json:"email" validate:"omitempty,email,max=255"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// AccountDeletionRequest confirms the deletion of the user's account
+type AccountDeletionRequest struct {
+	Password string `json:"password"`
+}
+
 // UserResponse represents the user data returned to the client
 type UserResponse struct {
 	ID        uuid.UUID `json:"id"`
 	Email     string    `json:"email"`
 	Name      string    `json:"name"`
+	Role      UserRole  `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+	// DeletionScheduledAt is set while the account awaits deletion
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
 }
 
 // ToResponse converts a User to UserResponse (without sensitive data)
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Name:      u.Name,
-		CreatedAt: u.CreatedAt,
+		ID:                  u.ID,
+		Email:               u.Email,
+		Name:                u.Name,
+		Role:                u.Role,
+		CreatedAt:           u.CreatedAt,
+		DeletionScheduledAt: u.DeletionScheduledAt,
 	}
 }
+
+// AccountExport is the personal data of a user: their profile, the
+// organizations they belong to and their documents, trashed ones included
+type AccountExport struct {
+	User          UserResponse    `json:"user"`
+	Organizations []*Organization `json:"organizations"`
+	Documents     []Document      `json:"documents"`
+	ExportedAt    time.Time       `json:"exported_at"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WarehouseSchemaVersion is the version of the exported record layout.
+// Bump it whenever fields are renamed or removed so BI pipelines can branch on it.
+const WarehouseSchemaVersion = 1
+
+// WarehouseExportCursor tracks the progress of an incremental warehouse export
+type WarehouseExportCursor struct {
+	Name          string     `json:"name"`
+	SchemaVersion int        `json:"schema_version"`
+	CursorAt      *time.Time `json:"cursor_at,omitempty"`
+	CursorID      *uuid.UUID `json:"cursor_id,omitempty"`
+	RowsExported  int64      `json:"rows_exported"`
+	LastFile      *string    `json:"last_file,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+}
+
+// WarehouseJobRecord is the metadata of a finished job and its result.
+// It intentionally excludes any recognized text.
+type WarehouseJobRecord struct {
+	SchemaVersion    int            `json:"schema_version"`
+	JobID            uuid.UUID      `json:"job_id"`
+	DocumentID       uuid.UUID      `json:"document_id"`
+	UserID           uuid.UUID      `json:"user_id"`
+	Status           JobStatus      `json:"status"`
+	OCRMode          OCRMode        `json:"ocr_mode"`
+	ResolutionMode   ResolutionMode `json:"resolution_mode"`
+	Priority         int            `json:"priority"`
+	RetryCount       int            `json:"retry_count"`
+	CreatedAt        time.Time      `json:"created_at"`
+	StartedAt        *time.Time     `json:"started_at,omitempty"`
+	CompletedAt      time.Time      `json:"completed_at"`
+	MimeType         *string        `json:"mime_type,omitempty"`
+	FileSize         *int64         `json:"file_size,omitempty"`
+	ResultID         *uuid.UUID     `json:"result_id,omitempty"`
+	ConfidenceScore  *float64       `json:"confidence_score,omitempty"`
+	ProcessingTimeMs *int           `json:"processing_time_ms,omitempty"`
+	NumPages         *int           `json:"num_pages,omitempty"`
+	// Page metrics cover the page versions the job processed, where recorded
+	Backends      []string `json:"backends,omitempty"`
+	PageCredits   *int     `json:"page_credits,omitempty"`
+	MaxPageTimeMs *int     `json:"max_page_time_ms,omitempty"`
+	AvgPageTimeMs *float64 `json:"avg_page_time_ms,omitempty"`
+}
+
+// WarehouseExportRun summarizes a single export run
+type WarehouseExportRun struct {
+	Files        []string  `json:"files"`
+	RowsExported int       `json:"rows_exported"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
@@ -0,0 +1,221 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/metrics"
+)
+
+// ErrCircuitOpen is returned instead of calling the OCR service while the
+// circuit breaker is open
+var ErrCircuitOpen = errors.New("OCR service unavailable: circuit breaker open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+// Circuit breaker states
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails requests fast until the cooldown elapses
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe request through to test the service
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+var (
+	breakerState = metrics.NewGaugeVec(
+		"visekai_ocr_circuit_state",
+		"State of the OCR service circuit breaker (0 closed, 1 half open, 2 open).",
+	)
+	breakerTransitions = metrics.NewCounterVec(
+		"visekai_ocr_circuit_transitions_total",
+		"Transitions of the OCR service circuit breaker, by new state.",
+		"state",
+	)
+)
+
+// stateValues maps breaker states to the value of the state gauge
+var stateValues = map[BreakerState]float64{
+	BreakerClosed:   0,
+	BreakerHalfOpen: 1,
+	BreakerOpen:     2,
+}
+
+// BreakerConfig configures a circuit breaker
+type BreakerConfig struct {
+	// Threshold is the number of consecutive failures opening the circuit;
+	// 0 disables the breaker
+	Threshold int
+	// Cooldown is how long the circuit stays open before a probe, doubled
+	// after every failed probe up to MaxCooldown
+	Cooldown    time.Duration
+	MaxCooldown time.Duration
+}
+
+// Breaker is a circuit breaker guarding the OCR service. After Threshold
+// consecutive failures it opens and fails requests fast; once the cooldown
+// elapses a single probe request is let through, closing the circuit when it
+// succeeds and reopening it for twice as long when it fails.
+type Breaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	state    BreakerState
+	failures int
+	cooldown time.Duration
+	// openUntil is when an open circuit lets a probe through
+	openUntil time.Time
+}
+
+// NewBreaker creates a new closed circuit breaker
+func NewBreaker(config BreakerConfig) *Breaker {
+	if config.MaxCooldown < config.Cooldown {
+		config.MaxCooldown = config.Cooldown
+	}
+	breakerState.Set(stateValues[BreakerClosed])
+	return &Breaker{
+		config:   config,
+		state:    BreakerClosed,
+		cooldown: config.Cooldown,
+	}
+}
+
+// Allow reports whether a request may be sent, returning ErrCircuitOpen when
+// it may not. Every allowed request must be followed by Record or Abandon.
+func (b *Breaker) Allow() error {
+	if b == nil || b.config.Threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return ErrCircuitOpen
+		}
+		b.transition(BreakerHalfOpen)
+		return nil
+	case BreakerHalfOpen:
+		// A probe is in flight
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// Record records the outcome of an allowed request
+func (b *Breaker) Record(success bool) {
+	if b == nil || b.config.Threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.failures = 0
+		b.cooldown = b.config.Cooldown
+		if b.state != BreakerClosed {
+			b.transition(BreakerClosed)
+		}
+		return
+	}
+
+	switch b.state {
+	case BreakerHalfOpen:
+		b.cooldown = min(b.cooldown*2, b.config.MaxCooldown)
+		b.open()
+	case BreakerClosed:
+		b.failures++
+		if b.failures >= b.config.Threshold {
+			b.open()
+		}
+	}
+}
+
+// Abandon records that an allowed request ended, for example because it was
+// cancelled, without telling whether the service is up. An abandoned probe
+// lets the next request probe instead.
+func (b *Breaker) Abandon() {
+	if b == nil || b.config.Threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen {
+		b.state = BreakerOpen
+		b.openUntil = time.Now()
+		breakerState.Set(stateValues[BreakerOpen])
+	}
+}
+
+// State returns the state of the breaker and, when it is not closed, when the
+// next probe may be sent
+func (b *Breaker) State() (BreakerState, time.Time) {
+	if b == nil || b.config.Threshold <= 0 {
+		return BreakerClosed, time.Time{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		return b.state, b.openUntil
+	case BreakerHalfOpen:
+		// Check again once the probe had time to finish
+		return b.state, time.Now().Add(b.config.Cooldown)
+	}
+	return b.state, time.Time{}
+}
+
+// Wait returns how long callers should wait before sending requests, 0 while
+// the circuit is closed or a probe may be sent
+func (b *Breaker) Wait() time.Duration {
+	state, retryAt := b.State()
+	if state == BreakerClosed {
+		return 0
+	}
+	return max(time.Until(retryAt), 0)
+}
+
+// Check reports an error while the circuit is not closed, so health checks
+// show the breaker's state
+func (b *Breaker) Check(context.Context) error {
+	state, retryAt := b.State()
+	if state == BreakerClosed {
+		return nil
+	}
+	return fmt.Errorf("circuit breaker %s, next probe at %s", state, retryAt.UTC().Format(time.RFC3339))
+}
+
+// open opens the circuit for the current cooldown
+func (b *Breaker) open() {
+	b.failures = 0
+	b.openUntil = time.Now().Add(b.cooldown)
+	b.transition(BreakerOpen)
+}
+
+// transition moves the breaker to a new state
+func (b *Breaker) transition(state BreakerState) {
+	b.state = state
+	breakerState.Set(stateValues[state])
+	breakerTransitions.Inc(string(state))
+
+	switch state {
+	case BreakerOpen:
+		logger.Warn("OCR circuit breaker opened", "retry_at", b.openUntil)
+	case BreakerHalfOpen:
+		logger.Info("OCR circuit breaker probing the service")
+	case BreakerClosed:
+		logger.Info("OCR circuit breaker closed")
+	}
+}
@@ -1,35 +1,302 @@
 package ocr
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"visekai/backend/internal/config"
 	"visekai/backend/internal/models"
 	"visekai/backend/pkg/logger"
 )
 
-// Client handles communication with the OCR service
+// A failed schema negotiation is retried after schemaRetryBackoff, doubled
+// after every further failure up to schemaMaxRetryBackoff
+const (
+	schemaRetryBackoff    = 5 * time.Second
+	schemaMaxRetryBackoff = 5 * time.Minute
+)
+
+// Client handles communication with the OCR service. It negotiates the
+// response schema version with the service on first use and normalizes every
+// supported version into OCRResponse.
 type Client struct {
+	mu         sync.RWMutex
 	baseURL    string
+	config     ClientConfig
 	httpClient *http.Client
+	// schema is the version negotiated with the service at baseURL, empty
+	// when it does not advertise one; lastSchema is the last one it answered with
+	schema     string
+	negotiated bool
+	lastSchema string
+	// negotiateAfter holds off negotiating again after a failure, for
+	// negotiateBackoff
+	negotiateAfter   time.Time
+	negotiateBackoff time.Duration
+	// breaker fails requests fast while the service is down; nil disables it
+	breaker *Breaker
+}
+
+// ClientConfig configures the timeouts, retries, connection pool and TLS of
+// the OCR client
+type ClientConfig struct {
+	// Timeout bounds each attempt at processing a document, plus
+	// TimeoutPerPage for every page and TimeoutPerMB for every megabyte sent,
+	// up to MaxTimeout. MaxTimeout also bounds every other request.
+	Timeout        time.Duration
+	TimeoutPerPage time.Duration
+	TimeoutPerMB   time.Duration
+	MaxTimeout     time.Duration
+	// Retries is the number of times a failed attempt at processing a document
+	// is repeated, after RetryBackoff doubled for every attempt. Only
+	// connection errors, timeouts and 5xx responses are retried, and only
+	// files that can be rewound, as documents are streamed to the service.
+	Retries      int
+	RetryBackoff time.Duration
+	// MaxIdleConns and MaxIdleConnsPerHost bound the connections kept open
+	// to the service between requests, for IdleConnTimeout
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// TLSCAFile is a PEM file of the certificate authorities trusted for an
+	// https service, instead of the system's; TLSServerName overrides the name
+	// verified; TLSInsecureSkipVerify disables verification altogether
+	TLSCAFile             string
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+	// TLSCertFile and TLSKeyFile are the PEM key pair of the client
+	// certificate presented to a service requiring mutual TLS
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// ClientConfigFrom returns the client configuration set in cfg
+func ClientConfigFrom(cfg *config.Config) ClientConfig {
+	return ClientConfig{
+		Timeout:               cfg.OCRTimeout,
+		TimeoutPerPage:        cfg.OCRTimeoutPerPage,
+		TimeoutPerMB:          cfg.OCRTimeoutPerMB,
+		MaxTimeout:            cfg.OCRMaxTimeout,
+		Retries:               cfg.OCRRetries,
+		RetryBackoff:          cfg.OCRRetryBackoff,
+		MaxIdleConns:          cfg.OCRMaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.OCRMaxIdleConnsPerHost,
+		IdleConnTimeout:       cfg.OCRIdleConnTimeout,
+		TLSCAFile:             cfg.OCRTLSCAFile,
+		TLSServerName:         cfg.OCRTLSServerName,
+		TLSInsecureSkipVerify: cfg.OCRTLSInsecureSkipVerify,
+		TLSCertFile:           cfg.OCRTLSCertFile,
+		TLSKeyFile:            cfg.OCRTLSKeyFile,
+	}
 }
 
 // NewClient creates a new OCR client
-func NewClient(baseURL string) *Client {
+func NewClient(baseURL string, config ClientConfig) (*Client, error) {
+	if config.MaxTimeout < config.Timeout {
+		config.MaxTimeout = config.Timeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = config.MaxIdleConns
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
 	return &Client{
 		baseURL: baseURL,
+		config:  config,
 		httpClient: &http.Client{
-			Timeout: 5 * time.Minute, // OCR can take time
+			Transport: transport,
+			Timeout:   config.MaxTimeout,
 		},
+	}, nil
+}
+
+// attemptTimeout returns the timeout of an attempt at processing pages of a
+// document of size bytes
+func (c *Client) attemptTimeout(size int64, pages int) time.Duration {
+	timeout := c.config.Timeout + time.Duration(max(pages, 1))*c.config.TimeoutPerPage +
+		time.Duration(float64(c.config.TimeoutPerMB)*float64(size)/(1<<20))
+	if timeout <= 0 || timeout > c.config.MaxTimeout {
+		return c.config.MaxTimeout
+	}
+	return timeout
+}
+
+// UseBreaker guards the requests of the client with a circuit breaker
+func (c *Client) UseBreaker(breaker *Breaker) {
+	c.breaker = breaker
+}
+
+// Breaker returns the circuit breaker guarding the client, nil when there is none
+func (c *Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+// do sends a request through the circuit breaker. Transport errors, timeouts
+// and 5xx responses count as failures of the service; other responses show it
+// is up, and cancelled requests tell nothing.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if err := c.breaker.Allow(); err != nil {
+		_, retryAt := c.breaker.State()
+		return nil, fmt.Errorf("%w, next probe at %s", err, retryAt.UTC().Format(time.RFC3339))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+		c.breaker.Abandon()
+	case err != nil:
+		c.breaker.Record(false)
+	default:
+		c.breaker.Record(resp.StatusCode < http.StatusInternalServerError)
+	}
+	return resp, err
+}
+
+// BaseURL returns the current OCR service URL
+func (c *Client) BaseURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.baseURL
+}
+
+// SetBaseURL changes the OCR service URL at runtime. The schema version is
+// negotiated again with the new service.
+func (c *Client) SetBaseURL(baseURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.baseURL = baseURL
+	c.schema = ""
+	c.negotiated = false
+	c.lastSchema = ""
+	c.negotiateAfter = time.Time{}
+	c.negotiateBackoff = 0
+}
+
+// Schema returns the response schema version in use, or an empty string before
+// it is known
+func (c *Client) Schema() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.schema != "" {
+		return c.schema
+	}
+	return c.lastSchema
+}
+
+// NegotiateSchema asks the service which response schema versions it serves,
+// from GET /version, and picks the most preferred one the client supports.
+// Services without the endpoint get an empty version, and their responses are
+// told apart by shape.
+func (c *Client) NegotiateSchema(ctx context.Context) (string, error) {
+	baseURL := c.BaseURL()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/version", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var version string
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var info struct {
+			SchemaVersions []string `json:"schema_versions"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			return "", fmt.Errorf("failed to decode response: %w", err)
+		}
+		version = pickSchema(info.SchemaVersions)
+		if version == "" {
+			return "", &UnsupportedSchemaError{Version: strings.Join(info.SchemaVersions, ", ")}
+		}
+	case http.StatusNotFound:
+	default:
+		return "", fmt.Errorf("OCR service returned status %d", resp.StatusCode)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Keep the result only if the service did not change meanwhile
+	if c.baseURL == baseURL {
+		c.schema = version
+		c.negotiated = true
+		c.negotiateAfter = time.Time{}
+		c.negotiateBackoff = 0
+	}
+	return version, nil
+}
+
+// ensureSchema negotiates the schema version once per service. A failed
+// negotiation is retried with backoff rather than on every request; until then
+// responses are told apart by shape.
+func (c *Client) ensureSchema(ctx context.Context) string {
+	c.mu.Lock()
+	if c.negotiated {
+		defer c.mu.Unlock()
+		return c.schema
+	}
+	now := time.Now()
+	if now.Before(c.negotiateAfter) {
+		c.mu.Unlock()
+		return ""
 	}
+	// Requests arriving meanwhile wait for this negotiation's outcome instead
+	// of probing too
+	baseURL := c.baseURL
+	c.negotiateAfter = now.Add(max(c.negotiateBackoff, schemaRetryBackoff))
+	c.mu.Unlock()
+
+	schema, err := c.NegotiateSchema(ctx)
+	if err != nil {
+		c.mu.Lock()
+		if c.baseURL == baseURL {
+			c.negotiateBackoff = nextSchemaBackoff(c.negotiateBackoff)
+			c.negotiateAfter = time.Now().Add(c.negotiateBackoff)
+		}
+		c.mu.Unlock()
+		if !errors.Is(err, ErrCircuitOpen) {
+			logger.Warn("Failed to negotiate OCR response schema", "error", err)
+		}
+		return ""
+	}
+	if schema != "" {
+		logger.Info("Negotiated OCR response schema", "version", schema)
+	}
+	return schema
+}
+
+// nextSchemaBackoff returns how long to wait after another failed negotiation
+func nextSchemaBackoff(backoff time.Duration) time.Duration {
+	if backoff == 0 {
+		return schemaRetryBackoff
+	}
+	return min(2*backoff, schemaMaxRetryBackoff)
 }
 
 // OCRRequest represents a request to the OCR service
@@ -47,98 +314,269 @@ type OCRResponse struct {
 	Confidence     float64                `json:"confidence"`
 	ProcessingTime int                    `json:"processing_time_ms"`
 	NumPages       int                    `json:"num_pages"`
+	Pages          []OCRPage              `json:"pages,omitempty"`
+	Regions        []OCRRegion            `json:"regions,omitempty"`
 	Error          string                 `json:"error,omitempty"`
+	// Backend names what processed the request, as reported by the service or
+	// else the service's host
+	Backend string `json:"backend,omitempty"`
+}
+
+// OCRPage represents the text of a single page, returned by OCR services that
+// report results per page
+type OCRPage struct {
+	Page       int     `json:"page"`
+	Text       string  `json:"text"`
+	Markdown   string  `json:"markdown"`
+	Confidence float64 `json:"confidence"`
+	// ProcessingTime and Backend are left empty by services that only report
+	// them for the whole request
+	ProcessingTime int    `json:"processing_time_ms,omitempty"`
+	Backend        string `json:"backend,omitempty"`
+	// Width and Height are the size in pixels of the page image the service
+	// read, which region boxes are measured in; 0 when not reported
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+}
+
+// OCRRegion represents a word or line bounding box returned by the OCR service
+type OCRRegion struct {
+	Page       int        `json:"page"`
+	Level      string     `json:"level"` // word, line
+	Text       string     `json:"text"`
+	BBox       [4]float64 `json:"bbox"` // x0, y0, x1, y1
+	Confidence float64    `json:"confidence"`
+}
+
+// ProcessDocument sends a document of the given number of pages to the OCR
+// service for processing. The page count scales the request's timeout; 0
+// counts as one page.
+func (c *Client) ProcessDocument(ctx context.Context, file io.Reader, filename string, pages int, ocrMode models.OCRMode, resolutionMode models.ResolutionMode) (*OCRResponse, error) {
+	return c.process(ctx, file, filename, ocrMode, resolutionMode, 0, pages)
 }
 
-// ProcessDocument sends a document to the OCR service for processing
-func (c *Client) ProcessDocument(ctx context.Context, filePath string, ocrMode models.OCRMode, resolutionMode models.ResolutionMode) (*OCRResponse, error) {
-	// Open the file
-	file, err := os.Open(filePath)
+// ProcessPage sends a document to the OCR service to process a single page,
+// numbered from 1. Regions in the response refer to that page.
+func (c *Client) ProcessPage(ctx context.Context, file io.Reader, filename string, page int, ocrMode models.OCRMode, resolutionMode models.ResolutionMode) (*OCRResponse, error) {
+	resp, err := c.process(ctx, file, filename, ocrMode, resolutionMode, page, 1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	// Create multipart form
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	for i := range resp.Regions {
+		resp.Regions[i].Page = page
+	}
+	return resp, nil
+}
 
-	// Add file
-	part, err := writer.CreateFormFile("file", filepath.Base(filePath))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
+// process sends a document to the OCR service; a page of 0 processes all
+// pages. The file is streamed into the request rather than held in memory.
+// Attempts failing with a connection error, a timeout or a 5xx response are
+// retried with backoff when the file can be rewound to send it again.
+func (c *Client) process(ctx context.Context, file io.Reader, filename string, ocrMode models.OCRMode, resolutionMode models.ResolutionMode, page, pages int) (*OCRResponse, error) {
+	fields := []formField{
+		{"mode", string(ocrMode)},
+		{"resolution", string(resolutionMode)},
+	}
+	if page > 0 {
+		fields = append(fields, formField{"page", strconv.Itoa(page)})
 	}
 
-	_, err = io.Copy(part, file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to copy file: %w", err)
+	// Remember where the file starts so a retry can send it again
+	seeker, rewindable := file.(io.Seeker)
+	var start int64
+	if rewindable {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		start, rewindable = offset, err == nil
+	}
+
+	schema := c.ensureSchema(ctx)
+
+	timeout := c.attemptTimeout(sizeOf(file), pages)
+	backoff := c.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		ocrResp, retryable, err := c.attempt(ctx, file, filename, fields, schema, timeout)
+		if err == nil || !retryable || attempt >= c.config.Retries || ctx.Err() != nil {
+			return ocrResp, err
+		}
+		if !rewindable {
+			logger.Warn("OCR request failed and the file cannot be sent again", "file", filepath.Base(filename), "error", err)
+			return nil, err
+		}
+
+		logger.Warn("OCR request failed, retrying", "file", filepath.Base(filename), "attempt", attempt+1, "retry_in", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind file: %w", err)
+		}
 	}
+}
+
+// formField is a field of a multipart form
+type formField struct {
+	name, value string
+}
+
+// streamForm returns a multipart form holding file followed by fields, written
+// as it is read, and its content type. Close the form to stop writing it, and
+// wait on the channel for writing to end before reading file again.
+func streamForm(file io.Reader, filename string, fields []formField) (io.ReadCloser, string, <-chan struct{}) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		pw.CloseWithError(writeForm(writer, file, filename, fields))
+	}()
 
-	// Add OCR parameters
-	_ = writer.WriteField("mode", string(ocrMode))
-	_ = writer.WriteField("resolution", string(resolutionMode))
+	return pr, writer.FormDataContentType(), done
+}
 
-	err = writer.Close()
+// writeForm writes file and fields as a multipart form
+func writeForm(writer *multipart.Writer, file io.Reader, filename string, fields []formField) error {
+	part, err := writer.CreateFormFile("file", filepath.Base(filename))
 	if err != nil {
-		return nil, fmt.Errorf("failed to close writer: %w", err)
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
 	}
 
+	for _, field := range fields {
+		if err := writer.WriteField(field.name, field.value); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// sizeOf returns the number of bytes left to read from file, or 0 when it
+// cannot be told without reading it
+func sizeOf(file io.Reader) int64 {
+	switch f := file.(type) {
+	case interface{ Len() int }:
+		return int64(f.Len())
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := f.Stat()
+		if err != nil {
+			return 0
+		}
+		if seeker, ok := file.(io.Seeker); ok {
+			if offset, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+				return info.Size() - offset
+			}
+		}
+		return info.Size()
+	}
+	return 0
+}
+
+// attempt streams a document to the OCR service once, reporting whether a
+// failure is worth retrying
+func (c *Client) attempt(ctx context.Context, file io.Reader, filename string, fields []formField, schema string, timeout time.Duration) (*OCRResponse, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, contentType, written := streamForm(file, filename, fields)
+	defer func() {
+		body.Close()
+		<-written
+	}()
+
 	// Create request
-	url := fmt.Sprintf("%s/ocr/process", c.baseURL)
+	baseURL := c.BaseURL()
+	url := fmt.Sprintf("%s/ocr/process", baseURL)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Content-Type", contentType)
+
+	// Ask for the negotiated schema version, or list every supported one
+	if schema != "" {
+		req.Header.Set(SchemaHeader, schema)
+	} else {
+		req.Header.Set(SchemaHeader, strings.Join(SupportedSchemas(), ", "))
+	}
 
 	// Send request
-	logger.Info("Sending OCR request", "url", url, "file", filepath.Base(filePath), "mode", ocrMode, "resolution", resolutionMode)
+	args := []interface{}{"url", url, "file", filepath.Base(filename), "timeout", timeout}
+	for _, field := range fields {
+		args = append(args, field.name, field.value)
+	}
+	logger.Info("Sending OCR request", args...)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, !errors.Is(err, ErrCircuitOpen), fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, true, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
 		logger.Error("OCR service returned error", "status", resp.StatusCode, "body", string(respBody))
-		return nil, fmt.Errorf("OCR service returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("OCR service returned status %d: %s", resp.StatusCode, string(respBody))
 	}
 
-	// Parse response
-	var ocrResp OCRResponse
-	err = json.Unmarshal(respBody, &ocrResp)
+	// Parse response with the adapter for the version the service answered with
+	if declared := resp.Header.Get(SchemaHeader); declared != "" {
+		schema = declared
+	}
+	ocrResp, schema, err := decodeResponse(schema, respBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	c.lastSchema = schema
+	c.mu.Unlock()
+
+	if ocrResp.Backend == "" {
+		ocrResp.Backend = backendName(baseURL)
 	}
 
 	if !ocrResp.Success {
-		return nil, fmt.Errorf("OCR processing failed: %s", ocrResp.Error)
+		return nil, false, fmt.Errorf("OCR processing failed: %s", ocrResp.Error)
 	}
 
-	logger.Info("OCR processing completed", "confidence", ocrResp.Confidence, "processing_time_ms", ocrResp.ProcessingTime)
+	logger.Info("OCR processing completed", "schema", schema, "confidence", ocrResp.Confidence, "processing_time_ms", ocrResp.ProcessingTime)
+
+	return ocrResp, false, nil
+}
 
-	return &ocrResp, nil
+// backendName returns the host of a service URL, or the URL itself when it
+// has none
+func backendName(baseURL string) string {
+	if u, err := neturl.Parse(baseURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return baseURL
 }
 
 // HealthCheck checks if the OCR service is healthy
 func (c *Client) HealthCheck(ctx context.Context) error {
-	url := fmt.Sprintf("%s/health", c.baseURL)
+	url := fmt.Sprintf("%s/health", c.BaseURL())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -153,14 +591,14 @@ func (c *Client) HealthCheck(ctx context.Context) error {
 
 // GetStatus gets the status of the OCR service
 func (c *Client) GetStatus(ctx context.Context) (map[string]interface{}, error) {
-	url := fmt.Sprintf("%s/status", c.baseURL)
+	url := fmt.Sprintf("%s/status", c.BaseURL())
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -0,0 +1,146 @@
+package ocr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Response schema versions of the OCR service's /ocr/process endpoint
+const (
+	// SchemaV1 is a flat object: success, text, markdown, confidence,
+	// processing_time_ms, num_pages, pages, regions and error
+	SchemaV1 = "1"
+	// SchemaV2 wraps the result in an envelope: job_id, status (completed or
+	// failed), result holding the v1 fields, and error or detail
+	SchemaV2 = "2"
+)
+
+// SchemaHeader carries the schema version: the client lists the versions it
+// accepts, most preferred first, and the service names the one it answered with
+const SchemaHeader = "X-OCR-Schema-Version"
+
+// adapter normalizes a response body of one schema version into an OCRResponse
+type adapter func(body []byte) (*OCRResponse, error)
+
+// adapters maps each supported schema version to its adapter
+var adapters = map[string]adapter{
+	SchemaV1: decodeV1,
+	SchemaV2: decodeV2,
+}
+
+// SupportedSchemas lists the schema versions the client understands, most
+// preferred first
+func SupportedSchemas() []string {
+	return []string{SchemaV2, SchemaV1}
+}
+
+// UnsupportedSchemaError is returned for responses of a schema version the client
+// has no adapter for
+type UnsupportedSchemaError struct {
+	Version string
+}
+
+func (e *UnsupportedSchemaError) Error() string {
+	return fmt.Sprintf("unsupported OCR response schema version %q (supported: %s)",
+		e.Version, strings.Join(SupportedSchemas(), ", "))
+}
+
+// decodeResponse normalizes a response body. The version is the one the service
+// declared, or the one negotiated with it; when neither is known it is
+// detected from the body's shape.
+func decodeResponse(version string, body []byte) (*OCRResponse, string, error) {
+	if version == "" {
+		version = detectSchema(body)
+	}
+
+	decode, ok := adapters[version]
+	if !ok {
+		return nil, version, &UnsupportedSchemaError{Version: version}
+	}
+
+	resp, err := decode(body)
+	if err != nil {
+		return nil, version, fmt.Errorf("failed to parse response (schema %s): %w", version, err)
+	}
+	return resp, version, nil
+}
+
+// detectSchema tells the versions apart by v2's envelope: a status along with
+// the result, which is null or left out when the job failed, or the job ID
+func detectSchema(body []byte) string {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return SchemaV1
+	}
+	_, hasStatus := probe["status"]
+	_, hasResult := probe["result"]
+	_, hasJobID := probe["job_id"]
+	if hasStatus && (hasResult || hasJobID) {
+		return SchemaV2
+	}
+	return SchemaV1
+}
+
+// pickSchema returns the most preferred supported version the service offers,
+// or an empty string when there is none
+func pickSchema(offered []string) string {
+	for _, version := range SupportedSchemas() {
+		if slices.Contains(offered, version) {
+			return version
+		}
+	}
+	return ""
+}
+
+func decodeV1(body []byte) (*OCRResponse, error) {
+	var resp OCRResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func decodeV2(body []byte) (*OCRResponse, error) {
+	var envelope struct {
+		JobID  string          `json:"job_id"`
+		Status string          `json:"status"`
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+		Detail string          `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+
+	resp := &OCRResponse{}
+	if len(envelope.Result) > 0 && !bytes.Equal(envelope.Result, []byte("null")) {
+		if err := json.Unmarshal(envelope.Result, resp); err != nil {
+			return nil, fmt.Errorf("invalid result: %w", err)
+		}
+	}
+
+	switch envelope.Status {
+	case "completed":
+		resp.Success = true
+	case "failed":
+		resp.Success = false
+		resp.Error = envelope.Error
+		if resp.Error == "" {
+			resp.Error = envelope.Detail
+		}
+	default:
+		// The client waits for the result, so anything unfinished is a failure
+		resp.Success = false
+		resp.Error = fmt.Sprintf("OCR service returned job %s with status %q", envelope.JobID, envelope.Status)
+	}
+
+	// v2 results of single images may leave out the page count
+	if resp.Success && resp.NumPages == 0 {
+		resp.NumPages = max(len(resp.Pages), 1)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,225 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return body
+}
+
+func TestDecodeV1(t *testing.T) {
+	resp, err := decodeV1(readFixture(t, "v1.json"))
+	if err != nil {
+		t.Fatalf("decodeV1: %v", err)
+	}
+
+	if !resp.Success || resp.Text != "Invoice 42\nTotal: 10.00" || resp.Markdown != "# Invoice 42\n\nTotal: 10.00" {
+		t.Errorf("unexpected result: %+v", resp)
+	}
+	if resp.Confidence != 0.93 || resp.ProcessingTime != 1250 || resp.NumPages != 2 {
+		t.Errorf("confidence, processing time and pages = %v, %d, %d; want 0.93, 1250, 2", resp.Confidence, resp.ProcessingTime, resp.NumPages)
+	}
+	if len(resp.Pages) != 2 || resp.Pages[1].Text != "Total: 10.00" {
+		t.Errorf("unexpected pages: %+v", resp.Pages)
+	}
+	if len(resp.Regions) != 1 || resp.Regions[0].BBox != [4]float64{10, 20, 200, 40} {
+		t.Errorf("unexpected regions: %+v", resp.Regions)
+	}
+}
+
+func TestDecodeV1Invalid(t *testing.T) {
+	if _, err := decodeV1([]byte(`{"success": "yes"}`)); err == nil {
+		t.Error("expected an error for a malformed body")
+	}
+}
+
+func TestDecodeV2(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      []byte
+		success   bool
+		text      string
+		numPages  int
+		errorText string
+	}{
+		{
+			name:     "completed without a page count",
+			body:     readFixture(t, "v2_completed.json"),
+			success:  true,
+			text:     "Invoice 42",
+			numPages: 1,
+		},
+		{
+			name:      "failed with detail",
+			body:      readFixture(t, "v2_failed.json"),
+			errorText: "CUDA out of memory",
+		},
+		{
+			name:      "failed with error",
+			body:      []byte(`{"job_id": "j1", "status": "failed", "error": "unreadable file", "detail": "ignored"}`),
+			errorText: "unreadable file",
+		},
+		{
+			name:      "unfinished",
+			body:      []byte(`{"job_id": "j2", "status": "processing", "result": null}`),
+			errorText: `OCR service returned job j2 with status "processing"`,
+		},
+		{
+			name:     "completed with a page count",
+			body:     []byte(`{"job_id": "j3", "status": "completed", "result": {"text": "a", "num_pages": 3}}`),
+			success:  true,
+			text:     "a",
+			numPages: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := decodeV2(tt.body)
+			if err != nil {
+				t.Fatalf("decodeV2: %v", err)
+			}
+			if resp.Success != tt.success || resp.Text != tt.text || resp.NumPages != tt.numPages || resp.Error != tt.errorText {
+				t.Errorf("got success %v, text %q, pages %d, error %q; want %v, %q, %d, %q",
+					resp.Success, resp.Text, resp.NumPages, resp.Error, tt.success, tt.text, tt.numPages, tt.errorText)
+			}
+		})
+	}
+}
+
+func TestDecodeV2Invalid(t *testing.T) {
+	if _, err := decodeV2([]byte(`{"status": "completed", "result": "text"}`)); err == nil {
+		t.Error("expected an error for a result that is not an object")
+	}
+}
+
+func TestDetectSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"v1 fixture", readFixture(t, "v1.json"), SchemaV1},
+		{"v2 fixture", readFixture(t, "v2_completed.json"), SchemaV2},
+		{"v2 failure", readFixture(t, "v2_failed.json"), SchemaV2},
+		{"v2 failure without result", []byte(`{"job_id": "j1", "status": "failed", "error": "unreadable file"}`), SchemaV2},
+		{"status without result", []byte(`{"status": "ok", "text": "a"}`), SchemaV1},
+		{"not JSON", []byte(`Internal Server Error`), SchemaV1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectSchema(tt.body); got != tt.want {
+				t.Errorf("detectSchema = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickSchema(t *testing.T) {
+	tests := []struct {
+		offered []string
+		want    string
+	}{
+		{[]string{"1", "2"}, SchemaV2},
+		{[]string{"2"}, SchemaV2},
+		{[]string{"1"}, SchemaV1},
+		{[]string{"3", "1"}, SchemaV1},
+		{[]string{"3"}, ""},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := pickSchema(tt.offered); got != tt.want {
+			t.Errorf("pickSchema(%v) = %q, want %q", tt.offered, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	resp, version, err := decodeResponse("", readFixture(t, "v2_completed.json"))
+	if err != nil || version != SchemaV2 || !resp.Success {
+		t.Errorf("detected decode = %+v, %q, %v; want a successful v2 response", resp, version, err)
+	}
+
+	_, _, err = decodeResponse("9", readFixture(t, "v1.json"))
+	var unsupported *UnsupportedSchemaError
+	if !errors.As(err, &unsupported) || unsupported.Version != "9" {
+		t.Errorf("error = %v, want UnsupportedSchemaError for version 9", err)
+	}
+}
+
+func TestEnsureSchemaBacksOffAfterFailure(t *testing.T) {
+	var probes atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"schema_versions": ["1", "2"]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, ClientConfig{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+
+	for range 5 {
+		if schema := client.ensureSchema(ctx); schema != "" {
+			t.Fatalf("ensureSchema = %q during an outage, want none", schema)
+		}
+	}
+	if got := probes.Load(); got != 1 {
+		t.Fatalf("service probed %d times, want once until the backoff passes", got)
+	}
+
+	// Once the backoff passes the service is probed again, and a successful
+	// negotiation is kept
+	healthy.Store(true)
+	client.mu.Lock()
+	if client.negotiateBackoff != schemaRetryBackoff {
+		t.Errorf("backoff = %s, want %s", client.negotiateBackoff, schemaRetryBackoff)
+	}
+	client.negotiateAfter = time.Now().Add(-time.Second)
+	client.mu.Unlock()
+
+	for range 3 {
+		if schema := client.ensureSchema(ctx); schema != SchemaV2 {
+			t.Fatalf("ensureSchema = %q, want %q", schema, SchemaV2)
+		}
+	}
+	if got := probes.Load(); got != 2 {
+		t.Errorf("service probed %d times, want twice", got)
+	}
+}
+
+func TestNextSchemaBackoff(t *testing.T) {
+	backoff := time.Duration(0)
+	for _, want := range []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second} {
+		backoff = nextSchemaBackoff(backoff)
+		if backoff != want {
+			t.Fatalf("backoff = %s, want %s", backoff, want)
+		}
+	}
+	if got := nextSchemaBackoff(4 * time.Minute); got != schemaMaxRetryBackoff {
+		t.Errorf("backoff = %s, want it capped at %s", got, schemaMaxRetryBackoff)
+	}
+}
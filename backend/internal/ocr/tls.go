@@ -0,0 +1,51 @@
+package ocr
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"visekai/backend/pkg/keypair"
+)
+
+// newTLSConfig returns the TLS configuration of an https service, or nil to
+// keep the defaults when none of the TLS settings are set
+func newTLSConfig(config ClientConfig) (*tls.Config, error) {
+	if config.TLSCAFile == "" && config.TLSServerName == "" && !config.TLSInsecureSkipVerify &&
+		config.TLSCertFile == "" && config.TLSKeyFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         config.TLSServerName,
+		InsecureSkipVerify: config.TLSInsecureSkipVerify,
+	}
+
+	if config.TLSCAFile != "" {
+		pem, err := os.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OCR CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in OCR CA file %s", config.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.TLSCertFile != "" || config.TLSKeyFile != "" {
+		if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+			return nil, fmt.Errorf("OCR client certificate and key files must be set together")
+		}
+		// Renewed certificates are picked up by new connections
+		cert, err := keypair.Load(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("OCR client certificate: %w", err)
+		}
+		tlsConfig.GetClientCertificate = cert.GetClientCertificate
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrAccessGrantNotFound is returned when an access grant does not exist
+var ErrAccessGrantNotFound = apperrors.NotFound("GRANT_001", "access grant not found")
+
+// accessGrantColumns are the columns scanned by scanAccessGrant, with g the
+// grant, o its owner and u its grantee
+const accessGrantColumns = `
+	g.id, g.owner_id, o.email, g.grantee_id, u.email, g.document_ids, g.note,
+	g.expires_at, g.created_at, g.revoked_at, g.reminder_sent_at
+`
+
+// accessGrantFrom joins grants with the users they name
+const accessGrantFrom = `
+	FROM access_grants g
+	JOIN users o ON o.id = g.owner_id
+	JOIN users u ON u.id = g.grantee_id
+`
+
+// AccessGrantRepository handles temporary access grants
+type AccessGrantRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAccessGrantRepository creates a new access grant repository
+func NewAccessGrantRepository(db *pgxpool.Pool) *AccessGrantRepository {
+	return &AccessGrantRepository{db: db}
+}
+
+// Create stores a new access grant
+func (r *AccessGrantRepository) Create(ctx context.Context, grant *models.AccessGrant) error {
+	query := `
+		INSERT INTO access_grants (id, owner_id, grantee_id, document_ids, note, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	grant.ID = uuid.New()
+	grant.CreatedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		grant.ID,
+		grant.OwnerID,
+		grant.GranteeID,
+		grant.DocumentIDs,
+		grant.Note,
+		grant.ExpiresAt,
+		grant.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create access grant: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an access grant by ID
+func (r *AccessGrantRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AccessGrant, error) {
+	query := `SELECT ` + accessGrantColumns + accessGrantFrom + `WHERE g.id = $1`
+
+	grant, err := scanAccessGrant(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrAccessGrantNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+// ListByOwner retrieves the grants a user has given, newest first
+func (r *AccessGrantRepository) ListByOwner(ctx context.Context, ownerID uuid.UUID) ([]*models.AccessGrant, error) {
+	query := `SELECT ` + accessGrantColumns + accessGrantFrom + `
+		WHERE g.owner_id = $1
+		ORDER BY g.created_at DESC
+	`
+
+	return r.list(ctx, query, ownerID)
+}
+
+// ListActiveByGrantee retrieves the unexpired, unrevoked grants a user has
+// received, soonest to expire first
+func (r *AccessGrantRepository) ListActiveByGrantee(ctx context.Context, granteeID uuid.UUID) ([]*models.AccessGrant, error) {
+	query := `SELECT ` + accessGrantColumns + accessGrantFrom + `
+		WHERE g.grantee_id = $1 AND g.revoked_at IS NULL AND g.expires_at > $2
+		ORDER BY g.expires_at
+	`
+
+	return r.list(ctx, query, granteeID, time.Now().UTC())
+}
+
+// Revoke ends one of a user's grants early
+func (r *AccessGrantRepository) Revoke(ctx context.Context, id, ownerID uuid.UUID) error {
+	query := `UPDATE access_grants SET revoked_at = $1 WHERE id = $2 AND owner_id = $3 AND revoked_at IS NULL`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, time.Now().UTC(), id, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access grant: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrAccessGrantNotFound
+	}
+
+	return nil
+}
+
+// MarkExpiring marks up to limit active grants expiring before the cutoff whose
+// grantee was not reminded yet as reminded and returns them. Rows locked by
+// another transaction are skipped.
+func (r *AccessGrantRepository) MarkExpiring(ctx context.Context, cutoff time.Time, limit int) ([]*models.AccessGrant, error) {
+	query := `
+		WITH reminded AS (
+			UPDATE access_grants
+			SET reminder_sent_at = $1
+			WHERE id IN (
+				SELECT id FROM access_grants
+				WHERE revoked_at IS NULL AND reminder_sent_at IS NULL
+				  AND expires_at > $1 AND expires_at < $2
+				ORDER BY expires_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT ` + accessGrantColumns + `
+		FROM reminded g
+		JOIN users o ON o.id = g.owner_id
+		JOIN users u ON u.id = g.grantee_id
+	`
+
+	return r.list(ctx, query, time.Now().UTC(), cutoff, limit)
+}
+
+func (r *AccessGrantRepository) list(ctx context.Context, query string, args ...any) ([]*models.AccessGrant, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access grants: %w", err)
+	}
+	defer rows.Close()
+
+	grants := []*models.AccessGrant{}
+	for rows.Next() {
+		grant, err := scanAccessGrant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access grant: %w", err)
+		}
+		grants = append(grants, grant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list access grants: %w", err)
+	}
+
+	return grants, nil
+}
+
+func scanAccessGrant(row pgx.Row) (*models.AccessGrant, error) {
+	var grant models.AccessGrant
+	err := row.Scan(
+		&grant.ID,
+		&grant.OwnerID,
+		&grant.OwnerEmail,
+		&grant.GranteeID,
+		&grant.GranteeEmail,
+		&grant.DocumentIDs,
+		&grant.Note,
+		&grant.ExpiresAt,
+		&grant.CreatedAt,
+		&grant.RevokedAt,
+		&grant.ReminderSentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
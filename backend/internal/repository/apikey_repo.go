@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrAPIKeyNotFound is returned when an API key does not exist
+var ErrAPIKeyNotFound = apperrors.NotFound("RES_006", "API key not found")
+
+// APIKeyRepository handles API key database operations
+type APIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create stores a new API key under the hash of its secret
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, key_hash, key_prefix, name, expires_at, is_active, created_at, labels, permissions)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE, $7, $8, $9)
+	`
+
+	key.ID = uuid.New()
+	key.IsActive = true
+	key.CreatedAt = time.Now().UTC()
+	if key.Labels == nil {
+		key.Labels = []string{}
+	}
+	if key.Permissions == nil {
+		key.Permissions = []models.Permission{}
+	}
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		key.ID,
+		key.UserID,
+		keyHash,
+		key.KeyPrefix,
+		key.Name,
+		key.ExpiresAt,
+		key.CreatedAt,
+		key.Labels,
+		key.Permissions,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return nil
+}
+
+// GetByHash retrieves an API key by the hash of its secret
+func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_prefix, last_used_at, expires_at, COALESCE(is_active, FALSE), created_at, labels, permissions
+		FROM api_keys
+		WHERE key_hash = $1
+	`
+
+	var key models.APIKey
+	err := conn(ctx, r.db).QueryRow(ctx, query, keyHash).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.KeyPrefix,
+		&key.LastUsedAt,
+		&key.ExpiresAt,
+		&key.IsActive,
+		&key.CreatedAt,
+		&key.Labels,
+		&key.Permissions,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// ListByUser retrieves a user's API keys, newest first
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_prefix, last_used_at, expires_at, COALESCE(is_active, FALSE), created_at, labels, permissions
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.KeyPrefix,
+			&key.LastUsedAt,
+			&key.ExpiresAt,
+			&key.IsActive,
+			&key.CreatedAt,
+			&key.Labels,
+			&key.Permissions,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke deactivates one of a user's API keys
+func (r *APIKeyRepository) Revoke(ctx context.Context, id, userID uuid.UUID) error {
+	query := `UPDATE api_keys SET is_active = FALSE WHERE id = $1 AND user_id = $2 AND is_active`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed records that a key was used
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, usedAt, id); err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+
+	return nil
+}
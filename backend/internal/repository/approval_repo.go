@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrApprovalNotFound is returned when a job has no approval request, because
+// it was never held for approval or does not exist
+var ErrApprovalNotFound = apperrors.NotFound("APPROVAL_002", "job is not awaiting approval")
+
+// ApprovalRepository handles the approval requests of held jobs
+type ApprovalRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewApprovalRepository creates a new approval repository
+func NewApprovalRepository(db *pgxpool.Pool) *ApprovalRepository {
+	return &ApprovalRepository{db: db}
+}
+
+// Create stores the approval request of a held job
+func (r *ApprovalRepository) Create(ctx context.Context, approval *models.JobApproval) error {
+	query := `
+		INSERT INTO job_approvals (job_id, reason, organization_id, requested_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	approval.RequestedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, approval.JobID, approval.Reason, approval.OrgID, approval.RequestedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create approval request: %w", err)
+	}
+
+	return nil
+}
+
+// GetByJobID retrieves the approval request of a job
+func (r *ApprovalRepository) GetByJobID(ctx context.Context, jobID uuid.UUID) (*models.JobApproval, error) {
+	query := `
+		SELECT job_id, reason, organization_id, requested_at, decision, decided_by, decided_at, comment
+		FROM job_approvals
+		WHERE job_id = $1
+	`
+
+	var approval models.JobApproval
+	err := conn(ctx, r.db).QueryRow(ctx, query, jobID).Scan(
+		&approval.JobID,
+		&approval.Reason,
+		&approval.OrgID,
+		&approval.RequestedAt,
+		&approval.Decision,
+		&approval.DecidedBy,
+		&approval.DecidedAt,
+		&approval.Comment,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrApprovalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get approval request: %w", err)
+	}
+
+	return &approval, nil
+}
+
+// ListUndecided retrieves the undecided approval requests of jobs still awaiting
+// approval with their jobs, oldest first. Only jobs held by the policies of
+// orgIDs are listed, along with those held by the instance-wide policy when
+// instance is set. Under a label scope only jobs carrying one of the labels are
+// listed.
+func (r *ApprovalRepository) ListUndecided(ctx context.Context, instance bool, orgIDs []uuid.UUID, page, perPage int) ([]*models.JobApproval, int, error) {
+	countQuery := `
+		SELECT COUNT(*)
+		FROM job_approvals a
+		JOIN ocr_jobs j ON j.id = a.job_id
+		WHERE a.decision IS NULL AND j.status = $1 AND ($2::text[] IS NULL OR j.labels && $2)
+		  AND (($3 AND a.organization_id IS NULL) OR a.organization_id = ANY($4))
+	`
+
+	var total int
+	if err := conn(ctx, r.db).QueryRow(ctx, countQuery, models.JobStatusAwaitingApproval, LabelScope(ctx), instance, orgIDs).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count approval requests: %w", err)
+	}
+
+	query := `
+		SELECT a.job_id, a.reason, a.organization_id, a.requested_at,
+			   j.id, j.document_id, j.user_id, j.status, j.ocr_mode, j.resolution_mode,
+			   j.priority, j.retry_count, j.max_retries, j.progress_percentage,
+			   j.created_at, j.started_at, j.completed_at, j.error_message, j.metadata, j.labels
+		FROM job_approvals a
+		JOIN ocr_jobs j ON j.id = a.job_id
+		WHERE a.decision IS NULL AND j.status = $1 AND ($4::text[] IS NULL OR j.labels && $4)
+		  AND (($5 AND a.organization_id IS NULL) OR a.organization_id = ANY($6))
+		ORDER BY a.requested_at
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, models.JobStatusAwaitingApproval, perPage, (page-1)*perPage, LabelScope(ctx), instance, orgIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list approval requests: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := []*models.JobApproval{}
+	for rows.Next() {
+		approval := &models.JobApproval{Job: &models.OCRJob{}}
+		job := approval.Job
+		err := rows.Scan(
+			&approval.JobID,
+			&approval.Reason,
+			&approval.OrgID,
+			&approval.RequestedAt,
+			&job.ID,
+			&job.DocumentID,
+			&job.UserID,
+			&job.Status,
+			&job.OCRMode,
+			&job.ResolutionMode,
+			&job.Priority,
+			&job.RetryCount,
+			&job.MaxRetries,
+			&job.ProgressPercentage,
+			&job.CreatedAt,
+			&job.StartedAt,
+			&job.CompletedAt,
+			&job.ErrorMessage,
+			&job.Metadata,
+			&job.Labels,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan approval request: %w", err)
+		}
+		approvals = append(approvals, approval)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list approval requests: %w", err)
+	}
+
+	return approvals, total, nil
+}
+
+// Decide records the decision on an undecided approval request. It returns
+// false when the request was already decided.
+func (r *ApprovalRepository) Decide(ctx context.Context, approval *models.JobApproval) (bool, error) {
+	query := `
+		UPDATE job_approvals
+		SET decision = $1, decided_by = $2, decided_at = $3, comment = $4
+		WHERE job_id = $5 AND decision IS NULL
+	`
+
+	now := time.Now().UTC()
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		approval.Decision,
+		approval.DecidedBy,
+		now,
+		approval.Comment,
+		approval.JobID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record approval decision: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	approval.DecidedAt = &now
+	return true, nil
+}
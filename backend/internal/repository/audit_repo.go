@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditRepository handles audit log database operations
+type AuditRepository struct {
+	db *pgxpool.Pool
+	// replica serves list queries; nil uses db
+	replica *pgxpool.Pool
+}
+
+// NewAuditRepository creates a new audit repository. The replica, if not
+// nil, serves list queries.
+func NewAuditRepository(db, replica *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db, replica: replica}
+}
+
+// Create stores an audit event
+func (r *AuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (
+			id, action, actor_id, actor_email, target_type, target_id,
+			ip_address, user_agent, request_id, metadata, created_at
+		) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10, $11)
+	`
+
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		event.ID,
+		event.Action,
+		event.ActorID,
+		event.ActorEmail,
+		event.TargetType,
+		event.TargetID,
+		event.IPAddress,
+		event.UserAgent,
+		event.RequestID,
+		event.Metadata,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+
+	return nil
+}
+
+// AnonymizeActor removes what identifies a user from the events they
+// performed or failed to sign in with, keeping the events themselves
+func (r *AuditRepository) AnonymizeActor(ctx context.Context, userID uuid.UUID, email string) error {
+	query := `
+		UPDATE audit_events
+		SET actor_email = NULL, ip_address = NULL, user_agent = NULL
+		WHERE actor_id = $1 OR LOWER(actor_email) = LOWER($2)
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, userID, email); err != nil {
+		return fmt.Errorf("failed to anonymize audit events: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves audit events matching the filter, newest first
+func (r *AuditRepository) List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, int, error) {
+	where := `
+		WHERE ($1 = '' OR action = $1)
+		  AND ($2::uuid IS NULL OR actor_id = $2)
+		  AND ($3::uuid IS NULL OR target_id = $3)
+		  AND ($4::timestamp IS NULL OR created_at >= $4)
+		  AND ($5::timestamp IS NULL OR created_at < $5)
+	`
+	args := []any{string(filter.Action), filter.ActorID, filter.TargetID, filter.Since, filter.Until}
+
+	// Count matching events
+	var total int
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, `SELECT COUNT(*) FROM audit_events`+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	query := `
+		SELECT id, action, actor_id, COALESCE(actor_email, ''), COALESCE(target_type, ''), target_id,
+		       COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(request_id, ''), metadata, created_at
+		FROM audit_events` + where + `
+		ORDER BY created_at DESC
+		LIMIT $6 OFFSET $7
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, append(args, filter.PerPage, (filter.Page-1)*filter.PerPage)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.Action,
+			&event.ActorID,
+			&event.ActorEmail,
+			&event.TargetType,
+			&event.TargetID,
+			&event.IPAddress,
+			&event.UserAgent,
+			&event.RequestID,
+			&event.Metadata,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	return events, total, nil
+}
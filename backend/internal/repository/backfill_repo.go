@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// backfillRunColumns are the columns scanned by scanBackfillRun
+const backfillRunColumns = `id, task, status, cursor, total, processed, updated, failed, COALESCE(error, ''),
+	started_by, created_at, started_at, heartbeat_at, completed_at`
+
+// BackfillRepository stores the runs of backfill tasks
+type BackfillRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewBackfillRepository creates a new backfill repository
+func NewBackfillRepository(db *pgxpool.Pool) *BackfillRepository {
+	return &BackfillRepository{db: db}
+}
+
+// Create stores a new pending run. It reports false, storing nothing, when
+// the task already has a pending or running run.
+func (r *BackfillRepository) Create(ctx context.Context, run *models.BackfillRun) (bool, error) {
+	query := `
+		INSERT INTO backfill_runs (task, status, total, started_by)
+		VALUES ($1, 'pending', $2, $3)
+		ON CONFLICT DO NOTHING
+		RETURNING ` + backfillRunColumns
+
+	created, err := scanBackfillRun(conn(ctx, r.db).QueryRow(ctx, query, run.Task, run.Total, run.StartedBy))
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create backfill run: %w", err)
+	}
+
+	*run = *created
+	return true, nil
+}
+
+// Resume makes a failed or cancelled run pending again, keeping its cursor
+// and counts. It reports false when the run cannot be resumed, as it is not
+// failed or cancelled or its task has another pending or running run.
+func (r *BackfillRepository) Resume(ctx context.Context, run *models.BackfillRun) (bool, error) {
+	query := `
+		UPDATE backfill_runs
+		SET status = 'pending', error = NULL, completed_at = NULL
+		WHERE id = $1 AND status IN ('failed', 'cancelled')
+		  AND NOT EXISTS (
+			  SELECT 1 FROM backfill_runs
+			  WHERE task = $2 AND status IN ('pending', 'running')
+		  )
+		RETURNING ` + backfillRunColumns
+
+	resumed, err := scanBackfillRun(conn(ctx, r.db).QueryRow(ctx, query, run.ID, run.Task))
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to resume backfill run: %w", err)
+	}
+
+	*run = *resumed
+	return true, nil
+}
+
+// Claim marks the oldest pending run, or a running run whose instance stopped
+// sending heartbeats before staleBefore, as running for the caller and
+// returns it, or nil when there is none
+func (r *BackfillRepository) Claim(ctx context.Context, staleBefore time.Time) (*models.BackfillRun, error) {
+	query := `
+		UPDATE backfill_runs
+		SET status = 'running', heartbeat_at = NOW(), started_at = COALESCE(started_at, NOW())
+		WHERE id = (
+			SELECT id FROM backfill_runs
+			WHERE status = 'pending' OR (status = 'running' AND heartbeat_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + backfillRunColumns
+
+	run, err := scanBackfillRun(conn(ctx, r.db).QueryRow(ctx, query, staleBefore))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim backfill run: %w", err)
+	}
+
+	return run, nil
+}
+
+// SaveProgress stores the cursor and counts of a running run and renews its
+// heartbeat. It reports false when the run is no longer running, for example
+// because it was cancelled.
+func (r *BackfillRepository) SaveProgress(ctx context.Context, run *models.BackfillRun) (bool, error) {
+	query := `
+		UPDATE backfill_runs
+		SET cursor = $2, processed = $3, updated = $4, failed = $5, heartbeat_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, run.ID, run.Cursor, run.Processed, run.Updated, run.Failed)
+	if err != nil {
+		return false, fmt.Errorf("failed to save backfill progress: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// Finish ends a running run with the given status and, for failed runs, error
+func (r *BackfillRepository) Finish(ctx context.Context, id uuid.UUID, status models.BackfillStatus, errMessage string) error {
+	query := `
+		UPDATE backfill_runs
+		SET status = $2, error = NULLIF($3, ''), completed_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, id, status, errMessage); err != nil {
+		return fmt.Errorf("failed to finish backfill run: %w", err)
+	}
+	return nil
+}
+
+// Release makes a running run pending again, for an instance shutting down
+// to hand it over without waiting for its heartbeat to go stale
+func (r *BackfillRepository) Release(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE backfill_runs SET status = 'pending' WHERE id = $1 AND status = 'running'`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to release backfill run: %w", err)
+	}
+	return nil
+}
+
+// Cancel cancels the pending or running run of a task. It reports false when
+// the task has none.
+func (r *BackfillRepository) Cancel(ctx context.Context, task string) (*models.BackfillRun, error) {
+	query := `
+		UPDATE backfill_runs
+		SET status = 'cancelled', completed_at = NOW()
+		WHERE task = $1 AND status IN ('pending', 'running')
+		RETURNING ` + backfillRunColumns
+
+	run, err := scanBackfillRun(conn(ctx, r.db).QueryRow(ctx, query, task))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel backfill run: %w", err)
+	}
+
+	return run, nil
+}
+
+// Latest retrieves the latest run of each task, by task
+func (r *BackfillRepository) Latest(ctx context.Context) (map[string]*models.BackfillRun, error) {
+	query := `
+		SELECT DISTINCT ON (task) ` + backfillRunColumns + `
+		FROM backfill_runs
+		ORDER BY task, created_at DESC
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backfill runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make(map[string]*models.BackfillRun)
+	for rows.Next() {
+		run, err := scanBackfillRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backfill run: %w", err)
+		}
+		runs[run.Task] = run
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list backfill runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// ListByTask retrieves the runs of a task, most recent first
+func (r *BackfillRepository) ListByTask(ctx context.Context, task string, limit int) ([]*models.BackfillRun, error) {
+	query := `SELECT ` + backfillRunColumns + ` FROM backfill_runs WHERE task = $1 ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, task, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backfill runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*models.BackfillRun{}
+	for rows.Next() {
+		run, err := scanBackfillRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backfill run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list backfill runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+func scanBackfillRun(row pgx.Row) (*models.BackfillRun, error) {
+	var run models.BackfillRun
+	err := row.Scan(
+		&run.ID,
+		&run.Task,
+		&run.Status,
+		&run.Cursor,
+		&run.Total,
+		&run.Processed,
+		&run.Updated,
+		&run.Failed,
+		&run.Error,
+		&run.StartedBy,
+		&run.CreatedAt,
+		&run.StartedAt,
+		&run.HeartbeatAt,
+		&run.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
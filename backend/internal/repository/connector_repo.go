@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrConnectionNotFound is returned when an import connection does not exist
+var ErrConnectionNotFound = apperrors.NotFound("CONN_001", "import connection not found")
+
+// connectionColumns are the columns scanned by scanConnection
+const connectionColumns = `id, user_id, provider, account, access_token, refresh_token, token_expiry,
+	folder_id, folder_name, labels, enabled, last_sync_at, last_error, next_sync_at, created_at`
+
+// ConnectorRepository handles the cloud storage accounts users import from
+type ConnectorRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewConnectorRepository creates a new connector repository
+func NewConnectorRepository(db *pgxpool.Pool) *ConnectorRepository {
+	return &ConnectorRepository{db: db}
+}
+
+// Create stores a newly linked account
+func (r *ConnectorRepository) Create(ctx context.Context, connection *models.ImportConnection) error {
+	query := `
+		INSERT INTO import_connections (
+			id, user_id, provider, account, access_token, refresh_token, token_expiry,
+			labels, enabled, next_sync_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+	`
+
+	connection.ID = uuid.New()
+	connection.CreatedAt = time.Now().UTC()
+	connection.NextSyncAt = connection.CreatedAt
+	if connection.Labels == nil {
+		connection.Labels = []string{}
+	}
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		connection.ID,
+		connection.UserID,
+		connection.Provider,
+		connection.Account,
+		connection.AccessToken,
+		connection.RefreshToken,
+		connection.TokenExpiry,
+		connection.Labels,
+		connection.Enabled,
+		connection.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create import connection: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an import connection by ID
+func (r *ConnectorRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ImportConnection, error) {
+	query := `SELECT ` + connectionColumns + ` FROM import_connections WHERE id = $1`
+
+	connection, err := scanConnection(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrConnectionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import connection: %w", err)
+	}
+
+	return connection, nil
+}
+
+// ListByUser retrieves a user's import connections, newest first
+func (r *ConnectorRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.ImportConnection, error) {
+	query := `SELECT ` + connectionColumns + ` FROM import_connections WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import connections: %w", err)
+	}
+	defer rows.Close()
+
+	return scanConnections(rows)
+}
+
+// UpdateFolder sets the folder a connection imports from, the labels of the
+// imported documents and whether it is enabled, and schedules a sync now
+func (r *ConnectorRepository) UpdateFolder(ctx context.Context, connection *models.ImportConnection) error {
+	query := `
+		UPDATE import_connections
+		SET folder_id = $1, folder_name = $2, labels = $3, enabled = $4, next_sync_at = $5
+		WHERE id = $6
+	`
+
+	connection.NextSyncAt = time.Now().UTC()
+
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		connection.FolderID,
+		connection.FolderName,
+		connection.Labels,
+		connection.Enabled,
+		connection.NextSyncAt,
+		connection.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update import connection: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrConnectionNotFound
+	}
+
+	return nil
+}
+
+// UpdateTokens stores refreshed tokens of a connection
+func (r *ConnectorRepository) UpdateTokens(ctx context.Context, connection *models.ImportConnection) error {
+	query := `UPDATE import_connections SET access_token = $1, refresh_token = $2, token_expiry = $3 WHERE id = $4`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, connection.AccessToken, connection.RefreshToken, connection.TokenExpiry, connection.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update import connection tokens: %w", err)
+	}
+
+	return nil
+}
+
+// ScheduleSync makes a connection due for a sync now
+func (r *ConnectorRepository) ScheduleSync(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `UPDATE import_connections SET next_sync_at = $1 WHERE id = $2`, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule import connection sync: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrConnectionNotFound
+	}
+
+	return nil
+}
+
+// ClaimDue takes enabled connections with a folder whose sync is due, pushing
+// their next sync to leaseUntil so other replicas leave them alone while they
+// are synced
+func (r *ConnectorRepository) ClaimDue(ctx context.Context, leaseUntil time.Time, limit int) ([]*models.ImportConnection, error) {
+	query := `
+		UPDATE import_connections SET next_sync_at = $2
+		WHERE id IN (
+			SELECT id FROM import_connections
+			WHERE enabled AND folder_id IS NOT NULL AND next_sync_at <= $1
+			ORDER BY next_sync_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + connectionColumns
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, time.Now().UTC(), leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due import connections: %w", err)
+	}
+	defer rows.Close()
+
+	return scanConnections(rows)
+}
+
+// FinishSync records the outcome of a sync; a nil error clears the last one
+func (r *ConnectorRepository) FinishSync(ctx context.Context, id uuid.UUID, syncedAt time.Time, syncErr *string) error {
+	query := `UPDATE import_connections SET last_sync_at = $1, last_error = $2 WHERE id = $3`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, syncedAt, syncErr, id)
+	if err != nil {
+		return fmt.Errorf("failed to record import connection sync: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes an import connection; the documents it imported are kept
+func (r *ConnectorRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM import_connections WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete import connection: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrConnectionNotFound
+	}
+
+	return nil
+}
+
+// ImportedIDs returns which of the remote file IDs a connection already
+// imported or skipped
+func (r *ConnectorRepository) ImportedIDs(ctx context.Context, connectionID uuid.UUID, remoteIDs []string) (map[string]bool, error) {
+	query := `SELECT remote_id FROM imported_files WHERE connection_id = $1 AND remote_id = ANY($2)`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, connectionID, remoteIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get imported files: %w", err)
+	}
+	defer rows.Close()
+
+	imported := map[string]bool{}
+	for rows.Next() {
+		var remoteID string
+		if err := rows.Scan(&remoteID); err != nil {
+			return nil, fmt.Errorf("failed to scan imported file: %w", err)
+		}
+		imported[remoteID] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get imported files: %w", err)
+	}
+
+	return imported, nil
+}
+
+// RecordImport records a remote file as imported or skipped
+func (r *ConnectorRepository) RecordImport(ctx context.Context, file *models.ImportedFile) error {
+	query := `
+		INSERT INTO imported_files (connection_id, remote_id, name, document_id, skipped_reason, imported_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (connection_id, remote_id) DO NOTHING
+	`
+
+	file.ImportedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		file.ConnectionID,
+		file.RemoteID,
+		file.Name,
+		file.DocumentID,
+		file.SkippedReason,
+		file.ImportedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record imported file: %w", err)
+	}
+
+	return nil
+}
+
+func scanConnections(rows pgx.Rows) ([]*models.ImportConnection, error) {
+	connections := []*models.ImportConnection{}
+	for rows.Next() {
+		connection, err := scanConnection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import connection: %w", err)
+		}
+		connections = append(connections, connection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list import connections: %w", err)
+	}
+
+	return connections, nil
+}
+
+func scanConnection(row pgx.Row) (*models.ImportConnection, error) {
+	var connection models.ImportConnection
+	err := row.Scan(
+		&connection.ID,
+		&connection.UserID,
+		&connection.Provider,
+		&connection.Account,
+		&connection.AccessToken,
+		&connection.RefreshToken,
+		&connection.TokenExpiry,
+		&connection.FolderID,
+		&connection.FolderName,
+		&connection.Labels,
+		&connection.Enabled,
+		&connection.LastSyncAt,
+		&connection.LastError,
+		&connection.NextSyncAt,
+		&connection.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &connection, nil
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CreditRepository handles the credit ledger
+type CreditRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewCreditRepository creates a new credit repository
+func NewCreditRepository(db *pgxpool.Pool) *CreditRepository {
+	return &CreditRepository{db: db}
+}
+
+// LockUser serializes changes to a user's credits until the transaction in ctx
+// ends, so a balance read under the lock stays valid until it commits
+func (r *CreditRepository) LockUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := conn(ctx, r.db).Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('credits:' || $1::text))`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to lock credits: %w", err)
+	}
+	return nil
+}
+
+// Balance returns the sum of a user's credit transactions
+func (r *CreditRepository) Balance(ctx context.Context, userID uuid.UUID) (int, error) {
+	var balance int
+	err := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM credit_transactions WHERE user_id = $1`,
+		userID,
+	).Scan(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get credit balance: %w", err)
+	}
+	return balance, nil
+}
+
+// UsedSince returns the credits a user's jobs used since a time, net of
+// adjustments and refunds
+func (r *CreditRepository) UsedSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	var used int
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		SELECT COALESCE(-SUM(amount), 0)
+		FROM credit_transactions
+		WHERE user_id = $1 AND kind IN ('charge', 'adjustment', 'refund') AND created_at >= $2
+	`, userID, since).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get credit usage: %w", err)
+	}
+	return used, nil
+}
+
+// JobTotal returns the net amount a job was charged, as a negative number
+func (r *CreditRepository) JobTotal(ctx context.Context, jobID uuid.UUID) (int, error) {
+	var total int
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM credit_transactions
+		WHERE job_id = $1 AND kind IN ('charge', 'adjustment')
+	`, jobID).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get job charges: %w", err)
+	}
+	return total, nil
+}
+
+// PurchasedRemaining returns the purchased credits a user has left, counting
+// credits as spent from the free allotments first
+func (r *CreditRepository) PurchasedRemaining(ctx context.Context, userID uuid.UUID) (int, error) {
+	var remaining int
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount) FILTER (WHERE kind = 'purchase'), 0)
+			 - GREATEST(
+				 COALESCE(-SUM(amount) FILTER (WHERE kind IN ('charge', 'adjustment', 'refund')), 0)
+				 - COALESCE(SUM(amount) FILTER (WHERE kind = 'allotment'), 0),
+				 0
+			 )
+		FROM credit_transactions
+		WHERE user_id = $1
+	`, userID).Scan(&remaining)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get purchased credits: %w", err)
+	}
+	return max(remaining, 0), nil
+}
+
+// HasPurchase reports whether a purchase with a reference was credited
+func (r *CreditRepository) HasPurchase(ctx context.Context, reference string) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM credit_transactions WHERE kind = 'purchase' AND reference = $1
+		)
+	`, reference).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check credit purchase: %w", err)
+	}
+	return exists, nil
+}
+
+// HasAllotment reports whether a user received the allotment for a period
+func (r *CreditRepository) HasAllotment(ctx context.Context, userID uuid.UUID, period time.Time) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM credit_transactions
+			WHERE user_id = $1 AND kind = 'allotment' AND period = $2
+		)
+	`, userID, period).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check credit allotment: %w", err)
+	}
+	return exists, nil
+}
+
+// Create adds a transaction to the ledger
+func (r *CreditRepository) Create(ctx context.Context, transaction *models.CreditTransaction) error {
+	query := `
+		INSERT INTO credit_transactions (id, user_id, kind, amount, job_id, period, description, reference, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	transaction.ID = uuid.New()
+	transaction.CreatedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		transaction.ID,
+		transaction.UserID,
+		transaction.Kind,
+		transaction.Amount,
+		transaction.JobID,
+		transaction.Period,
+		transaction.Description,
+		transaction.Reference,
+		transaction.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create credit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser retrieves a page of a user's transactions, newest first, with
+// their total count
+func (r *CreditRepository) ListByUser(ctx context.Context, userID uuid.UUID, page, perPage int) ([]*models.CreditTransaction, int, error) {
+	var total int
+	err := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT COUNT(*) FROM credit_transactions WHERE user_id = $1`,
+		userID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count credit transactions: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, kind, amount, job_id, period, description, reference, created_at
+		FROM credit_transactions
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list credit transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []*models.CreditTransaction{}
+	for rows.Next() {
+		transaction := &models.CreditTransaction{}
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.UserID,
+			&transaction.Kind,
+			&transaction.Amount,
+			&transaction.JobID,
+			&transaction.Period,
+			&transaction.Description,
+			&transaction.Reference,
+			&transaction.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan credit transaction: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list credit transactions: %w", err)
+	}
+
+	return transactions, total, nil
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrDataExportNotFound is returned when a data export does not exist
+var ErrDataExportNotFound = apperrors.NotFound("EXP_002", "data export not found")
+
+// dataExportColumns are the columns scanned by scanDataExport
+const dataExportColumns = `id, user_id, status, COALESCE(file_path, ''), file_size, documents, error,
+	created_at, started_at, completed_at, expires_at`
+
+// DataExportRepository handles users' data exports
+type DataExportRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewDataExportRepository creates a new data export repository
+func NewDataExportRepository(db *pgxpool.Pool) *DataExportRepository {
+	return &DataExportRepository{db: db}
+}
+
+// Create stores a new pending export
+func (r *DataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	query := `INSERT INTO data_exports (id, user_id, status, created_at) VALUES ($1, $2, $3, $4)`
+
+	export.ID = uuid.New()
+	export.Status = models.DataExportPending
+	export.CreatedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, export.ID, export.UserID, export.Status, export.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a data export by ID
+func (r *DataExportRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE id = $1`
+
+	export, err := scanDataExport(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrDataExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// GetActiveByUser retrieves the user's export that is still being built, or
+// nil if there is none
+func (r *DataExportRepository) GetActiveByUser(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	query := `
+		SELECT ` + dataExportColumns + ` FROM data_exports
+		WHERE user_id = $1 AND status IN ('pending', 'processing')
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	export, err := scanDataExport(conn(ctx, r.db).QueryRow(ctx, query, userID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// ListByUser retrieves a user's exports, newest first
+func (r *DataExportRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.DataExport, error) {
+	query := `SELECT ` + dataExportColumns + ` FROM data_exports WHERE user_id = $1 ORDER BY created_at DESC`
+
+	return r.list(ctx, query, userID)
+}
+
+// ClaimNext marks the oldest pending export as processing and returns it, or
+// nil if none is pending. Concurrent instances never claim the same export.
+func (r *DataExportRepository) ClaimNext(ctx context.Context) (*models.DataExport, error) {
+	query := `
+		UPDATE data_exports SET status = 'processing', started_at = $1
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'pending'
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + dataExportColumns
+
+	export, err := scanDataExport(conn(ctx, r.db).QueryRow(ctx, query, time.Now().UTC()))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// RequeueStale returns exports that started processing before the cutoff,
+// whose instance presumably stopped, to pending. It returns how many there were.
+func (r *DataExportRepository) RequeueStale(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `UPDATE data_exports SET status = 'pending', started_at = NULL WHERE status = 'processing' AND started_at < $1`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue data exports: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// Complete records that an export's archive is ready
+func (r *DataExportRepository) Complete(ctx context.Context, export *models.DataExport) error {
+	query := `
+		UPDATE data_exports
+		SET status = 'completed', file_path = $1, file_size = $2, documents = $3, completed_at = $4, expires_at = $5
+		WHERE id = $6
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		export.FilePath,
+		export.FileSize,
+		export.Documents,
+		export.CompletedAt,
+		export.ExpiresAt,
+		export.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+
+	return nil
+}
+
+// Fail records that an export could not be built
+func (r *DataExportRepository) Fail(ctx context.Context, id uuid.UUID, message string) error {
+	query := `UPDATE data_exports SET status = 'failed', error = $1, completed_at = $2 WHERE id = $3`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, message, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to fail data export: %w", err)
+	}
+
+	return nil
+}
+
+// ListExpired retrieves exports that expired before the cutoff, oldest first
+func (r *DataExportRepository) ListExpired(ctx context.Context, cutoff time.Time, limit int) ([]*models.DataExport, error) {
+	query := `
+		SELECT ` + dataExportColumns + ` FROM data_exports
+		WHERE expires_at < $1
+		ORDER BY expires_at
+		LIMIT $2
+	`
+
+	return r.list(ctx, query, cutoff, limit)
+}
+
+// Delete removes an export
+func (r *DataExportRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM data_exports WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete data export: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DataExportRepository) list(ctx context.Context, query string, args ...any) ([]*models.DataExport, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []*models.DataExport{}
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data export: %w", err)
+		}
+		exports = append(exports, export)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list data exports: %w", err)
+	}
+
+	return exports, nil
+}
+
+func scanDataExport(row pgx.Row) (*models.DataExport, error) {
+	var export models.DataExport
+	err := row.Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&export.FilePath,
+		&export.FileSize,
+		&export.Documents,
+		&export.Error,
+		&export.CreatedAt,
+		&export.StartedAt,
+		&export.CompletedAt,
+		&export.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
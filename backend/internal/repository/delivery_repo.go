@@ -0,0 +1,361 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrDeliveryTargetNotFound is returned when a delivery target does not exist
+var ErrDeliveryTargetNotFound = apperrors.NotFound("DLV_001", "delivery target not found")
+
+// deliveryTargetColumns are the columns scanned by scanDeliveryTarget
+const deliveryTargetColumns = `id, user_id, kind, name, settings, credentials, formats, labels, enabled,
+	cursor_at, cursor_id, created_at, updated_at`
+
+// resultDeliveryColumns are the columns scanned by scanResultDelivery
+const resultDeliveryColumns = `id, target_id, job_id, user_id, status, files, attempts, last_error,
+	next_attempt_at, delivered_at, created_at`
+
+// DeliveryRepository handles users' delivery targets and the deliveries of
+// results to them
+type DeliveryRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewDeliveryRepository creates a new delivery repository
+func NewDeliveryRepository(db *pgxpool.Pool) *DeliveryRepository {
+	return &DeliveryRepository{db: db}
+}
+
+// Create stores a new target, given the jobs that complete after its cursor.
+// Its ID is chosen by the caller, as its credentials are sealed to it.
+func (r *DeliveryRepository) Create(ctx context.Context, target *models.DeliveryTarget) error {
+	query := `
+		INSERT INTO delivery_targets (
+			id, user_id, kind, name, settings, credentials, formats, labels, enabled,
+			cursor_at, cursor_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+	`
+
+	target.CreatedAt = time.Now().UTC()
+	target.UpdatedAt = target.CreatedAt
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		target.ID,
+		target.UserID,
+		target.Kind,
+		target.Name,
+		target.Settings,
+		target.Credentials,
+		target.Formats,
+		target.Labels,
+		target.Enabled,
+		target.CursorAt,
+		target.CursorID,
+		target.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create delivery target: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a target by ID
+func (r *DeliveryRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DeliveryTarget, error) {
+	query := `SELECT ` + deliveryTargetColumns + ` FROM delivery_targets WHERE id = $1`
+
+	target, err := scanDeliveryTarget(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrDeliveryTargetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery target: %w", err)
+	}
+
+	return target, nil
+}
+
+// ListByUser retrieves a user's targets, newest first
+func (r *DeliveryRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.DeliveryTarget, error) {
+	query := `SELECT ` + deliveryTargetColumns + ` FROM delivery_targets WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list delivery targets: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDeliveryTargets(rows)
+}
+
+// Update saves a target's name, settings, credentials, formats, whether it is
+// enabled and its cursor
+func (r *DeliveryRepository) Update(ctx context.Context, target *models.DeliveryTarget) error {
+	query := `
+		UPDATE delivery_targets
+		SET name = $1, settings = $2, credentials = $3, formats = $4, enabled = $5,
+			cursor_at = $6, cursor_id = $7, updated_at = $8
+		WHERE id = $9
+	`
+
+	target.UpdatedAt = time.Now().UTC()
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		target.Name,
+		target.Settings,
+		target.Credentials,
+		target.Formats,
+		target.Enabled,
+		target.CursorAt,
+		target.CursorID,
+		target.UpdatedAt,
+		target.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery target: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDeliveryTargetNotFound
+	}
+
+	return nil
+}
+
+// SetHostKey records the host key an SFTP target's server was trusted with
+func (r *DeliveryRepository) SetHostKey(ctx context.Context, id uuid.UUID, hostKey string) error {
+	query := `
+		UPDATE delivery_targets
+		SET settings = jsonb_set(settings, '{host_key}', to_jsonb($1::text))
+		WHERE id = $2 AND COALESCE(settings->>'host_key', '') = ''
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, hostKey, id); err != nil {
+		return fmt.Errorf("failed to set delivery target host key: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a target along with its deliveries
+func (r *DeliveryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM delivery_targets WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete delivery target: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDeliveryTargetNotFound
+	}
+
+	return nil
+}
+
+// EnqueueCompleted creates a pending delivery to each enabled target for the
+// jobs of its user that completed after its cursor and before settledBefore,
+// at most limit per target, and moves the cursors past them. Jobs that
+// completed more recently are left for later, so a job committed late with an
+// earlier completion time is not passed over. Targets being enqueued by
+// another replica are skipped. It returns the number of deliveries created.
+func (r *DeliveryRepository) EnqueueCompleted(ctx context.Context, settledBefore time.Time, limit int) (int, error) {
+	query := `
+		WITH targets AS (
+			SELECT id, user_id, labels, cursor_at, cursor_id FROM delivery_targets
+			WHERE enabled
+			FOR UPDATE SKIP LOCKED
+		), picked AS (
+			SELECT t.id AS target_id, j.id AS job_id, t.user_id, j.completed_at
+			FROM targets t
+			CROSS JOIN LATERAL (
+				SELECT j.id, j.completed_at FROM ocr_jobs j
+				JOIN documents d ON d.id = j.document_id
+				WHERE j.user_id = t.user_id AND j.status = 'completed' AND d.deleted_at IS NULL
+				  AND (t.labels IS NULL OR d.labels && t.labels)
+				  AND (j.completed_at, j.id) > (t.cursor_at, t.cursor_id) AND j.completed_at <= $1
+				ORDER BY j.completed_at, j.id
+				LIMIT $2
+			) j
+		), inserted AS (
+			INSERT INTO result_deliveries (id, target_id, job_id, user_id, status, next_attempt_at, created_at)
+			SELECT uuid_generate_v4(), target_id, job_id, user_id, 'pending', $3, $3 FROM picked
+			ON CONFLICT (target_id, job_id) DO NOTHING
+			RETURNING id
+		), advanced AS (
+			UPDATE delivery_targets t
+			SET cursor_at = last.completed_at, cursor_id = last.job_id
+			FROM (
+				SELECT DISTINCT ON (target_id) target_id, completed_at, job_id FROM picked
+				ORDER BY target_id, completed_at DESC, job_id DESC
+			) last
+			WHERE t.id = last.target_id
+		)
+		SELECT COUNT(*) FROM inserted
+	`
+
+	var count int
+	if err := conn(ctx, r.db).QueryRow(ctx, query, settledBefore, limit, time.Now().UTC()).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to enqueue result deliveries: %w", err)
+	}
+
+	return count, nil
+}
+
+// ClaimDue locks pending deliveries to enabled targets due for an attempt,
+// oldest first.
+// It must run inside a transaction; deliveries locked by another replica are skipped.
+func (r *DeliveryRepository) ClaimDue(ctx context.Context, limit int) ([]*models.ResultDelivery, error) {
+	query := `SELECT ` + resultDeliveryColumns + `
+		FROM result_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1
+			AND target_id IN (SELECT id FROM delivery_targets WHERE enabled)
+		ORDER BY next_attempt_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due result deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanResultDeliveries(rows)
+}
+
+// UpdateDelivery saves the status, files, attempts, last error and next
+// attempt time of a delivery after an attempt
+func (r *DeliveryRepository) UpdateDelivery(ctx context.Context, delivery *models.ResultDelivery) error {
+	query := `
+		UPDATE result_deliveries
+		SET status = $1, files = $2, attempts = $3, last_error = $4,
+			next_attempt_at = $5, delivered_at = $6
+		WHERE id = $7
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		delivery.Status,
+		delivery.Files,
+		delivery.Attempts,
+		delivery.LastError,
+		delivery.NextAttemptAt,
+		delivery.DeliveredAt,
+		delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update result delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ListByJob retrieves the deliveries of a user's job, oldest first
+func (r *DeliveryRepository) ListByJob(ctx context.Context, jobID, userID uuid.UUID) ([]*models.ResultDelivery, error) {
+	query := `SELECT ` + resultDeliveryColumns + ` FROM result_deliveries WHERE job_id = $1 AND user_id = $2 ORDER BY created_at`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, jobID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list result deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanResultDeliveries(rows)
+}
+
+// ListByTarget retrieves the latest deliveries to a target, newest first
+func (r *DeliveryRepository) ListByTarget(ctx context.Context, targetID uuid.UUID, limit int) ([]*models.ResultDelivery, error) {
+	query := `SELECT ` + resultDeliveryColumns + ` FROM result_deliveries WHERE target_id = $1 ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, targetID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list result deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanResultDeliveries(rows)
+}
+
+func scanDeliveryTargets(rows pgx.Rows) ([]*models.DeliveryTarget, error) {
+	targets := []*models.DeliveryTarget{}
+	for rows.Next() {
+		target, err := scanDeliveryTarget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery target: %w", err)
+		}
+		targets = append(targets, target)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list delivery targets: %w", err)
+	}
+
+	return targets, nil
+}
+
+func scanDeliveryTarget(row pgx.Row) (*models.DeliveryTarget, error) {
+	var target models.DeliveryTarget
+	err := row.Scan(
+		&target.ID,
+		&target.UserID,
+		&target.Kind,
+		&target.Name,
+		&target.Settings,
+		&target.Credentials,
+		&target.Formats,
+		&target.Labels,
+		&target.Enabled,
+		&target.CursorAt,
+		&target.CursorID,
+		&target.CreatedAt,
+		&target.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+func scanResultDeliveries(rows pgx.Rows) ([]*models.ResultDelivery, error) {
+	deliveries := []*models.ResultDelivery{}
+	for rows.Next() {
+		delivery, err := scanResultDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan result delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list result deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func scanResultDelivery(row pgx.Row) (*models.ResultDelivery, error) {
+	var delivery models.ResultDelivery
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.TargetID,
+		&delivery.JobID,
+		&delivery.UserID,
+		&delivery.Status,
+		&delivery.Files,
+		&delivery.Attempts,
+		&delivery.LastError,
+		&delivery.NextAttemptAt,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
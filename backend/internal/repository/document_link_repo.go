@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrDocumentLinkNotFound is returned when a document link does not exist
+	ErrDocumentLinkNotFound = apperrors.NotFound("LINK_001", "document link not found")
+	// ErrDocumentLinkExists is returned when two documents are already linked
+	// with the same relation
+	ErrDocumentLinkExists = apperrors.Conflict("LINK_002", "documents are already linked with this relation")
+)
+
+// documentLinkColumns are the columns scanned by scanDocumentLink
+const documentLinkColumns = `id, user_id, source_id, target_id, relation, position, note, created_at`
+
+// DocumentLinkRepository handles links between documents
+type DocumentLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewDocumentLinkRepository creates a new document link repository
+func NewDocumentLinkRepository(db *pgxpool.Pool) *DocumentLinkRepository {
+	return &DocumentLinkRepository{db: db}
+}
+
+// Create stores a new link, or returns ErrDocumentLinkExists if the documents
+// are already linked the same way
+func (r *DocumentLinkRepository) Create(ctx context.Context, link *models.DocumentLink) error {
+	query := `
+		INSERT INTO document_links (id, user_id, source_id, target_id, relation, position, note, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (source_id, target_id, relation) DO NOTHING
+	`
+
+	link.ID = uuid.New()
+	link.CreatedAt = time.Now().UTC()
+
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		link.ID,
+		link.UserID,
+		link.SourceID,
+		link.TargetID,
+		link.Relation,
+		link.Position,
+		link.Note,
+		link.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create document link: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentLinkExists
+	}
+
+	return nil
+}
+
+// GetByID retrieves one of a user's links
+func (r *DocumentLinkRepository) GetByID(ctx context.Context, id, userID uuid.UUID) (*models.DocumentLink, error) {
+	query := `SELECT ` + documentLinkColumns + ` FROM document_links WHERE id = $1 AND user_id = $2`
+
+	link, err := scanDocumentLink(conn(ctx, r.db).QueryRow(ctx, query, id, userID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrDocumentLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document link: %w", err)
+	}
+
+	return link, nil
+}
+
+// Update stores the relation, position and note of a link
+func (r *DocumentLinkRepository) Update(ctx context.Context, link *models.DocumentLink) error {
+	query := `
+		UPDATE document_links SET relation = $1, position = $2, note = $3
+		WHERE id = $4 AND NOT EXISTS (
+			SELECT 1 FROM document_links o
+			WHERE o.source_id = $5 AND o.target_id = $6 AND o.relation = $1 AND o.id <> $4
+		)
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, link.Relation, link.Position, link.Note, link.ID, link.SourceID, link.TargetID)
+	if err != nil {
+		return fmt.Errorf("failed to update document link: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentLinkExists
+	}
+
+	return nil
+}
+
+// Delete removes one of a user's links
+func (r *DocumentLinkRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM document_links WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete document link: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentLinkNotFound
+	}
+
+	return nil
+}
+
+// ListByDocuments retrieves a user's links from or to any of the documents,
+// optionally only those with one of the relations
+func (r *DocumentLinkRepository) ListByDocuments(ctx context.Context, userID uuid.UUID, documentIDs []uuid.UUID, relations []string) ([]*models.DocumentLink, error) {
+	query := `SELECT ` + documentLinkColumns + `
+		FROM document_links
+		WHERE user_id = $1 AND (source_id = ANY($2) OR target_id = ANY($2))
+		  AND ($3::text[] IS NULL OR relation = ANY($3))
+		ORDER BY relation, position NULLS LAST, created_at
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, documentIDs, relations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []*models.DocumentLink{}
+	for rows.Next() {
+		link, err := scanDocumentLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document link: %w", err)
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list document links: %w", err)
+	}
+
+	return links, nil
+}
+
+func scanDocumentLink(row pgx.Row) (*models.DocumentLink, error) {
+	var link models.DocumentLink
+	err := row.Scan(
+		&link.ID,
+		&link.UserID,
+		&link.SourceID,
+		&link.TargetID,
+		&link.Relation,
+		&link.Position,
+		&link.Note,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
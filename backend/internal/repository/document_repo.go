@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"visekai/backend/internal/apperrors"
 	"visekai/backend/internal/models"
 
 	"github.com/google/uuid"
@@ -12,14 +14,20 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrDocumentNotFound is returned when a document does not exist
+var ErrDocumentNotFound = apperrors.NotFound("RES_002", "document not found")
+
 // DocumentRepository handles document database operations
 type DocumentRepository struct {
 	db *pgxpool.Pool
+	// replica serves list queries; nil uses db
+	replica *pgxpool.Pool
 }
 
-// NewDocumentRepository creates a new document repository
-func NewDocumentRepository(db *pgxpool.Pool) *DocumentRepository {
-	return &DocumentRepository{db: db}
+// NewDocumentRepository creates a new document repository. The replica, if not
+// nil, serves list queries.
+func NewDocumentRepository(db, replica *pgxpool.Pool) *DocumentRepository {
+	return &DocumentRepository{db: db, replica: replica}
 }
 
 // Create creates a new document in the database
@@ -27,15 +35,21 @@ func (r *DocumentRepository) Create(ctx context.Context, doc *models.Document) e
 	query := `
 		INSERT INTO documents (
 			id, user_id, filename, original_filename, file_path,
-			file_size, mime_type, file_hash, num_pages, thumbnail_path, uploaded_at
+			file_size, mime_type, file_hash, num_pages, thumbnail_path, uploaded_at, metadata, labels, org_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
 
 	doc.ID = uuid.New()
-	doc.UploadedAt = time.Now()
+	doc.UploadedAt = time.Now().UTC()
+	if doc.Metadata == nil {
+		doc.Metadata = map[string]any{}
+	}
+	if doc.Labels == nil {
+		doc.Labels = []string{}
+	}
 
-	_, err := r.db.Exec(ctx, query,
+	_, err := conn(ctx, r.db).Exec(ctx, query,
 		doc.ID,
 		doc.UserID,
 		doc.Filename,
@@ -47,6 +61,9 @@ func (r *DocumentRepository) Create(ctx context.Context, doc *models.Document) e
 		doc.NumPages,
 		doc.ThumbnailPath,
 		doc.UploadedAt,
+		doc.Metadata,
+		doc.Labels,
+		doc.OrgID,
 	)
 
 	if err != nil {
@@ -61,13 +78,13 @@ func (r *DocumentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 	query := `
 		SELECT id, user_id, filename, original_filename, file_path,
 		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
-		       uploaded_at, deleted_at
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
 		FROM documents
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND deleted_at IS NULL AND ($2::text[] IS NULL OR labels && $2)
 	`
 
 	var doc models.Document
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := conn(ctx, r.db).QueryRow(ctx, query, id, LabelScope(ctx)).Scan(
 		&doc.ID,
 		&doc.UserID,
 		&doc.Filename,
@@ -80,10 +97,15 @@ func (r *DocumentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 		&doc.ThumbnailPath,
 		&doc.UploadedAt,
 		&doc.DeletedAt,
+		&doc.Metadata,
+		&doc.Labels,
+		&doc.Quality,
+		&doc.Classification,
+		&doc.OrgID,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("document not found")
+		return nil, ErrDocumentNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get document: %w", err)
@@ -112,9 +134,14 @@ func (r *DocumentRepository) ListByUser(ctx context.Context, userID uuid.UUID, r
 	}
 
 	// Count total documents
-	countQuery := `SELECT COUNT(*) FROM documents WHERE user_id = $1 AND deleted_at IS NULL`
+	countQuery := `
+		SELECT COUNT(*) FROM documents
+		WHERE user_id = $1 AND deleted_at IS NULL AND ($2::text[] IS NULL OR labels && $2)
+		  AND ($3::int IS NULL OR quality_score >= $3) AND ($4::int IS NULL OR quality_score <= $4)
+		  AND ($5 = '' OR document_type = $5)
+	`
 	var total int
-	err := r.db.QueryRow(ctx, countQuery, userID).Scan(&total)
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, countQuery, userID, LabelScope(ctx), req.MinQuality, req.MaxQuality, req.Type).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count documents: %w", err)
 	}
@@ -123,14 +150,16 @@ func (r *DocumentRepository) ListByUser(ctx context.Context, userID uuid.UUID, r
 	query := fmt.Sprintf(`
 		SELECT id, user_id, filename, original_filename, file_path,
 		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
-		       uploaded_at, deleted_at
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
 		FROM documents
-		WHERE user_id = $1 AND deleted_at IS NULL
-		ORDER BY %s %s
+		WHERE user_id = $1 AND deleted_at IS NULL AND ($4::text[] IS NULL OR labels && $4)
+		  AND ($5::int IS NULL OR quality_score >= $5) AND ($6::int IS NULL OR quality_score <= $6)
+		  AND ($7 = '' OR document_type = $7)
+		ORDER BY %s %s NULLS LAST
 		LIMIT $2 OFFSET $3
 	`, req.SortBy, order)
 
-	rows, err := r.db.Query(ctx, query, userID, req.PerPage, offset)
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID, req.PerPage, offset, LabelScope(ctx), req.MinQuality, req.MaxQuality, req.Type)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list documents: %w", err)
 	}
@@ -152,6 +181,11 @@ func (r *DocumentRepository) ListByUser(ctx context.Context, userID uuid.UUID, r
 			&doc.ThumbnailPath,
 			&doc.UploadedAt,
 			&doc.DeletedAt,
+			&doc.Metadata,
+			&doc.Labels,
+			&doc.Quality,
+			&doc.Classification,
+			&doc.OrgID,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan document: %w", err)
@@ -162,35 +196,528 @@ func (r *DocumentRepository) ListByUser(ctx context.Context, userID uuid.UUID, r
 	return documents, total, nil
 }
 
+// UpdateQuality stores the quality assessment of a document's latest result
+func (r *DocumentRepository) UpdateQuality(ctx context.Context, id uuid.UUID, quality *models.DocumentQuality) error {
+	query := `UPDATE documents SET quality = $1, quality_score = $2 WHERE id = $3`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, quality, quality.Score, id)
+	if err != nil {
+		return fmt.Errorf("failed to update document quality: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+// UpdateClassification stores the type detected for a document
+func (r *DocumentRepository) UpdateClassification(ctx context.Context, id uuid.UUID, classification *models.DocumentClassification) error {
+	query := `UPDATE documents SET classification = $1, document_type = $2 WHERE id = $3`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, classification, classification.Type, id)
+	if err != nil {
+		return fmt.Errorf("failed to update document classification: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+// UpdateNumPages stores the page count of a document
+func (r *DocumentRepository) UpdateNumPages(ctx context.Context, id uuid.UUID, numPages int) error {
+	query := `UPDATE documents SET num_pages = $1 WHERE id = $2`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, numPages, id)
+	if err != nil {
+		return fmt.Errorf("failed to update document page count: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+// ListByMimeTypeAfter retrieves up to limit documents of a MIME type, those in
+// the trash included, ordered by ID from the first after afterID, for going
+// through all of them in batches
+func (r *DocumentRepository) ListByMimeTypeAfter(ctx context.Context, mimeType string, afterID uuid.UUID, limit int) ([]models.Document, error) {
+	query := `
+		SELECT id, user_id, filename, original_filename, file_path,
+		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
+		FROM documents
+		WHERE mime_type = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, mimeType, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// CountByMimeType counts the documents of a MIME type, those in the trash
+// included
+func (r *DocumentRepository) CountByMimeType(ctx context.Context, mimeType string) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT COUNT(*) FROM documents WHERE mime_type = $1`, mimeType).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+	return count, nil
+}
+
+// LockForShare locks a live document against deletion until the transaction in
+// ctx ends. Concurrent readers holding the same lock do not block each other.
+func (r *DocumentRepository) LockForShare(ctx context.Context, id uuid.UUID) error {
+	return r.lock(ctx, id, "FOR SHARE")
+}
+
+// LockForUpdate locks a live document exclusively until the transaction in ctx ends
+func (r *DocumentRepository) LockForUpdate(ctx context.Context, id uuid.UUID) error {
+	return r.lock(ctx, id, "FOR UPDATE")
+}
+
+func (r *DocumentRepository) lock(ctx context.Context, id uuid.UUID, mode string) error {
+	query := `SELECT id FROM documents WHERE id = $1 AND deleted_at IS NULL ` + mode
+
+	err := conn(ctx, r.db).QueryRow(ctx, query, id).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return ErrDocumentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock document: %w", err)
+	}
+
+	return nil
+}
+
 // SoftDelete soft deletes a document
 func (r *DocumentRepository) SoftDelete(ctx context.Context, id uuid.UUID) error {
 	query := `UPDATE documents SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
 
-	result, err := r.db.Exec(ctx, query, time.Now(), id)
+	result, err := conn(ctx, r.db).Exec(ctx, query, time.Now().UTC(), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete document: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("document not found")
+		return ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+// ListDeletedByUser retrieves a user's soft-deleted documents, most recently deleted first
+func (r *DocumentRepository) ListDeletedByUser(ctx context.Context, userID uuid.UUID, page, perPage int) ([]models.Document, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	// Count deleted documents
+	countQuery := `
+		SELECT COUNT(*) FROM documents
+		WHERE user_id = $1 AND deleted_at IS NOT NULL AND ($2::text[] IS NULL OR labels && $2)
+	`
+	var total int
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, countQuery, userID, LabelScope(ctx)).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count deleted documents: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, filename, original_filename, file_path,
+		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
+		FROM documents
+		WHERE user_id = $1 AND deleted_at IS NOT NULL AND ($4::text[] IS NULL OR labels && $4)
+		ORDER BY deleted_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID, perPage, (page-1)*perPage, LabelScope(ctx))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list deleted documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents, err := scanDocuments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return documents, total, nil
+}
+
+// GetDeletedByID retrieves a soft-deleted document by ID
+func (r *DocumentRepository) GetDeletedByID(ctx context.Context, id uuid.UUID) (*models.Document, error) {
+	query := `
+		SELECT id, user_id, filename, original_filename, file_path,
+		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
+		FROM documents
+		WHERE id = $1 AND deleted_at IS NOT NULL AND ($2::text[] IS NULL OR labels && $2)
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, id, LabelScope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted document: %w", err)
+	}
+	defer rows.Close()
+
+	documents, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, ErrDocumentNotFound
+	}
+
+	return &documents[0], nil
+}
+
+// Restore moves a soft-deleted document out of the trash
+func (r *DocumentRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE documents SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore document: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+// ListDeletedBefore retrieves documents soft-deleted before the cutoff, oldest first
+func (r *DocumentRepository) ListDeletedBefore(ctx context.Context, cutoff time.Time, limit int) ([]models.Document, error) {
+	query := `
+		SELECT id, user_id, filename, original_filename, file_path,
+		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
+		FROM documents
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1
+		ORDER BY deleted_at ASC
+		LIMIT $2
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired documents: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// HardDelete permanently removes a soft-deleted document.
+// Jobs and results of the document are removed by the ON DELETE CASCADE constraints.
+func (r *DocumentRepository) HardDelete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM documents WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge document: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+// ListAllByUser retrieves every document of a user, those in the trash
+// included, oldest first
+func (r *DocumentRepository) ListAllByUser(ctx context.Context, userID uuid.UUID) ([]models.Document, error) {
+	query := `
+		SELECT id, user_id, filename, original_filename, file_path,
+		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
+		FROM documents
+		WHERE user_id = $1
+		ORDER BY uploaded_at ASC
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	return documents, nil
+}
+
+// ReferencedPaths returns the subset of the given file paths that are referenced
+// by a document or thumbnail, including documents in the trash
+func (r *DocumentRepository) ReferencedPaths(ctx context.Context, paths []string) (map[string]bool, error) {
+	query := `
+		SELECT file_path FROM documents WHERE file_path = ANY($1)
+		UNION
+		SELECT thumbnail_path FROM documents WHERE thumbnail_path = ANY($1)
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check referenced paths: %w", err)
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan path: %w", err)
+		}
+		referenced[path] = true
+	}
+
+	return referenced, nil
+}
+
+// scanDocuments scans document rows
+func scanDocuments(rows pgx.Rows) ([]models.Document, error) {
+	documents := []models.Document{}
+	for rows.Next() {
+		var doc models.Document
+		err := rows.Scan(
+			&doc.ID,
+			&doc.UserID,
+			&doc.Filename,
+			&doc.OriginalFilename,
+			&doc.FilePath,
+			&doc.FileSize,
+			&doc.MimeType,
+			&doc.FileHash,
+			&doc.NumPages,
+			&doc.ThumbnailPath,
+			&doc.UploadedAt,
+			&doc.DeletedAt,
+			&doc.Metadata,
+			&doc.Labels,
+			&doc.Quality,
+			&doc.Classification,
+			&doc.OrgID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, doc)
+	}
+
+	return documents, nil
+}
+
+// ListForImport retrieves a user's active documents with any of the given IDs,
+// or known by one of the given names: their original filename, the filename as
+// uploaded or an alias
+func (r *DocumentRepository) ListForImport(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, filenames []string) ([]models.Document, error) {
+	query := `
+		SELECT id, user_id, filename, original_filename, file_path,
+		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
+		FROM documents
+		WHERE user_id = $1 AND deleted_at IS NULL
+		  AND (id = ANY($2) OR original_filename = ANY($3) OR metadata->>'source_filename' = ANY($3)
+		       OR metadata->'aliases' ?| $3)
+		  AND ($4::text[] IS NULL OR labels && $4)
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, ids, filenames, LabelScope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	return documents, nil
+}
+
+// UpdateMetadata replaces the labels and metadata of a document
+func (r *DocumentRepository) UpdateMetadata(ctx context.Context, id uuid.UUID, labels []string, metadata map[string]any) error {
+	query := `UPDATE documents SET labels = $1, metadata = $2 WHERE id = $3 AND deleted_at IS NULL`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, labels, metadata, id)
+	if err != nil {
+		return fmt.Errorf("failed to update document metadata: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+// ListByIDs retrieves the user's active documents with any of the given IDs
+func (r *DocumentRepository) ListByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) ([]models.Document, error) {
+	query := `
+		SELECT id, user_id, filename, original_filename, file_path,
+		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
+		FROM documents
+		WHERE user_id = $1 AND id = ANY($2) AND deleted_at IS NULL AND ($3::text[] IS NULL OR labels && $3)
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, ids, LabelScope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	return documents, nil
+}
+
+// ListByOrg retrieves the active documents shared with an organization, newest first
+func (r *DocumentRepository) ListByOrg(ctx context.Context, orgID uuid.UUID, page, perPage int) ([]models.Document, int, error) {
+	countQuery := `
+		SELECT COUNT(*) FROM documents
+		WHERE org_id = $1 AND deleted_at IS NULL AND ($2::text[] IS NULL OR labels && $2)
+	`
+	var total int
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, countQuery, orgID, LabelScope(ctx)).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, filename, original_filename, file_path,
+		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
+		FROM documents
+		WHERE org_id = $1 AND deleted_at IS NULL AND ($4::text[] IS NULL OR labels && $4)
+		ORDER BY uploaded_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, orgID, perPage, (page-1)*perPage, LabelScope(ctx))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents, err := scanDocuments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return documents, total, nil
+}
+
+// SetOrg shares a document with an organization, or stops sharing it when
+// orgID is nil
+func (r *DocumentRepository) SetOrg(ctx context.Context, id uuid.UUID, orgID *uuid.UUID) error {
+	query := `UPDATE documents SET org_id = $1 WHERE id = $2 AND deleted_at IS NULL`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, orgID, id)
+	if err != nil {
+		return fmt.Errorf("failed to update document organization: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrDocumentNotFound
 	}
 
 	return nil
 }
 
+// UnshareByUser stops sharing a user's documents with an organization, including
+// documents in the trash, and returns the number of documents unshared
+func (r *DocumentRepository) UnshareByUser(ctx context.Context, orgID, userID uuid.UUID) (int64, error) {
+	query := `UPDATE documents SET org_id = NULL WHERE org_id = $1 AND user_id = $2`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, orgID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unshare documents: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// FilenamesWithPrefix returns the original filenames of a user's active documents
+// that start with the given prefix
+func (r *DocumentRepository) FilenamesWithPrefix(ctx context.Context, userID uuid.UUID, prefix string) (map[string]bool, error) {
+	query := `
+		SELECT original_filename FROM documents
+		WHERE user_id = $1 AND deleted_at IS NULL AND original_filename LIKE $2 ESCAPE '\'
+	`
+
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, escaped+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list filenames: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan filename: %w", err)
+		}
+		names[name] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list filenames: %w", err)
+	}
+
+	return names, nil
+}
+
 // GetByHash retrieves a document by file hash (for deduplication)
 func (r *DocumentRepository) GetByHash(ctx context.Context, hash string, userID uuid.UUID) (*models.Document, error) {
 	query := `
 		SELECT id, user_id, filename, original_filename, file_path,
 		       file_size, mime_type, file_hash, num_pages, thumbnail_path,
-		       uploaded_at, deleted_at
+		       uploaded_at, deleted_at, metadata, labels, quality, classification, org_id
 		FROM documents
-		WHERE file_hash = $1 AND user_id = $2 AND deleted_at IS NULL
+		WHERE file_hash = $1 AND user_id = $2 AND deleted_at IS NULL AND ($3::text[] IS NULL OR labels && $3)
 		LIMIT 1
 	`
 
 	var doc models.Document
-	err := r.db.QueryRow(ctx, query, hash, userID).Scan(
+	err := conn(ctx, r.db).QueryRow(ctx, query, hash, userID, LabelScope(ctx)).Scan(
 		&doc.ID,
 		&doc.UserID,
 		&doc.Filename,
@@ -203,6 +730,11 @@ func (r *DocumentRepository) GetByHash(ctx context.Context, hash string, userID
 		&doc.ThumbnailPath,
 		&doc.UploadedAt,
 		&doc.DeletedAt,
+		&doc.Metadata,
+		&doc.Labels,
+		&doc.Quality,
+		&doc.Classification,
+		&doc.OrgID,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -214,3 +746,42 @@ func (r *DocumentRepository) GetByHash(ctx context.Context, hash string, userID
 
 	return &doc, nil
 }
+
+// GetByHashFromOthers retrieves a live document with the given file hash owned by
+// another member of one of the organizations, preferring documents that already
+// have a result, then the oldest. It returns nil when there is none.
+func (r *DocumentRepository) GetByHashFromOthers(ctx context.Context, hash string, userID uuid.UUID, orgIDs []uuid.UUID) (*models.Document, error) {
+	if len(orgIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT d.id, d.user_id, d.filename, d.original_filename, d.file_path,
+		       d.file_size, d.mime_type, d.file_hash, d.num_pages, d.thumbnail_path,
+		       d.uploaded_at, d.deleted_at, d.metadata, d.labels, d.quality, d.classification, d.org_id
+		FROM documents d
+		WHERE d.file_hash = $1 AND d.user_id <> $2 AND d.deleted_at IS NULL
+		  AND EXISTS (
+		      SELECT 1 FROM organization_members m
+		      WHERE m.user_id = d.user_id AND m.organization_id = ANY($3)
+		  )
+		ORDER BY EXISTS (SELECT 1 FROM ocr_results r WHERE r.document_id = d.id AND r.deleted_at IS NULL) DESC, d.uploaded_at
+		LIMIT 1
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, hash, userID, orgIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document by hash: %w", err)
+	}
+	defer rows.Close()
+
+	documents, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	return &documents[0], nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EmailNotificationRepository handles the jobs and digests users are emailed
+// about
+type EmailNotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewEmailNotificationRepository creates a new email notification repository
+func NewEmailNotificationRepository(db *pgxpool.Pool) *EmailNotificationRepository {
+	return &EmailNotificationRepository{db: db}
+}
+
+// ClaimFailedJobs locks failed jobs that finished since a time and were not
+// emailed about since they failed, oldest first, for users who get failure
+// emails. Must be called within a transaction so other instances skip them.
+func (r *EmailNotificationRepository) ClaimFailedJobs(ctx context.Context, since time.Time, limit int) ([]*models.FailedJobEmail, error) {
+	query := `
+		SELECT j.id, j.user_id, j.document_id, d.original_filename, j.error_message,
+			   j.completed_at, u.email, COALESCE(s.timezone, 'UTC')
+		FROM ocr_jobs j
+		JOIN documents d ON d.id = j.document_id
+		JOIN users u ON u.id = j.user_id
+		LEFT JOIN user_settings s ON s.user_id = j.user_id
+		WHERE j.status = 'failed' AND j.deleted_at IS NULL AND j.completed_at >= $1
+		  AND (j.failure_emailed_at IS NULL OR j.failure_emailed_at < j.completed_at)
+		  AND COALESCE(s.email_notifications, TRUE) AND COALESCE(s.email_on_failure, TRUE)
+		ORDER BY j.completed_at
+		LIMIT $2
+		FOR UPDATE OF j SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim failed jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.FailedJobEmail{}
+	for rows.Next() {
+		var job models.FailedJobEmail
+		err := rows.Scan(
+			&job.JobID,
+			&job.UserID,
+			&job.DocumentID,
+			&job.Filename,
+			&job.ErrorMessage,
+			&job.CompletedAt,
+			&job.Email,
+			&job.Timezone,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan failed job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim failed jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// MarkFailureEmailed records that the failure of jobs was emailed
+func (r *EmailNotificationRepository) MarkFailureEmailed(ctx context.Context, jobIDs []uuid.UUID, at time.Time) error {
+	_, err := conn(ctx, r.db).Exec(ctx, `UPDATE ocr_jobs SET failure_emailed_at = $2 WHERE id = ANY($1)`, jobIDs, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark failure emailed: %w", err)
+	}
+	return nil
+}
+
+// ScheduleDigest sets when a user's next digest is sent. A user's first
+// digest covers the day before it; a rescheduled one keeps its period.
+func (r *EmailNotificationRepository) ScheduleDigest(ctx context.Context, userID uuid.UUID, nextAt time.Time) error {
+	query := `
+		INSERT INTO email_digests (user_id, period_start, next_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET next_at = EXCLUDED.next_at
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, userID, nextAt.AddDate(0, 0, -1), nextAt); err != nil {
+		return fmt.Errorf("failed to schedule digest: %w", err)
+	}
+	return nil
+}
+
+// DeleteDigest stops a user's digests
+func (r *EmailNotificationRepository) DeleteDigest(ctx context.Context, userID uuid.UUID) error {
+	if _, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM email_digests WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete digest: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueDigests locks the digests due by now of users who still get them.
+// Must be called within a transaction so other instances skip them.
+func (r *EmailNotificationRepository) ClaimDueDigests(ctx context.Context, now time.Time, limit int) ([]*models.EmailDigest, error) {
+	query := `
+		SELECT g.user_id, g.period_start, g.next_at, g.last_sent_at,
+			   u.email, s.timezone, s.digest_hour
+		FROM email_digests g
+		JOIN users u ON u.id = g.user_id
+		JOIN user_settings s ON s.user_id = g.user_id
+		WHERE g.next_at <= $1 AND s.email_digest AND COALESCE(s.email_notifications, TRUE)
+		ORDER BY g.next_at
+		LIMIT $2
+		FOR UPDATE OF g SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim digests: %w", err)
+	}
+	defer rows.Close()
+
+	digests := []*models.EmailDigest{}
+	for rows.Next() {
+		var digest models.EmailDigest
+		err := rows.Scan(
+			&digest.UserID,
+			&digest.PeriodStart,
+			&digest.NextAt,
+			&digest.LastSentAt,
+			&digest.Email,
+			&digest.Timezone,
+			&digest.DigestHour,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan digest: %w", err)
+		}
+		digests = append(digests, &digest)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim digests: %w", err)
+	}
+
+	return digests, nil
+}
+
+// UpdateDigest saves the period and time of a user's next digest, and when
+// the last one was sent
+func (r *EmailNotificationRepository) UpdateDigest(ctx context.Context, digest *models.EmailDigest) error {
+	query := `
+		UPDATE email_digests
+		SET period_start = $2, next_at = $3, last_sent_at = $4
+		WHERE user_id = $1
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, digest.UserID, digest.PeriodStart, digest.NextAt, digest.LastSentAt); err != nil {
+		return fmt.Errorf("failed to update digest: %w", err)
+	}
+	return nil
+}
+
+// DigestSummary sums up the user's jobs that finished in [from, until), with
+// the credits they used and the latest failures
+func (r *EmailNotificationRepository) DigestSummary(ctx context.Context, userID uuid.UUID, from, until time.Time, failures int) (*models.DigestSummary, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE j.status = 'completed'),
+			   COUNT(*) FILTER (WHERE j.status = 'failed'),
+			   COALESCE(SUM(r.num_pages) FILTER (WHERE j.status = 'completed'), 0),
+			   AVG(r.confidence_score) FILTER (WHERE j.status = 'completed'),
+			   (SELECT COALESCE(-SUM(amount), 0) FROM credit_transactions
+				WHERE user_id = $1 AND kind IN ('charge', 'adjustment', 'refund')
+				  AND created_at >= $2 AND created_at < $3)
+		FROM ocr_jobs j
+		LEFT JOIN LATERAL (
+			SELECT num_pages, confidence_score FROM ocr_results
+			WHERE job_id = j.id AND deleted_at IS NULL
+			ORDER BY created_at DESC
+			LIMIT 1
+		) r ON TRUE
+		WHERE j.user_id = $1 AND j.status IN ('completed', 'failed') AND j.deleted_at IS NULL
+		  AND j.completed_at >= $2 AND j.completed_at < $3
+	`
+
+	summary := &models.DigestSummary{FailedJobs: []*models.JobNotification{}}
+	err := conn(ctx, r.db).QueryRow(ctx, query, userID, from, until).Scan(
+		&summary.Completed,
+		&summary.Failed,
+		&summary.Pages,
+		&summary.AverageConfidence,
+		&summary.CreditsUsed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum up jobs: %w", err)
+	}
+	if summary.Failed == 0 {
+		return summary, nil
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, `
+		SELECT j.id, j.document_id, d.original_filename, j.status, j.error_message, j.completed_at
+		FROM ocr_jobs j
+		JOIN documents d ON d.id = j.document_id
+		WHERE j.user_id = $1 AND j.status = 'failed' AND j.deleted_at IS NULL
+		  AND j.completed_at >= $2 AND j.completed_at < $3
+		ORDER BY j.completed_at DESC
+		LIMIT $4
+	`, userID, from, until, failures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed jobs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var job models.JobNotification
+		if err := rows.Scan(&job.JobID, &job.DocumentID, &job.Filename, &job.Status, &job.ErrorMessage, &job.CompletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan failed job: %w", err)
+		}
+		summary.FailedJobs = append(summary.FailedJobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list failed jobs: %w", err)
+	}
+
+	return summary, nil
+}
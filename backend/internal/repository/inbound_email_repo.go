@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrInboundAddressNotFound is returned when an email is sent to an address no
+// user has
+var ErrInboundAddressNotFound = apperrors.NotFound("INB_001", "inbound email address not found")
+
+// inboundEmailColumns are the columns of inbound emails, in scan order
+const inboundEmailColumns = `id, user_id, message_id, sender, subject, document_ids, job_ids, skipped, replied_at, created_at`
+
+// InboundEmailRepository handles users' inbound email addresses and the
+// emails received at them
+type InboundEmailRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewInboundEmailRepository creates a new inbound email repository
+func NewInboundEmailRepository(db *pgxpool.Pool) *InboundEmailRepository {
+	return &InboundEmailRepository{db: db}
+}
+
+// GetToken returns the token of a user's inbound address
+func (r *InboundEmailRepository) GetToken(ctx context.Context, userID uuid.UUID) (string, time.Time, error) {
+	var token string
+	var createdAt time.Time
+	err := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT token, created_at FROM inbound_email_addresses WHERE user_id = $1`, userID,
+	).Scan(&token, &createdAt)
+	if err == pgx.ErrNoRows {
+		return "", time.Time{}, ErrInboundAddressNotFound
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to get inbound email address: %w", err)
+	}
+
+	return token, createdAt, nil
+}
+
+// SetToken gives a user an inbound address with the token, replacing the
+// address they had
+func (r *InboundEmailRepository) SetToken(ctx context.Context, userID uuid.UUID, token string) (time.Time, error) {
+	query := `
+		INSERT INTO inbound_email_addresses (user_id, token, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token = EXCLUDED.token, created_at = EXCLUDED.created_at
+	`
+
+	createdAt := time.Now().UTC()
+	if _, err := conn(ctx, r.db).Exec(ctx, query, userID, token, createdAt); err != nil {
+		return time.Time{}, fmt.Errorf("failed to set inbound email address: %w", err)
+	}
+
+	return createdAt, nil
+}
+
+// GetUserByToken returns the user an inbound address belongs to
+func (r *InboundEmailRepository) GetUserByToken(ctx context.Context, token string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT user_id FROM inbound_email_addresses WHERE token = $1`, token,
+	).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, ErrInboundAddressNotFound
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get inbound email address: %w", err)
+	}
+
+	return userID, nil
+}
+
+// Received reports whether a user already received the message with this
+// Message-ID
+func (r *InboundEmailRepository) Received(ctx context.Context, userID uuid.UUID, messageID string) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM inbound_emails WHERE user_id = $1 AND message_id = $2)`, userID, messageID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check inbound email: %w", err)
+	}
+
+	return exists, nil
+}
+
+// Create records a received email; a message received already is ignored
+func (r *InboundEmailRepository) Create(ctx context.Context, email *models.InboundEmail) error {
+	query := `
+		INSERT INTO inbound_emails (id, user_id, message_id, sender, subject, document_ids, job_ids, skipped, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, message_id) WHERE message_id <> '' DO NOTHING
+	`
+
+	email.ID = uuid.New()
+	email.CreatedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		email.ID,
+		email.UserID,
+		email.MessageID,
+		email.Sender,
+		email.Subject,
+		email.DocumentIDs,
+		email.JobIDs,
+		email.Skipped,
+		email.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create inbound email: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimUnreplied locks emails awaiting a reply whose jobs all finished, or
+// that were received before giveUpBefore, oldest first. It must run in a
+// transaction, which holds the locks until the replies are recorded.
+func (r *InboundEmailRepository) ClaimUnreplied(ctx context.Context, giveUpBefore time.Time, limit int) ([]*models.InboundEmail, error) {
+	query := `
+		SELECT ` + inboundEmailColumns + ` FROM inbound_emails e
+		WHERE replied_at IS NULL
+			AND (created_at < $1 OR NOT EXISTS (
+				SELECT 1 FROM ocr_jobs j
+				WHERE j.id = ANY(e.job_ids) AND j.status NOT IN ('completed', 'failed', 'cancelled')
+			))
+		ORDER BY created_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, giveUpBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim inbound emails: %w", err)
+	}
+	defer rows.Close()
+
+	emails := []*models.InboundEmail{}
+	for rows.Next() {
+		var email models.InboundEmail
+		err := rows.Scan(
+			&email.ID,
+			&email.UserID,
+			&email.MessageID,
+			&email.Sender,
+			&email.Subject,
+			&email.DocumentIDs,
+			&email.JobIDs,
+			&email.Skipped,
+			&email.RepliedAt,
+			&email.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan inbound email: %w", err)
+		}
+		emails = append(emails, &email)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim inbound emails: %w", err)
+	}
+
+	return emails, nil
+}
+
+// MarkReplied records that an email was replied to
+func (r *InboundEmailRepository) MarkReplied(ctx context.Context, id uuid.UUID) error {
+	_, err := conn(ctx, r.db).Exec(ctx, `UPDATE inbound_emails SET replied_at = $1 WHERE id = $2`, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark inbound email replied: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InstanceSettingsRepository handles instance-wide key/value settings
+type InstanceSettingsRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewInstanceSettingsRepository creates a new instance settings repository
+func NewInstanceSettingsRepository(db *pgxpool.Pool) *InstanceSettingsRepository {
+	return &InstanceSettingsRepository{db: db}
+}
+
+// Get retrieves a setting, returning ok=false when it is not set
+func (r *InstanceSettingsRepository) Get(ctx context.Context, key string) (value string, ok bool, err error) {
+	query := `SELECT value FROM instance_settings WHERE key = $1`
+
+	err = conn(ctx, r.db).QueryRow(ctx, query, key).Scan(&value)
+	if err == pgx.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get setting: %w", err)
+	}
+
+	return value, true, nil
+}
+
+// GetAll retrieves all settings
+func (r *InstanceSettingsRepository) GetAll(ctx context.Context) (map[string]string, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, `SELECT key, value FROM instance_settings`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan setting: %w", err)
+		}
+		settings[key] = value
+	}
+
+	return settings, nil
+}
+
+// Set creates or updates a setting
+func (r *InstanceSettingsRepository) Set(ctx context.Context, key, value string) error {
+	query := `
+		INSERT INTO instance_settings (key, value, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, key, value, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to set setting: %w", err)
+	}
+
+	return nil
+}
+
+// Lock takes a transaction-scoped advisory lock so only one caller at a time
+// can run the named critical section. It must be called inside a transaction.
+func (r *InstanceSettingsRepository) Lock(ctx context.Context, name string) error {
+	_, err := conn(ctx, r.db).Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, name)
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrHookNotFound is returned when a REST hook subscription does not exist
+var ErrHookNotFound = apperrors.NotFound("HOOK_001", "hook not found")
+
+// hookColumns are the columns scanned by scanHook
+const hookColumns = `id, user_id, event, target_url, labels, cursor_at, cursor_id,
+	failures, last_error, last_delivered_at, next_attempt_at, created_at`
+
+// IntegrationRepository handles the REST hook subscriptions of no-code tools
+type IntegrationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewIntegrationRepository creates a new integration repository
+func NewIntegrationRepository(db *pgxpool.Pool) *IntegrationRepository {
+	return &IntegrationRepository{db: db}
+}
+
+// Create stores a new hook, delivering results created after its cursor
+func (r *IntegrationRepository) Create(ctx context.Context, hook *models.IntegrationHook) error {
+	query := `
+		INSERT INTO integration_hooks (
+			id, user_id, event, target_url, labels, cursor_at, cursor_id, next_attempt_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	`
+
+	hook.ID = uuid.New()
+	hook.CreatedAt = time.Now().UTC()
+	hook.NextAttemptAt = hook.CreatedAt
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		hook.ID,
+		hook.UserID,
+		hook.Event,
+		hook.TargetURL,
+		hook.Labels,
+		hook.CursorAt,
+		hook.CursorID,
+		hook.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create hook: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a hook by ID
+func (r *IntegrationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.IntegrationHook, error) {
+	query := `SELECT ` + hookColumns + ` FROM integration_hooks WHERE id = $1`
+
+	hook, err := scanHook(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrHookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hook: %w", err)
+	}
+
+	return hook, nil
+}
+
+// ListByUser retrieves a user's hooks, newest first
+func (r *IntegrationRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.IntegrationHook, error) {
+	query := `SELECT ` + hookColumns + ` FROM integration_hooks WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hooks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanHooks(rows)
+}
+
+// ClaimDue locks hooks that are due for a delivery attempt.
+// It must run inside a transaction; hooks locked by another replica are skipped.
+func (r *IntegrationRepository) ClaimDue(ctx context.Context, limit int) ([]*models.IntegrationHook, error) {
+	query := `SELECT ` + hookColumns + `
+		FROM integration_hooks
+		WHERE next_attempt_at <= $1
+		ORDER BY next_attempt_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due hooks: %w", err)
+	}
+	defer rows.Close()
+
+	return scanHooks(rows)
+}
+
+// UpdateDelivery saves the cursor, failure count, last error and next attempt
+// time of a hook after a delivery attempt
+func (r *IntegrationRepository) UpdateDelivery(ctx context.Context, hook *models.IntegrationHook) error {
+	query := `
+		UPDATE integration_hooks
+		SET cursor_at = $1, cursor_id = $2, failures = $3, last_error = $4,
+			last_delivered_at = $5, next_attempt_at = $6
+		WHERE id = $7
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		hook.CursorAt,
+		hook.CursorID,
+		hook.Failures,
+		hook.LastError,
+		hook.LastDeliveredAt,
+		hook.NextAttemptAt,
+		hook.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update hook delivery: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrHookNotFound
+	}
+
+	return nil
+}
+
+// Delete deletes a hook
+func (r *IntegrationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM integration_hooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete hook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrHookNotFound
+	}
+
+	return nil
+}
+
+func scanHooks(rows pgx.Rows) ([]*models.IntegrationHook, error) {
+	hooks := []*models.IntegrationHook{}
+	for rows.Next() {
+		hook, err := scanHook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list hooks: %w", err)
+	}
+
+	return hooks, nil
+}
+
+func scanHook(row pgx.Row) (*models.IntegrationHook, error) {
+	var hook models.IntegrationHook
+	err := row.Scan(
+		&hook.ID,
+		&hook.UserID,
+		&hook.Event,
+		&hook.TargetURL,
+		&hook.Labels,
+		&hook.CursorAt,
+		&hook.CursorID,
+		&hook.Failures,
+		&hook.LastError,
+		&hook.LastDeliveredAt,
+		&hook.NextAttemptAt,
+		&hook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
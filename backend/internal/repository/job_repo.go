@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"visekai/backend/internal/apperrors"
 	"visekai/backend/internal/models"
 
 	"github.com/google/uuid"
@@ -12,31 +14,46 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrInvalidTransition is returned when a job cannot move to a status from its
+// current one
+var ErrInvalidTransition = apperrors.Conflict("JOB_007", "invalid job status transition")
+
+// ErrJobNotFound is returned when a job does not exist
+var ErrJobNotFound = apperrors.NotFound("RES_003", "job not found")
+
 // JobRepository handles OCR job database operations
 type JobRepository struct {
 	db *pgxpool.Pool
+	// replica serves list queries; nil uses db
+	replica *pgxpool.Pool
 }
 
-// NewJobRepository creates a new job repository
-func NewJobRepository(db *pgxpool.Pool) *JobRepository {
-	return &JobRepository{db: db}
+// NewJobRepository creates a new job repository. The replica, if not
+// nil, serves list queries.
+func NewJobRepository(db, replica *pgxpool.Pool) *JobRepository {
+	return &JobRepository{db: db, replica: replica}
 }
 
-// Create creates a new OCR job
+// Create creates a new OCR job, pending unless job.Status says otherwise
 func (r *JobRepository) Create(ctx context.Context, job *models.OCRJob) error {
 	query := `
 		INSERT INTO ocr_jobs (
 			id, document_id, user_id, status, ocr_mode, resolution_mode,
-			priority, retry_count, max_retries, progress_percentage, created_at, metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			priority, retry_count, max_retries, progress_percentage, created_at, metadata, labels
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
 	job.ID = uuid.New()
-	job.Status = models.JobStatusPending
-	job.CreatedAt = time.Now()
+	if job.Status == "" {
+		job.Status = models.JobStatusPending
+	}
+	job.CreatedAt = time.Now().UTC()
 	job.ProgressPercentage = 0
+	if job.Labels == nil {
+		job.Labels = []string{}
+	}
 
-	_, err := r.db.Exec(ctx, query,
+	_, err := conn(ctx, r.db).Exec(ctx, query,
 		job.ID,
 		job.DocumentID,
 		job.UserID,
@@ -49,6 +66,7 @@ func (r *JobRepository) Create(ctx context.Context, job *models.OCRJob) error {
 		job.ProgressPercentage,
 		job.CreatedAt,
 		job.Metadata,
+		job.Labels,
 	)
 
 	if err != nil {
@@ -63,13 +81,13 @@ func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.OCRJ
 	query := `
 		SELECT id, document_id, user_id, status, ocr_mode, resolution_mode,
 			   priority, retry_count, max_retries, progress_percentage,
-			   created_at, started_at, completed_at, error_message, metadata
+			   created_at, started_at, completed_at, error_message, metadata, labels, archived_at
 		FROM ocr_jobs
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL AND ($2::text[] IS NULL OR labels && $2)
 	`
 
 	var job models.OCRJob
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := conn(ctx, r.db).QueryRow(ctx, query, id, LabelScope(ctx)).Scan(
 		&job.ID,
 		&job.DocumentID,
 		&job.UserID,
@@ -85,10 +103,12 @@ func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.OCRJ
 		&job.CompletedAt,
 		&job.ErrorMessage,
 		&job.Metadata,
+		&job.Labels,
+		&job.ArchivedAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("job not found")
+		return nil, ErrJobNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get job: %w", err)
@@ -97,30 +117,52 @@ func (r *JobRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.OCRJ
 	return &job, nil
 }
 
-// GetByUserID retrieves all jobs for a user with pagination
-func (r *JobRepository) GetByUserID(ctx context.Context, userID uuid.UUID, page, perPage int) ([]*models.OCRJob, int, error) {
-	offset := (page - 1) * perPage
+// jobSortColumns maps the sort_by values of job lists to their columns
+var jobSortColumns = map[string]string{
+	"created_at": "created_at",
+	"status":     "status",
+	"priority":   "priority",
+}
+
+// GetByUserID retrieves the jobs of a user with pagination, newest first
+// unless req sorts them otherwise, and only those of req's status when set.
+// Archived jobs are left out unless req asks for them, and then listed alone.
+func (r *JobRepository) GetByUserID(ctx context.Context, userID uuid.UUID, req models.JobListRequest) ([]*models.OCRJob, int, error) {
+	offset := (req.Page - 1) * req.PerPage
+
+	order := "created_at DESC"
+	if column, ok := jobSortColumns[req.SortBy]; ok {
+		order = column + " ASC, created_at DESC"
+		if req.SortDesc {
+			order = column + " DESC, created_at DESC"
+		}
+	}
 
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM ocr_jobs WHERE user_id = $1`
+	countQuery := `
+		SELECT COUNT(*) FROM ocr_jobs
+		WHERE user_id = $1 AND ($2::text[] IS NULL OR labels && $2) AND ($3 = '' OR status = $3)
+		  AND deleted_at IS NULL AND (archived_at IS NOT NULL) = $4
+	`
 	var total int
-	err := r.db.QueryRow(ctx, countQuery, userID).Scan(&total)
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, countQuery, userID, LabelScope(ctx), string(req.Status), req.Archived).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count jobs: %w", err)
 	}
 
 	// Get jobs
-	query := `
+	query := fmt.Sprintf(`
 		SELECT id, document_id, user_id, status, ocr_mode, resolution_mode,
 			   priority, retry_count, max_retries, progress_percentage,
-			   created_at, started_at, completed_at, error_message, metadata
+			   created_at, started_at, completed_at, error_message, metadata, labels, archived_at
 		FROM ocr_jobs
-		WHERE user_id = $1
-		ORDER BY created_at DESC
+		WHERE user_id = $1 AND ($4::text[] IS NULL OR labels && $4) AND ($5 = '' OR status = $5)
+		  AND deleted_at IS NULL AND (archived_at IS NOT NULL) = $6
+		ORDER BY %s
 		LIMIT $2 OFFSET $3
-	`
+	`, order)
 
-	rows, err := r.db.Query(ctx, query, userID, perPage, offset)
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID, req.PerPage, offset, LabelScope(ctx), string(req.Status), req.Archived)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get jobs: %w", err)
 	}
@@ -145,6 +187,8 @@ func (r *JobRepository) GetByUserID(ctx context.Context, userID uuid.UUID, page,
 			&job.CompletedAt,
 			&job.ErrorMessage,
 			&job.Metadata,
+			&job.Labels,
+			&job.ArchivedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan job: %w", err)
@@ -155,88 +199,717 @@ func (r *JobRepository) GetByUserID(ctx context.Context, userID uuid.UUID, page,
 	return jobs, total, nil
 }
 
-// UpdateStatus updates the status of a job
+// GetByOrgID retrieves the jobs of the documents shared with an organization,
+// newest first
+func (r *JobRepository) GetByOrgID(ctx context.Context, orgID uuid.UUID, page, perPage int) ([]*models.OCRJob, int, error) {
+	offset := (page - 1) * perPage
+
+	// Get total count
+	countQuery := `
+		SELECT COUNT(*) FROM ocr_jobs j
+		JOIN documents d ON d.id = j.document_id
+		WHERE d.org_id = $1 AND d.deleted_at IS NULL AND ($2::text[] IS NULL OR j.labels && $2)
+		  AND j.deleted_at IS NULL AND j.archived_at IS NULL
+	`
+	var total int
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, countQuery, orgID, LabelScope(ctx)).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	// Get jobs
+	query := `
+		SELECT j.id, j.document_id, j.user_id, j.status, j.ocr_mode, j.resolution_mode,
+			   j.priority, j.retry_count, j.max_retries, j.progress_percentage,
+			   j.created_at, j.started_at, j.completed_at, j.error_message, j.metadata, j.labels
+		FROM ocr_jobs j
+		JOIN documents d ON d.id = j.document_id
+		WHERE d.org_id = $1 AND d.deleted_at IS NULL AND ($4::text[] IS NULL OR j.labels && $4)
+		  AND j.deleted_at IS NULL AND j.archived_at IS NULL
+		ORDER BY j.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, orgID, perPage, offset, LabelScope(ctx))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.OCRJob{}
+	for rows.Next() {
+		var job models.OCRJob
+		err := rows.Scan(
+			&job.ID,
+			&job.DocumentID,
+			&job.UserID,
+			&job.Status,
+			&job.OCRMode,
+			&job.ResolutionMode,
+			&job.Priority,
+			&job.RetryCount,
+			&job.MaxRetries,
+			&job.ProgressPercentage,
+			&job.CreatedAt,
+			&job.StartedAt,
+			&job.CompletedAt,
+			&job.ErrorMessage,
+			&job.Metadata,
+			&job.Labels,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get jobs: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// UpdateStatus moves a job to a new status if the job state machine allows it
+// from the job's current status. The check is part of the UPDATE, so of two
+// racing changes, such as a cancellation and a worker completing the job, only
+// the first applies; the other gets an ErrInvalidTransition.
 func (r *JobRepository) UpdateStatus(ctx context.Context, jobID uuid.UUID, status models.JobStatus, errorMessage *string) error {
 	var query string
 	var args []interface{}
 
-	now := time.Now()
+	now := time.Now().UTC()
+	from := jobStatusStrings(models.JobStatusesBefore(status))
 
 	switch status {
 	case models.JobStatusProcessing:
 		query = `
 			UPDATE ocr_jobs
 			SET status = $1, started_at = $2
-			WHERE id = $3
+			WHERE id = $3 AND status = ANY($4)
 		`
-		args = []interface{}{status, now, jobID}
+		args = []interface{}{status, now, jobID, from}
 
 	case models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCancelled:
 		if errorMessage != nil {
 			query = `
 				UPDATE ocr_jobs
 				SET status = $1, completed_at = $2, error_message = $3, progress_percentage = $4
-				WHERE id = $5
+				WHERE id = $5 AND status = ANY($6)
 			`
 			progress := 0
 			if status == models.JobStatusCompleted {
 				progress = 100
 			}
-			args = []interface{}{status, now, *errorMessage, progress, jobID}
+			args = []interface{}{status, now, *errorMessage, progress, jobID, from}
 		} else {
 			query = `
 				UPDATE ocr_jobs
 				SET status = $1, completed_at = $2, progress_percentage = $3
-				WHERE id = $4
+				WHERE id = $4 AND status = ANY($5)
 			`
 			progress := 100
-			args = []interface{}{status, now, progress, jobID}
+			args = []interface{}{status, now, progress, jobID, from}
 		}
 
+	case models.JobStatusPending:
+		// Back in the queue, keeping the error of the attempt that failed if given
+		query = `
+			UPDATE ocr_jobs
+			SET status = $1, started_at = NULL, completed_at = NULL, progress_percentage = 0, error_message = $2
+			WHERE id = $3 AND status = ANY($4)
+		`
+		args = []interface{}{status, errorMessage, jobID, from}
+
 	default:
-		query = `UPDATE ocr_jobs SET status = $1 WHERE id = $2`
-		args = []interface{}{status, jobID}
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidTransition, status)
 	}
 
-	result, err := r.db.Exec(ctx, query, args...)
+	result, err := conn(ctx, r.db).Exec(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update job status: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("job not found")
+		var current models.JobStatus
+		err := conn(ctx, r.db).QueryRow(ctx, `SELECT status FROM ocr_jobs WHERE id = $1`, jobID).Scan(&current)
+		if err == pgx.ErrNoRows {
+			return ErrJobNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update job status: %w", err)
+		}
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, current, status)
 	}
 
 	return nil
 }
 
+// ExpirePending marks up to limit jobs that have been pending since before the cutoff
+// as failed and returns them. Rows locked by another transaction are skipped.
+func (r *JobRepository) ExpirePending(ctx context.Context, cutoff time.Time, errorMessage string, limit int) ([]*models.OCRJob, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $1, completed_at = $2, error_message = $3, progress_percentage = 0
+		WHERE id IN (
+			SELECT id FROM ocr_jobs
+			WHERE status = $4 AND created_at < $5
+			ORDER BY created_at
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, document_id, user_id, ocr_mode, resolution_mode, created_at
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query,
+		models.JobStatusFailed,
+		time.Now().UTC(),
+		errorMessage,
+		models.JobStatusPending,
+		cutoff,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire pending jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.OCRJob
+	for rows.Next() {
+		job := &models.OCRJob{
+			Status:       models.JobStatusFailed,
+			ErrorMessage: &errorMessage,
+		}
+		err := rows.Scan(
+			&job.ID,
+			&job.DocumentID,
+			&job.UserID,
+			&job.OCRMode,
+			&job.ResolutionMode,
+			&job.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expired job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to expire pending jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// RequeueStuck resets up to limit jobs that have been processing since before the
+// cutoff to pending and returns them. Each job gets an incident entry with the
+// note appended to metadata.incidents and its retry count incremented; jobs that
+// have used up their retries are failed instead. Rows locked by another
+// transaction are skipped.
+func (r *JobRepository) RequeueStuck(ctx context.Context, cutoff time.Time, note string, limit int) ([]*models.OCRJob, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET status = CASE WHEN retry_count < max_retries THEN $1 ELSE $2 END,
+			started_at = NULL,
+			completed_at = CASE WHEN retry_count < max_retries THEN NULL ELSE $3 END,
+			error_message = CASE WHEN retry_count < max_retries THEN NULL ELSE $4 END,
+			progress_percentage = 0,
+			retry_count = retry_count + 1,
+			metadata = jsonb_set(
+				COALESCE(metadata, '{}'),
+				'{incidents}',
+				COALESCE(metadata->'incidents', '[]') || jsonb_build_array(jsonb_build_object(
+					'type', 'stuck_processing',
+					'note', $8::text,
+					'started_at', started_at,
+					'detected_at', $9::timestamptz
+				))
+			)
+		WHERE id IN (
+			SELECT id FROM ocr_jobs
+			WHERE status = $5 AND started_at < $6
+			ORDER BY started_at
+			LIMIT $7
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, document_id, user_id, status, ocr_mode, resolution_mode, retry_count, max_retries, created_at
+	`
+
+	now := time.Now().UTC()
+	rows, err := conn(ctx, r.db).Query(ctx, query,
+		models.JobStatusPending,
+		models.JobStatusFailed,
+		now,
+		note,
+		models.JobStatusProcessing,
+		cutoff,
+		limit,
+		note,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue stuck jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.OCRJob
+	for rows.Next() {
+		job := &models.OCRJob{}
+		err := rows.Scan(
+			&job.ID,
+			&job.DocumentID,
+			&job.UserID,
+			&job.Status,
+			&job.OCRMode,
+			&job.ResolutionMode,
+			&job.RetryCount,
+			&job.MaxRetries,
+			&job.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stuck job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to requeue stuck jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// GetStatusForUpdate returns the status of a job and locks it until the transaction in ctx ends
+func (r *JobRepository) GetStatusForUpdate(ctx context.Context, jobID uuid.UUID) (models.JobStatus, error) {
+	query := `SELECT status FROM ocr_jobs WHERE id = $1 FOR UPDATE`
+
+	var status models.JobStatus
+	err := conn(ctx, r.db).QueryRow(ctx, query, jobID).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return "", ErrJobNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get job status: %w", err)
+	}
+
+	return status, nil
+}
+
+// CountActiveByDocument counts the jobs of a document that are awaiting
+// approval, pending or processing
+func (r *JobRepository) CountActiveByDocument(ctx context.Context, documentID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM ocr_jobs WHERE document_id = $1 AND status IN ($2, $3, $4)`
+
+	var count int
+	err := conn(ctx, r.db).QueryRow(ctx, query, documentID, models.JobStatusAwaitingApproval, models.JobStatusPending, models.JobStatusProcessing).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active jobs: %w", err)
+	}
+
+	return count, nil
+}
+
+// CancelActiveByDocument cancels the jobs of a document that are awaiting
+// approval, pending or processing and returns their IDs
+func (r *JobRepository) CancelActiveByDocument(ctx context.Context, documentID uuid.UUID, errorMessage string) ([]uuid.UUID, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $1, completed_at = $2, error_message = $3
+		WHERE document_id = $4 AND status IN ($5, $6, $7)
+		RETURNING id
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query,
+		models.JobStatusCancelled,
+		time.Now().UTC(),
+		errorMessage,
+		documentID,
+		models.JobStatusAwaitingApproval,
+		models.JobStatusPending,
+		models.JobStatusProcessing,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan cancelled job: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to cancel jobs: %w", err)
+	}
+
+	return ids, nil
+}
+
+// jobFilterWhere matches the jobs selected by a JobFilter; its arguments come
+// from jobFilterArgs and take placeholders $1 to $5
+const jobFilterWhere = `
+	WHERE ($1::text[] IS NULL OR status = ANY($1))
+	  AND ($2::uuid IS NULL OR user_id = $2)
+	  AND ($3::uuid IS NULL OR document_id = $3)
+	  AND ($4::timestamptz IS NULL OR created_at < $4)
+	  AND ($5::timestamptz IS NULL OR started_at < $5)
+	  AND deleted_at IS NULL
+`
+
+func jobFilterArgs(filter models.JobFilter) []any {
+	return []any{jobStatusStrings(filter.Statuses), filter.UserID, filter.DocumentID, filter.CreatedBefore, filter.StartedBefore}
+}
+
+// jobStatusStrings converts statuses to a text array argument; nil stays nil
+func jobStatusStrings(statuses []models.JobStatus) []string {
+	var values []string
+	for _, status := range statuses {
+		values = append(values, string(status))
+	}
+	return values
+}
+
+// QueueStats counts jobs by status along with the oldest pending and processing jobs
+func (r *JobRepository) QueueStats(ctx context.Context) (*models.QueueStats, error) {
+	query := `
+		SELECT status, COUNT(*), MIN(created_at), MIN(started_at)
+		FROM ocr_jobs
+		GROUP BY status
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &models.QueueStats{Counts: map[models.JobStatus]int{}}
+	for rows.Next() {
+		var status models.JobStatus
+		var count int
+		var oldestCreated, oldestStarted *time.Time
+		if err := rows.Scan(&status, &count, &oldestCreated, &oldestStarted); err != nil {
+			return nil, fmt.Errorf("failed to scan queue stats: %w", err)
+		}
+
+		stats.Counts[status] = count
+		switch status {
+		case models.JobStatusPending:
+			stats.OldestPendingAt = oldestCreated
+		case models.JobStatusProcessing:
+			stats.OldestProcessingAt = oldestStarted
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// Find retrieves up to filter.Limit jobs matching the filter, oldest first
+func (r *JobRepository) Find(ctx context.Context, filter models.JobFilter) ([]*models.OCRJob, error) {
+	query := `
+		SELECT id, document_id, user_id, status, ocr_mode, resolution_mode,
+			   priority, retry_count, max_retries, progress_percentage,
+			   created_at, started_at, completed_at, error_message, metadata, labels
+		FROM ocr_jobs` + jobFilterWhere + `
+		ORDER BY created_at
+		LIMIT $6
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, append(jobFilterArgs(filter), filter.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.OCRJob{}
+	for rows.Next() {
+		var job models.OCRJob
+		err := rows.Scan(
+			&job.ID,
+			&job.DocumentID,
+			&job.UserID,
+			&job.Status,
+			&job.OCRMode,
+			&job.ResolutionMode,
+			&job.Priority,
+			&job.RetryCount,
+			&job.MaxRetries,
+			&job.ProgressPercentage,
+			&job.CreatedAt,
+			&job.StartedAt,
+			&job.CompletedAt,
+			&job.ErrorMessage,
+			&job.Metadata,
+			&job.Labels,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// RequeueMatching resets up to filter.Limit jobs matching the filter to pending
+// and returns their IDs. Jobs that cannot be requeued, such as completed ones,
+// are left alone. Each job gets an incident entry with the note appended to
+// metadata.incidents. Rows locked by another transaction are skipped.
+func (r *JobRepository) RequeueMatching(ctx context.Context, filter models.JobFilter, note string) ([]uuid.UUID, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $7,
+			started_at = NULL,
+			completed_at = NULL,
+			error_message = NULL,
+			progress_percentage = 0,
+			metadata = jsonb_set(
+				COALESCE(metadata, '{}'),
+				'{incidents}',
+				COALESCE(metadata->'incidents', '[]') || jsonb_build_array(jsonb_build_object(
+					'type', 'requeued',
+					'note', $8::text,
+					'previous_status', status,
+					'detected_at', $9::timestamptz
+				))
+			)
+		WHERE id IN (
+			SELECT id FROM ocr_jobs` + jobFilterWhere + `
+			  AND status = ANY($10)
+			ORDER BY created_at
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
+
+	args := append(jobFilterArgs(filter), filter.Limit, models.JobStatusPending, note, time.Now().UTC(),
+		jobStatusStrings(models.JobStatusesBefore(models.JobStatusPending)))
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue jobs: %w", err)
+	}
+
+	return collectJobIDs(rows, "requeue")
+}
+
+// CancelMatching cancels up to filter.Limit jobs matching the filter and returns
+// their IDs. Only pending and processing jobs can be cancelled. Rows locked by
+// another transaction are skipped.
+func (r *JobRepository) CancelMatching(ctx context.Context, filter models.JobFilter, errorMessage string) ([]uuid.UUID, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET status = $7, completed_at = $8, error_message = $9
+		WHERE id IN (
+			SELECT id FROM ocr_jobs` + jobFilterWhere + `
+			  AND status = ANY($10)
+			ORDER BY created_at
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
+
+	args := append(jobFilterArgs(filter), filter.Limit, models.JobStatusCancelled, time.Now().UTC(), errorMessage,
+		jobStatusStrings(models.JobStatusesBefore(models.JobStatusCancelled)))
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel jobs: %w", err)
+	}
+
+	return collectJobIDs(rows, "cancel")
+}
+
+// collectJobIDs reads the job IDs returned by a bulk update
+func collectJobIDs(rows pgx.Rows, action string) ([]uuid.UUID, error) {
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan job id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to %s jobs: %w", action, err)
+	}
+
+	return ids, nil
+}
+
 // UpdateProgress updates the progress percentage of a job
 func (r *JobRepository) UpdateProgress(ctx context.Context, jobID uuid.UUID, progress int) error {
 	query := `UPDATE ocr_jobs SET progress_percentage = $1 WHERE id = $2`
 
-	result, err := r.db.Exec(ctx, query, progress, jobID)
+	result, err := conn(ctx, r.db).Exec(ctx, query, progress, jobID)
 	if err != nil {
 		return fmt.Errorf("failed to update job progress: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("job not found")
+		return ErrJobNotFound
 	}
 
 	return nil
 }
 
+// jobEventColumns are the columns scanned by scanJobEvent, from job_events e
+// joined with ocr_jobs j
+const jobEventColumns = `e.id, e.job_id, j.document_id, j.user_id, j.labels, e.event_type, e.status,
+	e.progress_percentage, e.retry_count, e.message, e.occurred_at`
+
+// ListEvents retrieves the timeline of a job, oldest first
+func (r *JobRepository) ListEvents(ctx context.Context, jobID uuid.UUID) ([]*models.JobEvent, error) {
+	query := `
+		SELECT ` + jobEventColumns + `
+		FROM job_events e
+		JOIN ocr_jobs j ON j.id = e.job_id
+		WHERE e.job_id = $1
+		ORDER BY e.id
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job events: %w", err)
+	}
+
+	return scanJobEvents(rows)
+}
+
+// ListEventsAfter retrieves up to limit of a user's job events with IDs after
+// afterID, oldest first
+func (r *JobRepository) ListEventsAfter(ctx context.Context, userID uuid.UUID, afterID int64, limit int) ([]*models.JobEvent, error) {
+	query := `
+		SELECT ` + jobEventColumns + `
+		FROM job_events e
+		JOIN ocr_jobs j ON j.id = e.job_id
+		WHERE j.user_id = $1 AND e.id > $2
+		  AND ($4::text[] IS NULL OR j.labels && $4)
+		ORDER BY e.id
+		LIMIT $3
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID, afterID, limit, LabelScope(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job events: %w", err)
+	}
+
+	return scanJobEvents(rows)
+}
+
+// GetEvent retrieves a job event by ID. It reads the primary, as the event is
+// usually fresh from a notification.
+func (r *JobRepository) GetEvent(ctx context.Context, id int64) (*models.JobEvent, error) {
+	query := `
+		SELECT ` + jobEventColumns + `
+		FROM job_events e
+		JOIN ocr_jobs j ON j.id = e.job_id
+		WHERE e.id = $1
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job event: %w", err)
+	}
+
+	events, err := scanJobEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrJobNotFound
+	}
+
+	return events[0], nil
+}
+
+// ListenEvents calls handle for each job event committed until the context is
+// cancelled or the connection fails. It holds a connection of the pool for as
+// long as it listens.
+func (r *JobRepository) ListenEvents(ctx context.Context, handle func(models.JobEventNotification)) error {
+	c, err := r.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	// The connection is still subscribed, so it must not go back to the pool
+	defer c.Hijack().Close(context.Background())
+
+	if _, err := c.Exec(ctx, "LISTEN job_events"); err != nil {
+		return fmt.Errorf("failed to listen for job events: %w", err)
+	}
+
+	for {
+		notification, err := c.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for job events: %w", err)
+		}
+
+		var n models.JobEventNotification
+		if err := json.Unmarshal([]byte(notification.Payload), &n); err != nil {
+			continue
+		}
+		handle(n)
+	}
+}
+
+func scanJobEvents(rows pgx.Rows) ([]*models.JobEvent, error) {
+	defer rows.Close()
+
+	events := []*models.JobEvent{}
+	for rows.Next() {
+		event := &models.JobEvent{}
+		err := rows.Scan(
+			&event.ID,
+			&event.JobID,
+			&event.DocumentID,
+			&event.UserID,
+			&event.Labels,
+			&event.Type,
+			&event.Status,
+			&event.ProgressPercentage,
+			&event.RetryCount,
+			&event.Message,
+			&event.OccurredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list job events: %w", err)
+	}
+
+	return events, nil
+}
+
 // IncrementRetryCount increments the retry count for a job
 func (r *JobRepository) IncrementRetryCount(ctx context.Context, jobID uuid.UUID) error {
 	query := `UPDATE ocr_jobs SET retry_count = retry_count + 1 WHERE id = $1`
 
-	result, err := r.db.Exec(ctx, query, jobID)
+	result, err := conn(ctx, r.db).Exec(ctx, query, jobID)
 	if err != nil {
 		return fmt.Errorf("failed to increment retry count: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("job not found")
+		return ErrJobNotFound
 	}
 
 	return nil
@@ -247,14 +920,14 @@ func (r *JobRepository) GetPendingJobs(ctx context.Context, limit int) ([]*model
 	query := `
 		SELECT id, document_id, user_id, status, ocr_mode, resolution_mode,
 			   priority, retry_count, max_retries, progress_percentage,
-			   created_at, started_at, completed_at, error_message, metadata
+			   created_at, started_at, completed_at, error_message, metadata, labels
 		FROM ocr_jobs
 		WHERE status = $1
 		ORDER BY priority DESC, created_at ASC
 		LIMIT $2
 	`
 
-	rows, err := r.db.Query(ctx, query, models.JobStatusPending, limit)
+	rows, err := conn(ctx, r.db).Query(ctx, query, models.JobStatusPending, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending jobs: %w", err)
 	}
@@ -279,6 +952,7 @@ func (r *JobRepository) GetPendingJobs(ctx context.Context, limit int) ([]*model
 			&job.CompletedAt,
 			&job.ErrorMessage,
 			&job.Metadata,
+			&job.Labels,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan job: %w", err)
@@ -289,30 +963,198 @@ func (r *JobRepository) GetPendingJobs(ctx context.Context, limit int) ([]*model
 	return jobs, nil
 }
 
-// Delete deletes a job
-func (r *JobRepository) Delete(ctx context.Context, jobID uuid.UUID) error {
-	query := `DELETE FROM ocr_jobs WHERE id = $1`
-
-	result, err := r.db.Exec(ctx, query, jobID)
+// SoftDelete moves a job and its results to the trash
+func (r *JobRepository) SoftDelete(ctx context.Context, jobID uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `UPDATE ocr_jobs SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, jobID)
 	if err != nil {
 		return fmt.Errorf("failed to delete job: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("job not found")
+		return ErrJobNotFound
+	}
+
+	if _, err := conn(ctx, r.db).Exec(ctx, `UPDATE ocr_results SET deleted_at = NOW() WHERE job_id = $1 AND deleted_at IS NULL`, jobID); err != nil {
+		return fmt.Errorf("failed to delete job results: %w", err)
 	}
 
 	return nil
 }
 
+// Restore moves a job owned by the user and its results out of the trash
+func (r *JobRepository) Restore(ctx context.Context, jobID, userID uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `UPDATE ocr_jobs SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL`, jobID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore job: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrJobNotFound
+	}
+
+	if _, err := conn(ctx, r.db).Exec(ctx, `UPDATE ocr_results SET deleted_at = NULL WHERE job_id = $1`, jobID); err != nil {
+		return fmt.Errorf("failed to restore job results: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeletedByUser retrieves the jobs in a user's trash, most recently
+// deleted first
+func (r *JobRepository) ListDeletedByUser(ctx context.Context, userID uuid.UUID, page, perPage int) ([]*models.OCRJob, int, error) {
+	var total int
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx,
+		`SELECT COUNT(*) FROM ocr_jobs WHERE user_id = $1 AND deleted_at IS NOT NULL`, userID,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count deleted jobs: %w", err)
+	}
+
+	query := `
+		SELECT id, document_id, user_id, status, ocr_mode, resolution_mode,
+			   priority, retry_count, max_retries, progress_percentage,
+			   created_at, started_at, completed_at, error_message, metadata, labels,
+			   archived_at, deleted_at
+		FROM ocr_jobs
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list deleted jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.OCRJob{}
+	for rows.Next() {
+		var job models.OCRJob
+		err := rows.Scan(
+			&job.ID,
+			&job.DocumentID,
+			&job.UserID,
+			&job.Status,
+			&job.OCRMode,
+			&job.ResolutionMode,
+			&job.Priority,
+			&job.RetryCount,
+			&job.MaxRetries,
+			&job.ProgressPercentage,
+			&job.CreatedAt,
+			&job.StartedAt,
+			&job.CompletedAt,
+			&job.ErrorMessage,
+			&job.Metadata,
+			&job.Labels,
+			&job.ArchivedAt,
+			&job.DeletedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list deleted jobs: %w", err)
+	}
+
+	return jobs, total, nil
+}
+
+// SetArchived archives or unarchives a job, returning when it was archived,
+// nil once unarchived
+func (r *JobRepository) SetArchived(ctx context.Context, jobID uuid.UUID, archived bool) (*time.Time, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET archived_at = CASE WHEN $2 THEN COALESCE(archived_at, NOW()) END
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING archived_at
+	`
+
+	var archivedAt *time.Time
+	err := conn(ctx, r.db).QueryRow(ctx, query, jobID, archived).Scan(&archivedAt)
+	if err == pgx.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive job: %w", err)
+	}
+
+	return archivedAt, nil
+}
+
+// ArchiveFinished archives up to limit finished jobs older than their users'
+// archive_jobs_after_days setting and returns how many were archived. Users
+// without the setting keep their jobs unarchived.
+func (r *JobRepository) ArchiveFinished(ctx context.Context, limit int) (int, error) {
+	query := `
+		UPDATE ocr_jobs
+		SET archived_at = NOW()
+		WHERE id IN (
+			SELECT j.id FROM ocr_jobs j
+			JOIN user_settings s ON s.user_id = j.user_id
+			WHERE s.archive_jobs_after_days IS NOT NULL
+			  AND j.archived_at IS NULL AND j.deleted_at IS NULL
+			  AND j.status = ANY($1)
+			  AND j.created_at < NOW() - make_interval(days => s.archive_jobs_after_days)
+			LIMIT $2
+			FOR UPDATE OF j SKIP LOCKED
+		)
+	`
+
+	finished := []string{string(models.JobStatusCompleted), string(models.JobStatusFailed), string(models.JobStatusCancelled)}
+	result, err := conn(ctx, r.db).Exec(ctx, query, finished, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive jobs: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
+// PurgeDeleted permanently deletes up to limit jobs, with their results, that
+// have been in the trash longer than their users' job_trash_retention_days
+// setting, or than retention for users without it. A nil retention keeps the
+// jobs of users without the setting. It returns how many were purged.
+func (r *JobRepository) PurgeDeleted(ctx context.Context, retention *time.Duration, limit int) (int, error) {
+	query := `
+		DELETE FROM ocr_jobs
+		WHERE id IN (
+			SELECT j.id FROM ocr_jobs j
+			LEFT JOIN user_settings s ON s.user_id = j.user_id
+			WHERE j.deleted_at IS NOT NULL
+			  AND j.deleted_at < NOW() - COALESCE(
+				  make_interval(days => s.job_trash_retention_days),
+				  make_interval(secs => $1::double precision)
+			  )
+			LIMIT $2
+			FOR UPDATE OF j SKIP LOCKED
+		)
+	`
+
+	var seconds *float64
+	if retention != nil {
+		value := retention.Seconds()
+		seconds = &value
+	}
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, seconds, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted jobs: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
+
 // GetJobsByStatus retrieves jobs by status with pagination
 func (r *JobRepository) GetJobsByStatus(ctx context.Context, userID uuid.UUID, status models.JobStatus, page, perPage int) ([]*models.OCRJob, int, error) {
 	offset := (page - 1) * perPage
 
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM ocr_jobs WHERE user_id = $1 AND status = $2`
+	countQuery := `SELECT COUNT(*) FROM ocr_jobs WHERE user_id = $1 AND status = $2 AND deleted_at IS NULL AND ($3::text[] IS NULL OR labels && $3)`
 	var total int
-	err := r.db.QueryRow(ctx, countQuery, userID, status).Scan(&total)
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, countQuery, userID, status, LabelScope(ctx)).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count jobs: %w", err)
 	}
@@ -321,14 +1163,14 @@ func (r *JobRepository) GetJobsByStatus(ctx context.Context, userID uuid.UUID, s
 	query := `
 		SELECT id, document_id, user_id, status, ocr_mode, resolution_mode,
 			   priority, retry_count, max_retries, progress_percentage,
-			   created_at, started_at, completed_at, error_message, metadata
+			   created_at, started_at, completed_at, error_message, metadata, labels
 		FROM ocr_jobs
-		WHERE user_id = $1 AND status = $2
+		WHERE user_id = $1 AND status = $2 AND deleted_at IS NULL AND ($5::text[] IS NULL OR labels && $5)
 		ORDER BY created_at DESC
 		LIMIT $3 OFFSET $4
 	`
 
-	rows, err := r.db.Query(ctx, query, userID, status, perPage, offset)
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID, status, perPage, offset, LabelScope(ctx))
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get jobs: %w", err)
 	}
@@ -353,6 +1195,7 @@ func (r *JobRepository) GetJobsByStatus(ctx context.Context, userID uuid.UUID, s
 			&job.CompletedAt,
 			&job.ErrorMessage,
 			&job.Metadata,
+			&job.Labels,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan job: %w", err)
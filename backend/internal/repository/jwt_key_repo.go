@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// JWTKeyRepository stores the keys signing access and refresh tokens
+type JWTKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewJWTKeyRepository creates a new JWT key repository
+func NewJWTKeyRepository(db *pgxpool.Pool) *JWTKeyRepository {
+	return &JWTKeyRepository{db: db}
+}
+
+// List returns every key, oldest first
+func (r *JWTKeyRepository) List(ctx context.Context) ([]*models.JWTSigningKey, error) {
+	query := `
+		SELECT id, algorithm, private_key, created_at, retired_at
+		FROM jwt_signing_keys
+		ORDER BY created_at, id
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list JWT signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.JWTSigningKey
+	for rows.Next() {
+		key := &models.JWTSigningKey{}
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.PrivateKey, &key.CreatedAt, &key.RetiredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan JWT signing key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate JWT signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Rotate retires the keys in use and adds key, which signs from then on
+func (r *JWTKeyRepository) Rotate(ctx context.Context, key *models.JWTSigningKey) error {
+	query := `
+		WITH retired AS (
+			UPDATE jwt_signing_keys SET retired_at = $4 WHERE retired_at IS NULL
+		)
+		INSERT INTO jwt_signing_keys (id, algorithm, private_key, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, key.ID, key.Algorithm, key.PrivateKey, key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to rotate JWT signing key: %w", err)
+	}
+	return nil
+}
+
+// DeleteRetiredBefore removes the keys retired before cutoff, returning how
+// many were
+func (r *JWTKeyRepository) DeleteRetiredBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM jwt_signing_keys WHERE retired_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete retired JWT signing keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNotificationChannelNotFound is returned when a notification channel does
+// not exist
+var ErrNotificationChannelNotFound = apperrors.NotFound("NOTIF_001", "notification channel not found")
+
+// notificationChannelColumns are the columns scanned by scanNotificationChannel
+const notificationChannelColumns = `id, user_id, kind, name, webhook_url, events, labels, enabled,
+	cursor_at, cursor_id, failures, last_error, last_delivered_at, next_attempt_at, created_at`
+
+// NotificationRepository handles users' Slack and Teams notification channels
+type NotificationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *pgxpool.Pool) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create stores a new channel, notifying jobs that finish after its cursor
+func (r *NotificationRepository) Create(ctx context.Context, channel *models.NotificationChannel) error {
+	query := `
+		INSERT INTO notification_channels (
+			id, user_id, kind, name, webhook_url, events, labels, enabled,
+			cursor_at, cursor_id, next_attempt_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
+	`
+
+	channel.ID = uuid.New()
+	channel.CreatedAt = time.Now().UTC()
+	channel.NextAttemptAt = channel.CreatedAt
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		channel.ID,
+		channel.UserID,
+		channel.Kind,
+		channel.Name,
+		channel.WebhookURL,
+		channel.Events,
+		channel.Labels,
+		channel.Enabled,
+		channel.CursorAt,
+		channel.CursorID,
+		channel.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create notification channel: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a channel by ID
+func (r *NotificationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.NotificationChannel, error) {
+	query := `SELECT ` + notificationChannelColumns + ` FROM notification_channels WHERE id = $1`
+
+	channel, err := scanNotificationChannel(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotificationChannelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification channel: %w", err)
+	}
+
+	return channel, nil
+}
+
+// ListByUser retrieves a user's channels, newest first
+func (r *NotificationRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.NotificationChannel, error) {
+	query := `SELECT ` + notificationChannelColumns + ` FROM notification_channels WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotificationChannels(rows)
+}
+
+// Update saves a channel's name, webhook, events and whether it is enabled,
+// along with its cursor and next attempt time
+func (r *NotificationRepository) Update(ctx context.Context, channel *models.NotificationChannel) error {
+	query := `
+		UPDATE notification_channels
+		SET name = $1, webhook_url = $2, events = $3, enabled = $4,
+			cursor_at = $5, cursor_id = $6, failures = $7, last_error = $8, next_attempt_at = $9
+		WHERE id = $10
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		channel.Name,
+		channel.WebhookURL,
+		channel.Events,
+		channel.Enabled,
+		channel.CursorAt,
+		channel.CursorID,
+		channel.Failures,
+		channel.LastError,
+		channel.NextAttemptAt,
+		channel.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update notification channel: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotificationChannelNotFound
+	}
+
+	return nil
+}
+
+// ClaimDue locks enabled channels that are due for a delivery attempt.
+// It must run inside a transaction; channels locked by another replica are skipped.
+func (r *NotificationRepository) ClaimDue(ctx context.Context, limit int) ([]*models.NotificationChannel, error) {
+	query := `SELECT ` + notificationChannelColumns + `
+		FROM notification_channels
+		WHERE enabled AND next_attempt_at <= $1
+		ORDER BY next_attempt_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNotificationChannels(rows)
+}
+
+// UpdateDelivery saves the cursor, failure count, last error and next attempt
+// time of a channel after a delivery attempt, and whether it is still enabled
+func (r *NotificationRepository) UpdateDelivery(ctx context.Context, channel *models.NotificationChannel) error {
+	query := `
+		UPDATE notification_channels
+		SET enabled = $1, cursor_at = $2, cursor_id = $3, failures = $4, last_error = $5,
+			last_delivered_at = $6, next_attempt_at = $7
+		WHERE id = $8
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		channel.Enabled,
+		channel.CursorAt,
+		channel.CursorID,
+		channel.Failures,
+		channel.LastError,
+		channel.LastDeliveredAt,
+		channel.NextAttemptAt,
+		channel.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update notification channel delivery: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotificationChannelNotFound
+	}
+
+	return nil
+}
+
+// Delete deletes a channel
+func (r *NotificationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM notification_channels WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrNotificationChannelNotFound
+	}
+
+	return nil
+}
+
+// ListFinishedJobs retrieves a user's jobs with one of the statuses that
+// finished after the (afterAt, afterID) position and before settledBefore,
+// oldest first, with their document and latest result. Jobs that finished
+// more recently are left for later, so a job committed late with an earlier
+// completion time is not passed over.
+func (r *NotificationRepository) ListFinishedJobs(ctx context.Context, userID uuid.UUID, statuses []models.JobStatus, afterAt time.Time, afterID uuid.UUID, settledBefore time.Time, limit int) ([]*models.JobNotification, error) {
+	query := `
+		SELECT j.id, j.document_id, d.original_filename, j.status, j.error_message,
+			   r.id, r.confidence_score, j.completed_at
+		FROM ocr_jobs j
+		JOIN documents d ON d.id = j.document_id
+		LEFT JOIN LATERAL (
+			SELECT id, confidence_score FROM ocr_results
+			WHERE job_id = j.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) r ON TRUE
+		WHERE j.user_id = $1 AND j.status = ANY($2) AND d.deleted_at IS NULL
+		  AND ($3::text[] IS NULL OR d.labels && $3)
+		  AND (j.completed_at, j.id) > ($4, $5) AND j.completed_at <= $6
+		ORDER BY j.completed_at, j.id
+		LIMIT $7
+	`
+
+	names := make([]string, len(statuses))
+	for i, status := range statuses {
+		names[i] = string(status)
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, names, LabelScope(ctx), afterAt, afterID, settledBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list finished jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.JobNotification{}
+	for rows.Next() {
+		var job models.JobNotification
+		err := rows.Scan(
+			&job.JobID,
+			&job.DocumentID,
+			&job.Filename,
+			&job.Status,
+			&job.ErrorMessage,
+			&job.ResultID,
+			&job.Confidence,
+			&job.CompletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan finished job: %w", err)
+		}
+		jobs = append(jobs, &job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list finished jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func scanNotificationChannels(rows pgx.Rows) ([]*models.NotificationChannel, error) {
+	channels := []*models.NotificationChannel{}
+	for rows.Next() {
+		channel, err := scanNotificationChannel(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification channel: %w", err)
+		}
+		channels = append(channels, channel)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list notification channels: %w", err)
+	}
+
+	return channels, nil
+}
+
+func scanNotificationChannel(row pgx.Row) (*models.NotificationChannel, error) {
+	var channel models.NotificationChannel
+	err := row.Scan(
+		&channel.ID,
+		&channel.UserID,
+		&channel.Kind,
+		&channel.Name,
+		&channel.WebhookURL,
+		&channel.Events,
+		&channel.Labels,
+		&channel.Enabled,
+		&channel.CursorAt,
+		&channel.CursorID,
+		&channel.Failures,
+		&channel.LastError,
+		&channel.LastDeliveredAt,
+		&channel.NextAttemptAt,
+		&channel.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
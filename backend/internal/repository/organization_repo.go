@@ -0,0 +1,559 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrOrganizationNotFound is returned when an organization does not exist
+	// or the user is not one of its members
+	ErrOrganizationNotFound = apperrors.NotFound("ORG_001", "organization not found")
+	// ErrOrgMemberNotFound is returned when a user is not a member of an organization
+	ErrOrgMemberNotFound = apperrors.NotFound("ORG_002", "organization member not found")
+	// ErrOrgInvitationNotFound is returned when an invitation does not exist
+	ErrOrgInvitationNotFound = apperrors.NotFound("ORG_003", "invitation not found")
+	// ErrCustomDomainClaimed is returned when a custom domain is verified by
+	// another organization or the instance
+	ErrCustomDomainClaimed = apperrors.Conflict("BRAND_002", "the custom domain is already used by another organization")
+)
+
+// orgInvitationColumns are the columns scanned by scanOrgInvitation, with i the
+// invitation and o its organization
+const orgInvitationColumns = `
+	i.id, i.organization_id, o.name, i.email, i.role, i.invited_by,
+	i.expires_at, i.created_at, i.accepted_at, i.declined_at
+`
+
+// OrganizationRepository handles organizations, their members and invitations
+type OrganizationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *pgxpool.Pool) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// Create stores a new organization
+func (r *OrganizationRepository) Create(ctx context.Context, org *models.Organization) error {
+	query := `
+		INSERT INTO organizations (id, name, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	org.ID = uuid.New()
+	org.CreatedAt = time.Now().UTC()
+	org.UpdatedAt = org.CreatedAt
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, org.ID, org.Name, org.CreatedBy, org.CreatedAt, org.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return nil
+}
+
+// GetForMember retrieves an organization with the role of one of its members.
+// Users outside the organization get ErrOrganizationNotFound.
+func (r *OrganizationRepository) GetForMember(ctx context.Context, id, userID uuid.UUID) (*models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_by, o.created_at, o.updated_at, m.role,
+		       (SELECT COUNT(*) FROM organization_members c WHERE c.organization_id = o.id)
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id AND m.user_id = $2
+		WHERE o.id = $1
+	`
+
+	org, err := scanOrganization(conn(ctx, r.db).QueryRow(ctx, query, id, userID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// ListByUser retrieves the organizations a user is a member of, by name
+func (r *OrganizationRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.created_by, o.created_at, o.updated_at, m.role,
+		       (SELECT COUNT(*) FROM organization_members c WHERE c.organization_id = o.id)
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.name, o.created_at
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []*models.Organization{}
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// Delete removes an organization with its memberships and invitations. Its
+// documents stop being shared.
+func (r *OrganizationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM organizations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrOrganizationNotFound
+	}
+
+	return nil
+}
+
+// AddMember adds a user to an organization with a role
+func (r *OrganizationRepository) AddMember(ctx context.Context, orgID, userID uuid.UUID, role models.OrgRole) error {
+	query := `
+		INSERT INTO organization_members (organization_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, orgID, userID, role, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to add organization member: %w", err)
+	}
+
+	return nil
+}
+
+// GetMemberRole returns a user's role in an organization
+func (r *OrganizationRepository) GetMemberRole(ctx context.Context, orgID, userID uuid.UUID) (models.OrgRole, error) {
+	query := `SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2`
+
+	var role models.OrgRole
+	err := conn(ctx, r.db).QueryRow(ctx, query, orgID, userID).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", ErrOrgMemberNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get organization member: %w", err)
+	}
+
+	return role, nil
+}
+
+// GetFirstOrgID returns the organization a user joined first, or nil when the
+// user is not a member of any
+func (r *OrganizationRepository) GetFirstOrgID(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	query := `
+		SELECT organization_id FROM organization_members
+		WHERE user_id = $1
+		ORDER BY created_at, organization_id
+		LIMIT 1
+	`
+
+	var orgID uuid.UUID
+	err := conn(ctx, r.db).QueryRow(ctx, query, userID).Scan(&orgID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization membership: %w", err)
+	}
+
+	return &orgID, nil
+}
+
+// GetOCRPolicy retrieves the encoded OCR policy of an organization
+func (r *OrganizationRepository) GetOCRPolicy(ctx context.Context, orgID uuid.UUID) (value []byte, ok bool, err error) {
+	query := `SELECT policy FROM organization_ocr_policies WHERE organization_id = $1`
+
+	err = conn(ctx, r.db).QueryRow(ctx, query, orgID).Scan(&value)
+	if err == pgx.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get OCR policy: %w", err)
+	}
+
+	return value, true, nil
+}
+
+// SetOCRPolicy creates or replaces the encoded OCR policy of an organization
+func (r *OrganizationRepository) SetOCRPolicy(ctx context.Context, orgID uuid.UUID, value []byte) error {
+	query := `
+		INSERT INTO organization_ocr_policies (organization_id, policy, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id) DO UPDATE SET policy = EXCLUDED.policy, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, orgID, value, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to set OCR policy: %w", err)
+	}
+
+	return nil
+}
+
+// GetBranding retrieves the encoded branding of an organization
+func (r *OrganizationRepository) GetBranding(ctx context.Context, orgID uuid.UUID) (value []byte, ok bool, err error) {
+	query := `SELECT branding FROM organization_branding WHERE organization_id = $1`
+
+	err = conn(ctx, r.db).QueryRow(ctx, query, orgID).Scan(&value)
+	if err == pgx.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get branding: %w", err)
+	}
+
+	return value, true, nil
+}
+
+// SetBranding creates or replaces the encoded branding of an organization and
+// the verified custom domain it is served on, if any
+func (r *OrganizationRepository) SetBranding(ctx context.Context, orgID uuid.UUID, value []byte, verifiedDomain *string) error {
+	query := `
+		INSERT INTO organization_branding (organization_id, branding, verified_domain, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			branding = EXCLUDED.branding,
+			verified_domain = EXCLUDED.verified_domain,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, orgID, value, verifiedDomain, time.Now().UTC())
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrCustomDomainClaimed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set branding: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrgIDByDomain returns the organization that verified a custom domain, or
+// nil when none did
+func (r *OrganizationRepository) GetOrgIDByDomain(ctx context.Context, domain string) (*uuid.UUID, error) {
+	query := `SELECT organization_id FROM organization_branding WHERE verified_domain = $1`
+
+	var orgID uuid.UUID
+	err := conn(ctx, r.db).QueryRow(ctx, query, domain).Scan(&orgID)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization by domain: %w", err)
+	}
+
+	return &orgID, nil
+}
+
+// IsMemberByEmail reports whether the user with an email is a member of an
+// organization
+func (r *OrganizationRepository) IsMemberByEmail(ctx context.Context, orgID uuid.UUID, email string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM organization_members m
+			JOIN users u ON u.id = m.user_id
+			WHERE m.organization_id = $1 AND LOWER(u.email) = LOWER($2)
+		)
+	`
+
+	var exists bool
+	if err := conn(ctx, r.db).QueryRow(ctx, query, orgID, email).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check organization member: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ListMembers retrieves the members of an organization, owners first
+func (r *OrganizationRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*models.OrgMember, error) {
+	query := `
+		SELECT m.user_id, u.email, u.name, m.role, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY m.role = 'owner' DESC, m.role = 'member' DESC, u.email
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*models.OrgMember{}
+	for rows.Next() {
+		var member models.OrgMember
+		if err := rows.Scan(&member.UserID, &member.Email, &member.Name, &member.Role, &member.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		members = append(members, &member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+
+	return members, nil
+}
+
+// LockOwners locks an organization's owners for the rest of the transaction
+// and returns how many there are, so that concurrent changes cannot leave it
+// without one
+func (r *OrganizationRepository) LockOwners(ctx context.Context, orgID uuid.UUID) (int, error) {
+	query := `
+		SELECT user_id FROM organization_members
+		WHERE organization_id = $1 AND role = 'owner'
+		FOR UPDATE
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, orgID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock organization owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := 0
+	for rows.Next() {
+		owners++
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to lock organization owners: %w", err)
+	}
+
+	return owners, nil
+}
+
+// UpdateMemberRole changes a member's role
+func (r *OrganizationRepository) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role models.OrgRole) error {
+	query := `UPDATE organization_members SET role = $1 WHERE organization_id = $2 AND user_id = $3`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, role, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update organization member: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrOrgMemberNotFound
+	}
+
+	return nil
+}
+
+// RemoveMember removes a user from an organization
+func (r *OrganizationRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	query := `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove organization member: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrOrgMemberNotFound
+	}
+
+	return nil
+}
+
+// CreateInvitation stores a new invitation
+func (r *OrganizationRepository) CreateInvitation(ctx context.Context, invitation *models.OrgInvitation) error {
+	query := `
+		INSERT INTO organization_invitations (id, organization_id, email, role, invited_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	invitation.ID = uuid.New()
+	invitation.CreatedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		invitation.ID,
+		invitation.OrganizationID,
+		invitation.Email,
+		invitation.Role,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+		invitation.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return nil
+}
+
+// GetInvitation retrieves an invitation by ID
+func (r *OrganizationRepository) GetInvitation(ctx context.Context, id uuid.UUID) (*models.OrgInvitation, error) {
+	query := `SELECT ` + orgInvitationColumns + `
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.organization_id
+		WHERE i.id = $1
+	`
+
+	invitation, err := scanOrgInvitation(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrOrgInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+// ListPendingByOrg retrieves the unanswered, unexpired invitations of an
+// organization, newest first
+func (r *OrganizationRepository) ListPendingByOrg(ctx context.Context, orgID uuid.UUID) ([]*models.OrgInvitation, error) {
+	query := `SELECT ` + orgInvitationColumns + `
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.organization_id
+		WHERE i.organization_id = $1 AND i.accepted_at IS NULL AND i.declined_at IS NULL AND i.expires_at > $2
+		ORDER BY i.created_at DESC
+	`
+
+	return r.listInvitations(ctx, query, orgID, time.Now().UTC())
+}
+
+// ListPendingByEmail retrieves the unanswered, unexpired invitations sent to
+// an email, newest first
+func (r *OrganizationRepository) ListPendingByEmail(ctx context.Context, email string) ([]*models.OrgInvitation, error) {
+	query := `SELECT ` + orgInvitationColumns + `
+		FROM organization_invitations i
+		JOIN organizations o ON o.id = i.organization_id
+		WHERE LOWER(i.email) = LOWER($1) AND i.accepted_at IS NULL AND i.declined_at IS NULL AND i.expires_at > $2
+		ORDER BY i.created_at DESC
+	`
+
+	return r.listInvitations(ctx, query, email, time.Now().UTC())
+}
+
+// AnswerInvitation marks a pending invitation as accepted or declined
+func (r *OrganizationRepository) AnswerInvitation(ctx context.Context, id uuid.UUID, accepted bool) error {
+	column := "declined_at"
+	if accepted {
+		column = "accepted_at"
+	}
+	query := `
+		UPDATE organization_invitations SET ` + column + ` = $1
+		WHERE id = $2 AND accepted_at IS NULL AND declined_at IS NULL AND expires_at > $1
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to answer invitation: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrOrgInvitationNotFound
+	}
+
+	return nil
+}
+
+// DeleteInvitation revokes an unanswered invitation of an organization
+func (r *OrganizationRepository) DeleteInvitation(ctx context.Context, id, orgID uuid.UUID) error {
+	query := `
+		DELETE FROM organization_invitations
+		WHERE id = $1 AND organization_id = $2 AND accepted_at IS NULL AND declined_at IS NULL
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrOrgInvitationNotFound
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepository) listInvitations(ctx context.Context, query string, args ...any) ([]*models.OrgInvitation, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []*models.OrgInvitation{}
+	for rows.Next() {
+		invitation, err := scanOrgInvitation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+func scanOrganization(row pgx.Row) (*models.Organization, error) {
+	var org models.Organization
+	err := row.Scan(
+		&org.ID,
+		&org.Name,
+		&org.CreatedBy,
+		&org.CreatedAt,
+		&org.UpdatedAt,
+		&org.Role,
+		&org.MemberCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func scanOrgInvitation(row pgx.Row) (*models.OrgInvitation, error) {
+	var invitation models.OrgInvitation
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.OrganizationID,
+		&invitation.OrganizationName,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&invitation.CreatedAt,
+		&invitation.AcceptedAt,
+		&invitation.DeclinedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OutboxRepository handles the domain event outbox
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Create stores an event in the outbox
+func (r *OutboxRepository) Create(ctx context.Context, event *models.DomainEvent) error {
+	query := `
+		INSERT INTO event_outbox (
+			id, event_type, aggregate_type, aggregate_id, user_id, data, occurred_at, next_attempt_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		event.ID,
+		event.Type,
+		event.AggregateType,
+		event.AggregateID,
+		event.UserID,
+		event.Data,
+		event.OccurredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimPending locks unpublished events that are due for a delivery attempt.
+// It must run inside a transaction; rows locked by another relay are skipped.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int) ([]*models.DomainEvent, error) {
+	query := `
+		SELECT id, event_type, aggregate_type, aggregate_id, user_id, data,
+			   occurred_at, published_at, attempts, last_error
+		FROM event_outbox
+		WHERE published_at IS NULL AND next_attempt_at <= $1
+		ORDER BY occurred_at ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim pending events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+// List retrieves events matching the filter, newest first
+func (r *OutboxRepository) List(ctx context.Context, filter models.EventFilter) ([]*models.DomainEvent, error) {
+	query := `
+		SELECT id, event_type, aggregate_type, aggregate_id, user_id, data,
+			   occurred_at, published_at, attempts, last_error
+		FROM event_outbox
+		WHERE ($1 = '' OR event_type = $1)
+		  AND ($2::uuid IS NULL OR aggregate_id = $2)
+		  AND ($3::timestamp IS NULL OR occurred_at >= $3)
+		  AND ($4::timestamp IS NULL OR occurred_at < $4)
+		  AND ($5 = ''
+		       OR ($5 = 'published' AND published_at IS NOT NULL)
+		       OR ($5 = 'pending' AND published_at IS NULL AND attempts = 0)
+		       OR ($5 = 'failed' AND published_at IS NULL AND attempts > 0))
+		ORDER BY occurred_at DESC
+		LIMIT $6
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query,
+		string(filter.Type),
+		filter.AggregateID,
+		filter.Since,
+		filter.Until,
+		filter.Status,
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+// Replay schedules events matching the filter for redelivery, including already published ones.
+// It returns the number of events rescheduled.
+func (r *OutboxRepository) Replay(ctx context.Context, filter models.EventFilter) (int64, error) {
+	query := `
+		UPDATE event_outbox
+		SET published_at = NULL, attempts = 0, last_error = NULL, next_attempt_at = $1
+		WHERE (cardinality($2::uuid[]) = 0 OR id = ANY($2))
+		  AND ($3 = '' OR event_type = $3)
+		  AND ($4::uuid IS NULL OR aggregate_id = $4)
+		  AND ($5::timestamp IS NULL OR occurred_at >= $5)
+		  AND ($6::timestamp IS NULL OR occurred_at < $6)
+	`
+
+	ids := filter.IDs
+	if ids == nil {
+		ids = []uuid.UUID{}
+	}
+
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		time.Now().UTC(),
+		ids,
+		string(filter.Type),
+		filter.AggregateID,
+		filter.Since,
+		filter.Until,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay events: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// scanEvents scans outbox rows into domain events
+func scanEvents(rows pgx.Rows) ([]*models.DomainEvent, error) {
+	events := []*models.DomainEvent{}
+	for rows.Next() {
+		var event models.DomainEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.Type,
+			&event.AggregateType,
+			&event.AggregateID,
+			&event.UserID,
+			&event.Data,
+			&event.OccurredAt,
+			&event.PublishedAt,
+			&event.Attempts,
+			&event.LastError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// MarkPublished records a successful delivery
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE event_outbox
+		SET published_at = $1, attempts = attempts + 1, last_error = NULL
+		WHERE id = $2
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark event published: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed records a failed delivery and schedules the next attempt
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, errorMessage string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE event_outbox
+		SET attempts = attempts + 1, last_error = $1, next_attempt_at = $2
+		WHERE id = $3
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, errorMessage, nextAttemptAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark event failed: %w", err)
+	}
+
+	return nil
+}
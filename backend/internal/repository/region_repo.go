@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RegionRepository handles result region database operations
+type RegionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRegionRepository creates a new region repository
+func NewRegionRepository(db *pgxpool.Pool) *RegionRepository {
+	return &RegionRepository{db: db}
+}
+
+// CreateBatch stores all regions of a result using a bulk copy
+func (r *RegionRepository) CreateBatch(ctx context.Context, regions []*models.ResultRegion) error {
+	if len(regions) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	rows := make([][]any, 0, len(regions))
+	for _, region := range regions {
+		region.ID = uuid.New()
+		rows = append(rows, []any{
+			region.ID,
+			region.ResultID,
+			region.PageNumber,
+			region.Level,
+			region.Sequence,
+			region.Text,
+			region.X0,
+			region.Y0,
+			region.X1,
+			region.Y1,
+			region.Confidence,
+			now,
+		})
+	}
+
+	_, err := conn(ctx, r.db).CopyFrom(ctx,
+		pgx.Identifier{"result_regions"},
+		[]string{"id", "result_id", "page_number", "level", "sequence", "text", "x0", "y0", "x1", "y1", "confidence", "created_at"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create regions: %w", err)
+	}
+
+	return nil
+}
+
+// ListByResult retrieves the regions of a result, optionally limited to one page.
+// A page of 0 returns the regions of all pages.
+func (r *RegionRepository) ListByResult(ctx context.Context, resultID uuid.UUID, page int) ([]*models.ResultRegion, error) {
+	query := `
+		SELECT id, result_id, page_number, level, sequence, text,
+			   x0, y0, x1, y1, confidence
+		FROM result_regions
+		WHERE result_id = $1 AND ($2 = 0 OR page_number = $2)
+		ORDER BY page_number ASC, level ASC, sequence ASC
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, resultID, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get regions: %w", err)
+	}
+	defer rows.Close()
+
+	regions := []*models.ResultRegion{}
+	for rows.Next() {
+		var region models.ResultRegion
+		var text *string
+		var confidence *float64
+		err := rows.Scan(
+			&region.ID,
+			&region.ResultID,
+			&region.PageNumber,
+			&region.Level,
+			&region.Sequence,
+			&text,
+			&region.X0,
+			&region.Y0,
+			&region.X1,
+			&region.Y1,
+			&confidence,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan region: %w", err)
+		}
+		if text != nil {
+			region.Text = *text
+		}
+		if confidence != nil {
+			region.Confidence = *confidence
+		}
+		regions = append(regions, &region)
+	}
+
+	return regions, nil
+}
+
+// DeletePage deletes the regions of one page of a result
+func (r *RegionRepository) DeletePage(ctx context.Context, resultID uuid.UUID, page int) error {
+	query := `DELETE FROM result_regions WHERE result_id = $1 AND page_number = $2`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, resultID, page); err != nil {
+		return fmt.Errorf("failed to delete regions: %w", err)
+	}
+
+	return nil
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
+	"visekai/backend/internal/apperrors"
 	"visekai/backend/internal/models"
 
 	"github.com/google/uuid"
@@ -12,6 +14,12 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrResultNotFound is returned when a result does not exist
+var ErrResultNotFound = apperrors.NotFound("RES_004", "result not found")
+
+// ErrResultPageNotFound is returned when a result has no such page or page version
+var ErrResultPageNotFound = apperrors.NotFound("RES_008", "result page not found")
+
 // ResultRepository handles OCR result database operations
 type ResultRepository struct {
 	db *pgxpool.Pool
@@ -32,9 +40,10 @@ func (r *ResultRepository) Create(ctx context.Context, result *models.OCRResult)
 	`
 
 	result.ID = uuid.New()
-	result.CreatedAt = time.Now()
+	result.Version = 1
+	result.CreatedAt = time.Now().UTC()
 
-	_, err := r.db.Exec(ctx, query,
+	_, err := conn(ctx, r.db).Exec(ctx, query,
 		result.ID,
 		result.JobID,
 		result.DocumentID,
@@ -58,13 +67,13 @@ func (r *ResultRepository) Create(ctx context.Context, result *models.OCRResult)
 func (r *ResultRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.OCRResult, error) {
 	query := `
 		SELECT id, job_id, document_id, raw_text, markdown_text, json_data,
-			   confidence_score, processing_time_ms, num_pages, created_at
+			   confidence_score, processing_time_ms, num_pages, version, created_at, updated_at
 		FROM ocr_results
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	var result models.OCRResult
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := conn(ctx, r.db).QueryRow(ctx, query, id).Scan(
 		&result.ID,
 		&result.JobID,
 		&result.DocumentID,
@@ -74,11 +83,13 @@ func (r *ResultRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.O
 		&result.ConfidenceScore,
 		&result.ProcessingTimeMs,
 		&result.NumPages,
+		&result.Version,
 		&result.CreatedAt,
+		&result.UpdatedAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("result not found")
+		return nil, ErrResultNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get result: %w", err)
@@ -91,13 +102,13 @@ func (r *ResultRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.O
 func (r *ResultRepository) GetByJobID(ctx context.Context, jobID uuid.UUID) (*models.OCRResult, error) {
 	query := `
 		SELECT id, job_id, document_id, raw_text, markdown_text, json_data,
-			   confidence_score, processing_time_ms, num_pages, created_at
+			   confidence_score, processing_time_ms, num_pages, version, created_at, updated_at
 		FROM ocr_results
-		WHERE job_id = $1
+		WHERE job_id = $1 AND deleted_at IS NULL
 	`
 
 	var result models.OCRResult
-	err := r.db.QueryRow(ctx, query, jobID).Scan(
+	err := conn(ctx, r.db).QueryRow(ctx, query, jobID).Scan(
 		&result.ID,
 		&result.JobID,
 		&result.DocumentID,
@@ -107,11 +118,13 @@ func (r *ResultRepository) GetByJobID(ctx context.Context, jobID uuid.UUID) (*mo
 		&result.ConfidenceScore,
 		&result.ProcessingTimeMs,
 		&result.NumPages,
+		&result.Version,
 		&result.CreatedAt,
+		&result.UpdatedAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("result not found")
+		return nil, ErrResultNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get result: %w", err)
@@ -124,13 +137,13 @@ func (r *ResultRepository) GetByJobID(ctx context.Context, jobID uuid.UUID) (*mo
 func (r *ResultRepository) GetByDocumentID(ctx context.Context, documentID uuid.UUID) ([]*models.OCRResult, error) {
 	query := `
 		SELECT id, job_id, document_id, raw_text, markdown_text, json_data,
-			   confidence_score, processing_time_ms, num_pages, created_at
+			   confidence_score, processing_time_ms, num_pages, version, created_at, updated_at
 		FROM ocr_results
-		WHERE document_id = $1
+		WHERE document_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, documentID)
+	rows, err := conn(ctx, r.db).Query(ctx, query, documentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get results: %w", err)
 	}
@@ -149,7 +162,9 @@ func (r *ResultRepository) GetByDocumentID(ctx context.Context, documentID uuid.
 			&result.ConfidenceScore,
 			&result.ProcessingTimeMs,
 			&result.NumPages,
+			&result.Version,
 			&result.CreatedAt,
+			&result.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan result: %w", err)
@@ -169,7 +184,7 @@ func (r *ResultRepository) Update(ctx context.Context, result *models.OCRResult)
 		WHERE id = $7
 	`
 
-	res, err := r.db.Exec(ctx, query,
+	res, err := conn(ctx, r.db).Exec(ctx, query,
 		result.RawText,
 		result.MarkdownText,
 		result.JSONData,
@@ -184,23 +199,472 @@ func (r *ResultRepository) Update(ctx context.Context, result *models.OCRResult)
 	}
 
 	if res.RowsAffected() == 0 {
-		return fmt.Errorf("result not found")
+		return ErrResultNotFound
+	}
+
+	return nil
+}
+
+// GetVersionForUpdate returns the version of a result and locks it until the
+// transaction in ctx ends
+func (r *ResultRepository) GetVersionForUpdate(ctx context.Context, id uuid.UUID) (int, error) {
+	query := `SELECT version FROM ocr_results WHERE id = $1 FOR UPDATE`
+
+	var version int
+	err := conn(ctx, r.db).QueryRow(ctx, query, id).Scan(&version)
+	if err == pgx.ErrNoRows {
+		return 0, ErrResultNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get result version: %w", err)
+	}
+
+	return version, nil
+}
+
+// UpdateText replaces the text and confidence of a result, increments its
+// version and sets Version and UpdatedAt on the result
+func (r *ResultRepository) UpdateText(ctx context.Context, result *models.OCRResult) error {
+	query := `
+		UPDATE ocr_results
+		SET raw_text = $1, markdown_text = $2, confidence_score = $3,
+		    version = version + 1, updated_at = $4
+		WHERE id = $5
+		RETURNING version, updated_at
+	`
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		result.RawText,
+		result.MarkdownText,
+		result.ConfidenceScore,
+		time.Now().UTC(),
+		result.ID,
+	).Scan(&result.Version, &result.UpdatedAt)
+
+	if err == pgx.ErrNoRows {
+		return ErrResultNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update result text: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateJSONData replaces the structured data of a result
+func (r *ResultRepository) UpdateJSONData(ctx context.Context, id uuid.UUID, data map[string]any) error {
+	query := `UPDATE ocr_results SET json_data = $1 WHERE id = $2`
+
+	res, err := conn(ctx, r.db).Exec(ctx, query, data, id)
+	if err != nil {
+		return fmt.Errorf("failed to update result data: %w", err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrResultNotFound
+	}
+
+	return nil
+}
+
+// CreatePages stores page versions of a result
+func (r *ResultRepository) CreatePages(ctx context.Context, pages []*models.ResultPage) error {
+	if len(pages) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	rows := make([][]any, 0, len(pages))
+	for _, page := range pages {
+		page.CreatedAt = now
+		rows = append(rows, []any{
+			page.ResultID,
+			page.PageNumber,
+			page.Version,
+			page.JobID,
+			page.ResolutionMode,
+			page.RawText,
+			page.MarkdownText,
+			page.ConfidenceScore,
+			page.ProcessingTimeMs,
+			page.Backend,
+			page.Credits,
+			page.ImageWidth,
+			page.ImageHeight,
+			page.CreatedAt,
+		})
+	}
+
+	_, err := conn(ctx, r.db).CopyFrom(ctx,
+		pgx.Identifier{"result_pages"},
+		[]string{"result_id", "page_number", "version", "job_id", "resolution_mode", "raw_text", "markdown_text", "confidence_score", "processing_time_ms", "backend", "credits", "image_width", "image_height", "created_at"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create result pages: %w", err)
 	}
 
 	return nil
 }
 
+// ListCurrentPages retrieves the latest version of every page of a result, in page order
+func (r *ResultRepository) ListCurrentPages(ctx context.Context, resultID uuid.UUID) ([]*models.ResultPage, error) {
+	query := `
+		SELECT DISTINCT ON (page_number)
+			   result_id, page_number, version, job_id, resolution_mode,
+			   raw_text, markdown_text, COALESCE(confidence_score, 0),
+			   processing_time_ms, backend, credits, image_width, image_height, created_at
+		FROM result_pages
+		WHERE result_id = $1
+		ORDER BY page_number, version DESC
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, resultID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get result pages: %w", err)
+	}
+	defer rows.Close()
+
+	var pages []*models.ResultPage
+	for rows.Next() {
+		var page models.ResultPage
+		err := rows.Scan(
+			&page.ResultID,
+			&page.PageNumber,
+			&page.Version,
+			&page.JobID,
+			&page.ResolutionMode,
+			&page.RawText,
+			&page.MarkdownText,
+			&page.ConfidenceScore,
+			&page.ProcessingTimeMs,
+			&page.Backend,
+			&page.Credits,
+			&page.ImageWidth,
+			&page.ImageHeight,
+			&page.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan result page: %w", err)
+		}
+		pages = append(pages, &page)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get result pages: %w", err)
+	}
+
+	return pages, nil
+}
+
+// GetPage retrieves a version of a page of a result; a version of 0 retrieves
+// the latest
+func (r *ResultRepository) GetPage(ctx context.Context, resultID uuid.UUID, pageNumber, version int) (*models.ResultPage, error) {
+	query := `
+		SELECT result_id, page_number, version, job_id, resolution_mode,
+			   raw_text, markdown_text, COALESCE(confidence_score, 0),
+			   processing_time_ms, backend, credits, image_width, image_height, created_at
+		FROM result_pages
+		WHERE result_id = $1 AND page_number = $2 AND ($3 = 0 OR version = $3)
+		ORDER BY version DESC
+		LIMIT 1
+	`
+
+	var page models.ResultPage
+	err := conn(ctx, r.db).QueryRow(ctx, query, resultID, pageNumber, version).Scan(
+		&page.ResultID,
+		&page.PageNumber,
+		&page.Version,
+		&page.JobID,
+		&page.ResolutionMode,
+		&page.RawText,
+		&page.MarkdownText,
+		&page.ConfidenceScore,
+		&page.ProcessingTimeMs,
+		&page.Backend,
+		&page.Credits,
+		&page.ImageWidth,
+		&page.ImageHeight,
+		&page.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrResultPageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get result page: %w", err)
+	}
+
+	return &page, nil
+}
+
+// pageCostColumns selects a models.PageCost from result_pages p joined to its
+// job j, whose resolution stands in for pages recorded without one
+const pageCostColumns = `
+	p.result_id, p.page_number, p.version, p.job_id,
+	COALESCE(p.resolution_mode, j.resolution_mode), p.backend,
+	p.processing_time_ms, p.credits,
+	NOT EXISTS (
+		SELECT 1 FROM result_pages n
+		WHERE n.result_id = p.result_id AND n.page_number = p.page_number AND n.version > p.version
+	),
+	p.created_at`
+
+// scanPageCosts scans rows selected with pageCostColumns
+func scanPageCosts(rows pgx.Rows) ([]*models.PageCost, error) {
+	defer rows.Close()
+
+	costs := []*models.PageCost{}
+	for rows.Next() {
+		var cost models.PageCost
+		err := rows.Scan(
+			&cost.ResultID,
+			&cost.PageNumber,
+			&cost.Version,
+			&cost.JobID,
+			&cost.ResolutionMode,
+			&cost.Backend,
+			&cost.ProcessingTimeMs,
+			&cost.Credits,
+			&cost.Current,
+			&cost.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan page cost: %w", err)
+		}
+		costs = append(costs, &cost)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get page costs: %w", err)
+	}
+
+	return costs, nil
+}
+
+// ListPageCosts retrieves what every version of every page of a result cost,
+// in page and version order
+func (r *ResultRepository) ListPageCosts(ctx context.Context, resultID uuid.UUID) ([]*models.PageCost, error) {
+	query := `SELECT ` + pageCostColumns + `
+		FROM result_pages p
+		LEFT JOIN ocr_jobs j ON j.id = p.job_id
+		WHERE p.result_id = $1
+		ORDER BY p.page_number, p.version
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, resultID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page costs: %w", err)
+	}
+
+	return scanPageCosts(rows)
+}
+
+// PageCostGroups sums the page versions a user's jobs processed in [since,
+// until) by resolution and by backend, most expensive first. Pages of an
+// unknown backend are grouped under "unknown".
+func (r *ResultRepository) PageCostGroups(ctx context.Context, userID uuid.UUID, since, until time.Time) (byResolution, byBackend []*models.PageCostGroup, err error) {
+	query := `
+		SELECT GROUPING(COALESCE(p.backend, 'unknown')) = 0,
+			   COALESCE(COALESCE(p.backend, 'unknown'), COALESCE(p.resolution_mode, j.resolution_mode)),
+			   COUNT(*),
+			   COALESCE(SUM(p.processing_time_ms), 0),
+			   COALESCE(AVG(p.processing_time_ms), 0),
+			   COALESCE(MAX(p.processing_time_ms), 0),
+			   COALESCE(SUM(p.credits), 0)
+		FROM result_pages p
+		JOIN ocr_jobs j ON j.id = p.job_id
+		WHERE j.user_id = $1 AND p.created_at >= $2 AND p.created_at < $3
+		GROUP BY GROUPING SETS (
+			(COALESCE(p.resolution_mode, j.resolution_mode)),
+			(COALESCE(p.backend, 'unknown'))
+		)
+		ORDER BY 7 DESC, 4 DESC
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, since, until)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get page cost groups: %w", err)
+	}
+	defer rows.Close()
+
+	byResolution = []*models.PageCostGroup{}
+	byBackend = []*models.PageCostGroup{}
+	for rows.Next() {
+		var isBackend bool
+		var group models.PageCostGroup
+		err := rows.Scan(
+			&isBackend,
+			&group.Key,
+			&group.Pages,
+			&group.ProcessingTimeMs,
+			&group.AvgProcessingTimeMs,
+			&group.MaxProcessingTimeMs,
+			&group.Credits,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan page cost group: %w", err)
+		}
+		if isBackend {
+			byBackend = append(byBackend, &group)
+		} else {
+			byResolution = append(byResolution, &group)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to get page cost groups: %w", err)
+	}
+
+	return byResolution, byBackend, nil
+}
+
+// ListSlowestPages retrieves the page versions a user's jobs processed in
+// [since, until) that took longest, slowest first
+func (r *ResultRepository) ListSlowestPages(ctx context.Context, userID uuid.UUID, since, until time.Time, limit int) ([]*models.PageCost, error) {
+	query := `SELECT ` + pageCostColumns + `
+		FROM result_pages p
+		JOIN ocr_jobs j ON j.id = p.job_id
+		WHERE j.user_id = $1 AND p.created_at >= $2 AND p.created_at < $3
+		  AND p.processing_time_ms IS NOT NULL
+		ORDER BY p.processing_time_ms DESC, p.created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, since, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slowest pages: %w", err)
+	}
+
+	return scanPageCosts(rows)
+}
+
+// ListDocumentFields retrieves a user's documents matching req, oldest upload
+// first, each with the fields stored in its latest result. At most limit
+// documents are returned.
+func (r *ResultRepository) ListDocumentFields(ctx context.Context, userID uuid.UUID, req models.FieldsExportRequest, limit int) ([]*models.DocumentFields, error) {
+	query := `
+		SELECT d.id, d.original_filename, d.uploaded_at, COALESCE(d.document_type, ''), r.json_data->'fields'
+		FROM documents d
+		LEFT JOIN LATERAL (
+			SELECT json_data FROM ocr_results
+			WHERE document_id = d.id AND deleted_at IS NULL
+			ORDER BY created_at DESC
+			LIMIT 1
+		) r ON true
+		WHERE d.user_id = $1 AND d.deleted_at IS NULL AND ($2::text[] IS NULL OR d.labels && $2)
+		  AND ($3::text[] IS NULL OR d.labels && $3)
+		  AND ($4::timestamptz IS NULL OR d.uploaded_at >= $4)
+		  AND ($5::timestamptz IS NULL OR d.uploaded_at < $5)
+		  AND ($6 = '' OR d.document_type = $6)
+		ORDER BY d.uploaded_at, d.id
+		LIMIT $7
+	`
+
+	var labels []string
+	if len(req.Labels) > 0 {
+		labels = req.Labels
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, LabelScope(ctx), labels, req.From, req.Until, req.DocumentType, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document fields: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []*models.DocumentFields{}
+	for rows.Next() {
+		document := &models.DocumentFields{}
+		if err := rows.Scan(
+			&document.DocumentID,
+			&document.Filename,
+			&document.UploadedAt,
+			&document.DocumentType,
+			&document.Fields,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan document fields: %w", err)
+		}
+		documents = append(documents, document)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list document fields: %w", err)
+	}
+
+	return documents, nil
+}
+
+// ListIntegrationResults retrieves results of a user's documents created
+// after the (afterAt, afterID) position, oldest first. Without a position it
+// retrieves the latest results, still oldest first. At most limit results are
+// returned.
+func (r *ResultRepository) ListIntegrationResults(ctx context.Context, userID uuid.UUID, afterAt *time.Time, afterID uuid.UUID, limit int) ([]*models.IntegrationResult, error) {
+	order := "ASC"
+	if afterAt == nil {
+		order = "DESC"
+	}
+	query := fmt.Sprintf(`
+		SELECT r.id, r.job_id, r.document_id, d.original_filename, COALESCE(d.document_type, ''),
+			   r.raw_text, r.confidence_score, r.num_pages, r.json_data->'fields', r.created_at
+		FROM ocr_results r
+		JOIN documents d ON d.id = r.document_id
+		WHERE d.user_id = $1 AND d.deleted_at IS NULL AND r.deleted_at IS NULL AND ($2::text[] IS NULL OR d.labels && $2)
+		  AND ($3::timestamptz IS NULL OR (r.created_at, r.id) > ($3, $4))
+		ORDER BY r.created_at %[1]s, r.id %[1]s
+		LIMIT $5
+	`, order)
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, LabelScope(ctx), afterAt, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list integration results: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*models.IntegrationResult{}
+	for rows.Next() {
+		result := &models.IntegrationResult{}
+		var fields *models.ResultFields
+		if err := rows.Scan(
+			&result.ID,
+			&result.JobID,
+			&result.DocumentID,
+			&result.Filename,
+			&result.DocumentType,
+			&result.Text,
+			&result.Confidence,
+			&result.NumPages,
+			&fields,
+			&result.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan integration result: %w", err)
+		}
+		result.Fields = map[string]string{}
+		if fields != nil && fields.Fields != nil {
+			result.Fields = fields.Fields
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list integration results: %w", err)
+	}
+
+	if afterAt == nil {
+		slices.Reverse(results)
+	}
+	return results, nil
+}
+
 // Delete deletes a result
 func (r *ResultRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM ocr_results WHERE id = $1`
 
-	result, err := r.db.Exec(ctx, query, id)
+	result, err := conn(ctx, r.db).Exec(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete result: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("result not found")
+		return ErrResultNotFound
 	}
 
 	return nil
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RetentionRepository stores users' retention policies and finds the data
+// they delete
+type RetentionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRetentionRepository creates a new retention repository
+func NewRetentionRepository(db *pgxpool.Pool) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// ListByUser retrieves a user's policies
+func (r *RetentionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.RetentionPolicy, error) {
+	query := `
+		SELECT user_id, target, days, warned_until, warned_at, updated_at
+		FROM retention_policies
+		WHERE user_id = $1
+	`
+	return r.list(ctx, query, userID)
+}
+
+// ListAll retrieves every user's policies
+func (r *RetentionRepository) ListAll(ctx context.Context) ([]*models.RetentionPolicy, error) {
+	query := `
+		SELECT user_id, target, days, warned_until, warned_at, updated_at
+		FROM retention_policies
+		ORDER BY user_id, target
+	`
+	return r.list(ctx, query)
+}
+
+// Days returns how many days a user keeps the data of a target, 0 when the
+// user has no policy for it
+func (r *RetentionRepository) Days(ctx context.Context, userID uuid.UUID, target models.RetentionTarget) (int, error) {
+	var days int
+	err := conn(ctx, r.db).QueryRow(ctx,
+		`SELECT days FROM retention_policies WHERE user_id = $1 AND target = $2`, userID, target,
+	).Scan(&days)
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get retention policy: %w", err)
+	}
+	return days, nil
+}
+
+// Set stores a user's policy for a target, removing it when days is 0. A
+// changed policy counts as new: it warns again about everything it will
+// delete, and deletes nothing until its grace period elapsed.
+func (r *RetentionRepository) Set(ctx context.Context, userID uuid.UUID, target models.RetentionTarget, days int) error {
+	if days <= 0 {
+		if _, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM retention_policies WHERE user_id = $1 AND target = $2`, userID, target); err != nil {
+			return fmt.Errorf("failed to delete retention policy: %w", err)
+		}
+		return nil
+	}
+
+	query := `
+		INSERT INTO retention_policies (user_id, target, days)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, target) DO UPDATE SET
+			days = EXCLUDED.days,
+			warned_until = CASE WHEN retention_policies.days = EXCLUDED.days THEN retention_policies.warned_until END,
+			updated_at = CASE WHEN retention_policies.days = EXCLUDED.days THEN retention_policies.updated_at ELSE NOW() END
+	`
+	if _, err := conn(ctx, r.db).Exec(ctx, query, userID, target, days); err != nil {
+		return fmt.Errorf("failed to save retention policy: %w", err)
+	}
+	return nil
+}
+
+// MarkWarned records that a policy's deletions up to until were warned about,
+// and when the user was last warned unless warnedAt is nil
+func (r *RetentionRepository) MarkWarned(ctx context.Context, policy *models.RetentionPolicy, until time.Time, warnedAt *time.Time) error {
+	query := `
+		UPDATE retention_policies
+		SET warned_until = $3, warned_at = COALESCE($4, warned_at)
+		WHERE user_id = $1 AND target = $2
+	`
+	if _, err := conn(ctx, r.db).Exec(ctx, query, policy.UserID, policy.Target, until, warnedAt); err != nil {
+		return fmt.Errorf("failed to mark retention warning: %w", err)
+	}
+	return nil
+}
+
+// CountResultText counts a user's results whose text is still stored and
+// that were created in [from, until), returning the oldest one's creation
+func (r *RetentionRepository) CountResultText(ctx context.Context, userID uuid.UUID, from, until time.Time) (int, *time.Time, error) {
+	query := `
+		SELECT COUNT(*), MIN(r.created_at)
+		FROM ocr_results r
+		JOIN documents d ON d.id = r.document_id
+		WHERE d.user_id = $1 AND r.text_deleted_at IS NULL AND r.deleted_at IS NULL
+		  AND r.created_at >= $2 AND r.created_at < $3
+	`
+	return r.count(ctx, query, userID, from, until)
+}
+
+// DeleteResultText deletes the text of up to limit of a user's results
+// created before cutoff: the text of the result and its pages and the words
+// and lines located on its pages. It returns the number of results cleared.
+func (r *RetentionRepository) DeleteResultText(ctx context.Context, userID uuid.UUID, cutoff time.Time, limit int) (int, error) {
+	query := `
+		WITH cleared AS (
+			UPDATE ocr_results
+			SET raw_text = '', markdown_text = '', text_deleted_at = NOW()
+			WHERE id IN (
+				SELECT r.id FROM ocr_results r
+				JOIN documents d ON d.id = r.document_id
+				WHERE d.user_id = $1 AND r.text_deleted_at IS NULL AND r.created_at < $2
+				LIMIT $3
+				FOR UPDATE OF r SKIP LOCKED
+			)
+			RETURNING id
+		), pages AS (
+			UPDATE result_pages SET raw_text = '', markdown_text = ''
+			WHERE result_id IN (SELECT id FROM cleared)
+		), regions AS (
+			DELETE FROM result_regions WHERE result_id IN (SELECT id FROM cleared)
+		)
+		SELECT COUNT(*) FROM cleared
+	`
+
+	var cleared int
+	if err := conn(ctx, r.db).QueryRow(ctx, query, userID, cutoff, limit).Scan(&cleared); err != nil {
+		return 0, fmt.Errorf("failed to delete result text: %w", err)
+	}
+	return cleared, nil
+}
+
+// CountDocuments counts a user's live documents uploaded in [from, until),
+// returning the oldest one's upload
+func (r *RetentionRepository) CountDocuments(ctx context.Context, userID uuid.UUID, from, until time.Time) (int, *time.Time, error) {
+	query := `
+		SELECT COUNT(*), MIN(uploaded_at)
+		FROM documents
+		WHERE user_id = $1 AND deleted_at IS NULL AND uploaded_at >= $2 AND uploaded_at < $3
+	`
+	return r.count(ctx, query, userID, from, until)
+}
+
+// ListDocumentsBefore retrieves the IDs of up to limit of a user's live
+// documents uploaded before cutoff, oldest first
+func (r *RetentionRepository) ListDocumentsBefore(ctx context.Context, userID uuid.UUID, cutoff time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id FROM documents
+		WHERE user_id = $1 AND deleted_at IS NULL AND uploaded_at < $2
+		ORDER BY uploaded_at
+		LIMIT $3
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan document id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	return ids, nil
+}
+
+// CountDataExports counts a user's data export archives expiring in
+// [from, until), returning the first expiry
+func (r *RetentionRepository) CountDataExports(ctx context.Context, userID uuid.UUID, from, until time.Time) (int, *time.Time, error) {
+	query := `
+		SELECT COUNT(*), MIN(expires_at)
+		FROM data_exports
+		WHERE user_id = $1 AND expires_at >= $2 AND expires_at < $3
+	`
+	return r.count(ctx, query, userID, from, until)
+}
+
+func (r *RetentionRepository) count(ctx context.Context, query string, args ...any) (int, *time.Time, error) {
+	var count int
+	var first *time.Time
+	if err := conn(ctx, r.db).QueryRow(ctx, query, args...).Scan(&count, &first); err != nil {
+		return 0, nil, fmt.Errorf("failed to count retained data: %w", err)
+	}
+	return count, first, nil
+}
+
+func (r *RetentionRepository) list(ctx context.Context, query string, args ...any) ([]*models.RetentionPolicy, error) {
+	rows, err := conn(ctx, r.db).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []*models.RetentionPolicy{}
+	for rows.Next() {
+		var policy models.RetentionPolicy
+		if err := rows.Scan(
+			&policy.UserID,
+			&policy.Target,
+			&policy.Days,
+			&policy.WarnedUntil,
+			&policy.WarnedAt,
+			&policy.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan retention policy: %w", err)
+		}
+		policies = append(policies, &policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list retention policies: %w", err)
+	}
+
+	return policies, nil
+}
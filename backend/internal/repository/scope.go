@@ -0,0 +1,22 @@
+package repository
+
+import "context"
+
+type labelScopeKey struct{}
+
+// WithLabelScope returns a context under which document and job lookups only
+// find rows carrying at least one of labels, as for API keys restricted to
+// labels. An empty list leaves ctx unscoped.
+func WithLabelScope(ctx context.Context, labels []string) context.Context {
+	if len(labels) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, labelScopeKey{}, labels)
+}
+
+// LabelScope returns the labels lookups under ctx are restricted to, or nil
+// when they are not restricted
+func LabelScope(ctx context.Context) []string {
+	labels, _ := ctx.Value(labelScopeKey{}).([]string)
+	return labels
+}
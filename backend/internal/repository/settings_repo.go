@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// UserSettingsRepository handles per-user preferences
+type UserSettingsRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewUserSettingsRepository creates a new user settings repository
+func NewUserSettingsRepository(db *pgxpool.Pool) *UserSettingsRepository {
+	return &UserSettingsRepository{db: db}
+}
+
+// GetByUserID retrieves a user's settings, falling back to the defaults for
+// users who never saved any
+func (r *UserSettingsRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	query := `
+		SELECT COALESCE(default_ocr_mode, 'document'), COALESCE(default_resolution, 'base'),
+		       COALESCE(email_notifications, TRUE), COALESCE(language, 'en'),
+		       COALESCE(theme, 'light'), duplicate_policy, timezone,
+		       COALESCE(job_trash_retention_days, 0), COALESCE(archive_jobs_after_days, 0),
+		       email_on_failure, email_digest, digest_hour, updated_at
+		FROM user_settings
+		WHERE user_id = $1
+	`
+
+	settings := models.DefaultUserSettings(userID)
+	err := conn(ctx, r.db).QueryRow(ctx, query, userID).Scan(
+		&settings.DefaultOCRMode,
+		&settings.DefaultResolution,
+		&settings.EmailNotifications,
+		&settings.Language,
+		&settings.Theme,
+		&settings.DuplicatePolicy,
+		&settings.Timezone,
+		&settings.JobTrashRetentionDays,
+		&settings.ArchiveJobsAfterDays,
+		&settings.EmailOnFailure,
+		&settings.EmailDigest,
+		&settings.DigestHour,
+		&settings.UpdatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return models.DefaultUserSettings(userID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// Upsert creates or replaces a user's settings
+func (r *UserSettingsRepository) Upsert(ctx context.Context, settings *models.UserSettings) error {
+	query := `
+		INSERT INTO user_settings (
+			user_id, default_ocr_mode, default_resolution, email_notifications,
+			language, theme, duplicate_policy, timezone,
+			job_trash_retention_days, archive_jobs_after_days,
+			email_on_failure, email_digest, digest_hour
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0), NULLIF($10, 0), $11, $12, $13)
+		ON CONFLICT (user_id) DO UPDATE SET
+			default_ocr_mode = EXCLUDED.default_ocr_mode,
+			default_resolution = EXCLUDED.default_resolution,
+			email_notifications = EXCLUDED.email_notifications,
+			language = EXCLUDED.language,
+			theme = EXCLUDED.theme,
+			duplicate_policy = EXCLUDED.duplicate_policy,
+			timezone = EXCLUDED.timezone,
+			job_trash_retention_days = EXCLUDED.job_trash_retention_days,
+			archive_jobs_after_days = EXCLUDED.archive_jobs_after_days,
+			email_on_failure = EXCLUDED.email_on_failure,
+			email_digest = EXCLUDED.email_digest,
+			digest_hour = EXCLUDED.digest_hour
+		RETURNING updated_at
+	`
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		settings.UserID,
+		settings.DefaultOCRMode,
+		settings.DefaultResolution,
+		settings.EmailNotifications,
+		settings.Language,
+		settings.Theme,
+		settings.DuplicatePolicy,
+		settings.Timezone,
+		settings.JobTrashRetentionDays,
+		settings.ArchiveJobsAfterDays,
+		settings.EmailOnFailure,
+		settings.EmailDigest,
+		settings.DigestHour,
+	).Scan(&settings.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save user settings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrShareLinkNotFound is returned when a share link does not exist
+var ErrShareLinkNotFound = apperrors.NotFound("SHARE_003", "share link not found")
+
+// shareLinkColumns are the columns scanned by scanShareLink
+const shareLinkColumns = `id, user_id, document_id, expires_at, created_at, revoked_at, access_count, last_accessed_at`
+
+// ShareLinkRepository handles document share links
+type ShareLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewShareLinkRepository creates a new share link repository
+func NewShareLinkRepository(db *pgxpool.Pool) *ShareLinkRepository {
+	return &ShareLinkRepository{db: db}
+}
+
+// Create stores a new share link
+func (r *ShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	query := `
+		INSERT INTO share_links (id, user_id, document_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	link.ID = uuid.New()
+	link.CreatedAt = time.Now().UTC()
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, link.ID, link.UserID, link.DocumentID, link.ExpiresAt, link.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a share link by ID
+func (r *ShareLinkRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE id = $1`
+
+	link, err := scanShareLink(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrShareLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	return link, nil
+}
+
+// ListByDocument retrieves the share links of a document, newest first
+func (r *ShareLinkRepository) ListByDocument(ctx context.Context, documentID uuid.UUID) ([]*models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE document_id = $1 ORDER BY created_at DESC`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []*models.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list share links: %w", err)
+	}
+
+	return links, nil
+}
+
+// Revoke ends a share link of a document early
+func (r *ShareLinkRepository) Revoke(ctx context.Context, id, documentID uuid.UUID) error {
+	query := `UPDATE share_links SET revoked_at = $1 WHERE id = $2 AND document_id = $3 AND revoked_at IS NULL`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, time.Now().UTC(), id, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrShareLinkNotFound
+	}
+
+	return nil
+}
+
+// RecordAccess counts a use of a share link
+func (r *ShareLinkRepository) RecordAccess(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE share_links SET access_count = access_count + 1, last_accessed_at = $1 WHERE id = $2`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to record share link access: %w", err)
+	}
+
+	return nil
+}
+
+func scanShareLink(row pgx.Row) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := row.Scan(
+		&link.ID,
+		&link.UserID,
+		&link.DocumentID,
+		&link.ExpiresAt,
+		&link.CreatedAt,
+		&link.RevokedAt,
+		&link.AccessCount,
+		&link.LastAccessedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrSSONotConfigured is returned when an organization has no SSO
+// configuration, or no enabled one is found for an email domain
+var ErrSSONotConfigured = apperrors.NotFound("SSO_001", "single sign-on is not configured for this organization")
+
+// ErrSSODomainClaimed is returned when an email domain is verified by
+// another organization's SSO
+var ErrSSODomainClaimed = apperrors.Conflict("SSO_006", "an email domain is already used by another organization's SSO")
+
+// ErrSSODomainNotFound is returned when an email domain is not one of the
+// organization's SSO configuration
+var ErrSSODomainNotFound = apperrors.NotFound("SSO_008", "the email domain is not configured for this organization's SSO")
+
+// orgSSOColumns are the columns scanned by scanOrgSSOConfig
+const orgSSOColumns = `organization_id, issuer, client_id, client_secret, client_secret_sealed, group_claim,
+	role_mapping, default_role, enabled, created_at, updated_at`
+
+// SSORepository handles organizations' SSO configurations and the identities
+// signed in through them
+type SSORepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSSORepository creates a new SSO repository
+func NewSSORepository(db *pgxpool.Pool) *SSORepository {
+	return &SSORepository{db: db}
+}
+
+// GetConfig retrieves an organization's SSO configuration
+func (r *SSORepository) GetConfig(ctx context.Context, orgID uuid.UUID) (*models.OrgSSOConfig, error) {
+	query := `SELECT ` + orgSSOColumns + ` FROM organization_sso WHERE organization_id = $1`
+
+	config, err := scanOrgSSOConfig(conn(ctx, r.db).QueryRow(ctx, query, orgID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrSSONotConfigured
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSO configuration: %w", err)
+	}
+
+	return config, r.loadDomains(ctx, config)
+}
+
+// GetConfigByDomain retrieves the enabled SSO configuration of the
+// organization that verified an email domain
+func (r *SSORepository) GetConfigByDomain(ctx context.Context, domain string) (*models.OrgSSOConfig, error) {
+	query := `SELECT ` + orgSSOColumns + `
+		FROM organization_sso
+		WHERE enabled AND organization_id = (
+			SELECT organization_id FROM organization_sso_domains
+			WHERE domain = $1 AND verified_at IS NOT NULL
+		)
+	`
+
+	config, err := scanOrgSSOConfig(conn(ctx, r.db).QueryRow(ctx, query, domain))
+	if err == pgx.ErrNoRows {
+		return nil, ErrSSONotConfigured
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSO configuration: %w", err)
+	}
+
+	return config, r.loadDomains(ctx, config)
+}
+
+// ListUnsealed returns the configurations whose client secret is stored in
+// plaintext
+func (r *SSORepository) ListUnsealed(ctx context.Context) ([]*models.OrgSSOConfig, error) {
+	query := `SELECT ` + orgSSOColumns + ` FROM organization_sso WHERE NOT client_secret_sealed`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SSO configurations: %w", err)
+	}
+	defer rows.Close()
+
+	var configs []*models.OrgSSOConfig
+	for rows.Next() {
+		config, err := scanOrgSSOConfig(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan SSO configuration: %w", err)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+// SealSecret replaces a plaintext client secret with its sealed form
+func (r *SSORepository) SealSecret(ctx context.Context, orgID uuid.UUID, sealed string) error {
+	query := `
+		UPDATE organization_sso SET client_secret = $2, client_secret_sealed = TRUE
+		WHERE organization_id = $1 AND NOT client_secret_sealed
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, orgID, sealed); err != nil {
+		return fmt.Errorf("failed to seal SSO client secret: %w", err)
+	}
+
+	return nil
+}
+
+// SaveConfig creates or replaces an organization's SSO configuration, with a
+// client secret sealed by the caller. Domains it keeps stay verified; new
+// ones are added unverified.
+func (r *SSORepository) SaveConfig(ctx context.Context, config *models.OrgSSOConfig) error {
+	query := `
+		INSERT INTO organization_sso (
+			organization_id, issuer, client_id, client_secret, client_secret_sealed, group_claim,
+			role_mapping, default_role, enabled, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, TRUE, $5, $6, $7, $8, $9, $9)
+		ON CONFLICT (organization_id) DO UPDATE SET
+			issuer = EXCLUDED.issuer,
+			client_id = EXCLUDED.client_id,
+			client_secret = EXCLUDED.client_secret,
+			client_secret_sealed = EXCLUDED.client_secret_sealed,
+			group_claim = EXCLUDED.group_claim,
+			role_mapping = EXCLUDED.role_mapping,
+			default_role = EXCLUDED.default_role,
+			enabled = EXCLUDED.enabled,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at
+	`
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		config.OrganizationID,
+		config.Issuer,
+		config.ClientID,
+		config.ClientSecret,
+		config.GroupClaim,
+		config.RoleMapping,
+		config.DefaultRole,
+		config.Enabled,
+		time.Now().UTC(),
+	).Scan(&config.CreatedAt, &config.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save SSO configuration: %w", err)
+	}
+
+	domains := make([]string, len(config.EmailDomains))
+	for i, domain := range config.EmailDomains {
+		domains[i] = domain.Domain
+	}
+	_, err = conn(ctx, r.db).Exec(ctx,
+		`DELETE FROM organization_sso_domains WHERE organization_id = $1 AND NOT (domain = ANY($2))`,
+		config.OrganizationID, domains)
+	if err != nil {
+		return fmt.Errorf("failed to save SSO domains: %w", err)
+	}
+	for _, domain := range config.EmailDomains {
+		_, err := conn(ctx, r.db).Exec(ctx, `
+			INSERT INTO organization_sso_domains (organization_id, domain, verification_record, verification_value)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (organization_id, domain) DO NOTHING
+		`, config.OrganizationID, domain.Domain, domain.VerificationRecord, domain.VerificationValue)
+		if err != nil {
+			return fmt.Errorf("failed to save SSO domains: %w", err)
+		}
+	}
+
+	return r.loadDomains(ctx, config)
+}
+
+// VerifyDomain marks an email domain of an organization's SSO verified
+func (r *SSORepository) VerifyDomain(ctx context.Context, orgID uuid.UUID, domain string, verifiedAt time.Time) error {
+	query := `
+		UPDATE organization_sso_domains SET verified_at = $3
+		WHERE organization_id = $1 AND domain = $2
+	`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, orgID, domain, verifiedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrSSODomainClaimed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify SSO domain: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSSODomainNotFound
+	}
+
+	return nil
+}
+
+// loadDomains sets the email domains of a configuration
+func (r *SSORepository) loadDomains(ctx context.Context, config *models.OrgSSOConfig) error {
+	query := `
+		SELECT domain, verification_record, verification_value, verified_at
+		FROM organization_sso_domains
+		WHERE organization_id = $1
+		ORDER BY domain
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, config.OrganizationID)
+	if err != nil {
+		return fmt.Errorf("failed to get SSO domains: %w", err)
+	}
+	defer rows.Close()
+
+	config.EmailDomains = []models.SSODomain{}
+	for rows.Next() {
+		var domain models.SSODomain
+		if err := rows.Scan(&domain.Domain, &domain.VerificationRecord, &domain.VerificationValue, &domain.VerifiedAt); err != nil {
+			return fmt.Errorf("failed to scan SSO domain: %w", err)
+		}
+		config.EmailDomains = append(config.EmailDomains, domain)
+	}
+
+	return rows.Err()
+}
+
+// DeleteConfig removes an organization's SSO configuration
+func (r *SSORepository) DeleteConfig(ctx context.Context, orgID uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM organization_sso WHERE organization_id = $1`, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete SSO configuration: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSSONotConfigured
+	}
+
+	return nil
+}
+
+// GetIdentityUser returns the user an IdP subject signed in as before, or
+// uuid.Nil if it never did
+func (r *SSORepository) GetIdentityUser(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	query := `SELECT user_id FROM sso_identities WHERE issuer = $1 AND subject = $2`
+
+	var userID uuid.UUID
+	err := conn(ctx, r.db).QueryRow(ctx, query, issuer, subject).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get SSO identity: %w", err)
+	}
+
+	return userID, nil
+}
+
+// SaveIdentity records that an IdP subject signed in as a user through an
+// organization
+func (r *SSORepository) SaveIdentity(ctx context.Context, issuer, subject string, userID, orgID uuid.UUID) error {
+	query := `
+		INSERT INTO sso_identities (issuer, subject, user_id, organization_id, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (issuer, subject) DO UPDATE SET
+			organization_id = EXCLUDED.organization_id,
+			last_login_at = EXCLUDED.last_login_at
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, issuer, subject, userID, orgID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save SSO identity: %w", err)
+	}
+
+	return nil
+}
+
+func scanOrgSSOConfig(row pgx.Row) (*models.OrgSSOConfig, error) {
+	var config models.OrgSSOConfig
+	err := row.Scan(
+		&config.OrganizationID,
+		&config.Issuer,
+		&config.ClientID,
+		&config.ClientSecret,
+		&config.ClientSecretSealed,
+		&config.GroupClaim,
+		&config.RoleMapping,
+		&config.DefaultRole,
+		&config.Enabled,
+		&config.CreatedAt,
+		&config.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// finishedJobsQuery selects a user's jobs that finished in [$2, $3) with the
+// pages and confidence of their latest result
+const finishedJobsQuery = `
+	SELECT j.status, j.resolution_mode, j.started_at, j.completed_at,
+		   r.num_pages, r.confidence_score
+	FROM ocr_jobs j
+	LEFT JOIN LATERAL (
+		SELECT num_pages, confidence_score FROM ocr_results
+		WHERE job_id = j.id AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	) r ON TRUE
+	WHERE j.user_id = $1 AND j.status IN ('completed', 'failed', 'cancelled')
+	  AND j.deleted_at IS NULL AND j.completed_at >= $2 AND j.completed_at < $3
+`
+
+// StatsRepository aggregates the statistics of users' jobs and of the
+// instance
+type StatsRepository struct {
+	db *pgxpool.Pool
+	// replica serves the aggregations; nil uses db
+	replica *pgxpool.Pool
+}
+
+// NewStatsRepository creates a new stats repository. The replica, if not
+// nil, serves the aggregations.
+func NewStatsRepository(db, replica *pgxpool.Pool) *StatsRepository {
+	return &StatsRepository{db: db, replica: replica}
+}
+
+// JobTotals sums up the user's jobs that finished in [since, until), for all
+// of them and by resolution mode, most used first
+func (r *StatsRepository) JobTotals(ctx context.Context, userID uuid.UUID, since, until time.Time) (*models.JobStatsTotals, []*models.ResolutionJobStats, error) {
+	query := `
+		WITH finished AS (` + finishedJobsQuery + `)
+		SELECT GROUPING(resolution_mode) = 1, resolution_mode,
+			   COUNT(*),
+			   COUNT(*) FILTER (WHERE status = 'completed'),
+			   COUNT(*) FILTER (WHERE status = 'failed'),
+			   COUNT(*) FILTER (WHERE status = 'cancelled'),
+			   COALESCE(SUM(num_pages) FILTER (WHERE status = 'completed'), 0),
+			   AVG(confidence_score) FILTER (WHERE status = 'completed'),
+			   AVG(EXTRACT(EPOCH FROM completed_at - started_at) * 1000)
+				   FILTER (WHERE status = 'completed' AND started_at IS NOT NULL)
+		FROM finished
+		GROUP BY GROUPING SETS ((resolution_mode), ())
+		ORDER BY COUNT(*) DESC, resolution_mode
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID, since, until)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sum up jobs: %w", err)
+	}
+	defer rows.Close()
+
+	totals := &models.JobStatsTotals{}
+	byResolution := []*models.ResolutionJobStats{}
+	for rows.Next() {
+		var all bool
+		var resolution *models.ResolutionMode
+		var stats models.JobStatsTotals
+		err := rows.Scan(
+			&all,
+			&resolution,
+			&stats.Jobs,
+			&stats.Completed,
+			&stats.Failed,
+			&stats.Cancelled,
+			&stats.Pages,
+			&stats.AverageConfidence,
+			&stats.AverageProcessingMs,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan job totals: %w", err)
+		}
+
+		if all {
+			*totals = stats
+			continue
+		}
+		group := &models.ResolutionJobStats{JobStatsTotals: stats}
+		if resolution != nil {
+			group.ResolutionMode = *resolution
+		}
+		byResolution = append(byResolution, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to sum up jobs: %w", err)
+	}
+
+	return totals, byResolution, nil
+}
+
+// JobsPerDay counts the user's jobs that finished in [since, until) by date
+// in a time zone, with a row for every date from first to last (YYYY-MM-DD)
+func (r *StatsRepository) JobsPerDay(ctx context.Context, userID uuid.UUID, since, until time.Time, timezone, first, last string) ([]*models.DailyJobStats, error) {
+	query := `
+		WITH finished AS (` + finishedJobsQuery + `)
+		SELECT to_char(d.day, 'YYYY-MM-DD'),
+			   COUNT(f.status),
+			   COUNT(*) FILTER (WHERE f.status = 'completed'),
+			   COUNT(*) FILTER (WHERE f.status = 'failed'),
+			   COALESCE(SUM(f.num_pages) FILTER (WHERE f.status = 'completed'), 0)
+		FROM generate_series($5::date, $6::date, interval '1 day') AS d(day)
+		LEFT JOIN finished f ON (f.completed_at AT TIME ZONE $4)::date = d.day::date
+		GROUP BY d.day
+		ORDER BY d.day
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, userID, since, until, timezone, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs per day: %w", err)
+	}
+	defer rows.Close()
+
+	days := []*models.DailyJobStats{}
+	for rows.Next() {
+		var day models.DailyJobStats
+		if err := rows.Scan(&day.Date, &day.Jobs, &day.Completed, &day.Failed, &day.Pages); err != nil {
+			return nil, fmt.Errorf("failed to scan jobs per day: %w", err)
+		}
+		days = append(days, &day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count jobs per day: %w", err)
+	}
+
+	return days, nil
+}
+
+// Throughput counts the jobs of all users that finished in [since, until),
+// with the pages of the completed ones
+func (r *StatsRepository) Throughput(ctx context.Context, since, until time.Time) (*models.JobThroughput, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE j.status = 'completed'),
+			   COUNT(*) FILTER (WHERE j.status = 'failed'),
+			   COUNT(*) FILTER (WHERE j.status = 'cancelled'),
+			   COALESCE(SUM(r.num_pages) FILTER (WHERE j.status = 'completed'), 0)
+		FROM ocr_jobs j
+		LEFT JOIN LATERAL (
+			SELECT num_pages FROM ocr_results
+			WHERE job_id = j.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) r ON TRUE
+		WHERE j.status IN ('completed', 'failed', 'cancelled')
+		  AND j.completed_at >= $1 AND j.completed_at < $2
+	`
+
+	throughput := &models.JobThroughput{}
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, query, since, until).Scan(
+		&throughput.Completed,
+		&throughput.Failed,
+		&throughput.Cancelled,
+		&throughput.Pages,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count finished jobs: %w", err)
+	}
+	return throughput, nil
+}
+
+// QueueWait returns the percentiles of how long the jobs started in
+// [since, until) waited to start
+func (r *StatsRepository) QueueWait(ctx context.Context, since, until time.Time) (*models.LatencyStats, error) {
+	return r.latency(ctx, `
+		SELECT EXTRACT(EPOCH FROM started_at - created_at) * 1000 AS ms
+		FROM ocr_jobs
+		WHERE started_at >= $1 AND started_at < $2
+	`, since, until)
+}
+
+// OCRLatency returns the percentiles of how long the OCR service took on the
+// pages processed in [since, until)
+func (r *StatsRepository) OCRLatency(ctx context.Context, since, until time.Time) (*models.LatencyStats, error) {
+	return r.latency(ctx, `
+		SELECT processing_time_ms AS ms
+		FROM result_pages
+		WHERE created_at >= $1 AND created_at < $2 AND processing_time_ms IS NOT NULL
+	`, since, until)
+}
+
+// latency returns the percentiles of the ms column of a query taking since
+// and until
+func (r *StatsRepository) latency(ctx context.Context, durations string, since, until time.Time) (*models.LatencyStats, error) {
+	query := `
+		SELECT COUNT(*),
+			   percentile_cont(0.5) WITHIN GROUP (ORDER BY ms),
+			   percentile_cont(0.9) WITHIN GROUP (ORDER BY ms),
+			   percentile_cont(0.99) WITHIN GROUP (ORDER BY ms)
+		FROM (` + durations + `) d
+	`
+
+	stats := &models.LatencyStats{}
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, query, since, until).Scan(
+		&stats.Count,
+		&stats.P50,
+		&stats.P90,
+		&stats.P99,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latency percentiles: %w", err)
+	}
+	return stats, nil
+}
+
+// ActiveUsers counts the users who uploaded documents or submitted jobs in
+// [since, until)
+func (r *StatsRepository) ActiveUsers(ctx context.Context, since, until time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM (
+			SELECT user_id FROM documents WHERE uploaded_at >= $1 AND uploaded_at < $2
+			UNION
+			SELECT user_id FROM ocr_jobs WHERE created_at >= $1 AND created_at < $2
+		) active
+	`
+
+	var count int
+	if err := readConn(ctx, r.db, r.replica).QueryRow(ctx, query, since, until).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count active users: %w", err)
+	}
+	return count, nil
+}
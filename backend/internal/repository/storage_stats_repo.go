@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// StorageStatsRepository stores the storage used by users and the instance
+type StorageStatsRepository struct {
+	db *pgxpool.Pool
+	// replica serves the dashboard reads; nil uses db
+	replica *pgxpool.Pool
+}
+
+// NewStorageStatsRepository creates a new storage stats repository. The
+// replica, if not nil, serves the dashboard reads.
+func NewStorageStatsRepository(db, replica *pgxpool.Pool) *StorageStatsRepository {
+	return &StorageStatsRepository{db: db, replica: replica}
+}
+
+// RefreshUsers recomputes the storage used by every user with documents,
+// including those in the trash, and removes the stats of users left without
+// any
+func (r *StorageStatsRepository) RefreshUsers(ctx context.Context, computedAt time.Time) error {
+	query := `
+		WITH computed AS (
+			INSERT INTO storage_stats (user_id, documents, trashed_documents, files, bytes, oldest_uploaded_at, computed_at)
+			SELECT user_id,
+				   COUNT(*),
+				   COUNT(*) FILTER (WHERE deleted_at IS NOT NULL),
+				   COUNT(DISTINCT file_path),
+				   COALESCE(SUM(file_size), 0),
+				   MIN(uploaded_at),
+				   $1
+			FROM documents
+			GROUP BY user_id
+			ON CONFLICT (user_id) DO UPDATE SET
+				documents = EXCLUDED.documents,
+				trashed_documents = EXCLUDED.trashed_documents,
+				files = EXCLUDED.files,
+				bytes = EXCLUDED.bytes,
+				oldest_uploaded_at = EXCLUDED.oldest_uploaded_at,
+				computed_at = EXCLUDED.computed_at
+			RETURNING user_id
+		)
+		DELETE FROM storage_stats WHERE user_id NOT IN (SELECT user_id FROM computed)
+	`
+
+	if _, err := conn(ctx, r.db).Exec(ctx, query, computedAt); err != nil {
+		return fmt.Errorf("failed to refresh storage stats: %w", err)
+	}
+	return nil
+}
+
+// DocumentTotals sums the documents and their sizes over the users' stats
+func (r *StorageStatsRepository) DocumentTotals(ctx context.Context) (int, int64, error) {
+	var documents int
+	var bytes int64
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT COALESCE(SUM(documents), 0), COALESCE(SUM(bytes), 0) FROM storage_stats`).Scan(&documents, &bytes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to sum storage stats: %w", err)
+	}
+	return documents, bytes, nil
+}
+
+// SaveSummary stores the instance totals, replacing the previous ones
+func (r *StorageStatsRepository) SaveSummary(ctx context.Context, stats *models.StorageStats) error {
+	query := `
+		INSERT INTO storage_stats_summary (id, totals, oldest_files, duration_ms, computed_at)
+		VALUES (TRUE, $1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET
+			totals = EXCLUDED.totals,
+			oldest_files = EXCLUDED.oldest_files,
+			duration_ms = EXCLUDED.duration_ms,
+			computed_at = EXCLUDED.computed_at
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query, stats.Totals, stats.OldestFiles, stats.DurationMs, stats.ComputedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save storage stats: %w", err)
+	}
+	return nil
+}
+
+// GetSummary retrieves the instance totals, or nil before they are first
+// computed
+func (r *StorageStatsRepository) GetSummary(ctx context.Context) (*models.StorageStats, error) {
+	query := `SELECT totals, oldest_files, duration_ms, computed_at FROM storage_stats_summary`
+
+	var stats models.StorageStats
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, query).Scan(
+		&stats.Totals,
+		&stats.OldestFiles,
+		&stats.DurationMs,
+		&stats.ComputedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// ListUsers retrieves the users' stats, those using the most storage first
+func (r *StorageStatsRepository) ListUsers(ctx context.Context, limit, offset int) ([]*models.UserStorageStats, int, error) {
+	db := readConn(ctx, r.db, r.replica)
+
+	var total int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM storage_stats`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count storage stats: %w", err)
+	}
+
+	query := `
+		SELECT s.user_id, COALESCE(u.email, ''), s.documents, s.trashed_documents, s.files, s.bytes, s.oldest_uploaded_at
+		FROM storage_stats s
+		LEFT JOIN users u ON u.id = s.user_id
+		ORDER BY s.bytes DESC, s.user_id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list storage stats: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.UserStorageStats{}
+	for rows.Next() {
+		var user models.UserStorageStats
+		err := rows.Scan(
+			&user.UserID,
+			&user.Email,
+			&user.Documents,
+			&user.TrashedDocuments,
+			&user.Files,
+			&user.Bytes,
+			&user.OldestUploadedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan storage stats: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list storage stats: %w", err)
+	}
+
+	return users, total, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrTemplateNotFound is returned when an extraction template does not exist
+var ErrTemplateNotFound = apperrors.NotFound("TPL_001", "extraction template not found")
+
+// templateColumns are the columns scanned by scanTemplate
+const templateColumns = `id, user_id, name, COALESCE(document_type, ''), fields, created_at, updated_at`
+
+// TemplateRepository handles extraction template database operations
+type TemplateRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTemplateRepository creates a new template repository
+func NewTemplateRepository(db *pgxpool.Pool) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// Create stores a new extraction template
+func (r *TemplateRepository) Create(ctx context.Context, template *models.ExtractionTemplate) error {
+	query := `
+		INSERT INTO extraction_templates (id, user_id, name, document_type, fields, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $6)
+	`
+
+	template.ID = uuid.New()
+	template.CreatedAt = time.Now().UTC()
+	template.UpdatedAt = template.CreatedAt
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		template.ID,
+		template.UserID,
+		template.Name,
+		template.DocumentType,
+		template.Fields,
+		template.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create extraction template: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an extraction template by ID
+func (r *TemplateRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ExtractionTemplate, error) {
+	query := `SELECT ` + templateColumns + ` FROM extraction_templates WHERE id = $1`
+
+	template, err := scanTemplate(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extraction template: %w", err)
+	}
+
+	return template, nil
+}
+
+// ListByUser retrieves a user's extraction templates, newest first
+func (r *TemplateRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.ExtractionTemplate, error) {
+	query := `SELECT ` + templateColumns + ` FROM extraction_templates WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list extraction templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []*models.ExtractionTemplate{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan extraction template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list extraction templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// Update replaces the name, document type and fields of an extraction template
+func (r *TemplateRepository) Update(ctx context.Context, template *models.ExtractionTemplate) error {
+	query := `
+		UPDATE extraction_templates
+		SET name = $1, document_type = NULLIF($2, ''), fields = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	template.UpdatedAt = time.Now().UTC()
+
+	result, err := conn(ctx, r.db).Exec(ctx, query,
+		template.Name,
+		template.DocumentType,
+		template.Fields,
+		template.UpdatedAt,
+		template.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update extraction template: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
+
+// Delete deletes an extraction template
+func (r *TemplateRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := conn(ctx, r.db).Exec(ctx, `DELETE FROM extraction_templates WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete extraction template: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+
+	return nil
+}
+
+func scanTemplate(row pgx.Row) (*models.ExtractionTemplate, error) {
+	var template models.ExtractionTemplate
+	err := row.Scan(
+		&template.ID,
+		&template.UserID,
+		&template.Name,
+		&template.DocumentType,
+		&template.Fields,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
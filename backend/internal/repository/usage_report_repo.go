@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrUsageReportNotFound is returned when a usage report does not exist
+var ErrUsageReportNotFound = apperrors.NotFound("REPORT_001", "usage report not found")
+
+// usageReportColumns are the columns scanned by scanUsageReport
+const usageReportColumns = `id, period_start, period_end, totals, peak_queue_depth, peak_queue_at, file_path, generated_at`
+
+// UsageReportRepository stores usage reports and computes the usage they sum
+type UsageReportRepository struct {
+	db *pgxpool.Pool
+	// replica serves the usage aggregations; nil uses db
+	replica *pgxpool.Pool
+}
+
+// NewUsageReportRepository creates a new usage report repository. The
+// replica, if not nil, serves the usage aggregations.
+func NewUsageReportRepository(db, replica *pgxpool.Pool) *UsageReportRepository {
+	return &UsageReportRepository{db: db, replica: replica}
+}
+
+// Save stores a report, replacing any earlier report of the same month
+func (r *UsageReportRepository) Save(ctx context.Context, report *models.UsageReport) error {
+	query := `
+		INSERT INTO usage_reports (id, period_start, period_end, totals, peak_queue_depth, peak_queue_at, file_path, generated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (period_start) DO UPDATE SET
+			period_end = EXCLUDED.period_end,
+			totals = EXCLUDED.totals,
+			peak_queue_depth = EXCLUDED.peak_queue_depth,
+			peak_queue_at = EXCLUDED.peak_queue_at,
+			file_path = EXCLUDED.file_path,
+			generated_at = EXCLUDED.generated_at
+		RETURNING id
+	`
+
+	err := conn(ctx, r.db).QueryRow(ctx, query,
+		uuid.New(),
+		report.PeriodStart,
+		report.PeriodEnd,
+		report.Totals,
+		report.PeakQueueDepth,
+		report.PeakQueueAt,
+		report.FilePath,
+		report.GeneratedAt,
+	).Scan(&report.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save usage report: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a report
+func (r *UsageReportRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.UsageReport, error) {
+	query := `SELECT ` + usageReportColumns + ` FROM usage_reports WHERE id = $1`
+
+	report, err := scanUsageReport(conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, ErrUsageReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage report: %w", err)
+	}
+
+	return report, nil
+}
+
+// Exists reports whether the report of the month starting at periodStart
+// has been generated
+func (r *UsageReportRepository) Exists(ctx context.Context, periodStart time.Time) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM usage_reports WHERE period_start = $1)`, periodStart).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check usage report: %w", err)
+	}
+	return exists, nil
+}
+
+// List retrieves reports, most recent month first
+func (r *UsageReportRepository) List(ctx context.Context, limit, offset int) ([]*models.UsageReport, int, error) {
+	var total int
+	if err := conn(ctx, r.db).QueryRow(ctx, `SELECT COUNT(*) FROM usage_reports`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count usage reports: %w", err)
+	}
+
+	query := `SELECT ` + usageReportColumns + ` FROM usage_reports ORDER BY period_start DESC LIMIT $1 OFFSET $2`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list usage reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*models.UsageReport{}
+	for rows.Next() {
+		report, err := scanUsageReport(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan usage report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list usage reports: %w", err)
+	}
+
+	return reports, total, nil
+}
+
+// JobUsage sums the jobs finished in [start, end), for the instance and by
+// organization of their document. Documents outside any organization are
+// summed under uuid.Nil.
+func (r *UsageReportRepository) JobUsage(ctx context.Context, start, end time.Time) (*models.UsageTotals, map[uuid.UUID]*models.OrgUsage, error) {
+	query := `
+		SELECT GROUPING(d.org_id) = 1, d.org_id, MAX(o.name),
+			   COUNT(*),
+			   COUNT(*) FILTER (WHERE j.status = 'failed'),
+			   COALESCE(SUM(r.num_pages) FILTER (WHERE j.status = 'completed'), 0),
+			   percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM j.started_at - j.created_at) * 1000)
+				   FILTER (WHERE j.started_at IS NOT NULL),
+			   percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM j.completed_at - j.started_at) * 1000)
+				   FILTER (WHERE j.started_at IS NOT NULL)
+		FROM ocr_jobs j
+		LEFT JOIN documents d ON d.id = j.document_id
+		LEFT JOIN organizations o ON o.id = d.org_id
+		LEFT JOIN ocr_results r ON r.job_id = j.id
+		WHERE j.status IN ('completed', 'failed', 'cancelled')
+		  AND j.completed_at >= $1 AND j.completed_at < $2
+		GROUP BY GROUPING SETS ((d.org_id), ())
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, start, end)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sum job usage: %w", err)
+	}
+	defer rows.Close()
+
+	totals := &models.UsageTotals{}
+	byOrg := map[uuid.UUID]*models.OrgUsage{}
+	for rows.Next() {
+		var instance bool
+		var orgName *string
+		var usage models.OrgUsage
+		err := rows.Scan(
+			&instance,
+			&usage.OrgID,
+			&orgName,
+			&usage.Jobs,
+			&usage.FailedJobs,
+			&usage.Pages,
+			&usage.P95QueueWaitMs,
+			&usage.P95ProcessingMs,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan job usage: %w", err)
+		}
+
+		if instance {
+			*totals = usage.UsageTotals
+			continue
+		}
+		if orgName != nil {
+			usage.OrgName = *orgName
+		}
+		byOrg[orgKey(usage.OrgID)] = &usage
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to sum job usage: %w", err)
+	}
+
+	return totals, byOrg, nil
+}
+
+// StorageUsage sums the documents uploaded in [start, end) and all documents
+// stored at end, for the instance and by organization. Documents outside any
+// organization are summed under uuid.Nil.
+func (r *UsageReportRepository) StorageUsage(ctx context.Context, start, end time.Time) (*models.UsageTotals, map[uuid.UUID]*models.OrgUsage, error) {
+	query := `
+		SELECT GROUPING(d.org_id) = 1, d.org_id, MAX(o.name),
+			   COUNT(*) FILTER (WHERE d.uploaded_at >= $1),
+			   COALESCE(SUM(d.file_size) FILTER (WHERE d.uploaded_at >= $1), 0),
+			   COALESCE(SUM(d.file_size), 0)
+		FROM documents d
+		LEFT JOIN organizations o ON o.id = d.org_id
+		WHERE d.uploaded_at < $2
+		GROUP BY GROUPING SETS ((d.org_id), ())
+	`
+
+	rows, err := readConn(ctx, r.db, r.replica).Query(ctx, query, start, end)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sum storage usage: %w", err)
+	}
+	defer rows.Close()
+
+	totals := &models.UsageTotals{}
+	byOrg := map[uuid.UUID]*models.OrgUsage{}
+	for rows.Next() {
+		var instance bool
+		var orgName *string
+		var usage models.OrgUsage
+		err := rows.Scan(
+			&instance,
+			&usage.OrgID,
+			&orgName,
+			&usage.DocumentsAdded,
+			&usage.StorageAddedBytes,
+			&usage.StorageTotalBytes,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan storage usage: %w", err)
+		}
+
+		if instance {
+			*totals = usage.UsageTotals
+			continue
+		}
+		if orgName != nil {
+			usage.OrgName = *orgName
+		}
+		byOrg[orgKey(usage.OrgID)] = &usage
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to sum storage usage: %w", err)
+	}
+
+	return totals, byOrg, nil
+}
+
+// PeakQueueDepth returns the most jobs waiting to start at once in
+// [start, end) and when the peak was first reached. A job waits from its
+// creation until it starts, or until it finishes if it never started.
+func (r *UsageReportRepository) PeakQueueDepth(ctx context.Context, start, end time.Time) (int, *time.Time, error) {
+	query := `
+		WITH waiting AS (
+			SELECT GREATEST(created_at, $1) AS queued_at, COALESCE(started_at, completed_at) AS left_at
+			FROM ocr_jobs
+			WHERE created_at < $2
+			  AND (COALESCE(started_at, completed_at) IS NULL OR COALESCE(started_at, completed_at) > $1)
+		), changes AS (
+			SELECT queued_at AS at, 1 AS delta FROM waiting
+			UNION ALL
+			SELECT left_at, -1 FROM waiting WHERE left_at < $2
+		), depths AS (
+			SELECT at, SUM(delta) OVER (ORDER BY at, delta ROWS UNBOUNDED PRECEDING) AS depth
+			FROM changes
+		)
+		SELECT depth, at FROM depths ORDER BY depth DESC, at LIMIT 1
+	`
+
+	var depth int
+	var at time.Time
+	err := readConn(ctx, r.db, r.replica).QueryRow(ctx, query, start, end).Scan(&depth, &at)
+	if err == pgx.ErrNoRows {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get peak queue depth: %w", err)
+	}
+
+	return depth, &at, nil
+}
+
+func scanUsageReport(row pgx.Row) (*models.UsageReport, error) {
+	var report models.UsageReport
+	err := row.Scan(
+		&report.ID,
+		&report.PeriodStart,
+		&report.PeriodEnd,
+		&report.Totals,
+		&report.PeakQueueDepth,
+		&report.PeakQueueAt,
+		&report.FilePath,
+		&report.GeneratedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// orgKey returns the key of an organization's usage, uuid.Nil for documents
+// outside any organization
+func orgKey(orgID *uuid.UUID) uuid.UUID {
+	if orgID == nil {
+		return uuid.Nil
+	}
+	return *orgID
+}
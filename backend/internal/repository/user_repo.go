@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"visekai/backend/internal/apperrors"
 	"visekai/backend/internal/models"
 
 	"github.com/google/uuid"
@@ -12,6 +13,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrUserNotFound is returned when an user does not exist
+var ErrUserNotFound = apperrors.NotFound("RES_001", "user not found")
+
 // UserRepository handles user database operations
 type UserRepository struct {
 	db *pgxpool.Pool
@@ -25,19 +29,23 @@ func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 // Create creates a new user in the database
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, name, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (id, email, password_hash, name, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
 	user.ID = uuid.New()
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
+	if user.Role == "" {
+		user.Role = models.UserRoleUser
+	}
+	user.CreatedAt = time.Now().UTC()
+	user.UpdatedAt = time.Now().UTC()
 
-	_, err := r.db.Exec(ctx, query,
+	_, err := conn(ctx, r.db).Exec(ctx, query,
 		user.ID,
 		user.Email,
 		user.PasswordHash,
 		user.Name,
+		user.Role,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
@@ -52,23 +60,25 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, created_at, updated_at
+		SELECT id, email, password_hash, name, role, created_at, updated_at, deletion_scheduled_at
 		FROM users
 		WHERE id = $1
 	`
 
 	var user models.User
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := conn(ctx, r.db).QueryRow(ctx, query, id).Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
 		&user.Name,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -80,23 +90,25 @@ func (r *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Use
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, created_at, updated_at
+		SELECT id, email, password_hash, name, role, created_at, updated_at, deletion_scheduled_at
 		FROM users
 		WHERE LOWER(email) = LOWER($1)
 	`
 
 	var user models.User
-	err := r.db.QueryRow(ctx, query, email).Scan(
+	err := conn(ctx, r.db).QueryRow(ctx, query, email).Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
 		&user.Name,
+		&user.Role,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletionScheduledAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -113,9 +125,9 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 		WHERE id = $4
 	`
 
-	user.UpdatedAt = time.Now()
+	user.UpdatedAt = time.Now().UTC()
 
-	result, err := r.db.Exec(ctx, query,
+	result, err := conn(ctx, r.db).Exec(ctx, query,
 		user.Email,
 		user.Name,
 		user.UpdatedAt,
@@ -127,7 +139,7 @@ func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
@@ -141,13 +153,13 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, p
 		WHERE id = $3
 	`
 
-	result, err := r.db.Exec(ctx, query, passwordHash, time.Now(), userID)
+	result, err := conn(ctx, r.db).Exec(ctx, query, passwordHash, time.Now().UTC(), userID)
 	if err != nil {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
@@ -157,27 +169,100 @@ func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, p
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM users WHERE id = $1`
 
-	result, err := r.db.Exec(ctx, query, id)
+	result, err := conn(ctx, r.db).Exec(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
+// ScheduleDeletion sets when a user's account will be deleted; nil cancels
+// the deletion
+func (r *UserRepository) ScheduleDeletion(ctx context.Context, id uuid.UUID, at *time.Time) error {
+	query := `UPDATE users SET deletion_scheduled_at = $1, updated_at = $2 WHERE id = $3`
+
+	result, err := conn(ctx, r.db).Exec(ctx, query, at, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to schedule user deletion: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ListDeletionDue returns the IDs of users whose deletion is scheduled
+// before the cutoff, soonest first
+func (r *UserRepository) ListDeletionDue(ctx context.Context, cutoff time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id FROM users
+		WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= $1
+		ORDER BY deletion_scheduled_at ASC
+		LIMIT $2
+	`
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users due for deletion: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list users due for deletion: %w", err)
+	}
+
+	return ids, nil
+}
+
+// CountAdmins returns the number of admins whose account is not awaiting
+// deletion
+func (r *UserRepository) CountAdmins(ctx context.Context) (int, error) {
+	query := `SELECT COUNT(*) FROM users WHERE role = 'admin' AND deletion_scheduled_at IS NULL`
+
+	var count int
+	if err := conn(ctx, r.db).QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count admins: %w", err)
+	}
+
+	return count, nil
+}
+
 // Exists checks if a user with the given email exists
 func (r *UserRepository) Exists(ctx context.Context, email string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(email) = LOWER($1))`
 
 	var exists bool
-	err := r.db.QueryRow(ctx, query, email).Scan(&exists)
+	err := conn(ctx, r.db).QueryRow(ctx, query, email).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check user existence: %w", err)
 	}
 
 	return exists, nil
 }
+
+// Count returns the total number of users
+func (r *UserRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	err := conn(ctx, r.db).QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WarehouseRepository handles data warehouse export queries
+type WarehouseRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewWarehouseRepository creates a new warehouse repository
+func NewWarehouseRepository(db *pgxpool.Pool) *WarehouseRepository {
+	return &WarehouseRepository{db: db}
+}
+
+// GetCursor retrieves an export cursor by name, returning nil if it does not exist yet
+func (r *WarehouseRepository) GetCursor(ctx context.Context, name string) (*models.WarehouseExportCursor, error) {
+	query := `
+		SELECT name, schema_version, cursor_at, cursor_id, rows_exported, last_file, last_run_at
+		FROM warehouse_export_cursors
+		WHERE name = $1
+	`
+
+	var cursor models.WarehouseExportCursor
+	err := conn(ctx, r.db).QueryRow(ctx, query, name).Scan(
+		&cursor.Name,
+		&cursor.SchemaVersion,
+		&cursor.CursorAt,
+		&cursor.CursorID,
+		&cursor.RowsExported,
+		&cursor.LastFile,
+		&cursor.LastRunAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export cursor: %w", err)
+	}
+
+	return &cursor, nil
+}
+
+// SaveCursor creates or updates an export cursor
+func (r *WarehouseRepository) SaveCursor(ctx context.Context, cursor *models.WarehouseExportCursor) error {
+	query := `
+		INSERT INTO warehouse_export_cursors (
+			name, schema_version, cursor_at, cursor_id, rows_exported, last_file, last_run_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (name) DO UPDATE SET
+			schema_version = EXCLUDED.schema_version,
+			cursor_at = EXCLUDED.cursor_at,
+			cursor_id = EXCLUDED.cursor_id,
+			rows_exported = EXCLUDED.rows_exported,
+			last_file = EXCLUDED.last_file,
+			last_run_at = EXCLUDED.last_run_at,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := conn(ctx, r.db).Exec(ctx, query,
+		cursor.Name,
+		cursor.SchemaVersion,
+		cursor.CursorAt,
+		cursor.CursorID,
+		cursor.RowsExported,
+		cursor.LastFile,
+		cursor.LastRunAt,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save export cursor: %w", err)
+	}
+
+	return nil
+}
+
+// ListFinishedJobsSince retrieves jobs finished no later than until, ordered by
+// (completed_at, id) strictly after the cursor
+func (r *WarehouseRepository) ListFinishedJobsSince(ctx context.Context, cursorAt *time.Time, cursorID *uuid.UUID, until time.Time, limit int) ([]*models.WarehouseJobRecord, error) {
+	query := `
+		SELECT j.id, j.document_id, j.user_id, j.status, j.ocr_mode, j.resolution_mode,
+			   j.priority, j.retry_count, j.created_at, j.started_at, j.completed_at,
+			   d.mime_type, d.file_size,
+			   r.id, r.confidence_score, r.processing_time_ms, r.num_pages,
+			   p.backends, p.credits, p.max_time_ms, p.avg_time_ms
+		FROM ocr_jobs j
+		LEFT JOIN documents d ON d.id = j.document_id
+		LEFT JOIN ocr_results r ON r.job_id = j.id
+		LEFT JOIN LATERAL (
+			SELECT array_agg(DISTINCT backend) FILTER (WHERE backend IS NOT NULL) AS backends,
+				   SUM(credits)::int AS credits,
+				   MAX(processing_time_ms) AS max_time_ms,
+				   AVG(processing_time_ms)::float8 AS avg_time_ms
+			FROM result_pages
+			WHERE job_id = j.id
+		) p ON true
+		WHERE j.status IN ('completed', 'failed', 'cancelled')
+		  AND j.completed_at IS NOT NULL
+		  AND j.completed_at <= $3
+		  AND ($1::timestamptz IS NULL OR (j.completed_at, j.id) > ($1::timestamptz, $2::uuid))
+		ORDER BY j.completed_at ASC, j.id ASC
+		LIMIT $4
+	`
+
+	nilID := uuid.Nil
+	if cursorID == nil {
+		cursorID = &nilID
+	}
+
+	rows, err := conn(ctx, r.db).Query(ctx, query, cursorAt, *cursorID, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list finished jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*models.WarehouseJobRecord
+	for rows.Next() {
+		record := models.WarehouseJobRecord{SchemaVersion: models.WarehouseSchemaVersion}
+		err := rows.Scan(
+			&record.JobID,
+			&record.DocumentID,
+			&record.UserID,
+			&record.Status,
+			&record.OCRMode,
+			&record.ResolutionMode,
+			&record.Priority,
+			&record.RetryCount,
+			&record.CreatedAt,
+			&record.StartedAt,
+			&record.CompletedAt,
+			&record.MimeType,
+			&record.FileSize,
+			&record.ResultID,
+			&record.ConfidenceScore,
+			&record.ProcessingTimeMs,
+			&record.NumPages,
+			&record.Backends,
+			&record.PageCredits,
+			&record.MaxPageTimeMs,
+			&record.AvgPageTimeMs,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job record: %w", err)
+		}
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,39 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Result is the verdict of a malware scan
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+// Scanner inspects file contents for malware
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// Config holds the malware scanner settings
+type Config struct {
+	Driver  string // none, clamd
+	Address string
+	Timeout time.Duration
+}
+
+// NewScanner creates the configured scanner.
+// It returns nil when scanning is disabled.
+func NewScanner(cfg Config) (Scanner, error) {
+	switch cfg.Driver {
+	case "", "none":
+		return nil, nil
+	case "clamd":
+		return NewClamdScanner(cfg.Address, cfg.Timeout)
+	default:
+		return nil, fmt.Errorf("unknown antivirus driver: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// accessGrantReminderBatchSize bounds the number of reminders sent per transaction
+const accessGrantReminderBatchSize = 200
+
+var (
+	// ErrSelfGrant is returned when a user grants access to themselves
+	ErrSelfGrant = apperrors.Validation("VAL_024", "cannot grant access to yourself")
+	// ErrAccessGrantInactive is returned when a grantee uses a grant that expired
+	// or was revoked
+	ErrAccessGrantInactive = apperrors.Forbidden("GRANT_002", "access grant has expired or was revoked")
+)
+
+// AccessGrantService lets users give another user temporary read access to a
+// set of their documents, for example for a review. Grantees are notified with
+// an access_grant.created event and reminded with an access_grant.expiring
+// event before the grant expires; what they access under it is audited.
+type AccessGrantService struct {
+	grantRepo    *repository.AccessGrantRepository
+	documentRepo *repository.DocumentRepository
+	resultRepo   *repository.ResultRepository
+	userRepo     *repository.UserRepository
+	transactor   *repository.Transactor
+	eventService *EventService
+	auditService *AuditService
+	interval     time.Duration
+	reminderLead time.Duration
+}
+
+// NewAccessGrantService creates a new access grant service. Grantees are
+// reminded reminderLead before their grant expires; a zero lead disables reminders.
+func NewAccessGrantService(
+	grantRepo *repository.AccessGrantRepository,
+	documentRepo *repository.DocumentRepository,
+	resultRepo *repository.ResultRepository,
+	userRepo *repository.UserRepository,
+	transactor *repository.Transactor,
+	eventService *EventService,
+	auditService *AuditService,
+	interval time.Duration,
+	reminderLead time.Duration,
+) *AccessGrantService {
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	return &AccessGrantService{
+		grantRepo:    grantRepo,
+		documentRepo: documentRepo,
+		resultRepo:   resultRepo,
+		userRepo:     userRepo,
+		transactor:   transactor,
+		eventService: eventService,
+		auditService: auditService,
+		interval:     interval,
+		reminderLead: reminderLead,
+	}
+}
+
+// Create grants the user with the requested email access to some of the owner's
+// documents for the requested number of hours
+func (s *AccessGrantService) Create(ctx context.Context, ownerID uuid.UUID, req models.AccessGrantCreateRequest) (*models.AccessGrant, error) {
+	grantee, err := s.userRepo.GetByEmail(ctx, req.GranteeEmail)
+	if err != nil {
+		return nil, err
+	}
+	if grantee.ID == ownerID {
+		return nil, ErrSelfGrant
+	}
+
+	grant := &models.AccessGrant{
+		OwnerID:      ownerID,
+		GranteeID:    grantee.ID,
+		GranteeEmail: grantee.Email,
+		DocumentIDs:  []uuid.UUID{},
+		ExpiresAt:    time.Now().UTC().Add(time.Duration(req.DurationHours) * time.Hour),
+	}
+	if note := strings.TrimSpace(req.Note); note != "" {
+		grant.Note = &note
+	}
+
+	for _, documentID := range req.DocumentIDs {
+		if slices.Contains(grant.DocumentIDs, documentID) {
+			continue
+		}
+		document, err := s.documentRepo.GetByID(ctx, documentID)
+		if err != nil {
+			return nil, err
+		}
+		if document.UserID != ownerID {
+			return nil, apperrors.Forbidden("AUTH_004", "document does not belong to user")
+		}
+		grant.DocumentIDs = append(grant.DocumentIDs, documentID)
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.grantRepo.Create(ctx, grant); err != nil {
+			return err
+		}
+
+		return s.eventService.Record(ctx, models.NewDomainEvent(
+			models.EventAccessGrantCreated, "access_grant", grant.ID, &grantee.ID,
+			map[string]any{
+				"owner_id":     ownerID,
+				"grantee_id":   grantee.ID,
+				"document_ids": grant.DocumentIDs,
+				"note":         grant.Note,
+				"expires_at":   grant.ExpiresAt,
+			},
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+// List returns the grants a user has given
+func (s *AccessGrantService) List(ctx context.Context, ownerID uuid.UUID) ([]*models.AccessGrant, error) {
+	return s.grantRepo.ListByOwner(ctx, ownerID)
+}
+
+// ListReceived returns the active grants a user has received
+func (s *AccessGrantService) ListReceived(ctx context.Context, granteeID uuid.UUID) ([]*models.AccessGrant, error) {
+	return s.grantRepo.ListActiveByGrantee(ctx, granteeID)
+}
+
+// Revoke ends one of a user's grants before it expires
+func (s *AccessGrantService) Revoke(ctx context.Context, id, ownerID uuid.UUID) error {
+	return s.grantRepo.Revoke(ctx, id, ownerID)
+}
+
+// Activity returns the audit trail of one of a user's grants: its creation,
+// every access made under it and its revocation
+func (s *AccessGrantService) Activity(ctx context.Context, id, ownerID uuid.UUID, page, perPage int) ([]*models.AuditEvent, int, error) {
+	grant, err := s.grantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if grant.OwnerID != ownerID {
+		return nil, 0, repository.ErrAccessGrantNotFound
+	}
+
+	return s.auditService.List(ctx, models.AuditFilter{
+		TargetID: &grant.ID,
+		Page:     page,
+		PerPage:  perPage,
+	})
+}
+
+// ListDocuments returns the documents a grantee can see under a grant. Documents
+// the owner deleted since are left out.
+func (s *AccessGrantService) ListDocuments(ctx context.Context, id, granteeID uuid.UUID) (*models.AccessGrant, []*models.Document, error) {
+	grant, err := s.activeGrant(ctx, id, granteeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	documents := []*models.Document{}
+	for _, documentID := range grant.DocumentIDs {
+		document, err := s.documentRepo.GetByID(ctx, documentID)
+		if errors.Is(err, repository.ErrDocumentNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	return grant, documents, nil
+}
+
+// GetDocument returns a document a grantee can see under a grant, with its
+// latest result
+func (s *AccessGrantService) GetDocument(ctx context.Context, id, granteeID, documentID uuid.UUID) (*models.AccessGrant, *models.GrantedDocument, error) {
+	grant, err := s.activeGrant(ctx, id, granteeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !slices.Contains(grant.DocumentIDs, documentID) {
+		return nil, nil, repository.ErrDocumentNotFound
+	}
+
+	document, err := s.documentRepo.GetByID(ctx, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	granted := &models.GrantedDocument{Document: document}
+	results, err := s.resultRepo.GetByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(results) > 0 {
+		granted.Result = results[0]
+	}
+
+	return grant, granted, nil
+}
+
+// activeGrant returns a grant the user received that can still be used
+func (s *AccessGrantService) activeGrant(ctx context.Context, id, granteeID uuid.UUID) (*models.AccessGrant, error) {
+	grant, err := s.grantRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if grant.GranteeID != granteeID {
+		return nil, repository.ErrAccessGrantNotFound
+	}
+	if !grant.Active(time.Now()) {
+		return nil, ErrAccessGrantInactive
+	}
+
+	return grant, nil
+}
+
+// Start reminds grantees of expiring grants on every interval until the
+// context is cancelled
+func (s *AccessGrantService) Start(ctx context.Context) {
+	if s.reminderLead <= 0 {
+		return
+	}
+
+	logger.Info("Access grant reminders started", "interval", s.interval, "lead", s.reminderLead)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Access grant reminders stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{
+				Name: "access_grants.remind_expiring",
+				Run: func(ctx context.Context) error {
+					_, err := s.RemindExpiring(ctx)
+					return err
+				},
+			})
+		}
+	}
+}
+
+// RemindExpiring records an access_grant.expiring event for each active grant
+// expiring within the reminder lead, once per grant. It returns the number of
+// grantees reminded.
+func (s *AccessGrantService) RemindExpiring(ctx context.Context) (int, error) {
+	if s.reminderLead <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(s.reminderLead)
+
+	total := 0
+	for {
+		var expiring []*models.AccessGrant
+
+		// Mark the grants and record the reminders atomically
+		err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			expiring, err = s.grantRepo.MarkExpiring(ctx, cutoff, accessGrantReminderBatchSize)
+			if err != nil {
+				return err
+			}
+
+			evts := make([]*models.DomainEvent, 0, len(expiring))
+			for _, grant := range expiring {
+				evts = append(evts, models.NewDomainEvent(
+					models.EventAccessGrantExpiring, "access_grant", grant.ID, &grant.GranteeID,
+					map[string]any{
+						"owner_id":     grant.OwnerID,
+						"grantee_id":   grant.GranteeID,
+						"document_ids": grant.DocumentIDs,
+						"expires_at":   grant.ExpiresAt,
+					},
+				))
+			}
+			return s.eventService.Record(ctx, evts...)
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += len(expiring)
+		if len(expiring) < accessGrantReminderBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.Info("Access grant reminders sent", "reminded", total)
+	}
+
+	return total, nil
+}
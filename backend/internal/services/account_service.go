@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrPasswordIncorrect is returned when the password confirming a
+	// sensitive change is wrong
+	ErrPasswordIncorrect = apperrors.Forbidden("AUTH_009", "password is incorrect")
+	// ErrPasswordRequired is returned when changing the email without the
+	// current password
+	ErrPasswordRequired = apperrors.Validation("VAL_040", "current_password is required to change the email")
+	// ErrLastAdmin is returned when deleting the only admin account
+	ErrLastAdmin = apperrors.Conflict("ACCT_001", "the last admin account cannot be deleted")
+	// ErrDeletionNotScheduled is returned when cancelling the deletion of an
+	// account that is not awaiting deletion
+	ErrDeletionNotScheduled = apperrors.Conflict("ACCT_002", "account is not scheduled for deletion")
+)
+
+// AccountService lets users manage their own account: change their profile,
+// export their data and delete the account. Deleted accounts are kept for a
+// grace period, during which users can export their data or change their
+// mind, before the janitor deletes them with their documents, jobs, results
+// and stored files, and anonymizes them in the audit log.
+type AccountService struct {
+	userRepo     *repository.UserRepository
+	documentRepo *repository.DocumentRepository
+	orgRepo      *repository.OrganizationRepository
+	auditRepo    *repository.AuditRepository
+	exportRepo   *repository.DataExportRepository
+	transactor   *repository.Transactor
+	storage      *storage.Storage
+	grace        time.Duration
+	batchSize    int
+}
+
+// NewAccountService creates a new account service.
+// A grace period of zero deletes accounts right away.
+func NewAccountService(
+	userRepo *repository.UserRepository,
+	documentRepo *repository.DocumentRepository,
+	orgRepo *repository.OrganizationRepository,
+	auditRepo *repository.AuditRepository,
+	exportRepo *repository.DataExportRepository,
+	transactor *repository.Transactor,
+	storage *storage.Storage,
+	grace time.Duration,
+) *AccountService {
+	return &AccountService{
+		userRepo:     userRepo,
+		documentRepo: documentRepo,
+		orgRepo:      orgRepo,
+		auditRepo:    auditRepo,
+		exportRepo:   exportRepo,
+		transactor:   transactor,
+		storage:      storage,
+		grace:        grace,
+		batchSize:    50,
+	}
+}
+
+// UpdateProfile changes the user's name and email
+func (s *AccountService) UpdateProfile(ctx context.Context, userID uuid.UUID, req models.UserUpdateRequest) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		user.Name = strings.TrimSpace(*req.Name)
+	}
+
+	if req.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*req.Email))
+		if email != user.Email {
+			if req.CurrentPassword == "" {
+				return nil, ErrPasswordRequired
+			}
+			if err := checkPassword(user, req.CurrentPassword); err != nil {
+				return nil, err
+			}
+
+			exists, err := s.userRepo.Exists(ctx, email)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				return nil, apperrors.Conflict("AUTH_001", "user with email "+email+" already exists")
+			}
+			user.Email = email
+		}
+	}
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Export returns the user's personal data
+func (s *AccountService) Export(ctx context.Context, userID uuid.UUID) (*models.AccountExport, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	orgs, err := s.orgRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.documentRepo.ListAllByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AccountExport{
+		User:          user.ToResponse(),
+		Organizations: orgs,
+		Documents:     documents,
+		ExportedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// RequestDeletion schedules the deletion of the user's account once the
+// grace period is over, confirmed by their password if they have one. With no
+// grace period the account is deleted right away and nil is returned.
+func (s *AccountService) RequestDeletion(ctx context.Context, userID uuid.UUID, password string) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPassword(user, password); err != nil {
+		return nil, err
+	}
+	if user.DeletionScheduledAt != nil {
+		return user, nil
+	}
+
+	if user.IsAdmin() {
+		admins, err := s.userRepo.CountAdmins(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if admins <= 1 {
+			return nil, ErrLastAdmin
+		}
+	}
+
+	if s.grace <= 0 {
+		return nil, s.deleteAccount(ctx, userID)
+	}
+
+	scheduledAt := time.Now().UTC().Add(s.grace)
+	if err := s.userRepo.ScheduleDeletion(ctx, userID, &scheduledAt); err != nil {
+		return nil, err
+	}
+	user.DeletionScheduledAt = &scheduledAt
+
+	logger.Info("Account deletion scheduled", "user_id", userID, "scheduled_at", scheduledAt)
+	return user, nil
+}
+
+// CancelDeletion keeps an account whose deletion was requested
+func (s *AccountService) CancelDeletion(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletionScheduledAt == nil {
+		return nil, ErrDeletionNotScheduled
+	}
+
+	if err := s.userRepo.ScheduleDeletion(ctx, userID, nil); err != nil {
+		return nil, err
+	}
+	user.DeletionScheduledAt = nil
+
+	logger.Info("Account deletion cancelled", "user_id", userID)
+	return user, nil
+}
+
+// PurgeDue deletes the accounts whose grace period is over. It returns the
+// number of accounts deleted.
+func (s *AccountService) PurgeDue(ctx context.Context) (int, error) {
+	deleted := 0
+
+	for {
+		ids, err := s.userRepo.ListDeletionDue(ctx, time.Now(), s.batchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		for _, id := range ids {
+			if err := s.deleteAccount(ctx, id); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+
+		if len(ids) < s.batchSize {
+			break
+		}
+	}
+
+	if deleted > 0 {
+		logger.Info("Deleted accounts after their grace period", "count", deleted)
+	}
+
+	return deleted, nil
+}
+
+// deleteAccount deletes a user with their documents, jobs, results and data
+// exports, which the ON DELETE CASCADE constraints remove, and their stored
+// files. Their organizations keep an owner: the next member is promoted, and
+// organizations with no other member are deleted.
+func (s *AccountService) deleteAccount(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	documents, err := s.documentRepo.ListAllByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	exports, err := s.exportRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.handOverOrganizations(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.auditRepo.AnonymizeActor(ctx, userID, user.Email); err != nil {
+			return err
+		}
+		return s.userRepo.Delete(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteFiles(ctx, userID, documents)
+	for _, export := range exports {
+		if export.FilePath == "" {
+			continue
+		}
+		if err := s.storage.DeleteFile(export.FilePath); err != nil {
+			logger.Warn("Failed to delete data export of deleted account", "user_id", userID, "error", err)
+		}
+	}
+
+	logger.Info("Account deleted", "user_id", userID, "documents", len(documents))
+	return nil
+}
+
+// handOverOrganizations makes sure no organization is left without an owner
+// when the user goes
+func (s *AccountService) handOverOrganizations(ctx context.Context, userID uuid.UUID) error {
+	orgs, err := s.orgRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, org := range orgs {
+		if org.Role != models.OrgRoleOwner {
+			continue
+		}
+
+		owners, err := s.orgRepo.LockOwners(ctx, org.ID)
+		if err != nil {
+			return err
+		}
+		if owners > 1 {
+			continue
+		}
+
+		members, err := s.orgRepo.ListMembers(ctx, org.ID)
+		if err != nil {
+			return err
+		}
+
+		var successor *models.OrgMember
+		for _, member := range members {
+			if member.UserID != userID {
+				successor = member
+				break
+			}
+		}
+		if successor == nil {
+			if err := s.orgRepo.Delete(ctx, org.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := s.orgRepo.UpdateMemberRole(ctx, org.ID, successor.UserID, models.OrgRoleOwner); err != nil {
+			return err
+		}
+		logger.Info("Organization ownership handed over from deleted account",
+			"organization_id", org.ID, "user_id", userID, "new_owner_id", successor.UserID)
+	}
+
+	return nil
+}
+
+// deleteFiles removes the stored files of a deleted user's documents that no
+// other document refers to
+func (s *AccountService) deleteFiles(ctx context.Context, userID uuid.UUID, documents []models.Document) {
+	seen := make(map[string]bool, len(documents))
+	paths := make([]string, 0, len(documents))
+	for _, document := range documents {
+		candidates := []string{document.FilePath}
+		if document.ThumbnailPath != nil {
+			candidates = append(candidates, *document.ThumbnailPath)
+		}
+		for _, path := range candidates {
+			if !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	// Files shared with other documents, such as a teammate's upload of the
+	// same file, stay
+	for _, path := range paths {
+		if err := s.storage.Release(ctx, path); err != nil {
+			logger.Warn("Failed to delete file of deleted account", "user_id", userID, "error", err)
+		}
+	}
+}
+
+// checkPassword checks a password confirming a sensitive change. Users
+// without a password, who sign in through SSO, have nothing to confirm.
+func checkPassword(user *models.User, password string) error {
+	if user.PasswordHash == "" {
+		return nil
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordIncorrect
+		}
+		return err
+	}
+	return nil
+}
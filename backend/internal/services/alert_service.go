@@ -0,0 +1,243 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"time"
+
+	"visekai/backend/internal/alerts"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/ocr"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+)
+
+// Keys of the conditions the alert service watches
+const (
+	AlertStorageLow     = "storage_low"
+	AlertOCRUnavailable = "ocr_unavailable"
+	AlertQueueWait      = "queue_wait_slo"
+	AlertOCRCanary      = "ocr_canary"
+)
+
+// AlertThresholds sets when the alert service raises each alert
+type AlertThresholds struct {
+	// StorageMinFreePercent is the free space below which storage is nearly
+	// full; zero disables the check
+	StorageMinFreePercent int
+	// QueueWaitSLO is how long a job may wait to be picked up; zero disables the check
+	QueueWaitSLO time.Duration
+	// FailureThreshold is the number of consecutive failed OCR health checks or
+	// canaries before alerting
+	FailureThreshold int
+	// Canary sends a blank page through the OCR backend on every check
+	Canary bool
+}
+
+// AlertService checks internal conditions on every interval and raises an alert
+// when one crosses its threshold, and a resolution notice once it clears.
+// The alerter deduplicates repeated firings within its cool-down.
+type AlertService struct {
+	alerter     *alerts.Alerter
+	jobRepo     *repository.JobRepository
+	fileStorage *storage.Storage
+	ocrClient   *ocr.Client
+	interval    time.Duration
+	thresholds  AlertThresholds
+
+	// Consecutive failures, only touched by the checking goroutine
+	ocrFailures    int
+	canaryFailures int
+}
+
+// NewAlertService creates a new alert service
+func NewAlertService(
+	alerter *alerts.Alerter,
+	jobRepo *repository.JobRepository,
+	fileStorage *storage.Storage,
+	ocrClient *ocr.Client,
+	interval time.Duration,
+	thresholds AlertThresholds,
+) *AlertService {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if thresholds.FailureThreshold < 1 {
+		thresholds.FailureThreshold = 3
+	}
+	return &AlertService{
+		alerter:     alerter,
+		jobRepo:     jobRepo,
+		fileStorage: fileStorage,
+		ocrClient:   ocrClient,
+		interval:    interval,
+		thresholds:  thresholds,
+	}
+}
+
+// Start checks the alert conditions on every interval until the context is
+// cancelled. Without alert targets it does nothing.
+func (s *AlertService) Start(ctx context.Context) {
+	if !s.alerter.Enabled() {
+		return
+	}
+
+	logger.Info("Alerting started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Alerting stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{
+				Name: "alerts.check",
+				Run:  s.Check,
+			})
+		}
+	}
+}
+
+// Check evaluates every enabled condition once
+func (s *AlertService) Check(ctx context.Context) error {
+	s.checkStorage(ctx)
+	s.checkOCR(ctx)
+	if s.thresholds.Canary {
+		s.checkCanary(ctx)
+	}
+	return s.checkQueueWait(ctx)
+}
+
+// checkStorage alerts when the storage filesystem is nearly full, critically
+// so below half the threshold
+func (s *AlertService) checkStorage(ctx context.Context) {
+	if s.thresholds.StorageMinFreePercent <= 0 {
+		return
+	}
+
+	usage, err := s.fileStorage.DiskUsage()
+	if err != nil {
+		logger.Warn("Failed to measure storage", "error", err)
+		return
+	}
+
+	free := usage.FreePercent()
+	alert := alerts.Alert{
+		Key:      AlertStorageLow,
+		Severity: alerts.SeverityWarning,
+		Summary:  fmt.Sprintf("Document storage is %.1f%% free", free),
+		Details: map[string]any{
+			"free_bytes":   usage.FreeBytes,
+			"total_bytes":  usage.TotalBytes,
+			"free_percent": fmt.Sprintf("%.1f", free),
+			"threshold":    s.thresholds.StorageMinFreePercent,
+		},
+	}
+	if free >= float64(s.thresholds.StorageMinFreePercent) {
+		s.alerter.Resolve(ctx, alert)
+		return
+	}
+	if free < float64(s.thresholds.StorageMinFreePercent)/2 {
+		alert.Severity = alerts.SeverityCritical
+	}
+	s.alerter.Fire(ctx, alert)
+}
+
+// checkOCR alerts when the OCR backend failed several health checks in a row
+func (s *AlertService) checkOCR(ctx context.Context) {
+	err := s.ocrClient.HealthCheck(ctx)
+	alert := alerts.Alert{
+		Key:      AlertOCRUnavailable,
+		Severity: alerts.SeverityCritical,
+		Summary:  "OCR service is unavailable",
+		Details:  map[string]any{"url": s.ocrClient.BaseURL()},
+	}
+	if err == nil {
+		s.ocrFailures = 0
+		s.alerter.Resolve(ctx, alert)
+		return
+	}
+
+	s.ocrFailures++
+	if s.ocrFailures >= s.thresholds.FailureThreshold {
+		alert.Details["consecutive_failures"] = s.ocrFailures
+		alert.Details["error"] = err.Error()
+		s.alerter.Fire(ctx, alert)
+	}
+}
+
+// checkCanary sends a blank page through the OCR backend and alerts when
+// several canaries in a row failed
+func (s *AlertService) checkCanary(ctx context.Context) {
+	_, err := s.ocrClient.ProcessDocument(ctx, bytes.NewReader(canaryPage()), "canary.png", 1, models.OCRModeDocument, models.ResolutionTiny)
+	alert := alerts.Alert{
+		Key:      AlertOCRCanary,
+		Severity: alerts.SeverityCritical,
+		Summary:  "OCR canary requests are failing",
+		Details:  map[string]any{"url": s.ocrClient.BaseURL()},
+	}
+	if err == nil {
+		s.canaryFailures = 0
+		s.alerter.Resolve(ctx, alert)
+		return
+	}
+
+	s.canaryFailures++
+	if s.canaryFailures >= s.thresholds.FailureThreshold {
+		alert.Details["consecutive_failures"] = s.canaryFailures
+		alert.Details["error"] = err.Error()
+		s.alerter.Fire(ctx, alert)
+	}
+}
+
+// checkQueueWait alerts when the oldest pending job has waited longer than the SLO
+func (s *AlertService) checkQueueWait(ctx context.Context) error {
+	if s.thresholds.QueueWaitSLO <= 0 {
+		return nil
+	}
+
+	stats, err := s.jobRepo.QueueStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	alert := alerts.Alert{
+		Key:      AlertQueueWait,
+		Severity: alerts.SeverityWarning,
+		Details: map[string]any{
+			"pending_jobs": stats.Counts[models.JobStatusPending],
+			"slo":          s.thresholds.QueueWaitSLO.String(),
+		},
+	}
+	if stats.OldestPendingAt == nil || time.Since(*stats.OldestPendingAt) <= s.thresholds.QueueWaitSLO {
+		alert.Summary = "OCR queue wait is within its SLO"
+		s.alerter.Resolve(ctx, alert)
+		return nil
+	}
+
+	wait := time.Since(*stats.OldestPendingAt).Round(time.Second)
+	alert.Summary = fmt.Sprintf("Oldest pending OCR job has waited %s", wait)
+	alert.Details["oldest_pending_at"] = stats.OldestPendingAt.UTC().Format(time.RFC3339)
+	s.alerter.Fire(ctx, alert)
+	return nil
+}
+
+// canaryPage encodes a small blank page for canary requests
+func canaryPage() []byte {
+	img := image.NewGray(image.Rect(0, 0, 200, 100))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
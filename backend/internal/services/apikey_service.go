@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidAPIKey is returned for unknown, revoked and expired API keys
+var ErrInvalidAPIKey = errors.New("invalid or expired API key")
+
+// apiKeyTouchInterval limits how often last_used_at is written for a busy key
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyService issues and verifies API keys
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{apiKeyRepo: apiKeyRepo}
+}
+
+// Create issues a new key for a user. The returned secret is not stored and
+// cannot be retrieved again.
+func (s *APIKeyService) Create(ctx context.Context, userID uuid.UUID, req models.APIKeyCreateRequest) (*models.APIKeyCreated, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expiry must be in the future")
+	}
+
+	labels, err := models.NormalizeLabels(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, permission := range req.Permissions {
+		if err := permission.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := models.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	key := &models.APIKey{
+		UserID:      userID,
+		Name:        req.Name,
+		KeyPrefix:   secret[:len(models.APIKeyPrefix)+8],
+		ExpiresAt:   req.ExpiresAt,
+		Labels:      labels,
+		Permissions: req.Permissions,
+	}
+	if err := s.apiKeyRepo.Create(ctx, key, hashAPIKey(secret)); err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyCreated{APIKey: key, Key: secret}, nil
+}
+
+// List returns a user's keys
+func (s *APIKeyService) List(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	return s.apiKeyRepo.ListByUser(ctx, userID)
+}
+
+// Revoke deactivates one of a user's keys
+func (s *APIKeyService) Revoke(ctx context.Context, id, userID uuid.UUID) error {
+	return s.apiKeyRepo.Revoke(ctx, id, userID)
+}
+
+// Authenticate resolves a presented key to the active key it belongs to
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	if !strings.HasPrefix(secret, models.APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.GetByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	now := time.Now().UTC()
+	if !key.IsActive || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			logger.Warn("Failed to record API key use", "api_key_id", key.ID, "error", err)
+		}
+	}
+
+	return key, nil
+}
+
+// hashAPIKey returns the stored form of a key. Keys carry 256 bits of entropy,
+// so a fast hash is sufficient.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
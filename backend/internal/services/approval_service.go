@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotApprover is returned when a user the OCR policy does not name as an
+	// approver lists or decides approval requests
+	ErrNotApprover = apperrors.Forbidden("APPROVAL_001", "only approvers may review jobs awaiting approval")
+	// ErrSelfApproval is returned when an approver decides on their own job
+	ErrSelfApproval = apperrors.Forbidden("APPROVAL_001", "approvers cannot decide on their own jobs")
+	// ErrApprovalDecided is returned when the job was already approved, rejected
+	// or cancelled
+	ErrApprovalDecided = apperrors.Conflict("APPROVAL_003", "job is no longer awaiting approval")
+)
+
+// ApprovalService lets the approvers named in the OCR policy approve or reject
+// jobs held for approval. Jobs held by an organization's policy are decided by
+// its approvers. Approved jobs join the queue like any other job; rejected jobs
+// are cancelled.
+type ApprovalService struct {
+	approvalRepo  *repository.ApprovalRepository
+	jobRepo       *repository.JobRepository
+	userRepo      *repository.UserRepository
+	orgRepo       *repository.OrganizationRepository
+	transactor    *repository.Transactor
+	eventService  *EventService
+	policyService *OCRPolicyService
+	jobService    *JobService
+}
+
+// NewApprovalService creates a new approval service
+func NewApprovalService(
+	approvalRepo *repository.ApprovalRepository,
+	jobRepo *repository.JobRepository,
+	userRepo *repository.UserRepository,
+	orgRepo *repository.OrganizationRepository,
+	transactor *repository.Transactor,
+	eventService *EventService,
+	policyService *OCRPolicyService,
+	jobService *JobService,
+) *ApprovalService {
+	return &ApprovalService{
+		approvalRepo:  approvalRepo,
+		jobRepo:       jobRepo,
+		userRepo:      userRepo,
+		orgRepo:       orgRepo,
+		transactor:    transactor,
+		eventService:  eventService,
+		policyService: policyService,
+		jobService:    jobService,
+	}
+}
+
+// List retrieves the jobs awaiting approval the user may decide on, oldest first
+func (s *ApprovalService) List(ctx context.Context, approverID uuid.UUID, page, perPage int) ([]*models.JobApproval, *models.Pagination, error) {
+	user, err := s.userRepo.GetByID(ctx, approverID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	instance, err := s.policyService.IsApprover(ctx, nil, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	orgs, err := s.orgRepo.ListByUser(ctx, approverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var orgIDs []uuid.UUID
+	for _, org := range orgs {
+		ok, err := s.policyService.IsApprover(ctx, &org.ID, user)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			orgIDs = append(orgIDs, org.ID)
+		}
+	}
+	if !instance && len(orgIDs) == 0 {
+		return nil, nil, ErrNotApprover
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	approvals, total, err := s.approvalRepo.ListUndecided(ctx, instance, orgIDs, page, perPage)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	totalPages := (total + perPage - 1) / perPage
+
+	pagination := &models.Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}
+
+	return approvals, pagination, nil
+}
+
+// Approve releases a held job into the queue
+func (s *ApprovalService) Approve(ctx context.Context, jobID, approverID uuid.UUID, comment string) (*models.JobApproval, error) {
+	approval, err := s.decide(ctx, jobID, approverID, models.ApprovalApproved, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("OCR job approved", "job_id", jobID, "approver_id", approverID)
+
+	// Start processing asynchronously
+	s.jobService.enqueue(jobID)
+
+	return approval, nil
+}
+
+// Reject cancels a held job, recording the approver's comment as its error message
+func (s *ApprovalService) Reject(ctx context.Context, jobID, approverID uuid.UUID, comment string) (*models.JobApproval, error) {
+	approval, err := s.decide(ctx, jobID, approverID, models.ApprovalRejected, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("OCR job rejected", "job_id", jobID, "approver_id", approverID)
+
+	return approval, nil
+}
+
+// decide records the decision and moves the job out of awaiting_approval in one
+// transaction, notifying the job's owner with a job.approved or job.rejected event
+func (s *ApprovalService) decide(ctx context.Context, jobID, approverID uuid.UUID, decision models.ApprovalDecision, comment string) (*models.JobApproval, error) {
+	comment = strings.TrimSpace(comment)
+
+	var approval *models.JobApproval
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		approval, err = s.approvalRepo.GetByJobID(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkApprover(ctx, approval.OrgID, approverID); err != nil {
+			return err
+		}
+
+		job, err := s.jobRepo.GetByID(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		if job.UserID == approverID {
+			return ErrSelfApproval
+		}
+		if job.Status != models.JobStatusAwaitingApproval {
+			return ErrApprovalDecided
+		}
+
+		approval.Decision = &decision
+		approval.DecidedBy = &approverID
+		if comment != "" {
+			approval.Comment = &comment
+		}
+		decided, err := s.approvalRepo.Decide(ctx, approval)
+		if err != nil {
+			return err
+		}
+		if !decided {
+			return ErrApprovalDecided
+		}
+
+		eventType := models.EventJobApproved
+		if decision == models.ApprovalApproved {
+			err = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusPending, nil)
+		} else {
+			eventType = models.EventJobRejected
+			message := "Rejected by approver"
+			if comment != "" {
+				message += ": " + comment
+			}
+			err = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusCancelled, &message)
+		}
+		// The owner may have cancelled the job since it was read
+		if errors.Is(err, repository.ErrInvalidTransition) {
+			return ErrApprovalDecided
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update job status: %w", err)
+		}
+
+		approval.Job = job
+		return s.eventService.Record(ctx, models.NewDomainEvent(
+			eventType, "job", jobID, &job.UserID,
+			map[string]any{
+				"document_id": job.DocumentID,
+				"decided_by":  approverID,
+				"comment":     comment,
+			},
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+// checkApprover returns ErrNotApprover unless the OCR policy of the
+// organization, or the instance-wide policy when orgID is nil, lets the user
+// decide on the jobs it holds
+func (s *ApprovalService) checkApprover(ctx context.Context, orgID *uuid.UUID, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.policyService.IsApprover(ctx, orgID, user)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotApprover
+	}
+
+	return nil
+}
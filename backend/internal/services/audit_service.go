@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+)
+
+// AuditService records and queries the audit log
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(auditRepo *repository.AuditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// Record stores an audit event. Failures are logged rather than returned so an
+// unavailable audit log never fails the action being audited.
+func (s *AuditService) Record(ctx context.Context, event *models.AuditEvent) {
+	if err := s.auditRepo.Create(ctx, event); err != nil {
+		logger.Error("Failed to record audit event", "action", event.Action, "request_id", event.RequestID, "error", err)
+	}
+}
+
+// List retrieves audit events matching the filter
+func (s *AuditService) List(ctx context.Context, filter models.AuditFilter) ([]*models.AuditEvent, int, error) {
+	return s.auditRepo.List(ctx, filter)
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"visekai/backend/internal/apperrors"
 	"visekai/backend/internal/config"
 	"visekai/backend/internal/models"
 	"visekai/backend/internal/repository"
@@ -17,15 +18,29 @@ import (
 
 // AuthService handles authentication operations
 type AuthService struct {
-	userRepo *repository.UserRepository
-	cfg      *config.Config
+	userRepo     *repository.UserRepository
+	transactor   *repository.Transactor
+	eventService *EventService
+	// keys signs tokens; nil signs them with the shared secret (HS256)
+	keys *JWTKeyService
+	cfg  *config.Config
 }
 
-// NewAuthService creates a new auth service
-func NewAuthService(userRepo *repository.UserRepository, cfg *config.Config) *AuthService {
+// NewAuthService creates a new auth service. Tokens are signed with the keys
+// of the ring, or with the shared secret when keys is nil.
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	transactor *repository.Transactor,
+	eventService *EventService,
+	keys *JWTKeyService,
+	cfg *config.Config,
+) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		cfg:      cfg,
+		userRepo:     userRepo,
+		transactor:   transactor,
+		eventService: eventService,
+		keys:         keys,
+		cfg:          cfg,
 	}
 }
 
@@ -38,6 +53,11 @@ type JWTClaims struct {
 
 // Register registers a new user
 func (s *AuthService) Register(ctx context.Context, req models.UserRegistration) (*models.User, error) {
+	return s.CreateUser(ctx, req, models.UserRoleUser)
+}
+
+// CreateUser creates a user with the given role
+func (s *AuthService) CreateUser(ctx context.Context, req models.UserRegistration, role models.UserRole) (*models.User, error) {
 	// Normalize email to lowercase
 	email := strings.ToLower(strings.TrimSpace(req.Email))
 
@@ -47,7 +67,7 @@ func (s *AuthService) Register(ctx context.Context, req models.UserRegistration)
 		return nil, fmt.Errorf("failed to check user existence: %w", err)
 	}
 	if exists {
-		return nil, fmt.Errorf("user with email %s already exists", email)
+		return nil, apperrors.Conflict("AUTH_001", fmt.Sprintf("user with email %s already exists", email))
 	}
 
 	// Hash password with cost factor 12 (recommended minimum)
@@ -61,9 +81,45 @@ func (s *AuthService) Register(ctx context.Context, req models.UserRegistration)
 		Email:        email,
 		PasswordHash: string(hashedPassword),
 		Name:         req.Name,
+		Role:         role,
 	}
 
-	err = s.userRepo.Create(ctx, user)
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+
+		return s.eventService.Record(ctx, models.NewDomainEvent(
+			models.EventUserRegistered, "user", user.ID, &user.ID,
+			map[string]any{"email": user.Email, "name": user.Name},
+		))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ProvisionUser creates a user signing in through single sign-on for the
+// first time. They have no password, so they can only sign in through SSO.
+func (s *AuthService) ProvisionUser(ctx context.Context, email, name string) (*models.User, error) {
+	user := &models.User{
+		Email: strings.ToLower(strings.TrimSpace(email)),
+		Name:  name,
+		Role:  models.UserRoleUser,
+	}
+
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Create(ctx, user); err != nil {
+			return err
+		}
+
+		return s.eventService.Record(ctx, models.NewDomainEvent(
+			models.EventUserRegistered, "user", user.ID, &user.ID,
+			map[string]any{"email": user.Email, "name": user.Name, "method": "sso"},
+		))
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -90,7 +146,12 @@ func (s *AuthService) Login(ctx context.Context, req models.UserLogin) (*models.
 		return nil, fmt.Errorf("invalid email or password")
 	}
 
-	// Generate tokens
+	return s.IssueTokens(user)
+}
+
+// IssueTokens returns a new access and refresh token pair for a user who
+// has been authenticated
+func (s *AuthService) IssueTokens(user *models.User) (*models.AuthResponse, error) {
 	accessToken, err := s.GenerateAccessToken(user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
@@ -133,13 +194,7 @@ func (s *AuthService) GenerateAccessToken(user *models.User) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.cfg.JWTSecret))
-	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
-	}
-
-	return tokenString, nil
+	return s.sign(claims)
 }
 
 // GenerateRefreshToken generates a JWT refresh token
@@ -160,24 +215,68 @@ func (s *AuthService) GenerateRefreshToken(user *models.User) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.cfg.JWTSecret))
+	return s.sign(claims)
+}
+
+// sign signs claims with the current key of the ring, naming it in the kid
+// header, or with the shared secret
+func (s *AuthService) sign(claims JWTClaims) (string, error) {
+	if s.keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		tokenString, err := token.SignedString([]byte(s.cfg.JWTSecret))
+		if err != nil {
+			return "", fmt.Errorf("failed to sign token: %w", err)
+		}
+		return tokenString, nil
+	}
+
+	key, err := s.keys.signingKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.meta.ID
+	tokenString, err := token.SignedString(key.signer)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
-
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// verificationKey returns the key verifying a token: the key of the ring its
+// kid header names, or the shared secret for HS256 tokens, which are only
+// accepted for one token lifetime after switching to signing keys
+func (s *AuthService) verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if s.keys != nil && !time.Now().Before(s.keys.sharedSecretUntil()) {
+			return nil, fmt.Errorf("tokens signed with the shared secret are no longer accepted")
 		}
 		return []byte(s.cfg.JWTSecret), nil
-	})
+	}
+	if s.keys == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, err := s.keys.verificationKey(context.Background(), kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("signing method %v does not match key %q", token.Header["alg"], kid)
+	}
+	return key.signer.Public(), nil
+}
+
+// ValidateToken validates a JWT token and returns the claims
+func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
+	// Retired keys may use another algorithm than the current one
+	methods := []string{jwt.SigningMethodHS256.Alg()}
+	if s.keys != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg())
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, s.verificationKey,
+		jwt.WithValidMethods(methods))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -187,6 +286,12 @@ func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	if !ok || !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
+	// Access and refresh tokens have no audience; other tokens signed with the
+	// shared secret, such as connector and SSO states, name theirs and must not
+	// pass for a session
+	if len(claims.Audience) > 0 {
+		return nil, fmt.Errorf("token is not an access token")
+	}
 
 	return claims, nil
 }
@@ -205,29 +310,7 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	// Generate new tokens
-	newAccessToken, err := s.GenerateAccessToken(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
-	}
-
-	newRefreshToken, err := s.GenerateRefreshToken(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-
-	// Parse expiry duration
-	duration, err := time.ParseDuration(s.cfg.JWTExpiry)
-	if err != nil {
-		duration = 24 * time.Hour
-	}
-
-	return &models.AuthResponse{
-		User:         user.ToResponse(),
-		AccessToken:  newAccessToken,
-		RefreshToken: newRefreshToken,
-		ExpiresIn:    int64(duration.Seconds()),
-	}, nil
+	return s.IssueTokens(user)
 }
 
 // ChangePassword changes a user's password
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"visekai/backend/internal/config"
+	"visekai/backend/internal/connectors"
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const testJWTSecret = "test-secret-that-is-long-enough-for-hs256"
+
+func newTestAuthService() *AuthService {
+	return NewAuthService(nil, nil, nil, nil, &config.Config{
+		JWTSecret:          testJWTSecret,
+		JWTExpiry:          "1h",
+		RefreshTokenExpiry: "24h",
+	})
+}
+
+func TestValidateTokenAcceptsIssuedTokens(t *testing.T) {
+	auth := newTestAuthService()
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+
+	tokens, err := auth.IssueTokens(user)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	for _, token := range []string{tokens.AccessToken, tokens.RefreshToken} {
+		claims, err := auth.ValidateToken(token)
+		if err != nil {
+			t.Fatalf("ValidateToken: %v", err)
+		}
+		if claims.UserID != user.ID {
+			t.Errorf("user = %s, want %s", claims.UserID, user.ID)
+		}
+	}
+}
+
+func TestValidateTokenRefusesConnectorState(t *testing.T) {
+	auth := newTestAuthService()
+	imports := &ImportService{
+		providers: map[string]connectors.Provider{
+			"google_drive": connectors.NewGoogleDrive("client", "secret", nil),
+		},
+		stateSecret: testJWTSecret,
+		callbackURL: "https://visekai.example.com/api/v1/connectors/callback",
+	}
+
+	userID := uuid.New()
+	authorization, state, err := imports.Authorize(context.Background(), userID, "google_drive", "")
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	authURL, err := url.Parse(authorization.AuthorizationURL)
+	if err != nil || authURL.Query().Get("state") != state {
+		t.Fatalf("authorization URL %q does not carry the state", authorization.AuthorizationURL)
+	}
+
+	if claims, err := auth.ValidateToken(state); err == nil {
+		t.Fatalf("state accepted as an access token for user %s", claims.UserID)
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrBackfillNotFound is returned for a backfill task that is not registered
+	ErrBackfillNotFound = apperrors.NotFound("TASK_001", "backfill task not found")
+
+	// ErrBackfillActive is returned when starting a backfill task that already
+	// has a pending or running run
+	ErrBackfillActive = apperrors.Conflict("TASK_002", "backfill task is already running")
+
+	// ErrBackfillNotActive is returned when cancelling a backfill task that has
+	// no pending or running run
+	ErrBackfillNotActive = apperrors.Conflict("TASK_003", "backfill task is not running")
+)
+
+const (
+	// backfillPollInterval is how often instances look for pending runs,
+	// including runs started on another instance
+	backfillPollInterval = 30 * time.Second
+	// backfillStaleAfter is how long a running run may go without a
+	// heartbeat before another instance takes it over
+	backfillStaleAfter = 5 * time.Minute
+	// backfillRunsListed is the number of runs listed for a task
+	backfillRunsListed = 20
+)
+
+// Backfill is a data migration admins can run over existing data. It goes
+// through the data in batches ordered by a cursor, so a run interrupted by a
+// restart or a failure resumes from the last batch completed.
+type Backfill struct {
+	// Name identifies the task in the API; keep it stable, as runs refer to it
+	Name        string
+	Description string
+	// Count returns the number of items the task goes through, for progress
+	Count func(ctx context.Context) (int, error)
+	// Batch processes up to limit items after cursor, empty for the first
+	// batch, and returns the cursor of the last one
+	Batch func(ctx context.Context, cursor string, limit int) (BackfillBatch, error)
+}
+
+// BackfillBatch is the outcome of a batch of a backfill task
+type BackfillBatch struct {
+	// Cursor is the position of the last item processed
+	Cursor string
+	// Processed counts the items processed, Updated those changed and Failed
+	// those that could not be processed, which do not stop the run
+	Processed int
+	Updated   int
+	Failed    int
+	// Done is set once there are no items left
+	Done bool
+}
+
+// BackfillService runs the registered backfill tasks admins start. Runs are
+// stored, so any instance picks up a pending run, and one whose instance
+// stopped is taken over once its heartbeat goes stale.
+type BackfillService struct {
+	repo      *repository.BackfillRepository
+	backfills map[string]Backfill
+	batchSize int
+	wake      chan struct{}
+	mu        sync.RWMutex
+}
+
+// NewBackfillService creates a new backfill service processing batchSize
+// items per batch
+func NewBackfillService(repo *repository.BackfillRepository, batchSize int) *BackfillService {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	return &BackfillService{
+		repo:      repo,
+		backfills: make(map[string]Backfill),
+		batchSize: batchSize,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Register makes a backfill task available to admins
+func (s *BackfillService) Register(backfill Backfill) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backfills[backfill.Name] = backfill
+}
+
+// List returns the registered tasks, sorted by name, with their latest runs
+func (s *BackfillService) List(ctx context.Context) ([]*models.BackfillTask, error) {
+	latest, err := s.repo.Latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	backfillTasks := make([]*models.BackfillTask, 0, len(s.backfills))
+	for _, backfill := range s.backfills {
+		backfillTasks = append(backfillTasks, &models.BackfillTask{
+			Name:        backfill.Name,
+			Description: backfill.Description,
+			LatestRun:   latest[backfill.Name],
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(backfillTasks, func(i, j int) bool {
+		return backfillTasks[i].Name < backfillTasks[j].Name
+	})
+	return backfillTasks, nil
+}
+
+// Runs returns a task's most recent runs
+func (s *BackfillService) Runs(ctx context.Context, name string) (*models.BackfillTask, []*models.BackfillRun, error) {
+	backfill, ok := s.backfill(name)
+	if !ok {
+		return nil, nil, ErrBackfillNotFound
+	}
+
+	runs, err := s.repo.ListByTask(ctx, name, backfillRunsListed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	task := &models.BackfillTask{Name: backfill.Name, Description: backfill.Description}
+	if len(runs) > 0 {
+		task.LatestRun = runs[0]
+	}
+	return task, runs, nil
+}
+
+// Run starts a task. A failed or cancelled latest run is resumed from where
+// it stopped, unless restart is set, which starts over.
+func (s *BackfillService) Run(ctx context.Context, name string, userID uuid.UUID, restart bool) (*models.BackfillRun, error) {
+	backfill, ok := s.backfill(name)
+	if !ok {
+		return nil, ErrBackfillNotFound
+	}
+
+	runs, err := s.repo.ListByTask(ctx, name, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(runs) > 0 {
+		run := runs[0]
+		if run.Active() {
+			return nil, ErrBackfillActive
+		}
+		if !restart && (run.Status == models.BackfillFailed || run.Status == models.BackfillCancelled) {
+			resumed, err := s.repo.Resume(ctx, run)
+			if err != nil {
+				return nil, err
+			}
+			if !resumed {
+				return nil, ErrBackfillActive
+			}
+			logger.Info("Backfill resumed", "task", name, "run_id", run.ID, "user_id", userID)
+			s.notify()
+			return run, nil
+		}
+	}
+
+	total, err := backfill.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &models.BackfillRun{Task: name, Total: total, StartedBy: &userID}
+	created, err := s.repo.Create(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrBackfillActive
+	}
+
+	logger.Info("Backfill started", "task", name, "run_id", run.ID, "user_id", userID)
+	s.notify()
+	return run, nil
+}
+
+// Cancel cancels the pending or running run of a task. A running run stops
+// after its current batch.
+func (s *BackfillService) Cancel(ctx context.Context, name string, userID uuid.UUID) (*models.BackfillRun, error) {
+	if _, ok := s.backfill(name); !ok {
+		return nil, ErrBackfillNotFound
+	}
+
+	run, err := s.repo.Cancel(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil {
+		return nil, ErrBackfillNotActive
+	}
+
+	logger.Info("Backfill cancelled", "task", name, "run_id", run.ID, "user_id", userID)
+	return run, nil
+}
+
+// Start runs pending runs one at a time, until the context is cancelled. A
+// run interrupted by the cancellation is released for the next instance.
+func (s *BackfillService) Start(ctx context.Context) {
+	logger.Info("Backfill runner started")
+
+	ticker := time.NewTicker(backfillPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			run, err := s.repo.Claim(ctx, time.Now().Add(-backfillStaleAfter))
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("Failed to claim backfill run", "error", err)
+				}
+				break
+			}
+			if run == nil {
+				break
+			}
+			s.execute(ctx, run)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Backfill runner stopped")
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// execute runs the batches of a claimed run until it is done, cancelled or
+// fails
+func (s *BackfillService) execute(ctx context.Context, run *models.BackfillRun) {
+	backfill, ok := s.backfill(run.Task)
+	if !ok {
+		// Registered by a newer version; leave it to an instance running it
+		logger.Warn("Backfill task not registered", "task", run.Task, "run_id", run.ID)
+		if err := s.repo.Release(context.Background(), run.ID); err != nil {
+			logger.Error("Failed to release backfill run", "run_id", run.ID, "error", err)
+		}
+		return
+	}
+
+	logger.Info("Backfill running", "task", run.Task, "run_id", run.ID, "cursor", run.Cursor)
+
+	cancelled := false
+	err := tasks.Run(ctx, tasks.Task{
+		Name: "backfill." + run.Task,
+		Run: func(ctx context.Context) error {
+			for {
+				batch, err := backfill.Batch(ctx, run.Cursor, s.batchSize)
+				if err != nil {
+					return err
+				}
+
+				run.Cursor = batch.Cursor
+				run.Processed += batch.Processed
+				run.Updated += batch.Updated
+				run.Failed += batch.Failed
+
+				running, err := s.repo.SaveProgress(ctx, run)
+				if err != nil {
+					return err
+				}
+				if !running {
+					cancelled = true
+					return nil
+				}
+				if batch.Done {
+					return s.repo.Finish(ctx, run.ID, models.BackfillCompleted, "")
+				}
+			}
+		},
+	})
+
+	switch {
+	case ctx.Err() != nil:
+		// Shutting down; hand the run over from its last saved batch
+		if err := s.repo.Release(context.Background(), run.ID); err != nil {
+			logger.Error("Failed to release backfill run", "run_id", run.ID, "error", err)
+		}
+	case err != nil:
+		if err := s.repo.Finish(context.Background(), run.ID, models.BackfillFailed, err.Error()); err != nil {
+			logger.Error("Failed to mark backfill run failed", "run_id", run.ID, "error", err)
+		}
+	case cancelled:
+		logger.Info("Backfill stopped after cancellation", "task", run.Task, "run_id", run.ID, "processed", run.Processed)
+	default:
+		logger.Info("Backfill finished", "task", run.Task, "run_id", run.ID,
+			"processed", run.Processed, "updated", run.Updated, "failed", run.Failed)
+	}
+}
+
+// backfill returns a registered task
+func (s *BackfillService) backfill(name string) (Backfill, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	backfill, ok := s.backfills[name]
+	return backfill, ok
+}
+
+// notify wakes the runner up to pick a new run
+func (s *BackfillService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
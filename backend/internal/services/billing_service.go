@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/billing"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownCreditPack is returned for checkouts of a pack that is not sold
+	ErrUnknownCreditPack = apperrors.Validation("VAL_027", "unknown credit pack")
+	// ErrInvalidBillingSignature is returned for webhook events not signed by Stripe
+	ErrInvalidBillingSignature = apperrors.Validation("BILLING_001", "invalid webhook signature")
+)
+
+// Stripe checkout metadata keys naming what a payment buys
+const (
+	checkoutMetaUserID  = "user_id"
+	checkoutMetaCredits = "credits"
+)
+
+// BillingService sells credit packs through Stripe checkout. Users are sent to
+// a hosted payment page, and Stripe's webhook events add the credits once the
+// payment succeeds.
+type BillingService struct {
+	stripe        *billing.Stripe
+	creditService *CreditService
+	userRepo      *repository.UserRepository
+	packs         []models.CreditPack
+	successURL    string
+	cancelURL     string
+}
+
+// NewBillingService creates a new billing service selling packs of credits,
+// mapped to their price in the currency's smallest unit
+func NewBillingService(
+	stripe *billing.Stripe,
+	creditService *CreditService,
+	userRepo *repository.UserRepository,
+	packs map[int]int,
+	currency string,
+	successURL string,
+	cancelURL string,
+) *BillingService {
+	s := &BillingService{
+		stripe:        stripe,
+		creditService: creditService,
+		userRepo:      userRepo,
+		successURL:    successURL,
+		cancelURL:     cancelURL,
+	}
+	for credits, price := range packs {
+		s.packs = append(s.packs, models.CreditPack{
+			ID:       strconv.Itoa(credits),
+			Credits:  credits,
+			Price:    price,
+			Currency: currency,
+		})
+	}
+	sort.Slice(s.packs, func(i, j int) bool { return s.packs[i].Credits < s.packs[j].Credits })
+	return s
+}
+
+// Packs returns the credit packs for sale, smallest first
+func (s *BillingService) Packs() []models.CreditPack {
+	return s.packs
+}
+
+// Checkout starts the purchase of a credit pack, returning the payment page
+// to send the user to
+func (s *BillingService) Checkout(ctx context.Context, userID uuid.UUID, packID string) (*models.CheckoutSession, error) {
+	var pack *models.CreditPack
+	for i := range s.packs {
+		if s.packs[i].ID == packID {
+			pack = &s.packs[i]
+		}
+	}
+	if pack == nil {
+		return nil, ErrUnknownCreditPack
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.stripe.CreateCheckoutSession(ctx, billing.CheckoutParams{
+		ProductName:       fmt.Sprintf("%d OCR credits", pack.Credits),
+		UnitAmount:        pack.Price,
+		Currency:          pack.Currency,
+		CustomerEmail:     user.Email,
+		ClientReferenceID: user.ID.String(),
+		SuccessURL:        s.successURL,
+		CancelURL:         s.cancelURL,
+		Metadata: map[string]string{
+			checkoutMetaUserID:  user.ID.String(),
+			checkoutMetaCredits: strconv.Itoa(pack.Credits),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkout session: %w", err)
+	}
+
+	checkout := &models.CheckoutSession{
+		ID:   session.ID,
+		URL:  session.URL,
+		Pack: *pack,
+	}
+	if session.ExpiresAt > 0 {
+		expiresAt := time.Unix(session.ExpiresAt, 0).UTC()
+		checkout.ExpiresAt = &expiresAt
+	}
+
+	logger.Info("Credit checkout started", "user_id", userID, "credits", pack.Credits, "session_id", session.ID)
+	return checkout, nil
+}
+
+// HandleWebhook verifies and processes a Stripe webhook event. Completed
+// checkouts add their credits once paid; payment methods that settle later are
+// credited by the async_payment_succeeded event. Other events are ignored.
+// An error makes Stripe retry the event, so events no retry could process are
+// logged and acknowledged instead.
+func (s *BillingService) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	event, err := s.stripe.ParseEvent(payload, signature, time.Now())
+	if errors.Is(err, billing.ErrInvalidSignature) {
+		return ErrInvalidBillingSignature
+	}
+	if err != nil {
+		return apperrors.Validation("VAL_001", err.Error())
+	}
+
+	switch event.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+	default:
+		logger.Debug("Ignoring Stripe event", "event_id", event.ID, "type", event.Type)
+		return nil
+	}
+
+	var session billing.CheckoutSession
+	if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+		logger.Warn("Ignoring malformed Stripe checkout session", "event_id", event.ID, "error", err)
+		return nil
+	}
+	if session.PaymentStatus != "paid" {
+		logger.Info("Checkout completed without payment yet", "session_id", session.ID, "payment_status", session.PaymentStatus)
+		return nil
+	}
+
+	userID, err := uuid.Parse(session.Metadata[checkoutMetaUserID])
+	credits, creditsErr := strconv.Atoi(session.Metadata[checkoutMetaCredits])
+	if err != nil || creditsErr != nil || credits <= 0 {
+		logger.Warn("Ignoring checkout session without credit metadata", "session_id", session.ID)
+		return nil
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			logger.Warn("Paid checkout session of a deleted user", "session_id", session.ID, "user_id", userID)
+			return nil
+		}
+		return err
+	}
+
+	credited, err := s.creditService.Purchase(ctx, userID, credits, session.ID, fmt.Sprintf("%d credits purchased", credits))
+	if err != nil {
+		return err
+	}
+	if credited {
+		logger.Info("Credits purchased", "user_id", userID, "credits", credits, "session_id", session.ID,
+			"amount", session.AmountTotal, "currency", session.Currency)
+	}
+	return nil
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoCustomDomain is returned when verifying without a custom domain configured
+	ErrNoCustomDomain = apperrors.Validation("VAL_022", "no custom domain is configured")
+	// ErrDomainNotVerified is returned when the verification TXT record is missing
+	ErrDomainNotVerified = errors.New("domain verification record not found")
+)
+
+// domainVerificationPrefix names the TXT record proving control of a custom
+// domain, published at _visekai-verification.<domain>
+const domainVerificationPrefix = "_visekai-verification."
+
+// BrandingService manages the branding and custom domain of organizations,
+// set by their owners, and the instance-wide branding admins set for users
+// outside any organization
+type BrandingService struct {
+	settingsRepo *repository.InstanceSettingsRepository
+	orgRepo      *repository.OrganizationRepository
+	resolver     *net.Resolver
+}
+
+// NewBrandingService creates a new branding service
+func NewBrandingService(settingsRepo *repository.InstanceSettingsRepository, orgRepo *repository.OrganizationRepository) *BrandingService {
+	return &BrandingService{
+		settingsRepo: settingsRepo,
+		orgRepo:      orgRepo,
+		resolver:     net.DefaultResolver,
+	}
+}
+
+// Get returns the instance-wide branding, which is empty until an admin sets it
+func (s *BrandingService) Get(ctx context.Context) (*models.Branding, error) {
+	value, ok, err := s.settingsRepo.Get(ctx, models.SettingBranding)
+	if err != nil {
+		return nil, err
+	}
+
+	branding := &models.Branding{}
+	if !ok {
+		return branding, nil
+	}
+	if err := json.Unmarshal([]byte(value), branding); err != nil {
+		return nil, fmt.Errorf("failed to decode branding: %w", err)
+	}
+
+	return branding, nil
+}
+
+// Update applies the fields present in the request to the instance-wide
+// branding and returns the new branding. A new custom domain gets a fresh
+// verification token and is unverified.
+func (s *BrandingService) Update(ctx context.Context, req models.UpdateBrandingRequest, adminID uuid.UUID) (*models.Branding, error) {
+	branding, err := s.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyBrandingUpdate(branding, req, adminID); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, branding); err != nil {
+		return nil, err
+	}
+
+	return branding, nil
+}
+
+// VerifyDomain looks up the verification TXT record of the instance-wide
+// custom domain and marks the domain verified when it holds the expected value
+func (s *BrandingService) VerifyDomain(ctx context.Context) (*models.Branding, error) {
+	branding, err := s.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := s.verify(ctx, branding)
+	if err != nil || done {
+		return branding, err
+	}
+
+	// An organization may have verified the domain first
+	orgID, err := s.orgRepo.GetOrgIDByDomain(ctx, branding.CustomDomain.Domain)
+	if err != nil {
+		return nil, err
+	}
+	if orgID != nil {
+		return nil, repository.ErrCustomDomainClaimed
+	}
+
+	if err := s.save(ctx, branding); err != nil {
+		return nil, err
+	}
+
+	return branding, nil
+}
+
+// GetForOrg returns the branding of an organization the user is a member of
+func (s *BrandingService) GetForOrg(ctx context.Context, orgID, userID uuid.UUID) (*models.Branding, error) {
+	if _, err := s.orgRepo.GetForMember(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.orgBranding(ctx, orgID)
+}
+
+// UpdateForOrg applies the fields present in the request to the branding of an
+// organization the user owns and returns the new branding
+func (s *BrandingService) UpdateForOrg(ctx context.Context, orgID, userID uuid.UUID, req models.UpdateBrandingRequest) (*models.Branding, error) {
+	if err := s.requireOwner(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+
+	branding, err := s.orgBranding(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyBrandingUpdate(branding, req, userID); err != nil {
+		return nil, err
+	}
+	if err := s.saveOrg(ctx, orgID, branding); err != nil {
+		return nil, err
+	}
+
+	return branding, nil
+}
+
+// VerifyDomainForOrg looks up the verification TXT record of the custom domain
+// of an organization the user owns and marks the domain verified when it
+// holds the expected value. A domain belongs to one organization.
+func (s *BrandingService) VerifyDomainForOrg(ctx context.Context, orgID, userID uuid.UUID) (*models.Branding, error) {
+	if err := s.requireOwner(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+
+	branding, err := s.orgBranding(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := s.verify(ctx, branding)
+	if err != nil || done {
+		return branding, err
+	}
+
+	// The instance may serve the domain already
+	instance, err := s.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if instance.CustomDomain.Verified() && instance.CustomDomain.Domain == branding.CustomDomain.Domain {
+		return nil, repository.ErrCustomDomainClaimed
+	}
+
+	if err := s.saveOrg(ctx, orgID, branding); err != nil {
+		return nil, err
+	}
+
+	return branding, nil
+}
+
+// ForHost returns the public branding for a request made to host: that of the
+// organization whose verified custom domain host is, otherwise the
+// instance-wide branding
+func (s *BrandingService) ForHost(ctx context.Context, host string) (*models.PublicBranding, error) {
+	orgID, err := s.orgRepo.GetOrgIDByDomain(ctx, normalizeHost(host))
+	if err != nil {
+		return nil, err
+	}
+
+	var branding *models.Branding
+	if orgID != nil {
+		branding, err = s.orgBranding(ctx, *orgID)
+	} else {
+		branding, err = s.Get(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.public(ctx, branding, host)
+}
+
+// ForDocument returns the public branding a document is presented with when
+// opened on host: that of the organization the document is shared with,
+// otherwise the owner's, and the instance-wide branding for owners outside
+// any organization. CustomDomain reports whether host is that branding's
+// verified custom domain.
+func (s *BrandingService) ForDocument(ctx context.Context, document *models.Document, host string) (*models.PublicBranding, error) {
+	branding, err := s.forDocument(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+	return s.public(ctx, branding, host)
+}
+
+// ForExports returns the branding exports of a document are headed with, or
+// nil when its branding disables branded exports
+func (s *BrandingService) ForExports(ctx context.Context, document *models.Document) (*models.PublicBranding, error) {
+	branding, err := s.forDocument(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+	if !branding.BrandExports {
+		return nil, nil
+	}
+	return s.public(ctx, branding, "")
+}
+
+func (s *BrandingService) forDocument(ctx context.Context, document *models.Document) (*models.Branding, error) {
+	orgID := document.OrgID
+	if orgID == nil {
+		var err error
+		if orgID, err = s.orgRepo.GetFirstOrgID(ctx, document.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if orgID == nil {
+		return s.Get(ctx)
+	}
+	return s.orgBranding(ctx, *orgID)
+}
+
+// orgBranding returns the branding of an organization, starting from the
+// instance-wide branding when the organization never saved one
+func (s *BrandingService) orgBranding(ctx context.Context, orgID uuid.UUID) (*models.Branding, error) {
+	value, ok, err := s.orgRepo.GetBranding(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var branding *models.Branding
+	if ok {
+		branding = &models.Branding{}
+		if err := json.Unmarshal(value, branding); err != nil {
+			return nil, fmt.Errorf("failed to decode branding: %w", err)
+		}
+	} else {
+		if branding, err = s.Get(ctx); err != nil {
+			return nil, err
+		}
+		// The instance's custom domain is not the organization's
+		branding.CustomDomain = nil
+		branding.UpdatedAt, branding.UpdatedBy = nil, nil
+	}
+
+	branding.OrgID = &orgID
+	return branding, nil
+}
+
+// verify checks the verification record of the custom domain of a branding
+// and marks the domain verified; done reports that it was verified already
+func (s *BrandingService) verify(ctx context.Context, branding *models.Branding) (done bool, err error) {
+	domain := branding.CustomDomain
+	if domain == nil {
+		return false, ErrNoCustomDomain
+	}
+	if domain.Verified() {
+		return true, nil
+	}
+
+	if err := lookupVerificationRecord(ctx, s.resolver, domain.VerificationRecord, domain.VerificationValue); err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	domain.VerifiedAt = &now
+	return false, nil
+}
+
+func (s *BrandingService) requireOwner(ctx context.Context, orgID, userID uuid.UUID) error {
+	org, err := s.orgRepo.GetForMember(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if org.Role != models.OrgRoleOwner {
+		return ErrOrgRoleForbidden
+	}
+	return nil
+}
+
+func (s *BrandingService) public(ctx context.Context, branding *models.Branding, host string) (*models.PublicBranding, error) {
+	public := &models.PublicBranding{
+		DisplayName:  branding.DisplayName,
+		LogoURL:      branding.LogoURL,
+		PrimaryColor: branding.PrimaryColor,
+		AccentColor:  branding.AccentColor,
+	}
+	if public.DisplayName == "" {
+		name, _, err := s.settingsRepo.Get(ctx, models.SettingInstanceName)
+		if err != nil {
+			return nil, err
+		}
+		public.DisplayName = name
+	}
+	if branding.CustomDomain.Verified() {
+		public.Domain = branding.CustomDomain.Domain
+		public.CustomDomain = normalizeHost(host) == public.Domain
+	}
+
+	return public, nil
+}
+
+func (s *BrandingService) save(ctx context.Context, branding *models.Branding) error {
+	value, err := json.Marshal(branding)
+	if err != nil {
+		return fmt.Errorf("failed to encode branding: %w", err)
+	}
+	return s.settingsRepo.Set(ctx, models.SettingBranding, string(value))
+}
+
+func (s *BrandingService) saveOrg(ctx context.Context, orgID uuid.UUID, branding *models.Branding) error {
+	value, err := json.Marshal(branding)
+	if err != nil {
+		return fmt.Errorf("failed to encode branding: %w", err)
+	}
+	var verifiedDomain *string
+	if branding.CustomDomain.Verified() {
+		verifiedDomain = &branding.CustomDomain.Domain
+	}
+	return s.orgRepo.SetBranding(ctx, orgID, value, verifiedDomain)
+}
+
+// applyBrandingUpdate applies the fields present in the request to the branding
+func applyBrandingUpdate(branding *models.Branding, req models.UpdateBrandingRequest, userID uuid.UUID) error {
+	if req.DisplayName != nil {
+		branding.DisplayName = strings.TrimSpace(*req.DisplayName)
+	}
+	if req.LogoURL != nil {
+		branding.LogoURL = *req.LogoURL
+	}
+	if req.PrimaryColor != nil {
+		branding.PrimaryColor = strings.ToLower(*req.PrimaryColor)
+	}
+	if req.AccentColor != nil {
+		branding.AccentColor = strings.ToLower(*req.AccentColor)
+	}
+	if req.BrandExports != nil {
+		branding.BrandExports = *req.BrandExports
+	}
+	if req.CustomDomain != nil {
+		domain := normalizeHost(*req.CustomDomain)
+		switch {
+		case domain == "":
+			branding.CustomDomain = nil
+		case branding.CustomDomain == nil || branding.CustomDomain.Domain != domain:
+			token, err := verificationToken()
+			if err != nil {
+				return err
+			}
+			branding.CustomDomain = &models.CustomDomain{
+				Domain:             domain,
+				VerificationRecord: domainVerificationPrefix + domain,
+				VerificationValue:  "visekai-verification=" + token,
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	branding.UpdatedAt = &now
+	branding.UpdatedBy = &userID
+	return nil
+}
+
+// normalizeHost lower-cases a host and strips its port and trailing dot
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// lookupVerificationRecord checks that the TXT record proving control of a
+// domain holds the value, returning ErrDomainNotVerified when it does not
+func lookupVerificationRecord(ctx context.Context, resolver *net.Resolver, name, value string) error {
+	records, err := resolver.LookupTXT(ctx, name)
+	var dnsErr *net.DNSError
+	if err != nil && !(errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: publish a TXT record %s with the value %q", ErrDomainNotVerified, name, value)
+}
+
+// verificationToken returns a random token for a domain verification record
+func verificationToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"visekai/backend/internal/classifier"
+	"visekai/backend/internal/models"
+	"visekai/backend/pkg/logger"
+)
+
+// ClassificationService detects the type of documents, such as invoice or
+// contract, from the text of their results with the configured classifier
+type ClassificationService struct {
+	classifier classifier.Classifier
+}
+
+// NewClassificationService creates a new classification service. A nil
+// classifier disables classification.
+func NewClassificationService(classifier classifier.Classifier) *ClassificationService {
+	return &ClassificationService{classifier: classifier}
+}
+
+// Classify detects the type of a result's document. It returns nil when
+// classification is disabled or the classifier failed, which is logged rather
+// than failing the job.
+func (s *ClassificationService) Classify(ctx context.Context, result *models.OCRResult) *models.DocumentClassification {
+	if s.classifier == nil {
+		return nil
+	}
+
+	detected, err := s.classifier.Classify(ctx, result.RawText)
+	if err != nil {
+		logger.Warn("Failed to classify document", "document_id", result.DocumentID, "job_id", result.JobID, "error", err)
+		return nil
+	}
+
+	logger.Debug("Classified document",
+		"document_id", result.DocumentID, "type", detected.Type, "confidence", detected.Confidence)
+	return &models.DocumentClassification{
+		Type:         detected.Type,
+		Confidence:   detected.Confidence,
+		Classifier:   s.classifier.Name(),
+		ClassifiedAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// CreditService keeps each user's credit ledger. Jobs cost credits per page,
+// weighted by resolution: they are charged when submitted, adjusted to the
+// pages actually processed when they complete, and refunded by the database
+// when they fail or are cancelled. Users get a free allotment each calendar
+// month (UTC), which tops their balance up to it rather than accumulating.
+// Purchased credits do not expire: jobs spend the free credits first, and the
+// allotment tops up only the free part of the balance.
+type CreditService struct {
+	creditRepo       *repository.CreditRepository
+	transactor       *repository.Transactor
+	costPerPage      map[models.ResolutionMode]int
+	monthlyAllotment int
+	enforced         bool
+}
+
+// NewCreditService creates a new credit service. Resolutions missing from
+// costPerPage cost one credit per page. Unless enforced, jobs are charged but
+// not refused when the balance is too low.
+func NewCreditService(
+	creditRepo *repository.CreditRepository,
+	transactor *repository.Transactor,
+	costPerPage map[models.ResolutionMode]int,
+	monthlyAllotment int,
+	enforced bool,
+) *CreditService {
+	return &CreditService{
+		creditRepo:       creditRepo,
+		transactor:       transactor,
+		costPerPage:      costPerPage,
+		monthlyAllotment: monthlyAllotment,
+		enforced:         enforced,
+	}
+}
+
+// Cost returns the credits a job over the given pages costs at a resolution
+func (s *CreditService) Cost(pages int, resolution models.ResolutionMode) int {
+	rate, ok := s.costPerPage[resolution]
+	if !ok {
+		rate = 1
+	}
+	return max(pages, 1) * rate
+}
+
+// Charge debits a new job's cost from its owner's balance, refusing the job
+// when enforced and the balance is too low. It must run in the transaction
+// creating the job.
+func (s *CreditService) Charge(ctx context.Context, job *models.OCRJob, pages int) error {
+	if err := s.creditRepo.LockUser(ctx, job.UserID); err != nil {
+		return err
+	}
+	if err := s.topUp(ctx, job.UserID); err != nil {
+		return err
+	}
+
+	cost := s.Cost(pages, job.ResolutionMode)
+	if s.enforced {
+		balance, err := s.creditRepo.Balance(ctx, job.UserID)
+		if err != nil {
+			return err
+		}
+		if balance < cost {
+			return apperrors.PaymentRequired("CREDIT_001",
+				fmt.Sprintf("this job costs %d credits but only %d are available", cost, max(balance, 0)))
+		}
+	}
+
+	description := fmt.Sprintf("%d page(s) at %s resolution", max(pages, 1), job.ResolutionMode)
+	return s.creditRepo.Create(ctx, &models.CreditTransaction{
+		UserID:      job.UserID,
+		Kind:        models.CreditCharge,
+		Amount:      -cost,
+		JobID:       &job.ID,
+		Description: &description,
+	})
+}
+
+// Settle adjusts a completed job's charge to the pages it processed. Jobs
+// submitted before credits were tracked are left alone.
+func (s *CreditService) Settle(ctx context.Context, job *models.OCRJob, pages int) error {
+	if err := s.creditRepo.LockUser(ctx, job.UserID); err != nil {
+		return err
+	}
+
+	charged, err := s.creditRepo.JobTotal(ctx, job.ID)
+	if err != nil || charged == 0 {
+		return err
+	}
+
+	diff := charged + s.Cost(pages, job.ResolutionMode)
+	if diff == 0 {
+		return nil
+	}
+
+	description := fmt.Sprintf("%d page(s) processed", max(pages, 1))
+	return s.creditRepo.Create(ctx, &models.CreditTransaction{
+		UserID:      job.UserID,
+		Kind:        models.CreditAdjustment,
+		Amount:      -diff,
+		JobID:       &job.ID,
+		Description: &description,
+	})
+}
+
+// Purchase adds credits a user paid for. A purchase is credited once per
+// reference; it reports whether this call credited it.
+func (s *CreditService) Purchase(ctx context.Context, userID uuid.UUID, credits int, reference, description string) (bool, error) {
+	credited := false
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.creditRepo.LockUser(ctx, userID); err != nil {
+			return err
+		}
+
+		exists, err := s.creditRepo.HasPurchase(ctx, reference)
+		if err != nil || exists {
+			return err
+		}
+
+		err = s.creditRepo.Create(ctx, &models.CreditTransaction{
+			UserID:      userID,
+			Kind:        models.CreditPurchase,
+			Amount:      credits,
+			Description: &description,
+			Reference:   &reference,
+		})
+		credited = err == nil
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return credited, nil
+}
+
+// Usage returns a user's balance and a page of their ledger, granting the
+// month's allotment first if it is due
+func (s *CreditService) Usage(ctx context.Context, userID uuid.UUID, page, perPage int) (*models.CreditUsage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	start := periodStart(time.Now())
+	usage := &models.CreditUsage{
+		Enforced:         s.enforced,
+		MonthlyAllotment: s.monthlyAllotment,
+		PeriodStart:      start,
+		NextAllotmentAt:  start.AddDate(0, 1, 0),
+		CostPerPage:      make(map[models.ResolutionMode]int),
+	}
+	for _, resolution := range []models.ResolutionMode{models.ResolutionTiny, models.ResolutionSmall, models.ResolutionBase, models.ResolutionLarge, models.ResolutionGundam} {
+		usage.CostPerPage[resolution] = s.Cost(1, resolution)
+	}
+
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.creditRepo.LockUser(ctx, userID); err != nil {
+			return err
+		}
+		if err := s.topUp(ctx, userID); err != nil {
+			return err
+		}
+
+		var err error
+		if usage.Balance, err = s.creditRepo.Balance(ctx, userID); err != nil {
+			return err
+		}
+		if usage.UsedThisPeriod, err = s.creditRepo.UsedSince(ctx, userID, start); err != nil {
+			return err
+		}
+
+		var total int
+		usage.Transactions, total, err = s.creditRepo.ListByUser(ctx, userID, page, perPage)
+		if err != nil {
+			return err
+		}
+		totalPages := (total + perPage - 1) / perPage
+		usage.Pagination = &models.Pagination{
+			Page:       page,
+			PerPage:    perPage,
+			Total:      total,
+			TotalPages: totalPages,
+			HasNext:    page < totalPages,
+			HasPrev:    page > 1,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// topUp grants the current month's allotment if the user has not had it,
+// raising the free part of the balance to the allotment. The user's credits
+// must be locked.
+func (s *CreditService) topUp(ctx context.Context, userID uuid.UUID) error {
+	if s.monthlyAllotment <= 0 {
+		return nil
+	}
+
+	period := periodStart(time.Now())
+	granted, err := s.creditRepo.HasAllotment(ctx, userID, period)
+	if err != nil || granted {
+		return err
+	}
+
+	balance, err := s.creditRepo.Balance(ctx, userID)
+	if err != nil {
+		return err
+	}
+	purchased, err := s.creditRepo.PurchasedRemaining(ctx, userID)
+	if err != nil {
+		return err
+	}
+	free := balance - min(purchased, max(balance, 0))
+
+	// The allotment is recorded even when nothing is added, so it is granted once
+	description := "Free monthly allotment"
+	return s.creditRepo.Create(ctx, &models.CreditTransaction{
+		UserID:      userID,
+		Kind:        models.CreditAllotment,
+		Amount:      max(s.monthlyAllotment-free, 0),
+		Period:      &period,
+		Description: &description,
+	})
+}
+
+// periodStart returns the start of the calendar month (UTC) allotments are granted for
+func periodStart(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// submissionPages returns the pages a submitted job covers: the whole
+// document, or one page for page reprocessing jobs
+func submissionPages(req models.JobSubmissionRequest, document *models.Document) int {
+	if _, singlePage := req.Metadata[models.JobMetaReprocessPage]; singlePage {
+		return 1
+	}
+	return document.NumPages
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// dataExportPollInterval is how often pending exports are looked for when
+	// no request wakes the builder
+	dataExportPollInterval = time.Minute
+	// dataExportStaleAfter is how long an export may be processing before it
+	// is assumed abandoned by a stopped instance and built again
+	dataExportStaleAfter = time.Hour
+)
+
+// ErrDataExportLinkInvalid is returned for download links whose signature
+// does not match or that expired
+var ErrDataExportLinkInvalid = apperrors.Forbidden("EXP_003", "download link is invalid or has expired")
+
+// DataExportService builds takeout archives of everything users stored: the
+// original files of their documents, the latest result of each as markdown
+// and JSON, and their account data. Archives are built in the background and
+// downloaded through a signed link until they expire.
+type DataExportService struct {
+	exportRepo     *repository.DataExportRepository
+	resultRepo     *repository.ResultRepository
+	retentionRepo  *repository.RetentionRepository
+	accountService *AccountService
+	storage        *storage.Storage
+	secret         []byte
+	ttl            time.Duration
+	wake           chan struct{}
+}
+
+// NewDataExportService creates a new data export service signing download
+// links with secret. Archives can be downloaded for ttl once built, or for
+// as long as the user's data export retention policy keeps them.
+func NewDataExportService(
+	exportRepo *repository.DataExportRepository,
+	resultRepo *repository.ResultRepository,
+	retentionRepo *repository.RetentionRepository,
+	accountService *AccountService,
+	storage *storage.Storage,
+	secret string,
+	ttl time.Duration,
+) *DataExportService {
+	if ttl <= 0 {
+		ttl = 48 * time.Hour
+	}
+	return &DataExportService{
+		exportRepo:     exportRepo,
+		resultRepo:     resultRepo,
+		retentionRepo:  retentionRepo,
+		accountService: accountService,
+		storage:        storage,
+		secret:         []byte(secret),
+		ttl:            ttl,
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// Start builds pending exports as they are requested and removes expired
+// ones, until the context is cancelled
+func (s *DataExportService) Start(ctx context.Context) {
+	logger.Info("Data exports started", "ttl", s.ttl)
+
+	ticker := time.NewTicker(dataExportPollInterval)
+	defer ticker.Stop()
+
+	for {
+		_ = tasks.Run(ctx, tasks.Task{
+			Name: "data_exports.build",
+			Run:  s.buildPending,
+		})
+		_ = tasks.Run(ctx, tasks.Task{
+			Name: "data_exports.expire",
+			Run:  s.removeExpired,
+		})
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Data exports stopped")
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Request queues an export of the user's data. A user has at most one export
+// being built at a time; requesting another returns it.
+func (s *DataExportService) Request(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	active, err := s.exportRepo.GetActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return active, nil
+	}
+
+	export := &models.DataExport{UserID: userID}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	logger.Info("Data export requested", "export_id", export.ID, "user_id", userID)
+	return export, nil
+}
+
+// List returns the user's exports, newest first, with the download links of
+// those that are ready
+func (s *DataExportService) List(ctx context.Context, userID uuid.UUID, origin string) ([]*models.DataExport, error) {
+	exports, err := s.exportRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, export := range exports {
+		s.setDownloadURL(export, origin)
+	}
+	return exports, nil
+}
+
+// Get returns one of the user's exports, with its download link once ready
+func (s *DataExportService) Get(ctx context.Context, id, userID uuid.UUID, origin string) (*models.DataExport, error) {
+	export, err := s.exportRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if export.UserID != userID {
+		return nil, repository.ErrDataExportNotFound
+	}
+
+	s.setDownloadURL(export, origin)
+	return export, nil
+}
+
+// Open verifies a download link's parameters and opens the archive it names
+func (s *DataExportService) Open(ctx context.Context, id uuid.UUID, expires, signature string) (*models.DataExport, io.ReadCloser, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(s.sign(id, expiresAt)), []byte(signature)) {
+		return nil, nil, ErrDataExportLinkInvalid
+	}
+	if !time.Now().Before(time.Unix(expiresAt, 0)) {
+		return nil, nil, ErrDataExportLinkInvalid
+	}
+
+	export, err := s.exportRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != models.DataExportCompleted || export.ExpiresAt == nil || export.ExpiresAt.Unix() != expiresAt {
+		return nil, nil, ErrDataExportLinkInvalid
+	}
+
+	file, err := s.storage.Open(export.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return export, file, nil
+}
+
+// buildPending builds pending exports one at a time until none is left
+func (s *DataExportService) buildPending(ctx context.Context) error {
+	requeued, err := s.exportRepo.RequeueStale(ctx, time.Now().Add(-dataExportStaleAfter))
+	if err != nil {
+		return err
+	}
+	if requeued > 0 {
+		logger.Warn("Requeued abandoned data exports", "count", requeued)
+	}
+
+	for ctx.Err() == nil {
+		export, err := s.exportRepo.ClaimNext(ctx)
+		if err != nil || export == nil {
+			return err
+		}
+
+		if err := s.build(ctx, export); err != nil {
+			logger.Error("Failed to build data export", "export_id", export.ID, "user_id", export.UserID, "error", err)
+			if failErr := s.exportRepo.Fail(ctx, export.ID, "failed to build the archive"); failErr != nil {
+				return failErr
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// build writes the archive of an export and records it as completed
+func (s *DataExportService) build(ctx context.Context, export *models.DataExport) error {
+	account, err := s.accountService.Export(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	written := make(chan int, 1)
+	go func() {
+		documents, err := s.writeArchive(ctx, pw, account)
+		written <- documents
+		pw.CloseWithError(err)
+	}()
+
+	relativePath := path.Join(storage.ExportsDir, export.UserID.String(), export.ID.String()+".zip")
+	counter := &countingReader{r: pr}
+	export.FilePath, err = s.storage.WriteEncryptedFile(relativePath, counter)
+	// Unblock the writer if storing failed before the archive was complete
+	pr.CloseWithError(err)
+	documents := <-written
+	if err != nil {
+		return err
+	}
+
+	ttl := s.ttl
+	days, err := s.retentionRepo.Days(ctx, export.UserID, models.RetentionDataExports)
+	if err != nil {
+		_ = s.storage.DeleteFile(export.FilePath)
+		return err
+	}
+	if days > 0 {
+		ttl = time.Duration(days) * 24 * time.Hour
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl).Truncate(time.Second)
+	export.Status = models.DataExportCompleted
+	export.FileSize = counter.n
+	export.Documents = documents
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	if err := s.exportRepo.Complete(ctx, export); err != nil {
+		_ = s.storage.DeleteFile(export.FilePath)
+		return err
+	}
+
+	logger.Info("Data export completed",
+		"export_id", export.ID, "user_id", export.UserID, "documents", documents, "bytes", export.FileSize)
+	return nil
+}
+
+// writeArchive writes the zip archive of a user's data to w and returns the
+// number of documents in it. Documents in the trash are only listed in
+// account.json.
+func (s *DataExportService) writeArchive(ctx context.Context, w io.Writer, account *models.AccountExport) (int, error) {
+	archive := zip.NewWriter(w)
+
+	entry, err := archive.Create("account.json")
+	if err != nil {
+		return 0, err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(account); err != nil {
+		return 0, err
+	}
+
+	documents := 0
+	for _, document := range account.Documents {
+		if document.DeletedAt != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return documents, err
+		}
+		if err := s.writeDocument(ctx, archive, &document); err != nil {
+			return documents, err
+		}
+		documents++
+	}
+
+	return documents, archive.Close()
+}
+
+// writeDocument adds a document's original file and its latest result to the
+// archive, under documents/<id>/
+func (s *DataExportService) writeDocument(ctx context.Context, archive *zip.Writer, document *models.Document) error {
+	dir := path.Join("documents", document.ID.String())
+
+	file, err := s.storage.Open(document.FilePath)
+	if err != nil {
+		// Keep exporting the rest rather than failing on one missing file
+		logger.Warn("Skipping missing file in data export", "document_id", document.ID, "error", err)
+	} else {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     path.Join(dir, storage.NormalizeFilename(document.OriginalFilename)),
+			Method:   zip.Deflate,
+			Modified: document.UploadedAt,
+		})
+		if err == nil {
+			_, err = io.Copy(entry, file)
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	results, err := s.resultRepo.GetByDocumentID(ctx, document.ID)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	result := results[0]
+
+	entry, err := archive.Create(path.Join(dir, "result.md"))
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(entry, result.MarkdownText); err != nil {
+		return err
+	}
+
+	entry, err = archive.Create(path.Join(dir, "result.json"))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// removeExpired deletes the archives and records of expired exports
+func (s *DataExportService) removeExpired(ctx context.Context) error {
+	expired, err := s.exportRepo.ListExpired(ctx, time.Now(), 100)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range expired {
+		if export.FilePath != "" {
+			if err := s.storage.DeleteFile(export.FilePath); err != nil {
+				logger.Warn("Failed to delete expired data export", "export_id", export.ID, "error", err)
+				continue
+			}
+		}
+		if err := s.exportRepo.Delete(ctx, export.ID); err != nil {
+			return err
+		}
+	}
+
+	if len(expired) > 0 {
+		logger.Info("Removed expired data exports", "count", len(expired))
+	}
+	return nil
+}
+
+// setDownloadURL sets the signed download link of a completed export that has
+// not expired
+func (s *DataExportService) setDownloadURL(export *models.DataExport, origin string) {
+	if export.Status != models.DataExportCompleted || export.ExpiresAt == nil || !time.Now().Before(*export.ExpiresAt) {
+		return
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(export.ExpiresAt.Unix(), 10))
+	query.Set("signature", s.sign(export.ID, export.ExpiresAt.Unix()))
+	export.DownloadURL = strings.TrimSuffix(origin, "/") + "/api/v1/export/" + export.ID.String() + "/download?" + query.Encode()
+}
+
+// sign returns the hex HMAC-SHA256 of an export's ID and expiry. The input is
+// prefixed so that signatures cannot be mistaken for share links' ones.
+func (s *DataExportService) sign(id uuid.UUID, expiresAt int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("export."))
+	mac.Write([]byte(id.String()))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(expiresAt, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
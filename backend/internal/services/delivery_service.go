@@ -0,0 +1,507 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/delivery"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// deliveryBatchSize is the number of deliveries attempted per cycle and
+	// the number of jobs enqueued per target per cycle
+	deliveryBatchSize = 20
+	// deliverySettleDelay is how long after a job completed its result is
+	// delivered, so jobs whose status was committed late are not passed over
+	deliverySettleDelay = 30 * time.Second
+	// deliveryRetryDelay is the delay before the first retry of a delivery,
+	// doubled on every further failure
+	deliveryRetryDelay = 30 * time.Second
+	// maxDeliveryListLimit caps the deliveries listed for a target
+	maxDeliveryListLimit = 100
+	// deliveryTestFile is the file written to check a target
+	deliveryTestFile = "visekai-test.txt"
+)
+
+// DeliveryService pushes users' completed results to their S3 buckets and
+// SFTP servers, tracking each job's delivery to each target
+type DeliveryService struct {
+	deliveryRepo  *repository.DeliveryRepository
+	resultRepo    *repository.ResultRepository
+	documentRepo  *repository.DocumentRepository
+	resultService *ResultService
+	transactor    *repository.Transactor
+	sealer        *delivery.Sealer
+	httpClient    *http.Client
+	dial          delivery.DialFunc
+	interval      time.Duration
+	timeout       time.Duration
+	maxAttempts   int
+}
+
+// NewDeliveryService creates a new delivery service. Credentials are sealed
+// with sealer. Unless allowPrivate is set, targets cannot be on loopback,
+// private or link-local addresses.
+func NewDeliveryService(
+	deliveryRepo *repository.DeliveryRepository,
+	resultRepo *repository.ResultRepository,
+	documentRepo *repository.DocumentRepository,
+	resultService *ResultService,
+	transactor *repository.Transactor,
+	sealer *delivery.Sealer,
+	interval, timeout time.Duration,
+	maxAttempts int,
+	allowPrivate bool,
+) *DeliveryService {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 10
+	}
+
+	return &DeliveryService{
+		deliveryRepo:  deliveryRepo,
+		resultRepo:    resultRepo,
+		documentRepo:  documentRepo,
+		resultService: resultService,
+		transactor:    transactor,
+		sealer:        sealer,
+		httpClient:    newWebhookClient(timeout, allowPrivate),
+		dial:          newWebhookDialer(timeout, allowPrivate).DialContext,
+		interval:      interval,
+		timeout:       timeout,
+		maxAttempts:   maxAttempts,
+	}
+}
+
+// Create adds a target the user's results completing from now on are pushed
+// to, by default as Markdown. A target created with an API key restricted to
+// labels keeps that restriction.
+func (s *DeliveryService) Create(ctx context.Context, userID uuid.UUID, req models.DeliveryTargetRequest) (*models.DeliveryTarget, error) {
+	credentials := models.DeliveryCredentials{}
+	if req.Credentials != nil {
+		credentials = *req.Credentials
+	}
+	if err := validateDeliveryTarget(req.Kind, req.Settings, credentials); err != nil {
+		return nil, err
+	}
+
+	formats := req.Formats
+	if len(formats) == 0 {
+		formats = []string{string(models.ExportFormatMarkdown)}
+	}
+
+	target := &models.DeliveryTarget{
+		ID:       uuid.New(),
+		UserID:   userID,
+		Kind:     req.Kind,
+		Name:     req.Name,
+		Settings: req.Settings,
+		Formats:  formats,
+		Labels:   repository.LabelScope(ctx),
+		Enabled:  req.Enabled == nil || *req.Enabled,
+		CursorAt: time.Now().UTC(),
+		CursorID: uuid.Nil,
+	}
+	if err := s.sealCredentials(target, credentials); err != nil {
+		return nil, err
+	}
+	if err := s.deliveryRepo.Create(ctx, target); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Delivery target created", "target_id", target.ID, "user_id", userID, "kind", target.Kind)
+	return target, nil
+}
+
+// List returns the user's targets, newest first
+func (s *DeliveryService) List(ctx context.Context, userID uuid.UUID) ([]*models.DeliveryTarget, error) {
+	return s.deliveryRepo.ListByUser(ctx, userID)
+}
+
+// Update replaces the settings of one of the user's targets. Credentials are
+// kept unless given, and the trusted host key unless the host changes. A
+// target that is enabled again starts over with results completing from now on.
+func (s *DeliveryService) Update(ctx context.Context, id, userID uuid.UUID, req models.DeliveryTargetRequest) (*models.DeliveryTarget, error) {
+	target, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Kind != target.Kind {
+		return nil, apperrors.Validation("VAL_059", "the kind of a delivery target cannot be changed")
+	}
+
+	credentials, err := s.openCredentials(target)
+	if err != nil {
+		return nil, err
+	}
+	if req.Credentials != nil {
+		credentials = *req.Credentials
+	}
+	settings := req.Settings
+	if settings.HostKey == "" && settings.Host == target.Settings.Host && settings.Port == target.Settings.Port {
+		settings.HostKey = target.Settings.HostKey
+	}
+	if err := validateDeliveryTarget(req.Kind, settings, credentials); err != nil {
+		return nil, err
+	}
+
+	target.Name = req.Name
+	target.Settings = settings
+	if len(req.Formats) > 0 {
+		target.Formats = req.Formats
+	}
+	if req.Enabled != nil {
+		if *req.Enabled && !target.Enabled {
+			target.CursorAt, target.CursorID = time.Now().UTC(), uuid.Nil
+		}
+		target.Enabled = *req.Enabled
+	}
+	if err := s.sealCredentials(target, credentials); err != nil {
+		return nil, err
+	}
+	if err := s.deliveryRepo.Update(ctx, target); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Delivery target updated", "target_id", id, "user_id", userID, "enabled", target.Enabled)
+	return target, nil
+}
+
+// Delete removes one of the user's targets and its delivery history
+func (s *DeliveryService) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.getOwned(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.deliveryRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	logger.Info("Delivery target deleted", "target_id", id, "user_id", userID)
+	return nil
+}
+
+// Test writes a small text file to one of the user's targets, so they can
+// check its settings before a result is delivered
+func (s *DeliveryService) Test(ctx context.Context, id, userID uuid.UUID) error {
+	target, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	dest, err := s.open(target)
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("Delivery test from Visekai at %s\n", time.Now().UTC().Format(time.RFC3339))
+	if err := dest.Put(ctx, deliveryTestFile, []byte(content)); err != nil {
+		return apperrors.Validation("DLV_002", "delivery target rejected the test file: "+err.Error())
+	}
+
+	return s.trustHostKey(ctx, target, dest)
+}
+
+// ListDeliveries returns the latest deliveries to one of the user's targets,
+// newest first
+func (s *DeliveryService) ListDeliveries(ctx context.Context, id, userID uuid.UUID, limit int) ([]*models.ResultDelivery, error) {
+	if _, err := s.getOwned(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > maxDeliveryListLimit {
+		limit = maxDeliveryListLimit
+	}
+	return s.deliveryRepo.ListByTarget(ctx, id, limit)
+}
+
+// ListJobDeliveries returns the deliveries of one of the user's jobs
+func (s *DeliveryService) ListJobDeliveries(ctx context.Context, jobID, userID uuid.UUID) ([]*models.ResultDelivery, error) {
+	return s.deliveryRepo.ListByJob(ctx, jobID, userID)
+}
+
+// Start delivers completed results on every interval until the context is cancelled
+func (s *DeliveryService) Start(ctx context.Context) {
+	logger.Info("Result delivery started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Result delivery stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{Name: "deliveries.push", Run: s.DeliverPending})
+		}
+	}
+}
+
+// DeliverPending queues the results that completed since each target's last
+// check, then pushes the due deliveries. A failed delivery is retried with
+// exponential backoff until it has failed too often. Deliveries are claimed
+// with row locks so several API replicas can deliver concurrently.
+func (s *DeliveryService) DeliverPending(ctx context.Context) error {
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := s.deliveryRepo.EnqueueCompleted(ctx, time.Now().UTC().Add(-deliverySettleDelay), deliveryBatchSize)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		deliveries, err := s.deliveryRepo.ClaimDue(ctx, deliveryBatchSize)
+		if err != nil {
+			return err
+		}
+
+		targets := map[uuid.UUID]*models.DeliveryTarget{}
+		for _, d := range deliveries {
+			target, ok := targets[d.TargetID]
+			if !ok {
+				if target, err = s.deliveryRepo.GetByID(ctx, d.TargetID); err != nil {
+					return err
+				}
+				targets[d.TargetID] = target
+			}
+
+			if err := s.attempt(ctx, target, d); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// attempt pushes a job's result to a target and records the outcome. Only
+// database errors are returned.
+func (s *DeliveryService) attempt(ctx context.Context, target *models.DeliveryTarget, d *models.ResultDelivery) error {
+	now := time.Now().UTC()
+	d.Attempts++
+
+	files, err := s.push(ctx, target, d.JobID)
+	if err != nil {
+		message := err.Error()
+		d.LastError = &message
+		logger.Warn("Failed to deliver result", "delivery_id", d.ID, "target_id", target.ID, "job_id", d.JobID, "attempts", d.Attempts, "error", err)
+
+		if d.Attempts >= s.maxAttempts || errors.Is(err, repository.ErrResultNotFound) {
+			d.Status = models.DeliveryStatusFailed
+		} else {
+			backoff := deliveryRetryDelay << min(d.Attempts-1, 12)
+			if backoff > maxHookBackoff {
+				backoff = maxHookBackoff
+			}
+			d.NextAttemptAt = now.Add(backoff)
+		}
+		return s.deliveryRepo.UpdateDelivery(ctx, d)
+	}
+
+	d.Status = models.DeliveryStatusDelivered
+	d.Files = files
+	d.LastError = nil
+	d.DeliveredAt = &now
+	return s.deliveryRepo.UpdateDelivery(ctx, d)
+}
+
+// push writes a job's result to a target in each of its formats and returns
+// the names written. Formats a document cannot be exported to are skipped.
+func (s *DeliveryService) push(ctx context.Context, target *models.DeliveryTarget, jobID uuid.UUID) ([]string, error) {
+	result, err := s.resultRepo.GetByJobID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	document, err := s.documentRepo.GetByID(ctx, result.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dest, err := s.open(target)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name files after the document, made unique by the job
+	filename := storage.NormalizeFilename(document.OriginalFilename)
+	baseName := strings.TrimSuffix(filename, filepath.Ext(filename)) + "_" + jobID.String()[:8]
+
+	files := []string{}
+	for _, format := range target.Formats {
+		content, ext, err := s.export(ctx, result, models.ResultExportFormat(format))
+		if errors.Is(err, ErrSearchablePDFUnsupported) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := baseName + ext
+		if err := dest.Put(ctx, name, content); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+
+	if err := s.trustHostKey(ctx, target, dest); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// export renders a result in a format, returning the content and the file
+// extension
+func (s *DeliveryService) export(ctx context.Context, result *models.OCRResult, format models.ResultExportFormat) ([]byte, string, error) {
+	switch format {
+	case models.ExportFormatText:
+		return []byte(result.RawText), ".txt", nil
+	case models.ExportFormatMarkdown:
+		return []byte(result.MarkdownText), ".md", nil
+	case models.ExportFormatJSON:
+		content, err := json.MarshalIndent(result, "", "    ")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode result: %w", err)
+		}
+		return content, ".json", nil
+	case models.ExportFormatSearchablePDF:
+		var buf bytes.Buffer
+		if err := s.resultService.WriteSearchablePDF(ctx, result, &buf); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), ".pdf", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported delivery format: %s", format)
+	}
+}
+
+// open returns the destination a target writes to, with its credentials
+func (s *DeliveryService) open(target *models.DeliveryTarget) (delivery.Target, error) {
+	credentials, err := s.openCredentials(target)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := target.Settings
+	switch target.Kind {
+	case delivery.KindS3:
+		return delivery.NewS3(delivery.S3Config{
+			Endpoint:        settings.Endpoint,
+			Region:          settings.Region,
+			Bucket:          settings.Bucket,
+			Prefix:          settings.Prefix,
+			AccessKeyID:     settings.AccessKeyID,
+			SecretAccessKey: credentials.SecretAccessKey,
+		}, s.httpClient), nil
+	case delivery.KindSFTP:
+		return delivery.NewSFTP(delivery.SFTPConfig{
+			Host:       settings.Host,
+			Port:       settings.Port,
+			Username:   settings.Username,
+			Password:   credentials.Password,
+			PrivateKey: credentials.PrivateKey,
+			Directory:  settings.Directory,
+			HostKey:    settings.HostKey,
+		}, s.dial, s.timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown delivery target kind: %s", target.Kind)
+	}
+}
+
+// trustHostKey records the host key an SFTP server presented the first time
+// a target connected to it, so a different server is refused from then on
+func (s *DeliveryService) trustHostKey(ctx context.Context, target *models.DeliveryTarget, dest delivery.Target) error {
+	sftp, ok := dest.(*delivery.SFTP)
+	if !ok || target.Settings.HostKey != "" || sftp.HostKey() == "" {
+		return nil
+	}
+
+	if err := s.deliveryRepo.SetHostKey(ctx, target.ID, sftp.HostKey()); err != nil {
+		return err
+	}
+	target.Settings.HostKey = sftp.HostKey()
+	logger.Info("Delivery target host key trusted", "target_id", target.ID, "host_key", target.Settings.HostKey)
+	return nil
+}
+
+// sealCredentials encrypts a target's credentials, bound to its ID
+func (s *DeliveryService) sealCredentials(target *models.DeliveryTarget, credentials models.DeliveryCredentials) error {
+	raw, err := json.Marshal(credentials)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	sealed, err := s.sealer.Seal(raw, target.ID[:])
+	if err != nil {
+		return err
+	}
+	target.Credentials = sealed
+	return nil
+}
+
+// openCredentials decrypts a target's credentials
+func (s *DeliveryService) openCredentials(target *models.DeliveryTarget) (models.DeliveryCredentials, error) {
+	var credentials models.DeliveryCredentials
+	if target.Credentials == "" {
+		return credentials, nil
+	}
+	raw, err := s.sealer.Open(target.Credentials, target.ID[:])
+	if err != nil {
+		return credentials, err
+	}
+	if err := json.Unmarshal(raw, &credentials); err != nil {
+		return credentials, fmt.Errorf("failed to decode credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+// getOwned retrieves a target, reporting it missing unless the user owns it
+func (s *DeliveryService) getOwned(ctx context.Context, id, userID uuid.UUID) (*models.DeliveryTarget, error) {
+	target, err := s.deliveryRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if target.UserID != userID {
+		return nil, repository.ErrDeliveryTargetNotFound
+	}
+	return target, nil
+}
+
+// validateDeliveryTarget checks that a target has the settings and
+// credentials its kind needs
+func validateDeliveryTarget(kind string, settings models.DeliveryTargetSettings, credentials models.DeliveryCredentials) error {
+	switch kind {
+	case delivery.KindS3:
+		if settings.Bucket == "" || settings.Region == "" || settings.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+			return apperrors.Validation("VAL_059", "an S3 target needs bucket, region, access_key_id and credentials.secret_access_key")
+		}
+		if settings.Endpoint != "" && !strings.HasPrefix(settings.Endpoint, "https://") && !strings.HasPrefix(settings.Endpoint, "http://") {
+			return apperrors.Validation("VAL_059", "endpoint must be an http or https URL")
+		}
+	case delivery.KindSFTP:
+		if settings.Host == "" || settings.Username == "" || (credentials.Password == "" && credentials.PrivateKey == "") {
+			return apperrors.Validation("VAL_059", "an SFTP target needs host, username and credentials.password or credentials.private_key")
+		}
+	default:
+		return apperrors.Validation("VAL_059", "unknown delivery target kind: "+kind)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxRelatedDepth bounds the number of links followed from a document
+	MaxRelatedDepth = 5
+	// maxRelatedDocuments bounds the documents returned by a traversal
+	maxRelatedDocuments = 200
+)
+
+// ErrSelfLink is returned when linking a document to itself
+var ErrSelfLink = apperrors.Validation("VAL_032", "cannot link a document to itself")
+
+// DocumentLinkService lets users link their documents with typed relations,
+// such as a delivery note referencing an invoice or a page that is part of a
+// larger document, and navigate and export the documents linked together
+type DocumentLinkService struct {
+	linkRepo     *repository.DocumentLinkRepository
+	documentRepo *repository.DocumentRepository
+	resultRepo   *repository.ResultRepository
+	storage      *storage.Storage
+}
+
+// NewDocumentLinkService creates a new document link service
+func NewDocumentLinkService(
+	linkRepo *repository.DocumentLinkRepository,
+	documentRepo *repository.DocumentRepository,
+	resultRepo *repository.ResultRepository,
+	storage *storage.Storage,
+) *DocumentLinkService {
+	return &DocumentLinkService{
+		linkRepo:     linkRepo,
+		documentRepo: documentRepo,
+		resultRepo:   resultRepo,
+		storage:      storage,
+	}
+}
+
+// Create links one of the user's documents to another
+func (s *DocumentLinkService) Create(ctx context.Context, userID, sourceID uuid.UUID, req models.DocumentLinkCreateRequest) (*models.DocumentLink, error) {
+	if req.TargetID == sourceID {
+		return nil, ErrSelfLink
+	}
+	if _, err := s.ownedDocument(ctx, userID, sourceID); err != nil {
+		return nil, err
+	}
+	if _, err := s.ownedDocument(ctx, userID, req.TargetID); err != nil {
+		return nil, err
+	}
+
+	link := &models.DocumentLink{
+		UserID:   userID,
+		SourceID: sourceID,
+		TargetID: req.TargetID,
+		Relation: req.Relation,
+		Position: req.Position,
+	}
+	if note := strings.TrimSpace(req.Note); note != "" {
+		link.Note = &note
+	}
+
+	if err := s.linkRepo.Create(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// List returns the links from and to one of the user's documents, with the
+// documents at their other end. Links to documents in the trash are left out.
+func (s *DocumentLinkService) List(ctx context.Context, userID, documentID uuid.UUID) ([]*models.LinkedDocument, error) {
+	if _, err := s.ownedDocument(ctx, userID, documentID); err != nil {
+		return nil, err
+	}
+
+	links, err := s.linkRepo.ListByDocuments(ctx, userID, []uuid.UUID{documentID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	otherIDs := make([]uuid.UUID, 0, len(links))
+	for _, link := range links {
+		otherIDs = append(otherIDs, otherEnd(link, documentID))
+	}
+	documents, err := s.documentsByID(ctx, userID, otherIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := []*models.LinkedDocument{}
+	for _, link := range links {
+		document, ok := documents[otherEnd(link, documentID)]
+		if !ok {
+			continue
+		}
+		direction := models.LinkOutgoing
+		if link.TargetID == documentID {
+			direction = models.LinkIncoming
+		}
+		linked = append(linked, &models.LinkedDocument{Link: link, Direction: direction, Document: document})
+	}
+
+	return linked, nil
+}
+
+// Update changes the relation, position or note of a link of one of the
+// user's documents
+func (s *DocumentLinkService) Update(ctx context.Context, userID, documentID, linkID uuid.UUID, req models.DocumentLinkUpdateRequest) (*models.DocumentLink, error) {
+	link, err := s.documentLink(ctx, userID, documentID, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Relation != nil {
+		link.Relation = *req.Relation
+	}
+	if req.Position != nil {
+		link.Position = req.Position
+	}
+	if req.Note != nil {
+		link.Note = nil
+		if note := strings.TrimSpace(*req.Note); note != "" {
+			link.Note = &note
+		}
+	}
+
+	if err := s.linkRepo.Update(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// Delete removes a link of one of the user's documents
+func (s *DocumentLinkService) Delete(ctx context.Context, userID, documentID, linkID uuid.UUID) error {
+	if _, err := s.documentLink(ctx, userID, documentID, linkID); err != nil {
+		return err
+	}
+
+	return s.linkRepo.Delete(ctx, linkID, userID)
+}
+
+// Related follows links in both directions from one of the user's documents,
+// up to depth links away, optionally only links with one of the relations.
+// Documents in the trash and the links through them are left out.
+func (s *DocumentLinkService) Related(ctx context.Context, userID, documentID uuid.UUID, depth int, relations []string) (*models.RelatedGraph, error) {
+	root, err := s.ownedDocument(ctx, userID, documentID)
+	if err != nil {
+		return nil, err
+	}
+	depth = max(1, min(depth, MaxRelatedDepth))
+
+	graph := &models.RelatedGraph{
+		RootID:    root.ID,
+		Depth:     depth,
+		Documents: []*models.RelatedDocument{{Document: root, Depth: 0}},
+		Links:     []*models.DocumentLink{},
+	}
+	visited := map[uuid.UUID]bool{root.ID: true}
+	seenLinks := map[uuid.UUID]bool{}
+	frontier := []uuid.UUID{root.ID}
+
+	for level := 1; level <= depth && len(frontier) > 0 && !graph.Truncated; level++ {
+		links, err := s.linkRepo.ListByDocuments(ctx, userID, frontier, relations)
+		if err != nil {
+			return nil, err
+		}
+
+		var candidates []uuid.UUID
+		for _, link := range links {
+			for _, id := range []uuid.UUID{link.SourceID, link.TargetID} {
+				if !visited[id] && !slices.Contains(candidates, id) {
+					candidates = append(candidates, id)
+				}
+			}
+		}
+		documents, err := s.documentsByID(ctx, userID, candidates)
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for _, id := range candidates {
+			document, ok := documents[id]
+			if !ok {
+				continue
+			}
+			if len(graph.Documents) == maxRelatedDocuments {
+				graph.Truncated = true
+				break
+			}
+			visited[id] = true
+			frontier = append(frontier, id)
+			graph.Documents = append(graph.Documents, &models.RelatedDocument{Document: document, Depth: level})
+		}
+
+		for _, link := range links {
+			if visited[link.SourceID] && visited[link.TargetID] && !seenLinks[link.ID] {
+				seenLinks[link.ID] = true
+				graph.Links = append(graph.Links, link)
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// Export writes a zip archive of the documents of a related graph: for each
+// document its original scan and its latest result in the export format, text
+// or markdown, plus a manifest.json holding the graph
+func (s *DocumentLinkService) Export(ctx context.Context, graph *models.RelatedGraph, format models.ResultExportFormat, w io.Writer) error {
+	archive := zip.NewWriter(w)
+	for i, related := range graph.Documents {
+		document := related.Document
+		filename := storage.NormalizeFilename(document.OriginalFilename)
+		dir := fmt.Sprintf("%02d-%s/", i+1, strings.TrimSuffix(filename, filepath.Ext(filename)))
+
+		if err := s.addScan(archive, dir+filename, document); err != nil {
+			logger.Warn("Failed to export related scan", "document_id", document.ID, "error", err)
+		}
+
+		results, err := s.resultRepo.GetByDocumentID(ctx, document.ID)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			continue
+		}
+		name, text := dir+"result.md", results[0].MarkdownText
+		if format == models.ExportFormatText {
+			name, text = dir+"result.txt", results[0].RawText
+		}
+		entry, err := archive.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		if _, err := io.WriteString(entry, text); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	}
+
+	entry, err := archive.Create("manifest.json")
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(graph); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	return archive.Close()
+}
+
+// addScan copies a document's stored file into the archive
+func (s *DocumentLinkService) addScan(archive *zip.Writer, name string, document *models.Document) error {
+	file, err := s.storage.Open(document.FilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: document.UploadedAt})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
+
+// ownedDocument returns one of the user's active documents
+func (s *DocumentLinkService) ownedDocument(ctx context.Context, userID, documentID uuid.UUID) (*models.Document, error) {
+	document, err := s.documentRepo.GetByID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.UserID != userID {
+		return nil, apperrors.Forbidden("AUTH_004", "document does not belong to user")
+	}
+	return document, nil
+}
+
+// documentLink returns one of the user's links from or to a document
+func (s *DocumentLinkService) documentLink(ctx context.Context, userID, documentID, linkID uuid.UUID) (*models.DocumentLink, error) {
+	link, err := s.linkRepo.GetByID(ctx, linkID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if link.SourceID != documentID && link.TargetID != documentID {
+		return nil, repository.ErrDocumentLinkNotFound
+	}
+	return link, nil
+}
+
+// documentsByID returns the user's active documents among the IDs, by ID
+func (s *DocumentLinkService) documentsByID(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*models.Document, error) {
+	byID := map[uuid.UUID]*models.Document{}
+	if len(ids) == 0 {
+		return byID, nil
+	}
+
+	documents, err := s.documentRepo.ListByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range documents {
+		byID[documents[i].ID] = &documents[i]
+	}
+	return byID, nil
+}
+
+// otherEnd returns the document at the other end of a link from documentID
+func otherEnd(link *models.DocumentLink, documentID uuid.UUID) uuid.UUID {
+	if link.SourceID == documentID {
+		return link.TargetID
+	}
+	return link.SourceID
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/mail"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// failureEmailBatchSize is the number of failed jobs emailed about per cycle
+	failureEmailBatchSize = 100
+	// failureEmailWindow is how long after a job failed it is still emailed
+	// about, so failures are not announced days late after an outage
+	failureEmailWindow = 24 * time.Hour
+	// digestBatchSize is the number of digests sent per cycle
+	digestBatchSize = 20
+	// digestFailures is the number of failed jobs listed in a digest
+	digestFailures = 10
+	// digestRetryDelay is how long after a digest could not be sent it is
+	// tried again
+	digestRetryDelay = time.Hour
+	// emailTimeFormat formats times in emails, in the user's time zone
+	emailTimeFormat = "January 2, 2006 15:04 MST"
+)
+
+// EmailNotificationService emails users about their jobs: as soon as jobs
+// fail, and in a daily digest of the past day's jobs at the hour they chose.
+// Users choose either in their settings, and get neither when their email
+// notifications are off.
+type EmailNotificationService struct {
+	emailRepo   *repository.EmailNotificationRepository
+	transactor  *repository.Transactor
+	sender      *mail.Sender
+	documentURL string
+	interval    time.Duration
+}
+
+// NewEmailNotificationService creates a new email notification service.
+// Emails link to documentURL followed by the document ID.
+func NewEmailNotificationService(
+	emailRepo *repository.EmailNotificationRepository,
+	transactor *repository.Transactor,
+	sender *mail.Sender,
+	documentURL string,
+	interval time.Duration,
+) *EmailNotificationService {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &EmailNotificationService{
+		emailRepo:   emailRepo,
+		transactor:  transactor,
+		sender:      sender,
+		documentURL: documentURL,
+		interval:    interval,
+	}
+}
+
+// Start sends failure emails and due digests on every interval until the
+// context is cancelled
+func (s *EmailNotificationService) Start(ctx context.Context) {
+	logger.Info("Email notifications started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Email notifications stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{Name: "email_notifications.failures", Run: s.SendFailures})
+			_ = tasks.Run(ctx, tasks.Task{Name: "email_notifications.digests", Run: s.SendDigests})
+		}
+	}
+}
+
+// ScheduleDigest schedules the user's next digest after their settings
+// changed, or stops their digests when they turned them off
+func (s *EmailNotificationService) ScheduleDigest(ctx context.Context, settings *models.UserSettings) error {
+	if !settings.EmailNotifications || !settings.EmailDigest {
+		return s.emailRepo.DeleteDigest(ctx, settings.UserID)
+	}
+	return s.emailRepo.ScheduleDigest(ctx, settings.UserID, nextDigestAt(time.Now().UTC(), settings.DigestHour, settings.Location()))
+}
+
+// SendFailures emails users about their jobs that failed since the last
+// cycle, one email per user listing their failures. Sending stops at the
+// first email that fails, leaving the rest for the next cycle.
+func (s *EmailNotificationService) SendFailures(ctx context.Context) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		now := time.Now().UTC()
+		jobs, err := s.emailRepo.ClaimFailedJobs(ctx, now.Add(-failureEmailWindow), failureEmailBatchSize)
+		if err != nil {
+			return err
+		}
+
+		// Group the failures by user, keeping the order they failed in
+		users := []uuid.UUID{}
+		byUser := map[uuid.UUID][]*models.FailedJobEmail{}
+		for _, job := range jobs {
+			if _, ok := byUser[job.UserID]; !ok {
+				users = append(users, job.UserID)
+			}
+			byUser[job.UserID] = append(byUser[job.UserID], job)
+		}
+
+		for _, userID := range users {
+			failed := byUser[userID]
+			if err := s.sendFailures(ctx, failed); err != nil {
+				logger.Warn("Failed to email job failures", "user_id", userID, "jobs", len(failed), "error", err)
+				return nil
+			}
+
+			ids := make([]uuid.UUID, len(failed))
+			for i, job := range failed {
+				ids[i] = job.JobID
+			}
+			if err := s.emailRepo.MarkFailureEmailed(ctx, ids, now); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// sendFailures emails a user about their failed jobs
+func (s *EmailNotificationService) sendFailures(ctx context.Context, jobs []*models.FailedJobEmail) error {
+	loc := models.TimezoneLocation(jobs[0].Timezone)
+
+	views := make([]emailJobView, len(jobs))
+	for i, job := range jobs {
+		views[i] = s.jobView(job.DocumentID, job.Filename, job.ErrorMessage, job.CompletedAt, loc)
+	}
+
+	msg, err := mail.Render("job_failed", map[string]any{"Jobs": views}, jobs[0].Email)
+	if err != nil {
+		return err
+	}
+	return s.sender.Send(ctx, msg)
+}
+
+// SendDigests sends the digests that are due, summing up each user's jobs
+// since their last digest. No email is sent for a day without jobs. A digest
+// that cannot be sent is tried again after digestRetryDelay.
+func (s *EmailNotificationService) SendDigests(ctx context.Context) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		now := time.Now().UTC()
+		digests, err := s.emailRepo.ClaimDueDigests(ctx, now, digestBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, digest := range digests {
+			sent, err := s.sendDigest(ctx, digest, now)
+			if err != nil {
+				logger.Warn("Failed to send digest", "user_id", digest.UserID, "error", err)
+				digest.NextAt = now.Add(digestRetryDelay)
+			} else {
+				if sent {
+					digest.LastSentAt = &now
+				}
+				digest.PeriodStart = now
+				digest.NextAt = nextDigestAt(now, digest.DigestHour, models.TimezoneLocation(digest.Timezone))
+			}
+
+			if err := s.emailRepo.UpdateDigest(ctx, digest); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// sendDigest emails a user the summary of their jobs since the digest's
+// period started, reporting whether there was anything to send
+func (s *EmailNotificationService) sendDigest(ctx context.Context, digest *models.EmailDigest, now time.Time) (bool, error) {
+	summary, err := s.emailRepo.DigestSummary(ctx, digest.UserID, digest.PeriodStart, now, digestFailures)
+	if err != nil {
+		return false, err
+	}
+	if summary.Completed == 0 && summary.Failed == 0 {
+		return false, nil
+	}
+
+	loc := models.TimezoneLocation(digest.Timezone)
+	confidence := "n/a"
+	if summary.AverageConfidence != nil {
+		confidence = fmt.Sprintf("%.1f%%", *summary.AverageConfidence*100)
+	}
+	failures := make([]emailJobView, len(summary.FailedJobs))
+	for i, job := range summary.FailedJobs {
+		failures[i] = s.jobView(job.DocumentID, job.Filename, job.ErrorMessage, job.CompletedAt, loc)
+	}
+
+	msg, err := mail.Render("digest", map[string]any{
+		"From":        digest.PeriodStart.In(loc).Format(emailTimeFormat),
+		"Until":       now.In(loc).Format(emailTimeFormat),
+		"Completed":   summary.Completed,
+		"Failed":      summary.Failed,
+		"Pages":       summary.Pages,
+		"Confidence":  confidence,
+		"CreditsUsed": summary.CreditsUsed,
+		"FailedJobs":  failures,
+		"Hour":        fmt.Sprintf("%02d:00 (%s)", digest.DigestHour, loc),
+	}, digest.Email)
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.sender.Send(ctx, msg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// emailJobView is a failed job as the email templates show it
+type emailJobView struct {
+	Filename string
+	FailedAt string
+	Error    string
+	URL      string
+}
+
+// jobView formats a failed job for the email templates
+func (s *EmailNotificationService) jobView(documentID uuid.UUID, filename string, errorMessage *string, failedAt time.Time, loc *time.Location) emailJobView {
+	view := emailJobView{
+		Filename: filename,
+		FailedAt: failedAt.In(loc).Format(emailTimeFormat),
+		Error:    "unknown error",
+	}
+	if errorMessage != nil && *errorMessage != "" {
+		view.Error = *errorMessage
+	}
+	if s.documentURL != "" {
+		view.URL = s.documentURL + documentID.String()
+	}
+	return view
+}
+
+// nextDigestAt returns the first time after now at the hour in loc
+func nextDigestAt(now time.Time, hour int, loc *time.Location) time.Time {
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, 0, 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, 0, 0, 0, loc)
+	}
+	return next.UTC()
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"visekai/backend/internal/entities"
+	"visekai/backend/internal/models"
+	"visekai/backend/pkg/logger"
+)
+
+// EntityService extracts entities such as dates, amounts and IBANs from the
+// raw text of results, with the configured providers, and stores them in the
+// results' JSON data
+type EntityService struct {
+	extractor entities.Extractor
+}
+
+// NewEntityService creates a new entity service. A nil extractor disables
+// extraction.
+func NewEntityService(extractor entities.Extractor) *EntityService {
+	return &EntityService{extractor: extractor}
+}
+
+// Enabled reports whether entities are extracted
+func (s *EntityService) Enabled() bool {
+	return s.extractor != nil
+}
+
+// Annotate stores the entities found in a result's raw text in its JSON data.
+// Extraction is best effort: a failing provider is logged and leaves the
+// result as it was.
+func (s *EntityService) Annotate(ctx context.Context, result *models.OCRResult) bool {
+	if s.extractor == nil {
+		return false
+	}
+
+	found, err := s.extractor.Extract(ctx, result.RawText)
+	if err != nil {
+		logger.Warn("Failed to extract entities", "result_id", result.ID, "job_id", result.JobID, "error", err)
+		return false
+	}
+
+	if result.JSONData == nil {
+		result.JSONData = map[string]any{}
+	}
+	result.JSONData[models.ResultJSONEntities] = found
+	logger.Debug("Extracted entities", "result_id", result.ID, "job_id", result.JobID, "count", len(found))
+	return true
+}
+
+// Entities returns the entities stored on a result, only those of entityType
+// unless it is empty. It returns nil for results that were never annotated.
+func (s *EntityService) Entities(result *models.OCRResult, entityType models.EntityType) []models.ResultEntity {
+	stored, ok := result.JSONData[models.ResultJSONEntities]
+	if !ok {
+		return nil
+	}
+
+	// Entities read back from the JSONB column are plain maps
+	var found []models.ResultEntity
+	raw, err := json.Marshal(stored)
+	if err == nil {
+		err = json.Unmarshal(raw, &found)
+	}
+	if err != nil {
+		logger.Warn("Ignoring unreadable result entities", "result_id", result.ID, "error", err)
+		return nil
+	}
+
+	filtered := []models.ResultEntity{}
+	for _, entity := range found {
+		if entityType == "" || entity.Type == entityType {
+			filtered = append(filtered, entity)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"visekai/backend/internal/events"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+)
+
+// maxEventBackoff caps the delay between delivery attempts of an event
+const maxEventBackoff = 10 * time.Minute
+
+// EventService records domain events in the transactional outbox and relays
+// them to the message bus and webhook
+type EventService struct {
+	outboxRepo *repository.OutboxRepository
+	transactor *repository.Transactor
+	publisher  events.Publisher
+	interval   time.Duration
+	batchSize  int
+}
+
+// NewEventService creates a new event service.
+// A nil publisher disables recording entirely.
+func NewEventService(
+	outboxRepo *repository.OutboxRepository,
+	transactor *repository.Transactor,
+	publisher events.Publisher,
+	interval time.Duration,
+) *EventService {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &EventService{
+		outboxRepo: outboxRepo,
+		transactor: transactor,
+		publisher:  publisher,
+		interval:   interval,
+		batchSize:  100,
+	}
+}
+
+// Enabled reports whether a delivery destination is configured
+func (s *EventService) Enabled() bool {
+	return s != nil && s.publisher != nil
+}
+
+// Record stores events in the outbox. When ctx carries a transaction (see
+// repository.Transactor) the events are written in it, so they are committed
+// or rolled back together with the state change they describe.
+func (s *EventService) Record(ctx context.Context, evts ...*models.DomainEvent) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	for _, event := range evts {
+		if err := s.outboxRepo.Create(ctx, event); err != nil {
+			return fmt.Errorf("failed to record %s event: %w", event.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// Start relays pending events on every interval until the context is cancelled
+func (s *EventService) Start(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	logger.Info("Event relay started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = s.publisher.Close()
+			logger.Info("Event relay stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{Name: "events.relay", Run: s.RelayPending})
+		}
+	}
+}
+
+// RelayPending publishes due outbox events, rescheduling failures with exponential backoff.
+// Events are claimed with row locks so several API replicas can relay concurrently.
+func (s *EventService) RelayPending(ctx context.Context) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		pending, err := s.outboxRepo.ClaimPending(ctx, s.batchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range pending {
+			if err := s.publisher.Publish(ctx, event); err != nil {
+				backoff := time.Duration(1<<min(event.Attempts, 10)) * time.Second
+				if backoff > maxEventBackoff {
+					backoff = maxEventBackoff
+				}
+				logger.Warn("Failed to publish domain event", "event_id", event.ID, "type", event.Type, "attempts", event.Attempts+1, "error", err)
+				if err := s.outboxRepo.MarkFailed(ctx, event.ID, err.Error(), time.Now().Add(backoff)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.outboxRepo.MarkPublished(ctx, event.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ListEvents retrieves outbox events for inspection
+func (s *EventService) ListEvents(ctx context.Context, filter models.EventFilter) ([]*models.DomainEvent, error) {
+	if filter.Limit < 1 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	return s.outboxRepo.List(ctx, filter)
+}
+
+// Replay schedules matching events for redelivery on the next relay cycle
+func (s *EventService) Replay(ctx context.Context, req models.EventReplayRequest) (int64, error) {
+	if len(req.EventIDs) == 0 && req.Type == "" && req.AggregateID == nil && req.Since == nil && req.Until == nil {
+		return 0, fmt.Errorf("at least one of event_ids, type, aggregate_id, since or until is required")
+	}
+
+	replayed, err := s.outboxRepo.Replay(ctx, models.EventFilter{
+		IDs:         req.EventIDs,
+		Type:        req.Type,
+		AggregateID: req.AggregateID,
+		Since:       req.Since,
+		Until:       req.Until,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Info("Domain events scheduled for replay", "count", replayed)
+	return replayed, nil
+}
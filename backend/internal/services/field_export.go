@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/xlsx"
+
+	"github.com/google/uuid"
+)
+
+// fieldsExportLimit caps the number of documents in one fields export
+const fieldsExportLimit = 10000
+
+// fieldsExportColumns are the columns written before the extracted fields
+var fieldsExportColumns = []string{"document_id", "filename", "uploaded_at", "document_type", "template"}
+
+// ExportFields writes the fields extracted from the latest result of each of
+// the user's documents matching req as a spreadsheet in the requested format,
+// one row per document and one column per field name found in any of them
+func (s *ResultService) ExportFields(ctx context.Context, userID uuid.UUID, req models.FieldsExportRequest, w io.Writer) error {
+	labels, err := models.NormalizeLabels(req.Labels)
+	if err != nil {
+		return apperrors.Validation("VAL_050", err.Error())
+	}
+	req.Labels = labels
+	if req.From != nil && req.Until != nil && !req.From.Before(*req.Until) {
+		return apperrors.Validation("VAL_050", "from must be before until")
+	}
+
+	documents, err := s.resultRepo.ListDocumentFields(ctx, userID, req, fieldsExportLimit+1)
+	if err != nil {
+		return err
+	}
+	if len(documents) > fieldsExportLimit {
+		return apperrors.Validation("VAL_050",
+			fmt.Sprintf("more than %d documents match; narrow the filter", fieldsExportLimit))
+	}
+
+	rows := fieldsExportRows(documents)
+	logger.Info("Exporting document fields",
+		"user_id", userID, "documents", len(documents), "columns", len(rows[0]), "format", req.Format)
+
+	if req.Format == "xlsx" {
+		return xlsx.Write(w, "Fields", rows)
+	}
+
+	writer := csv.NewWriter(w)
+	for _, row := range rows {
+		for i, value := range row {
+			row[i] = csvSafe(value)
+		}
+		_ = writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// fieldsExportRows lays out the export: a header row, then one row per
+// document with the values of the fields its template found and blanks for
+// the others
+func fieldsExportRows(documents []*models.DocumentFields) [][]string {
+	seen := map[string]bool{}
+	var names []string
+	for _, document := range documents {
+		if document.Fields == nil {
+			continue
+		}
+		for name := range document.Fields.Fields {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		for _, name := range document.Fields.Missing {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	header := append(append([]string{}, fieldsExportColumns...), names...)
+	rows := [][]string{header}
+	for _, document := range documents {
+		row := make([]string, len(header))
+		row[0] = document.DocumentID.String()
+		row[1] = document.Filename
+		row[2] = document.UploadedAt.UTC().Format(time.RFC3339)
+		row[3] = string(document.DocumentType)
+		if document.Fields != nil {
+			row[4] = document.Fields.TemplateName
+			for i, name := range names {
+				row[len(fieldsExportColumns)+i] = document.Fields.Fields[name]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// csvSafe keeps spreadsheet applications from evaluating text read off a
+// document as a formula when the CSV is opened, by prefixing it with a quote.
+// Negative numbers are left alone.
+func csvSafe(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '@', '\t', '\r':
+		return "'" + value
+	case '-':
+		if len(value) > 1 && (value[1] >= '0' && value[1] <= '9' || value[1] == '.') {
+			return value
+		}
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,548 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/connectors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/scanner"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// connectorStateTTL is how long a user has to grant access at the provider
+	connectorStateTTL = 10 * time.Minute
+	// connectorStateAudience keeps other tokens signed with the same secret
+	// from passing for linking states; ValidateToken refuses tokens with an
+	// audience, so states do not pass for access tokens either
+	connectorStateAudience = "connector_state"
+	// connectorCheckInterval is how often connections are checked for a due sync
+	connectorCheckInterval = time.Minute
+	// connectorSyncBatch is the number of connections claimed per check
+	connectorSyncBatch = 10
+	// importBatchSize caps the number of files a connection imports per sync;
+	// the rest follow in the next syncs
+	importBatchSize = 50
+	// tokenRefreshMargin is how long before it expires an access token is renewed
+	tokenRefreshMargin = time.Minute
+)
+
+var (
+	// ErrConnectorUnavailable is returned when linking an account of a provider
+	// the server has no OAuth app for
+	ErrConnectorUnavailable = apperrors.Validation("VAL_054", "this storage provider is not available")
+	// ErrConnectorStateInvalid is returned when the callback's state does not
+	// check out
+	ErrConnectorStateInvalid = apperrors.Forbidden("CONN_002", "account linking attempt is invalid or has expired")
+	// ErrConnectorRevoked is returned when the provider no longer accepts the
+	// connection's tokens
+	ErrConnectorRevoked = apperrors.Conflict("CONN_003", "access to the account was revoked or has expired; link it again")
+)
+
+// connectorState is the signed state carried through the provider's consent page
+type connectorState struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Provider    string    `json:"provider"`
+	RedirectURL string    `json:"redirect_url"`
+	Nonce       string    `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// ImportService imports files from users' Google Drive and Dropbox accounts.
+// Users link an account with OAuth and pick a folder; new files in the folder
+// are then periodically downloaded, stored as documents of the user and
+// processed. Remote file IDs are recorded so a file is imported once, even
+// when renamed or after its document is deleted.
+type ImportService struct {
+	connectorRepo *repository.ConnectorRepository
+	jobService    *JobService
+	ingester      *fileIngester
+	providers     map[string]connectors.Provider
+	stateSecret   string
+	callbackURL   string
+	interval      time.Duration
+}
+
+// NewImportService creates a new import service. Providers redirect back to
+// callbackURL, or to the API of the request's origin when it is empty, and
+// each linked folder is synced every interval.
+func NewImportService(
+	connectorRepo *repository.ConnectorRepository,
+	documentRepo *repository.DocumentRepository,
+	transactor *repository.Transactor,
+	storage *storage.Storage,
+	eventService *EventService,
+	jobService *JobService,
+	fileScanner scanner.Scanner,
+	scanFailOpen bool,
+	providers map[string]connectors.Provider,
+	stateSecret string,
+	callbackURL string,
+	interval time.Duration,
+	maxFileSize int64,
+	allowedExts []string,
+) *ImportService {
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+
+	return &ImportService{
+		connectorRepo: connectorRepo,
+		jobService:    jobService,
+		ingester: &fileIngester{
+			documentRepo: documentRepo,
+			transactor:   transactor,
+			storage:      storage,
+			eventService: eventService,
+			scanner:      fileScanner,
+			scanFailOpen: scanFailOpen,
+			maxFileSize:  maxFileSize,
+			allowedExts:  allowedExts,
+		},
+		providers:   providers,
+		stateSecret: stateSecret,
+		callbackURL: callbackURL,
+		interval:    interval,
+	}
+}
+
+// Authorize starts linking an account of a provider. It returns where to send
+// the user and the state the callback must come back with.
+func (s *ImportService) Authorize(ctx context.Context, userID uuid.UUID, providerName, origin string) (*models.ConnectorAuthorization, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, "", ErrConnectorUnavailable
+	}
+
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	redirectURL := s.callbackURL
+	if redirectURL == "" {
+		redirectURL = origin + "/api/v1/connectors/callback"
+	}
+
+	now := time.Now()
+	state, err := jwt.NewWithClaims(jwt.SigningMethodHS256, connectorState{
+		UserID:      userID,
+		Provider:    providerName,
+		RedirectURL: redirectURL,
+		Nonce:       nonce,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{connectorStateAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(connectorStateTTL)),
+		},
+	}).SignedString([]byte(s.stateSecret))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign connector state: %w", err)
+	}
+
+	return &models.ConnectorAuthorization{
+		Provider:         providerName,
+		AuthorizationURL: provider.AuthCodeURL(redirectURL, state),
+	}, state, nil
+}
+
+// Complete finishes linking an account with the code the provider redirected
+// back with and returns the new connection, which imports nothing until a
+// folder is picked
+func (s *ImportService) Complete(ctx context.Context, code, rawState string) (*models.ImportConnection, error) {
+	state := &connectorState{}
+	_, err := jwt.ParseWithClaims(rawState, state, func(token *jwt.Token) (any, error) {
+		return []byte(s.stateSecret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(connectorStateAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrConnectorStateInvalid
+	}
+
+	provider, ok := s.providers[state.Provider]
+	if !ok {
+		return nil, ErrConnectorUnavailable
+	}
+
+	token, err := provider.Exchange(ctx, state.RedirectURL, code)
+	if errors.Is(err, connectors.ErrUnauthorized) {
+		return nil, ErrConnectorStateInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to link account: %w", err)
+	}
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("failed to link account: provider issued no refresh token")
+	}
+
+	account, err := provider.Account(ctx, token.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked account: %w", err)
+	}
+
+	connection := &models.ImportConnection{
+		UserID:       state.UserID,
+		Provider:     state.Provider,
+		Account:      account,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenExpiry:  tokenExpiry(token),
+		Enabled:      true,
+	}
+	if err := s.connectorRepo.Create(ctx, connection); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Import connection linked", "connection_id", connection.ID, "user_id", connection.UserID, "provider", connection.Provider)
+	return connection, nil
+}
+
+// ListConnections returns the user's import connections, newest first
+func (s *ImportService) ListConnections(ctx context.Context, userID uuid.UUID) ([]*models.ImportConnection, error) {
+	return s.connectorRepo.ListByUser(ctx, userID)
+}
+
+// ListFolders lists the folders inside parent, or at the root of the account
+// when parent is empty, for the user to pick the one to import from
+func (s *ImportService) ListFolders(ctx context.Context, id, userID uuid.UUID, parent string) ([]connectors.Entry, error) {
+	connection, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, accessToken, err := s.authorized(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := provider.ListFolders(ctx, accessToken, parent)
+	if errors.Is(err, connectors.ErrUnauthorized) {
+		return nil, ErrConnectorRevoked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folders: %w", err)
+	}
+	return folders, nil
+}
+
+// Update sets the folder a connection imports from, the labels of the imported
+// documents and whether it is enabled, and syncs it right away. Connections
+// updated with an API key restricted to labels may only use its labels, and
+// get all of them when none are given.
+func (s *ImportService) Update(ctx context.Context, id, userID uuid.UUID, req models.ImportConnectionUpdateRequest) (*models.ImportConnection, error) {
+	connection, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := models.NormalizeLabels(req.Labels)
+	if err != nil {
+		return nil, apperrors.Validation("VAL_055", err.Error())
+	}
+	if scope := repository.LabelScope(ctx); scope != nil {
+		if len(labels) == 0 {
+			labels = scope
+		}
+		for _, label := range labels {
+			if !slices.Contains(scope, label) {
+				return nil, apperrors.Forbidden("AUTH_007", "API key may not use label "+label)
+			}
+		}
+	}
+
+	connection.FolderID = &req.FolderID
+	connection.FolderName = nil
+	if req.FolderName != "" {
+		connection.FolderName = &req.FolderName
+	}
+	connection.Labels = labels
+	if req.Enabled != nil {
+		connection.Enabled = *req.Enabled
+	}
+	if err := s.connectorRepo.UpdateFolder(ctx, connection); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Import connection updated", "connection_id", id, "enabled", connection.Enabled)
+	return connection, nil
+}
+
+// Delete unlinks one of the user's accounts; the documents it imported are kept
+func (s *ImportService) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.getOwned(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.connectorRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Import connection deleted", "connection_id", id)
+	return nil
+}
+
+// SyncNow makes one of the user's connections sync at the next check rather
+// than at the end of its interval
+func (s *ImportService) SyncNow(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.getOwned(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.connectorRepo.ScheduleSync(ctx, id)
+}
+
+// Start syncs due connections every minute until the context is cancelled
+func (s *ImportService) Start(ctx context.Context) {
+	logger.Info("Import connector sync started", "interval", s.interval, "providers", len(s.providers))
+
+	ticker := time.NewTicker(connectorCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Import connector sync stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{Name: "connectors.sync", Run: s.SyncDue})
+		}
+	}
+}
+
+// SyncDue imports the new files of the connections whose sync is due. Each
+// connection is claimed until its next sync, so several API replicas can sync
+// concurrently without importing a file twice.
+func (s *ImportService) SyncDue(ctx context.Context) error {
+	connections, err := s.connectorRepo.ClaimDue(ctx, time.Now().UTC().Add(s.interval), connectorSyncBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, connection := range connections {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		ctx := logger.WithContext(ctx, "user_id", connection.UserID, "connection_id", connection.ID, "provider", connection.Provider)
+
+		var syncErr *string
+		if err := s.sync(ctx, connection); err != nil {
+			logger.FromContext(ctx).Warn("Import connection sync failed", "error", err)
+			message := err.Error()
+			if errors.Is(err, ErrConnectorRevoked) {
+				message = ErrConnectorRevoked.Message
+			}
+			syncErr = &message
+		}
+		if err := s.connectorRepo.FinishSync(ctx, connection.ID, time.Now().UTC(), syncErr); err != nil {
+			logger.FromContext(ctx).Error("Failed to record import connection sync", "error", err)
+		}
+	}
+	return nil
+}
+
+// sync imports the files of a connection's folder it has not imported yet
+func (s *ImportService) sync(ctx context.Context, connection *models.ImportConnection) error {
+	provider, accessToken, err := s.authorized(ctx, connection)
+	if err != nil {
+		return err
+	}
+
+	files, err := provider.ListFiles(ctx, accessToken, *connection.FolderID)
+	if errors.Is(err, connectors.ErrUnauthorized) {
+		return ErrConnectorRevoked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	remoteIDs := make([]string, len(files))
+	for i, file := range files {
+		remoteIDs[i] = file.ID
+	}
+	imported, err := s.connectorRepo.ImportedIDs(ctx, connection.ID, remoteIDs)
+	if err != nil {
+		return err
+	}
+
+	pending := slices.DeleteFunc(files, func(file connectors.Entry) bool {
+		return imported[file.ID]
+	})
+	if len(pending) > importBatchSize {
+		pending = pending[:importBatchSize]
+		// Come back for the rest at the next check
+		if err := s.connectorRepo.ScheduleSync(ctx, connection.ID); err != nil {
+			logger.FromContext(ctx).Warn("Failed to schedule import connection sync", "error", err)
+		}
+	}
+
+	for _, file := range pending {
+		if err := s.importFile(ctx, provider, accessToken, connection, file); err != nil {
+			if errors.Is(err, connectors.ErrUnauthorized) {
+				return ErrConnectorRevoked
+			}
+			return fmt.Errorf("failed to import %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// importFile downloads a remote file and stores it as a document of the
+// connection's user, then submits an OCR job for it. Files that are not
+// supported documents are recorded as skipped so they are not downloaded
+// again; a failed download is retried at the next sync.
+func (s *ImportService) importFile(ctx context.Context, provider connectors.Provider, accessToken string, connection *models.ImportConnection, entry connectors.Entry) error {
+	record := &models.ImportedFile{
+		ConnectionID: connection.ID,
+		RemoteID:     entry.ID,
+		Name:         entry.Name,
+	}
+	skip := func(reason string) error {
+		logger.FromContext(ctx).Info("Skipped imported file", "remote_id", entry.ID, "reason", reason)
+		record.SkippedReason = &reason
+		return s.connectorRepo.RecordImport(ctx, record)
+	}
+
+	if reason := s.ingester.skipReason(entry.Name, entry.Size); reason != "" {
+		return skip(reason)
+	}
+
+	// Download to a temporary file first: its content is checked before it
+	// is stored, which needs to read it more than once
+	temp, err := s.download(ctx, provider, accessToken, entry.ID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		temp.Close()
+		os.Remove(temp.Name())
+	}()
+
+	info, err := temp.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat downloaded file: %w", err)
+	}
+
+	outcome, err := s.ingester.ingest(ctx, temp, ingestRequest{
+		UserID: connection.UserID,
+		Name:   entry.Name,
+		Size:   info.Size(),
+		Labels: connection.Labels,
+		Metadata: map[string]any{
+			models.DocumentMetaImportConnectionID: connection.ID.String(),
+		},
+		Within: func(ctx context.Context, document *models.Document) error {
+			record.DocumentID = &document.ID
+			return s.connectorRepo.RecordImport(ctx, record)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	switch {
+	case outcome.Skipped != "":
+		return skip(outcome.Skipped)
+	case outcome.Existing:
+		// A file the user already has is recorded against the existing document
+		record.DocumentID = &outcome.Document.ID
+		return s.connectorRepo.RecordImport(ctx, record)
+	}
+
+	logger.FromContext(ctx).Info("Imported file", "document_id", outcome.Document.ID)
+
+	// The document stays imported when the job cannot be submitted, for
+	// instance when the user is out of credits; it can be processed later
+	if _, err := s.jobService.SubmitJob(ctx, models.JobSubmissionRequest{DocumentID: outcome.Document.ID}, connection.UserID); err != nil {
+		logger.FromContext(ctx).Warn("Failed to submit OCR job for imported file", "document_id", outcome.Document.ID, "error", err)
+	}
+	return nil
+}
+
+// download copies a remote file to a temporary file, reading at most one byte
+// more than the maximum file size so oversized files are told apart
+func (s *ImportService) download(ctx context.Context, provider connectors.Provider, accessToken, fileID string) (*os.File, error) {
+	body, err := provider.Download(ctx, accessToken, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	temp, err := os.CreateTemp("", "visekai-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	if _, err := io.Copy(temp, io.LimitReader(body, s.ingester.maxFileSize+1)); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	return temp, nil
+}
+
+// authorized returns the connection's provider with a current access token,
+// renewing it when it is about to expire
+func (s *ImportService) authorized(ctx context.Context, connection *models.ImportConnection) (connectors.Provider, string, error) {
+	provider, ok := s.providers[connection.Provider]
+	if !ok {
+		return nil, "", ErrConnectorUnavailable
+	}
+
+	if connection.TokenExpiry == nil || time.Until(*connection.TokenExpiry) > tokenRefreshMargin {
+		return provider, connection.AccessToken, nil
+	}
+
+	token, err := provider.Refresh(ctx, connection.RefreshToken)
+	if errors.Is(err, connectors.ErrUnauthorized) {
+		return nil, "", ErrConnectorRevoked
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to refresh access token: %w", err)
+	}
+
+	connection.AccessToken = token.AccessToken
+	if token.RefreshToken != "" {
+		connection.RefreshToken = token.RefreshToken
+	}
+	connection.TokenExpiry = tokenExpiry(token)
+	if err := s.connectorRepo.UpdateTokens(ctx, connection); err != nil {
+		return nil, "", err
+	}
+	return provider, connection.AccessToken, nil
+}
+
+// getOwned returns one of the user's connections
+func (s *ImportService) getOwned(ctx context.Context, id, userID uuid.UUID) (*models.ImportConnection, error) {
+	connection, err := s.connectorRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if connection.UserID != userID {
+		return nil, repository.ErrConnectionNotFound
+	}
+	return connection, nil
+}
+
+// tokenExpiry returns when a token expires, or nil when it does not
+func tokenExpiry(token *connectors.Token) *time.Time {
+	if token.Expiry.IsZero() {
+		return nil
+	}
+	expiry := token.Expiry.UTC()
+	return &expiry
+}
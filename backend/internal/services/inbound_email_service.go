@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	mailer "visekai/backend/internal/mail"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/scanner"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// inboundReplyInterval is how often finished emails are replied to
+	inboundReplyInterval = time.Minute
+	// inboundReplyBatch is the number of emails replied to per cycle
+	inboundReplyBatch = 20
+	// inboundReplyWait is how long a reply waits for the jobs of an email to
+	// finish before it is sent with the jobs still running
+	inboundReplyWait = time.Hour
+	// mailgunSignatureMaxAge is how old a Mailgun webhook signature may be
+	mailgunSignatureMaxAge = 5 * time.Minute
+)
+
+// ErrInboundSignatureInvalid is returned when a webhook does not come from
+// the mail provider
+var ErrInboundSignatureInvalid = apperrors.Forbidden("INB_002", "inbound email signature is invalid")
+
+// InboundEmailService turns emails into documents. Every user gets a secret
+// address at the inbound domain; the mail provider posts what is sent to it,
+// each attachment becomes a document processed with the user's default OCR
+// settings, and the user is emailed the links to the results once the jobs
+// finish.
+type InboundEmailService struct {
+	inboundRepo    *repository.InboundEmailRepository
+	userRepo       *repository.UserRepository
+	settingsRepo   *repository.UserSettingsRepository
+	jobRepo        *repository.JobRepository
+	transactor     *repository.Transactor
+	jobService     *JobService
+	ingester       *fileIngester
+	sender         *mailer.Sender
+	domain         string
+	signingKey     string
+	documentURL    string
+	maxAttachments int
+}
+
+// NewInboundEmailService creates a new inbound email service. Addresses are
+// at domain, webhooks are verified with the provider's signingKey, and replies
+// link to documentURL followed by a document's ID.
+func NewInboundEmailService(
+	inboundRepo *repository.InboundEmailRepository,
+	userRepo *repository.UserRepository,
+	settingsRepo *repository.UserSettingsRepository,
+	jobRepo *repository.JobRepository,
+	documentRepo *repository.DocumentRepository,
+	transactor *repository.Transactor,
+	storage *storage.Storage,
+	eventService *EventService,
+	jobService *JobService,
+	fileScanner scanner.Scanner,
+	scanFailOpen bool,
+	sender *mailer.Sender,
+	domain string,
+	signingKey string,
+	documentURL string,
+	maxAttachments int,
+	maxFileSize int64,
+	allowedExts []string,
+) *InboundEmailService {
+	if maxAttachments <= 0 {
+		maxAttachments = 10
+	}
+
+	return &InboundEmailService{
+		inboundRepo:  inboundRepo,
+		userRepo:     userRepo,
+		settingsRepo: settingsRepo,
+		jobRepo:      jobRepo,
+		transactor:   transactor,
+		jobService:   jobService,
+		ingester: &fileIngester{
+			documentRepo: documentRepo,
+			transactor:   transactor,
+			storage:      storage,
+			eventService: eventService,
+			scanner:      fileScanner,
+			scanFailOpen: scanFailOpen,
+			maxFileSize:  maxFileSize,
+			allowedExts:  allowedExts,
+		},
+		sender:         sender,
+		domain:         strings.ToLower(domain),
+		signingKey:     signingKey,
+		documentURL:    documentURL,
+		maxAttachments: maxAttachments,
+	}
+}
+
+// GetAddress returns the user's inbound address, giving them one on first use
+func (s *InboundEmailService) GetAddress(ctx context.Context, userID uuid.UUID) (*models.InboundEmailAddress, error) {
+	token, createdAt, err := s.inboundRepo.GetToken(ctx, userID)
+	if errors.Is(err, repository.ErrInboundAddressNotFound) {
+		return s.RotateAddress(ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.InboundEmailAddress{Address: token + "@" + s.domain, CreatedAt: createdAt}, nil
+}
+
+// RotateAddress gives the user a new inbound address; mail sent to the
+// previous one is refused from then on
+func (s *InboundEmailService) RotateAddress(ctx context.Context, userID uuid.UUID) (*models.InboundEmailAddress, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate inbound address: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	createdAt, err := s.inboundRepo.SetToken(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Inbound email address issued")
+	return &models.InboundEmailAddress{Address: token + "@" + s.domain, CreatedAt: createdAt}, nil
+}
+
+// VerifyMailgun checks the signature Mailgun adds to the webhooks it posts,
+// an HMAC-SHA256 of the timestamp and token keyed with the signing key, and
+// refuses signatures too old to be anything but replays
+func (s *InboundEmailService) VerifyMailgun(timestamp, token, signature string) error {
+	if s.signingKey == "" {
+		return ErrInboundSignatureInvalid
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInboundSignatureInvalid
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > mailgunSignatureMaxAge || age < -mailgunSignatureMaxAge {
+		return ErrInboundSignatureInvalid
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.signingKey))
+	mac.Write([]byte(timestamp + token))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return ErrInboundSignatureInvalid
+	}
+	return nil
+}
+
+// Receive ingests the attachments of an email sent to a user's inbound
+// address and submits an OCR job for each new document. A message delivered
+// again by the provider is ignored.
+func (s *InboundEmailService) Receive(ctx context.Context, msg models.InboundMessage) (*models.InboundEmail, error) {
+	userID, err := s.recipientUser(ctx, msg.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logger.WithContext(ctx, "user_id", userID)
+
+	if msg.MessageID != "" {
+		received, err := s.inboundRepo.Received(ctx, userID, msg.MessageID)
+		if err != nil {
+			return nil, err
+		}
+		if received {
+			logger.FromContext(ctx).Info("Ignored inbound email received already", "message_id", msg.MessageID)
+			return nil, nil
+		}
+	}
+
+	email := &models.InboundEmail{
+		UserID:      userID,
+		MessageID:   msg.MessageID,
+		Sender:      msg.Sender,
+		Subject:     msg.Subject,
+		DocumentIDs: []uuid.UUID{},
+		JobIDs:      []uuid.UUID{},
+		Skipped:     []string{},
+	}
+
+	settings, err := s.settingsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to load OCR defaults, using the policy's", "error", err)
+		settings = &models.UserSettings{}
+	}
+
+	for i, attachment := range msg.Attachments {
+		if i >= s.maxAttachments {
+			email.Skipped = append(email.Skipped, attachment.Filename+": too many attachments")
+			continue
+		}
+
+		document, err := s.ingestAttachment(ctx, userID, attachment, email)
+		if err != nil {
+			return nil, err
+		}
+		if document == nil {
+			continue
+		}
+
+		job, err := s.jobService.SubmitJob(ctx, models.JobSubmissionRequest{
+			DocumentID:     document.ID,
+			OCRMode:        settings.DefaultOCRMode,
+			ResolutionMode: settings.DefaultResolution,
+		}, userID)
+		if err != nil {
+			// The document stays; it can be processed later
+			logger.FromContext(ctx).Warn("Failed to submit OCR job for emailed file", "document_id", document.ID, "error", err)
+			continue
+		}
+		email.JobIDs = append(email.JobIDs, job.ID)
+	}
+
+	if err := s.inboundRepo.Create(ctx, email); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Inbound email received", "documents", len(email.DocumentIDs), "skipped", len(email.Skipped))
+	return email, nil
+}
+
+// ingestAttachment stores an attachment as a document and lists it on the
+// email. It returns the new document, or nil when the attachment was skipped
+// or the user already had it.
+func (s *InboundEmailService) ingestAttachment(ctx context.Context, userID uuid.UUID, attachment *multipart.FileHeader, email *models.InboundEmail) (*models.Document, error) {
+	src, err := attachment.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer src.Close()
+
+	outcome, err := s.ingester.ingest(ctx, src, ingestRequest{
+		UserID: userID,
+		Name:   attachment.Filename,
+		Size:   attachment.Size,
+		Metadata: map[string]any{
+			models.DocumentMetaEmailSender: email.Sender,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if outcome.Skipped != "" {
+		email.Skipped = append(email.Skipped, attachment.Filename+": "+outcome.Skipped)
+		return nil, nil
+	}
+
+	email.DocumentIDs = append(email.DocumentIDs, outcome.Document.ID)
+	if outcome.Existing {
+		return nil, nil
+	}
+	return outcome.Document, nil
+}
+
+// recipientUser returns the user owning the first recipient address at the
+// inbound domain. Subaddresses, as in token+invoices@domain, reach the user.
+func (s *InboundEmailService) recipientUser(ctx context.Context, recipients []string) (uuid.UUID, error) {
+	for _, recipient := range recipients {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			continue
+		}
+		local, domain, ok := strings.Cut(strings.ToLower(address.Address), "@")
+		if !ok || domain != s.domain {
+			continue
+		}
+		token, _, _ := strings.Cut(local, "+")
+		return s.inboundRepo.GetUserByToken(ctx, token)
+	}
+	return uuid.Nil, repository.ErrInboundAddressNotFound
+}
+
+// Start replies to emails whose jobs finished, every minute until the
+// context is cancelled
+func (s *InboundEmailService) Start(ctx context.Context) {
+	logger.Info("Inbound email replies started", "interval", inboundReplyInterval)
+
+	ticker := time.NewTicker(inboundReplyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Inbound email replies stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{Name: "inbound_email.reply", Run: s.SendReplies})
+		}
+	}
+}
+
+// SendReplies emails users the results of the emails whose jobs all
+// finished, or that waited for them long enough. Replies go to the account's
+// email address rather than the sender, so a leaked inbound address cannot be
+// used to send mail to others. A failed reply is retried at the next cycle.
+func (s *InboundEmailService) SendReplies(ctx context.Context) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		emails, err := s.inboundRepo.ClaimUnreplied(ctx, time.Now().UTC().Add(-inboundReplyWait), inboundReplyBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, email := range emails {
+			user, err := s.userRepo.GetByID(ctx, email.UserID)
+			if err != nil {
+				return err
+			}
+
+			err = s.sender.Send(ctx, mailer.Message{
+				To:        []string{user.Email},
+				Subject:   replySubject(email.Subject),
+				Body:      s.replyBody(ctx, email),
+				InReplyTo: email.MessageID,
+			})
+			if err != nil {
+				logger.Warn("Failed to reply to inbound email", "inbound_email_id", email.ID, "error", err)
+				continue
+			}
+
+			if err := s.inboundRepo.MarkReplied(ctx, email.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// replyBody lists the documents an email created with the status of their
+// jobs and links to them, then the attachments that were skipped
+func (s *InboundEmailService) replyBody(ctx context.Context, email *models.InboundEmail) string {
+	var b strings.Builder
+	b.WriteString("Your emailed documents were received.\n\n")
+
+	statuses := map[uuid.UUID]models.JobStatus{}
+	for _, jobID := range email.JobIDs {
+		if job, err := s.jobRepo.GetByID(ctx, jobID); err == nil {
+			statuses[job.DocumentID] = job.Status
+		}
+	}
+
+	for _, documentID := range email.DocumentIDs {
+		status, ok := statuses[documentID]
+		switch {
+		case !ok:
+			// Already among the user's documents, or the job could not be submitted
+			b.WriteString("- Received")
+		case status == models.JobStatusCompleted:
+			b.WriteString("- Processed")
+		case status == models.JobStatusFailed || status == models.JobStatusCancelled:
+			b.WriteString("- Processing " + string(status))
+		default:
+			b.WriteString("- Still processing")
+		}
+		b.WriteString(": " + s.documentURL + documentID.String() + "\n")
+	}
+
+	if len(email.Skipped) > 0 {
+		b.WriteString("\nNot imported:\n")
+		for _, skipped := range email.Skipped {
+			b.WriteString("- " + skipped + "\n")
+		}
+	}
+	return b.String()
+}
+
+// replySubject prefixes a subject with Re: unless it already is a reply
+func replySubject(subject string) string {
+	if subject == "" {
+		return "Your emailed documents"
+	}
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
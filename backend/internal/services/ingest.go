@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/scanner"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a file is not ingested
+const (
+	skipFileType = "file type not allowed"
+	skipFileSize = "file size exceeds maximum allowed size"
+	skipMalware  = "malware detected"
+)
+
+// fileIngester stores files that arrive other than through an upload, such as
+// imported or emailed ones, as documents, with the same checks as uploads
+type fileIngester struct {
+	documentRepo *repository.DocumentRepository
+	transactor   *repository.Transactor
+	storage      *storage.Storage
+	eventService *EventService
+	scanner      scanner.Scanner
+	scanFailOpen bool
+	maxFileSize  int64
+	allowedExts  []string
+}
+
+// ingestRequest is a file to store as a document
+type ingestRequest struct {
+	UserID   uuid.UUID
+	Name     string
+	Size     int64
+	Labels   []string
+	Metadata map[string]any
+	// Within runs in the transaction creating the document
+	Within func(ctx context.Context, document *models.Document) error
+}
+
+// ingestOutcome is what became of an ingested file
+type ingestOutcome struct {
+	// Document is the new document, or the user's existing document with the
+	// same content
+	Document *models.Document
+	Existing bool
+	// Skipped is why the file was not stored, when it was not
+	Skipped string
+}
+
+// skipReason returns why a file of this name and size would be skipped
+// without reading it, or an empty string
+func (f *fileIngester) skipReason(name string, size int64) string {
+	if !storage.ValidateFileType(storage.NormalizeFilename(name), f.allowedExts) {
+		return skipFileType
+	}
+	if size > f.maxFileSize {
+		return skipFileSize
+	}
+	return ""
+}
+
+// ingest checks a file's type, content and size, scans it for malware and
+// stores it as a document of the user, unless the user already has a
+// document with the same content
+func (f *fileIngester) ingest(ctx context.Context, src multipart.File, req ingestRequest) (*ingestOutcome, error) {
+	if reason := f.skipReason(req.Name, req.Size); reason != "" {
+		return &ingestOutcome{Skipped: reason}, nil
+	}
+	displayName := storage.NormalizeFilename(req.Name)
+
+	if _, err := storage.ValidateFileContent(src, displayName, req.Size); err != nil {
+		if errors.Is(err, storage.ErrContentMismatch) || errors.Is(err, storage.ErrEncryptedPDF) || errors.Is(err, storage.ErrCorruptPDF) {
+			return &ingestOutcome{Skipped: err.Error()}, nil
+		}
+		return nil, err
+	}
+
+	if f.scanner != nil {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind file: %w", err)
+		}
+		result, err := f.scanner.Scan(ctx, src)
+		if err != nil && !f.scanFailOpen {
+			return nil, fmt.Errorf("malware scan failed: %w", err)
+		}
+		if err == nil && result.Infected {
+			logger.Warn("Infected file rejected", "user_id", req.UserID, "signature", result.Signature)
+			return &ingestOutcome{Skipped: skipMalware}, nil
+		}
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind file: %w", err)
+	}
+	filePath, fileHash, err := f.storage.SaveReader(src, displayName, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	relativePath, err := f.storage.RelativePath(filePath)
+	if err != nil {
+		_ = f.storage.Release(ctx, filePath)
+		return nil, err
+	}
+
+	if existing, err := f.documentRepo.GetByHash(ctx, fileHash, req.UserID); err == nil && existing != nil {
+		_ = f.storage.Release(ctx, filePath)
+		return &ingestOutcome{Document: existing, Existing: true}, nil
+	}
+
+	// Keep display names unique among the user's documents
+	taken, err := f.documentRepo.FilenamesWithPrefix(ctx, req.UserID, strings.TrimSuffix(displayName, filepath.Ext(displayName)))
+	if err != nil {
+		logger.Warn("Failed to check filename collisions", "user_id", req.UserID, "error", err)
+	}
+	displayName = storage.DisambiguateFilename(displayName, func(name string) bool {
+		return taken[name]
+	})
+
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	if displayName != req.Name {
+		metadata[models.DocumentMetaSourceFilename] = storage.SourceFilename(req.Name)
+	}
+
+	mimeType := storage.GetMimeType(displayName)
+	document := &models.Document{
+		UserID:           req.UserID,
+		Filename:         relativePath,
+		OriginalFilename: displayName,
+		FilePath:         filePath,
+		FileSize:         req.Size,
+		MimeType:         mimeType,
+		FileHash:         fileHash,
+		NumPages:         CountPages(f.storage, filePath, mimeType),
+		Metadata:         metadata,
+		Labels:           req.Labels,
+	}
+
+	err = f.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := f.documentRepo.Create(ctx, document); err != nil {
+			return err
+		}
+
+		if req.Within != nil {
+			if err := req.Within(ctx, document); err != nil {
+				return err
+			}
+		}
+
+		return f.eventService.Record(ctx, models.NewDomainEvent(
+			models.EventDocumentUploaded, "document", document.ID, &req.UserID,
+			map[string]any{
+				"original_filename": document.OriginalFilename,
+				"file_size":         document.FileSize,
+				"mime_type":         document.MimeType,
+				"file_hash":         document.FileHash,
+			},
+		))
+	})
+	if err != nil {
+		_ = f.storage.Release(ctx, filePath)
+		return nil, err
+	}
+
+	return &ingestOutcome{Document: document}, nil
+}
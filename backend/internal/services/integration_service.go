@@ -0,0 +1,353 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// hookBatchSize is the number of hooks claimed per delivery cycle and the
+	// number of results delivered to each hook per cycle
+	hookBatchSize = 20
+	// maxHookFailures is the number of failed deliveries in a row after which
+	// a hook is removed
+	maxHookFailures = 50
+	// maxHookBackoff caps the delay between delivery attempts of a hook
+	maxHookBackoff = time.Hour
+	// maxPollLimit caps the number of results of a polling trigger page
+	maxPollLimit = 100
+)
+
+// ErrInvalidCursor is returned when a polling cursor was not issued by the API
+var ErrInvalidCursor = apperrors.Validation("VAL_051", "invalid cursor")
+
+// errPrivateTarget is returned when a webhook target resolves to a private address
+var errPrivateTarget = errors.New("webhook target resolves to a private address")
+
+// IntegrationService serves no-code tools such as Zapier and Make: a polling
+// trigger listing new results after a cursor, and REST hooks the new results
+// are posted to as they complete
+type IntegrationService struct {
+	hookRepo   *repository.IntegrationRepository
+	resultRepo *repository.ResultRepository
+	transactor *repository.Transactor
+	httpClient *http.Client
+	interval   time.Duration
+}
+
+// NewIntegrationService creates a new integration service. Unless
+// allowPrivate is set, hooks cannot target loopback, private or link-local
+// addresses.
+func NewIntegrationService(
+	hookRepo *repository.IntegrationRepository,
+	resultRepo *repository.ResultRepository,
+	transactor *repository.Transactor,
+	interval, timeout time.Duration,
+	allowPrivate bool,
+) *IntegrationService {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &IntegrationService{
+		hookRepo:   hookRepo,
+		resultRepo: resultRepo,
+		transactor: transactor,
+		httpClient: newWebhookClient(timeout, allowPrivate),
+		interval:   interval,
+	}
+}
+
+// PollResults returns the results of the user's documents that completed
+// after since, oldest first, or the latest ones when since is empty, as
+// polling triggers expect for their sample data
+func (s *IntegrationService) PollResults(ctx context.Context, userID uuid.UUID, since string, limit int) (*models.IntegrationResultPage, error) {
+	if limit < 1 || limit > maxPollLimit {
+		limit = maxPollLimit
+	}
+
+	var afterAt *time.Time
+	var afterID uuid.UUID
+	if since != "" {
+		at, id, err := decodeResultCursor(since)
+		if err != nil {
+			return nil, err
+		}
+		afterAt, afterID = &at, id
+	}
+
+	// One more result than asked for tells whether another page follows
+	fetch := limit
+	if afterAt != nil {
+		fetch++
+	}
+	results, err := s.resultRepo.ListIntegrationResults(ctx, userID, afterAt, afterID, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.IntegrationResultPage{Results: results, Cursor: since}
+	if len(results) > limit {
+		page.Results, page.HasMore = results[:limit], true
+	}
+	if n := len(page.Results); n > 0 {
+		last := page.Results[n-1]
+		page.Cursor = encodeResultCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+// Subscribe creates a REST hook posting the user's results that complete from
+// now on to the target URL. A hook created with an API key restricted to
+// labels keeps that restriction.
+func (s *IntegrationService) Subscribe(ctx context.Context, userID uuid.UUID, req models.HookSubscribeRequest) (*models.IntegrationHook, error) {
+	if !strings.HasPrefix(req.TargetURL, "https://") && !strings.HasPrefix(req.TargetURL, "http://") {
+		return nil, apperrors.Validation("VAL_052", "target_url must be an http or https URL")
+	}
+	if req.Event == "" {
+		req.Event = models.HookEventResultCompleted
+	}
+
+	hook := &models.IntegrationHook{
+		UserID:    userID,
+		Event:     req.Event,
+		TargetURL: req.TargetURL,
+		Labels:    repository.LabelScope(ctx),
+		CursorAt:  time.Now().UTC(),
+		CursorID:  uuid.Nil,
+	}
+	if err := s.hookRepo.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Integration hook subscribed", "hook_id", hook.ID, "user_id", userID, "event", hook.Event)
+	return hook, nil
+}
+
+// ListHooks returns the user's REST hooks, newest first
+func (s *IntegrationService) ListHooks(ctx context.Context, userID uuid.UUID) ([]*models.IntegrationHook, error) {
+	return s.hookRepo.ListByUser(ctx, userID)
+}
+
+// Unsubscribe deletes one of the user's REST hooks
+func (s *IntegrationService) Unsubscribe(ctx context.Context, id, userID uuid.UUID) error {
+	hook, err := s.hookRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hook.UserID != userID {
+		return repository.ErrHookNotFound
+	}
+
+	if err := s.hookRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	logger.Info("Integration hook unsubscribed", "hook_id", id, "user_id", userID)
+	return nil
+}
+
+// Start delivers new results to hooks on every interval until the context is cancelled
+func (s *IntegrationService) Start(ctx context.Context) {
+	logger.Info("Integration hook delivery started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Integration hook delivery stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{Name: "integrations.deliver_hooks", Run: s.DeliverPending})
+		}
+	}
+}
+
+// DeliverPending posts the results each due hook has not received yet, in
+// order. A hook stops at its first failed delivery and is retried with
+// exponential backoff; a target answering 410 Gone, as REST hook consumers do
+// once unsubscribed, or failing too often in a row is removed. Hooks are
+// claimed with row locks so several API replicas can deliver concurrently.
+func (s *IntegrationService) DeliverPending(ctx context.Context) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		hooks, err := s.hookRepo.ClaimDue(ctx, hookBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, hook := range hooks {
+			if err := s.deliverHook(ctx, hook); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// deliverHook posts a batch of new results to a hook and saves how far it got.
+// Only database errors are returned.
+func (s *IntegrationService) deliverHook(ctx context.Context, hook *models.IntegrationHook) error {
+	scoped := repository.WithLabelScope(ctx, hook.Labels)
+	results, err := s.resultRepo.ListIntegrationResults(scoped, hook.UserID, &hook.CursorAt, hook.CursorID, hookBatchSize)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	hook.NextAttemptAt = now.Add(s.interval)
+	for _, result := range results {
+		gone, err := s.post(ctx, hook, result)
+		if gone {
+			logger.Info("Integration hook removed by its target", "hook_id", hook.ID, "user_id", hook.UserID)
+			return s.hookRepo.Delete(ctx, hook.ID)
+		}
+		if err != nil {
+			hook.Failures++
+			message := err.Error()
+			hook.LastError = &message
+			logger.Warn("Failed to deliver integration hook", "hook_id", hook.ID, "result_id", result.ID, "failures", hook.Failures, "error", err)
+
+			if hook.Failures >= maxHookFailures {
+				logger.Warn("Integration hook removed after repeated failures", "hook_id", hook.ID, "user_id", hook.UserID)
+				return s.hookRepo.Delete(ctx, hook.ID)
+			}
+
+			backoff := time.Duration(1<<min(hook.Failures, 12)) * time.Second
+			if backoff > maxHookBackoff {
+				backoff = maxHookBackoff
+			}
+			hook.NextAttemptAt = now.Add(backoff)
+			break
+		}
+
+		hook.CursorAt, hook.CursorID = result.CreatedAt, result.ID
+		hook.Failures = 0
+		hook.LastError = nil
+		hook.LastDeliveredAt = &now
+	}
+
+	return s.hookRepo.UpdateDelivery(ctx, hook)
+}
+
+// post delivers one result to a hook's target. It reports whether the target
+// answered 410 Gone; any other non-2xx response is a failure.
+func (s *IntegrationService) post(ctx context.Context, hook *models.IntegrationHook, result *models.IntegrationResult) (bool, error) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", hook.TargetURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Visekai-Event", hook.Event)
+	req.Header.Set("X-Visekai-Hook-ID", hook.ID.String())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send hook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode == http.StatusGone {
+		return true, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false, fmt.Errorf("hook target returned status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// newWebhookClient returns a client for posting to URLs users configure.
+// Unless allowPrivate is set, it refuses loopback, private and link-local
+// addresses.
+func newWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: newWebhookDialer(timeout, allowPrivate).DialContext},
+		// A redirect could lead to an address the dialer would refuse
+		// only after the URL was accepted; treat it as a failure
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// newWebhookDialer returns a dialer for addresses users configure, refusing
+// loopback, private and link-local ones unless allowPrivate is set
+func newWebhookDialer(timeout time.Duration, allowPrivate bool) *net.Dialer {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = rejectPrivateAddress
+	}
+	return dialer
+}
+
+// rejectPrivateAddress refuses connections to addresses inside the network the
+// API runs in, so webhooks cannot be used to reach internal services
+func rejectPrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return errPrivateTarget
+	}
+	return nil
+}
+
+// encodeResultCursor encodes the position of a result in (created_at, id)
+// order as an opaque cursor
+func encodeResultCursor(at time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(at.UnixNano(), 10) + ":" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeResultCursor decodes a cursor made by encodeResultCursor
+func decodeResultCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	nanos, rawID, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return time.Unix(0, n).UTC(), id, nil
+}
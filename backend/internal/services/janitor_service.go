@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+)
+
+// orphanCheckBatchSize bounds the number of paths checked against the database per query
+const orphanCheckBatchSize = 500
+
+// importReportRetention is how long the error reports of imports can be downloaded
+const importReportRetention = 7 * 24 * time.Hour
+
+// JanitorReport summarizes a janitor run
+type JanitorReport struct {
+	OrphanedFilesRemoved int           `json:"orphaned_files_removed"`
+	DocumentsPurged      int           `json:"documents_purged"`
+	JobsPurged           int           `json:"jobs_purged"`
+	JobsArchived         int           `json:"jobs_archived"`
+	AccountsDeleted      int           `json:"accounts_deleted"`
+	ImportReportsRemoved int           `json:"import_reports_removed"`
+	Errors               int           `json:"errors"`
+	Duration             time.Duration `json:"duration"`
+}
+
+// JanitorService periodically removes orphaned files and expired data
+type JanitorService struct {
+	documentRepo    *repository.DocumentRepository
+	trashService    *TrashService
+	jobTrash        *JobTrashService
+	accountService  *AccountService
+	storage         *storage.Storage
+	interval        time.Duration
+	orphanFileGrace time.Duration
+}
+
+// NewJanitorService creates a new janitor service.
+// A zero grace period disables orphaned file removal.
+func NewJanitorService(
+	documentRepo *repository.DocumentRepository,
+	trashService *TrashService,
+	jobTrash *JobTrashService,
+	accountService *AccountService,
+	storage *storage.Storage,
+	interval time.Duration,
+	orphanFileGrace time.Duration,
+) *JanitorService {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &JanitorService{
+		documentRepo:    documentRepo,
+		trashService:    trashService,
+		jobTrash:        jobTrash,
+		accountService:  accountService,
+		storage:         storage,
+		interval:        interval,
+		orphanFileGrace: orphanFileGrace,
+	}
+}
+
+// Start runs the janitor on every interval until the context is cancelled
+func (s *JanitorService) Start(ctx context.Context) {
+	logger.Info("Janitor started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Janitor stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{
+				Name: "janitor.run",
+				Run: func(ctx context.Context) error {
+					s.Run(ctx)
+					return nil
+				},
+			})
+		}
+	}
+}
+
+// Run performs a single cleanup pass. Each task runs independently so one
+// failing task does not prevent the others from making progress.
+func (s *JanitorService) Run(ctx context.Context) *JanitorReport {
+	started := time.Now()
+	report := &JanitorReport{}
+
+	purged, err := s.trashService.PurgeExpired(ctx)
+	report.DocumentsPurged = purged
+	if err != nil {
+		report.Errors++
+		logger.Error("Janitor failed to purge trash", "error", err)
+	}
+
+	jobsPurged, err := s.jobTrash.PurgeExpired(ctx)
+	report.JobsPurged = jobsPurged
+	if err != nil {
+		report.Errors++
+		logger.Error("Janitor failed to purge deleted jobs", "error", err)
+	}
+
+	jobsArchived, err := s.jobTrash.ArchiveExpired(ctx)
+	report.JobsArchived = jobsArchived
+	if err != nil {
+		report.Errors++
+		logger.Error("Janitor failed to archive jobs", "error", err)
+	}
+
+	deleted, err := s.accountService.PurgeDue(ctx)
+	report.AccountsDeleted = deleted
+	if err != nil {
+		report.Errors++
+		logger.Error("Janitor failed to delete accounts", "error", err)
+	}
+
+	removed, err := s.removeOrphanedFiles(ctx)
+	report.OrphanedFilesRemoved = removed
+	if err != nil {
+		report.Errors++
+		logger.Error("Janitor failed to remove orphaned files", "error", err)
+	}
+
+	reports, err := s.removeExpiredImportReports()
+	report.ImportReportsRemoved = reports
+	if err != nil {
+		report.Errors++
+		logger.Error("Janitor failed to remove import reports", "error", err)
+	}
+
+	report.Duration = time.Since(started)
+	logger.Info("Janitor run completed",
+		"orphaned_files_removed", report.OrphanedFilesRemoved,
+		"documents_purged", report.DocumentsPurged,
+		"jobs_purged", report.JobsPurged,
+		"jobs_archived", report.JobsArchived,
+		"accounts_deleted", report.AccountsDeleted,
+		"import_reports_removed", report.ImportReportsRemoved,
+		"errors", report.Errors,
+		"duration", report.Duration,
+	)
+
+	return report
+}
+
+// removeOrphanedFiles deletes uploaded files no document refers to. Only files older
+// than the grace period are considered so uploads in flight are never touched.
+func (s *JanitorService) removeOrphanedFiles(ctx context.Context) (int, error) {
+	if s.orphanFileGrace <= 0 {
+		return 0, nil
+	}
+
+	files, err := s.storage.ListFiles(storage.DocumentsDir, time.Now().Add(-s.orphanFileGrace))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for start := 0; start < len(files); start += orphanCheckBatchSize {
+		batch := files[start:min(start+orphanCheckBatchSize, len(files))]
+
+		referenced, err := s.documentRepo.ReferencedPaths(ctx, batch)
+		if err != nil {
+			return removed, err
+		}
+
+		for _, path := range batch {
+			if referenced[path] {
+				continue
+			}
+			if err := s.storage.DeleteFile(path); err != nil {
+				logger.Warn("Failed to remove orphaned file", "path", path, "error", err)
+				continue
+			}
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// removeExpiredImportReports deletes import error reports past their retention
+func (s *JanitorService) removeExpiredImportReports() (int, error) {
+	files, err := s.storage.ListFiles(storage.ImportsDir, time.Now().Add(-importReportRetention))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, path := range files {
+		if err := s.storage.DeleteFile(path); err != nil {
+			logger.Warn("Failed to remove import report", "path", path, "error", err)
+			continue
+		}
+		removed++
+	}
+
+	return removed, nil
+}
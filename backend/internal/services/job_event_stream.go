@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// jobEventBufferSize is the number of events a subscriber can fall behind
+	// before it is dropped
+	jobEventBufferSize = 64
+	// jobEventBacklogLimit bounds the events replayed to a resuming subscriber
+	jobEventBacklogLimit = 500
+	// jobEventListenRetry is the delay before listening again after the
+	// connection failed
+	jobEventListenRetry = 5 * time.Second
+)
+
+// JobEventStream fans the job events committed by any instance out to the
+// users watching their jobs. It listens for the notifications of the
+// job_events trigger, so it holds one database connection while running.
+//
+// Subscribers that fall behind, and all subscribers when the listening
+// connection fails, are closed rather than left with a gap; clients resume
+// from the last event they saw.
+type JobEventStream struct {
+	jobRepo *repository.JobRepository
+
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[*JobEventSubscription]struct{}
+	// stopped is set once Start returns; later subscriptions start closed
+	stopped bool
+}
+
+// JobEventSubscription receives the job events of one user
+type JobEventSubscription struct {
+	stream *JobEventStream
+	userID uuid.UUID
+	// labels restricts the subscription to jobs with any of them; nil for all jobs
+	labels []string
+	events chan *models.JobEvent
+	closed bool
+}
+
+// NewJobEventStream creates a new job event stream
+func NewJobEventStream(jobRepo *repository.JobRepository) *JobEventStream {
+	return &JobEventStream{
+		jobRepo:     jobRepo,
+		subscribers: make(map[uuid.UUID]map[*JobEventSubscription]struct{}),
+	}
+}
+
+// Start listens for job events until the context is cancelled
+func (s *JobEventStream) Start(ctx context.Context) {
+	logger.Info("Job event stream started")
+
+	for {
+		err := s.jobRepo.ListenEvents(ctx, func(n models.JobEventNotification) {
+			s.dispatch(ctx, n)
+		})
+		s.closeAll(ctx.Err() != nil)
+
+		if ctx.Err() != nil {
+			logger.Info("Job event stream stopped")
+			return
+		}
+		logger.Warn("Job event stream interrupted", "error", err, "retry_in", jobEventListenRetry)
+
+		select {
+		case <-ctx.Done():
+			s.closeAll(true)
+			logger.Info("Job event stream stopped")
+			return
+		case <-time.After(jobEventListenRetry):
+		}
+	}
+}
+
+// Subscribe starts receiving a user's job events, restricted to jobs with any
+// of labels unless labels is nil. The subscription must be closed when done.
+func (s *JobEventStream) Subscribe(userID uuid.UUID, labels []string) *JobEventSubscription {
+	sub := &JobEventSubscription{
+		stream: s,
+		userID: userID,
+		labels: labels,
+		events: make(chan *models.JobEvent, jobEventBufferSize),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		sub.closed = true
+		close(sub.events)
+		return sub
+	}
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[*JobEventSubscription]struct{})
+	}
+	s.subscribers[userID][sub] = struct{}{}
+
+	return sub
+}
+
+// Backlog returns the user's job events after afterID that a resuming
+// subscriber missed, oldest first. The label scope in ctx applies.
+func (s *JobEventStream) Backlog(ctx context.Context, userID uuid.UUID, afterID int64) ([]*models.JobEvent, error) {
+	return s.jobRepo.ListEventsAfter(ctx, userID, afterID, jobEventBacklogLimit)
+}
+
+// dispatch delivers a new event to the subscribers of its job's owner
+func (s *JobEventStream) dispatch(ctx context.Context, n models.JobEventNotification) {
+	s.mu.Lock()
+	watched := len(s.subscribers[n.UserID]) > 0
+	s.mu.Unlock()
+	if !watched {
+		return
+	}
+
+	event, err := s.jobRepo.GetEvent(ctx, n.ID)
+	if err != nil {
+		logger.Warn("Failed to load job event", "event_id", n.ID, "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers[n.UserID] {
+		if sub.labels != nil && !slices.ContainsFunc(event.Labels, func(label string) bool {
+			return slices.Contains(sub.labels, label)
+		}) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			// Dropping the subscriber lets the client resume without a gap
+			logger.Debug("Job event subscriber fell behind", "user_id", sub.userID)
+			s.remove(sub)
+		}
+	}
+}
+
+// closeAll closes every subscription, and all later ones if stop is set
+func (s *JobEventStream) closeAll(stop bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = s.stopped || stop
+	for _, subs := range s.subscribers {
+		for sub := range subs {
+			s.remove(sub)
+		}
+	}
+}
+
+// remove closes a subscription; s.mu must be held
+func (s *JobEventStream) remove(sub *JobEventSubscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.events)
+
+	delete(s.subscribers[sub.userID], sub)
+	if len(s.subscribers[sub.userID]) == 0 {
+		delete(s.subscribers, sub.userID)
+	}
+}
+
+// Events returns the channel the subscription's events arrive on. It is
+// closed when the subscription is dropped and the client should resume.
+func (sub *JobEventSubscription) Events() <-chan *models.JobEvent {
+	return sub.events
+}
+
+// Close stops the subscription
+func (sub *JobEventSubscription) Close() {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+
+	sub.stream.remove(sub)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/metrics"
+)
+
+// jobExpiryBatchSize bounds the number of jobs expired per transaction
+const jobExpiryBatchSize = 200
+
+var jobsExpired = metrics.NewCounterVec(
+	"visekai_jobs_expired_total",
+	"OCR jobs failed because they stayed pending longer than the allowed lifetime.",
+	"ocr_mode",
+)
+
+// JobExpiryService fails jobs that were never picked up by a worker, for example
+// because dispatching them failed. Jobs that started processing are left alone.
+type JobExpiryService struct {
+	jobRepo      *repository.JobRepository
+	transactor   *repository.Transactor
+	eventService *EventService
+	interval     time.Duration
+	lifetime     time.Duration
+}
+
+// NewJobExpiryService creates a new job expiry service.
+// A zero lifetime disables expiry.
+func NewJobExpiryService(
+	jobRepo *repository.JobRepository,
+	transactor *repository.Transactor,
+	eventService *EventService,
+	interval time.Duration,
+	lifetime time.Duration,
+) *JobExpiryService {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &JobExpiryService{
+		jobRepo:      jobRepo,
+		transactor:   transactor,
+		eventService: eventService,
+		interval:     interval,
+		lifetime:     lifetime,
+	}
+}
+
+// Start expires stale pending jobs on every interval until the context is cancelled
+func (s *JobExpiryService) Start(ctx context.Context) {
+	if s.lifetime <= 0 {
+		return
+	}
+
+	logger.Info("Pending job expiry started", "interval", s.interval, "lifetime", s.lifetime)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Pending job expiry stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{
+				Name: "jobs.expire_pending",
+				Run: func(ctx context.Context) error {
+					_, err := s.ExpirePending(ctx)
+					return err
+				},
+			})
+		}
+	}
+}
+
+// ExpirePending marks jobs pending for longer than the lifetime as failed and
+// notifies their owners with a job.expired event. It returns the number of jobs expired.
+func (s *JobExpiryService) ExpirePending(ctx context.Context) (int, error) {
+	if s.lifetime <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-s.lifetime)
+	message := "Job expired: not processed within " + s.lifetime.String()
+
+	total := 0
+	for {
+		var expired []*models.OCRJob
+
+		// Fail the jobs and record the owner notifications atomically
+		err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			expired, err = s.jobRepo.ExpirePending(ctx, cutoff, message, jobExpiryBatchSize)
+			if err != nil {
+				return err
+			}
+
+			evts := make([]*models.DomainEvent, 0, len(expired))
+			for _, job := range expired {
+				evts = append(evts, models.NewDomainEvent(
+					models.EventJobExpired, "job", job.ID, &job.UserID,
+					map[string]any{
+						"document_id": job.DocumentID,
+						"ocr_mode":    job.OCRMode,
+						"created_at":  job.CreatedAt,
+						"reason":      message,
+					},
+				))
+			}
+			return s.eventService.Record(ctx, evts...)
+		})
+		if err != nil {
+			return total, err
+		}
+
+		for _, job := range expired {
+			jobsExpired.Inc(string(job.OCRMode))
+			logger.Warn("Expired stale pending job", "job_id", job.ID, "user_id", job.UserID, "created_at", job.CreatedAt)
+		}
+
+		total += len(expired)
+		if len(expired) < jobExpiryBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.Info("Pending job expiry completed", "expired", total)
+	}
+
+	return total, nil
+}
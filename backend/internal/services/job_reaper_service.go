@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/metrics"
+
+	"github.com/google/uuid"
+)
+
+// jobReaperBatchSize bounds the number of stuck jobs reset per statement
+const jobReaperBatchSize = 200
+
+var jobsReaped = metrics.NewCounterVec(
+	"visekai_jobs_reaped_total",
+	"OCR jobs found stuck in processing, by outcome (requeued or failed).",
+	"outcome",
+)
+
+// JobReaperService resets jobs that stayed in processing longer than the timeout,
+// for example because the worker running them crashed, so they are picked up again.
+type JobReaperService struct {
+	jobRepo    *repository.JobRepository
+	jobService *JobService
+	interval   time.Duration
+	timeout    time.Duration
+}
+
+// NewJobReaperService creates a new stuck job reaper.
+// A zero timeout disables the periodic sweep.
+func NewJobReaperService(
+	jobRepo *repository.JobRepository,
+	jobService *JobService,
+	interval time.Duration,
+	timeout time.Duration,
+) *JobReaperService {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &JobReaperService{
+		jobRepo:    jobRepo,
+		jobService: jobService,
+		interval:   interval,
+		timeout:    timeout,
+	}
+}
+
+// Start sweeps for stuck jobs on every interval until the context is cancelled
+func (s *JobReaperService) Start(ctx context.Context) {
+	if s.timeout <= 0 {
+		return
+	}
+
+	logger.Info("Stuck job reaper started", "interval", s.interval, "timeout", s.timeout)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stuck job reaper stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{
+				Name: "jobs.reap_stuck",
+				Run: func(ctx context.Context) error {
+					_, err := s.Sweep(ctx)
+					return err
+				},
+			})
+		}
+	}
+}
+
+// Sweep resets jobs processing for longer than the timeout to pending and queues
+// them again. Each job gets an incident note in its metadata; jobs without
+// retries left are failed instead. It also runs when the periodic sweep is disabled.
+func (s *JobReaperService) Sweep(ctx context.Context) (*models.StuckJobSweep, error) {
+	timeout := s.timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+
+	cutoff := time.Now().Add(-timeout)
+	note := "Job was still processing after " + timeout.String() + ", the worker is presumed lost"
+
+	sweep := &models.StuckJobSweep{
+		Requeued: []uuid.UUID{},
+		Failed:   []uuid.UUID{},
+	}
+	for {
+		jobs, err := s.jobRepo.RequeueStuck(ctx, cutoff, note, jobReaperBatchSize)
+		if err != nil {
+			return sweep, err
+		}
+
+		for _, job := range jobs {
+			if job.Status == models.JobStatusFailed {
+				sweep.Failed = append(sweep.Failed, job.ID)
+				jobsReaped.Inc("failed")
+				logger.Warn("Failed stuck job, no retries left", "job_id", job.ID, "retry_count", job.RetryCount)
+				continue
+			}
+
+			sweep.Requeued = append(sweep.Requeued, job.ID)
+			jobsReaped.Inc("requeued")
+			logger.Warn("Requeued stuck job", "job_id", job.ID, "retry_count", job.RetryCount)
+			s.jobService.enqueue(job.ID)
+		}
+
+		if len(jobs) < jobReaperBatchSize {
+			break
+		}
+	}
+
+	if len(sweep.Requeued)+len(sweep.Failed) > 0 {
+		logger.Info("Stuck job sweep completed", "requeued", len(sweep.Requeued), "failed", len(sweep.Failed))
+	}
+
+	return sweep, nil
+}
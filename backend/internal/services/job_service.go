@@ -2,37 +2,81 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"time"
 
+	"visekai/backend/internal/apperrors"
 	"visekai/backend/internal/models"
 	"visekai/backend/internal/ocr"
 	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
 	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/quality"
+	"visekai/backend/pkg/storage"
 
 	"github.com/google/uuid"
 )
 
 // JobService handles OCR job operations
 type JobService struct {
-	jobRepo      *repository.JobRepository
-	resultRepo   *repository.ResultRepository
-	documentRepo *repository.DocumentRepository
-	ocrClient    *ocr.Client
+	jobRepo       *repository.JobRepository
+	approvalRepo  *repository.ApprovalRepository
+	resultRepo    *repository.ResultRepository
+	regionRepo    *repository.RegionRepository
+	documentRepo  *repository.DocumentRepository
+	ocrClient     *ocr.Client
+	storage       *storage.Storage
+	transactor    *repository.Transactor
+	eventService  *EventService
+	policyService *OCRPolicyService
+	creditService *CreditService
+	entityService *EntityService
+	classifier    *ClassificationService
+	templates     *TemplateService
+	orientation   *OrientationService
+	runner        *tasks.Runner
 }
 
 // NewJobService creates a new job service
 func NewJobService(
 	jobRepo *repository.JobRepository,
+	approvalRepo *repository.ApprovalRepository,
 	resultRepo *repository.ResultRepository,
+	regionRepo *repository.RegionRepository,
 	documentRepo *repository.DocumentRepository,
 	ocrClient *ocr.Client,
+	storage *storage.Storage,
+	transactor *repository.Transactor,
+	eventService *EventService,
+	policyService *OCRPolicyService,
+	creditService *CreditService,
+	entityService *EntityService,
+	classificationService *ClassificationService,
+	templateService *TemplateService,
+	orientationService *OrientationService,
+	runner *tasks.Runner,
 ) *JobService {
 	return &JobService{
-		jobRepo:      jobRepo,
-		resultRepo:   resultRepo,
-		documentRepo: documentRepo,
-		ocrClient:    ocrClient,
+		jobRepo:       jobRepo,
+		approvalRepo:  approvalRepo,
+		resultRepo:    resultRepo,
+		regionRepo:    regionRepo,
+		documentRepo:  documentRepo,
+		ocrClient:     ocrClient,
+		storage:       storage,
+		transactor:    transactor,
+		eventService:  eventService,
+		policyService: policyService,
+		creditService: creditService,
+		entityService: entityService,
+		classifier:    classificationService,
+		templates:     templateService,
+		orientation:   orientationService,
+		runner:        runner,
 	}
 }
 
@@ -41,11 +85,16 @@ func (s *JobService) SubmitJob(ctx context.Context, req models.JobSubmissionRequ
 	// Verify document exists and belongs to user
 	document, err := s.documentRepo.GetByID(ctx, req.DocumentID)
 	if err != nil {
-		return nil, fmt.Errorf("document not found: %w", err)
+		return nil, err
 	}
 
 	if document.UserID != userID {
-		return nil, fmt.Errorf("unauthorized: document does not belong to user")
+		return nil, apperrors.Forbidden("AUTH_004", "document does not belong to user")
+	}
+
+	// Apply the organization's OCR defaults and limits
+	if err := s.policyService.Apply(ctx, &req, document); err != nil {
+		return nil, err
 	}
 
 	// Create job
@@ -58,21 +107,77 @@ func (s *JobService) SubmitJob(ctx context.Context, req models.JobSubmissionRequ
 		MaxRetries:     3,
 		RetryCount:     0,
 		Metadata:       req.Metadata,
+		Labels:         document.Labels,
+	}
+
+	// Jobs over the policy's approval thresholds wait for an approver
+	reason, orgID, err := s.policyService.ApprovalReason(ctx, req, document)
+	if err != nil {
+		return nil, err
+	}
+	pages := submissionPages(req, document)
+	if reason != "" {
+		if err := s.holdForApproval(ctx, job, pages, reason, orgID); err != nil {
+			return nil, err
+		}
+
+		logger.FromContext(ctx).Info("OCR job awaiting approval", "job_id", job.ID, "document_id", job.DocumentID, "reason", reason)
+
+		return job, nil
 	}
 
-	err = s.jobRepo.Create(ctx, job)
+	// Charge the job's credits along with creating it
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.jobRepo.Create(ctx, job); err != nil {
+			return fmt.Errorf("failed to create job: %w", err)
+		}
+		return s.creditService.Charge(ctx, job, pages)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create job: %w", err)
+		return nil, err
 	}
 
-	logger.Info("OCR job submitted", "job_id", job.ID, "document_id", job.DocumentID, "user_id", userID)
+	logger.FromContext(ctx).Info("OCR job submitted", "job_id", job.ID, "document_id", job.DocumentID)
 
 	// Start processing asynchronously
-	go s.processJob(context.Background(), job.ID)
+	s.enqueue(job.ID)
 
 	return job, nil
 }
 
+// holdForApproval creates job awaiting approval along with its approval request
+// for the approvers of the organization whose policy held it, and notifies them
+// with a job.approval_requested event. The job is charged right away and
+// refunded if rejected.
+func (s *JobService) holdForApproval(ctx context.Context, job *models.OCRJob, pages int, reason string, orgID *uuid.UUID) error {
+	job.Status = models.JobStatusAwaitingApproval
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.jobRepo.Create(ctx, job); err != nil {
+			return fmt.Errorf("failed to create job: %w", err)
+		}
+
+		if err := s.creditService.Charge(ctx, job, pages); err != nil {
+			return err
+		}
+
+		approval := &models.JobApproval{JobID: job.ID, Reason: reason, OrgID: orgID}
+		if err := s.approvalRepo.Create(ctx, approval); err != nil {
+			return err
+		}
+
+		return s.eventService.Record(ctx, models.NewDomainEvent(
+			models.EventJobApprovalRequested, "job", job.ID, &job.UserID,
+			map[string]any{
+				"document_id":     job.DocumentID,
+				"ocr_mode":        job.OCRMode,
+				"resolution_mode": job.ResolutionMode,
+				"reason":          reason,
+			},
+		))
+	})
+}
+
 // GetJob retrieves a job by ID
 func (s *JobService) GetJob(ctx context.Context, jobID uuid.UUID, userID uuid.UUID) (*models.OCRJob, error) {
 	job, err := s.jobRepo.GetByID(ctx, jobID)
@@ -82,22 +187,33 @@ func (s *JobService) GetJob(ctx context.Context, jobID uuid.UUID, userID uuid.UU
 
 	// Verify ownership
 	if job.UserID != userID {
-		return nil, fmt.Errorf("unauthorized: job does not belong to user")
+		return nil, apperrors.Forbidden("AUTH_004", "job does not belong to user")
 	}
 
 	return job, nil
 }
 
-// ListJobs retrieves jobs for a user with pagination
-func (s *JobService) ListJobs(ctx context.Context, userID uuid.UUID, page, perPage int) ([]*models.OCRJob, *models.Pagination, error) {
-	if page < 1 {
-		page = 1
+// GetJobEvents retrieves the timeline of one of a user's jobs
+func (s *JobService) GetJobEvents(ctx context.Context, jobID uuid.UUID, userID uuid.UUID) ([]*models.JobEvent, error) {
+	if _, err := s.GetJob(ctx, jobID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.jobRepo.ListEvents(ctx, jobID)
+}
+
+// ListJobs retrieves jobs for a user with pagination, optionally filtered by
+// status and sorted
+func (s *JobService) ListJobs(ctx context.Context, userID uuid.UUID, req models.JobListRequest) ([]*models.OCRJob, *models.Pagination, error) {
+	if req.Page < 1 {
+		req.Page = 1
 	}
-	if perPage < 1 || perPage > 100 {
-		perPage = 20
+	if req.PerPage < 1 || req.PerPage > 100 {
+		req.PerPage = 20
 	}
+	page, perPage := req.Page, req.PerPage
 
-	jobs, total, err := s.jobRepo.GetByUserID(ctx, userID, page, perPage)
+	jobs, total, err := s.jobRepo.GetByUserID(ctx, userID, req)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -126,21 +242,25 @@ func (s *JobService) CancelJob(ctx context.Context, jobID uuid.UUID, userID uuid
 
 	// Verify ownership
 	if job.UserID != userID {
-		return fmt.Errorf("unauthorized: job does not belong to user")
+		return apperrors.Forbidden("AUTH_004", "job does not belong to user")
 	}
 
 	// Check if job can be cancelled
 	if job.Status == models.JobStatusCompleted || job.Status == models.JobStatusFailed || job.Status == models.JobStatusCancelled {
-		return fmt.Errorf("cannot cancel job with status: %s", job.Status)
+		return apperrors.Conflict("JOB_002", fmt.Sprintf("cannot cancel job with status: %s", job.Status))
 	}
 
 	// Update status
+	// The job may have finished since it was read
 	err = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusCancelled, nil)
+	if errors.Is(err, repository.ErrInvalidTransition) {
+		return apperrors.Conflict("JOB_002", fmt.Sprintf("cannot cancel job: %v", err))
+	}
 	if err != nil {
 		return fmt.Errorf("failed to cancel job: %w", err)
 	}
 
-	logger.Info("OCR job cancelled", "job_id", jobID, "user_id", userID)
+	logger.FromContext(ctx).Info("OCR job cancelled", "job_id", jobID)
 
 	return nil
 }
@@ -155,25 +275,47 @@ func (s *JobService) DeleteJob(ctx context.Context, jobID uuid.UUID, userID uuid
 
 	// Verify ownership
 	if job.UserID != userID {
-		return fmt.Errorf("unauthorized: job does not belong to user")
+		return apperrors.Forbidden("AUTH_004", "job does not belong to user")
 	}
 
 	// Check if job can be deleted
-	if job.Status == models.JobStatusPending || job.Status == models.JobStatusProcessing {
-		return fmt.Errorf("cannot delete active job, cancel it first")
+	if job.Status == models.JobStatusAwaitingApproval || job.Status == models.JobStatusPending || job.Status == models.JobStatusProcessing {
+		return apperrors.Conflict("JOB_003", "cannot delete active job, cancel it first")
 	}
 
-	// Delete job (cascade will delete results)
-	err = s.jobRepo.Delete(ctx, jobID)
+	// Move the job and its results to the trash, purged after the retention
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		return s.jobRepo.SoftDelete(ctx, jobID)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete job: %w", err)
 	}
 
-	logger.Info("OCR job deleted", "job_id", jobID, "user_id", userID)
+	logger.FromContext(ctx).Info("OCR job deleted", "job_id", jobID)
 
 	return nil
 }
 
+// ArchiveJob archives a job of the user, hiding it from the default job
+// listings, or unarchives it
+func (s *JobService) ArchiveJob(ctx context.Context, jobID, userID uuid.UUID, archived bool) (*models.OCRJob, error) {
+	job, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.UserID != userID {
+		return nil, apperrors.Forbidden("AUTH_004", "job does not belong to user")
+	}
+
+	job.ArchivedAt, err = s.jobRepo.SetArchived(ctx, jobID, archived)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
 // GetJobResult retrieves the result for a job
 func (s *JobService) GetJobResult(ctx context.Context, jobID uuid.UUID, userID uuid.UUID) (*models.OCRResult, error) {
 	// Verify job ownership
@@ -183,7 +325,12 @@ func (s *JobService) GetJobResult(ctx context.Context, jobID uuid.UUID, userID u
 	}
 
 	if job.UserID != userID {
-		return nil, fmt.Errorf("unauthorized: job does not belong to user")
+		return nil, apperrors.Forbidden("AUTH_004", "job does not belong to user")
+	}
+
+	// Page reprocessing jobs patch the result of an earlier job
+	if resultID, _, ok := reprocessTarget(job); ok {
+		return s.resultRepo.GetByID(ctx, resultID)
 	}
 
 	// Get result
@@ -195,63 +342,329 @@ func (s *JobService) GetJobResult(ctx context.Context, jobID uuid.UUID, userID u
 	return result, nil
 }
 
+// SubmitPageReprocess creates a job that runs OCR again on one page of a result,
+// numbered from 1, and splices the new text into the result as a new version.
+// The job uses the original mode and, unless overridden, its resolution.
+func (s *JobService) SubmitPageReprocess(ctx context.Context, resultID uuid.UUID, page int, req models.ReprocessPageRequest, userID uuid.UUID) (*models.OCRJob, error) {
+	result, err := s.resultRepo.GetByID(ctx, resultID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.jobRepo.GetByID(ctx, result.JobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.UserID != userID {
+		return nil, apperrors.Forbidden("AUTH_004", "result does not belong to user")
+	}
+
+	if page < 1 || page > max(result.NumPages, 1) {
+		return nil, ErrPageOutOfRange
+	}
+
+	metadata := map[string]any{
+		models.JobMetaReprocessResultID: result.ID.String(),
+		models.JobMetaReprocessPage:     page,
+	}
+	if len(req.BBox) == 4 {
+		if req.BBox[0] >= req.BBox[2] || req.BBox[1] >= req.BBox[3] {
+			return nil, ErrInvalidRegion
+		}
+		document, err := s.documentRepo.GetByID(ctx, result.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(document.MimeType, "image/") {
+			return nil, ErrRegionUnsupported
+		}
+		metadata[models.JobMetaReprocessRegion] = req.BBox
+	}
+
+	// Splicing needs the text of every page; a single-page result is its only page
+	if result.NumPages > 1 {
+		pages, err := s.resultRepo.ListCurrentPages(ctx, result.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(pages) == 0 {
+			return nil, ErrNoPageText
+		}
+	}
+
+	resolution := req.ResolutionMode
+	if resolution == "" {
+		resolution = original.ResolutionMode
+	}
+
+	return s.SubmitJob(ctx, models.JobSubmissionRequest{
+		DocumentID:     result.DocumentID,
+		OCRMode:        original.OCRMode,
+		ResolutionMode: resolution,
+		Priority:       original.Priority,
+		Metadata:       metadata,
+	}, userID)
+}
+
+// SubmitDocumentReOCR creates a job that runs OCR again on one page of a
+// document, or a region of it, patching the document's latest result
+func (s *JobService) SubmitDocumentReOCR(ctx context.Context, documentID uuid.UUID, req models.DocumentReOCRRequest, userID uuid.UUID) (*models.OCRJob, error) {
+	results, err := s.resultRepo.GetByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		// Report another user's document as such rather than as unprocessed
+		document, err := s.documentRepo.GetByID(ctx, documentID)
+		if err != nil {
+			return nil, err
+		}
+		if document.UserID != userID {
+			return nil, apperrors.Forbidden("AUTH_004", "document does not belong to user")
+		}
+		return nil, ErrNoResultToPatch
+	}
+
+	return s.SubmitPageReprocess(ctx, results[0].ID, req.Page, req.ReprocessPageRequest, userID)
+}
+
+// reprocessTarget returns the result and page a page reprocessing job patches
+func reprocessTarget(job *models.OCRJob) (uuid.UUID, int, bool) {
+	rawID, _ := job.Metadata[models.JobMetaReprocessResultID].(string)
+	resultID, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.Nil, 0, false
+	}
+
+	// Numbers decoded from the JSONB column are float64
+	var page int
+	switch v := job.Metadata[models.JobMetaReprocessPage].(type) {
+	case int:
+		page = v
+	case float64:
+		page = int(v)
+	}
+	if page < 1 {
+		return uuid.Nil, 0, false
+	}
+
+	return resultID, page, true
+}
+
+// reprocessRegion returns the region of the page a page reprocessing job redoes,
+// if it is limited to one
+func reprocessRegion(job *models.OCRJob) ([4]float64, bool) {
+	var bbox [4]float64
+	// Numbers decoded from the JSONB column are float64
+	switch v := job.Metadata[models.JobMetaReprocessRegion].(type) {
+	case []float64:
+		if len(v) != 4 {
+			return bbox, false
+		}
+		copy(bbox[:], v)
+	case []any:
+		if len(v) != 4 {
+			return bbox, false
+		}
+		for i, n := range v {
+			f, ok := n.(float64)
+			if !ok {
+				return bbox, false
+			}
+			bbox[i] = f
+		}
+	default:
+		return bbox, false
+	}
+	return bbox, true
+}
+
+// enqueue schedules a job for processing on the worker pool. When the queue is
+// full the job stays pending and is picked up again by ProcessNextJob.
+func (s *JobService) enqueue(jobID uuid.UUID) {
+	if err := s.runner.Submit(s.processTask(jobID)); err != nil {
+		logger.Warn("Failed to queue OCR job, leaving it pending", "job_id", jobID, "error", err)
+	}
+}
+
+// EnqueuePending queues jobs left pending by a previous run, or requeued with
+// visekai-admin against the database, in priority order until the queue is full.
+// It returns the number of jobs queued.
+func (s *JobService) EnqueuePending(ctx context.Context) (int, error) {
+	jobs, err := s.jobRepo.GetPendingJobs(ctx, s.runner.Workers()+s.runner.Capacity())
+	if err != nil {
+		return 0, err
+	}
+
+	for i, job := range jobs {
+		if err := s.runner.Submit(s.processTask(job.ID)); err != nil {
+			return i, nil
+		}
+	}
+
+	return len(jobs), nil
+}
+
+// processTask wraps processJob in a task that fails the job if processing panics
+func (s *JobService) processTask(jobID uuid.UUID) tasks.Task {
+	return tasks.Task{
+		Name: "ocr.process_job",
+		Run: func(ctx context.Context) error {
+			s.processJob(ctx, jobID)
+			return nil
+		},
+		OnError: func(err error) {
+			errorMsg := fmt.Sprintf("Job processing crashed: %v", err)
+			if updateErr := s.jobRepo.UpdateStatus(context.Background(), jobID, models.JobStatusFailed, &errorMsg); updateErr != nil {
+				logger.Error("Failed to mark crashed job as failed", "job_id", jobID, "error", updateErr)
+			}
+		},
+	}
+}
+
 // processJob processes an OCR job asynchronously
 func (s *JobService) processJob(ctx context.Context, jobID uuid.UUID) {
-	logger.Info("Starting OCR job processing", "job_id", jobID)
+	// Everything logged while processing carries the job and its user
+	ctx = logger.WithContext(ctx, "job_id", jobID)
+	logger.FromContext(ctx).Info("Starting OCR job processing")
 
 	// Get job
 	job, err := s.jobRepo.GetByID(ctx, jobID)
 	if err != nil {
-		logger.Error("Failed to get job", "job_id", jobID, "error", err)
+		logger.FromContext(ctx).Error("Failed to get job", "error", err)
 		return
 	}
+	ctx = logger.WithContext(ctx, "user_id", job.UserID)
 
 	// Check if job is still pending
 	if job.Status != models.JobStatusPending {
-		logger.Warn("Job is not pending, skipping", "job_id", jobID, "status", job.Status)
+		logger.FromContext(ctx).Warn("Job is not pending, skipping", "status", job.Status)
 		return
 	}
 
-	// Update status to processing
-	err = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusProcessing, nil)
+	// Leave the job pending while the OCR service is down, rather than
+	// spending its retries on requests failing fast
+	if wait := s.ocrClient.Breaker().Wait(); wait > 0 {
+		s.runner.SubmitAfter(wait, s.processTask(jobID))
+		return
+	}
+
+	// Claim the job while holding a shared lock on its document, so the document
+	// cannot be deleted between the liveness check and the job becoming visible
+	// as processing. Deletion refuses or cancels processing jobs from then on.
+	var document *models.Document
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.lockDocument(ctx, job, models.JobStatusPending); err != nil {
+			return err
+		}
+
+		if err := s.claimJob(ctx, jobID, models.JobStatusPending); err != nil {
+			return err
+		}
+
+		if err := s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusProcessing, nil); err != nil {
+			return err
+		}
+
+		document, err = s.documentRepo.GetByID(ctx, job.DocumentID)
+		return err
+	})
+	if errors.Is(err, errJobNotActive) {
+		logger.FromContext(ctx).Warn("Job is not pending, skipping")
+		return
+	}
 	if err != nil {
-		logger.Error("Failed to update job status", "job_id", jobID, "error", err)
+		s.failJob(ctx, jobID, models.JobStatusPending, fmt.Sprintf("Failed to get document: %v", err))
+		logger.FromContext(ctx).Error("Failed to get document", "document_id", job.DocumentID, "error", err)
 		return
 	}
 
-	// Get document
-	document, err := s.documentRepo.GetByID(ctx, job.DocumentID)
+	// Open the stored file, decrypting it if encrypted at rest
+	file, err := s.storage.Open(document.FilePath)
+	if err != nil {
+		s.failJob(ctx, jobID, models.JobStatusProcessing, fmt.Sprintf("Failed to open document: %v", err))
+		logger.FromContext(ctx).Error("Failed to open document", "document_id", job.DocumentID, "error", err)
+		return
+	}
+
+	// Send images upright, however they were photographed or scanned, and only
+	// the region being reprocessed when there is one
+	var source io.Reader
+	var filename string
+	region, hasRegion := reprocessRegion(job)
+	if hasRegion {
+		source, filename, err = s.orientation.UprightRegion(ctx, document, file, region)
+	} else {
+		source, filename, err = s.orientation.Upright(ctx, document, file)
+	}
 	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to get document: %v", err)
-		_ = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusFailed, &errorMsg)
-		logger.Error("Failed to get document", "job_id", jobID, "document_id", job.DocumentID, "error", err)
+		file.Close()
+		s.failJob(ctx, jobID, models.JobStatusProcessing, fmt.Sprintf("Failed to read document: %v", err))
+		logger.FromContext(ctx).Error("Failed to read document", "document_id", job.DocumentID, "error", err)
 		return
 	}
 
-	// Process document with OCR service
+	// Process document with OCR service, or only the page being reprocessed
 	startTime := time.Now()
-	ocrResponse, err := s.ocrClient.ProcessDocument(ctx, document.FilePath, job.OCRMode, job.ResolutionMode)
+	resultID, page, reprocess := reprocessTarget(job)
+	pages := document.NumPages
+	if hasRegion {
+		pages = 1
+	}
+	var ocrResponse *ocr.OCRResponse
+	switch {
+	case reprocess:
+		ocrResponse, err = s.ocrClient.ProcessPage(ctx, source, filename, page, job.OCRMode, job.ResolutionMode)
+	case s.wantsPageEvents(job, document):
+		ocrResponse, err = s.processPages(ctx, job, document, source)
+	default:
+		ocrResponse, err = s.ocrClient.ProcessDocument(ctx, source, filename, pages, job.OCRMode, job.ResolutionMode)
+	}
+	file.Close()
+	if errors.Is(err, ocr.ErrCircuitOpen) {
+		// The circuit opened while the job was claimed; it waits without
+		// counting a retry
+		if err := s.requeueJob(ctx, job, err.Error()); err != nil {
+			if !errors.Is(err, errJobNotActive) {
+				logger.FromContext(ctx).Error("Failed to requeue job", "error", err)
+			}
+			return
+		}
+		s.runner.SubmitAfter(max(s.ocrClient.Breaker().Wait(), time.Second), s.processTask(jobID))
+		return
+	}
 	if err != nil {
 		errorMsg := fmt.Sprintf("OCR processing failed: %v", err)
-		_ = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusFailed, &errorMsg)
+		retry := job.RetryCount < job.MaxRetries
+		if err := s.retryOrFailJob(ctx, job, retry, errorMsg); err != nil {
+			if !errors.Is(err, errJobNotActive) {
+				logger.FromContext(ctx).Error("Failed to update failed job", "error", err)
+			}
+			return
+		}
 
-		// Check if we should retry
-		if job.RetryCount < job.MaxRetries {
-			_ = s.jobRepo.IncrementRetryCount(ctx, jobID)
-			_ = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusPending, nil)
-			logger.Warn("OCR processing failed, will retry", "job_id", jobID, "retry_count", job.RetryCount+1, "error", err)
+		if retry {
+			logger.FromContext(ctx).Warn("OCR processing failed, will retry", "retry_count", job.RetryCount+1, "error", err)
 
 			// Retry after a delay
-			time.Sleep(10 * time.Second)
-			go s.processJob(context.Background(), jobID)
+			s.runner.SubmitAfter(10*time.Second, s.processTask(jobID))
 		} else {
-			logger.Error("OCR processing failed after max retries", "job_id", jobID, "error", err)
+			logger.FromContext(ctx).Error("OCR processing failed after max retries", "error", err)
 		}
 		return
 	}
 
 	processingTime := time.Since(startTime)
-	logger.Info("OCR processing completed", "job_id", jobID, "processing_time", processingTime)
+	logger.FromContext(ctx).Info("OCR processing completed", "processing_time", processingTime)
+
+	if reprocess {
+		s.completePageReprocess(ctx, job, resultID, page, ocrResponse)
+		return
+	}
+
+	// Assess how well the document was recognized
+	documentQuality := assessQuality(job, ocrResponse, s.measureImage(document))
 
 	// Save result
 	result := &models.OCRResult{
@@ -265,22 +678,595 @@ func (s *JobService) processJob(ctx context.Context, jobID uuid.UUID) {
 		NumPages:         ocrResponse.NumPages,
 	}
 
-	err = s.resultRepo.Create(ctx, result)
+	// Pick out dates, amounts and the like, what kind of document this is and
+	// the fields its templates ask for, before saving
+	s.entityService.Annotate(ctx, result)
+	classification := s.classifier.Classify(ctx, result)
+	documentType := document.Type()
+	if classification != nil {
+		documentType = classification.Type
+	}
+	s.templates.Annotate(ctx, job.UserID, documentType, result, toResultRegions(uuid.Nil, ocrResponse.Regions))
+
+	// Persist the result, the status transition and their events atomically,
+	// unless the document was deleted or the job cancelled in the meantime
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.lockDocument(ctx, job, models.JobStatusProcessing); err != nil {
+			return err
+		}
+
+		if err := s.claimJob(ctx, jobID, models.JobStatusProcessing); err != nil {
+			return err
+		}
+
+		if err := s.resultRepo.Create(ctx, result); err != nil {
+			return err
+		}
+
+		if err := s.resultRepo.CreatePages(ctx, toResultPages(result.ID, job, ocrResponse, s.creditService.Cost(1, job.ResolutionMode))); err != nil {
+			return err
+		}
+
+		if err := s.regionRepo.CreateBatch(ctx, toResultRegions(result.ID, ocrResponse.Regions)); err != nil {
+			return err
+		}
+
+		if err := s.documentRepo.UpdateQuality(ctx, job.DocumentID, documentQuality); err != nil {
+			return err
+		}
+
+		if classification != nil {
+			if err := s.documentRepo.UpdateClassification(ctx, job.DocumentID, classification); err != nil {
+				return err
+			}
+		}
+
+		if err := s.creditService.Settle(ctx, job, result.NumPages); err != nil {
+			return err
+		}
+
+		if err := s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusCompleted, nil); err != nil {
+			return err
+		}
+
+		return s.eventService.Record(ctx,
+			models.NewDomainEvent(
+				models.EventResultCreated, "result", result.ID, &job.UserID,
+				map[string]any{
+					"job_id":             jobID,
+					"document_id":        job.DocumentID,
+					"confidence_score":   result.ConfidenceScore,
+					"processing_time_ms": result.ProcessingTimeMs,
+					"num_pages":          result.NumPages,
+				},
+			),
+			models.NewDomainEvent(
+				models.EventJobCompleted, "job", jobID, &job.UserID,
+				map[string]any{
+					"document_id":     job.DocumentID,
+					"result_id":       result.ID,
+					"ocr_mode":        job.OCRMode,
+					"resolution_mode": job.ResolutionMode,
+				},
+			),
+		)
+	})
+	if errors.Is(err, errJobNotActive) {
+		logger.FromContext(ctx).Info("Job was cancelled during processing, discarding result")
+		return
+	}
 	if err != nil {
-		errorMsg := fmt.Sprintf("Failed to save result: %v", err)
-		_ = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusFailed, &errorMsg)
-		logger.Error("Failed to save result", "job_id", jobID, "error", err)
+		s.failJob(ctx, jobID, models.JobStatusProcessing, fmt.Sprintf("Failed to save result: %v", err))
+		logger.FromContext(ctx).Error("Failed to save result", "error", err)
 		return
 	}
 
-	// Update job status to completed
-	err = s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusCompleted, nil)
+	logger.FromContext(ctx).Info("OCR job completed successfully", "result_id", result.ID)
+}
+
+// measureImage measures the resolution and sharpness of an image document for
+// its quality assessment. It returns nil for other documents and images that
+// cannot be decoded.
+func (s *JobService) measureImage(document *models.Document) *quality.ImageMetrics {
+	if !strings.HasPrefix(document.MimeType, "image/") {
+		return nil
+	}
+
+	file, err := s.storage.Open(document.FilePath)
+	if err != nil {
+		logger.Warn("Failed to open document for quality assessment", "document_id", document.ID, "error", err)
+		return nil
+	}
+	defer file.Close()
+
+	metrics, err := quality.AnalyzeImage(file)
+	if err != nil {
+		logger.Debug("Image quality not measured", "document_id", document.ID, "mime_type", document.MimeType, "error", err)
+		return nil
+	}
+
+	return metrics
+}
+
+// wantsPageEvents reports whether a job asked for per-page events on a
+// multi-page document and events are delivered anywhere
+func (s *JobService) wantsPageEvents(job *models.OCRJob, document *models.Document) bool {
+	enabled, _ := job.Metadata[models.JobMetaPageEvents].(bool)
+	return enabled && document.NumPages > 1 && s.eventService.Enabled()
+}
+
+// processPages runs OCR on one page of the document at a time, reading the first
+// page from file, and records a job.page_completed event with a snippet of the
+// text as each page finishes, so consumers can start on early pages of a long
+// document. The pages are combined into a response like ProcessDocument's.
+// Pages of a retried job are reported again.
+func (s *JobService) processPages(ctx context.Context, job *models.OCRJob, document *models.Document, file io.Reader) (*ocr.OCRResponse, error) {
+	combined := &ocr.OCRResponse{Success: true, NumPages: document.NumPages}
+
+	for page := 1; page <= document.NumPages; page++ {
+		var resp *ocr.OCRResponse
+		var err error
+		if page == 1 {
+			resp, err = s.ocrClient.ProcessPage(ctx, file, document.Filename, page, job.OCRMode, job.ResolutionMode)
+		} else {
+			// Stop early when the job was cancelled meanwhile
+			current, getErr := s.jobRepo.GetByID(ctx, job.ID)
+			if getErr != nil {
+				return nil, getErr
+			}
+			if current.Status != models.JobStatusProcessing {
+				return nil, errJobNotActive
+			}
+
+			pageFile, openErr := s.storage.Open(document.FilePath)
+			if openErr != nil {
+				return nil, fmt.Errorf("failed to open document: %w", openErr)
+			}
+			resp, err = s.ocrClient.ProcessPage(ctx, pageFile, document.Filename, page, job.OCRMode, job.ResolutionMode)
+			pageFile.Close()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+
+		width, height := singlePageSize(resp)
+		combined.Pages = append(combined.Pages, ocr.OCRPage{
+			Page:           page,
+			Text:           resp.Text,
+			Markdown:       resp.Markdown,
+			Confidence:     resp.Confidence,
+			ProcessingTime: resp.ProcessingTime,
+			Backend:        resp.Backend,
+			Width:          width,
+			Height:         height,
+		})
+		combined.Regions = append(combined.Regions, resp.Regions...)
+		combined.ProcessingTime += resp.ProcessingTime
+
+		if page < document.NumPages {
+			if err := s.jobRepo.UpdateProgress(ctx, job.ID, page*100/document.NumPages); err != nil {
+				logger.FromContext(ctx).Warn("Failed to update job progress", "page", page, "error", err)
+			}
+		}
+
+		snippet, truncated := textSnippet(resp.Text, pageEventSnippetLength)
+		err = s.eventService.Record(ctx, models.NewDomainEvent(
+			models.EventJobPageCompleted, "job", job.ID, &job.UserID,
+			map[string]any{
+				"document_id":       job.DocumentID,
+				"page":              page,
+				"num_pages":         document.NumPages,
+				"confidence_score":  resp.Confidence,
+				"snippet":           snippet,
+				"snippet_truncated": truncated,
+				"attempt":           job.RetryCount + 1,
+			},
+		))
+		if err != nil {
+			// The event is a preview of the result, so losing one does not fail the job
+			logger.FromContext(ctx).Warn("Failed to record page event", "page", page, "error", err)
+		}
+	}
+
+	raw := make([]string, len(combined.Pages))
+	markdown := make([]string, len(combined.Pages))
+	for i, page := range combined.Pages {
+		raw[i] = page.Text
+		markdown[i] = page.Markdown
+		combined.Confidence += page.Confidence
+	}
+	combined.Text = strings.Join(raw, "\n\n")
+	combined.Markdown = strings.Join(markdown, "\n\n")
+	combined.Confidence /= float64(len(combined.Pages))
+
+	return combined, nil
+}
+
+// pageEventSnippetLength is the number of characters of page text sent in a
+// job.page_completed event; the full text is in the result once the job completes
+const pageEventSnippetLength = 500
+
+// textSnippet returns the first n characters of text and whether it was cut short
+func textSnippet(text string, n int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text, false
+	}
+	return string(runes[:n]), true
+}
+
+// completePageReprocess splices the text of a reprocessed page into its result
+// as a new page version, replaces the page's regions and completes the job
+func (s *JobService) completePageReprocess(ctx context.Context, job *models.OCRJob, resultID uuid.UUID, page int, ocrResponse *ocr.OCRResponse) {
+	var result *models.OCRResult
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.lockDocument(ctx, job, models.JobStatusProcessing); err != nil {
+			return err
+		}
+
+		if err := s.claimJob(ctx, job.ID, models.JobStatusProcessing); err != nil {
+			return err
+		}
+
+		// Serialize concurrent reprocessing of pages of the same result
+		if _, err := s.resultRepo.GetVersionForUpdate(ctx, resultID); err != nil {
+			return err
+		}
+
+		var err error
+		result, err = s.resultRepo.GetByID(ctx, resultID)
+		if err != nil {
+			return err
+		}
+
+		pages, err := s.resultRepo.ListCurrentPages(ctx, resultID)
+		if err != nil {
+			return err
+		}
+
+		// A single-page result without page rows is its own first page version
+		if len(pages) == 0 {
+			pages = []*models.ResultPage{{
+				ResultID:         resultID,
+				PageNumber:       1,
+				Version:          1,
+				JobID:            &result.JobID,
+				RawText:          result.RawText,
+				MarkdownText:     result.MarkdownText,
+				ConfidenceScore:  result.ConfidenceScore,
+				ProcessingTimeMs: &result.ProcessingTimeMs,
+			}}
+			if err := s.resultRepo.CreatePages(ctx, pages); err != nil {
+				return err
+			}
+		}
+
+		// Add the new page version, replacing the current one
+		credits := s.creditService.Cost(1, job.ResolutionMode)
+		newPage := &models.ResultPage{
+			ResultID:         resultID,
+			PageNumber:       page,
+			Version:          1,
+			JobID:            &job.ID,
+			ResolutionMode:   &job.ResolutionMode,
+			RawText:          ocrResponse.Text,
+			MarkdownText:     ocrResponse.Markdown,
+			ConfidenceScore:  ocrResponse.Confidence,
+			ProcessingTimeMs: &ocrResponse.ProcessingTime,
+			Backend:          &ocrResponse.Backend,
+			Credits:          &credits,
+		}
+		newPage.ImageWidth, newPage.ImageHeight = pageImageSize(singlePageSize(ocrResponse))
+		current := &models.ResultPage{ResultID: resultID, PageNumber: page}
+		replaced := false
+		for i, p := range pages {
+			if p.PageNumber == page {
+				newPage.Version = p.Version + 1
+				current = p
+				pages[i] = newPage
+				replaced = true
+			}
+		}
+
+		// A region patches the current version of the page rather than replacing it
+		regions := toResultRegions(resultID, ocrResponse.Regions)
+		if bbox, ok := reprocessRegion(job); ok {
+			existing, err := s.regionRepo.ListByResult(ctx, resultID, page)
+			if err != nil {
+				return err
+			}
+			regions = patchRegion(current, newPage, existing, bbox, ocrResponse)
+			// The boxes stay measured in the whole page, not the region read
+			newPage.ImageWidth, newPage.ImageHeight = current.ImageWidth, current.ImageHeight
+		}
+
+		if !replaced {
+			pages = append(pages, newPage)
+			sort.Slice(pages, func(i, j int) bool { return pages[i].PageNumber < pages[j].PageNumber })
+		}
+		if err := s.resultRepo.CreatePages(ctx, []*models.ResultPage{newPage}); err != nil {
+			return err
+		}
+
+		// Rebuild the document text from the current pages
+		spliceResultPages(result, pages)
+		if err := s.resultRepo.UpdateText(ctx, result); err != nil {
+			return err
+		}
+
+		if err := s.regionRepo.DeletePage(ctx, resultID, page); err != nil {
+			return err
+		}
+		if err := s.regionRepo.CreateBatch(ctx, regions); err != nil {
+			return err
+		}
+
+		if err := s.jobRepo.UpdateStatus(ctx, job.ID, models.JobStatusCompleted, nil); err != nil {
+			return err
+		}
+
+		return s.eventService.Record(ctx,
+			models.NewDomainEvent(
+				models.EventResultUpdated, "result", resultID, &job.UserID,
+				map[string]any{
+					"job_id":           job.ID,
+					"document_id":      job.DocumentID,
+					"page":             page,
+					"page_version":     newPage.Version,
+					"version":          result.Version,
+					"confidence_score": result.ConfidenceScore,
+				},
+			),
+			models.NewDomainEvent(
+				models.EventJobCompleted, "job", job.ID, &job.UserID,
+				map[string]any{
+					"document_id":     job.DocumentID,
+					"result_id":       resultID,
+					"ocr_mode":        job.OCRMode,
+					"resolution_mode": job.ResolutionMode,
+					"page":            page,
+				},
+			),
+		)
+	})
+	if errors.Is(err, errJobNotActive) {
+		logger.FromContext(ctx).Info("Job was cancelled during processing, discarding page")
+		return
+	}
 	if err != nil {
-		logger.Error("Failed to update job status to completed", "job_id", jobID, "error", err)
+		s.failJob(ctx, job.ID, models.JobStatusProcessing, fmt.Sprintf("Failed to save reprocessed page: %v", err))
+		logger.FromContext(ctx).Error("Failed to save reprocessed page", "result_id", resultID, "page", page, "error", err)
 		return
 	}
 
-	logger.Info("OCR job completed successfully", "job_id", jobID, "result_id", result.ID)
+	// The spliced text may hold other entities and field values; they are not
+	// worth failing the job over
+	if s.reannotate(ctx, job, result) {
+		if err := s.resultRepo.UpdateJSONData(ctx, resultID, result.JSONData); err != nil {
+			logger.FromContext(ctx).Warn("Failed to save result annotations", "result_id", resultID, "error", err)
+		}
+	}
+
+	logger.FromContext(ctx).Info("Page reprocessing completed", "result_id", resultID, "page", page, "version", result.Version)
+}
+
+// reannotate extracts entities and template fields again from a result whose
+// text changed. It reports whether the result's JSON data changed.
+func (s *JobService) reannotate(ctx context.Context, job *models.OCRJob, result *models.OCRResult) bool {
+	changed := s.entityService.Annotate(ctx, result)
+
+	document, err := s.documentRepo.GetByID(ctx, job.DocumentID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get document for template fields", "document_id", job.DocumentID, "error", err)
+		return changed
+	}
+	regions, err := s.regionRepo.ListByResult(ctx, result.ID, 0)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get regions for template fields", "result_id", result.ID, "error", err)
+		return changed
+	}
+
+	return s.templates.Annotate(ctx, job.UserID, document.Type(), result, regions) || changed
+}
+
+// spliceResultPages sets the text of a result from its pages, separated by blank
+// lines, and its confidence to the mean page confidence
+func spliceResultPages(result *models.OCRResult, pages []*models.ResultPage) {
+	raw := make([]string, len(pages))
+	markdown := make([]string, len(pages))
+	confidence := 0.0
+	for i, page := range pages {
+		raw[i] = page.RawText
+		markdown[i] = page.MarkdownText
+		confidence += page.ConfidenceScore
+	}
+
+	result.RawText = strings.Join(raw, "\n\n")
+	result.MarkdownText = strings.Join(markdown, "\n\n")
+	if len(pages) > 0 {
+		result.ConfidenceScore = confidence / float64(len(pages))
+	}
+}
+
+// toResultPages converts the per-page text of an OCR response into first page
+// versions costing creditsPerPage each. Pages the service did not time get an
+// even share of the request's processing time. A single-page response without
+// pages becomes the first page itself, so every processed page is accounted for.
+func toResultPages(resultID uuid.UUID, job *models.OCRJob, ocrResponse *ocr.OCRResponse, creditsPerPage int) []*models.ResultPage {
+	ocrPages := ocrResponse.Pages
+	if len(ocrPages) == 0 && ocrResponse.NumPages <= 1 {
+		ocrPages = []ocr.OCRPage{{
+			Page:           1,
+			Text:           ocrResponse.Text,
+			Markdown:       ocrResponse.Markdown,
+			Confidence:     ocrResponse.Confidence,
+			ProcessingTime: ocrResponse.ProcessingTime,
+		}}
+	}
+
+	pages := make([]*models.ResultPage, 0, len(ocrPages))
+	for i, p := range ocrPages {
+		number := p.Page
+		if number < 1 {
+			number = i + 1
+		}
+		processingTime := p.ProcessingTime
+		if processingTime == 0 {
+			processingTime = ocrResponse.ProcessingTime / len(ocrPages)
+		}
+		backend := p.Backend
+		if backend == "" {
+			backend = ocrResponse.Backend
+		}
+		credits := creditsPerPage
+		width, height := pageImageSize(p.Width, p.Height)
+		pages = append(pages, &models.ResultPage{
+			ResultID:         resultID,
+			PageNumber:       number,
+			Version:          1,
+			JobID:            &job.ID,
+			ResolutionMode:   &job.ResolutionMode,
+			RawText:          p.Text,
+			MarkdownText:     p.Markdown,
+			ConfidenceScore:  p.Confidence,
+			ProcessingTimeMs: &processingTime,
+			Backend:          &backend,
+			Credits:          &credits,
+			ImageWidth:       width,
+			ImageHeight:      height,
+		})
+	}
+	return pages
+}
+
+// singlePageSize returns the page image size a response for one page reports
+func singlePageSize(resp *ocr.OCRResponse) (width, height int) {
+	if len(resp.Pages) != 1 {
+		return 0, 0
+	}
+	return resp.Pages[0].Width, resp.Pages[0].Height
+}
+
+// pageImageSize returns a page image size as stored on a result page, unknown
+// unless both sides were reported
+func pageImageSize(width, height int) (*int, *int) {
+	if width <= 0 || height <= 0 {
+		return nil, nil
+	}
+	return &width, &height
+}
+
+// toResultRegions converts OCR service regions into result regions
+func toResultRegions(resultID uuid.UUID, ocrRegions []ocr.OCRRegion) []*models.ResultRegion {
+	regions := make([]*models.ResultRegion, 0, len(ocrRegions))
+	for i, r := range ocrRegions {
+		level := models.RegionLevel(r.Level)
+		if level != models.RegionLevelLine {
+			level = models.RegionLevelWord
+		}
+		page := r.Page
+		if page < 1 {
+			page = 1
+		}
+		regions = append(regions, &models.ResultRegion{
+			ResultID:   resultID,
+			PageNumber: page,
+			Level:      level,
+			Sequence:   i,
+			Text:       r.Text,
+			X0:         r.BBox[0],
+			Y0:         r.BBox[1],
+			X1:         r.BBox[2],
+			Y1:         r.BBox[3],
+			Confidence: r.Confidence,
+		})
+	}
+	return regions
+}
+
+var (
+	// ErrPageOutOfRange is returned for a page number the result does not have
+	ErrPageOutOfRange = apperrors.Validation("VAL_021", "page is out of range")
+	// ErrNoPageText is returned when a multi-page result has no per-page text to splice into
+	ErrNoPageText = apperrors.Conflict("JOB_006", "result has no per-page text, reprocess the whole document instead")
+	// ErrInvalidRegion is returned for a region whose corners are not top-left and bottom-right
+	ErrInvalidRegion = apperrors.Validation("VAL_046", "region must be given as x0, y0, x1, y1 with x0 < x1 and y0 < y1")
+	// ErrNoResultToPatch is returned when re-running OCR on part of a document that has no result yet
+	ErrNoResultToPatch = apperrors.Conflict("JOB_008", "document has no result to patch, submit an OCR job first")
+)
+
+// errJobNotActive is returned when a job left the expected status concurrently
+var errJobNotActive = errors.New("job is no longer active")
+
+// claimJob locks a job until the transaction in ctx ends and verifies it still
+// has the expected status
+func (s *JobService) claimJob(ctx context.Context, jobID uuid.UUID, expected models.JobStatus) error {
+	status, err := s.jobRepo.GetStatusForUpdate(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if status != expected {
+		return errJobNotActive
+	}
+	return nil
+}
+
+// failJob marks a job as failed if it still has the expected status, so a job
+// cancelled or requeued in the meantime keeps its new state
+func (s *JobService) failJob(ctx context.Context, jobID uuid.UUID, expected models.JobStatus, errorMsg string) {
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.claimJob(ctx, jobID, expected); err != nil {
+			return err
+		}
+		return s.jobRepo.UpdateStatus(ctx, jobID, models.JobStatusFailed, &errorMsg)
+	})
+	if err != nil && !errors.Is(err, errJobNotActive) {
+		logger.FromContext(ctx).Error("Failed to mark job as failed", "error", err)
+	}
+}
+
+// retryOrFailJob returns a processing job whose attempt failed to the queue with
+// its retry count incremented, or fails it, in one transaction so a crash cannot
+// leave it failed but counted as retried or vice versa
+func (s *JobService) retryOrFailJob(ctx context.Context, job *models.OCRJob, retry bool, errorMsg string) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.claimJob(ctx, job.ID, models.JobStatusProcessing); err != nil {
+			return err
+		}
+
+		if !retry {
+			return s.jobRepo.UpdateStatus(ctx, job.ID, models.JobStatusFailed, &errorMsg)
+		}
+
+		if err := s.jobRepo.IncrementRetryCount(ctx, job.ID); err != nil {
+			return err
+		}
+		return s.jobRepo.UpdateStatus(ctx, job.ID, models.JobStatusPending, &errorMsg)
+	})
+}
+
+// requeueJob returns a processing job to the queue without counting a retry
+func (s *JobService) requeueJob(ctx context.Context, job *models.OCRJob, errorMsg string) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.claimJob(ctx, job.ID, models.JobStatusProcessing); err != nil {
+			return err
+		}
+		return s.jobRepo.UpdateStatus(ctx, job.ID, models.JobStatusPending, &errorMsg)
+	})
+}
+
+// lockDocument takes a shared lock on the job's document until the transaction
+// in ctx ends. A document deleted together with its jobs reports the job as no
+// longer active rather than failing it.
+func (s *JobService) lockDocument(ctx context.Context, job *models.OCRJob, expected models.JobStatus) error {
+	err := s.documentRepo.LockForShare(ctx, job.DocumentID)
+	if err == nil {
+		return nil
+	}
+	if claimErr := s.claimJob(ctx, job.ID, expected); claimErr != nil {
+		return claimErr
+	}
+	return fmt.Errorf("document is no longer available: %w", err)
 }
 
 // GetPendingJobs retrieves pending jobs for processing
@@ -299,6 +1285,6 @@ func (s *JobService) ProcessNextJob(ctx context.Context) error {
 		return nil // No jobs to process
 	}
 
-	go s.processJob(context.Background(), jobs[0].ID)
+	s.enqueue(jobs[0].ID)
 	return nil
 }
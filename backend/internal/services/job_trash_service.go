@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// JobTrashService manages deleted jobs, which are kept with their results
+// until their users' retention elapses, and archives users' old finished jobs
+type JobTrashService struct {
+	jobRepo      *repository.JobRepository
+	settingsRepo *repository.UserSettingsRepository
+	retention    time.Duration
+	batchSize    int
+}
+
+// NewJobTrashService creates a new job trash service. The retention applies
+// to users who did not set their own; zero keeps their deleted jobs forever.
+func NewJobTrashService(
+	jobRepo *repository.JobRepository,
+	settingsRepo *repository.UserSettingsRepository,
+	retention time.Duration,
+) *JobTrashService {
+	return &JobTrashService{
+		jobRepo:      jobRepo,
+		settingsRepo: settingsRepo,
+		retention:    retention,
+		batchSize:    500,
+	}
+}
+
+// List retrieves the jobs in a user's trash with when each will be purged
+func (s *JobTrashService) List(ctx context.Context, userID uuid.UUID, page, perPage int) ([]models.TrashedJob, int, error) {
+	settings, err := s.settingsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	retention := s.retention
+	if settings.JobTrashRetentionDays > 0 {
+		retention = time.Duration(settings.JobTrashRetentionDays) * 24 * time.Hour
+	}
+
+	jobs, total, err := s.jobRepo.ListDeletedByUser(ctx, userID, page, perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]models.TrashedJob, 0, len(jobs))
+	for _, job := range jobs {
+		item := models.TrashedJob{OCRJob: job}
+		if retention > 0 && job.DeletedAt != nil {
+			purgeAt := job.DeletedAt.Add(retention)
+			item.PurgeAt = &purgeAt
+		}
+		items = append(items, item)
+	}
+
+	return items, total, nil
+}
+
+// Restore moves a job owned by the user, with its results, out of the trash
+func (s *JobTrashService) Restore(ctx context.Context, jobID, userID uuid.UUID) (*models.OCRJob, error) {
+	if err := s.jobRepo.Restore(ctx, jobID, userID); err != nil {
+		return nil, err
+	}
+	return s.jobRepo.GetByID(ctx, jobID)
+}
+
+// PurgeExpired permanently deletes the jobs, and their results, that have been
+// in the trash longer than their users' retention. It returns the number of
+// jobs purged.
+func (s *JobTrashService) PurgeExpired(ctx context.Context) (int, error) {
+	var retention *time.Duration
+	if s.retention > 0 {
+		retention = &s.retention
+	}
+
+	purged := 0
+	for {
+		n, err := s.jobRepo.PurgeDeleted(ctx, retention, s.batchSize)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+		if n < s.batchSize {
+			break
+		}
+	}
+
+	if purged > 0 {
+		logger.Info("Purged expired jobs from trash", "count", purged)
+	}
+
+	return purged, nil
+}
+
+// ArchiveExpired archives the finished jobs of users who have them archived
+// after a number of days, once they are older. It returns the number of jobs
+// archived.
+func (s *JobTrashService) ArchiveExpired(ctx context.Context) (int, error) {
+	archived := 0
+	for {
+		n, err := s.jobRepo.ArchiveFinished(ctx, s.batchSize)
+		archived += n
+		if err != nil {
+			return archived, err
+		}
+		if n < s.batchSize {
+			break
+		}
+	}
+
+	if archived > 0 {
+		logger.Info("Archived old jobs", "count", archived)
+	}
+
+	return archived, nil
+}
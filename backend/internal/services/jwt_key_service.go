@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/delivery"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// jwtKeyRefreshInterval is how often the ring is reloaded, picking up keys
+	// rotated by other instances
+	jwtKeyRefreshInterval = time.Minute
+	// jwtKeyMissReload is the least time between reloads caused by tokens
+	// signed with an unknown key
+	jwtKeyMissReload = 10 * time.Second
+	// jwtRSAKeyBits is the size of generated RSA keys
+	jwtRSAKeyBits = 2048
+)
+
+// ErrJWTKeysDisabled is returned when tokens are signed with the shared secret
+var ErrJWTKeysDisabled = apperrors.Conflict("AUTH_010", "tokens are signed with the shared secret; set JWT_ALGORITHM to RS256 or EdDSA to use signing keys")
+
+// jwtKey is a key of the ring, ready to sign or verify
+type jwtKey struct {
+	meta   *models.JWTSigningKey
+	method jwt.SigningMethod
+	signer crypto.Signer
+}
+
+// jwtKeyRing is a snapshot of the keys: current signs, all of them verify
+type jwtKeyRing struct {
+	current *jwtKey
+	keys    []*jwtKey
+	byID    map[string]*jwtKey
+}
+
+// JWTKeyService keeps the ring of asymmetric keys signing access and refresh
+// tokens, stored sealed in the database shared by every instance. The newest
+// key signs; keys retired by a rotation keep verifying the tokens they signed
+// until those expire, and are then removed.
+type JWTKeyService struct {
+	keyRepo          *repository.JWTKeyRepository
+	sealer           *delivery.Sealer
+	algorithm        string
+	rotationInterval time.Duration
+	retention        time.Duration
+
+	ring atomic.Pointer[jwtKeyRing]
+	// mu serializes reloads and rotations
+	mu       sync.Mutex
+	lastMiss time.Time
+}
+
+// NewJWTKeyService creates a key service generating algorithm keys (RS256 or
+// EdDSA), rotated every rotationInterval when it is positive. Retired keys are
+// kept for retention, the lifetime of the longest lived tokens.
+func NewJWTKeyService(
+	keyRepo *repository.JWTKeyRepository,
+	sealer *delivery.Sealer,
+	algorithm string,
+	rotationInterval time.Duration,
+	retention time.Duration,
+) *JWTKeyService {
+	return &JWTKeyService{
+		keyRepo:          keyRepo,
+		sealer:           sealer,
+		algorithm:        algorithm,
+		rotationInterval: rotationInterval,
+		retention:        retention,
+	}
+}
+
+// Init loads the ring, generating a key when none signs with the configured
+// algorithm
+func (s *JWTKeyService) Init(ctx context.Context) error {
+	if err := s.reload(ctx); err != nil {
+		return err
+	}
+	if current := s.ring.Load().current; current == nil || current.meta.Algorithm != s.algorithm {
+		if _, err := s.Rotate(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start reloads the ring every minute until the context is cancelled,
+// rotating the signing key once it is older than the rotation interval and
+// removing retired keys no unexpired token can be signed with
+func (s *JWTKeyService) Start(ctx context.Context) {
+	logger.Info("JWT key rotation started", "algorithm", s.algorithm, "rotation_interval", s.rotationInterval)
+
+	ticker := time.NewTicker(jwtKeyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("JWT key rotation stopped")
+			return
+		case <-ticker.C:
+		}
+
+		_ = tasks.Run(ctx, tasks.Task{
+			Name: "jwt_keys.refresh",
+			Run:  s.refresh,
+		})
+	}
+}
+
+// refresh reloads the ring, rotates the signing key when due and prunes
+// expired keys
+func (s *JWTKeyService) refresh(ctx context.Context) error {
+	if err := s.reload(ctx); err != nil {
+		return err
+	}
+
+	current := s.ring.Load().current
+	if s.rotationInterval > 0 && (current == nil || time.Since(current.meta.CreatedAt) >= s.rotationInterval) {
+		key, err := s.Rotate(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info("Rotated JWT signing key", "kid", key.ID)
+	}
+
+	removed, err := s.keyRepo.DeleteRetiredBefore(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		logger.Info("Removed expired JWT signing keys", "count", removed)
+		return s.reload(ctx)
+	}
+	return nil
+}
+
+// Rotate generates a new signing key, retiring the current one
+func (s *JWTKeyService) Rotate(ctx context.Context) (*models.JWTSigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	signer, err := generateJWTKey(s.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JWT signing key: %w", err)
+	}
+
+	key := &models.JWTSigningKey{
+		ID:        uuid.New().String(),
+		Algorithm: s.algorithm,
+		CreatedAt: time.Now().UTC(),
+		Current:   true,
+	}
+	key.PrivateKey, err = s.sealer.Seal(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), []byte(key.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal JWT signing key: %w", err)
+	}
+
+	if err := s.keyRepo.Rotate(ctx, key); err != nil {
+		return nil, err
+	}
+	if err := s.reloadLocked(ctx); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// List returns the keys of the ring, oldest first
+func (s *JWTKeyService) List() []*models.JWTSigningKey {
+	ring := s.ring.Load()
+	if ring == nil {
+		return nil
+	}
+	keys := make([]*models.JWTSigningKey, 0, len(ring.keys))
+	for _, key := range ring.keys {
+		keys = append(keys, key.meta)
+	}
+	return keys
+}
+
+// JWKS returns the public keys verifying tokens
+func (s *JWTKeyService) JWKS() models.JSONWebKeySet {
+	set := models.JSONWebKeySet{Keys: []models.JSONWebKey{}}
+	ring := s.ring.Load()
+	if ring == nil {
+		return set
+	}
+	for _, key := range ring.keys {
+		jwk := models.JSONWebKey{KeyID: key.meta.ID, Use: "sig", Algorithm: key.method.Alg()}
+		switch public := key.signer.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Modulus = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.Exponent = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// sharedSecretUntil returns when tokens signed with the shared secret stop
+// being accepted: one token lifetime after the oldest key of the ring was
+// generated, on switching to signing keys. Once that key is pruned, the
+// remaining ones are at least as old as its retention, so the time has passed.
+func (s *JWTKeyService) sharedSecretUntil() time.Time {
+	ring := s.ring.Load()
+	if ring == nil || len(ring.keys) == 0 {
+		return time.Time{}
+	}
+	return ring.keys[0].meta.CreatedAt.Add(s.retention)
+}
+
+// signingKey returns the key signing new tokens
+func (s *JWTKeyService) signingKey() (*jwtKey, error) {
+	ring := s.ring.Load()
+	if ring == nil || ring.current == nil {
+		return nil, fmt.Errorf("no JWT signing key")
+	}
+	return ring.current, nil
+}
+
+// verificationKey returns the key with the ID. An unknown ID reloads the
+// ring, at most every jwtKeyMissReload, as another instance may have just
+// rotated the key.
+func (s *JWTKeyService) verificationKey(ctx context.Context, kid string) (*jwtKey, error) {
+	if ring := s.ring.Load(); ring != nil {
+		if key, ok := ring.byID[kid]; ok {
+			return key, nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastMiss) >= jwtKeyMissReload {
+		s.lastMiss = time.Now()
+		if err := s.reloadLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if key, ok := s.ring.Load().byID[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// reload replaces the ring with the keys in the database
+func (s *JWTKeyService) reload(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloadLocked(ctx)
+}
+
+func (s *JWTKeyService) reloadLocked(ctx context.Context) error {
+	stored, err := s.keyRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	ring := &jwtKeyRing{byID: make(map[string]*jwtKey, len(stored))}
+	for _, meta := range stored {
+		key, err := s.openKey(meta)
+		if err != nil {
+			// One unreadable key should not stop the others from verifying
+			logger.Error("Failed to load JWT signing key", "kid", meta.ID, "error", err)
+			continue
+		}
+		ring.keys = append(ring.keys, key)
+		ring.byID[meta.ID] = key
+		if meta.RetiredAt == nil {
+			ring.current = key
+		}
+	}
+	if ring.current != nil {
+		ring.current.meta.Current = true
+	}
+
+	s.ring.Store(ring)
+	return nil
+}
+
+// openKey unseals and parses a stored key
+func (s *JWTKeyService) openKey(meta *models.JWTSigningKey) (*jwtKey, error) {
+	raw, err := s.sealer.Open(meta.PrivateKey, []byte(meta.ID))
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{meta: meta}
+	switch signer := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.signer = jwt.SigningMethodRS256, signer
+	case ed25519.PrivateKey:
+		key.method, key.signer = jwt.SigningMethodEdDSA, signer
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	return key, nil
+}
+
+// generateJWTKey generates a private key for the algorithm
+func generateJWTKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, jwtRSAKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		return key, nil
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+	}
+}
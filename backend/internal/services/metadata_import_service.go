@@ -0,0 +1,477 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// metadataImportBatchSize is the number of rows resolved and applied per transaction
+	metadataImportBatchSize = 500
+	// maxMetadataImportRows bounds the rows of a single import
+	maxMetadataImportRows = 50000
+	// maxImportFieldLength bounds the length of a folder or custom field value
+	maxImportFieldLength = 1000
+	// metadataImportInlineErrors is the number of row errors returned with the result
+	metadataImportInlineErrors = 100
+)
+
+// importFieldPattern is the form of a custom field column name
+var importFieldPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9 _.-]{0,63}$`)
+
+// ErrInvalidImportHeader is returned for CSVs naming no document column
+var ErrInvalidImportHeader = apperrors.Validation("VAL_028", "the CSV needs a document_id or filename column")
+
+// MetadataImportService applies document metadata from CSV files, for
+// migrating existing archives. Each row names a document by ID or filename
+// and sets its tags (labels), folder and custom fields.
+type MetadataImportService struct {
+	documentRepo *repository.DocumentRepository
+	transactor   *repository.Transactor
+	storage      *storage.Storage
+}
+
+// NewMetadataImportService creates a new metadata import service
+func NewMetadataImportService(
+	documentRepo *repository.DocumentRepository,
+	transactor *repository.Transactor,
+	storage *storage.Storage,
+) *MetadataImportService {
+	return &MetadataImportService{
+		documentRepo: documentRepo,
+		transactor:   transactor,
+		storage:      storage,
+	}
+}
+
+// importRow is a parsed CSV row. Empty cells leave the document unchanged.
+type importRow struct {
+	line       int
+	documentID *uuid.UUID
+	filename   string
+	tags       []string
+	hasTags    bool
+	folder     string
+	fields     map[string]string
+}
+
+// Import validates the rows of a metadata CSV and applies them in batches,
+// each in its own transaction. Invalid rows are skipped and reported; with
+// dryRun nothing is applied. The error report is kept for download until the
+// janitor removes it.
+func (s *MetadataImportService) Import(ctx context.Context, userID uuid.UUID, r io.Reader, mode models.MetadataImportMode, dryRun bool) (*models.MetadataImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, apperrors.Validation("VAL_028", "the CSV is empty")
+	}
+	if err != nil {
+		return nil, apperrors.Validation("VAL_028", fmt.Sprintf("invalid CSV: %v", err))
+	}
+	columns, err := parseImportHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.MetadataImportResult{
+		ID:     uuid.New(),
+		Mode:   mode,
+		DryRun: dryRun,
+		Errors: []*models.MetadataImportRowError{},
+	}
+	var rowErrors []*models.MetadataImportRowError
+	fail := func(row *importRow, line int, message string) {
+		rowError := &models.MetadataImportRowError{Row: line, Error: message}
+		if row != nil {
+			rowError.DocumentID = row.documentID
+			rowError.Filename = row.filename
+		}
+		rowErrors = append(rowErrors, rowError)
+	}
+
+	batch := make([]*importRow, 0, metadataImportBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.applyBatch(ctx, userID, batch, mode, dryRun, result, fail)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			result.Rows++
+			fail(nil, parseErr.StartLine, parseErr.Err.Error())
+			continue
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		result.Rows++
+		if result.Rows > maxMetadataImportRows {
+			return nil, apperrors.Validation("VAL_028", fmt.Sprintf("a metadata import may have at most %d rows", maxMetadataImportRows))
+		}
+
+		row, err := parseImportRow(columns, record, line)
+		if err != nil {
+			fail(row, line, err.Error())
+			continue
+		}
+
+		batch = append(batch, row)
+		if len(batch) == metadataImportBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	result.Failed = len(rowErrors)
+	if len(rowErrors) > 0 {
+		slices.SortStableFunc(rowErrors, func(a, b *models.MetadataImportRowError) int { return a.Row - b.Row })
+		result.Errors = rowErrors[:min(len(rowErrors), metadataImportInlineErrors)]
+		if err := s.writeErrorReport(userID, result.ID, rowErrors); err != nil {
+			return nil, err
+		}
+		reportURL := "/api/v1/documents/metadata-import/" + result.ID.String() + "/errors"
+		result.ErrorReportURL = &reportURL
+	}
+
+	logger.Info("Document metadata imported",
+		"user_id", userID, "import_id", result.ID, "rows", result.Rows,
+		"updated", result.Updated, "failed", result.Failed, "dry_run", dryRun)
+
+	return result, nil
+}
+
+// OpenErrorReport opens the error report of one of the user's imports
+func (s *MetadataImportService) OpenErrorReport(userID, importID uuid.UUID) (io.ReadCloser, error) {
+	reportPath := s.storage.GetFilePath(errorReportPath(userID, importID))
+	if !s.storage.FileExists(reportPath) {
+		return nil, apperrors.NotFound("RES_007", "error report not found")
+	}
+	return s.storage.Open(reportPath)
+}
+
+// applyBatch resolves the documents a batch of rows names and applies the
+// rows that are valid in a single transaction
+func (s *MetadataImportService) applyBatch(
+	ctx context.Context,
+	userID uuid.UUID,
+	rows []*importRow,
+	mode models.MetadataImportMode,
+	dryRun bool,
+	result *models.MetadataImportResult,
+	fail func(row *importRow, line int, message string),
+) error {
+	var ids []uuid.UUID
+	var filenames []string
+	for _, row := range rows {
+		if row.documentID != nil {
+			ids = append(ids, *row.documentID)
+		} else {
+			filenames = append(filenames, row.filename, storage.NormalizeFilename(row.filename))
+		}
+	}
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		documents, err := s.documentRepo.ListForImport(ctx, userID, ids, filenames)
+		if err != nil {
+			return err
+		}
+
+		byID := map[uuid.UUID]*models.Document{}
+		byName := map[string][]*models.Document{}
+		for i := range documents {
+			doc := &documents[i]
+			byID[doc.ID] = doc
+			byName[doc.OriginalFilename] = append(byName[doc.OriginalFilename], doc)
+			if source, ok := doc.Metadata[models.DocumentMetaSourceFilename].(string); ok && source != doc.OriginalFilename {
+				byName[source] = append(byName[source], doc)
+			}
+			for _, alias := range doc.Aliases() {
+				byName[alias] = append(byName[alias], doc)
+			}
+		}
+
+		// Rows are applied in order, so a later row naming the same document
+		// sees the changes of earlier ones
+		changed := map[uuid.UUID]*models.Document{}
+		for _, row := range rows {
+			doc, message := resolveImportRow(row, byID, byName)
+			if doc == nil {
+				fail(row, row.line, message)
+				continue
+			}
+
+			labels, metadata, err := applyImportRow(ctx, doc, row, mode)
+			if err != nil {
+				fail(row, row.line, err.Error())
+				continue
+			}
+			if slices.Equal(labels, doc.Labels) && reflect.DeepEqual(metadata, doc.Metadata) {
+				result.Unchanged++
+				continue
+			}
+
+			doc.Labels = labels
+			doc.Metadata = metadata
+			changed[doc.ID] = doc
+			result.Updated++
+		}
+
+		if dryRun {
+			return nil
+		}
+		for _, doc := range changed {
+			if err := s.documentRepo.UpdateMetadata(ctx, doc.ID, doc.Labels, doc.Metadata); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeErrorReport stores the failed rows of an import as CSV
+func (s *MetadataImportService) writeErrorReport(userID, importID uuid.UUID, rowErrors []*models.MetadataImportRowError) error {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write([]string{"row", models.ImportColumnDocumentID, models.ImportColumnFilename, "error"})
+	for _, rowError := range rowErrors {
+		documentID := ""
+		if rowError.DocumentID != nil {
+			documentID = rowError.DocumentID.String()
+		}
+		_ = writer.Write([]string{fmt.Sprint(rowError.Row), documentID, rowError.Filename, rowError.Error})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write error report: %w", err)
+	}
+
+	_, err := s.storage.WriteEncryptedFile(errorReportPath(userID, importID), &buf)
+	return err
+}
+
+// errorReportPath returns the path of an import's error report, relative to
+// the storage root
+func errorReportPath(userID, importID uuid.UUID) string {
+	return path.Join(storage.ImportsDir, "metadata", userID.String(), importID.String()+".csv")
+}
+
+// importColumns maps the columns of a metadata CSV to their index
+type importColumns struct {
+	documentID int
+	filename   int
+	tags       int
+	folder     int
+	fields     map[string]int
+}
+
+// parseImportHeader checks the header of a metadata CSV. It must name the
+// document_id or filename column; tags and folder are optional and any other
+// column is a custom field.
+func parseImportHeader(header []string) (*importColumns, error) {
+	columns := &importColumns{documentID: -1, filename: -1, tags: -1, folder: -1, fields: map[string]int{}}
+	seen := map[string]bool{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		key := strings.ToLower(name)
+		if seen[key] {
+			return nil, apperrors.Validation("VAL_028", fmt.Sprintf("column %q appears twice", name))
+		}
+		seen[key] = true
+
+		switch key {
+		case models.ImportColumnDocumentID:
+			columns.documentID = i
+		case models.ImportColumnFilename:
+			columns.filename = i
+		case models.ImportColumnTags, "labels":
+			columns.tags = i
+		case models.ImportColumnFolder:
+			columns.folder = i
+		default:
+			if !importFieldPattern.MatchString(name) {
+				return nil, apperrors.Validation("VAL_028",
+					fmt.Sprintf("invalid column %q: custom fields use up to 64 letters, digits, spaces, '.', '_' or '-'", name))
+			}
+			columns.fields[name] = i
+		}
+	}
+
+	if columns.documentID < 0 && columns.filename < 0 {
+		return nil, ErrInvalidImportHeader
+	}
+	return columns, nil
+}
+
+// parseImportRow validates a CSV record. The returned row identifies the
+// document even when the rest of the record is invalid.
+func parseImportRow(columns *importColumns, record []string, line int) (*importRow, error) {
+	cell := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := &importRow{line: line, filename: cell(columns.filename), fields: map[string]string{}}
+	if raw := cell(columns.documentID); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return row, fmt.Errorf("invalid document ID %q", raw)
+		}
+		row.documentID = &id
+	}
+	if row.documentID == nil && row.filename == "" {
+		return row, errors.New("the row names no document: set document_id or filename")
+	}
+
+	if raw := cell(columns.tags); raw != "" {
+		tags, err := models.NormalizeLabels(strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ';' }))
+		if err != nil {
+			return row, err
+		}
+		row.tags = tags
+		row.hasTags = true
+	}
+
+	row.folder = strings.Trim(cell(columns.folder), "/")
+	if utf8.RuneCountInString(row.folder) > maxImportFieldLength {
+		return row, fmt.Errorf("folder is longer than %d characters", maxImportFieldLength)
+	}
+
+	for name, i := range columns.fields {
+		value := cell(i)
+		if value == "" {
+			continue
+		}
+		if utf8.RuneCountInString(value) > maxImportFieldLength {
+			return row, fmt.Errorf("field %q is longer than %d characters", name, maxImportFieldLength)
+		}
+		row.fields[name] = value
+	}
+
+	return row, nil
+}
+
+// resolveImportRow finds the document a row names, or explains why there is
+// none
+func resolveImportRow(row *importRow, byID map[uuid.UUID]*models.Document, byName map[string][]*models.Document) (*models.Document, string) {
+	if row.documentID != nil {
+		if doc, ok := byID[*row.documentID]; ok {
+			return doc, ""
+		}
+		return nil, "document not found"
+	}
+
+	matches := byName[row.filename]
+	if normalized := storage.NormalizeFilename(row.filename); normalized != row.filename {
+		for _, doc := range byName[normalized] {
+			if !slices.Contains(matches, doc) {
+				matches = append(matches, doc)
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, "no document has this filename"
+	case 1:
+		return matches[0], ""
+	default:
+		return nil, fmt.Sprintf("%d documents have this filename; use document_id", len(matches))
+	}
+}
+
+// applyImportRow returns a document's labels and metadata with a row applied.
+// In replace mode the row's tags and custom fields replace the document's
+// instead of being added to them. API keys restricted to labels may only set their own labels.
+func applyImportRow(ctx context.Context, doc *models.Document, row *importRow, mode models.MetadataImportMode) ([]string, map[string]any, error) {
+	labels := doc.Labels
+	if row.hasTags {
+		if scope := repository.LabelScope(ctx); scope != nil {
+			for _, tag := range row.tags {
+				if !slices.Contains(scope, tag) {
+					return nil, nil, fmt.Errorf("API key may not use label %s", tag)
+				}
+			}
+		}
+
+		var err error
+		if mode == models.MetadataImportReplace {
+			labels = row.tags
+		} else {
+			labels, err = models.NormalizeLabels(append(slices.Clone(doc.Labels), row.tags...))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	metadata := maps.Clone(doc.Metadata)
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	if row.folder != "" {
+		metadata[models.DocumentMetaFolder] = row.folder
+	}
+	if len(row.fields) > 0 {
+		fields := map[string]any{}
+		if existing, ok := metadata[models.DocumentMetaFields].(map[string]any); ok && mode == models.MetadataImportMerge {
+			fields = maps.Clone(existing)
+		}
+		for name, value := range row.fields {
+			fields[name] = value
+		}
+		metadata[models.DocumentMetaFields] = fields
+	}
+
+	return labels, metadata, nil
+}
+
+// isBlankRecord reports whether every cell of a record is empty
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,473 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// notificationBatchSize is the number of channels claimed per delivery
+	// cycle and the number of jobs notified in each channel per cycle
+	notificationBatchSize = 20
+	// maxNotificationFailures is the number of failed deliveries in a row
+	// after which a channel is disabled
+	maxNotificationFailures = 50
+	// notificationSettleDelay is how long after a job finished it is
+	// notified, so jobs whose status was committed late are not passed over
+	notificationSettleDelay = 30 * time.Second
+)
+
+// NotificationService posts a message to users' Slack and Microsoft Teams
+// channels when their jobs complete or fail, and notices of other events such
+// as upcoming retention deletions
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	transactor       *repository.Transactor
+	httpClient       *http.Client
+	documentURL      string
+	interval         time.Duration
+}
+
+// NewNotificationService creates a new notification service. Messages link to
+// documentURL followed by the document ID. Unless allowPrivate is set,
+// webhooks cannot target loopback, private or link-local addresses.
+func NewNotificationService(
+	notificationRepo *repository.NotificationRepository,
+	transactor *repository.Transactor,
+	documentURL string,
+	interval, timeout time.Duration,
+	allowPrivate bool,
+) *NotificationService {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		transactor:       transactor,
+		httpClient:       newWebhookClient(timeout, allowPrivate),
+		documentURL:      documentURL,
+		interval:         interval,
+	}
+}
+
+// Create adds a channel notified of the user's jobs that finish from now on,
+// by default of both completed and failed ones. A channel created with an API
+// key restricted to labels keeps that restriction.
+func (s *NotificationService) Create(ctx context.Context, userID uuid.UUID, req models.NotificationChannelRequest) (*models.NotificationChannel, error) {
+	events := req.Events
+	if len(events) == 0 {
+		events = []string{models.NotificationEventJobCompleted, models.NotificationEventJobFailed, models.NotificationEventRetentionWarning}
+	}
+
+	channel := &models.NotificationChannel{
+		UserID:     userID,
+		Kind:       req.Kind,
+		Name:       req.Name,
+		WebhookURL: req.WebhookURL,
+		Events:     events,
+		Labels:     repository.LabelScope(ctx),
+		Enabled:    true,
+		CursorAt:   time.Now().UTC(),
+		CursorID:   uuid.Nil,
+	}
+	if err := s.notificationRepo.Create(ctx, channel); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Notification channel created", "channel_id", channel.ID, "user_id", userID, "kind", channel.Kind)
+	return channel, nil
+}
+
+// List returns the user's channels, newest first
+func (s *NotificationService) List(ctx context.Context, userID uuid.UUID) ([]*models.NotificationChannel, error) {
+	return s.notificationRepo.ListByUser(ctx, userID)
+}
+
+// Update changes one of the user's channels. A channel that is enabled again,
+// or given a new webhook, starts over with jobs that finish from now on.
+func (s *NotificationService) Update(ctx context.Context, id, userID uuid.UUID, req models.NotificationChannelUpdateRequest) (*models.NotificationChannel, error) {
+	channel, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	restart := false
+	if req.Name != nil {
+		channel.Name = *req.Name
+	}
+	if req.WebhookURL != nil && *req.WebhookURL != channel.WebhookURL {
+		channel.WebhookURL = *req.WebhookURL
+		restart = true
+	}
+	if len(req.Events) > 0 {
+		channel.Events = req.Events
+	}
+	if req.Enabled != nil {
+		restart = restart || (*req.Enabled && !channel.Enabled)
+		channel.Enabled = *req.Enabled
+	}
+
+	if restart {
+		now := time.Now().UTC()
+		channel.CursorAt, channel.CursorID = now, uuid.Nil
+		channel.Failures = 0
+		channel.LastError = nil
+		channel.NextAttemptAt = now
+	}
+
+	if err := s.notificationRepo.Update(ctx, channel); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Notification channel updated", "channel_id", id, "user_id", userID, "enabled", channel.Enabled)
+	return channel, nil
+}
+
+// Delete removes one of the user's channels
+func (s *NotificationService) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.getOwned(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.notificationRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	logger.Info("Notification channel deleted", "channel_id", id, "user_id", userID)
+	return nil
+}
+
+// Test posts a sample message to one of the user's channels, so they can
+// check the webhook before a job finishes
+func (s *NotificationService) Test(ctx context.Context, id, userID uuid.UUID) error {
+	channel, err := s.getOwned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	confidence := 0.97
+	sample := &models.JobNotification{
+		JobID:       uuid.Nil,
+		DocumentID:  uuid.Nil,
+		Filename:    "sample.pdf",
+		Status:      models.JobStatusCompleted,
+		Confidence:  &confidence,
+		CompletedAt: time.Now().UTC(),
+	}
+	if _, err := s.post(ctx, channel, sample); err != nil {
+		return apperrors.Validation("NOTIF_002", "webhook rejected the test message: "+err.Error())
+	}
+
+	return nil
+}
+
+// Start notifies channels of finished jobs on every interval until the
+// context is cancelled
+func (s *NotificationService) Start(ctx context.Context) {
+	logger.Info("Job notifications started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Job notifications stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{Name: "notifications.deliver", Run: s.DeliverPending})
+		}
+	}
+}
+
+// DeliverPending posts the jobs each due channel has not been notified of
+// yet, in order. A channel stops at its first failed delivery and is retried
+// with exponential backoff; one whose webhook was removed, or failing too
+// often in a row, is disabled. Channels are claimed with row locks so several
+// API replicas can deliver concurrently.
+func (s *NotificationService) DeliverPending(ctx context.Context) error {
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		channels, err := s.notificationRepo.ClaimDue(ctx, notificationBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, channel := range channels {
+			if err := s.deliverChannel(ctx, channel); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// deliverChannel posts a batch of finished jobs to a channel and saves how far
+// it got. Only database errors are returned.
+func (s *NotificationService) deliverChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	now := time.Now().UTC()
+	scoped := repository.WithLabelScope(ctx, channel.Labels)
+	jobs, err := s.notificationRepo.ListFinishedJobs(scoped, channel.UserID, notificationStatuses(channel.Events),
+		channel.CursorAt, channel.CursorID, now.Add(-notificationSettleDelay), notificationBatchSize)
+	if err != nil {
+		return err
+	}
+
+	channel.NextAttemptAt = now.Add(s.interval)
+	for _, job := range jobs {
+		gone, err := s.post(ctx, channel, job)
+		if err != nil {
+			channel.Failures++
+			message := err.Error()
+			channel.LastError = &message
+			logger.Warn("Failed to post job notification", "channel_id", channel.ID, "job_id", job.JobID, "failures", channel.Failures, "error", err)
+
+			if gone || channel.Failures >= maxNotificationFailures {
+				logger.Warn("Notification channel disabled", "channel_id", channel.ID, "user_id", channel.UserID, "failures", channel.Failures)
+				channel.Enabled = false
+				break
+			}
+
+			backoff := time.Duration(1<<min(channel.Failures, 12)) * time.Second
+			if backoff > maxHookBackoff {
+				backoff = maxHookBackoff
+			}
+			channel.NextAttemptAt = now.Add(backoff)
+			break
+		}
+
+		channel.CursorAt, channel.CursorID = job.CompletedAt, job.JobID
+		channel.Failures = 0
+		channel.LastError = nil
+		channel.LastDeliveredAt = &now
+	}
+
+	return s.notificationRepo.UpdateDelivery(ctx, channel)
+}
+
+// Notify posts a notice of an event to the user's enabled channels notified
+// of it, once, without retrying failed posts. Channels restricted to labels
+// are left out, as the notice is about all of the user's data. It reports
+// whether any channel got the notice.
+func (s *NotificationService) Notify(ctx context.Context, userID uuid.UUID, event string, notice models.Notice) (bool, error) {
+	channels, err := s.notificationRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	notified := false
+	for _, channel := range channels {
+		if !channel.Enabled || len(channel.Labels) > 0 || !slices.Contains(channel.Events, event) {
+			continue
+		}
+
+		var payload any
+		if channel.Kind == models.NotificationKindTeams {
+			payload = teamsNotice(notice)
+		} else {
+			payload = slackNotice(notice)
+		}
+		if _, err := s.send(ctx, channel, payload); err != nil {
+			logger.Warn("Failed to post notice", "channel_id", channel.ID, "event", event, "error", err)
+			continue
+		}
+		notified = true
+	}
+
+	return notified, nil
+}
+
+// post sends a job's message to a channel's webhook. It reports whether the
+// webhook no longer exists, as Slack and Teams answer once it was removed or
+// its channel archived; any other non-2xx response is a failure too.
+func (s *NotificationService) post(ctx context.Context, channel *models.NotificationChannel, job *models.JobNotification) (bool, error) {
+	var payload any
+	if channel.Kind == models.NotificationKindTeams {
+		payload = s.teamsMessage(job)
+	} else {
+		payload = s.slackMessage(job)
+	}
+	return s.send(ctx, channel, payload)
+}
+
+// send posts a message to a channel's webhook, reporting like post
+func (s *NotificationService) send(ctx context.Context, channel *models.NotificationChannel, payload any) (bool, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", channel.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send message: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		gone := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone
+		return gone, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// slackMessage formats a job as a Slack mrkdwn message
+func (s *NotificationService) slackMessage(job *models.JobNotification) map[string]string {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*<%s|%s>* %s", s.documentURL+job.DocumentID.String(), escape.Replace(job.Filename), notificationOutcome(job))
+	for _, fact := range notificationFacts(job) {
+		fmt.Fprintf(&b, "\n• %s: %s", fact[0], escape.Replace(fact[1]))
+	}
+	return map[string]string{"text": b.String()}
+}
+
+// teamsMessage formats a job as a Microsoft Teams message holding an
+// Adaptive Card
+func (s *NotificationService) teamsMessage(job *models.JobNotification) map[string]any {
+	facts := []map[string]string{}
+	for _, fact := range notificationFacts(job) {
+		facts = append(facts, map[string]string{"title": fact[0], "value": fact[1]})
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]any{
+			{"type": "TextBlock", "text": job.Filename + " " + notificationOutcome(job), "weight": "Bolder", "wrap": true},
+			{"type": "FactSet", "facts": facts},
+		},
+		"actions": []map[string]any{
+			{"type": "Action.OpenUrl", "title": "View result", "url": s.documentURL + job.DocumentID.String()},
+		},
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
+
+// slackNotice formats a notice as a Slack mrkdwn message
+func slackNotice(notice models.Notice) map[string]string {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+	var b strings.Builder
+	if notice.URL != "" {
+		fmt.Fprintf(&b, "*<%s|%s>*", notice.URL, escape.Replace(notice.Title))
+	} else {
+		fmt.Fprintf(&b, "*%s*", escape.Replace(notice.Title))
+	}
+	for _, fact := range notice.Facts {
+		fmt.Fprintf(&b, "\n• %s: %s", fact[0], escape.Replace(fact[1]))
+	}
+	return map[string]string{"text": b.String()}
+}
+
+// teamsNotice formats a notice as a Microsoft Teams message holding an
+// Adaptive Card
+func teamsNotice(notice models.Notice) map[string]any {
+	facts := []map[string]string{}
+	for _, fact := range notice.Facts {
+		facts = append(facts, map[string]string{"title": fact[0], "value": fact[1]})
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]any{
+			{"type": "TextBlock", "text": notice.Title, "weight": "Bolder", "wrap": true},
+			{"type": "FactSet", "facts": facts},
+		},
+	}
+	if notice.URL != "" {
+		card["actions"] = []map[string]any{
+			{"type": "Action.OpenUrl", "title": "Open", "url": notice.URL},
+		}
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
+
+// notificationOutcome describes how a job finished
+func notificationOutcome(job *models.JobNotification) string {
+	if job.Status == models.JobStatusFailed {
+		return "failed"
+	}
+	return "was processed"
+}
+
+// notificationFacts lists the status, confidence and error of a job as
+// title, value pairs
+func notificationFacts(job *models.JobNotification) [][2]string {
+	facts := [][2]string{{"Status", string(job.Status)}}
+	if job.Confidence != nil {
+		facts = append(facts, [2]string{"Confidence", fmt.Sprintf("%.1f%%", *job.Confidence*100)})
+	}
+	if job.ErrorMessage != nil && *job.ErrorMessage != "" {
+		facts = append(facts, [2]string{"Error", *job.ErrorMessage})
+	}
+	return facts
+}
+
+// notificationStatuses maps a channel's events to the job statuses they are
+// fired for
+func notificationStatuses(events []string) []models.JobStatus {
+	statuses := []models.JobStatus{}
+	for _, event := range events {
+		switch event {
+		case models.NotificationEventJobCompleted:
+			statuses = append(statuses, models.JobStatusCompleted)
+		case models.NotificationEventJobFailed:
+			statuses = append(statuses, models.JobStatusFailed)
+		}
+	}
+	return statuses
+}
+
+// getOwned retrieves a channel, reporting it missing unless the user owns it
+func (s *NotificationService) getOwned(ctx context.Context, id, userID uuid.UUID) (*models.NotificationChannel, error) {
+	channel, err := s.notificationRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if channel.UserID != userID {
+		return nil, repository.ErrNotificationChannelNotFound
+	}
+	return channel, nil
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// PolicyViolationError is returned when a job breaks the organization's OCR policy
+type PolicyViolationError struct {
+	Field   string
+	Message string
+}
+
+func (e *PolicyViolationError) Error() string {
+	return e.Message
+}
+
+// OCRPolicyService manages the OCR defaults and limits set for members. Each
+// organization's owners set its policy; admins set the instance-wide policy
+// users outside any organization follow, which organizations that never set
+// their own also start from. The policy is read on every submission, so
+// changes apply immediately.
+type OCRPolicyService struct {
+	settingsRepo *repository.InstanceSettingsRepository
+	orgRepo      *repository.OrganizationRepository
+}
+
+// NewOCRPolicyService creates a new OCR policy service
+func NewOCRPolicyService(settingsRepo *repository.InstanceSettingsRepository, orgRepo *repository.OrganizationRepository) *OCRPolicyService {
+	return &OCRPolicyService{settingsRepo: settingsRepo, orgRepo: orgRepo}
+}
+
+// Get returns the instance-wide policy, or the default policy when none was saved
+func (s *OCRPolicyService) Get(ctx context.Context) (*models.OCRPolicy, error) {
+	value, ok, err := s.settingsRepo.Get(ctx, models.SettingOCRPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := models.DefaultOCRPolicy()
+	if !ok {
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		return nil, fmt.Errorf("failed to decode OCR policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// Update applies the fields present in the request to the instance-wide policy
+// and returns the new policy
+func (s *OCRPolicyService) Update(ctx context.Context, req models.UpdateOCRPolicyRequest, adminID uuid.UUID) (*models.OCRPolicy, error) {
+	policy, err := s.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyPolicyUpdate(policy, req, adminID); err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OCR policy: %w", err)
+	}
+	if err := s.settingsRepo.Set(ctx, models.SettingOCRPolicy, string(value)); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// GetForOrg returns the policy of an organization the user is a member of
+func (s *OCRPolicyService) GetForOrg(ctx context.Context, orgID, userID uuid.UUID) (*models.OCRPolicy, error) {
+	if _, err := s.orgRepo.GetForMember(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.orgPolicy(ctx, orgID)
+}
+
+// UpdateForOrg applies the fields present in the request to the policy of an
+// organization the user owns and returns the new policy. Approvers must be
+// members of the organization.
+func (s *OCRPolicyService) UpdateForOrg(ctx context.Context, orgID, userID uuid.UUID, req models.UpdateOCRPolicyRequest) (*models.OCRPolicy, error) {
+	org, err := s.orgRepo.GetForMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if org.Role != models.OrgRoleOwner {
+		return nil, ErrOrgRoleForbidden
+	}
+
+	policy, err := s.orgPolicy(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyPolicyUpdate(policy, req, userID); err != nil {
+		return nil, err
+	}
+	for _, approverID := range policy.Approvers {
+		_, err := s.orgRepo.GetMemberRole(ctx, orgID, approverID)
+		if errors.Is(err, repository.ErrOrgMemberNotFound) {
+			return nil, &PolicyViolationError{
+				Field:   "approvers",
+				Message: fmt.Sprintf("approver %s is not a member of the organization", approverID),
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OCR policy: %w", err)
+	}
+	if err := s.orgRepo.SetOCRPolicy(ctx, orgID, value); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// GetForUser returns the policy the user's jobs follow unless they are on a
+// document shared with another organization
+func (s *OCRPolicyService) GetForUser(ctx context.Context, userID uuid.UUID) (*models.OCRPolicy, error) {
+	orgID, err := s.orgRepo.GetFirstOrgID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if orgID == nil {
+		return s.Get(ctx)
+	}
+
+	return s.orgPolicy(ctx, *orgID)
+}
+
+// SharingOrgIDs returns the organizations of the user whose policy shares
+// duplicate uploads between their members
+func (s *OCRPolicyService) SharingOrgIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	orgs, err := s.orgRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var orgIDs []uuid.UUID
+	for _, org := range orgs {
+		policy, err := s.orgPolicy(ctx, org.ID)
+		if err != nil {
+			return nil, err
+		}
+		if policy.ShareDuplicateUploads {
+			orgIDs = append(orgIDs, org.ID)
+		}
+	}
+
+	return orgIDs, nil
+}
+
+// IsApprover reports whether the user may decide on jobs held by the policy of
+// the organization, or by the instance-wide policy when orgID is nil. An
+// organization's approvers must be members of it; when it names none, its
+// owners decide.
+func (s *OCRPolicyService) IsApprover(ctx context.Context, orgID *uuid.UUID, user *models.User) (bool, error) {
+	if orgID == nil {
+		policy, err := s.Get(ctx)
+		if err != nil {
+			return false, err
+		}
+		return policy.IsApprover(user), nil
+	}
+
+	role, err := s.orgRepo.GetMemberRole(ctx, *orgID, user.ID)
+	if errors.Is(err, repository.ErrOrgMemberNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	policy, err := s.orgPolicy(ctx, *orgID)
+	if err != nil {
+		return false, err
+	}
+	if len(policy.Approvers) == 0 {
+		return role == models.OrgRoleOwner, nil
+	}
+	return slices.Contains(policy.Approvers, user.ID), nil
+}
+
+// orgPolicy returns the policy of an organization, starting from the
+// instance-wide policy when the organization never saved one
+func (s *OCRPolicyService) orgPolicy(ctx context.Context, orgID uuid.UUID) (*models.OCRPolicy, error) {
+	value, ok, err := s.orgRepo.GetOCRPolicy(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy *models.OCRPolicy
+	if ok {
+		policy = models.DefaultOCRPolicy()
+		if err := json.Unmarshal(value, policy); err != nil {
+			return nil, fmt.Errorf("failed to decode OCR policy: %w", err)
+		}
+	} else {
+		if policy, err = s.Get(ctx); err != nil {
+			return nil, err
+		}
+		// The instance's approvers are not the organization's
+		policy.Approvers = []uuid.UUID{}
+		policy.UpdatedAt, policy.UpdatedBy = nil, nil
+	}
+
+	policy.OrgID = &orgID
+	return policy, nil
+}
+
+// forDocument returns the policy a job on the document follows: that of the
+// organization the document is shared with, otherwise the owner's
+func (s *OCRPolicyService) forDocument(ctx context.Context, document *models.Document) (*models.OCRPolicy, error) {
+	if document.OrgID != nil {
+		return s.orgPolicy(ctx, *document.OrgID)
+	}
+	return s.GetForUser(ctx, document.UserID)
+}
+
+// applyPolicyUpdate applies the fields present in the request to the policy
+func applyPolicyUpdate(policy *models.OCRPolicy, req models.UpdateOCRPolicyRequest, userID uuid.UUID) error {
+	if req.DefaultOCRMode != nil {
+		policy.DefaultOCRMode = *req.DefaultOCRMode
+	}
+	if req.DefaultResolution != nil {
+		policy.DefaultResolution = *req.DefaultResolution
+	}
+	if req.AllowedResolutions != nil {
+		policy.AllowedResolutions = *req.AllowedResolutions
+	}
+	if req.MaxPagesPerJob != nil {
+		policy.MaxPagesPerJob = *req.MaxPagesPerJob
+	}
+	if req.ShareDuplicateUploads != nil {
+		policy.ShareDuplicateUploads = *req.ShareDuplicateUploads
+	}
+	if req.ApprovalPageThreshold != nil {
+		policy.ApprovalPageThreshold = *req.ApprovalPageThreshold
+	}
+	if req.ApprovalResolutions != nil {
+		policy.ApprovalResolutions = *req.ApprovalResolutions
+	}
+	if req.Approvers != nil {
+		policy.Approvers = *req.Approvers
+	}
+
+	// A default members could not use would make every job without an explicit
+	// resolution fail
+	if !policy.AllowsResolution(policy.DefaultResolution) {
+		return &PolicyViolationError{
+			Field:   "default_resolution",
+			Message: fmt.Sprintf("default resolution %q must be one of the allowed resolutions", policy.DefaultResolution),
+		}
+	}
+
+	now := time.Now().UTC()
+	policy.UpdatedAt = &now
+	policy.UpdatedBy = &userID
+	return nil
+}
+
+// Apply fills in the policy defaults for a submission and checks it against the
+// policy limits, returning a PolicyViolationError when the job is not allowed
+func (s *OCRPolicyService) Apply(ctx context.Context, req *models.JobSubmissionRequest, document *models.Document) error {
+	policy, err := s.forDocument(ctx, document)
+	if err != nil {
+		return err
+	}
+
+	if req.OCRMode == "" {
+		req.OCRMode = policy.DefaultOCRMode
+	}
+	if req.ResolutionMode == "" {
+		req.ResolutionMode = policy.DefaultResolution
+	}
+
+	if !policy.AllowsResolution(req.ResolutionMode) {
+		allowed := make([]string, len(policy.AllowedResolutions))
+		for i, mode := range policy.AllowedResolutions {
+			allowed[i] = string(mode)
+		}
+		return &PolicyViolationError{
+			Field: "resolution_mode",
+			Message: fmt.Sprintf("resolution %q is not allowed by your organization's OCR policy (allowed: %s)",
+				req.ResolutionMode, strings.Join(allowed, ", ")),
+		}
+	}
+
+	// Page reprocessing jobs only cover a single page
+	_, singlePage := req.Metadata[models.JobMetaReprocessPage]
+	if policy.MaxPagesPerJob > 0 && !singlePage && document.NumPages > policy.MaxPagesPerJob {
+		return &PolicyViolationError{
+			Field: "document_id",
+			Message: fmt.Sprintf("document has %d pages, more than the %d pages per job your organization's OCR policy allows",
+				document.NumPages, policy.MaxPagesPerJob),
+		}
+	}
+
+	return nil
+}
+
+// ApprovalReason returns why a submission must wait for an approver before it
+// is queued, or an empty string when the policy lets it run right away, with
+// the organization whose policy holds it (nil for the instance-wide policy)
+func (s *OCRPolicyService) ApprovalReason(ctx context.Context, req models.JobSubmissionRequest, document *models.Document) (string, *uuid.UUID, error) {
+	policy, err := s.forDocument(ctx, document)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return policy.ApprovalReason(submissionPages(req, document), req.ResolutionMode), policy.OrgID, nil
+}
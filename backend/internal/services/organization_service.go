@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// orgInvitationTTL is how long an invitation to an organization can be accepted
+const orgInvitationTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrOrgRoleForbidden is returned when a member's role does not allow an action
+	ErrOrgRoleForbidden = apperrors.Forbidden("ORG_004", "your organization role does not allow this")
+	// ErrLastOrgOwner is returned when a change would leave an organization without an owner
+	ErrLastOrgOwner = apperrors.Conflict("ORG_005", "an organization must keep at least one owner")
+	// ErrAlreadyOrgMember is returned when inviting or adding an existing member
+	ErrAlreadyOrgMember = apperrors.Conflict("ORG_006", "user is already a member of the organization")
+	// ErrOrgInvitationInactive is returned when answering an invitation that
+	// expired or was already answered
+	ErrOrgInvitationInactive = apperrors.Conflict("ORG_007", "invitation has expired or was already answered")
+	// ErrOrgInvitationPending is returned when inviting an email that already
+	// has a pending invitation
+	ErrOrgInvitationPending = apperrors.Conflict("ORG_008", "an invitation to this email is already pending")
+)
+
+// OrganizationService lets teams share scanned documents and their results.
+// Owners invite users by email and manage the members; owners and members
+// share their documents with the organization, and every member, viewers
+// included, can read the shared documents, their jobs and latest results.
+type OrganizationService struct {
+	orgRepo      *repository.OrganizationRepository
+	documentRepo *repository.DocumentRepository
+	jobRepo      *repository.JobRepository
+	resultRepo   *repository.ResultRepository
+	userRepo     *repository.UserRepository
+	transactor   *repository.Transactor
+	eventService *EventService
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(
+	orgRepo *repository.OrganizationRepository,
+	documentRepo *repository.DocumentRepository,
+	jobRepo *repository.JobRepository,
+	resultRepo *repository.ResultRepository,
+	userRepo *repository.UserRepository,
+	transactor *repository.Transactor,
+	eventService *EventService,
+) *OrganizationService {
+	return &OrganizationService{
+		orgRepo:      orgRepo,
+		documentRepo: documentRepo,
+		jobRepo:      jobRepo,
+		resultRepo:   resultRepo,
+		userRepo:     userRepo,
+		transactor:   transactor,
+		eventService: eventService,
+	}
+}
+
+// Create creates an organization owned by the user
+func (s *OrganizationService) Create(ctx context.Context, userID uuid.UUID, req models.OrgCreateRequest) (*models.Organization, error) {
+	org := &models.Organization{
+		Name:        strings.TrimSpace(req.Name),
+		CreatedBy:   &userID,
+		Role:        models.OrgRoleOwner,
+		MemberCount: 1,
+	}
+
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.orgRepo.Create(ctx, org); err != nil {
+			return err
+		}
+		return s.orgRepo.AddMember(ctx, org.ID, userID, models.OrgRoleOwner)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Organization created", "organization_id", org.ID, "user_id", userID)
+	return org, nil
+}
+
+// List returns the organizations the user is a member of
+func (s *OrganizationService) List(ctx context.Context, userID uuid.UUID) ([]*models.Organization, error) {
+	return s.orgRepo.ListByUser(ctx, userID)
+}
+
+// Get returns one of the user's organizations with its members
+func (s *OrganizationService) Get(ctx context.Context, id, userID uuid.UUID) (*models.OrganizationDetail, error) {
+	org, err := s.orgRepo.GetForMember(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.orgRepo.ListMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OrganizationDetail{Organization: *org, Members: members}, nil
+}
+
+// Delete deletes an organization the user owns. Its documents stay with the
+// members who shared them.
+func (s *OrganizationService) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.requireRole(ctx, id, userID, models.OrgRoleOwner); err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	logger.Info("Organization deleted", "organization_id", id, "user_id", userID)
+	return nil
+}
+
+// Invite invites the user with an email to join an organization the inviter
+// owns. The invitee is notified with an organization.invitation_created event
+// if they already have an account; otherwise they see the invitation once they
+// register with that email.
+func (s *OrganizationService) Invite(ctx context.Context, id, userID uuid.UUID, req models.OrgInviteRequest) (*models.OrgInvitation, error) {
+	org, err := s.requireRole(ctx, id, userID, models.OrgRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	member, err := s.orgRepo.IsMemberByEmail(ctx, id, email)
+	if err != nil {
+		return nil, err
+	}
+	if member {
+		return nil, ErrAlreadyOrgMember
+	}
+
+	pending, err := s.orgRepo.ListPendingByOrg(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(pending, func(i *models.OrgInvitation) bool { return strings.EqualFold(i.Email, email) }) {
+		return nil, ErrOrgInvitationPending
+	}
+
+	var inviteeID *uuid.UUID
+	invitee, err := s.userRepo.GetByEmail(ctx, email)
+	if err == nil {
+		inviteeID = &invitee.ID
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	invitation := &models.OrgInvitation{
+		OrganizationID:   id,
+		OrganizationName: org.Name,
+		Email:            email,
+		Role:             req.Role,
+		InvitedBy:        &userID,
+		ExpiresAt:        time.Now().UTC().Add(orgInvitationTTL),
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.orgRepo.CreateInvitation(ctx, invitation); err != nil {
+			return err
+		}
+
+		return s.eventService.Record(ctx, models.NewDomainEvent(
+			models.EventOrgInvitationCreated, "organization_invitation", invitation.ID, inviteeID,
+			map[string]any{
+				"organization_id":   id,
+				"organization_name": org.Name,
+				"email":             email,
+				"role":              invitation.Role,
+				"invited_by":        userID,
+				"expires_at":        invitation.ExpiresAt,
+			},
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// ListInvitations returns the pending invitations of an organization the user owns
+func (s *OrganizationService) ListInvitations(ctx context.Context, id, userID uuid.UUID) ([]*models.OrgInvitation, error) {
+	if _, err := s.requireRole(ctx, id, userID, models.OrgRoleOwner); err != nil {
+		return nil, err
+	}
+
+	return s.orgRepo.ListPendingByOrg(ctx, id)
+}
+
+// RevokeInvitation withdraws a pending invitation of an organization the user owns
+func (s *OrganizationService) RevokeInvitation(ctx context.Context, id, invitationID, userID uuid.UUID) error {
+	if _, err := s.requireRole(ctx, id, userID, models.OrgRoleOwner); err != nil {
+		return err
+	}
+
+	return s.orgRepo.DeleteInvitation(ctx, invitationID, id)
+}
+
+// ListReceivedInvitations returns the pending invitations sent to the user's email
+func (s *OrganizationService) ListReceivedInvitations(ctx context.Context, userID uuid.UUID) ([]*models.OrgInvitation, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.orgRepo.ListPendingByEmail(ctx, user.Email)
+}
+
+// AcceptInvitation makes the user a member of the organization an invitation
+// sent to their email is for, with the invited role
+func (s *OrganizationService) AcceptInvitation(ctx context.Context, invitationID, userID uuid.UUID) (*models.OrgInvitation, error) {
+	invitation, err := s.receivedInvitation(ctx, invitationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.orgRepo.AnswerInvitation(ctx, invitation.ID, true); err != nil {
+			if errors.Is(err, repository.ErrOrgInvitationNotFound) {
+				return ErrOrgInvitationInactive
+			}
+			return err
+		}
+
+		_, err := s.orgRepo.GetMemberRole(ctx, invitation.OrganizationID, userID)
+		if err == nil {
+			return ErrAlreadyOrgMember
+		}
+		if !errors.Is(err, repository.ErrOrgMemberNotFound) {
+			return err
+		}
+
+		return s.orgRepo.AddMember(ctx, invitation.OrganizationID, userID, invitation.Role)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Organization invitation accepted",
+		"organization_id", invitation.OrganizationID, "user_id", userID, "role", invitation.Role)
+	return invitation, nil
+}
+
+// DeclineInvitation declines an invitation sent to the user's email
+func (s *OrganizationService) DeclineInvitation(ctx context.Context, invitationID, userID uuid.UUID) error {
+	invitation, err := s.receivedInvitation(ctx, invitationID, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.orgRepo.AnswerInvitation(ctx, invitation.ID, false)
+	if errors.Is(err, repository.ErrOrgInvitationNotFound) {
+		return ErrOrgInvitationInactive
+	}
+	return err
+}
+
+// UpdateMember changes the role of a member of an organization the user owns
+func (s *OrganizationService) UpdateMember(ctx context.Context, id, userID, memberID uuid.UUID, role models.OrgRole) error {
+	if _, err := s.requireRole(ctx, id, userID, models.OrgRoleOwner); err != nil {
+		return err
+	}
+
+	return s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		owners, err := s.orgRepo.LockOwners(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		current, err := s.orgRepo.GetMemberRole(ctx, id, memberID)
+		if err != nil {
+			return err
+		}
+		if current == models.OrgRoleOwner && role != models.OrgRoleOwner && owners <= 1 {
+			return ErrLastOrgOwner
+		}
+
+		return s.orgRepo.UpdateMemberRole(ctx, id, memberID, role)
+	})
+}
+
+// RemoveMember removes a member from an organization. Owners may remove anyone
+// and every member may leave. The documents the member shared stop being shared.
+func (s *OrganizationService) RemoveMember(ctx context.Context, id, userID, memberID uuid.UUID) error {
+	if memberID != userID {
+		if _, err := s.requireRole(ctx, id, userID, models.OrgRoleOwner); err != nil {
+			return err
+		}
+	} else if _, err := s.memberRole(ctx, id, userID); err != nil {
+		return err
+	}
+
+	var unshared int64
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		owners, err := s.orgRepo.LockOwners(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		role, err := s.orgRepo.GetMemberRole(ctx, id, memberID)
+		if err != nil {
+			return err
+		}
+		if role == models.OrgRoleOwner && owners <= 1 {
+			return ErrLastOrgOwner
+		}
+
+		if err := s.orgRepo.RemoveMember(ctx, id, memberID); err != nil {
+			return err
+		}
+
+		unshared, err = s.documentRepo.UnshareByUser(ctx, id, memberID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Organization member removed",
+		"organization_id", id, "member_id", memberID, "removed_by", userID, "documents_unshared", unshared)
+	return nil
+}
+
+// CheckCanShare reports whether the user may share documents with an organization
+func (s *OrganizationService) CheckCanShare(ctx context.Context, id, userID uuid.UUID) error {
+	role, err := s.memberRole(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !role.CanShare() {
+		return ErrOrgRoleForbidden
+	}
+	return nil
+}
+
+// ShareDocuments shares some of the user's documents with an organization,
+// moving them from any organization they were shared with before
+func (s *OrganizationService) ShareDocuments(ctx context.Context, id, userID uuid.UUID, documentIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if err := s.CheckCanShare(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	shared := []uuid.UUID{}
+	for _, documentID := range documentIDs {
+		if slices.Contains(shared, documentID) {
+			continue
+		}
+		document, err := s.documentRepo.GetByID(ctx, documentID)
+		if err != nil {
+			return nil, err
+		}
+		if document.UserID != userID {
+			return nil, apperrors.Forbidden("AUTH_004", "document does not belong to user")
+		}
+		shared = append(shared, documentID)
+	}
+
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		for _, documentID := range shared {
+			if err := s.documentRepo.SetOrg(ctx, documentID, &id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return shared, nil
+}
+
+// UnshareDocument stops sharing a document with an organization. The member
+// who shared it and the organization's owners may unshare it.
+func (s *OrganizationService) UnshareDocument(ctx context.Context, id, userID, documentID uuid.UUID) error {
+	role, err := s.memberRole(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	document, err := s.orgDocument(ctx, id, documentID)
+	if err != nil {
+		return err
+	}
+	if document.UserID != userID && role != models.OrgRoleOwner {
+		return ErrOrgRoleForbidden
+	}
+
+	return s.documentRepo.SetOrg(ctx, documentID, nil)
+}
+
+// ListDocuments returns a page of the documents shared with one of the user's
+// organizations
+func (s *OrganizationService) ListDocuments(ctx context.Context, id, userID uuid.UUID, page, perPage int) ([]models.Document, int, error) {
+	if _, err := s.memberRole(ctx, id, userID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.documentRepo.ListByOrg(ctx, id, page, perPage)
+}
+
+// GetDocument returns a document shared with one of the user's organizations,
+// with its latest result
+func (s *OrganizationService) GetDocument(ctx context.Context, id, userID, documentID uuid.UUID) (*models.OrgDocument, error) {
+	if _, err := s.memberRole(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	document, err := s.orgDocument(ctx, id, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	orgDocument := &models.OrgDocument{Document: document}
+	results, err := s.resultRepo.GetByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		orgDocument.Result = results[0]
+	}
+
+	return orgDocument, nil
+}
+
+// ListJobs returns a page of the jobs of the documents shared with one of the
+// user's organizations
+func (s *OrganizationService) ListJobs(ctx context.Context, id, userID uuid.UUID, page, perPage int) ([]*models.OCRJob, int, error) {
+	if _, err := s.memberRole(ctx, id, userID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.jobRepo.GetByOrgID(ctx, id, page, perPage)
+}
+
+// orgDocument returns a document shared with an organization
+func (s *OrganizationService) orgDocument(ctx context.Context, id, documentID uuid.UUID) (*models.Document, error) {
+	document, err := s.documentRepo.GetByID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.OrgID == nil || *document.OrgID != id {
+		return nil, repository.ErrDocumentNotFound
+	}
+	return document, nil
+}
+
+// memberRole returns the user's role in an organization. Organizations the
+// user is not a member of are reported as not found.
+func (s *OrganizationService) memberRole(ctx context.Context, id, userID uuid.UUID) (models.OrgRole, error) {
+	role, err := s.orgRepo.GetMemberRole(ctx, id, userID)
+	if errors.Is(err, repository.ErrOrgMemberNotFound) {
+		return "", repository.ErrOrganizationNotFound
+	}
+	return role, err
+}
+
+// requireRole returns an organization the user is a member of with the given role
+func (s *OrganizationService) requireRole(ctx context.Context, id, userID uuid.UUID, role models.OrgRole) (*models.Organization, error) {
+	org, err := s.orgRepo.GetForMember(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if org.Role != role {
+		return nil, ErrOrgRoleForbidden
+	}
+	return org, nil
+}
+
+// receivedInvitation returns an invitation sent to the user's email
+func (s *OrganizationService) receivedInvitation(ctx context.Context, invitationID, userID uuid.UUID) (*models.OrgInvitation, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.orgRepo.GetInvitation(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, repository.ErrOrgInvitationNotFound
+	}
+	if !invitation.Pending(time.Now()) {
+		return nil, ErrOrgInvitationInactive
+	}
+
+	return invitation, nil
+}
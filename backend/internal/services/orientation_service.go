@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/imaging"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+)
+
+// ErrPreviewUnsupported is returned for documents no preview can be rendered of
+var ErrPreviewUnsupported = errors.New("previews are only available for JPEG, PNG and GIF documents")
+
+var (
+	// ErrRegionUnsupported is returned for region reprocessing of documents
+	// other than images, which have no pixels to crop
+	ErrRegionUnsupported = apperrors.Validation("VAL_044", "regions can only be reprocessed on JPEG, PNG and GIF documents")
+	// ErrRegionOutside is returned for a region that does not overlap the page
+	ErrRegionOutside = apperrors.Validation("VAL_045", "region is outside the page")
+)
+
+// OrientationService turns image documents upright for OCR and previews. The
+// orientation of a document is found the first time it is needed, from its
+// EXIF tag and the layout of its text, and recorded in its metadata.
+type OrientationService struct {
+	documentRepo *repository.DocumentRepository
+	storage      *storage.Storage
+}
+
+// NewOrientationService creates a new orientation service
+func NewOrientationService(documentRepo *repository.DocumentRepository, storage *storage.Storage) *OrientationService {
+	return &OrientationService{
+		documentRepo: documentRepo,
+		storage:      storage,
+	}
+}
+
+// Upright returns the contents of a document read from file turned upright,
+// with the filename to send them under. Documents other than JPEG, PNG and GIF
+// images, and images already upright, are returned as they are.
+func (s *OrientationService) Upright(ctx context.Context, document *models.Document, file io.Reader) (io.Reader, string, error) {
+	if !strings.HasPrefix(document.MimeType, "image/") {
+		return file, document.Filename, nil
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read document: %w", err)
+	}
+
+	decoded, err := imaging.Decode(data)
+	if err != nil {
+		return bytes.NewReader(data), document.Filename, nil
+	}
+
+	orientation := s.orientation(ctx, document, decoded)
+	if orientation.Upright() {
+		return bytes.NewReader(data), document.Filename, nil
+	}
+
+	upright, ext, err := imaging.Encode(orientation.Apply(decoded.Image), decoded.Format)
+	if err != nil {
+		logger.Warn("Failed to encode upright image, sending it as stored", "document_id", document.ID, "error", err)
+		return bytes.NewReader(data), document.Filename, nil
+	}
+
+	logger.Debug("Turned document upright", "document_id", document.ID, "rotation", orientation.Rotation)
+	return bytes.NewReader(upright), strings.TrimSuffix(document.Filename, filepath.Ext(document.Filename)) + ext, nil
+}
+
+// UprightRegion returns the part of an image document read from file inside
+// bbox, given as x0, y0, x1, y1 in pixels of the upright image, with the
+// filename to send it under
+func (s *OrientationService) UprightRegion(ctx context.Context, document *models.Document, file io.Reader, bbox [4]float64) (io.Reader, string, error) {
+	if !strings.HasPrefix(document.MimeType, "image/") {
+		return nil, "", ErrRegionUnsupported
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read document: %w", err)
+	}
+
+	decoded, err := imaging.Decode(data)
+	if err != nil {
+		return nil, "", ErrRegionUnsupported
+	}
+
+	orientation := s.orientation(ctx, document, decoded)
+	rect := image.Rect(
+		int(math.Floor(bbox[0])), int(math.Floor(bbox[1])),
+		int(math.Ceil(bbox[2])), int(math.Ceil(bbox[3])),
+	)
+	region, ok := imaging.Crop(orientation.Apply(decoded.Image), rect)
+	if !ok {
+		return nil, "", ErrRegionOutside
+	}
+
+	cropped, ext, err := imaging.Encode(region, decoded.Format)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode region: %w", err)
+	}
+
+	return bytes.NewReader(cropped), strings.TrimSuffix(document.Filename, filepath.Ext(document.Filename)) + ext, nil
+}
+
+// Preview renders an image document upright, scaled down so that neither side
+// is longer than maxSide. It returns the image and its content type.
+func (s *OrientationService) Preview(ctx context.Context, document *models.Document, maxSide int) ([]byte, string, error) {
+	if !strings.HasPrefix(document.MimeType, "image/") {
+		return nil, "", ErrPreviewUnsupported
+	}
+
+	file, err := s.storage.Open(document.FilePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read document: %w", err)
+	}
+
+	decoded, err := imaging.Decode(data)
+	if err != nil {
+		return nil, "", ErrPreviewUnsupported
+	}
+
+	orientation := s.orientation(ctx, document, decoded)
+	preview, ext, err := imaging.Encode(imaging.Fit(orientation.Apply(decoded.Image), maxSide), decoded.Format)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode preview: %w", err)
+	}
+
+	return preview, storage.GetMimeType(ext), nil
+}
+
+// orientation returns the orientation recorded for a document, detecting and
+// recording it when there is none yet
+func (s *OrientationService) orientation(ctx context.Context, document *models.Document, decoded *imaging.Decoded) imaging.Orientation {
+	if recorded, ok := document.Metadata[models.DocumentMetaOrientation]; ok {
+		var orientation imaging.Orientation
+		raw, err := json.Marshal(recorded)
+		if err == nil {
+			err = json.Unmarshal(raw, &orientation)
+		}
+		if err == nil {
+			return orientation
+		}
+		logger.Warn("Ignoring unreadable document orientation", "document_id", document.ID, "error", err)
+	}
+
+	decoded.Detect()
+
+	if document.Metadata == nil {
+		document.Metadata = map[string]any{}
+	}
+	document.Metadata[models.DocumentMetaOrientation] = decoded.Orientation
+	if err := s.documentRepo.UpdateMetadata(ctx, document.ID, document.Labels, document.Metadata); err != nil {
+		logger.Warn("Failed to record document orientation", "document_id", document.ID, "error", err)
+	}
+
+	if decoded.Orientation.Rotation != 0 {
+		logger.Info("Detected document orientation",
+			"document_id", document.ID, "exif", decoded.Orientation.EXIF, "rotation", decoded.Orientation.Rotation)
+	}
+	return decoded.Orientation
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/pdf"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// pdfMimeType is the MIME type of the documents whose pages are counted
+const pdfMimeType = "application/pdf"
+
+// CountPages returns the number of pages of a stored document: the page count
+// of a PDF, or 1 for an image or a PDF whose pages cannot be counted
+func CountPages(files *storage.Storage, filePath, mimeType string) int {
+	if mimeType != pdfMimeType {
+		return 1
+	}
+	pages, err := countPDFPages(files, filePath)
+	if err != nil {
+		logger.Warn("Failed to count PDF pages", "path", filePath, "error", err)
+		return 1
+	}
+	return pages
+}
+
+// countPDFPages reads the page count of a stored PDF
+func countPDFPages(files *storage.Storage, filePath string) (int, error) {
+	file, err := files.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return pdf.CountPages(file)
+}
+
+// NewPageCountBackfill creates the backfill task recounting the pages of
+// every PDF, which documents uploaded before pages were counted lack
+func NewPageCountBackfill(documentRepo *repository.DocumentRepository, files *storage.Storage) Backfill {
+	return Backfill{
+		Name:        "recount_pdf_pages",
+		Description: "Recount the pages of every PDF document and fix the stored page counts",
+		Count: func(ctx context.Context) (int, error) {
+			return documentRepo.CountByMimeType(ctx, pdfMimeType)
+		},
+		Batch: func(ctx context.Context, cursor string, limit int) (BackfillBatch, error) {
+			var after uuid.UUID
+			if cursor != "" {
+				var err error
+				if after, err = uuid.Parse(cursor); err != nil {
+					return BackfillBatch{}, err
+				}
+			}
+
+			documents, err := documentRepo.ListByMimeTypeAfter(ctx, pdfMimeType, after, limit)
+			if err != nil {
+				return BackfillBatch{}, err
+			}
+
+			batch := BackfillBatch{Cursor: cursor, Done: len(documents) < limit}
+			for _, document := range documents {
+				batch.Cursor = document.ID.String()
+				batch.Processed++
+
+				pages, err := countPDFPages(files, document.FilePath)
+				if err != nil {
+					logger.Warn("Failed to count PDF pages", "document_id", document.ID, "error", err)
+					batch.Failed++
+					continue
+				}
+				if pages == document.NumPages {
+					continue
+				}
+
+				if err := documentRepo.UpdateNumPages(ctx, document.ID, pages); err != nil {
+					if errors.Is(err, repository.ErrDocumentNotFound) {
+						// Deleted since it was listed
+						continue
+					}
+					return BackfillBatch{}, err
+				}
+				batch.Updated++
+			}
+			return batch, nil
+		},
+	}
+}
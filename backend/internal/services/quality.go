@@ -0,0 +1,104 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/ocr"
+	"visekai/backend/pkg/quality"
+)
+
+// Thresholds below which a document gets a suggestion
+const (
+	// qualityMinDPI is the resolution below which small print is lost; scans
+	// should be made at 300 DPI
+	qualityMinDPI = 200.0
+	// qualityMinSharpness is the Laplacian variance below which scans are blurry
+	qualityMinSharpness = 100.0
+	// qualityMaxSkew is the rotation in degrees above which recognition degrades
+	qualityMaxSkew = 2.0
+	// qualityLowConfidence is the confidence below which a word or result counts as poorly recognized
+	qualityLowConfidence = 0.6
+	// qualityLowConfidenceWords is the share of poorly recognized words that
+	// suggests the wrong OCR mode or resolution
+	qualityLowConfidenceWords = 0.25
+)
+
+// assessQuality scores how well a job recognized its document and suggests what
+// would improve it. metrics is nil for documents that are not images.
+func assessQuality(job *models.OCRJob, resp *ocr.OCRResponse, metrics *quality.ImageMetrics) *models.DocumentQuality {
+	assessment := &models.DocumentQuality{
+		Confidence:  resp.Confidence,
+		Suggestions: []models.QualitySuggestion{},
+		JobID:       job.ID,
+		AssessedAt:  time.Now().UTC(),
+	}
+
+	var lines, words []quality.Box
+	lowWords := 0
+	for _, region := range resp.Regions {
+		box := quality.Box{X0: region.BBox[0], Y0: region.BBox[1], X1: region.BBox[2], Y1: region.BBox[3]}
+		if models.RegionLevel(region.Level) == models.RegionLevelLine {
+			lines = append(lines, box)
+			continue
+		}
+		words = append(words, box)
+		if region.Confidence < qualityLowConfidence {
+			lowWords++
+		}
+	}
+	if len(words) > 0 {
+		assessment.LowConfidenceWords = float64(lowWords) / float64(len(words))
+	}
+
+	// The score weighs confidence most, and each image factor that was measured
+	weighted, weights := 0.55*clamp01(resp.Confidence), 0.55
+	suggest := func(code, message string) {
+		assessment.Suggestions = append(assessment.Suggestions, models.QualitySuggestion{Code: code, Message: message})
+	}
+
+	if metrics != nil {
+		assessment.DPI = &metrics.DPI
+		assessment.DPIEstimated = metrics.DPIEstimated
+		assessment.Sharpness = &metrics.Sharpness
+		weighted += 0.15 * clamp01((metrics.DPI-100)/200)
+		weighted += 0.15 * clamp01(metrics.Sharpness/qualityMinSharpness)
+		weights += 0.3
+
+		if metrics.DPI < qualityMinDPI {
+			suggest(models.SuggestRescanHigherDPI, "Rescan at 300 DPI")
+		}
+		if metrics.Sharpness < qualityMinSharpness {
+			suggest(models.SuggestRescanSharper, "Rescan with the page flat and in focus")
+		}
+
+		// Regions of multi-page documents would mix pages, so skew is only
+		// measured on single images
+		if skew, ok := quality.Skew(lines, words); ok {
+			assessment.SkewDegrees = &skew
+			weighted += 0.15 * clamp01(1-math.Abs(skew)/10)
+			weights += 0.15
+
+			if math.Abs(skew) > qualityMaxSkew {
+				suggest(models.SuggestStraighten, "Straighten the page on the scanner and rescan")
+			}
+		}
+	}
+
+	poorlyRecognized := resp.Confidence < qualityLowConfidence || assessment.LowConfidenceWords > qualityLowConfidenceWords
+	if poorlyRecognized && job.OCRMode != models.OCRModeHandwritten {
+		suggest(models.SuggestHandwrittenMode, "Use handwritten mode if the document is handwritten")
+	}
+	if poorlyRecognized && job.ResolutionMode != models.ResolutionLarge && job.ResolutionMode != models.ResolutionGundam {
+		suggest(models.SuggestLargerResolution, "Reprocess at large resolution")
+	}
+
+	assessment.Score = int(math.Round(100 * weighted / weights))
+	return assessment
+}
+
+// clamp01 limits v to the range 0 to 1
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
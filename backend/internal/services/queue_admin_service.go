@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/ocr"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultJobFilterLimit and maxJobFilterLimit bound the jobs a filter selects
+	defaultJobFilterLimit = 100
+	maxJobFilterLimit     = 1000
+)
+
+// ErrInvalidJobFilter is returned when a filter selects jobs in a status the
+// operation does not apply to
+var ErrInvalidJobFilter = apperrors.Validation("VAL_001", "invalid job filter")
+
+// ErrServerOnly is returned for operations that need the API server's worker
+// pool or storage, when the service runs elsewhere such as in visekai-admin
+var ErrServerOnly = errors.New("operation is only available through the API server")
+
+// CleanupReport summarizes a cleanup run triggered by an operator
+type CleanupReport struct {
+	Janitor     *JanitorReport `json:"janitor"`
+	ExpiredJobs int            `json:"expired_jobs"`
+}
+
+// OCRHealthReport describes the reachability of the OCR backend
+type OCRHealthReport struct {
+	URL       string         `json:"url"`
+	Healthy   bool           `json:"healthy"`
+	LatencyMS int64          `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Status    map[string]any `json:"status,omitempty"`
+	// SchemaVersion is the response schema version the client negotiated or
+	// last received, empty before the first request
+	SchemaVersion string `json:"schema_version,omitempty"`
+}
+
+// QueueAdminService lets operators inspect and manipulate the job queue. It backs
+// both the admin API and visekai-admin, which runs it against the database
+// directly; there jobService, janitor and expiry are nil, so requeued jobs stay
+// pending until the API server next starts and cleanup is unavailable.
+type QueueAdminService struct {
+	jobRepo    *repository.JobRepository
+	jobService *JobService
+	janitor    *JanitorService
+	expiry     *JobExpiryService
+	ocrClient  *ocr.Client
+}
+
+// NewQueueAdminService creates a new queue admin service
+func NewQueueAdminService(
+	jobRepo *repository.JobRepository,
+	jobService *JobService,
+	janitor *JanitorService,
+	expiry *JobExpiryService,
+	ocrClient *ocr.Client,
+) *QueueAdminService {
+	return &QueueAdminService{
+		jobRepo:    jobRepo,
+		jobService: jobService,
+		janitor:    janitor,
+		expiry:     expiry,
+		ocrClient:  ocrClient,
+	}
+}
+
+// QueueStats counts jobs by status and, inside the API server, reports the
+// state of its worker pool
+func (s *QueueAdminService) QueueStats(ctx context.Context) (*models.QueueStats, error) {
+	stats, err := s.jobRepo.QueueStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.jobService != nil {
+		stats.Workers = s.jobService.runner.Workers()
+		stats.Queued = s.jobService.runner.Queued()
+	}
+
+	return stats, nil
+}
+
+// ListJobs returns the jobs matching the filter, oldest first
+func (s *QueueAdminService) ListJobs(ctx context.Context, filter models.JobFilter) ([]*models.OCRJob, error) {
+	return s.jobRepo.Find(ctx, withFilterLimit(filter))
+}
+
+// Requeue resets failed, cancelled or processing jobs matching the filter to
+// pending and queues them. Without statuses the filter selects failed jobs.
+func (s *QueueAdminService) Requeue(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error) {
+	filter, err := withStatuses(filter,
+		[]models.JobStatus{models.JobStatusFailed},
+		[]models.JobStatus{models.JobStatusFailed, models.JobStatusCancelled, models.JobStatusProcessing},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := s.jobRepo.RequeueMatching(ctx, filter, "Requeued by an administrator")
+	if err != nil {
+		return nil, err
+	}
+
+	if s.jobService != nil {
+		for _, id := range ids {
+			s.jobService.enqueue(id)
+		}
+	}
+	logger.Info("Jobs requeued by an administrator", "count", len(ids), "queued", s.jobService != nil)
+
+	return ids, nil
+}
+
+// Cancel cancels pending or processing jobs matching the filter. Without
+// statuses the filter selects both.
+func (s *QueueAdminService) Cancel(ctx context.Context, filter models.JobFilter) ([]uuid.UUID, error) {
+	active := []models.JobStatus{models.JobStatusPending, models.JobStatusProcessing}
+	filter, err := withStatuses(filter, active, active)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := s.jobRepo.CancelMatching(ctx, filter, "Cancelled by an administrator")
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Jobs cancelled by an administrator", "count", len(ids))
+
+	return ids, nil
+}
+
+// Cleanup runs the janitor and expires overdue pending jobs right away
+func (s *QueueAdminService) Cleanup(ctx context.Context) (*CleanupReport, error) {
+	if s.janitor == nil || s.expiry == nil {
+		return nil, ErrServerOnly
+	}
+
+	report := &CleanupReport{Janitor: s.janitor.Run(ctx)}
+
+	expired, err := s.expiry.ExpirePending(ctx)
+	report.ExpiredJobs = expired
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// OCRHealth checks the OCR backend and fetches its status
+func (s *QueueAdminService) OCRHealth(ctx context.Context) *OCRHealthReport {
+	report := &OCRHealthReport{URL: s.ocrClient.BaseURL()}
+
+	started := time.Now()
+	err := s.ocrClient.HealthCheck(ctx)
+	report.LatencyMS = time.Since(started).Milliseconds()
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Healthy = true
+
+	// The status endpoint is informational; a failure does not make the backend unhealthy
+	if status, err := s.ocrClient.GetStatus(ctx); err == nil {
+		report.Status = status
+	}
+	report.SchemaVersion = s.ocrClient.Schema()
+
+	return report
+}
+
+// withFilterLimit applies the default and maximum number of jobs to a filter
+func withFilterLimit(filter models.JobFilter) models.JobFilter {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultJobFilterLimit
+	}
+	filter.Limit = min(filter.Limit, maxJobFilterLimit)
+	return filter
+}
+
+// withStatuses defaults the statuses of a filter and checks they are all allowed
+func withStatuses(filter models.JobFilter, defaults, allowed []models.JobStatus) (models.JobFilter, error) {
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = defaults
+	}
+	for _, status := range filter.Statuses {
+		if !slices.Contains(allowed, status) {
+			return filter, fmt.Errorf("%w: jobs with status %s cannot be selected", ErrInvalidJobFilter, status)
+		}
+	}
+	return withFilterLimit(filter), nil
+}
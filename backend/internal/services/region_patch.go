@@ -0,0 +1,143 @@
+package services
+
+import (
+	"strings"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/ocr"
+
+	"github.com/google/uuid"
+)
+
+// patchRegion merges the OCR of a region of a page into the page's current
+// version, filling in the text and confidence of its new version. Regions of
+// the page centered inside bbox make way for the new ones, which are moved from
+// the cropped image's coordinates to the page's, and the lines of text they
+// held are replaced in the page text. It returns the page's merged regions.
+func patchRegion(current, patched *models.ResultPage, existing []*models.ResultRegion, bbox [4]float64, ocrResponse *ocr.OCRResponse) []*models.ResultRegion {
+	recognized := toResultRegions(patched.ResultID, ocrResponse.Regions)
+	for _, region := range recognized {
+		region.PageNumber = patched.PageNumber
+		region.X0 += bbox[0]
+		region.Y0 += bbox[1]
+		region.X1 += bbox[0]
+		region.Y1 += bbox[1]
+	}
+
+	// Merge each level on its own, keeping the reading order of the page
+	var merged, removedLines []*models.ResultRegion
+	var nextLine *models.ResultRegion
+	for _, level := range []models.RegionLevel{models.RegionLevelLine, models.RegionLevelWord} {
+		var kept, removed []*models.ResultRegion
+		at := -1
+		for _, region := range existing {
+			if region.Level != level {
+				continue
+			}
+			cx, cy := (region.X0+region.X1)/2, (region.Y0+region.Y1)/2
+			switch {
+			case cx >= bbox[0] && cx <= bbox[2] && cy >= bbox[1] && cy <= bbox[3]:
+				removed = append(removed, region)
+				if at < 0 {
+					at = len(kept)
+				}
+			case at < 0 && len(removed) == 0 && cy > bbox[3]:
+				at = len(kept)
+				if level == models.RegionLevelLine {
+					nextLine = region
+				}
+				kept = append(kept, region)
+			default:
+				kept = append(kept, region)
+			}
+		}
+		if at < 0 {
+			at = len(kept)
+		}
+
+		merged = append(merged, kept[:at]...)
+		for _, region := range recognized {
+			if region.Level == level {
+				merged = append(merged, region)
+			}
+		}
+		merged = append(merged, kept[at:]...)
+
+		if level == models.RegionLevelLine {
+			removedLines = removed
+		}
+	}
+
+	old := make([]string, len(removedLines))
+	for i, region := range removedLines {
+		old[i] = region.Text
+	}
+	next := ""
+	if nextLine != nil {
+		next = nextLine.Text
+	}
+	patched.RawText = spliceText(current.RawText, old, next, ocrResponse.Text)
+	patched.MarkdownText = spliceText(current.MarkdownText, old, next, ocrResponse.Markdown)
+
+	// Score the page by all its lines, not only the ones read again
+	patched.ConfidenceScore = ocrResponse.Confidence
+	for _, level := range []models.RegionLevel{models.RegionLevelLine, models.RegionLevelWord} {
+		total, n := 0.0, 0
+		for _, region := range merged {
+			if region.Level == level {
+				total += region.Confidence
+				n++
+			}
+		}
+		if n > 0 {
+			patched.ConfidenceScore = total / float64(n)
+			break
+		}
+	}
+
+	for i, region := range merged {
+		region.ID = uuid.Nil
+		region.Sequence = i
+	}
+	return merged
+}
+
+// spliceText replaces the span of text holding the old lines, in order, with
+// replacement. When none of them can be found, replacement is put before the
+// next line, or at the end of the text.
+func spliceText(text string, old []string, next, replacement string) string {
+	replacement = strings.TrimSpace(replacement)
+
+	start, end, from := -1, -1, 0
+	for _, line := range old {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.Index(text[from:], line)
+		if i < 0 {
+			continue
+		}
+		if start < 0 {
+			start = from + i
+		}
+		from += i + len(line)
+		end = from
+	}
+	if start >= 0 {
+		return text[:start] + replacement + text[end:]
+	}
+
+	if replacement == "" {
+		return text
+	}
+	if next = strings.TrimSpace(next); next != "" {
+		if i := strings.Index(text, next); i >= 0 {
+			return text[:i] + replacement + "\n" + text[i:]
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return replacement
+	}
+	return strings.TrimRight(text, "\n") + "\n" + replacement
+}
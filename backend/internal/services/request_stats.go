@@ -0,0 +1,81 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// requestStatsMinutes is how many minutes of requests are kept
+const requestStatsMinutes = 24 * 60
+
+// requestBucket counts the requests answered in one minute
+type requestBucket struct {
+	minute   int64
+	requests int
+	errors   map[string]int
+}
+
+// RequestStats counts the API requests this instance answered and its error
+// responses by code, by minute over the last day, for the admin dashboard
+type RequestStats struct {
+	mu        sync.Mutex
+	buckets   [requestStatsMinutes]requestBucket
+	startedAt time.Time
+}
+
+// NewRequestStats creates empty request statistics
+func NewRequestStats() *RequestStats {
+	return &RequestStats{startedAt: time.Now().UTC()}
+}
+
+// Record counts a request answered at a time, with the error code of its
+// response or "" for successes
+func (s *RequestStats) Record(at time.Time, code string) {
+	minute := at.Unix() / 60
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket := &s.buckets[minute%requestStatsMinutes]
+	if bucket.minute != minute {
+		*bucket = requestBucket{minute: minute}
+	}
+	bucket.requests++
+	if code != "" {
+		if bucket.errors == nil {
+			bucket.errors = map[string]int{}
+		}
+		bucket.errors[code]++
+	}
+}
+
+// Since sums the requests and errors by code answered from a time on, to the
+// minute, and returns from when they were counted: since, or when counting
+// started if later
+func (s *RequestStats) Since(since time.Time) (int, map[string]int, time.Time) {
+	from := since.Unix() / 60
+	if oldest := time.Now().Unix()/60 - requestStatsMinutes + 1; from < oldest {
+		from = oldest
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := 0
+	errors := map[string]int{}
+	for _, bucket := range s.buckets {
+		if bucket.minute < from {
+			continue
+		}
+		requests += bucket.requests
+		for code, n := range bucket.errors {
+			errors[code] += n
+		}
+	}
+
+	counted := time.Unix(from*60, 0).UTC()
+	if counted.Before(s.startedAt) {
+		counted = s.startedAt
+	}
+	return requests, errors, counted
+}
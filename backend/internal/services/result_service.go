@@ -0,0 +1,397 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/pdf"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// ErrSearchablePDFUnsupported is returned when the source document cannot carry a text layer
+var ErrSearchablePDFUnsupported = errors.New("searchable PDF export needs a readable PDF or a JPEG, PNG, GIF, TIFF, BMP or WEBP image")
+
+// ResultService handles OCR result operations
+type ResultService struct {
+	resultRepo   *repository.ResultRepository
+	regionRepo   *repository.RegionRepository
+	jobRepo      *repository.JobRepository
+	documentRepo *repository.DocumentRepository
+	entities     *EntityService
+	orientation  *OrientationService
+	storage      *storage.Storage
+}
+
+// NewResultService creates a new result service
+func NewResultService(
+	resultRepo *repository.ResultRepository,
+	regionRepo *repository.RegionRepository,
+	jobRepo *repository.JobRepository,
+	documentRepo *repository.DocumentRepository,
+	entityService *EntityService,
+	orientationService *OrientationService,
+	storage *storage.Storage,
+) *ResultService {
+	return &ResultService{
+		resultRepo:   resultRepo,
+		regionRepo:   regionRepo,
+		jobRepo:      jobRepo,
+		documentRepo: documentRepo,
+		entities:     entityService,
+		orientation:  orientationService,
+		storage:      storage,
+	}
+}
+
+// GetResult retrieves a result by ID, verifying that the user owns its job
+func (s *ResultService) GetResult(ctx context.Context, resultID uuid.UUID, userID uuid.UUID) (*models.OCRResult, error) {
+	result, err := s.resultRepo.GetByID(ctx, resultID)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.jobRepo.GetByID(ctx, result.JobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.UserID != userID {
+		return nil, apperrors.Forbidden("AUTH_004", "result does not belong to user")
+	}
+
+	return result, nil
+}
+
+// GetRegions retrieves the bounding box regions of a result.
+// A page of 0 returns the regions of all pages.
+func (s *ResultService) GetRegions(ctx context.Context, resultID uuid.UUID, userID uuid.UUID, page int) ([]*models.ResultRegion, error) {
+	result, err := s.GetResult(ctx, resultID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.regionRepo.ListByResult(ctx, result.ID, page)
+}
+
+// GetEntities retrieves the entities extracted from a result's text, only those
+// of entityType unless it is empty
+func (s *ResultService) GetEntities(ctx context.Context, resultID uuid.UUID, userID uuid.UUID, entityType models.EntityType) ([]models.ResultEntity, error) {
+	result, err := s.GetResult(ctx, resultID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := s.entities.Entities(result, entityType)
+	if entities == nil {
+		return []models.ResultEntity{}, nil
+	}
+	return entities, nil
+}
+
+// GetFields retrieves the fields extracted from a result with the user's
+// templates, or nil when no template found any
+func (s *ResultService) GetFields(ctx context.Context, resultID uuid.UUID, userID uuid.UUID) (*models.ResultFields, error) {
+	result, err := s.GetResult(ctx, resultID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, ok := result.JSONData[models.ResultJSONFields]
+	if !ok {
+		return nil, nil
+	}
+
+	// Fields read back from the JSONB column are plain maps
+	var fields models.ResultFields
+	raw, err := json.Marshal(stored)
+	if err == nil {
+		err = json.Unmarshal(raw, &fields)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result fields: %w", err)
+	}
+	return &fields, nil
+}
+
+// GetPages retrieves the latest version of every page of a result
+func (s *ResultService) GetPages(ctx context.Context, resultID uuid.UUID, userID uuid.UUID) ([]*models.ResultPage, error) {
+	result, err := s.GetResult(ctx, resultID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := s.resultRepo.ListCurrentPages(ctx, result.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pages == nil {
+		pages = []*models.ResultPage{}
+	}
+	return pages, nil
+}
+
+// GetPage retrieves a version of a page of a result; a version of 0 retrieves
+// the latest
+func (s *ResultService) GetPage(ctx context.Context, resultID uuid.UUID, userID uuid.UUID, page, version int) (*models.ResultPage, error) {
+	result, err := s.GetResult(ctx, resultID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resultRepo.GetPage(ctx, result.ID, page, version)
+}
+
+// GetBreakdown retrieves what each page version of a result cost and sums it
+// by resolution and backend
+func (s *ResultService) GetBreakdown(ctx context.Context, resultID uuid.UUID, userID uuid.UUID) (*models.ResultBreakdown, error) {
+	result, err := s.GetResult(ctx, resultID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := s.resultRepo.ListPageCosts(ctx, result.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &models.ResultBreakdown{
+		ResultID: result.ID,
+		JobID:    result.JobID,
+		NumPages: result.NumPages,
+		Pages:    pages,
+	}
+
+	for _, page := range pages {
+		if page.ProcessingTimeMs != nil {
+			breakdown.ProcessingTimeMs += int64(*page.ProcessingTimeMs)
+		}
+		if page.Credits != nil {
+			breakdown.Credits += *page.Credits
+		}
+	}
+	breakdown.ByResolution = groupPageCosts(pages, func(page *models.PageCost) *string {
+		return (*string)(page.ResolutionMode)
+	})
+	breakdown.ByBackend = groupPageCosts(pages, func(page *models.PageCost) *string {
+		return page.Backend
+	})
+	setPageCostShares(breakdown.ByResolution)
+	setPageCostShares(breakdown.ByBackend)
+
+	return breakdown, nil
+}
+
+// PageUsage sums the pages a user had processed in [since, until) by
+// resolution and backend, with the slowest of them
+func (s *ResultService) PageUsage(ctx context.Context, userID uuid.UUID, since, until time.Time) (*models.PageUsage, error) {
+	byResolution, byBackend, err := s.resultRepo.PageCostGroups(ctx, userID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	slowest, err := s.resultRepo.ListSlowestPages(ctx, userID, since, until, slowestPagesLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.PageUsage{
+		Since:        since,
+		Until:        until,
+		ByResolution: byResolution,
+		ByBackend:    byBackend,
+		SlowestPages: slowest,
+	}
+	// Every page is in exactly one resolution group
+	for _, group := range byResolution {
+		usage.Pages += group.Pages
+		usage.ProcessingTimeMs += group.ProcessingTimeMs
+		usage.Credits += group.Credits
+	}
+	setPageCostShares(usage.ByResolution)
+	setPageCostShares(usage.ByBackend)
+
+	return usage, nil
+}
+
+// slowestPagesLimit is the number of slowest pages in page usage
+const slowestPagesLimit = 10
+
+// groupPageCosts sums page versions by a key, most expensive first. Versions
+// without a key are grouped under "unknown", and the average time is of the
+// versions that were timed.
+func groupPageCosts(pages []*models.PageCost, key func(*models.PageCost) *string) []*models.PageCostGroup {
+	groups := []*models.PageCostGroup{}
+	byKey := map[string]*models.PageCostGroup{}
+	timed := map[string]int{}
+	for _, page := range pages {
+		k := "unknown"
+		if v := key(page); v != nil && *v != "" {
+			k = *v
+		}
+
+		group, ok := byKey[k]
+		if !ok {
+			group = &models.PageCostGroup{Key: k}
+			byKey[k] = group
+			groups = append(groups, group)
+		}
+
+		group.Pages++
+		if page.ProcessingTimeMs != nil {
+			timed[k]++
+			group.ProcessingTimeMs += int64(*page.ProcessingTimeMs)
+			group.MaxProcessingTimeMs = max(group.MaxProcessingTimeMs, *page.ProcessingTimeMs)
+		}
+		if page.Credits != nil {
+			group.Credits += *page.Credits
+		}
+	}
+
+	for k, n := range timed {
+		byKey[k].AvgProcessingTimeMs = float64(byKey[k].ProcessingTimeMs) / float64(n)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Credits != groups[j].Credits {
+			return groups[i].Credits > groups[j].Credits
+		}
+		return groups[i].ProcessingTimeMs > groups[j].ProcessingTimeMs
+	})
+	return groups
+}
+
+// setPageCostShares sets each group's share of the groups' total time and credits
+func setPageCostShares(groups []*models.PageCostGroup) {
+	var totalTime int64
+	var totalCredits int
+	for _, group := range groups {
+		totalTime += group.ProcessingTimeMs
+		totalCredits += group.Credits
+	}
+
+	for _, group := range groups {
+		if totalTime > 0 {
+			group.TimeShare = float64(group.ProcessingTimeMs) / float64(totalTime)
+		}
+		if totalCredits > 0 {
+			group.CreditShare = float64(group.Credits) / float64(totalCredits)
+		}
+	}
+}
+
+// GetResultDocument retrieves the source document of a result
+func (s *ResultService) GetResultDocument(ctx context.Context, result *models.OCRResult) (*models.Document, error) {
+	return s.documentRepo.GetByID(ctx, result.DocumentID)
+}
+
+// WriteSearchablePDF writes the source document of a result with the
+// recognized text as an invisible layer positioned on the word bounding boxes
+// of every page. Images are turned upright as they were for OCR and become
+// the page; PDFs keep their pages, with the text laid over them.
+func (s *ResultService) WriteSearchablePDF(ctx context.Context, result *models.OCRResult, w io.Writer) error {
+	document, err := s.GetResultDocument(ctx, result)
+	if err != nil {
+		return err
+	}
+
+	isImage := strings.HasPrefix(document.MimeType, "image/")
+	if !isImage && document.MimeType != pdfMimeType {
+		return ErrSearchablePDFUnsupported
+	}
+
+	regions, err := s.regionRepo.ListByResult(ctx, result.ID, 0)
+	if err != nil {
+		return err
+	}
+
+	file, err := s.storage.Open(document.FilePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if isImage {
+		upright, _, err := s.orientation.Upright(ctx, document, file)
+		if err != nil {
+			return err
+		}
+
+		image, err := io.ReadAll(upright)
+		if err != nil {
+			return fmt.Errorf("failed to read document: %w", err)
+		}
+
+		page := pdf.Page{
+			Image:        image,
+			Words:        pageWords(regions, 1),
+			FallbackText: result.RawText,
+		}
+		err = pdf.WriteSearchable(w, []pdf.Page{page})
+		if errors.Is(err, pdf.ErrUnsupportedImage) {
+			return ErrSearchablePDFUnsupported
+		}
+		return err
+	}
+
+	source, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("failed to read document: %w", err)
+	}
+
+	pages, err := s.resultRepo.ListCurrentPages(ctx, result.ID)
+	if err != nil {
+		return err
+	}
+	// A single-page result without page rows is its own first page
+	if len(pages) == 0 {
+		pages = []*models.ResultPage{{PageNumber: 1, RawText: result.RawText}}
+	}
+
+	layers := make([]pdf.TextLayer, 0, len(pages))
+	for _, page := range pages {
+		layer := pdf.TextLayer{
+			Page:         page.PageNumber,
+			Words:        pageWords(regions, page.PageNumber),
+			FallbackText: page.RawText,
+		}
+		if page.ImageWidth != nil && page.ImageHeight != nil {
+			layer.Width, layer.Height = float64(*page.ImageWidth), float64(*page.ImageHeight)
+		}
+		layers = append(layers, layer)
+	}
+
+	err = pdf.AddText(w, bytes.NewReader(source), layers)
+	if errors.Is(err, pdf.ErrUnreadablePDF) {
+		return ErrSearchablePDFUnsupported
+	}
+	return err
+}
+
+// pageWords converts the word regions of a page into PDF words, or its line
+// regions when it has no words
+func pageWords(regions []*models.ResultRegion, page int) []pdf.Word {
+	for _, level := range []models.RegionLevel{models.RegionLevelWord, models.RegionLevelLine} {
+		var words []pdf.Word
+		for _, r := range regions {
+			if r.PageNumber != page || r.Level != level {
+				continue
+			}
+			words = append(words, pdf.Word{Text: r.Text, X0: r.X0, Y0: r.Y0, X1: r.X1, Y1: r.Y1})
+		}
+		if len(words) > 0 {
+			return words
+		}
+	}
+	return nil
+}
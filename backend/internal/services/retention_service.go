@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/mail"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// retentionBatchSize is the number of items deleted per query
+	retentionBatchSize = 500
+	// retentionWarningGap is the least time between two warnings of a policy,
+	// so items entering the warning period are announced in daily batches
+	retentionWarningGap = 24 * time.Hour
+)
+
+// RetentionService enforces users' retention policies: on every interval it
+// warns users of the data their policies will delete within the warning
+// period, by email and in their notification channels, and deletes the data
+// that is due. Only data that was warned about is deleted, and a new or
+// changed policy deletes nothing until the warning period elapsed, so data
+// already past a new policy's retention is announced before it goes.
+type RetentionService struct {
+	retentionRepo       *repository.RetentionRepository
+	userRepo            *repository.UserRepository
+	settingsRepo        *repository.UserSettingsRepository
+	trashService        *TrashService
+	notificationService *NotificationService
+	sender              *mail.Sender
+	interval            time.Duration
+	warning             time.Duration
+}
+
+// NewRetentionService creates a new retention service warning users warning
+// ahead of deletions; a zero warning deletes data as soon as it is due
+func NewRetentionService(
+	retentionRepo *repository.RetentionRepository,
+	userRepo *repository.UserRepository,
+	settingsRepo *repository.UserSettingsRepository,
+	trashService *TrashService,
+	notificationService *NotificationService,
+	sender *mail.Sender,
+	interval, warning time.Duration,
+) *RetentionService {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &RetentionService{
+		retentionRepo:       retentionRepo,
+		userRepo:            userRepo,
+		settingsRepo:        settingsRepo,
+		trashService:        trashService,
+		notificationService: notificationService,
+		sender:              sender,
+		interval:            interval,
+		warning:             warning,
+	}
+}
+
+// Start enforces the policies on every interval until the context is cancelled
+func (s *RetentionService) Start(ctx context.Context) {
+	logger.Info("Retention worker started", "interval", s.interval, "warning", s.warning)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Retention worker stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{Name: "retention.enforce", Run: s.Enforce})
+		}
+	}
+}
+
+// List returns the user's policy for every target, with 0 days for targets
+// kept until the user deletes them
+func (s *RetentionService) List(ctx context.Context, userID uuid.UUID) ([]*models.RetentionPolicy, error) {
+	stored, err := s.retentionRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]*models.RetentionPolicy, 0, len(models.RetentionTargets))
+	for _, target := range models.RetentionTargets {
+		policy := &models.RetentionPolicy{UserID: userID, Target: target}
+		for _, existing := range stored {
+			if existing.Target == target {
+				policy = existing
+			}
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// Update sets the user's policies for the targets in the request
+func (s *RetentionService) Update(ctx context.Context, userID uuid.UUID, req models.UpdateRetentionRequest) ([]*models.RetentionPolicy, error) {
+	for _, policy := range req.Policies {
+		if err := s.retentionRepo.Set(ctx, userID, policy.Target, policy.Days); err != nil {
+			return nil, err
+		}
+	}
+	return s.List(ctx, userID)
+}
+
+// Enforce warns about and deletes the data of every policy. A policy that
+// fails is logged and does not stop the others.
+func (s *RetentionService) Enforce(ctx context.Context) error {
+	policies, err := s.retentionRepo.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var failed error
+	for _, policy := range policies {
+		if err := s.warn(ctx, policy, now); err != nil {
+			logger.Error("Failed to warn of retention deletions", "user_id", policy.UserID, "target", policy.Target, "error", err)
+			failed = err
+		}
+
+		deleted, err := s.apply(ctx, policy, now)
+		if err != nil {
+			logger.Error("Failed to enforce retention policy", "user_id", policy.UserID, "target", policy.Target, "error", err)
+			failed = err
+		}
+		if deleted > 0 {
+			logger.Info("Retention policy applied", "user_id", policy.UserID, "target", policy.Target, "deleted", deleted)
+		}
+	}
+
+	return failed
+}
+
+// warn announces the items of a policy that become due within the warning
+// period and were not announced yet, at most once per retentionWarningGap.
+// A policy's first warning also announces the items already past retention.
+func (s *RetentionService) warn(ctx context.Context, policy *models.RetentionPolicy, now time.Time) error {
+	if s.warning <= 0 {
+		return nil
+	}
+	if policy.WarnedAt != nil && policy.WarnedUntil != nil && now.Sub(*policy.WarnedAt) < retentionWarningGap {
+		return nil
+	}
+
+	from := time.Unix(0, 0).UTC()
+	if policy.WarnedUntil != nil {
+		from = *policy.WarnedUntil
+	}
+	until := now.Add(s.warning)
+	if !until.After(from) {
+		return nil
+	}
+
+	count, first, err := s.countDue(ctx, policy, from, until)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return s.retentionRepo.MarkWarned(ctx, policy, until, nil)
+	}
+
+	// Items already past retention go once the policy's grace period elapsed
+	deleteFrom := *first
+	for _, later := range []time.Time{s.graceEnd(policy), now} {
+		if deleteFrom.Before(later) {
+			deleteFrom = later
+		}
+	}
+
+	warning := &models.RetentionWarning{
+		UserID:     policy.UserID,
+		Target:     policy.Target,
+		Days:       policy.Days,
+		Count:      count,
+		DeleteFrom: deleteFrom,
+	}
+	if err := s.notify(ctx, warning); err != nil {
+		return err
+	}
+
+	return s.retentionRepo.MarkWarned(ctx, policy, until, &now)
+}
+
+// countDue counts the items of a policy due for deletion in [from, until),
+// returning when the first one is due
+func (s *RetentionService) countDue(ctx context.Context, policy *models.RetentionPolicy, from, until time.Time) (int, *time.Time, error) {
+	age := time.Duration(policy.Days) * 24 * time.Hour
+
+	var count int
+	var first *time.Time
+	var err error
+	switch policy.Target {
+	case models.RetentionResultText:
+		count, first, err = s.retentionRepo.CountResultText(ctx, policy.UserID, from.Add(-age), until.Add(-age))
+	case models.RetentionDocuments:
+		count, first, err = s.retentionRepo.CountDocuments(ctx, policy.UserID, from.Add(-age), until.Add(-age))
+	case models.RetentionDataExports:
+		// Archives are due when they expire, which their policy set
+		return s.retentionRepo.CountDataExports(ctx, policy.UserID, from, until)
+	default:
+		return 0, nil, nil
+	}
+	if err != nil || first == nil {
+		return count, nil, err
+	}
+
+	due := first.Add(age)
+	return count, &due, nil
+}
+
+// apply deletes the items of a policy that are due and were warned about,
+// returning how many were deleted. Data exports are left to the export
+// builder, which removes archives once they expire.
+func (s *RetentionService) apply(ctx context.Context, policy *models.RetentionPolicy, now time.Time) (int, error) {
+	due := now
+	if s.warning > 0 {
+		if policy.WarnedUntil == nil || now.Before(s.graceEnd(policy)) {
+			return 0, nil
+		}
+		if policy.WarnedUntil.Before(now) {
+			due = *policy.WarnedUntil
+		}
+	}
+	cutoff := due.Add(-time.Duration(policy.Days) * 24 * time.Hour)
+
+	deleted := 0
+	switch policy.Target {
+	case models.RetentionResultText:
+		for {
+			n, err := s.retentionRepo.DeleteResultText(ctx, policy.UserID, cutoff, retentionBatchSize)
+			deleted += n
+			if err != nil || n < retentionBatchSize {
+				return deleted, err
+			}
+		}
+
+	case models.RetentionDocuments:
+		for {
+			ids, err := s.retentionRepo.ListDocumentsBefore(ctx, policy.UserID, cutoff, retentionBatchSize)
+			if err != nil {
+				return deleted, err
+			}
+
+			trashed := 0
+			for _, id := range ids {
+				if _, err := s.trashService.Delete(ctx, id, false); err != nil {
+					if errors.Is(err, ErrDocumentBusy) {
+						// Taken again once its jobs finish
+						continue
+					}
+					return deleted, err
+				}
+				trashed++
+			}
+			deleted += trashed
+
+			if len(ids) < retentionBatchSize || trashed == 0 {
+				return deleted, nil
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+// graceEnd returns when a policy starts deleting, the warning period after it
+// was set
+func (s *RetentionService) graceEnd(policy *models.RetentionPolicy) time.Time {
+	if policy.UpdatedAt == nil {
+		return time.Time{}
+	}
+	return policy.UpdatedAt.Add(s.warning)
+}
+
+// notify sends a warning to the user's notification channels and, when the
+// user gets email notifications and mail is configured, by email
+func (s *RetentionService) notify(ctx context.Context, warning *models.RetentionWarning) error {
+	settings, err := s.settingsRepo.GetByUserID(ctx, warning.UserID)
+	if err != nil {
+		return err
+	}
+
+	notice := retentionNotice(warning, settings.Location())
+	if _, err := s.notificationService.Notify(ctx, warning.UserID, models.NotificationEventRetentionWarning, notice); err != nil {
+		return err
+	}
+
+	if !settings.EmailNotifications || !s.sender.Configured() {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, warning.UserID)
+	if err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	body.WriteString(notice.Title + "\n\n")
+	for _, fact := range notice.Facts {
+		fmt.Fprintf(&body, "%s: %s\n", fact[0], fact[1])
+	}
+	body.WriteString("\nChange or remove your retention policies in your settings to keep this data.\n")
+
+	err = s.sender.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: notice.Title,
+		Body:    body.String(),
+	})
+	if err != nil {
+		logger.Warn("Failed to email retention warning", "user_id", warning.UserID, "target", warning.Target, "error", err)
+	}
+	return nil
+}
+
+// retentionNotice describes a warning, with dates in the user's time zone
+func retentionNotice(warning *models.RetentionWarning, loc *time.Location) models.Notice {
+	var title string
+	switch warning.Target {
+	case models.RetentionResultText:
+		title = fmt.Sprintf("The text of %d OCR results will be deleted", warning.Count)
+	case models.RetentionDocuments:
+		title = fmt.Sprintf("%d documents will be moved to the trash", warning.Count)
+	case models.RetentionDataExports:
+		title = fmt.Sprintf("%d data exports will expire", warning.Count)
+	default:
+		title = fmt.Sprintf("%d items will be deleted", warning.Count)
+	}
+
+	return models.Notice{
+		Title: title,
+		Facts: [][2]string{
+			{"Retention", fmt.Sprintf("%d days", warning.Days)},
+			{"Starting", warning.DeleteFrom.In(loc).Format("January 2, 2006 15:04 MST")},
+		},
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/ocr"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+)
+
+var (
+	// ErrSetupCompleted is returned once the instance has been set up
+	ErrSetupCompleted = apperrors.Conflict("SETUP_001", "setup has already been completed")
+	// ErrInvalidServiceURL is returned for a malformed OCR service URL
+	ErrInvalidServiceURL = apperrors.Validation("VAL_012", "OCR service URL must be an absolute http or https URL")
+)
+
+// setupLockName identifies the advisory lock serializing setup attempts
+const setupLockName = "visekai_setup"
+
+// SetupService runs the first-run setup wizard
+type SetupService struct {
+	settingsRepo *repository.InstanceSettingsRepository
+	userRepo     *repository.UserRepository
+	transactor   *repository.Transactor
+	authService  *AuthService
+	ocrClient    *ocr.Client
+}
+
+// NewSetupService creates a new setup service
+func NewSetupService(
+	settingsRepo *repository.InstanceSettingsRepository,
+	userRepo *repository.UserRepository,
+	transactor *repository.Transactor,
+	authService *AuthService,
+	ocrClient *ocr.Client,
+) *SetupService {
+	return &SetupService{
+		settingsRepo: settingsRepo,
+		userRepo:     userRepo,
+		transactor:   transactor,
+		authService:  authService,
+		ocrClient:    ocrClient,
+	}
+}
+
+// Status reports whether setup is still required.
+// Setup is only offered while the users table is empty and it has never been completed.
+func (s *SetupService) Status(ctx context.Context) (*models.SetupStatus, error) {
+	settings, err := s.settingsRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := s.setupRequired(ctx, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SetupStatus{
+		SetupRequired: required,
+		InstanceName:  settings[models.SettingInstanceName],
+	}, nil
+}
+
+// Complete creates the initial admin account and stores the instance settings.
+// Concurrent attempts are serialized so exactly one of them can succeed.
+func (s *SetupService) Complete(ctx context.Context, req models.SetupRequest) (*models.User, error) {
+	ocrURL, err := normalizeServiceURL(req.OCRServiceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var admin *models.User
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.settingsRepo.Lock(ctx, setupLockName); err != nil {
+			return err
+		}
+
+		settings, err := s.settingsRepo.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		required, err := s.setupRequired(ctx, settings)
+		if err != nil {
+			return err
+		}
+		if !required {
+			return ErrSetupCompleted
+		}
+
+		admin, err = s.authService.CreateUser(ctx, models.UserRegistration{
+			Email:    req.AdminEmail,
+			Password: req.AdminPassword,
+			Name:     req.AdminName,
+		}, models.UserRoleAdmin)
+		if err != nil {
+			return err
+		}
+
+		values := map[string]string{
+			models.SettingInstanceName:     strings.TrimSpace(req.InstanceName),
+			models.SettingStorageBackend:   req.StorageBackend,
+			models.SettingOCRServiceURL:    ocrURL,
+			models.SettingSetupCompletedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		for key, value := range values {
+			if err := s.settingsRepo.Set(ctx, key, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.ocrClient.SetBaseURL(ocrURL)
+	logger.Info("Instance setup completed", "admin_id", admin.ID, "instance_name", req.InstanceName)
+
+	return admin, nil
+}
+
+// ApplyStoredSettings applies settings saved by the setup wizard at startup
+func (s *SetupService) ApplyStoredSettings(ctx context.Context) error {
+	ocrURL, ok, err := s.settingsRepo.Get(ctx, models.SettingOCRServiceURL)
+	if err != nil {
+		return err
+	}
+	if ok {
+		s.ocrClient.SetBaseURL(ocrURL)
+	}
+	return nil
+}
+
+// setupRequired reports whether setup has neither been completed nor been made redundant by existing users
+func (s *SetupService) setupRequired(ctx context.Context, settings map[string]string) (bool, error) {
+	if _, completed := settings[models.SettingSetupCompletedAt]; completed {
+		return false, nil
+	}
+
+	count, err := s.userRepo.Count(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return count == 0, nil
+}
+
+// normalizeServiceURL validates an absolute http(s) URL and strips any trailing slash
+func normalizeServiceURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", ErrInvalidServiceURL
+	}
+	return strings.TrimRight(parsed.String(), "/"), nil
+}
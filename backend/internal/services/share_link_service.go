@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// defaultShareLinkTTL is how long share links last unless asked otherwise
+const defaultShareLinkTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrShareLinkInvalid is returned for share links whose signature does not
+	// match or that expired
+	ErrShareLinkInvalid = apperrors.Forbidden("SHARE_001", "share link is invalid or has expired")
+	// ErrShareLinkRevoked is returned for share links the owner revoked
+	ErrShareLinkRevoked = apperrors.Forbidden("SHARE_002", "share link was revoked")
+)
+
+// ShareLinkService shares documents through signed, expiring URLs. The URL
+// names the link and its expiry and carries an HMAC of both, so it cannot be
+// forged or extended; revoked links stop working even before they expire.
+type ShareLinkService struct {
+	linkRepo        *repository.ShareLinkRepository
+	documentRepo    *repository.DocumentRepository
+	resultRepo      *repository.ResultRepository
+	brandingService *BrandingService
+	storage         *storage.Storage
+	secret          []byte
+	maxTTL          time.Duration
+}
+
+// NewShareLinkService creates a new share link service signing links with
+// secret. Links may last up to maxTTL.
+func NewShareLinkService(
+	linkRepo *repository.ShareLinkRepository,
+	documentRepo *repository.DocumentRepository,
+	resultRepo *repository.ResultRepository,
+	brandingService *BrandingService,
+	storage *storage.Storage,
+	secret string,
+	maxTTL time.Duration,
+) *ShareLinkService {
+	if maxTTL <= 0 {
+		maxTTL = 30 * 24 * time.Hour
+	}
+	return &ShareLinkService{
+		linkRepo:        linkRepo,
+		documentRepo:    documentRepo,
+		resultRepo:      resultRepo,
+		brandingService: brandingService,
+		storage:         storage,
+		secret:          []byte(secret),
+		maxTTL:          maxTTL,
+	}
+}
+
+// Create shares one of the user's documents and returns the link with its
+// signed URL. The URL points at origin, the scheme and host the request was
+// made to, or at the verified custom domain of the document's branding when
+// there is one.
+func (s *ShareLinkService) Create(ctx context.Context, userID, documentID uuid.UUID, req models.ShareLinkCreateRequest, origin string) (*models.ShareLink, error) {
+	document, err := s.ownedDocument(ctx, userID, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := defaultShareLinkTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if ttl > s.maxTTL {
+		return nil, apperrors.Validation("VAL_034", fmt.Sprintf("share links may last at most %d hours", int(s.maxTTL.Hours())))
+	}
+
+	link := &models.ShareLink{
+		UserID:     userID,
+		DocumentID: documentID,
+		// Expiry is signed in whole seconds
+		ExpiresAt: time.Now().UTC().Add(ttl).Truncate(time.Second),
+	}
+	if err := s.linkRepo.Create(ctx, link); err != nil {
+		return nil, err
+	}
+
+	branding, err := s.brandingService.ForDocument(ctx, document, "")
+	if err != nil {
+		logger.Warn("Failed to get branding for share link", "error", err)
+	} else if branding.Domain != "" {
+		origin = "https://" + branding.Domain
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(link.ExpiresAt.Unix(), 10))
+	query.Set("signature", s.sign(link.ID, link.ExpiresAt.Unix()))
+	link.URL = strings.TrimSuffix(origin, "/") + "/api/v1/shared/" + link.ID.String() + "?" + query.Encode()
+
+	logger.Info("Document shared", "document_id", documentID, "user_id", userID, "link_id", link.ID, "expires_at", link.ExpiresAt)
+	return link, nil
+}
+
+// List returns the share links of one of the user's documents
+func (s *ShareLinkService) List(ctx context.Context, userID, documentID uuid.UUID) ([]*models.ShareLink, error) {
+	if _, err := s.ownedDocument(ctx, userID, documentID); err != nil {
+		return nil, err
+	}
+
+	return s.linkRepo.ListByDocument(ctx, documentID)
+}
+
+// Revoke ends a share link of one of the user's documents before it expires
+func (s *ShareLinkService) Revoke(ctx context.Context, userID, documentID, linkID uuid.UUID) error {
+	if _, err := s.ownedDocument(ctx, userID, documentID); err != nil {
+		return err
+	}
+
+	return s.linkRepo.Revoke(ctx, linkID, documentID)
+}
+
+// Open returns what a share link shows: the document, its latest result and
+// the document's branding, as opened on host. Each use is counted.
+func (s *ShareLinkService) Open(ctx context.Context, linkID uuid.UUID, expires, signature, host string) (*models.SharedDocument, error) {
+	link, document, err := s.resolve(ctx, linkID, expires, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := &models.SharedDocument{
+		ID:         document.ID,
+		Filename:   document.OriginalFilename,
+		MimeType:   document.MimeType,
+		FileSize:   document.FileSize,
+		NumPages:   document.NumPages,
+		UploadedAt: document.UploadedAt,
+		ExpiresAt:  link.ExpiresAt,
+	}
+
+	results, err := s.resultRepo.GetByDocumentID(ctx, document.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		shared.Result = results[0]
+	}
+
+	shared.Branding, err = s.brandingService.ForDocument(ctx, document, host)
+	if err != nil {
+		logger.Warn("Failed to get branding for shared document", "error", err)
+	}
+
+	s.recordAccess(ctx, link.ID)
+	return shared, nil
+}
+
+// OpenFile opens the original file of a shared document
+func (s *ShareLinkService) OpenFile(ctx context.Context, linkID uuid.UUID, expires, signature string) (*models.Document, io.ReadCloser, error) {
+	link, document, err := s.resolve(ctx, linkID, expires, signature)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := s.storage.Open(document.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.recordAccess(ctx, link.ID)
+	return document, file, nil
+}
+
+// resolve verifies a share link's URL parameters and returns the link and the
+// document it shares. The signature is checked before the database is touched,
+// so forged links cost nothing.
+func (s *ShareLinkService) resolve(ctx context.Context, linkID uuid.UUID, expires, signature string) (*models.ShareLink, *models.Document, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(s.sign(linkID, expiresAt)), []byte(signature)) {
+		return nil, nil, ErrShareLinkInvalid
+	}
+	if !time.Now().Before(time.Unix(expiresAt, 0)) {
+		return nil, nil, ErrShareLinkInvalid
+	}
+
+	link, err := s.linkRepo.GetByID(ctx, linkID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if link.ExpiresAt.Unix() != expiresAt {
+		return nil, nil, ErrShareLinkInvalid
+	}
+	if link.RevokedAt != nil {
+		return nil, nil, ErrShareLinkRevoked
+	}
+
+	document, err := s.documentRepo.GetByID(ctx, link.DocumentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return link, document, nil
+}
+
+// recordAccess counts a use of a share link; failures only lose the count
+func (s *ShareLinkService) recordAccess(ctx context.Context, linkID uuid.UUID) {
+	if err := s.linkRepo.RecordAccess(ctx, linkID); err != nil {
+		logger.Warn("Failed to record share link access", "link_id", linkID, "error", err)
+	}
+}
+
+// sign returns the hex-encoded HMAC-SHA256 of a link's ID and expiry
+func (s *ShareLinkService) sign(linkID uuid.UUID, expiresAt int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(linkID.String()))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(expiresAt, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ownedDocument returns one of the user's active documents
+func (s *ShareLinkService) ownedDocument(ctx context.Context, userID, documentID uuid.UUID) (*models.Document, error) {
+	document, err := s.documentRepo.GetByID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.UserID != userID {
+		return nil, apperrors.Forbidden("AUTH_004", "document does not belong to user")
+	}
+	return document, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// SharedUploadService lets members upload a file a teammate already uploaded
+// without storing or processing it again, when the OCR policy of an
+// organization they share allows it. The new document points at the teammate's stored file and gets a
+// copy of the teammate's latest result, so ownership, metadata and later
+// changes stay separate while the file is stored and processed once.
+type SharedUploadService struct {
+	documentRepo  *repository.DocumentRepository
+	jobRepo       *repository.JobRepository
+	resultRepo    *repository.ResultRepository
+	regionRepo    *repository.RegionRepository
+	userRepo      *repository.UserRepository
+	eventService  *EventService
+	policyService *OCRPolicyService
+}
+
+// NewSharedUploadService creates a new shared upload service
+func NewSharedUploadService(
+	documentRepo *repository.DocumentRepository,
+	jobRepo *repository.JobRepository,
+	resultRepo *repository.ResultRepository,
+	regionRepo *repository.RegionRepository,
+	userRepo *repository.UserRepository,
+	eventService *EventService,
+	policyService *OCRPolicyService,
+) *SharedUploadService {
+	return &SharedUploadService{
+		documentRepo:  documentRepo,
+		jobRepo:       jobRepo,
+		resultRepo:    resultRepo,
+		regionRepo:    regionRepo,
+		userRepo:      userRepo,
+		eventService:  eventService,
+		policyService: policyService,
+	}
+}
+
+// FindTeammateUpload returns the live document with the given file hash of a
+// teammate in one of the user's organizations sharing duplicate uploads, or
+// nil when none of them does or no teammate there uploaded the file
+func (s *SharedUploadService) FindTeammateUpload(ctx context.Context, fileHash string, userID uuid.UUID) (*models.Document, error) {
+	orgIDs, err := s.policyService.SharingOrgIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.documentRepo.GetByHashFromOthers(ctx, fileHash, userID, orgIDs)
+}
+
+// Share points document at the stored file of the teammate's upload and records
+// where it came from. Call it before creating document, then ShareResult in the
+// same transaction once it exists.
+func (s *SharedUploadService) Share(document, source *models.Document) {
+	document.Filename = source.Filename
+	document.FilePath = source.FilePath
+	document.ThumbnailPath = source.ThumbnailPath
+	document.NumPages = source.NumPages
+
+	if document.Metadata == nil {
+		document.Metadata = map[string]any{}
+	}
+	document.Metadata[models.DocumentMetaSharedFromDocumentID] = source.ID.String()
+	document.Metadata[models.DocumentMetaSharedFromUserID] = source.UserID.String()
+}
+
+// ShareResult copies the latest result of the teammate's upload to document,
+// under a completed job owned by the document's owner, and returns who the
+// upload was shared with. It must run in the transaction creating document:
+// the source document is locked so it cannot be purged along with the stored
+// file meanwhile.
+func (s *SharedUploadService) ShareResult(ctx context.Context, document, source *models.Document) (*models.SharedUploadInfo, error) {
+	if err := s.documentRepo.LockForShare(ctx, source.ID); err != nil {
+		return nil, fmt.Errorf("shared document is no longer available: %w", err)
+	}
+
+	info := &models.SharedUploadInfo{
+		DocumentID: source.ID,
+		UserID:     source.UserID,
+	}
+	if owner, err := s.userRepo.GetByID(ctx, source.UserID); err == nil {
+		info.UserName = owner.Name
+	}
+
+	results, err := s.resultRepo.GetByDocumentID(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		// The teammate has not processed the file yet; the member submits a job as usual
+		return info, nil
+	}
+	sourceResult := results[0]
+
+	sourceJob, err := s.jobRepo.GetByID(ctx, sourceResult.JobID)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.OCRJob{
+		DocumentID:     document.ID,
+		UserID:         document.UserID,
+		OCRMode:        sourceJob.OCRMode,
+		ResolutionMode: sourceJob.ResolutionMode,
+		Metadata: map[string]any{
+			models.JobMetaSharedFromResultID: sourceResult.ID.String(),
+		},
+		Labels: document.Labels,
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	for _, status := range []models.JobStatus{models.JobStatusProcessing, models.JobStatusCompleted} {
+		if err := s.jobRepo.UpdateStatus(ctx, job.ID, status, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &models.OCRResult{
+		JobID:            job.ID,
+		DocumentID:       document.ID,
+		RawText:          sourceResult.RawText,
+		MarkdownText:     sourceResult.MarkdownText,
+		JSONData:         sourceResult.JSONData,
+		ConfidenceScore:  sourceResult.ConfidenceScore,
+		ProcessingTimeMs: sourceResult.ProcessingTimeMs,
+		NumPages:         sourceResult.NumPages,
+	}
+	if err := s.resultRepo.Create(ctx, result); err != nil {
+		return nil, err
+	}
+
+	// Copy the current page versions; earlier versions stay with the source, and
+	// so does what processing them cost
+	pages, err := s.resultRepo.ListCurrentPages(ctx, sourceResult.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		page.ResultID = result.ID
+		page.Version = 1
+		page.JobID = &job.ID
+		page.ProcessingTimeMs = nil
+		page.Backend = nil
+		page.Credits = nil
+	}
+	if err := s.resultRepo.CreatePages(ctx, pages); err != nil {
+		return nil, err
+	}
+
+	regions, err := s.regionRepo.ListByResult(ctx, sourceResult.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, region := range regions {
+		region.ResultID = result.ID
+	}
+	if err := s.regionRepo.CreateBatch(ctx, regions); err != nil {
+		return nil, err
+	}
+
+	info.ResultID = &result.ID
+	info.JobID = &job.ID
+
+	err = s.eventService.Record(ctx,
+		models.NewDomainEvent(
+			models.EventResultCreated, "result", result.ID, &document.UserID,
+			map[string]any{
+				"job_id":                job.ID,
+				"document_id":           document.ID,
+				"confidence_score":      result.ConfidenceScore,
+				"processing_time_ms":    result.ProcessingTimeMs,
+				"num_pages":             result.NumPages,
+				"shared_from_result_id": sourceResult.ID,
+			},
+		),
+		models.NewDomainEvent(
+			models.EventJobCompleted, "job", job.ID, &document.UserID,
+			map[string]any{
+				"document_id":     document.ID,
+				"result_id":       result.ID,
+				"ocr_mode":        job.OCRMode,
+				"resolution_mode": job.ResolutionMode,
+			},
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,547 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/delivery"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/sso"
+	"visekai/backend/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// ssoStateTTL is how long a user has to sign in at their IdP
+	ssoStateTTL = 10 * time.Minute
+	// ssoStateAudience keeps other tokens signed with the same secret from
+	// passing for sign-in states; ValidateToken refuses tokens with an
+	// audience, so states do not pass for access tokens either
+	ssoStateAudience = "sso_state"
+)
+
+var (
+	// ErrSSOSignInInvalid is returned when the callback's state or ID token
+	// does not check out
+	ErrSSOSignInInvalid = apperrors.Forbidden("SSO_002", "single sign-on attempt is invalid or has expired")
+	// ErrSSONoRole is returned when none of the user's IdP groups maps to a
+	// role and the organization has no default role
+	ErrSSONoRole = apperrors.Forbidden("SSO_003", "your identity provider groups do not grant access to this organization")
+	// ErrSSOAccountExists is returned when the email of a first SSO sign-in
+	// belongs to an account outside the organization
+	ErrSSOAccountExists = apperrors.Conflict("SSO_004", "an account with this email already exists; ask an organization owner to invite it before signing in with SSO")
+	// ErrSSOEmailDomain is returned when the IdP's user has no email in the
+	// organization's verified domains
+	ErrSSOEmailDomain = apperrors.Forbidden("SSO_005", "your email is not in a verified domain of this organization")
+	// ErrSSODomainClaimed is returned for an email domain another
+	// organization verified
+	ErrSSODomainClaimed = repository.ErrSSODomainClaimed
+	// ErrSSODisabled is returned when there is no key to seal client secrets with
+	ErrSSODisabled = apperrors.Conflict("SSO_007", "single sign-on is disabled; set SSO_ENCRYPTION_KEY to enable it")
+	// ErrSSOSecretRequired is returned when configuring SSO for the first time
+	// without a client secret
+	ErrSSOSecretRequired = apperrors.Validation("VAL_038", "client_secret is required to configure SSO")
+)
+
+// ssoState is the signed state carried through the IdP's sign-in page
+type ssoState struct {
+	OrgID       uuid.UUID `json:"org_id"`
+	Nonce       string    `json:"nonce"`
+	RedirectURL string    `json:"redirect_url"`
+	jwt.RegisteredClaims
+}
+
+// SSOService signs users in through their organization's OpenID Connect
+// identity provider. Users signing in for the first time get an account and
+// join the organization; on every sign-in their role follows the IdP groups
+// the organization maps to roles.
+type SSOService struct {
+	ssoRepo     *repository.SSORepository
+	orgRepo     *repository.OrganizationRepository
+	userRepo    *repository.UserRepository
+	transactor  *repository.Transactor
+	authService *AuthService
+	oidc        *sso.OIDC
+	sealer      *delivery.Sealer
+	resolver    *net.Resolver
+	stateSecret string
+	callbackURL string
+}
+
+// NewSSOService creates a new SSO service. Client secrets are stored sealed
+// with sealer; SSO is disabled when it is nil. The IdP redirects back to
+// callbackURL, or to the callback of the API the sign-in started from when
+// it is empty.
+func NewSSOService(
+	ssoRepo *repository.SSORepository,
+	orgRepo *repository.OrganizationRepository,
+	userRepo *repository.UserRepository,
+	transactor *repository.Transactor,
+	authService *AuthService,
+	oidc *sso.OIDC,
+	sealer *delivery.Sealer,
+	stateSecret string,
+	callbackURL string,
+) *SSOService {
+	return &SSOService{
+		ssoRepo:     ssoRepo,
+		orgRepo:     orgRepo,
+		userRepo:    userRepo,
+		transactor:  transactor,
+		authService: authService,
+		oidc:        oidc,
+		sealer:      sealer,
+		resolver:    net.DefaultResolver,
+		stateSecret: stateSecret,
+		callbackURL: callbackURL,
+	}
+}
+
+// SealSecrets seals the client secrets stored in plaintext before secrets
+// were sealed
+func (s *SSOService) SealSecrets(ctx context.Context) error {
+	configs, err := s.ssoRepo.ListUnsealed(ctx)
+	if err != nil || len(configs) == 0 {
+		return err
+	}
+	if s.sealer == nil {
+		logger.Warn("SSO client secrets are stored in plaintext; set SSO_ENCRYPTION_KEY to seal them", "count", len(configs))
+		return nil
+	}
+
+	for _, config := range configs {
+		sealed, err := s.sealer.Seal([]byte(config.ClientSecret), config.OrganizationID[:])
+		if err != nil {
+			return err
+		}
+		if err := s.ssoRepo.SealSecret(ctx, config.OrganizationID, sealed); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Sealed SSO client secrets", "count", len(configs))
+	return nil
+}
+
+// GetConfig returns the SSO configuration of an organization the user owns
+func (s *SSOService) GetConfig(ctx context.Context, orgID, userID uuid.UUID) (*models.OrgSSOConfig, error) {
+	if err := s.requireOwner(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.ssoRepo.GetConfig(ctx, orgID)
+}
+
+// Configure creates or replaces the SSO configuration of an organization the
+// user owns
+func (s *SSOService) Configure(ctx context.Context, orgID, userID uuid.UUID, req models.OrgSSORequest) (*models.OrgSSOConfig, error) {
+	if err := s.requireOwner(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	if s.sealer == nil {
+		return nil, ErrSSODisabled
+	}
+
+	config := &models.OrgSSOConfig{
+		OrganizationID: orgID,
+		Issuer:         strings.TrimSuffix(req.Issuer, "/"),
+		ClientID:       req.ClientID,
+		ClientSecret:   req.ClientSecret,
+		EmailDomains:   make([]models.SSODomain, 0, len(req.EmailDomains)),
+		GroupClaim:     req.GroupClaim,
+		RoleMapping:    req.RoleMapping,
+		DefaultRole:    req.DefaultRole,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+	}
+	if config.GroupClaim == "" {
+		config.GroupClaim = "groups"
+	}
+	if config.RoleMapping == nil {
+		config.RoleMapping = map[string]models.OrgRole{}
+	}
+
+	existing, err := s.getConfig(ctx, orgID)
+	if err != nil && !errors.Is(err, repository.ErrSSONotConfigured) {
+		return nil, err
+	}
+	if config.ClientSecret == "" {
+		if existing == nil {
+			return nil, ErrSSOSecretRequired
+		}
+		config.ClientSecret = existing.ClientSecret
+	}
+
+	// Kept domains keep their verification; new ones must be verified
+	for _, name := range req.EmailDomains {
+		name = strings.TrimSuffix(strings.ToLower(name), ".")
+		if findDomain(config, name) != nil {
+			continue
+		}
+		owner, err := s.ssoRepo.GetConfigByDomain(ctx, name)
+		if err != nil && !errors.Is(err, repository.ErrSSONotConfigured) {
+			return nil, err
+		}
+		if owner != nil && owner.OrganizationID != orgID {
+			return nil, ErrSSODomainClaimed
+		}
+		if existing != nil {
+			if domain := findDomain(existing, name); domain != nil {
+				config.EmailDomains = append(config.EmailDomains, *domain)
+				continue
+			}
+		}
+		token, err := verificationToken()
+		if err != nil {
+			return nil, err
+		}
+		config.EmailDomains = append(config.EmailDomains, models.SSODomain{
+			Domain:             name,
+			VerificationRecord: domainVerificationPrefix + name,
+			VerificationValue:  "visekai-verification=" + token,
+		})
+	}
+
+	sealed, err := s.sealer.Seal([]byte(config.ClientSecret), orgID[:])
+	if err != nil {
+		return nil, err
+	}
+	stored := *config
+	stored.ClientSecret = sealed
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		return s.ssoRepo.SaveConfig(ctx, &stored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	stored.ClientSecret = config.ClientSecret
+	config = &stored
+
+	logger.Info("Organization SSO configured",
+		"organization_id", orgID, "issuer", config.Issuer, "enabled", config.Enabled, "configured_by", userID)
+	return config, nil
+}
+
+// VerifyDomain looks up the verification TXT record of an email domain of an
+// organization the user owns, and marks the domain verified when it holds the
+// expected value. Only then do sign-ins with emails in the domain go to the
+// organization's IdP.
+func (s *SSOService) VerifyDomain(ctx context.Context, orgID, userID uuid.UUID, name string) (*models.OrgSSOConfig, error) {
+	if err := s.requireOwner(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+
+	config, err := s.ssoRepo.GetConfig(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	domain := findDomain(config, strings.TrimSuffix(strings.ToLower(name), "."))
+	if domain == nil {
+		return nil, repository.ErrSSODomainNotFound
+	}
+	if domain.Verified() {
+		return config, nil
+	}
+
+	if err := lookupVerificationRecord(ctx, s.resolver, domain.VerificationRecord, domain.VerificationValue); err != nil {
+		return nil, err
+	}
+	if err := s.ssoRepo.VerifyDomain(ctx, orgID, domain.Domain, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Organization SSO domain verified", "organization_id", orgID, "domain", domain.Domain, "verified_by", userID)
+	return s.ssoRepo.GetConfig(ctx, orgID)
+}
+
+// DeleteConfig removes the SSO configuration of an organization the user
+// owns. Users who signed in with it keep their accounts and memberships.
+func (s *SSOService) DeleteConfig(ctx context.Context, orgID, userID uuid.UUID) error {
+	if err := s.requireOwner(ctx, orgID, userID); err != nil {
+		return err
+	}
+	return s.ssoRepo.DeleteConfig(ctx, orgID)
+}
+
+// Begin starts a sign-in with the IdP of an organization, found by its ID or
+// else by the verified domain of the user's email. The IdP redirects back to
+// callbackURL unless one is configured. It returns where to send the user and
+// the state the callback must come back with.
+func (s *SSOService) Begin(ctx context.Context, orgID uuid.UUID, email, callbackURL string) (*models.SSOLogin, string, error) {
+	var config *models.OrgSSOConfig
+	var err error
+	if orgID != uuid.Nil {
+		config, err = s.getConfig(ctx, orgID)
+	} else {
+		_, domain, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+		config, err = s.ssoRepo.GetConfigByDomain(ctx, domain)
+		if err == nil {
+			err = s.openSecret(config)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if !config.Enabled {
+		return nil, "", repository.ErrSSONotConfigured
+	}
+
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	redirectURL := s.callbackURL
+	if redirectURL == "" {
+		redirectURL = callbackURL
+	}
+
+	now := time.Now()
+	state, err := jwt.NewWithClaims(jwt.SigningMethodHS256, ssoState{
+		OrgID:       config.OrganizationID,
+		Nonce:       nonce,
+		RedirectURL: redirectURL,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{ssoStateAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ssoStateTTL)),
+		},
+	}).SignedString([]byte(s.stateSecret))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign SSO state: %w", err)
+	}
+
+	authURL, err := s.oidc.AuthCodeURL(ctx, clientConfig(config, redirectURL), state, nonce)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to reach identity provider: %w", err)
+	}
+
+	return &models.SSOLogin{
+		OrganizationID:   config.OrganizationID,
+		AuthorizationURL: authURL,
+	}, state, nil
+}
+
+// Complete finishes a sign-in with the code the IdP redirected back with. It
+// signs the user in, creating their account on their first sign-in, and gives
+// them the role their groups map to in the organization, which it returns.
+func (s *SSOService) Complete(ctx context.Context, code, rawState string) (*models.AuthResponse, uuid.UUID, error) {
+	state := &ssoState{}
+	_, err := jwt.ParseWithClaims(rawState, state, func(token *jwt.Token) (any, error) {
+		return []byte(s.stateSecret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(ssoStateAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, uuid.Nil, ErrSSOSignInInvalid
+	}
+
+	config, err := s.getConfig(ctx, state.OrgID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	if !config.Enabled {
+		return nil, uuid.Nil, repository.ErrSSONotConfigured
+	}
+
+	claims, err := s.oidc.Exchange(ctx, clientConfig(config, state.RedirectURL), code, state.Nonce)
+	if errors.Is(err, sso.ErrInvalidIDToken) {
+		logger.Warn("Rejected SSO ID token", "organization_id", config.OrganizationID, "error", err)
+		return nil, uuid.Nil, ErrSSOSignInInvalid
+	}
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("failed to sign in with identity provider: %w", err)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	_, domain, _ := strings.Cut(email, "@")
+	if verified := findDomain(config, domain); verified == nil || !verified.Verified() {
+		return nil, uuid.Nil, ErrSSOEmailDomain
+	}
+
+	role, ok := mapOrgRole(config, claims.Groups)
+	if !ok {
+		return nil, uuid.Nil, ErrSSONoRole
+	}
+
+	var user *models.User
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		user, err = s.resolveUser(ctx, config, claims, email)
+		if err != nil {
+			return err
+		}
+		if err := s.applyRole(ctx, config.OrganizationID, user.ID, role); err != nil {
+			return err
+		}
+		return s.ssoRepo.SaveIdentity(ctx, config.Issuer, claims.Subject, user.ID, config.OrganizationID)
+	})
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	response, err := s.authService.IssueTokens(user)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	return response, config.OrganizationID, nil
+}
+
+// resolveUser returns the user an IdP subject signs in as: the one it signed
+// in as before, else the organization member with its email, else a new user.
+// Accounts outside the organization are never taken over by email.
+func (s *SSOService) resolveUser(ctx context.Context, config *models.OrgSSOConfig, claims *sso.Claims, email string) (*models.User, error) {
+	userID, err := s.ssoRepo.GetIdentityUser(ctx, config.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if userID != uuid.Nil {
+		return s.userRepo.GetByID(ctx, userID)
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err == nil {
+		if _, err := s.orgRepo.GetMemberRole(ctx, config.OrganizationID, user.ID); err != nil {
+			if errors.Is(err, repository.ErrOrgMemberNotFound) {
+				return nil, ErrSSOAccountExists
+			}
+			return nil, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	user, err = s.authService.ProvisionUser(ctx, email, name)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("User provisioned through SSO", "user_id", user.ID, "organization_id", config.OrganizationID)
+	return user, nil
+}
+
+// applyRole makes the user a member of the organization with the role, unless
+// that would demote its last owner
+func (s *SSOService) applyRole(ctx context.Context, orgID, userID uuid.UUID, role models.OrgRole) error {
+	owners, err := s.orgRepo.LockOwners(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.orgRepo.GetMemberRole(ctx, orgID, userID)
+	if errors.Is(err, repository.ErrOrgMemberNotFound) {
+		return s.orgRepo.AddMember(ctx, orgID, userID, role)
+	}
+	if err != nil || current == role {
+		return err
+	}
+	if current == models.OrgRoleOwner && owners <= 1 {
+		logger.Warn("SSO groups would demote the last organization owner; keeping owner role",
+			"organization_id", orgID, "user_id", userID, "mapped_role", role)
+		return nil
+	}
+	return s.orgRepo.UpdateMemberRole(ctx, orgID, userID, role)
+}
+
+// getConfig returns an organization's SSO configuration with its client
+// secret opened
+func (s *SSOService) getConfig(ctx context.Context, orgID uuid.UUID) (*models.OrgSSOConfig, error) {
+	config, err := s.ssoRepo.GetConfig(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.openSecret(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// openSecret replaces the stored client secret of a configuration with its
+// plaintext
+func (s *SSOService) openSecret(config *models.OrgSSOConfig) error {
+	if s.sealer == nil {
+		return ErrSSODisabled
+	}
+	if !config.ClientSecretSealed {
+		return nil
+	}
+	secret, err := s.sealer.Open(config.ClientSecret, config.OrganizationID[:])
+	if err != nil {
+		return fmt.Errorf("failed to open SSO client secret: %w", err)
+	}
+	config.ClientSecret, config.ClientSecretSealed = string(secret), false
+	return nil
+}
+
+// requireOwner checks that the user owns the organization
+func (s *SSOService) requireOwner(ctx context.Context, orgID, userID uuid.UUID) error {
+	org, err := s.orgRepo.GetForMember(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if org.Role != models.OrgRoleOwner {
+		return ErrOrgRoleForbidden
+	}
+	return nil
+}
+
+// mapOrgRole returns the highest role the groups map to, else the default role
+func mapOrgRole(config *models.OrgSSOConfig, groups []string) (models.OrgRole, bool) {
+	var role models.OrgRole
+	for _, group := range groups {
+		if mapped, ok := config.RoleMapping[group]; ok && mapped.Rank() > role.Rank() {
+			role = mapped
+		}
+	}
+	if role != "" {
+		return role, true
+	}
+	if config.DefaultRole != nil {
+		return *config.DefaultRole, true
+	}
+	return "", false
+}
+
+// clientConfig returns the OIDC client of an organization's configuration
+func clientConfig(config *models.OrgSSOConfig, redirectURL string) sso.ClientConfig {
+	return sso.ClientConfig{
+		Issuer:       config.Issuer,
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  redirectURL,
+		GroupClaim:   config.GroupClaim,
+	}
+}
+
+// findDomain returns the email domain of a configuration with the name
+func findDomain(config *models.OrgSSOConfig, name string) *models.SSODomain {
+	for i := range config.EmailDomains {
+		if strings.EqualFold(config.EmailDomains[i].Domain, name) {
+			return &config.EmailDomains[i]
+		}
+	}
+	return nil
+}
+
+// randomToken returns 32 random bytes, hex encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// StatsService computes the statistics of users' jobs
+type StatsService struct {
+	statsRepo    *repository.StatsRepository
+	settingsRepo *repository.UserSettingsRepository
+}
+
+// NewStatsService creates a new stats service
+func NewStatsService(statsRepo *repository.StatsRepository, settingsRepo *repository.UserSettingsRepository) *StatsService {
+	return &StatsService{
+		statsRepo:    statsRepo,
+		settingsRepo: settingsRepo,
+	}
+}
+
+// JobStats sums up the user's jobs that finished in [since, until), with
+// the days counted in the user's time zone
+func (s *StatsService) JobStats(ctx context.Context, userID uuid.UUID, since, until time.Time) (*models.JobStats, error) {
+	settings, err := s.settingsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	loc := settings.Location()
+
+	totals, byResolution, err := s.statsRepo.JobTotals(ctx, userID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	// until is exclusive, so a period ending at midnight ends the day before
+	last := until.Add(-time.Nanosecond)
+	if last.Before(since) {
+		last = since
+	}
+	daily, err := s.statsRepo.JobsPerDay(ctx, userID, since, until, loc.String(),
+		since.In(loc).Format(time.DateOnly), last.In(loc).Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+
+	setFailureRate(totals)
+	for _, group := range byResolution {
+		setFailureRate(&group.JobStatsTotals)
+	}
+
+	return &models.JobStats{
+		Since:          since,
+		Until:          until,
+		Timezone:       loc.String(),
+		JobStatsTotals: *totals,
+		ByResolution:   byResolution,
+		Daily:          daily,
+	}, nil
+}
+
+// setFailureRate sets the share of completed and failed jobs that failed
+func setFailureRate(totals *models.JobStatsTotals) {
+	if finished := totals.Completed + totals.Failed; finished > 0 {
+		totals.FailureRate = float64(totals.Failed) / float64(finished)
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+)
+
+// storageStatsOldestFiles is the number of oldest files the stats list
+const storageStatsOldestFiles = 10
+
+// StorageStatsService keeps the storage used by each user and the instance
+// up to date for the admin storage dashboard, recomputing it on every
+// interval: the users' documents are summed in the database, and the document
+// files on disk are walked to find the bytes actually stored, the oldest
+// files and the orphans no document refers to
+type StorageStatsService struct {
+	statsRepo    *repository.StorageStatsRepository
+	documentRepo *repository.DocumentRepository
+	storage      *storage.Storage
+	interval     time.Duration
+	mu           sync.Mutex
+}
+
+// NewStorageStatsService creates a new storage stats service recomputing the
+// stats every interval
+func NewStorageStatsService(
+	statsRepo *repository.StorageStatsRepository,
+	documentRepo *repository.DocumentRepository,
+	storage *storage.Storage,
+	interval time.Duration,
+) *StorageStatsService {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &StorageStatsService{
+		statsRepo:    statsRepo,
+		documentRepo: documentRepo,
+		storage:      storage,
+		interval:     interval,
+	}
+}
+
+// Start recomputes the stats at start-up and then on every interval, until
+// the context is cancelled
+func (s *StorageStatsService) Start(ctx context.Context) {
+	logger.Info("Storage stats started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		_ = tasks.Run(ctx, tasks.Task{
+			Name: "storage_stats.refresh",
+			Run: func(ctx context.Context) error {
+				_, err := s.Refresh(ctx)
+				return err
+			},
+		})
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Storage stats stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get returns the instance's stats and a page of the users using the most
+// storage, computing the stats first when they never were
+func (s *StorageStatsService) Get(ctx context.Context, page, perPage int) (*models.StorageStats, []*models.UserStorageStats, int, error) {
+	stats, err := s.statsRepo.GetSummary(ctx)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if stats == nil {
+		if stats, err = s.Refresh(ctx); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+
+	users, total, err := s.statsRepo.ListUsers(ctx, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return stats, users, total, nil
+}
+
+// Refresh recomputes and stores the stats
+func (s *StorageStatsService) Refresh(ctx context.Context) (*models.StorageStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	started := time.Now()
+	stats := &models.StorageStats{
+		OldestFiles: []models.StoredFile{},
+		ComputedAt:  started.UTC(),
+	}
+
+	if err := s.statsRepo.RefreshUsers(ctx, stats.ComputedAt); err != nil {
+		return nil, err
+	}
+	documents, bytes, err := s.statsRepo.DocumentTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.Totals.Documents, stats.Totals.DocumentBytes = documents, bytes
+
+	if err := s.scanFiles(ctx, stats); err != nil {
+		return nil, err
+	}
+
+	stats.DurationMs = time.Since(started).Milliseconds()
+	if err := s.statsRepo.SaveSummary(ctx, stats); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Storage stats refreshed",
+		"documents", stats.Totals.Documents,
+		"files", stats.Totals.Files,
+		"bytes", stats.Totals.Bytes,
+		"orphan_files", stats.Totals.OrphanFiles,
+		"duration_ms", stats.DurationMs,
+	)
+
+	return stats, nil
+}
+
+// scanFiles walks the document files on disk, summing them, keeping the
+// oldest and counting those no document refers to
+func (s *StorageStatsService) scanFiles(ctx context.Context, stats *models.StorageStats) error {
+	root := s.storage.GetFilePath("")
+	batch := make(map[string]int64, orphanCheckBatchSize)
+
+	countOrphans := func() error {
+		paths := make([]string, 0, len(batch))
+		for path := range batch {
+			paths = append(paths, path)
+		}
+		referenced, err := s.documentRepo.ReferencedPaths(ctx, paths)
+		if err != nil {
+			return err
+		}
+		for path, size := range batch {
+			if !referenced[path] {
+				stats.Totals.OrphanFiles++
+				stats.Totals.OrphanBytes += size
+			}
+		}
+		clear(batch)
+		return nil
+	}
+
+	err := s.storage.WalkFiles(storage.DocumentsDir, func(path string, info fs.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		stats.Totals.Files++
+		stats.Totals.Bytes += info.Size()
+
+		if rel, err := filepath.Rel(root, path); err == nil {
+			stats.OldestFiles = keepOldest(stats.OldestFiles, models.StoredFile{
+				Path:       rel,
+				Size:       info.Size(),
+				ModifiedAt: info.ModTime().UTC(),
+			})
+		}
+
+		batch[path] = info.Size()
+		if len(batch) < orphanCheckBatchSize {
+			return nil
+		}
+		return countOrphans()
+	})
+	if err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return countOrphans()
+	}
+	return nil
+}
+
+// keepOldest adds file to files, oldest first, keeping at most
+// storageStatsOldestFiles of them
+func keepOldest(files []models.StoredFile, file models.StoredFile) []models.StoredFile {
+	i := sort.Search(len(files), func(i int) bool {
+		return files[i].ModifiedAt.After(file.ModifiedAt)
+	})
+	if i >= storageStatsOldestFiles {
+		return files
+	}
+
+	files = append(files, models.StoredFile{})
+	copy(files[i+1:], files[i:])
+	files[i] = file
+	if len(files) > storageStatsOldestFiles {
+		files = files[:storageStatsOldestFiles]
+	}
+	return files
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+)
+
+// SystemStatsService computes the instance-wide statistics of the admin
+// dashboard: job throughput, queue wait and OCR latency percentiles, active
+// users, and the error responses of the answering instance by code
+type SystemStatsService struct {
+	statsRepo    *repository.StatsRepository
+	queueService *QueueAdminService
+	requestStats *RequestStats
+}
+
+// NewSystemStatsService creates a new system stats service
+func NewSystemStatsService(statsRepo *repository.StatsRepository, queueService *QueueAdminService, requestStats *RequestStats) *SystemStatsService {
+	return &SystemStatsService{
+		statsRepo:    statsRepo,
+		queueService: queueService,
+		requestStats: requestStats,
+	}
+}
+
+// Get returns the statistics of the window ending now
+func (s *SystemStatsService) Get(ctx context.Context, window time.Duration) (*models.SystemStats, error) {
+	until := time.Now().UTC()
+	since := until.Add(-window)
+
+	throughput, err := s.statsRepo.Throughput(ctx, since, until)
+	if err != nil {
+		return nil, err
+	}
+	minutes := window.Minutes()
+	throughput.JobsPerMinute = float64(throughput.Completed+throughput.Failed+throughput.Cancelled) / minutes
+	throughput.PagesPerMinute = float64(throughput.Pages) / minutes
+	if finished := throughput.Completed + throughput.Failed; finished > 0 {
+		throughput.FailureRate = float64(throughput.Failed) / float64(finished)
+	}
+
+	queueWait, err := s.statsRepo.QueueWait(ctx, since, until)
+	if err != nil {
+		return nil, err
+	}
+	ocrLatency, err := s.statsRepo.OCRLatency(ctx, since, until)
+	if err != nil {
+		return nil, err
+	}
+	activeUsers, err := s.statsRepo.ActiveUsers(ctx, since, until)
+	if err != nil {
+		return nil, err
+	}
+	queue, err := s.queueService.QueueStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SystemStats{
+		Since:       since,
+		Until:       until,
+		Throughput:  *throughput,
+		QueueWait:   *queueWait,
+		OCRLatency:  *ocrLatency,
+		Queue:       queue,
+		API:         s.apiErrors(since),
+		ActiveUsers: activeUsers,
+	}, nil
+}
+
+// apiErrors sums the requests this instance answered since a time, with its
+// error codes from the most frequent
+func (s *SystemStatsService) apiErrors(since time.Time) models.APIErrorStats {
+	requests, byCode, counted := s.requestStats.Since(since)
+
+	stats := models.APIErrorStats{
+		Since:    counted,
+		Requests: requests,
+		ByCode:   []*models.ErrorCodeCount{},
+	}
+	for code, count := range byCode {
+		stats.Errors += count
+		stats.ByCode = append(stats.ByCode, &models.ErrorCodeCount{Code: code, Count: count})
+	}
+	if requests > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(requests)
+		for _, code := range stats.ByCode {
+			code.Rate = float64(code.Count) / float64(requests)
+		}
+	}
+	sort.Slice(stats.ByCode, func(i, j int) bool {
+		if stats.ByCode[i].Count != stats.ByCode[j].Count {
+			return stats.ByCode[i].Count > stats.ByCode[j].Count
+		}
+		return stats.ByCode[i].Code < stats.ByCode[j].Code
+	})
+
+	return stats
+}
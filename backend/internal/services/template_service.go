@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// TemplateService manages users' extraction templates and applies them to
+// results as jobs complete
+type TemplateService struct {
+	templateRepo *repository.TemplateRepository
+}
+
+// NewTemplateService creates a new template service
+func NewTemplateService(templateRepo *repository.TemplateRepository) *TemplateService {
+	return &TemplateService{templateRepo: templateRepo}
+}
+
+// Create adds an extraction template for the user
+func (s *TemplateService) Create(ctx context.Context, userID uuid.UUID, req models.TemplateRequest) (*models.ExtractionTemplate, error) {
+	if err := validateTemplateFields(req.Fields); err != nil {
+		return nil, err
+	}
+
+	template := &models.ExtractionTemplate{
+		UserID:       userID,
+		Name:         req.Name,
+		DocumentType: req.DocumentType,
+		Fields:       req.Fields,
+	}
+	if err := s.templateRepo.Create(ctx, template); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Extraction template created", "template_id", template.ID, "user_id", userID)
+	return template, nil
+}
+
+// List returns the user's extraction templates, newest first
+func (s *TemplateService) List(ctx context.Context, userID uuid.UUID) ([]*models.ExtractionTemplate, error) {
+	return s.templateRepo.ListByUser(ctx, userID)
+}
+
+// Get returns one of the user's extraction templates
+func (s *TemplateService) Get(ctx context.Context, id, userID uuid.UUID) (*models.ExtractionTemplate, error) {
+	template, err := s.templateRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.UserID != userID {
+		return nil, repository.ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+// Update replaces one of the user's extraction templates. Results already
+// extracted keep their fields until their document is processed again.
+func (s *TemplateService) Update(ctx context.Context, id, userID uuid.UUID, req models.TemplateRequest) (*models.ExtractionTemplate, error) {
+	template, err := s.Get(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateTemplateFields(req.Fields); err != nil {
+		return nil, err
+	}
+
+	template.Name = req.Name
+	template.DocumentType = req.DocumentType
+	template.Fields = req.Fields
+	if err := s.templateRepo.Update(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// Delete deletes one of the user's extraction templates
+func (s *TemplateService) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.Get(ctx, id, userID); err != nil {
+		return err
+	}
+
+	return s.templateRepo.Delete(ctx, id)
+}
+
+// Annotate extracts fields from a result with the user's template that finds
+// the most of them, among those for the document's type and those for any
+// type, and stores them in the result's JSON data. It reports whether the
+// JSON data changed. Failing to load templates is logged and changes nothing.
+func (s *TemplateService) Annotate(ctx context.Context, userID uuid.UUID, documentType models.DocumentType, result *models.OCRResult, regions []*models.ResultRegion) bool {
+	templates, err := s.templateRepo.ListByUser(ctx, userID)
+	if err != nil {
+		logger.Warn("Failed to load extraction templates", "user_id", userID, "result_id", result.ID, "error", err)
+		return false
+	}
+
+	var best *models.ResultFields
+	bestSpecific := false
+	for _, template := range templates {
+		specific := template.DocumentType != ""
+		if specific && template.DocumentType != documentType {
+			continue
+		}
+
+		fields := applyTemplate(template, result.RawText, regions)
+		// Prefer more fields, then templates for the type, then newer ones
+		if best == nil || len(fields.Fields) > len(best.Fields) ||
+			(len(fields.Fields) == len(best.Fields) && specific && !bestSpecific) {
+			best, bestSpecific = fields, specific
+		}
+	}
+
+	if best == nil || len(best.Fields) == 0 {
+		if _, ok := result.JSONData[models.ResultJSONFields]; !ok {
+			return false
+		}
+		delete(result.JSONData, models.ResultJSONFields)
+		return true
+	}
+
+	if result.JSONData == nil {
+		result.JSONData = map[string]any{}
+	}
+	result.JSONData[models.ResultJSONFields] = best
+	logger.Debug("Extracted template fields",
+		"result_id", result.ID, "template_id", best.TemplateID, "fields", len(best.Fields), "missing", len(best.Missing))
+	return true
+}
+
+// validateTemplateFields checks what the request's validation tags cannot:
+// that field names are unique, each field has a rule, patterns compile and
+// zones are boxes
+func validateTemplateFields(fields []models.TemplateField) error {
+	names := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if names[field.Name] {
+			return apperrors.Validation("VAL_048", fmt.Sprintf("field %q is defined twice", field.Name))
+		}
+		names[field.Name] = true
+
+		if field.Zone == nil && field.Anchor == "" && field.Pattern == "" {
+			return apperrors.Validation("VAL_048", fmt.Sprintf("field %q needs a zone, an anchor or a pattern", field.Name))
+		}
+		if field.Pattern != "" {
+			if _, err := regexp.Compile(field.Pattern); err != nil {
+				return apperrors.Validation("VAL_048", fmt.Sprintf("field %q has an invalid pattern: %v", field.Name, err))
+			}
+		}
+		if zone := field.Zone; zone != nil && (zone.BBox[0] >= zone.BBox[2] || zone.BBox[1] >= zone.BBox[3]) {
+			return apperrors.Validation("VAL_048", fmt.Sprintf("field %q has a zone without x0 < x1 and y0 < y1", field.Name))
+		}
+	}
+	return nil
+}
+
+// applyTemplate extracts the fields of a template from a result's raw text and
+// regions
+func applyTemplate(template *models.ExtractionTemplate, text string, regions []*models.ResultRegion) *models.ResultFields {
+	fields := &models.ResultFields{
+		TemplateID:   template.ID,
+		TemplateName: template.Name,
+		Fields:       map[string]string{},
+		Missing:      []string{},
+	}
+	for _, field := range template.Fields {
+		if value, ok := extractField(field, text, regions); ok {
+			fields.Fields[field.Name] = value
+		} else {
+			fields.Missing = append(fields.Missing, field.Name)
+		}
+	}
+	return fields
+}
+
+// extractField narrows text down with the field's zone, anchor and pattern in
+// turn, and returns what is left with its whitespace collapsed
+func extractField(field models.TemplateField, text string, regions []*models.ResultRegion) (string, bool) {
+	source := text
+	if field.Zone != nil {
+		source = zoneText(field.Zone, regions)
+	}
+
+	if field.Anchor != "" {
+		var ok bool
+		if source, ok = afterAnchor(source, field.Anchor); !ok {
+			return "", false
+		}
+	}
+
+	if field.Pattern != "" {
+		pattern, err := regexp.Compile(field.Pattern)
+		if err != nil {
+			return "", false
+		}
+		match := pattern.FindStringSubmatch(source)
+		switch {
+		case match == nil:
+			return "", false
+		case len(match) > 1:
+			source = match[1]
+		default:
+			source = match[0]
+		}
+	}
+
+	value := strings.Join(strings.Fields(source), " ")
+	return value, value != ""
+}
+
+// zoneText returns the text of the lines on the zone's page centered inside
+// its box, one per line, falling back to words for pages without lines
+func zoneText(zone *models.TemplateZone, regions []*models.ResultRegion) string {
+	for _, level := range []models.RegionLevel{models.RegionLevelLine, models.RegionLevelWord} {
+		var texts []string
+		onPage := false
+		for _, region := range regions {
+			if region.PageNumber != zone.Page || region.Level != level {
+				continue
+			}
+			onPage = true
+			cx, cy := (region.X0+region.X1)/2, (region.Y0+region.Y1)/2
+			if cx >= zone.BBox[0] && cx <= zone.BBox[2] && cy >= zone.BBox[1] && cy <= zone.BBox[3] {
+				texts = append(texts, region.Text)
+			}
+		}
+		if onPage {
+			if level == models.RegionLevelLine {
+				return strings.Join(texts, "\n")
+			}
+			return strings.Join(texts, " ")
+		}
+	}
+	return ""
+}
+
+// afterAnchor returns the rest of the line after the first occurrence of
+// anchor, ignoring case, without the separators usually put after labels. A
+// label alone on its line is followed by the next line that is not empty.
+func afterAnchor(text, anchor string) (string, bool) {
+	loc := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(anchor)).FindStringIndex(text)
+	if loc == nil {
+		return "", false
+	}
+
+	rest := text[loc[1]:]
+	line, next, _ := strings.Cut(rest, "\n")
+	if value := strings.TrimLeft(line, " \t:#.-"); strings.TrimSpace(value) != "" {
+		return value, true
+	}
+
+	for _, line := range strings.Split(next, "\n") {
+		if strings.TrimSpace(line) != "" {
+			return line, true
+		}
+	}
+	return "", false
+}
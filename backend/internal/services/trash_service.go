@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// ErrDocumentBusy is returned when deleting a document that OCR jobs are still working on
+var ErrDocumentBusy = apperrors.Conflict("JOB_004", "document has active OCR jobs")
+
+// TrashService manages soft-deleted documents and purges them after the retention period
+type TrashService struct {
+	documentRepo *repository.DocumentRepository
+	jobRepo      *repository.JobRepository
+	transactor   *repository.Transactor
+	storage      *storage.Storage
+	retention    time.Duration
+	batchSize    int
+}
+
+// NewTrashService creates a new trash service.
+// A retention of zero keeps deleted documents forever.
+func NewTrashService(
+	documentRepo *repository.DocumentRepository,
+	jobRepo *repository.JobRepository,
+	transactor *repository.Transactor,
+	storage *storage.Storage,
+	retention time.Duration,
+) *TrashService {
+	return &TrashService{
+		documentRepo: documentRepo,
+		jobRepo:      jobRepo,
+		transactor:   transactor,
+		storage:      storage,
+		retention:    retention,
+		batchSize:    100,
+	}
+}
+
+// Retention returns how long deleted documents are kept
+func (s *TrashService) Retention() time.Duration {
+	return s.retention
+}
+
+// List retrieves the documents in a user's trash
+func (s *TrashService) List(ctx context.Context, userID uuid.UUID, page, perPage int) ([]models.Document, int, error) {
+	return s.documentRepo.ListDeletedByUser(ctx, userID, page, perPage)
+}
+
+// Delete moves a document to the trash. Documents with pending or processing
+// jobs are refused with ErrDocumentBusy unless cancelJobs is set, in which case
+// those jobs are cancelled first. It returns the IDs of the cancelled jobs.
+func (s *TrashService) Delete(ctx context.Context, documentID uuid.UUID, cancelJobs bool) ([]uuid.UUID, error) {
+	var cancelled []uuid.UUID
+
+	err := s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		// Block workers from starting or finishing a job on the document meanwhile
+		if err := s.documentRepo.LockForUpdate(ctx, documentID); err != nil {
+			return err
+		}
+
+		if cancelJobs {
+			var err error
+			cancelled, err = s.jobRepo.CancelActiveByDocument(ctx, documentID, "Cancelled: document was deleted")
+			if err != nil {
+				return err
+			}
+		} else {
+			active, err := s.jobRepo.CountActiveByDocument(ctx, documentID)
+			if err != nil {
+				return err
+			}
+			if active > 0 {
+				return ErrDocumentBusy
+			}
+		}
+
+		return s.documentRepo.SoftDelete(ctx, documentID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cancelled) > 0 {
+		logger.Info("Cancelled jobs of deleted document", "document_id", documentID, "jobs", len(cancelled))
+	}
+
+	return cancelled, nil
+}
+
+// Restore moves a document owned by the user out of the trash
+func (s *TrashService) Restore(ctx context.Context, documentID, userID uuid.UUID) (*models.Document, error) {
+	document, err := s.documentRepo.GetDeletedByID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.UserID != userID {
+		return nil, apperrors.Forbidden("AUTH_004", "document does not belong to user")
+	}
+
+	if err := s.documentRepo.Restore(ctx, documentID); err != nil {
+		return nil, err
+	}
+	document.DeletedAt = nil
+
+	return document, nil
+}
+
+// PurgeExpired permanently deletes documents that have been in the trash longer
+// than the retention period, including their stored files. It returns the number
+// of documents purged.
+func (s *TrashService) PurgeExpired(ctx context.Context) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-s.retention)
+	purged := 0
+
+	for {
+		documents, err := s.documentRepo.ListDeletedBefore(ctx, cutoff, s.batchSize)
+		if err != nil {
+			return purged, err
+		}
+		if len(documents) == 0 {
+			break
+		}
+
+		for _, document := range documents {
+			// Remove the row first so a failed file deletion never leaves a
+			// document pointing at a missing file
+			if err := s.documentRepo.HardDelete(ctx, document.ID); err != nil {
+				return purged, err
+			}
+			purged++
+
+			// Files shared with other documents, such as a teammate's upload
+			// of the same file, stay until no document refers to them
+			if err := s.storage.Release(ctx, document.FilePath); err != nil {
+				logger.Warn("Failed to delete purged document file", "document_id", document.ID, "error", err)
+			}
+			if document.ThumbnailPath != nil {
+				if err := s.storage.Release(ctx, *document.ThumbnailPath); err != nil {
+					logger.Warn("Failed to delete purged thumbnail", "document_id", document.ID, "error", err)
+				}
+			}
+		}
+
+		if len(documents) < s.batchSize {
+			break
+		}
+	}
+
+	if purged > 0 {
+		logger.Info("Purged expired documents from trash", "count", purged)
+	}
+
+	return purged, nil
+}
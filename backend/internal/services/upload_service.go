@@ -0,0 +1,452 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/scanner"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUploadFileType is returned for files with an extension that is not
+	// allowed
+	ErrUploadFileType = apperrors.Validation("VAL_006", "File type not allowed")
+	// ErrInvalidDuplicatePolicy is returned for an unknown duplicate policy
+	ErrInvalidDuplicatePolicy = apperrors.Validation("VAL_018", "Invalid dedupe: must be reject, link, replace or allow")
+	// ErrInvalidChecksum is returned for a checksum that is not a hex-encoded
+	// SHA-256 digest
+	ErrInvalidChecksum = apperrors.Validation("VAL_016", "Invalid checksum: expected a hex-encoded SHA-256 digest")
+	// ErrChecksumMismatch is returned for a file that does not match the
+	// checksum the client supplied
+	ErrChecksumMismatch = apperrors.Validation("VAL_017", "Checksum mismatch: the uploaded file does not match the supplied SHA-256 digest")
+	// ErrMalwareDetected is returned for a file the scanner found infected; the
+	// file is quarantined
+	ErrMalwareDetected = apperrors.Validation("SEC_001", "File rejected: malware detected")
+	// ErrDuplicateUpload is the error a DuplicateUploadError unwraps to
+	ErrDuplicateUpload = apperrors.Conflict("RES_005", "File already exists")
+	// ErrScanUnavailable is returned when the scanner fails and uploads are
+	// not accepted unscanned
+	ErrScanUnavailable = errors.New("virus scan unavailable, please try again later")
+	// ErrReplaceFailed is returned when the document a file replaces could
+	// not be moved to the trash
+	ErrReplaceFailed = errors.New("failed to replace existing document")
+)
+
+// DuplicateUploadError is returned for a file rejected as a duplicate of one
+// of the user's documents
+type DuplicateUploadError struct {
+	Duplicate *models.DuplicateInfo
+}
+
+func (e *DuplicateUploadError) Error() string {
+	return ErrDuplicateUpload.Error()
+}
+
+func (e *DuplicateUploadError) Unwrap() error {
+	return ErrDuplicateUpload
+}
+
+// UploadService stores uploaded files as documents, for the REST upload and
+// gRPC consumers alike
+type UploadService struct {
+	documentRepo  *repository.DocumentRepository
+	settingsRepo  *repository.UserSettingsRepository
+	storage       *storage.Storage
+	transactor    *repository.Transactor
+	eventService  *EventService
+	trashService  *TrashService
+	sharedUploads *SharedUploadService
+	orgService    *OrganizationService
+	scanner       scanner.Scanner
+	scanFailOpen  bool
+	maxFileSize   int64
+	allowedExts   []string
+}
+
+// NewUploadService creates a new upload service
+func NewUploadService(
+	documentRepo *repository.DocumentRepository,
+	settingsRepo *repository.UserSettingsRepository,
+	storage *storage.Storage,
+	transactor *repository.Transactor,
+	eventService *EventService,
+	trashService *TrashService,
+	sharedUploads *SharedUploadService,
+	orgService *OrganizationService,
+	fileScanner scanner.Scanner,
+	scanFailOpen bool,
+	maxFileSize int64,
+	allowedExts []string,
+) *UploadService {
+	return &UploadService{
+		documentRepo:  documentRepo,
+		settingsRepo:  settingsRepo,
+		storage:       storage,
+		transactor:    transactor,
+		eventService:  eventService,
+		trashService:  trashService,
+		sharedUploads: sharedUploads,
+		orgService:    orgService,
+		scanner:       fileScanner,
+		scanFailOpen:  scanFailOpen,
+		maxFileSize:   maxFileSize,
+		allowedExts:   allowedExts,
+	}
+}
+
+// ReceivedUpload is an uploaded file stored as it was received, before it
+// becomes a document
+type ReceivedUpload struct {
+	UserID uuid.UUID
+	// Filename is the name sent by the client, DisplayName its normalized form
+	Filename    string
+	DisplayName string
+	// Path is where the file is stored under a generated name, RelativePath
+	// the same below the storage root
+	Path         string
+	RelativePath string
+	Hash         string
+	Size         int64
+}
+
+// UploadOptions are the choices a client makes with an upload
+type UploadOptions struct {
+	// Labels are resolved as described for UploadLabels
+	Labels []string
+	// OrgID shares the document with an organization the user may share with
+	OrgID *uuid.UUID
+	// Checksum is the SHA-256 digest the client expects the file to have;
+	// empty skips the check
+	Checksum string
+	// DuplicatePolicy handles a duplicate of one of the user's documents;
+	// empty uses the user's setting
+	DuplicatePolicy models.DuplicatePolicy
+}
+
+// UploadOutcome is what became of an upload
+type UploadOutcome struct {
+	// Document is the new document, or the existing one when Existing is set
+	Document  *models.Document
+	Existing  bool
+	Duplicate *models.DuplicateInfo
+	Shared    *models.SharedUploadInfo
+	// Replaced is the document moved to the trash for the new one
+	Replaced *models.Document
+}
+
+// Upload receives the content read from src as a file of the user named
+// name and stores it as a document
+func (s *UploadService) Upload(ctx context.Context, userID uuid.UUID, name string, src io.Reader, opts UploadOptions) (*UploadOutcome, error) {
+	file, err := s.Receive(ctx, userID, name, src)
+	if err != nil {
+		return nil, err
+	}
+	return s.Store(ctx, file, opts)
+}
+
+// Receive writes the content read from src to storage as it arrives, hashing
+// it, limiting its size and validating its content, and fails as soon as
+// either check does. The file is kept until Store or Discard is called.
+func (s *UploadService) Receive(ctx context.Context, userID uuid.UUID, name string, src io.Reader) (*ReceivedUpload, error) {
+	file := &ReceivedUpload{
+		UserID:      userID,
+		Filename:    name,
+		DisplayName: storage.NormalizeFilename(name),
+	}
+	if !storage.ValidateFileType(file.DisplayName, s.allowedExts) {
+		return nil, ErrUploadFileType
+	}
+
+	counter := &countingReader{r: src}
+	inspector := storage.NewContentInspector(file.DisplayName)
+	path, hash, err := s.storage.SaveReader(io.TeeReader(storage.LimitReader(counter, s.maxFileSize), inspector), file.DisplayName, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := inspector.Result(); err != nil {
+		_ = s.storage.Release(ctx, path)
+		return nil, err
+	}
+	relativePath, err := s.storage.RelativePath(path)
+	if err != nil {
+		_ = s.storage.Release(ctx, path)
+		return nil, err
+	}
+
+	file.Path, file.RelativePath, file.Hash, file.Size = path, relativePath, hash, counter.n
+	return file, nil
+}
+
+// Discard removes a received file that will not be stored
+func (s *UploadService) Discard(ctx context.Context, file *ReceivedUpload) {
+	_ = s.storage.Release(ctx, file.Path)
+}
+
+// Store checks a received file against the options, scans it for malware and
+// stores it as a document of the user. A duplicate of one of the user's
+// documents is handled by the duplicate policy, and a file a teammate already
+// uploaded reuses their stored file and result when the organization allows
+// it. The received file is removed whenever it is not kept.
+func (s *UploadService) Store(ctx context.Context, file *ReceivedUpload, opts UploadOptions) (*UploadOutcome, error) {
+	outcome, err := s.store(ctx, file, opts)
+	if err != nil && !errors.Is(err, ErrMalwareDetected) {
+		s.Discard(ctx, file)
+	}
+	return outcome, err
+}
+
+func (s *UploadService) store(ctx context.Context, file *ReceivedUpload, opts UploadOptions) (*UploadOutcome, error) {
+	policy := opts.DuplicatePolicy
+	if policy == "" {
+		policy = s.duplicatePolicy(ctx, file.UserID)
+	} else if !policy.Valid() {
+		return nil, ErrInvalidDuplicatePolicy
+	}
+
+	checksum, err := normalizeChecksum(opts.Checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := UploadLabels(ctx, opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.OrgID != nil {
+		if err := s.orgService.CheckCanShare(ctx, *opts.OrgID, file.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Verify end-to-end integrity against the client checksum
+	if checksum != "" && checksum != file.Hash {
+		return nil, ErrChecksumMismatch
+	}
+
+	// Scan for malware before the document becomes visible
+	if s.scanner != nil {
+		infected, err := s.scan(ctx, file.Path)
+		if err != nil && !s.scanFailOpen {
+			return nil, ErrScanUnavailable
+		}
+		if infected {
+			return nil, ErrMalwareDetected
+		}
+	}
+
+	outcome := &UploadOutcome{}
+	if existing, err := s.documentRepo.GetByHash(ctx, file.Hash, file.UserID); err == nil && existing != nil {
+		outcome.Duplicate = &models.DuplicateInfo{
+			Policy:              policy,
+			ExistingDocumentID:  existing.ID,
+			ExistingDocumentURL: documentURL(existing.ID),
+		}
+
+		switch policy {
+		case models.DuplicateReject:
+			return nil, &DuplicateUploadError{Duplicate: outcome.Duplicate}
+
+		case models.DuplicateReturnExisting:
+			s.Discard(ctx, file)
+
+			// Keep the new filename so the document can be found by it
+			if existing.AddAlias(file.DisplayName) {
+				outcome.Duplicate.Alias = file.DisplayName
+				if err := s.documentRepo.UpdateMetadata(ctx, existing.ID, existing.Labels, existing.Metadata); err != nil {
+					logger.FromContext(ctx).Warn("Failed to record duplicate filename", "document_id", existing.ID, "error", err)
+				}
+			}
+			outcome.Document, outcome.Existing = existing, true
+			return outcome, nil
+
+		case models.DuplicateReplace:
+			// The existing document goes to the trash, where it can be restored from
+			if _, err := s.trashService.Delete(ctx, existing.ID, false); err != nil {
+				if errors.Is(err, ErrDocumentBusy) {
+					return nil, err
+				}
+				return nil, fmt.Errorf("%w: %v", ErrReplaceFailed, err)
+			}
+			outcome.Replaced = existing
+		}
+
+		// create_copy and replace keep the new file and store it as a document
+		// of its own
+	}
+
+	// Reuse the stored file and result of a teammate's identical upload when the
+	// organization allows it
+	var teammateUpload *models.Document
+	if outcome.Duplicate == nil {
+		teammateUpload, err = s.sharedUploads.FindTeammateUpload(ctx, file.Hash, file.UserID)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Failed to look up teammate uploads", "error", err)
+		}
+	}
+
+	// Keep display names unique among the user's documents
+	displayName := file.DisplayName
+	taken, err := s.documentRepo.FilenamesWithPrefix(ctx, file.UserID, strings.TrimSuffix(displayName, filepath.Ext(displayName)))
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to check filename collisions", "error", err)
+	}
+	displayName = storage.DisambiguateFilename(displayName, func(name string) bool {
+		return taken[name]
+	})
+
+	// Preserve the filename as sent when normalization changed it
+	metadata := map[string]any{}
+	if displayName != file.Filename {
+		metadata[models.DocumentMetaSourceFilename] = storage.SourceFilename(file.Filename)
+	}
+
+	mimeType := storage.GetMimeType(displayName)
+	document := &models.Document{
+		UserID:           file.UserID,
+		Filename:         file.RelativePath,
+		OriginalFilename: displayName,
+		FilePath:         file.Path,
+		FileSize:         file.Size,
+		MimeType:         mimeType,
+		FileHash:         file.Hash,
+		NumPages:         CountPages(s.storage, file.Path, mimeType),
+		Metadata:         metadata,
+		Labels:           labels,
+		OrgID:            opts.OrgID,
+	}
+	if teammateUpload != nil {
+		s.sharedUploads.Share(document, teammateUpload)
+	}
+
+	err = s.transactor.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.documentRepo.Create(ctx, document); err != nil {
+			return err
+		}
+
+		if teammateUpload != nil {
+			var err error
+			outcome.Shared, err = s.sharedUploads.ShareResult(ctx, document, teammateUpload)
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.eventService.Record(ctx, models.NewDomainEvent(
+			models.EventDocumentUploaded, "document", document.ID, &file.UserID,
+			map[string]any{
+				"original_filename": document.OriginalFilename,
+				"file_size":         document.FileSize,
+				"mime_type":         document.MimeType,
+				"file_hash":         document.FileHash,
+			},
+		))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document record: %w", err)
+	}
+
+	// The document uses the teammate's stored file instead of the new one
+	if outcome.Shared != nil {
+		s.Discard(ctx, file)
+	}
+
+	outcome.Document = document
+	return outcome, nil
+}
+
+// duplicatePolicy returns the user's duplicate policy
+func (s *UploadService) duplicatePolicy(ctx context.Context, userID uuid.UUID) models.DuplicatePolicy {
+	settings, err := s.settingsRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to load duplicate policy, using default", "error", err)
+		return models.DuplicateReturnExisting
+	}
+	return settings.DuplicatePolicy
+}
+
+// scan scans a received file and quarantines it when infected
+func (s *UploadService) scan(ctx context.Context, filePath string) (bool, error) {
+	file, err := s.storage.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	result, err := s.scanner.Scan(ctx, file)
+	file.Close()
+	if err != nil {
+		logger.FromContext(ctx).Error("Malware scan failed", "error", err)
+		return false, err
+	}
+	if !result.Infected {
+		return false, nil
+	}
+
+	quarantinePath, err := s.storage.Quarantine(filePath)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to quarantine infected file", "path", filePath, "error", err)
+		_ = s.storage.DeleteFile(filePath)
+	}
+	logger.FromContext(ctx).Warn("Infected upload quarantined", "signature", result.Signature, "quarantine_path", quarantinePath)
+
+	return true, nil
+}
+
+// normalizeChecksum lower-cases a client-supplied SHA-256 digest, failing
+// when it is present but not a hex-encoded digest
+func normalizeChecksum(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", nil
+	}
+	if len(value) != sha256.Size*2 {
+		return "", ErrInvalidChecksum
+	}
+	if _, err := hex.DecodeString(value); err != nil {
+		return "", ErrInvalidChecksum
+	}
+	return value, nil
+}
+
+// documentURL returns the API path of a document
+func documentURL(id uuid.UUID) string {
+	return "/api/v1/documents/" + id.String()
+}
+
+// UploadLabels normalizes the labels given for a new document. API keys
+// restricted to labels may only use their own, and apply all of them when
+// none are given.
+func UploadLabels(ctx context.Context, values []string) ([]string, error) {
+	labels, err := models.NormalizeLabels(values)
+	if err != nil {
+		return nil, apperrors.Validation("VAL_023", err.Error())
+	}
+
+	scope := repository.LabelScope(ctx)
+	if scope == nil {
+		return labels, nil
+	}
+	if len(labels) == 0 {
+		return scope, nil
+	}
+	for _, label := range labels {
+		if !slices.Contains(scope, label) {
+			return nil, apperrors.Forbidden("AUTH_007", "API key may not use label "+label)
+		}
+	}
+
+	return labels, nil
+}
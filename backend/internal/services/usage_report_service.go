@@ -0,0 +1,262 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"visekai/backend/internal/apperrors"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+// ErrReportMonthNotOver is returned when generating the report of a month
+// that has not ended yet
+var ErrReportMonthNotOver = apperrors.Validation("VAL_036", "usage reports can only be generated for months that are over")
+
+// UsageReportService generates a usage report for every calendar month once
+// it is over: pages processed, storage growth, peak queue depth and p95
+// latencies, for the instance and each organization, so operators can plan
+// GPU and storage capacity
+type UsageReportService struct {
+	reportRepo *repository.UsageReportRepository
+	storage    *storage.Storage
+	interval   time.Duration
+	mu         sync.Mutex
+}
+
+// NewUsageReportService creates a new usage report service that checks for a
+// missing report of the previous month every interval
+func NewUsageReportService(reportRepo *repository.UsageReportRepository, storage *storage.Storage, interval time.Duration) *UsageReportService {
+	return &UsageReportService{
+		reportRepo: reportRepo,
+		storage:    storage,
+		interval:   interval,
+	}
+}
+
+// Start generates the report of the previous month when it is missing, at
+// start-up and then on every interval, until the context is cancelled
+func (s *UsageReportService) Start(ctx context.Context) {
+	logger.Info("Usage reports started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		_ = tasks.Run(ctx, tasks.Task{
+			Name: "usage_reports.generate",
+			Run:  s.generateDue,
+		})
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Usage reports stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// generateDue generates the report of the previous month unless it exists
+func (s *UsageReportService) generateDue(ctx context.Context) error {
+	previous := monthStart(time.Now().UTC()).AddDate(0, -1, 0)
+	exists, err := s.reportRepo.Exists(ctx, previous)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = s.Generate(ctx, previous)
+	return err
+}
+
+// Generate computes the report of the month containing month and stores it,
+// replacing an earlier report of the same month
+func (s *UsageReportService) Generate(ctx context.Context, month time.Time) (*models.UsageReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := monthStart(month.UTC())
+	end := start.AddDate(0, 1, 0)
+	if end.After(time.Now()) {
+		return nil, ErrReportMonthNotOver
+	}
+
+	totals, jobsByOrg, err := s.reportRepo.JobUsage(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	storageTotals, storageByOrg, err := s.reportRepo.StorageUsage(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	peak, peakAt, err := s.reportRepo.PeakQueueDepth(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.UsageReport{
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		Totals:         *totals,
+		PeakQueueDepth: peak,
+		PeakQueueAt:    peakAt,
+		Orgs:           mergeOrgUsage(jobsByOrg, storageByOrg),
+		GeneratedAt:    time.Now().UTC(),
+	}
+	copyStorageUsage(&report.Totals, storageTotals)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return nil, fmt.Errorf("failed to encode usage report: %w", err)
+	}
+	report.FilePath, err = s.storage.WriteEncryptedFile(path.Join(storage.ReportsDir, "usage", start.Format("2006-01")+".json"), &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reportRepo.Save(ctx, report); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Usage report generated",
+		"period", start.Format("2006-01"),
+		"pages", report.Totals.Pages,
+		"peak_queue_depth", report.PeakQueueDepth,
+		"organizations", len(report.Orgs))
+
+	return report, nil
+}
+
+// List returns the reports, most recent month first, without their
+// organization breakdown
+func (s *UsageReportService) List(ctx context.Context, page, perPage int) ([]*models.UsageReport, int, error) {
+	return s.reportRepo.List(ctx, perPage, (page-1)*perPage)
+}
+
+// Get returns a full report, with its organization breakdown
+func (s *UsageReportService) Get(ctx context.Context, id uuid.UUID) (*models.UsageReport, error) {
+	stored, err := s.reportRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.storage.Open(stored.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var report models.UsageReport
+	if err := json.NewDecoder(file).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to read usage report: %w", err)
+	}
+	report.ID = stored.ID
+	report.FilePath = stored.FilePath
+
+	return &report, nil
+}
+
+// WriteCSV writes a report as CSV: a row for the instance followed by a row
+// per organization
+func (s *UsageReportService) WriteCSV(report *models.UsageReport, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{
+		"scope", "org_id", "org_name", "jobs", "failed_jobs", "pages",
+		"documents_added", "storage_added_bytes", "storage_total_bytes",
+		"p95_queue_wait_ms", "p95_processing_ms",
+	})
+	_ = writer.Write(usageRow("instance", "", "", report.Totals))
+	for _, org := range report.Orgs {
+		if org.OrgID == nil {
+			_ = writer.Write(usageRow("personal", "", "", org.UsageTotals))
+			continue
+		}
+		_ = writer.Write(usageRow("organization", org.OrgID.String(), org.OrgName, org.UsageTotals))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// usageRow formats the totals of one scope as a CSV row
+func usageRow(scope, orgID, orgName string, totals models.UsageTotals) []string {
+	latency := func(ms *float64) string {
+		if ms == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*ms, 'f', 0, 64)
+	}
+	return []string{
+		scope, orgID, orgName,
+		strconv.FormatInt(totals.Jobs, 10),
+		strconv.FormatInt(totals.FailedJobs, 10),
+		strconv.FormatInt(totals.Pages, 10),
+		strconv.FormatInt(totals.DocumentsAdded, 10),
+		strconv.FormatInt(totals.StorageAddedBytes, 10),
+		strconv.FormatInt(totals.StorageTotalBytes, 10),
+		latency(totals.P95QueueWaitMs),
+		latency(totals.P95ProcessingMs),
+	}
+}
+
+// mergeOrgUsage combines the job and storage usage of each organization,
+// ordered by pages processed, documents outside any organization last
+func mergeOrgUsage(jobs, stored map[uuid.UUID]*models.OrgUsage) []*models.OrgUsage {
+	merged := make(map[uuid.UUID]*models.OrgUsage, len(jobs))
+	for key, usage := range jobs {
+		merged[key] = usage
+	}
+	for key, usage := range stored {
+		org, ok := merged[key]
+		if !ok {
+			merged[key] = usage
+			continue
+		}
+		copyStorageUsage(&org.UsageTotals, &usage.UsageTotals)
+		if org.OrgName == "" {
+			org.OrgName = usage.OrgName
+		}
+	}
+
+	orgs := make([]*models.OrgUsage, 0, len(merged))
+	for _, usage := range merged {
+		orgs = append(orgs, usage)
+	}
+	sort.Slice(orgs, func(i, j int) bool {
+		if (orgs[i].OrgID == nil) != (orgs[j].OrgID == nil) {
+			return orgs[j].OrgID == nil
+		}
+		if orgs[i].Pages != orgs[j].Pages {
+			return orgs[i].Pages > orgs[j].Pages
+		}
+		return orgs[i].OrgName < orgs[j].OrgName
+	})
+	return orgs
+}
+
+// copyStorageUsage copies the storage figures of from into totals
+func copyStorageUsage(totals, from *models.UsageTotals) {
+	totals.DocumentsAdded = from.DocumentsAdded
+	totals.StorageAddedBytes = from.StorageAddedBytes
+	totals.StorageTotalBytes = from.StorageTotalBytes
+}
+
+// monthStart returns the first instant of the month containing t, in t's location
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
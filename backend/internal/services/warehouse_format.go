@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"visekai/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Formats of the warehouse export files
+const (
+	WarehouseFormatNDJSON  = "ndjson"
+	WarehouseFormatParquet = "parquet"
+)
+
+// warehouseParquetRecord is the Parquet layout of a WarehouseJobRecord.
+// Timestamps are stored in microseconds, which every common reader supports;
+// a zero started_at or result_id is written as null.
+type warehouseParquetRecord struct {
+	SchemaVersion    int32     `parquet:"schema_version"`
+	JobID            uuid.UUID `parquet:"job_id,uuid"`
+	DocumentID       uuid.UUID `parquet:"document_id,uuid"`
+	UserID           uuid.UUID `parquet:"user_id,uuid"`
+	Status           string    `parquet:"status,dict"`
+	OCRMode          string    `parquet:"ocr_mode,dict"`
+	ResolutionMode   string    `parquet:"resolution_mode,dict"`
+	Priority         int32     `parquet:"priority"`
+	RetryCount       int32     `parquet:"retry_count"`
+	CreatedAt        time.Time `parquet:"created_at,timestamp(microsecond)"`
+	StartedAt        int64     `parquet:"started_at,optional,timestamp(microsecond)"`
+	CompletedAt      time.Time `parquet:"completed_at,timestamp(microsecond)"`
+	MimeType         *string   `parquet:"mime_type,optional"`
+	FileSize         *int64    `parquet:"file_size,optional"`
+	ResultID         uuid.UUID `parquet:"result_id,optional,uuid"`
+	ConfidenceScore  *float64  `parquet:"confidence_score,optional"`
+	ProcessingTimeMs *int64    `parquet:"processing_time_ms,optional"`
+	NumPages         *int64    `parquet:"num_pages,optional"`
+	Backends         []string  `parquet:"backends,list"`
+	PageCredits      *int64    `parquet:"page_credits,optional"`
+	MaxPageTimeMs    *int64    `parquet:"max_page_time_ms,optional"`
+	AvgPageTimeMs    *float64  `parquet:"avg_page_time_ms,optional"`
+}
+
+// encodeWarehouseRecords encodes a batch of records as one file of the format
+func encodeWarehouseRecords(format string, records []*models.WarehouseJobRecord) ([]byte, error) {
+	switch format {
+	case WarehouseFormatNDJSON:
+		return encodeWarehouseNDJSON(records)
+	case WarehouseFormatParquet:
+		return encodeWarehouseParquet(records)
+	default:
+		return nil, fmt.Errorf("unknown warehouse export format: %s", format)
+	}
+}
+
+// encodeWarehouseNDJSON writes one JSON object per line
+func encodeWarehouseNDJSON(records []*models.WarehouseJobRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, fmt.Errorf("failed to encode record: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeWarehouseParquet writes a single row group, with the schema version
+// also recorded in the file's key/value metadata
+func encodeWarehouseParquet(records []*models.WarehouseJobRecord) ([]byte, error) {
+	rows := make([]warehouseParquetRecord, len(records))
+	for i, record := range records {
+		rows[i] = warehouseParquetRecord{
+			SchemaVersion:    int32(record.SchemaVersion),
+			JobID:            record.JobID,
+			DocumentID:       record.DocumentID,
+			UserID:           record.UserID,
+			Status:           string(record.Status),
+			OCRMode:          string(record.OCRMode),
+			ResolutionMode:   string(record.ResolutionMode),
+			Priority:         int32(record.Priority),
+			RetryCount:       int32(record.RetryCount),
+			CreatedAt:        record.CreatedAt,
+			CompletedAt:      record.CompletedAt,
+			MimeType:         record.MimeType,
+			FileSize:         record.FileSize,
+			ConfidenceScore:  record.ConfidenceScore,
+			ProcessingTimeMs: int64Ptr(record.ProcessingTimeMs),
+			NumPages:         int64Ptr(record.NumPages),
+			Backends:         record.Backends,
+			PageCredits:      int64Ptr(record.PageCredits),
+			MaxPageTimeMs:    int64Ptr(record.MaxPageTimeMs),
+			AvgPageTimeMs:    record.AvgPageTimeMs,
+		}
+		if record.StartedAt != nil {
+			rows[i].StartedAt = record.StartedAt.UnixMicro()
+		}
+		if record.ResultID != nil {
+			rows[i].ResultID = *record.ResultID
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := parquet.NewGenericWriter[warehouseParquetRecord](&buf,
+		parquet.Compression(&parquet.Snappy),
+		parquet.KeyValueMetadata("schema_version", strconv.Itoa(models.WarehouseSchemaVersion)),
+	)
+	if _, err := writer.Write(rows); err != nil {
+		return nil, fmt.Errorf("failed to encode records: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode records: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// int64Ptr widens an optional int
+func int64Ptr(value *int) *int64 {
+	if value == nil {
+		return nil
+	}
+	widened := int64(*value)
+	return &widened
+}
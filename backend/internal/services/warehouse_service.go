@@ -0,0 +1,194 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"visekai/backend/internal/delivery"
+	"visekai/backend/internal/models"
+	"visekai/backend/internal/repository"
+	"visekai/backend/internal/tasks"
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/storage"
+)
+
+// warehouseJobsCursor is the cursor name of the job metadata export
+const warehouseJobsCursor = "jobs"
+
+// warehouseLocalDir is where exports to local storage are written
+const warehouseLocalDir = "exports/warehouse"
+
+// WarehouseExportService periodically exports job/result metadata for BI pipelines
+type WarehouseExportService struct {
+	warehouseRepo *repository.WarehouseRepository
+	target        delivery.Target
+	format        string
+	interval      time.Duration
+	lag           time.Duration
+	batchSize     int
+	mu            sync.Mutex
+}
+
+// NewWarehouseExportService creates a new warehouse export service writing
+// files of the format to the target. Jobs finished less than lag ago are left
+// for a later run, as a transaction that set an earlier completed_at may not
+// have committed yet and would otherwise fall behind the cursor.
+func NewWarehouseExportService(
+	warehouseRepo *repository.WarehouseRepository,
+	target delivery.Target,
+	format string,
+	interval time.Duration,
+	lag time.Duration,
+	batchSize int,
+) *WarehouseExportService {
+	if batchSize < 1 {
+		batchSize = 5000
+	}
+	return &WarehouseExportService{
+		warehouseRepo: warehouseRepo,
+		target:        target,
+		format:        format,
+		interval:      interval,
+		lag:           lag,
+		batchSize:     batchSize,
+	}
+}
+
+// localExportTarget writes export files under the exports directory of the
+// local storage
+type localExportTarget struct {
+	storage *storage.Storage
+}
+
+// NewLocalExportTarget returns a target writing warehouse exports to local storage
+func NewLocalExportTarget(storage *storage.Storage) delivery.Target {
+	return &localExportTarget{storage: storage}
+}
+
+// Put writes a file under the exports directory
+func (t *localExportTarget) Put(ctx context.Context, name string, content []byte) error {
+	_, err := t.storage.WriteEncryptedFile(path.Join(warehouseLocalDir, name), bytes.NewReader(content))
+	return err
+}
+
+// Start runs the export on every interval until the context is cancelled
+func (s *WarehouseExportService) Start(ctx context.Context) {
+	logger.Info("Warehouse export started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Warehouse export stopped")
+			return
+		case <-ticker.C:
+			_ = tasks.Run(ctx, tasks.Task{
+				Name: "warehouse.export",
+				Run: func(ctx context.Context) error {
+					_, err := s.Run(ctx)
+					return err
+				},
+			})
+		}
+	}
+}
+
+// Status returns the current export cursor
+func (s *WarehouseExportService) Status(ctx context.Context) (*models.WarehouseExportCursor, error) {
+	cursor, err := s.warehouseRepo.GetCursor(ctx, warehouseJobsCursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil {
+		cursor = &models.WarehouseExportCursor{
+			Name:          warehouseJobsCursor,
+			SchemaVersion: models.WarehouseSchemaVersion,
+		}
+	}
+	return cursor, nil
+}
+
+// Run exports all job metadata finished since the last run, up to the lag.
+// Each batch is written to its own file and the cursor is advanced after every file,
+// so an interrupted run resumes where it stopped.
+func (s *WarehouseExportService) Run(ctx context.Context) (*models.WarehouseExportRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := &models.WarehouseExportRun{
+		Files:     []string{},
+		StartedAt: time.Now().UTC(),
+	}
+
+	cursor, err := s.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new schema version starts a fresh export so every file holds a single layout
+	if cursor.SchemaVersion != models.WarehouseSchemaVersion {
+		logger.Warn("Warehouse schema version changed, restarting export",
+			"from", cursor.SchemaVersion, "to", models.WarehouseSchemaVersion)
+		cursor = &models.WarehouseExportCursor{
+			Name:          warehouseJobsCursor,
+			SchemaVersion: models.WarehouseSchemaVersion,
+		}
+	}
+
+	until := run.StartedAt.Add(-s.lag)
+	for {
+		records, err := s.warehouseRepo.ListFinishedJobsSince(ctx, cursor.CursorAt, cursor.CursorID, until, s.batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			break
+		}
+
+		content, err := encodeWarehouseRecords(s.format, records)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		relativePath := path.Join(
+			fmt.Sprintf("v%d", models.WarehouseSchemaVersion),
+			"jobs",
+			"dt="+now.Format("2006-01-02"),
+			fmt.Sprintf("jobs-%s.%s", now.Format("20060102T150405.000000000"), s.format),
+		)
+
+		if err := s.target.Put(ctx, relativePath, content); err != nil {
+			return nil, fmt.Errorf("failed to write export file: %w", err)
+		}
+
+		last := records[len(records)-1]
+		cursor.CursorAt = &last.CompletedAt
+		cursor.CursorID = &last.JobID
+		cursor.RowsExported += int64(len(records))
+		cursor.LastFile = &relativePath
+		cursor.LastRunAt = &now
+
+		if err := s.warehouseRepo.SaveCursor(ctx, cursor); err != nil {
+			return nil, err
+		}
+
+		run.Files = append(run.Files, relativePath)
+		run.RowsExported += len(records)
+
+		if len(records) < s.batchSize {
+			break
+		}
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	logger.Info("Warehouse export completed", "rows", run.RowsExported, "files", len(run.Files))
+
+	return run, nil
+}
@@ -0,0 +1,381 @@
+// Package sso signs users in through their organization's identity provider
+// with OpenID Connect, the authorization code flow enterprise IdPs such as
+// Okta, Entra ID, Google Workspace and Keycloak all support
+package sso
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// providerTTL is how long an issuer's discovery document and signing keys are
+// cached before they are fetched again
+const providerTTL = time.Hour
+
+// ErrInvalidIDToken is returned for ID tokens that are malformed, expired,
+// not signed by the issuer, not meant for the client or without a verified
+// email
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Claims are the claims of a verified ID token the sign-in uses
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Groups are the values of the configured group claim
+	Groups []string
+}
+
+// ClientConfig identifies the backend to an issuer
+type ClientConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// GroupClaim names the ID token claim listing the user's groups
+	GroupClaim string
+}
+
+// provider is the part of an issuer's discovery document the flow uses, with
+// its signing keys
+type provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	keys                  map[string]any
+	fetchedAt             time.Time
+}
+
+// OIDC runs the authorization code flow against any number of issuers,
+// caching their discovery documents and signing keys
+type OIDC struct {
+	httpClient *http.Client
+	mu         sync.Mutex
+	providers  map[string]*provider
+}
+
+// NewOIDC creates a new OpenID Connect client
+func NewOIDC(timeout time.Duration) *OIDC {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &OIDC{
+		httpClient: &http.Client{Timeout: timeout},
+		providers:  map[string]*provider{},
+	}
+}
+
+// AuthCodeURL returns the issuer's sign-in page for the client, carrying the
+// state and nonce the callback checks
+func (o *OIDC) AuthCodeURL(ctx context.Context, cfg ClientConfig, state, nonce string) (string, error) {
+	p, err := o.provider(ctx, cfg.Issuer, false)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", cfg.ClientID)
+	query.Set("redirect_uri", cfg.RedirectURL)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+
+	separator := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified claims of
+// the ID token, which must carry nonce
+func (o *OIDC) Exchange(ctx context.Context, cfg ClientConfig, code, nonce string) (*Claims, error) {
+	p, err := o.provider(ctx, cfg.Issuer, false)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", cfg.RedirectURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := o.do(req, &token)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || token.IDToken == "" {
+		return nil, fmt.Errorf("token endpoint returned status %d: %s %s", status, token.Error, token.ErrorDescription)
+	}
+
+	return o.verify(ctx, cfg, p, token.IDToken, nonce)
+}
+
+// verify checks an ID token's signature, issuer, audience, expiry and nonce
+func (o *OIDC) verify(ctx context.Context, cfg ClientConfig, p *provider, rawToken, nonce string) (*Claims, error) {
+	keyfunc := func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if key := p.key(kid); key != nil {
+			return key, nil
+		}
+		// The issuer may have rotated its keys since they were fetched
+		refreshed, err := o.provider(ctx, cfg.Issuer, true)
+		if err != nil {
+			return nil, err
+		}
+		if key := refreshed.key(kid); key != nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	mapClaims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, mapClaims, keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if got, _ := mapClaims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+
+	claims := &Claims{}
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.Email, _ = mapClaims["email"].(string)
+	claims.Name, _ = mapClaims["name"].(string)
+	switch verified := mapClaims["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	// An unverified email could be any address the user typed in at the IdP
+	if !claims.EmailVerified {
+		return nil, fmt.Errorf("%w: email is not verified", ErrInvalidIDToken)
+	}
+
+	groupClaim := cfg.GroupClaim
+	if groupClaim == "" {
+		groupClaim = "groups"
+	}
+	switch groups := mapClaims[groupClaim].(type) {
+	case []any:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				claims.Groups = append(claims.Groups, name)
+			}
+		}
+	case string:
+		claims.Groups = []string{groups}
+	}
+
+	return claims, nil
+}
+
+// provider returns an issuer's discovery document and signing keys, from the
+// cache unless they are stale or refresh is set
+func (o *OIDC) provider(ctx context.Context, issuer string, refresh bool) (*provider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	o.mu.Lock()
+	cached, ok := o.providers[issuer]
+	o.mu.Unlock()
+	if ok && !refresh && time.Since(cached.fetchedAt) < providerTTL {
+		return cached, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+	p := &provider{}
+	status, err := o.do(req, p)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery returned status %d", status)
+	}
+	if strings.TrimSuffix(p.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document names issuer %q, expected %q", p.Issuer, issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s is incomplete", issuer)
+	}
+
+	p.keys, err = o.fetchKeys(ctx, p.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.fetchedAt = time.Now()
+
+	o.mu.Lock()
+	o.providers[issuer] = p
+	o.mu.Unlock()
+	return p, nil
+}
+
+// fetchKeys returns the RSA and EC signing keys of a JWK set by key ID
+func (o *OIDC) fetchKeys(ctx context.Context, jwksURI string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	status, err := o.do(req, &set)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned status %d", status)
+	}
+
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS at %s holds no usable signing keys", jwksURI)
+	}
+	return keys, nil
+}
+
+// do sends a request and decodes its JSON response into out
+func (o *OIDC) do(req *http.Request, out any) (int, error) {
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp.StatusCode, nil
+}
+
+// key returns the signing key with an ID. Tokens without a key ID match the
+// provider's only key.
+func (p *provider) key(kid string) any {
+	if key, ok := p.keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// jwk is a JSON Web Key holding an RSA or EC public key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the key
+func (k jwk) publicKey() (any, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
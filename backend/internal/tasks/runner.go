@@ -0,0 +1,283 @@
+// Package tasks runs background work with bounded concurrency, panic
+// recovery and duration metrics.
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"visekai/backend/pkg/logger"
+	"visekai/backend/pkg/metrics"
+)
+
+// ErrQueueFull is returned when a task is submitted to a runner whose queue is full
+var ErrQueueFull = errors.New("task queue is full")
+
+// ErrStopped is returned when a task is submitted to a stopped runner
+var ErrStopped = errors.New("task runner is stopped")
+
+// PanicError is the error reported for a task that panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+var (
+	taskDuration = metrics.NewHistogramVec(
+		"visekai_task_duration_seconds",
+		"Duration of background tasks.",
+		nil, "task",
+	)
+	taskTotal = metrics.NewCounterVec(
+		"visekai_tasks_total",
+		"Background tasks run, by outcome (ok, error, panic).",
+		"task", "outcome",
+	)
+	tasksInFlight = metrics.NewGaugeVec(
+		"visekai_tasks_in_flight",
+		"Background tasks currently running.",
+		"runner",
+	)
+	tasksQueued = metrics.NewGaugeVec(
+		"visekai_tasks_queued",
+		"Background tasks waiting for a worker.",
+		"runner",
+	)
+	tasksRejected = metrics.NewCounterVec(
+		"visekai_tasks_rejected_total",
+		"Background tasks rejected because the queue was full.",
+		"runner",
+	)
+)
+
+// Task is a unit of background work
+type Task struct {
+	// Name identifies the task in logs and metrics; keep its cardinality low
+	Name string
+	// Run performs the work
+	Run func(ctx context.Context) error
+	// OnError, if set, is called with the error returned by Run or the
+	// PanicError recovered from it, e.g. to mark a job as failed
+	OnError func(err error)
+}
+
+// Run executes a task synchronously, recovering panics and recording metrics
+func Run(ctx context.Context, task Task) (err error) {
+	started := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+
+		outcome := "ok"
+		var panicErr *PanicError
+		switch {
+		case errors.As(err, &panicErr):
+			outcome = "panic"
+			logger.Error("Background task panicked", "task", task.Name, "panic", panicErr.Value, "stack", string(panicErr.Stack))
+		case err != nil:
+			outcome = "error"
+			logger.Error("Background task failed", "task", task.Name, "error", err)
+		}
+
+		taskDuration.Observe(time.Since(started).Seconds(), task.Name)
+		taskTotal.Inc(task.Name, outcome)
+
+		if err != nil && task.OnError != nil {
+			reportErr := err
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						logger.Error("Background task error handler panicked", "task", task.Name, "panic", r)
+					}
+				}()
+				task.OnError(reportErr)
+			}()
+		}
+	}()
+
+	return task.Run(ctx)
+}
+
+// Runner executes submitted tasks on a number of workers fed by a bounded queue
+type Runner struct {
+	name    string
+	workers int
+	queue   chan Task
+	retire  chan struct{}
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.RWMutex
+	done    bool
+}
+
+// NewRunner starts a runner with the given number of workers and queue capacity
+func NewRunner(name string, workers, queueSize int) *Runner {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	r := &Runner{
+		name:    name,
+		workers: workers,
+		queue:   make(chan Task, queueSize),
+		retire:  make(chan struct{}),
+		ctx:     ctx,
+		stop:    stop,
+	}
+
+	r.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go r.work()
+	}
+
+	return r
+}
+
+// Workers returns the number of workers
+func (r *Runner) Workers() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.workers
+}
+
+// Resize changes the number of workers. Workers beyond the new number stop
+// once they finish their current task.
+func (r *Runner) Resize(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done || workers == r.workers {
+		return
+	}
+
+	if workers > r.workers {
+		r.wg.Add(workers - r.workers)
+		for i := r.workers; i < workers; i++ {
+			go r.work()
+		}
+	} else {
+		retiring := r.workers - workers
+		go func() {
+			for i := 0; i < retiring; i++ {
+				select {
+				case r.retire <- struct{}{}:
+				case <-r.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	r.workers = workers
+}
+
+// Capacity returns the number of tasks the queue holds
+func (r *Runner) Capacity() int {
+	return cap(r.queue)
+}
+
+// Queued returns the number of tasks waiting for a worker
+func (r *Runner) Queued() int {
+	return len(r.queue)
+}
+
+// Submit queues a task without blocking. It returns ErrQueueFull when every
+// worker is busy and the queue is at capacity.
+func (r *Runner) Submit(task Task) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.done {
+		return ErrStopped
+	}
+
+	// Count the task before handing it over so a fast worker never drives the gauge negative
+	tasksQueued.Inc(r.name)
+	select {
+	case r.queue <- task:
+		return nil
+	default:
+		tasksQueued.Dec(r.name)
+		tasksRejected.Inc(r.name)
+		return ErrQueueFull
+	}
+}
+
+// SubmitAfter queues a task once the delay has elapsed, unless the runner stops first
+func (r *Runner) SubmitAfter(delay time.Duration, task Task) {
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-r.ctx.Done():
+		case <-timer.C:
+			if err := r.Submit(task); err != nil {
+				logger.Warn("Failed to submit delayed task", "runner", r.name, "task", task.Name, "error", err)
+			}
+		}
+	}()
+}
+
+// Shutdown stops accepting tasks, lets queued tasks finish and waits for the
+// workers until ctx expires, at which point running tasks are cancelled
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.done {
+		r.done = true
+		close(r.queue)
+	}
+	r.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		r.stop()
+		return nil
+	case <-ctx.Done():
+		r.stop()
+		return ctx.Err()
+	}
+}
+
+// work runs queued tasks until the queue is closed or the worker is retired
+func (r *Runner) work() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.retire:
+			return
+		case task, ok := <-r.queue:
+			if !ok {
+				return
+			}
+			tasksQueued.Dec(r.name)
+			tasksInFlight.Inc(r.name)
+			_ = Run(r.ctx, task)
+			tasksInFlight.Dec(r.name)
+		}
+	}
+}
@@ -0,0 +1,472 @@
+// Package imaging turns scanned page images upright and renders previews of
+// them. Orientation comes from the EXIF tag cameras record and, for scans
+// without one, from the layout of the text on the page.
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF decoder
+	"image/jpeg"
+	"image/png"
+)
+
+// ErrUnsupportedImage is returned for images that cannot be decoded
+var ErrUnsupportedImage = errors.New("unsupported image format")
+
+// Orientation is how a page image is turned upright: the EXIF orientation
+// recorded in the file is applied first, then the clockwise rotation found
+// from the text on the page
+type Orientation struct {
+	// EXIF is the orientation tag of the file, 1 when it records none
+	EXIF int `json:"exif"`
+	// Detected is the clockwise rotation in degrees the text still needed once
+	// the EXIF orientation was applied
+	Detected int `json:"detected"`
+	// Rotation is the total clockwise rotation in degrees applied to the image
+	Rotation int `json:"rotation"`
+	// Mirrored is set when the EXIF orientation also flips the image
+	Mirrored bool `json:"mirrored,omitempty"`
+}
+
+// Upright reports whether the image needs no correction
+func (o Orientation) Upright() bool {
+	return (o.EXIF <= 1 || o.EXIF > 8) && o.Detected == 0
+}
+
+// Apply returns the image turned upright
+func (o Orientation) Apply(img image.Image) image.Image {
+	if o.EXIF > 1 && o.EXIF <= 8 {
+		img = transform(img, o.EXIF)
+	}
+	if o.Detected != 0 {
+		img = transform(img, rotationOrientation(o.Detected))
+	}
+	return img
+}
+
+// Decoded is a page image as stored and its orientation
+type Decoded struct {
+	Image       image.Image
+	Format      string
+	Orientation Orientation
+}
+
+// Decode decodes a JPEG, PNG or GIF page image and reads its EXIF orientation.
+// The content is only analyzed by Detect.
+func Decode(data []byte) (*Decoded, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	exif := 1
+	if format == "jpeg" {
+		exif = exifOrientation(data)
+	}
+
+	return &Decoded{
+		Image:  img,
+		Format: format,
+		Orientation: Orientation{
+			EXIF:     exif,
+			Rotation: orientationRotation(exif),
+			Mirrored: exif == 2 || exif == 4 || exif == 5 || exif == 7,
+		},
+	}, nil
+}
+
+// Detect finds how far the text of the image, once its EXIF orientation is
+// applied, is rotated from upright, and records it in the image's orientation
+func (d *Decoded) Detect() {
+	upright := d.Orientation
+	upright.Detected = 0
+	if rotation, ok := detectRotation(upright.Apply(d.Image)); ok {
+		d.Orientation.Detected = rotation
+	}
+	d.Orientation.Rotation = (orientationRotation(d.Orientation.EXIF) + d.Orientation.Detected) % 360
+}
+
+// Encode encodes an image in the format it was stored in where possible:
+// JPEG stays JPEG and everything else becomes PNG. It returns the encoded
+// image and its file extension.
+func Encode(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 92}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), ".jpg", nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), ".png", nil
+}
+
+// rotationOrientation returns the EXIF orientation of a clockwise rotation
+func rotationOrientation(degrees int) int {
+	switch degrees {
+	case 90:
+		return 6
+	case 180:
+		return 3
+	case 270:
+		return 8
+	}
+	return 1
+}
+
+// orientationRotation returns the clockwise rotation in degrees of an EXIF
+// orientation, ignoring any flip
+func orientationRotation(orientation int) int {
+	switch orientation {
+	case 3, 4:
+		return 180
+	case 5, 6:
+		return 90
+	case 7, 8:
+		return 270
+	}
+	return 0
+}
+
+// transform applies an EXIF orientation to an image
+func transform(img image.Image, orientation int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	ow, oh := w, h
+	if orientation >= 5 {
+		ow, oh = h, w
+	}
+
+	// Returns the source pixel of each output pixel
+	var source func(x, y int) (int, int)
+	switch orientation {
+	case 2:
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3:
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4:
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5:
+		source = func(x, y int) (int, int) { return y, x }
+	case 6:
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7:
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8:
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	default:
+		return img
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, ow, oh))
+	for y := 0; y < oh; y++ {
+		for x := 0; x < ow; x++ {
+			sx, sy := source(x, y)
+			out.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return out
+}
+
+// exifOrientation returns the orientation tag of a JPEG's EXIF segment, or 1
+// when it has none
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Segments follow the SOI marker until the image data starts
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		if marker == 0xE1 {
+			if orientation, ok := tiffOrientation(data[i+4 : i+2+length]); ok {
+				return orientation
+			}
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of an EXIF
+// APP1 segment
+func tiffOrientation(segment []byte) (int, bool) {
+	if len(segment) < 14 || string(segment[:6]) != "Exif\x00\x00" {
+		return 0, false
+	}
+	tiff := segment[6:]
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 0, false
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 0, false
+			}
+			return orientation, true
+		}
+	}
+	return 0, false
+}
+
+// maxDetectionSide bounds the image size text orientation is analyzed at;
+// larger images are sampled down first
+const maxDetectionSide = 800
+
+// inkMap is a binarized page image: true where there is ink
+type inkMap struct {
+	w, h int
+	ink  []bool
+}
+
+// detectRotation finds the clockwise rotation in degrees that turns the text
+// of an image upright. Lines of text show as alternating bands of ink and
+// blank in the profile across them, which tells whether the page is sideways;
+// letters reaching above the line outnumber those reaching below it in Latin
+// text, which tells which way up it is. It returns false for pages with too
+// little text to tell.
+func detectRotation(img image.Image) (int, bool) {
+	page := binarize(img)
+	if page == nil {
+		return 0, false
+	}
+
+	rows, cols := page.rowProfile(), page.transpose().rowProfile()
+	rowContrast, colContrast := contrast(rows), contrast(cols)
+	switch {
+	case rowContrast > 1.2*colContrast:
+		// Lines run across the page: upright or upside down
+		up, ok := ascending(rows, page.w, false)
+		if !ok {
+			return 0, false
+		}
+		if up {
+			return 0, true
+		}
+		return 180, true
+	case colContrast > 1.2*rowContrast:
+		// Lines run down the page, which is turned a quarter either way: the
+		// top of the text facing right needs turning back anticlockwise
+		up, ok := ascending(cols, page.h, true)
+		if !ok {
+			return 0, false
+		}
+		if up {
+			return 270, true
+		}
+		return 90, true
+	}
+	return 0, false
+}
+
+// binarize samples an image down and separates ink from paper with Otsu's
+// threshold. It returns nil for images that are too small or nearly blank.
+func binarize(img image.Image) *inkMap {
+	bounds := img.Bounds()
+	step := max(1, (max(bounds.Dx(), bounds.Dy())+maxDetectionSide-1)/maxDetectionSide)
+	w, h := bounds.Dx()/step, bounds.Dy()/step
+	if w < 32 || h < 32 {
+		return nil
+	}
+
+	gray := make([]uint8, w*h)
+	var histogram [256]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.GrayModel.Convert(img.At(bounds.Min.X+x*step, bounds.Min.Y+y*step)).(color.Gray)
+			gray[y*w+x] = c.Y
+			histogram[c.Y]++
+		}
+	}
+
+	threshold := otsu(histogram, w*h)
+	page := &inkMap{w: w, h: h, ink: make([]bool, w*h)}
+	inked := 0
+	for i, g := range gray {
+		if g < threshold {
+			page.ink[i] = true
+			inked++
+		}
+	}
+
+	// Blank pages and photos have no text layout to read
+	share := float64(inked) / float64(w*h)
+	if share < 0.005 || share > 0.4 {
+		return nil
+	}
+	return page
+}
+
+// otsu returns the gray level that best separates the two classes of pixels
+func otsu(histogram [256]int, total int) uint8 {
+	var sum float64
+	for level, count := range histogram {
+		sum += float64(level * count)
+	}
+
+	var sumBackground, best float64
+	var weightBackground int
+	threshold := uint8(128)
+	for level, count := range histogram {
+		weightBackground += count
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+		sumBackground += float64(level * count)
+		meanBackground := sumBackground / float64(weightBackground)
+		meanForeground := (sum - sumBackground) / float64(weightForeground)
+		between := float64(weightBackground) * float64(weightForeground) * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if between > best {
+			best = between
+			threshold = uint8(level + 1)
+		}
+	}
+	return threshold
+}
+
+// transpose swaps the rows and columns of the map
+func (m *inkMap) transpose() *inkMap {
+	t := &inkMap{w: m.h, h: m.w, ink: make([]bool, len(m.ink))}
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			t.ink[x*t.w+y] = m.ink[y*m.w+x]
+		}
+	}
+	return t
+}
+
+// rowProfile returns the ink of each row
+func (m *inkMap) rowProfile() []int {
+	profile := make([]int, m.h)
+	for y := 0; y < m.h; y++ {
+		for x := 0; x < m.w; x++ {
+			if m.ink[y*m.w+x] {
+				profile[y]++
+			}
+		}
+	}
+	return profile
+}
+
+// contrast returns the mean squared change between neighbouring values of a
+// profile relative to its mean, which is high where bands of ink alternate
+// with blank lines
+func contrast(profile []int) float64 {
+	var sum, changes float64
+	for i, value := range profile {
+		sum += float64(value)
+		if i > 0 {
+			d := float64(value - profile[i-1])
+			changes += d * d
+		}
+	}
+	if sum == 0 || len(profile) < 2 {
+		return 0
+	}
+	mean := sum / float64(len(profile))
+	return changes / float64(len(profile)-1) / (mean * mean)
+}
+
+// ascending reports whether the text lines of a profile have more ink above
+// their body than below it, as upright Latin text does. Each line's body is
+// the rows inked at least half as much as its densest row, and lines are
+// lineLength pixels long. With reversed set the profile's last row is the top
+// of the text. It returns false when fewer than three lines are found or
+// neither side clearly has more ink.
+func ascending(profile []int, lineLength int, reversed bool) (bool, bool) {
+	peak := 0
+	for _, value := range profile {
+		peak = max(peak, value)
+	}
+	floor := max(1, peak/20)
+
+	var above, below, lines int
+	for start := 0; start < len(profile); {
+		if profile[start] < floor {
+			start++
+			continue
+		}
+		end := start
+		for end < len(profile) && profile[end] >= floor {
+			end++
+		}
+
+		// Lines of a few pixels are rules and specks rather than text
+		if end-start >= 4 {
+			linePeak := 0
+			for _, value := range profile[start:end] {
+				linePeak = max(linePeak, value)
+			}
+			bodyStart, bodyEnd := -1, -1
+			for i := start; i < end; i++ {
+				if profile[i]*2 >= linePeak {
+					if bodyStart < 0 {
+						bodyStart = i
+					}
+					bodyEnd = i
+				}
+			}
+			for i := start; i < bodyStart; i++ {
+				above += profile[i]
+			}
+			for i := bodyEnd + 1; i < end; i++ {
+				below += profile[i]
+			}
+			lines++
+		}
+		start = end
+	}
+
+	if reversed {
+		above, below = below, above
+	}
+	if lines < 3 || above+below < lineLength/4 {
+		return false, false
+	}
+	switch {
+	case float64(above) > 1.25*float64(below):
+		return true, true
+	case float64(below) > 1.25*float64(above):
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,73 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+)
+
+// Fit scales an image down so that neither side is longer than maxSide,
+// averaging the pixels each output pixel covers. Smaller images are returned
+// as they are.
+func Fit(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if maxSide <= 0 || (w <= maxSide && h <= maxSide) {
+		return img
+	}
+
+	ow, oh := maxSide, maxSide
+	if w >= h {
+		oh = max(1, h*maxSide/w)
+	} else {
+		ow = max(1, w*maxSide/h)
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, ow, oh))
+	for y := 0; y < oh; y++ {
+		y0, y1 := y*h/oh, max((y+1)*h/oh, y*h/oh+1)
+		for x := 0; x < ow; x++ {
+			x0, x1 := x*w/ow, max((x+1)*w/ow, x*w/ow+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+					r, g, b, a = r+pr, g+pg, b+pb, a+pa
+					n++
+				}
+			}
+			out.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return out
+}
+
+// Crop returns the part of an image inside r, given relative to the image's
+// top-left corner and clamped to its bounds. It returns false when nothing of
+// the image is inside r.
+func Crop(img image.Image, r image.Rectangle) (image.Image, bool) {
+	bounds := img.Bounds()
+	r = r.Add(bounds.Min).Intersect(bounds)
+	if r.Empty() {
+		return nil, false
+	}
+
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r), true
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			out.Set(x, y, img.At(r.Min.X+x, r.Min.Y+y))
+		}
+	}
+	return out, true
+}
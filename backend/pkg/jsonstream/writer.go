@@ -0,0 +1,180 @@
+// Package jsonstream writes JSON documents incrementally so large string
+// values are escaped and flushed in chunks instead of being marshalled into
+// a single in-memory buffer.
+package jsonstream
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// bufferSize is the size of the chunks written to the underlying writer
+const bufferSize = 32 * 1024
+
+const hex = "0123456789abcdef"
+
+// Writer writes a JSON document piece by piece. The first error is retained
+// and returned by Flush; later calls become no-ops.
+type Writer struct {
+	bw     *bufio.Writer
+	indent string
+	// first records, per open object, whether the next key is the first one
+	first []bool
+	err   error
+}
+
+// NewWriter creates a writer producing compact JSON
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{bw: bufio.NewWriterSize(w, bufferSize)}
+}
+
+// NewIndentWriter creates a writer producing JSON indented like json.MarshalIndent with an empty prefix
+func NewIndentWriter(w io.Writer, indent string) *Writer {
+	return &Writer{bw: bufio.NewWriterSize(w, bufferSize), indent: indent}
+}
+
+// BeginObject opens a JSON object
+func (w *Writer) BeginObject() {
+	w.raw("{")
+	w.first = append(w.first, true)
+}
+
+// EndObject closes the innermost JSON object
+func (w *Writer) EndObject() {
+	empty := w.first[len(w.first)-1]
+	w.first = w.first[:len(w.first)-1]
+	if !empty {
+		w.newline()
+	}
+	w.raw("}")
+}
+
+// Key writes an object key; it must be followed by exactly one value
+func (w *Writer) Key(name string) {
+	if !w.first[len(w.first)-1] {
+		w.raw(",")
+	}
+	w.first[len(w.first)-1] = false
+	w.newline()
+	w.String(name)
+	if w.indent != "" {
+		w.raw(": ")
+	} else {
+		w.raw(":")
+	}
+}
+
+// Value writes a value using encoding/json. It is meant for small values;
+// use String for large text.
+func (w *Writer) Value(v any) {
+	if w.err != nil {
+		return
+	}
+
+	var data []byte
+	if w.indent != "" {
+		data, w.err = json.MarshalIndent(v, strings.Repeat(w.indent, len(w.first)), w.indent)
+	} else {
+		data, w.err = json.Marshal(v)
+	}
+	if w.err == nil {
+		_, w.err = w.bw.Write(data)
+	}
+}
+
+// String writes s as a JSON string, escaping it the same way encoding/json does
+func (w *Writer) String(s string) {
+	w.raw(`"`)
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			w.raw(s[start:i])
+			switch b {
+			case '"':
+				w.raw(`\"`)
+			case '\\':
+				w.raw(`\\`)
+			case '\b':
+				w.raw(`\b`)
+			case '\f':
+				w.raw(`\f`)
+			case '\n':
+				w.raw(`\n`)
+			case '\r':
+				w.raw(`\r`)
+			case '\t':
+				w.raw(`\t`)
+			default:
+				// Control characters and the HTML-sensitive <, > and &
+				w.raw(`\u00`)
+				w.byte(hex[b>>4])
+				w.byte(hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			w.raw(s[start:i])
+			w.raw("\ufffd")
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but break JavaScript parsers
+		if r == '\u2028' || r == '\u2029' {
+			w.raw(s[start:i])
+			w.raw(`\u202`)
+			w.byte(hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	w.raw(s[start:])
+
+	w.raw(`"`)
+}
+
+// Flush writes any buffered data and returns the first error encountered
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.bw.Flush()
+}
+
+// newline starts a new indented line in indent mode
+func (w *Writer) newline() {
+	if w.indent == "" {
+		return
+	}
+	w.raw("\n" + strings.Repeat(w.indent, len(w.first)))
+}
+
+// raw writes s unmodified
+func (w *Writer) raw(s string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.bw.WriteString(s)
+}
+
+// byte writes a single byte unmodified
+func (w *Writer) byte(b byte) {
+	if w.err != nil {
+		return
+	}
+	w.err = w.bw.WriteByte(b)
+}
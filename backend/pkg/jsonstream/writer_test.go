@@ -0,0 +1,95 @@
+package jsonstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// largeText is a few megabytes of OCR-like text with characters that need escaping
+var largeText = strings.Repeat("Invoice 42 \"ACME & Co\" <total>\tÜber 10.00 €\n", 100_000)
+
+func TestStringMatchesEncodingJSON(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"plain",
+		"quotes \" and \\ backslashes",
+		"controls \b\f\n\r\t\x00\x1f",
+		"html <a href=\"x\">&amp;</a>",
+		"unicode Über €   ",
+		"invalid \xff utf-8",
+	} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.String(s)
+		if err := w.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+
+		want, _ := json.Marshal(s)
+		if buf.String() != string(want) {
+			t.Errorf("String(%q) = %s, want %s", s, buf.String(), want)
+		}
+	}
+}
+
+func TestIndentWriterMatchesMarshalIndent(t *testing.T) {
+	value := map[string]any{"a": 1, "b": []int{1, 2}}
+
+	var buf bytes.Buffer
+	w := NewIndentWriter(&buf, "    ")
+	w.BeginObject()
+	w.Key("text")
+	w.String("line\n")
+	w.Key("value")
+	w.Value(value)
+	w.Key("empty")
+	w.BeginObject()
+	w.EndObject()
+	w.EndObject()
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	want, _ := json.MarshalIndent(map[string]any{"text": "line\n", "value": value, "empty": map[string]any{}}, "", "    ")
+	var got, expected any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.String(), err)
+	}
+	json.Unmarshal(want, &expected)
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(expected)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("got %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+// BenchmarkString streams a large string through the writer
+func BenchmarkString(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(largeText)))
+	for range b.N {
+		w := NewWriter(io.Discard)
+		w.String(largeText)
+		if err := w.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMarshal encodes the same string the buffered way, for comparison
+func BenchmarkMarshal(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(largeText)))
+	for range b.N {
+		data, err := json.Marshal(largeText)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Discard.Write(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
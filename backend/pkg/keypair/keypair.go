@@ -0,0 +1,75 @@
+// Package keypair serves a TLS certificate from PEM files, loaded again when
+// they change, so certificates renewed on disk by a tool such as certbot or
+// cert-manager are picked up by new connections without a restart.
+package keypair
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeyPair is the certificate of a certificate file and a key file
+type KeyPair struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	loadedAt time.Time
+}
+
+// Load loads the certificate of the files
+func Load(certFile, keyFile string) (*KeyPair, error) {
+	k := &KeyPair{certFile: certFile, keyFile: keyFile}
+	if _, err := k.Certificate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Certificate returns the certificate, loading it again when a file was
+// modified since it was loaded. A failed reload keeps the previous
+// certificate, as the files may be caught halfway through being replaced.
+func (k *KeyPair) Certificate() (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.cert != nil && !k.modified() {
+		return k.cert, nil
+	}
+
+	loadedAt := time.Now()
+	cert, err := tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	if err != nil {
+		if k.cert != nil {
+			return k.cert, nil
+		}
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	k.cert, k.loadedAt = &cert, loadedAt
+	return k.cert, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (k *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return k.Certificate()
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate
+func (k *KeyPair) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return k.Certificate()
+}
+
+// modified reports whether either file changed since the certificate was
+// loaded
+func (k *KeyPair) modified() bool {
+	for _, path := range []string{k.certFile, k.keyFile} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(k.loadedAt) {
+			return true
+		}
+	}
+	return false
+}
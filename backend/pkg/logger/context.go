@@ -0,0 +1,66 @@
+package logger
+
+import "context"
+
+type contextKey struct{}
+
+// Logger logs messages with the fields carried by a context, such as the
+// request ID and user ID of the request being served
+type Logger struct {
+	fields []interface{}
+}
+
+// WithContext returns a copy of ctx whose logger adds the key-value pairs to
+// every message, after the fields ctx already carries
+func WithContext(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	parent, _ := ctx.Value(contextKey{}).([]interface{})
+	fields := make([]interface{}, 0, len(parent)+len(keysAndValues))
+	fields = append(fields, parent...)
+	fields = append(fields, keysAndValues...)
+	return context.WithValue(ctx, contextKey{}, fields)
+}
+
+// FromContext returns a logger adding the fields carried by ctx to every
+// message. A context without fields logs like the package functions.
+func FromContext(ctx context.Context) *Logger {
+	fields, _ := ctx.Value(contextKey{}).([]interface{})
+	return &Logger{fields: fields}
+}
+
+// with returns the context's fields followed by the message's
+func (l *Logger) with(keysAndValues []interface{}) []interface{} {
+	if len(l.fields) == 0 {
+		return keysAndValues
+	}
+	fields := make([]interface{}, 0, len(l.fields)+len(keysAndValues))
+	fields = append(fields, l.fields...)
+	return append(fields, keysAndValues...)
+}
+
+// Info logs info level messages
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	if logger != nil {
+		logger.Infow(msg, l.with(keysAndValues)...)
+	}
+}
+
+// Debug logs debug level messages
+func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
+	if logger != nil {
+		logger.Debugw(msg, l.with(keysAndValues)...)
+	}
+}
+
+// Warn logs warning level messages
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	if logger != nil {
+		logger.Warnw(msg, l.with(keysAndValues)...)
+	}
+}
+
+// Error logs error level messages
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	if logger != nil {
+		logger.Errorw(msg, l.with(keysAndValues)...)
+	}
+}
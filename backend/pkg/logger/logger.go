@@ -1,43 +1,156 @@
 package logger
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var logger *zap.SugaredLogger
+var (
+	logger *zap.SugaredLogger
+	// level is the least level logged, which SetLevel changes while logging
+	level = zap.NewAtomicLevel()
+)
+
+// Outputs messages can be written to
+const (
+	OutputStdout = "stdout"
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+	OutputLoki   = "loki"
+)
+
+// Config selects the level, format and outputs of the logger
+type Config struct {
+	// Level is the least level logged: debug, info, warn or error
+	Level string
+	// Format is json, or console for human-readable lines on stdout; the
+	// other outputs always get JSON
+	Format string
+	// Outputs lists where messages are written; stdout when empty
+	Outputs []string
+
+	// File is written with OutputFile
+	File FileConfig
 
-// Init initializes the logger
+	// SyslogNetwork and SyslogAddress locate the syslog daemon, the local
+	// one when empty; SyslogTag names the program in its messages
+	SyslogNetwork string
+	SyslogAddress string
+	SyslogTag     string
+
+	// Loki is pushed to with OutputLoki
+	Loki LokiConfig
+}
+
+// Init initializes the logger writing to stdout at a level
 func Init(level string) {
-	var zapLevel zapcore.Level
-	switch level {
+	if err := Setup(Config{Level: level}); err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+}
+
+// Setup initializes the logger with its outputs. Outputs already open, such
+// as a Loki pusher of an earlier setup, are not closed; call Sync first.
+func Setup(cfg Config) error {
+	level.SetLevel(parseLevel(cfg.Level))
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	jsonEncoder := zapcore.NewJSONEncoder(encoderConfig)
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{OutputStdout}
+	}
+
+	cores := make([]zapcore.Core, 0, len(outputs))
+	for _, output := range outputs {
+		switch output {
+		case OutputStdout:
+			encoder := jsonEncoder
+			switch cfg.Format {
+			case "", "json":
+			case "console":
+				consoleConfig := zap.NewDevelopmentEncoderConfig()
+				consoleConfig.EncodeTime = zapcore.TimeEncoderOfLayout(time.DateTime + ".000")
+				consoleConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+				encoder = zapcore.NewConsoleEncoder(consoleConfig)
+			default:
+				return fmt.Errorf("log format %q is not supported (supported: json, console)", cfg.Format)
+			}
+			cores = append(cores, zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), level))
+
+		case OutputFile:
+			file, err := newRotatingFile(cfg.File)
+			if err != nil {
+				return err
+			}
+			cores = append(cores, zapcore.NewCore(jsonEncoder.Clone(), file, level))
+
+		case OutputSyslog:
+			core, err := newSyslogCore(jsonEncoder.Clone(), level, cfg.SyslogNetwork, cfg.SyslogAddress, cfg.SyslogTag)
+			if err != nil {
+				return err
+			}
+			cores = append(cores, core)
+
+		case OutputLoki:
+			pusher, err := newLokiPusher(cfg.Loki)
+			if err != nil {
+				return err
+			}
+			cores = append(cores, zapcore.NewCore(jsonEncoder.Clone(), pusher, level))
+
+		default:
+			return fmt.Errorf("log output %q is not supported (supported: stdout, file, syslog, loki)", output)
+		}
+	}
+
+	// Sample and annotate messages as zap's production config does, naming the
+	// caller of this package's functions
+	core := zapcore.NewSamplerWithOptions(zapcore.NewTee(cores...), time.Second, 100, 100)
+	l := zap.New(core,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	)
+	logger = l.Sugar()
+	return nil
+}
+
+// SetLevel changes the least level logged: debug, info, warn or error
+func SetLevel(l string) {
+	level.SetLevel(parseLevel(l))
+}
+
+// parseLevel returns the level named l, info for unknown names
+func parseLevel(l string) zapcore.Level {
+	switch l {
 	case "debug":
-		zapLevel = zapcore.DebugLevel
+		return zapcore.DebugLevel
 	case "info":
-		zapLevel = zapcore.InfoLevel
+		return zapcore.InfoLevel
 	case "warn":
-		zapLevel = zapcore.WarnLevel
+		return zapcore.WarnLevel
 	case "error":
-		zapLevel = zapcore.ErrorLevel
+		return zapcore.ErrorLevel
 	default:
-		zapLevel = zapcore.InfoLevel
+		return zapcore.InfoLevel
 	}
+}
 
-	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
-	config.EncoderConfig.TimeKey = "timestamp"
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-
-	l, err := config.Build()
-	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
-		os.Exit(1)
+// Sync flushes buffered messages, such as those waiting to be pushed to Loki
+func Sync() {
+	if logger != nil {
+		_ = logger.Sync()
 	}
-
-	logger = l.Sugar()
 }
 
 // Info logs info level messages
@@ -0,0 +1,186 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lokiPushPath is the path of Loki's push API
+	lokiPushPath = "/loki/api/v1/push"
+	// lokiMaxPending is the number of lines kept while Loki is unreachable;
+	// the oldest are dropped beyond it
+	lokiMaxPending = 10000
+)
+
+// LokiConfig sets where and how messages are pushed to Loki
+type LokiConfig struct {
+	// URL is the Loki server, with or without the push API path
+	URL string
+	// Labels are the stream labels of every message; keep their cardinality low
+	Labels map[string]string
+	// TenantID is sent as X-Scope-OrgID to multi-tenant Loki
+	TenantID string
+	// Username and Password authenticate with basic auth when set
+	Username string
+	Password string
+	// BatchWait is the longest a message waits to be pushed
+	BatchWait time.Duration
+	// BatchSize is the number of messages pushed as soon as they are written
+	BatchSize int
+}
+
+// lokiPusher collects messages and pushes them to Loki in batches, in the
+// background and on Sync. Messages that cannot be pushed are kept for the
+// next push, up to lokiMaxPending.
+type lokiPusher struct {
+	cfg    LokiConfig
+	url    string
+	client *http.Client
+	flush  chan struct{}
+
+	mu      sync.Mutex
+	pending [][2]string
+	pushing sync.Mutex
+}
+
+// newLokiPusher starts pushing to Loki
+func newLokiPusher(cfg LokiConfig) (*lokiPusher, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("Loki URL is required")
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if len(cfg.Labels) == 0 {
+		cfg.Labels = map[string]string{"service": "visekai-backend"}
+	}
+
+	url := strings.TrimSuffix(cfg.URL, "/")
+	if !strings.HasSuffix(url, lokiPushPath) {
+		url += lokiPushPath
+	}
+
+	p := &lokiPusher{
+		cfg:    cfg,
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		flush:  make(chan struct{}, 1),
+	}
+	go p.run()
+	return p, nil
+}
+
+// Write queues a message, stamped with the time it was written
+func (p *lokiPusher) Write(b []byte) (int, error) {
+	line := strings.TrimSuffix(string(b), "\n")
+
+	p.mu.Lock()
+	p.pending = append(p.pending, [2]string{strconv.FormatInt(time.Now().UnixNano(), 10), line})
+	if len(p.pending) > lokiMaxPending {
+		p.pending = p.pending[len(p.pending)-lokiMaxPending:]
+	}
+	full := len(p.pending) >= p.cfg.BatchSize
+	p.mu.Unlock()
+
+	if full {
+		select {
+		case p.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(b), nil
+}
+
+// Sync pushes the queued messages
+func (p *lokiPusher) Sync() error {
+	return p.push()
+}
+
+// run pushes the queued messages every BatchWait, and when a batch is full
+func (p *lokiPusher) run() {
+	ticker := time.NewTicker(p.cfg.BatchWait)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.flush:
+		}
+		if err := p.push(); err != nil {
+			// Logging the failure would only queue more messages
+			fmt.Fprintf(os.Stderr, "Failed to push logs to Loki: %v\n", err)
+		}
+	}
+}
+
+// push sends the queued messages to Loki, requeuing them when it fails
+func (p *lokiPusher) push() error {
+	p.pushing.Lock()
+	defer p.pushing.Unlock()
+
+	p.mu.Lock()
+	batch := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := p.send(batch); err != nil {
+		p.mu.Lock()
+		p.pending = append(batch, p.pending...)
+		if len(p.pending) > lokiMaxPending {
+			p.pending = p.pending[len(p.pending)-lokiMaxPending:]
+		}
+		p.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// send posts a batch as one stream
+func (p *lokiPusher) send(batch [][2]string) error {
+	body, err := json.Marshal(map[string]any{
+		"streams": []map[string]any{{
+			"stream": p.cfg.Labels,
+			"values": batch,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", p.cfg.TenantID)
+	}
+	if p.cfg.Username != "" {
+		req.SetBasicAuth(p.cfg.Username, p.cfg.Password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Loki responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
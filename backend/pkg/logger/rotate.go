@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileConfig sets where the log file is written and how it is rotated
+type FileConfig struct {
+	// Path is the file written to
+	Path string
+	// MaxSizeMB is the size at which the file is rotated
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files kept; 0 keeps all
+	MaxBackups int
+	// MaxAge is how long rotated files are kept, rounded up to whole days; 0
+	// keeps them regardless of age
+	MaxAge time.Duration
+}
+
+// newRotatingFile returns a writer to the log file, which lumberjack renames
+// with the time as a suffix once it reaches its maximum size and replaces by a
+// new one, removing rotated files beyond the maximum count or age. The file is
+// opened up front so that an unwritable path fails at startup.
+func newRotatingFile(cfg FileConfig) (zapcore.WriteSyncer, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("log file path is required")
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = 100
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	file.Close()
+
+	maxAgeDays := 0
+	if cfg.MaxAge > 0 {
+		maxAgeDays = int((cfg.MaxAge + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+
+	return zapcore.AddSync(&lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     maxAgeDays,
+	}), nil
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package logger
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// newSyslogCore is not supported on this platform
+func newSyslogCore(encoder zapcore.Encoder, level zapcore.LevelEnabler, network, address, tag string) (zapcore.Core, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
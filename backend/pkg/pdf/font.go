@@ -0,0 +1,345 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// The text layer is set in a glyphless TrueType font: every character maps to
+// an empty glyph half an em wide, so any script can be written and the
+// ToUnicode map makes it searchable and copyable
+const (
+	fontName     = "GlyphLessFont"
+	unitsPerEm   = 1000
+	glyphAdvance = 500
+	fontAscent   = 800
+	fontDescent  = -200
+)
+
+// glyphlessFont is the TrueType program embedded in every searchable PDF
+var glyphlessFont = buildGlyphlessFont()
+
+// textFont assigns each character written in the text layer a CID, which is
+// the two-byte code the text is shown with
+type textFont struct {
+	cids map[rune]uint16
+}
+
+func newTextFont() *textFont {
+	return &textFont{cids: make(map[rune]uint16)}
+}
+
+// encode returns text as a hex string of CIDs, dropping control characters
+func (f *textFont) encode(text string) (string, int) {
+	var b bytes.Buffer
+	n := 0
+	for _, r := range text {
+		if r == '\t' {
+			r = ' '
+		}
+		if r < 0x20 || r == 0x7f {
+			continue
+		}
+		cid, ok := f.cids[r]
+		if !ok {
+			if len(f.cids) >= 0xfffe {
+				continue
+			}
+			cid = uint16(len(f.cids) + 1)
+			f.cids[r] = cid
+		}
+		fmt.Fprintf(&b, "%04X", cid)
+		n++
+	}
+	return "<" + b.String() + ">", n
+}
+
+// addTo adds the font to a document and returns its Type0 font dictionary
+func (f *textFont) addTo(xRefTable *model.XRefTable) (*types.IndirectRef, error) {
+	program, err := newStream(xRefTable, glyphlessFont)
+	if err != nil {
+		return nil, err
+	}
+	program.InsertInt("Length1", len(glyphlessFont))
+	programRef, err := xRefTable.IndRefForNewObject(*program)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptorRef, err := xRefTable.IndRefForNewObject(types.Dict{
+		"Type":        types.Name("FontDescriptor"),
+		"FontName":    types.Name(fontName),
+		"Flags":       types.Integer(5),
+		"FontBBox":    types.Array{types.Integer(0), types.Integer(fontDescent), types.Integer(glyphAdvance), types.Integer(fontAscent)},
+		"ItalicAngle": types.Integer(0),
+		"Ascent":      types.Integer(fontAscent),
+		"Descent":     types.Integer(fontDescent),
+		"CapHeight":   types.Integer(fontAscent),
+		"StemV":       types.Integer(80),
+		"FontFile2":   *programRef,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every CID but 0 shows glyph 1
+	cidToGID := make([]byte, 2*(len(f.cids)+1))
+	for cid := 1; cid <= len(f.cids); cid++ {
+		binary.BigEndian.PutUint16(cidToGID[2*cid:], 1)
+	}
+	cidToGIDStream, err := newStream(xRefTable, cidToGID)
+	if err != nil {
+		return nil, err
+	}
+	cidToGIDRef, err := xRefTable.IndRefForNewObject(*cidToGIDStream)
+	if err != nil {
+		return nil, err
+	}
+
+	cidFontRef, err := xRefTable.IndRefForNewObject(types.Dict{
+		"Type":     types.Name("Font"),
+		"Subtype":  types.Name("CIDFontType2"),
+		"BaseFont": types.Name(fontName),
+		"CIDSystemInfo": types.Dict{
+			"Registry":   types.StringLiteral("Adobe"),
+			"Ordering":   types.StringLiteral("Identity"),
+			"Supplement": types.Integer(0),
+		},
+		"FontDescriptor": *descriptorRef,
+		"DW":             types.Integer(glyphAdvance),
+		"CIDToGIDMap":    *cidToGIDRef,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	toUnicode, err := newStream(xRefTable, f.toUnicode())
+	if err != nil {
+		return nil, err
+	}
+	toUnicodeRef, err := xRefTable.IndRefForNewObject(*toUnicode)
+	if err != nil {
+		return nil, err
+	}
+
+	return xRefTable.IndRefForNewObject(types.Dict{
+		"Type":            types.Name("Font"),
+		"Subtype":         types.Name("Type0"),
+		"BaseFont":        types.Name(fontName),
+		"Encoding":        types.Name("Identity-H"),
+		"DescendantFonts": types.Array{*cidFontRef},
+		"ToUnicode":       *toUnicodeRef,
+	})
+}
+
+// toUnicode writes the CMap mapping each CID back to its character
+func (f *textFont) toUnicode() []byte {
+	runes := make([]rune, 0, len(f.cids))
+	for r := range f.cids {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return f.cids[runes[i]] < f.cids[runes[j]] })
+
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// A bfchar block holds at most 100 mappings
+	for start := 0; start < len(runes); start += 100 {
+		block := runes[start:min(start+100, len(runes))]
+		fmt.Fprintf(&b, "%d beginbfchar\n", len(block))
+		for _, r := range block {
+			fmt.Fprintf(&b, "<%04X> <", f.cids[r])
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMapResource defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+// newStream returns a compressed stream of data
+func newStream(xRefTable *model.XRefTable, data []byte) (*types.StreamDict, error) {
+	sd, err := xRefTable.NewStreamDictForBuf(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := sd.Encode(); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+	return sd, nil
+}
+
+// buildGlyphlessFont assembles a TrueType font of two empty glyphs, .notdef
+// and the one every character is shown with
+func buildGlyphlessFont() []byte {
+	be := binary.BigEndian
+	// Negative metrics are stored in two's complement
+	descent, underline := int16(fontDescent), int16(-100)
+
+	head := make([]byte, 54)
+	be.PutUint32(head[0:], 0x00010000)  // version
+	be.PutUint32(head[4:], 0x00010000)  // fontRevision
+	be.PutUint32(head[12:], 0x5F0F3CF5) // magicNumber
+	be.PutUint16(head[16:], 0x000B)     // flags
+	be.PutUint16(head[18:], unitsPerEm)
+	be.PutUint16(head[38:], uint16(descent))      // yMin
+	be.PutUint16(head[40:], uint16(glyphAdvance)) // xMax
+	be.PutUint16(head[42:], uint16(fontAscent))   // yMax
+	be.PutUint16(head[46:], 8)                    // lowestRecPPEM
+	be.PutUint16(head[48:], 2)                    // fontDirectionHint
+
+	hhea := make([]byte, 36)
+	be.PutUint32(hhea[0:], 0x00010000)
+	be.PutUint16(hhea[4:], uint16(fontAscent))
+	be.PutUint16(hhea[6:], uint16(descent))
+	be.PutUint16(hhea[10:], glyphAdvance) // advanceWidthMax
+	be.PutUint16(hhea[18:], 1)            // caretSlopeRise
+	be.PutUint16(hhea[34:], 2)            // numberOfHMetrics
+
+	maxp := make([]byte, 32)
+	be.PutUint32(maxp[0:], 0x00010000)
+	be.PutUint16(maxp[4:], 2)  // numGlyphs
+	be.PutUint16(maxp[14:], 2) // maxZones
+
+	os2 := make([]byte, 86)
+	be.PutUint16(os2[0:], 1)            // version
+	be.PutUint16(os2[2:], glyphAdvance) // xAvgCharWidth
+	be.PutUint16(os2[4:], 400)          // usWeightClass
+	be.PutUint16(os2[6:], 5)            // usWidthClass
+	copy(os2[58:], "NONE")              // achVendID
+	be.PutUint16(os2[62:], 0x0040)      // fsSelection: regular
+	be.PutUint16(os2[64:], 0x0020)      // usFirstCharIndex
+	be.PutUint16(os2[66:], 0xFFFF)      // usLastCharIndex
+	be.PutUint16(os2[68:], uint16(fontAscent))
+	be.PutUint16(os2[70:], uint16(descent))
+	be.PutUint16(os2[74:], uint16(fontAscent))   // usWinAscent
+	be.PutUint16(os2[76:], uint16(-fontDescent)) // usWinDescent
+	be.PutUint32(os2[78:], 1)                    // ulCodePageRange1: Latin 1
+
+	hmtx := make([]byte, 8)
+	be.PutUint16(hmtx[0:], glyphAdvance)
+	be.PutUint16(hmtx[4:], glyphAdvance)
+
+	// A Windows Unicode cmap holding only the terminating segment
+	cmap := make([]byte, 4+8+24)
+	be.PutUint16(cmap[2:], 1) // numTables
+	be.PutUint16(cmap[4:], 3) // platformID
+	be.PutUint16(cmap[6:], 1) // encodingID
+	be.PutUint32(cmap[8:], 12)
+	sub := cmap[12:]
+	be.PutUint16(sub[0:], 4)  // format
+	be.PutUint16(sub[2:], 24) // length
+	be.PutUint16(sub[6:], 2)  // segCountX2
+	be.PutUint16(sub[8:], 2)  // searchRange
+	be.PutUint16(sub[14:], 0xFFFF)
+	be.PutUint16(sub[18:], 0xFFFF)
+	be.PutUint16(sub[20:], 1) // idDelta
+
+	loca := make([]byte, 6)
+	glyf := make([]byte, 4)
+
+	post := make([]byte, 32)
+	be.PutUint32(post[0:], 0x00030000)
+	be.PutUint16(post[8:], uint16(underline)) // underlinePosition
+	be.PutUint16(post[10:], 50)               // underlineThickness
+	be.PutUint32(post[12:], 1)                // isFixedPitch
+
+	tables := map[string][]byte{
+		"OS/2": os2,
+		"cmap": cmap,
+		"glyf": glyf,
+		"head": head,
+		"hhea": hhea,
+		"hmtx": hmtx,
+		"loca": loca,
+		"maxp": maxp,
+		"name": nameTable(),
+		"post": post,
+	}
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var font bytes.Buffer
+	header := make([]byte, 12+16*len(tags))
+	be.PutUint32(header[0:], 0x00010000)
+	be.PutUint16(header[4:], uint16(len(tags)))
+	be.PutUint16(header[6:], 128) // searchRange for 10 tables
+	be.PutUint16(header[8:], 3)   // entrySelector
+	be.PutUint16(header[10:], uint16(16*len(tags)-128))
+	offset := len(header)
+	headOffset := 0
+	for i, tag := range tags {
+		table := tables[tag]
+		record := header[12+16*i:]
+		copy(record, tag)
+		be.PutUint32(record[4:], tableChecksum(table))
+		be.PutUint32(record[8:], uint32(offset))
+		be.PutUint32(record[12:], uint32(len(table)))
+		if tag == "head" {
+			headOffset = offset
+		}
+		offset += (len(table) + 3) &^ 3
+	}
+	font.Write(header)
+	for _, tag := range tags {
+		table := tables[tag]
+		font.Write(table)
+		font.Write(make([]byte, ((len(table)+3)&^3)-len(table)))
+	}
+
+	data := font.Bytes()
+	be.PutUint32(data[headOffset+8:], 0xB1B0AFBA-tableChecksum(data))
+	return data
+}
+
+// nameTable names the font for the Windows platform
+func nameTable() []byte {
+	names := []string{"", fontName, "Regular", fontName, fontName, "Version 1.0", fontName}
+	var records, values bytes.Buffer
+	count := 0
+	for id, name := range names {
+		if name == "" {
+			continue
+		}
+		encoded := make([]byte, 0, 2*len(name))
+		for _, unit := range utf16.Encode([]rune(name)) {
+			encoded = binary.BigEndian.AppendUint16(encoded, unit)
+		}
+		for _, field := range []uint16{3, 1, 0x409, uint16(id), uint16(len(encoded)), uint16(values.Len())} {
+			records.Write(binary.BigEndian.AppendUint16(nil, field))
+		}
+		values.Write(encoded)
+		count++
+	}
+
+	table := binary.BigEndian.AppendUint16(nil, 0)
+	table = binary.BigEndian.AppendUint16(table, uint16(count))
+	table = binary.BigEndian.AppendUint16(table, uint16(6+records.Len()))
+	table = append(table, records.Bytes()...)
+	return append(table, values.Bytes()...)
+}
+
+// tableChecksum sums data as big-endian 32-bit words, zero padded
+func tableChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,319 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// ErrNoPageTree is returned when no page tree is found in a PDF
+var ErrNoPageTree = errors.New("no page tree found in PDF")
+
+// maxObjectStreamSize bounds the compressed object streams read into memory
+// while looking for the page tree
+const maxObjectStreamSize = 16 << 20
+
+// CountPages returns the number of pages of a PDF, the /Count of the root of
+// its page tree, which is the largest of the page tree nodes. The file is
+// read once as a stream; only compressed object streams, which may hold the
+// page tree, are read into memory.
+func CountPages(r io.Reader) (int, error) {
+	scanner := &pageScanner{}
+	if err := scanner.scan(bufio.NewReaderSize(r, 64*1024), true); err != nil {
+		return 0, err
+	}
+	if scanner.pages == 0 {
+		return 0, ErrNoPageTree
+	}
+	return scanner.pages, nil
+}
+
+// pageScanner looks for page tree nodes in the objects of a PDF
+type pageScanner struct {
+	pages int
+}
+
+// dictionary is the state of a dictionary being scanned
+type dictionary struct {
+	// key is the key whose value comes next, empty when a key comes next
+	key    string
+	arrays int
+	typ    string
+	filter string
+	count  int
+}
+
+// scan reads the objects of r, following object streams when objectStreams
+// is set
+func (s *pageScanner) scan(r *bufio.Reader, objectStreams bool) error {
+	var stack []*dictionary
+	// closed is the last top-level dictionary, which describes the stream
+	// following it
+	var closed *dictionary
+
+	// value records that the value of the current key was read
+	value := func() {
+		if len(stack) > 0 && stack[len(stack)-1].arrays == 0 {
+			stack[len(stack)-1].key = ""
+		}
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case b == '%':
+			if err := skipComment(r); err != nil {
+				return err
+			}
+
+		case b == '(':
+			if err := skipString(r); err != nil {
+				return err
+			}
+			value()
+
+		case b == '<':
+			if next, _ := r.Peek(1); len(next) == 1 && next[0] == '<' {
+				_, _ = r.ReadByte()
+				stack = append(stack, &dictionary{})
+				continue
+			}
+			// A hex string
+			if _, err := r.ReadSlice('>'); err != nil && err != bufio.ErrBufferFull {
+				return err
+			}
+			value()
+
+		case b == '>':
+			if next, _ := r.Peek(1); len(next) == 1 && next[0] == '>' && len(stack) > 0 {
+				_, _ = r.ReadByte()
+				dict := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if dict.typ == "Pages" {
+					s.pages = max(s.pages, dict.count)
+				}
+				if len(stack) == 0 {
+					closed = dict
+				}
+				value()
+			}
+
+		case b == '[':
+			if len(stack) > 0 {
+				stack[len(stack)-1].arrays++
+			}
+
+		case b == ']':
+			if len(stack) > 0 && stack[len(stack)-1].arrays > 0 {
+				stack[len(stack)-1].arrays--
+				value()
+			}
+
+		case b == '/':
+			name, err := readToken(r)
+			if err != nil {
+				return err
+			}
+			if len(stack) == 0 || stack[len(stack)-1].arrays > 0 {
+				continue
+			}
+			dict := stack[len(stack)-1]
+			switch dict.key {
+			case "":
+				dict.key = name
+			case "Type":
+				dict.typ = name
+			case "Filter":
+				dict.filter = name
+			}
+			if dict.key != name {
+				dict.key = ""
+			}
+
+		case isWhitespace(b):
+
+		default:
+			_ = r.UnreadByte()
+			token, err := readToken(r)
+			if err != nil {
+				return err
+			}
+			if token == "" {
+				// A delimiter with no meaning here
+				_, _ = r.ReadByte()
+				continue
+			}
+
+			if token == "stream" {
+				dict := closed
+				closed = nil
+				if err := s.stream(r, dict, objectStreams); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if len(stack) > 0 {
+				dict := stack[len(stack)-1]
+				if dict.arrays == 0 && dict.key == "Count" {
+					if n, err := strconv.Atoi(token); err == nil {
+						dict.count = n
+					}
+				}
+				// Skip the generation and R of an indirect reference by
+				// taking the first number as the value
+				value()
+			}
+		}
+	}
+}
+
+// stream reads the stream following dict, scanning it when it is a
+// compressed object stream
+func (s *pageScanner) stream(r *bufio.Reader, dict *dictionary, objectStreams bool) error {
+	follow := objectStreams && dict != nil && dict.typ == "ObjStm" && dict.filter == "FlateDecode"
+
+	var data []byte
+	matched := 0
+	for matched < len(endStream) {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for matched > 0 && b != endStream[matched] {
+			matched = endStreamFailure[matched-1]
+		}
+		if b == endStream[matched] {
+			matched++
+		}
+
+		if follow {
+			if len(data) >= maxObjectStreamSize {
+				follow = false
+				data = nil
+			} else {
+				data = append(data, b)
+			}
+		}
+	}
+	if !follow {
+		return nil
+	}
+
+	data = bytes.TrimLeft(data[:len(data)-len(endStream)], "\r\n")
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Not a stream this scanner can read; the page tree may be elsewhere
+		return nil
+	}
+	defer zr.Close()
+
+	// Likewise for a corrupt object stream
+	_ = s.scan(bufio.NewReader(zr), false)
+	return nil
+}
+
+// endStream ends the data of a stream
+var endStream = []byte("endstream")
+
+// endStreamFailure is the failure function of endStream for matching it in a
+// stream with the Knuth-Morris-Pratt algorithm
+var endStreamFailure = func() []int {
+	failure := make([]int, len(endStream))
+	k := 0
+	for i := 1; i < len(endStream); i++ {
+		for k > 0 && endStream[i] != endStream[k] {
+			k = failure[k-1]
+		}
+		if endStream[i] == endStream[k] {
+			k++
+		}
+		failure[i] = k
+	}
+	return failure
+}()
+
+// readToken reads a name or another regular token up to the next whitespace
+// or delimiter
+func readToken(r *bufio.Reader) (string, error) {
+	var token []byte
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return string(token), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if isWhitespace(b) || isDelimiter(b) {
+			_ = r.UnreadByte()
+			return string(token), nil
+		}
+		token = append(token, b)
+	}
+}
+
+// skipComment reads a comment up to the end of its line
+func skipComment(r *bufio.Reader) error {
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF || b == '\r' || b == '\n' {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// skipString reads a literal string up to its closing parenthesis, after
+// the opening one
+func skipString(r *bufio.Reader) error {
+	depth := 1
+	for depth > 0 {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch b {
+		case '\\':
+			if _, err := r.ReadByte(); err != nil && err != io.EOF {
+				return err
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	return nil
+}
+
+func isWhitespace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '\f' || b == 0
+}
+
+func isDelimiter(b byte) bool {
+	switch b {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
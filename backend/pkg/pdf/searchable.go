@@ -0,0 +1,196 @@
+// Package pdf produces searchable PDFs: the original pages, or page images,
+// with the recognized text laid over them as an invisible, selectable text
+// layer.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // register GIF decoder
+	_ "image/jpeg" // register JPEG decoder
+	_ "image/png"  // register PNG decoder
+	"io"
+	"strings"
+
+	_ "golang.org/x/image/bmp"  // register BMP decoder
+	_ "golang.org/x/image/tiff" // register TIFF decoder
+	_ "golang.org/x/image/webp" // register WEBP decoder
+)
+
+// ErrUnsupportedImage is returned when a page image cannot be embedded
+var ErrUnsupportedImage = errors.New("unsupported page image format")
+
+// assumedDPI converts image pixels to PDF points when the scan resolution is unknown
+const assumedDPI = 150.0
+
+// Word is a piece of recognized text and its bounding box in image pixels,
+// with the origin at the top-left corner of the page
+type Word struct {
+	Text           string
+	X0, Y0, X1, Y1 float64
+}
+
+// Page is a single page image and the text recognized on it
+type Page struct {
+	Image []byte
+	Words []Word
+	// FallbackText is written as invisible lines when no word boxes are available
+	FallbackText string
+}
+
+// WriteSearchable writes a PDF with one page per image and an invisible text layer
+func WriteSearchable(w io.Writer, pages []Page) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("no pages to write")
+	}
+
+	doc := &writer{}
+	doc.header()
+
+	// Object numbers: 1 catalog, 2 page tree, then 3 per page
+	const catalogID, pagesID = 1, 2
+	kids := make([]string, 0, len(pages))
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 3+i*3))
+	}
+
+	doc.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	doc.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+
+	layers := make([]TextLayer, 0, len(pages))
+	for i, page := range pages {
+		pageID, imageID, contentID := 3+i*3, 4+i*3, 5+i*3
+
+		img, err := encodeImage(page.Image)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+
+		width := float64(img.width) * 72 / assumedDPI
+		height := float64(img.height) * 72 / assumedDPI
+
+		doc.object(pageID, fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, width, height, imageID, contentID,
+		))
+		doc.stream(imageID, fmt.Sprintf(
+			"/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s",
+			img.width, img.height, img.colorSpace, img.filter,
+		), img.data)
+		doc.stream(contentID, "", []byte(fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q\n", width, height)))
+
+		layers = append(layers, TextLayer{
+			Page:         i + 1,
+			Width:        float64(img.width),
+			Height:       float64(img.height),
+			Words:        page.Words,
+			FallbackText: page.FallbackText,
+		})
+	}
+
+	doc.trailer(catalogID)
+
+	return AddText(w, bytes.NewReader(doc.buf.Bytes()), layers)
+}
+
+// pageImage is an image ready to be embedded as an XObject
+type pageImage struct {
+	width, height int
+	colorSpace    string
+	filter        string
+	data          []byte
+}
+
+// encodeImage embeds JPEGs as-is and converts other formats to deflated RGB
+func encodeImage(data []byte) (*pageImage, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	if format == "jpeg" {
+		switch cfg.ColorModel {
+		case color.YCbCrModel:
+			return &pageImage{cfg.Width, cfg.Height, "DeviceRGB", "DCTDecode", data}, nil
+		case color.GrayModel:
+			return &pageImage{cfg.Width, cfg.Height, "DeviceGray", "DCTDecode", data}, nil
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	bounds := img.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			// Composite transparent pixels onto white
+			a := uint32(c.A)
+			rgb = append(rgb,
+				byte((uint32(c.R)*a+255*(255-a))/255),
+				byte((uint32(c.G)*a+255*(255-a))/255),
+				byte((uint32(c.B)*a+255*(255-a))/255),
+			)
+		}
+	}
+
+	compressed, err := deflate(rgb)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pageImage{bounds.Dx(), bounds.Dy(), "DeviceRGB", "FlateDecode", compressed}, nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// writer assembles PDF objects and the cross-reference table
+type writer struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func (w *writer) header() {
+	w.offsets = make(map[int]int)
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+}
+
+func (w *writer) object(id int, body string) {
+	w.offsets[id] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (w *writer) stream(id int, dict string, data []byte) {
+	w.offsets[id] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (w *writer) trailer(rootID int) {
+	count := len(w.offsets) + 1
+	xref := w.buf.Len()
+
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", count)
+	for id := 1; id < count; id++ {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", w.offsets[id])
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", count, rootID, xref)
+}
@@ -0,0 +1,179 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestGlyphlessFontParses(t *testing.T) {
+	font, err := sfnt.Parse(glyphlessFont)
+	if err != nil {
+		t.Fatalf("parse font: %v", err)
+	}
+	if n := font.NumGlyphs(); n != 2 {
+		t.Errorf("glyphs = %d, want 2", n)
+	}
+}
+
+func TestWriteSearchableUnicode(t *testing.T) {
+	words := []Word{
+		{Text: "Grüße", X0: 10, Y0: 10, X1: 100, Y1: 30},
+		{Text: "Привет", X0: 110, Y0: 10, X1: 200, Y1: 30},
+		{Text: "日本語", X0: 10, Y0: 40, X1: 90, Y1: 60},
+	}
+	var out bytes.Buffer
+	if err := WriteSearchable(&out, []Page{{Image: testPNG(t, 300, 200), Words: words}}); err != nil {
+		t.Fatalf("WriteSearchable: %v", err)
+	}
+
+	got := pageTexts(t, out.Bytes())
+	want := [][]string{{"Grüße", "Привет", "日本語"}}
+	if !equalTexts(got, want) {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestAddTextToEveryPage(t *testing.T) {
+	var source bytes.Buffer
+	scan := testPNG(t, 300, 200)
+	if err := WriteSearchable(&source, []Page{{Image: scan}, {Image: scan}}); err != nil {
+		t.Fatalf("WriteSearchable: %v", err)
+	}
+
+	layers := []TextLayer{
+		{Page: 1, Width: 300, Height: 200, Words: []Word{{Text: "first", X0: 10, Y0: 10, X1: 60, Y1: 30}}},
+		{Page: 2, FallbackText: "second page\nłódź"},
+		{Page: 3, FallbackText: "no such page"},
+	}
+	var out bytes.Buffer
+	if err := AddText(&out, bytes.NewReader(source.Bytes()), layers); err != nil {
+		t.Fatalf("AddText: %v", err)
+	}
+
+	got := pageTexts(t, out.Bytes())
+	want := [][]string{{"first"}, {"second page", "łódź"}}
+	if !equalTexts(got, want) {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestAddTextRefusesGarbage(t *testing.T) {
+	err := AddText(&bytes.Buffer{}, strings.NewReader("not a pdf"), nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(width/2, height/2, color.Black)
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+var (
+	shownText = regexp.MustCompile(`/(\w+) [\d.]+ Tf .*<([0-9A-F]*)> Tj`)
+	bfchar    = regexp.MustCompile(`<([0-9A-F]{4})> <([0-9A-F]+)>`)
+)
+
+// pageTexts validates a PDF and decodes the text shown on each of its pages
+// through the ToUnicode maps of their fonts
+func pageTexts(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	ctx, err := api.ReadContext(bytes.NewReader(data), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatalf("read PDF: %v", err)
+	}
+	if err := api.ValidateContext(ctx); err != nil {
+		t.Fatalf("validate PDF: %v", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		t.Fatal(err)
+	}
+
+	var texts [][]string
+	for nr := 1; nr <= ctx.PageCount; nr++ {
+		page, _, inherited, err := ctx.PageDict(nr, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ctx.PageContent(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var lines []string
+		for _, match := range shownText.FindAllStringSubmatch(string(content), -1) {
+			unicode := toUnicode(t, ctx.XRefTable, inherited.Resources, match[1])
+			var text strings.Builder
+			for i := 0; i+4 <= len(match[2]); i += 4 {
+				text.WriteString(unicode[match[2][i:i+4]])
+			}
+			lines = append(lines, text.String())
+		}
+		texts = append(texts, lines)
+	}
+	return texts
+}
+
+// toUnicode reads the ToUnicode map of a font resource
+func toUnicode(t *testing.T, xRefTable *model.XRefTable, resources types.Dict, name string) map[string]string {
+	t.Helper()
+	fonts, err := xRefTable.DereferenceDict(resources["Font"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := xRefTable.DereferenceDict(fonts[name])
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, _, err := xRefTable.DereferenceStreamDict(font["ToUnicode"])
+	if err != nil || stream == nil {
+		t.Fatalf("font %s has no ToUnicode map: %v", name, err)
+	}
+	if err := stream.Decode(); err != nil {
+		t.Fatal(err)
+	}
+
+	mapping := make(map[string]string)
+	for _, match := range bfchar.FindAllStringSubmatch(string(stream.Content), -1) {
+		var units []uint16
+		for i := 0; i+4 <= len(match[2]); i += 4 {
+			unit, _ := strconv.ParseUint(match[2][i:i+4], 16, 16)
+			units = append(units, uint16(unit))
+		}
+		mapping[match[1]] = string(utf16.Decode(units))
+	}
+	return mapping
+}
+
+func equalTexts(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if strings.Join(a[i], "\n") != strings.Join(b[i], "\n") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,253 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// ErrUnreadablePDF is returned for a document that cannot be parsed, or is
+// encrypted with a password
+var ErrUnreadablePDF = errors.New("unreadable PDF document")
+
+func init() {
+	// Keep pdfcpu from reading and writing a config file in the user's home
+	model.ConfigPath = "disable"
+}
+
+// TextLayer is the text recognized on a page of a PDF
+type TextLayer struct {
+	// Page is numbered from 1
+	Page int
+	// Width and Height are the size in pixels of the page image the words were
+	// recognized on; 0 takes the page as rendered at assumedDPI
+	Width, Height float64
+	Words         []Word
+	// FallbackText is written as invisible lines when no word boxes are available
+	FallbackText string
+}
+
+// AddText writes src to w with the text of each layer laid over its page as
+// invisible, selectable text. Word boxes are scaled from the page image onto
+// the visible area of the page, following its rotation.
+func AddText(w io.Writer, src io.ReadSeeker, layers []TextLayer) error {
+	ctx, err := api.ReadContext(src, model.NewDefaultConfiguration())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnreadablePDF, err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnreadablePDF, err)
+	}
+	xRefTable := ctx.XRefTable
+
+	type overlay struct {
+		page      types.Dict
+		resources types.Dict
+		fontName  string
+		content   []byte
+	}
+	font := newTextFont()
+	var overlays []overlay
+	for _, layer := range layers {
+		if layer.Page < 1 || layer.Page > xRefTable.PageCount {
+			continue
+		}
+		page, _, inherited, err := xRefTable.PageDict(layer.Page, false)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", layer.Page, err)
+		}
+		box := inherited.CropBox
+		if box == nil {
+			box = inherited.MediaBox
+		}
+		if box == nil {
+			return fmt.Errorf("page %d: %w: no media box", layer.Page, ErrUnreadablePDF)
+		}
+
+		fontName, err := unusedFontName(xRefTable, inherited.Resources)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", layer.Page, err)
+		}
+		content := textContent(font, fontName, layer, box, inherited.Rotate)
+		if content != nil {
+			overlays = append(overlays, overlay{page, inherited.Resources, fontName, content})
+		}
+	}
+
+	if len(overlays) > 0 {
+		fontRef, err := font.addTo(xRefTable)
+		if err != nil {
+			return fmt.Errorf("failed to add font: %w", err)
+		}
+		// The page's own content is wrapped in q/Q so the graphics state it
+		// leaves behind does not move the text
+		save, err := newStream(xRefTable, []byte("q\n"))
+		if err != nil {
+			return err
+		}
+		saveRef, err := xRefTable.IndRefForNewObject(*save)
+		if err != nil {
+			return err
+		}
+
+		for _, o := range overlays {
+			if err := addFontResource(xRefTable, o.page, o.resources, o.fontName, *fontRef); err != nil {
+				return err
+			}
+			text, err := newStream(xRefTable, append([]byte("Q\n"), o.content...))
+			if err != nil {
+				return err
+			}
+			textRef, err := xRefTable.IndRefForNewObject(*text)
+			if err != nil {
+				return err
+			}
+
+			contents := types.Array{*saveRef}
+			if existing, found := o.page.Find("Contents"); found && existing != nil {
+				resolved, err := xRefTable.Dereference(existing)
+				if err != nil {
+					return err
+				}
+				if parts, ok := resolved.(types.Array); ok {
+					contents = append(contents, parts...)
+				} else {
+					contents = append(contents, existing)
+				}
+			}
+			o.page["Contents"] = append(contents, *textRef)
+		}
+	}
+
+	if err := api.WriteContext(ctx, w); err != nil {
+		return fmt.Errorf("failed to write PDF: %w", err)
+	}
+	return nil
+}
+
+// unusedFontName returns a name for the text font that the page's resources
+// do not use yet
+func unusedFontName(xRefTable *model.XRefTable, resources types.Dict) (string, error) {
+	fonts, err := fontResources(xRefTable, resources)
+	if err != nil {
+		return "", err
+	}
+	name := "OCRText"
+	for i := 1; fonts[name] != nil; i++ {
+		name = fmt.Sprintf("OCRText%d", i)
+	}
+	return name, nil
+}
+
+// addFontResource gives the page its own resources with the font added,
+// leaving resources shared with other pages as they are
+func addFontResource(xRefTable *model.XRefTable, page, inherited types.Dict, name string, font types.IndirectRef) error {
+	fonts, err := fontResources(xRefTable, inherited)
+	if err != nil {
+		return err
+	}
+	fonts[name] = font
+
+	resources := types.NewDict()
+	for key, value := range inherited {
+		resources[key] = value
+	}
+	resources["Font"] = fonts
+	page["Resources"] = resources
+	return nil
+}
+
+// fontResources returns a copy of the fonts of resources
+func fontResources(xRefTable *model.XRefTable, resources types.Dict) (types.Dict, error) {
+	fonts := types.NewDict()
+	existing, found := resources.Find("Font")
+	if !found {
+		return fonts, nil
+	}
+	dict, err := xRefTable.DereferenceDict(existing)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range dict {
+		fonts[key] = value
+	}
+	return fonts, nil
+}
+
+// textContent draws the words of a layer as invisible text in the font of the
+// given resource name; it returns nil when there is nothing to write
+func textContent(font *textFont, fontName string, layer TextLayer, box *types.Rectangle, rotate int) []byte {
+	rotate = ((rotate % 360) + 360) % 360
+	// The page as displayed, after rotation
+	width, height := box.Width(), box.Height()
+	if rotate == 90 || rotate == 270 {
+		width, height = height, width
+	}
+
+	imageWidth, imageHeight := layer.Width, layer.Height
+	if imageWidth <= 0 || imageHeight <= 0 {
+		imageWidth, imageHeight = width*assumedDPI/72, height*assumedDPI/72
+	}
+	scaleX, scaleY := width/imageWidth, height/imageHeight
+
+	var b bytes.Buffer
+	written := false
+
+	// Map the displayed page, origin at its bottom-left corner, onto the
+	// page's own coordinates
+	llx, lly, urx, ury := box.LL.X, box.LL.Y, box.UR.X, box.UR.Y
+	switch rotate {
+	case 90:
+		fmt.Fprintf(&b, "q 0 1 -1 0 %.2f %.2f cm\n", urx, lly)
+	case 180:
+		fmt.Fprintf(&b, "q -1 0 0 -1 %.2f %.2f cm\n", urx, ury)
+	case 270:
+		fmt.Fprintf(&b, "q 0 -1 1 0 %.2f %.2f cm\n", llx, ury)
+	default:
+		fmt.Fprintf(&b, "q 1 0 0 1 %.2f %.2f cm\n", llx, lly)
+	}
+	b.WriteString("BT 3 Tr\n")
+
+	if len(layer.Words) > 0 {
+		for _, word := range layer.Words {
+			if word.X1 <= word.X0 || word.Y1 <= word.Y0 {
+				continue
+			}
+			text, n := font.encode(word.Text)
+			if n == 0 {
+				continue
+			}
+			size := (word.Y1 - word.Y0) * scaleY
+			// Stretch the text horizontally so the selection matches the word box
+			natural := float64(glyphAdvance) / unitsPerEm * size * float64(n)
+			stretch := (word.X1 - word.X0) * scaleX / natural * 100
+			fmt.Fprintf(&b, "/%s %.2f Tf %.2f Tz 1 0 0 1 %.2f %.2f Tm %s Tj\n",
+				fontName, size, stretch, word.X0*scaleX, height-word.Y1*scaleY, text)
+			written = true
+		}
+	} else if layer.FallbackText != "" {
+		lines := strings.Split(layer.FallbackText, "\n")
+		size := min(height/float64(len(lines)+1), 12)
+		for i, line := range lines {
+			text, n := font.encode(line)
+			if n == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "/%s %.2f Tf 100 Tz 1 0 0 1 0 %.2f Tm %s Tj\n",
+				fontName, size, height-float64(i+1)*size, text)
+			written = true
+		}
+	}
+
+	if !written {
+		return nil
+	}
+	b.WriteString("ET\nQ\n")
+	return b.Bytes()
+}
@@ -0,0 +1,205 @@
+// Package quality measures properties of scanned page images that predict how
+// well they can be recognized: resolution, sharpness and skew.
+package quality
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"  // register GIF decoder
+	_ "image/jpeg" // register JPEG decoder
+	_ "image/png"  // register PNG decoder
+	"io"
+	"math"
+	"sort"
+)
+
+// ErrUnsupportedImage is returned for images that cannot be decoded
+var ErrUnsupportedImage = errors.New("unsupported image format")
+
+// assumedPageInches is the long side of the letter and A4 pages most scans are
+// of, used to estimate the resolution of images that do not record it
+const assumedPageInches = 11.0
+
+// maxAnalysisSide bounds the image size sharpness is measured at; larger images
+// are sampled down first
+const maxAnalysisSide = 1200
+
+// ImageMetrics describes a page image
+type ImageMetrics struct {
+	Width  int
+	Height int
+	// DPI is the resolution recorded in the file, or estimated from its size
+	// when DPIEstimated is set
+	DPI          float64
+	DPIEstimated bool
+	// Sharpness is the variance of the Laplacian of the grayscale image; blurry
+	// images score low, with values under about 100 usually too blurry to read
+	Sharpness float64
+}
+
+// AnalyzeImage measures a JPEG, PNG or GIF page image
+func AnalyzeImage(r io.Reader) (*ImageMetrics, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	bounds := img.Bounds()
+	metrics := &ImageMetrics{
+		Width:     bounds.Dx(),
+		Height:    bounds.Dy(),
+		Sharpness: laplacianVariance(img),
+	}
+
+	switch format {
+	case "jpeg":
+		metrics.DPI = jpegDPI(data)
+	case "png":
+		metrics.DPI = pngDPI(data)
+	}
+	if metrics.DPI <= 0 {
+		metrics.DPI = float64(max(metrics.Width, metrics.Height)) / assumedPageInches
+		metrics.DPIEstimated = true
+	}
+
+	return metrics, nil
+}
+
+// laplacianVariance returns the variance of the 4-neighbour Laplacian of the
+// grayscale image, sampled down to at most maxAnalysisSide pixels a side
+func laplacianVariance(img image.Image) float64 {
+	bounds := img.Bounds()
+	step := max(1, (max(bounds.Dx(), bounds.Dy())+maxAnalysisSide-1)/maxAnalysisSide)
+	w, h := bounds.Dx()/step, bounds.Dy()/step
+	if w < 3 || h < 3 {
+		return 0
+	}
+
+	gray := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.GrayModel.Convert(img.At(bounds.Min.X+x*step, bounds.Min.Y+y*step)).(color.Gray)
+			gray[y*w+x] = float64(c.Y)
+		}
+	}
+
+	var sum, sumSq float64
+	n := 0
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			lap := gray[i-w] + gray[i+w] + gray[i-1] + gray[i+1] - 4*gray[i]
+			sum += lap
+			sumSq += lap * lap
+			n++
+		}
+	}
+
+	mean := sum / float64(n)
+	return sumSq/float64(n) - mean*mean
+}
+
+// jpegDPI returns the horizontal density of a JFIF header in dots per inch, or
+// 0 when the file does not record one
+func jpegDPI(data []byte) float64 {
+	// SOI, then an APP0 segment: marker, length, "JFIF\0", version, units, density
+	if len(data) < 18 || data[0] != 0xFF || data[1] != 0xD8 || data[2] != 0xFF || data[3] != 0xE0 {
+		return 0
+	}
+	if string(data[6:11]) != "JFIF\x00" {
+		return 0
+	}
+
+	density := float64(binary.BigEndian.Uint16(data[14:16]))
+	switch data[13] {
+	case 1: // dots per inch
+		return density
+	case 2: // dots per centimetre
+		return density * 2.54
+	}
+	return 0
+}
+
+// pngDPI returns the horizontal density of a PNG pHYs chunk in dots per inch,
+// or 0 when the file does not record one
+func pngDPI(data []byte) float64 {
+	// Chunks follow the 8-byte signature: length, type, data, CRC
+	for i := 8; i+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		kind := string(data[i+4 : i+8])
+		if kind == "IDAT" || i+12+length > len(data) {
+			break
+		}
+		if kind == "pHYs" && length == 9 {
+			body := data[i+8 : i+17]
+			if body[8] == 1 { // pixels per metre
+				return float64(binary.BigEndian.Uint32(body[0:4])) * 0.0254
+			}
+			return 0
+		}
+		i += 12 + length
+	}
+	return 0
+}
+
+// Box is a recognized word or line and its bounding box in image pixels
+type Box struct {
+	X0, Y0, X1, Y1 float64
+}
+
+// Skew estimates how far the text on a page is rotated, in degrees, from the
+// words recognized within each line: a straight line of words has centres at
+// one height. It returns false when too few lines have enough words to tell.
+func Skew(lines, words []Box) (float64, bool) {
+	var angles []float64
+	for _, line := range lines {
+		var xs, ys []float64
+		for _, word := range words {
+			cx, cy := (word.X0+word.X1)/2, (word.Y0+word.Y1)/2
+			if cx >= line.X0 && cx <= line.X1 && cy >= line.Y0 && cy <= line.Y1 {
+				xs = append(xs, cx)
+				ys = append(ys, cy)
+			}
+		}
+		if len(xs) < 3 {
+			continue
+		}
+		if slope, ok := fitSlope(xs, ys); ok {
+			angles = append(angles, math.Atan(slope)*180/math.Pi)
+		}
+	}
+
+	if len(angles) < 3 {
+		return 0, false
+	}
+
+	// The median ignores lines whose words were grouped wrongly
+	sort.Float64s(angles)
+	return angles[len(angles)/2], true
+}
+
+// fitSlope returns the least-squares slope of y over x
+func fitSlope(xs, ys []float64) (float64, bool) {
+	n := float64(len(xs))
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+
+	denom := n*sxx - sx*sx
+	if denom == 0 {
+		return 0, false
+	}
+	return (n*sxy - sx*sy) / denom, true
+}
@@ -0,0 +1,20 @@
+package storage
+
+import "errors"
+
+// ErrDiskUsageUnsupported is returned by DiskUsage on platforms it cannot measure
+var ErrDiskUsageUnsupported = errors.New("disk usage is not supported on this platform")
+
+// DiskUsage describes the filesystem holding the storage root
+type DiskUsage struct {
+	TotalBytes uint64
+	FreeBytes  uint64
+}
+
+// FreePercent returns the share of the filesystem still available, from 0 to 100
+func (u *DiskUsage) FreePercent() float64 {
+	if u.TotalBytes == 0 {
+		return 0
+	}
+	return float64(u.FreeBytes) / float64(u.TotalBytes) * 100
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package storage
+
+// DiskUsage is not supported on this platform
+func (s *Storage) DiskUsage() (*DiskUsage, error) {
+	return nil, ErrDiskUsageUnsupported
+}
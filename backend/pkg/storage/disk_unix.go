@@ -0,0 +1,22 @@
+//go:build unix
+
+package storage
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// DiskUsage measures the filesystem holding the storage root. Free space is
+// what unprivileged processes can still use.
+func (s *Storage) DiskUsage() (*DiskUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(s.basePath, &stat); err != nil {
+		return nil, fmt.Errorf("failed to stat storage filesystem: %w", err)
+	}
+
+	return &DiskUsage{
+		TotalBytes: uint64(stat.Blocks) * uint64(stat.Bsize),
+		FreeBytes:  uint64(stat.Bavail) * uint64(stat.Bsize),
+	}, nil
+}
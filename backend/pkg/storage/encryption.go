@@ -0,0 +1,352 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Encrypted files start with a header holding the wrapped data key:
+//
+//	magic (8) | key ID length (1) | key ID | wrapped key length (2) | wrapped key | nonce prefix (7)
+//
+// followed by the content split into segments of segmentSize bytes, each sealed
+// with AES-256-GCM under the data key. A segment's nonce is the prefix, a 4-byte
+// counter and a final-segment flag, so segments cannot be reordered, dropped or
+// truncated without detection.
+const (
+	encryptionMagic   = "VSKENC01"
+	segmentSize       = 64 * 1024
+	noncePrefixSize   = 7
+	dataKeySize       = 32
+	maxSegmentCounter = 1<<32 - 1
+)
+
+var (
+	// ErrNoEncryptionKey is returned when reading an encrypted file without a key wrapper
+	ErrNoEncryptionKey = errors.New("file is encrypted but no encryption key is configured")
+	// ErrCorruptCiphertext is returned when an encrypted file fails authentication
+	ErrCorruptCiphertext = errors.New("encrypted file is corrupted or was tampered with")
+)
+
+// KeyWrapper protects per-file data keys with a key encryption key held in
+// configuration or an external KMS
+type KeyWrapper interface {
+	// CurrentKeyID identifies the key new files are encrypted with
+	CurrentKeyID() string
+	// WrapKey encrypts a data key with the current key
+	WrapKey(dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key wrapped with the given key
+	UnwrapKey(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Keyring is a KeyWrapper using AES-256 keys from configuration
+type Keyring struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// ParseKeyring parses a comma-separated list of id:base64-key pairs.
+// Every key must decode to 32 bytes; currentID selects the key for new files.
+func ParseKeyring(spec, currentID string) (*Keyring, error) {
+	keyring := &Keyring{currentID: currentID, keys: make(map[string]cipher.AEAD)}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("invalid encryption key entry: expected id:base64-key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes, base64 encoded", id)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		keyring.keys[id] = aead
+	}
+
+	if len(keyring.keys) == 0 {
+		return nil, fmt.Errorf("no encryption keys configured")
+	}
+	if _, ok := keyring.keys[currentID]; !ok {
+		return nil, fmt.Errorf("current encryption key %q is not in the keyring", currentID)
+	}
+
+	return keyring, nil
+}
+
+// CurrentKeyID returns the ID of the key new files are encrypted with
+func (k *Keyring) CurrentKeyID() string {
+	return k.currentID
+}
+
+// WrapKey encrypts a data key with the current key
+func (k *Keyring) WrapKey(dataKey []byte) (string, []byte, error) {
+	aead := k.keys[k.currentID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return k.currentID, aead.Seal(nonce, nonce, dataKey, []byte(k.currentID)), nil
+}
+
+// UnwrapKey decrypts a data key wrapped with the given key
+func (k *Keyring) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("encryption key %q is not in the keyring", keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrCorruptCiphertext
+	}
+	dataKey, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, ErrCorruptCiphertext
+	}
+	return dataKey, nil
+}
+
+// newGCM creates an AES-GCM cipher for a 32-byte key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// encryptWriter encrypts everything written to it into dst. Close must be
+// called to seal the final segment; it does not close dst.
+type encryptWriter struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	out     []byte
+}
+
+// newEncryptWriter writes the header for a fresh data key and returns a writer encrypting into dst
+func newEncryptWriter(dst io.Writer, keys KeyWrapper) (*encryptWriter, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, wrapped, err := keys.WrapKey(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	if len(keyID) > 255 || len(wrapped) > 65535 {
+		return nil, fmt.Errorf("wrapped data key too large")
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	var header bytes.Buffer
+	header.WriteString(encryptionMagic)
+	header.WriteByte(byte(len(keyID)))
+	header.WriteString(keyID)
+	_ = binary.Write(&header, binary.BigEndian, uint16(len(wrapped)))
+	header.Write(wrapped)
+	header.Write(prefix)
+	if _, err := dst.Write(header.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write encryption header: %w", err)
+	}
+
+	return &encryptWriter{
+		dst:    dst,
+		aead:   aead,
+		prefix: prefix,
+		buf:    make([]byte, 0, segmentSize),
+		out:    make([]byte, 0, segmentSize+aead.Overhead()),
+	}, nil
+}
+
+// Write buffers p, sealing each full segment once more data follows it
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if len(w.buf) == segmentSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):segmentSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the final segment
+func (w *encryptWriter) Close() error {
+	return w.seal(true)
+}
+
+// seal encrypts and writes the buffered segment
+func (w *encryptWriter) seal(last bool) error {
+	if w.counter == maxSegmentCounter {
+		return fmt.Errorf("file too large to encrypt")
+	}
+	w.out = w.aead.Seal(w.out[:0], segmentNonce(w.prefix, w.counter, last), w.buf, nil)
+	if _, err := w.dst.Write(w.out); err != nil {
+		return fmt.Errorf("failed to write encrypted data: %w", err)
+	}
+	w.counter++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// decryptReader decrypts a file produced by encryptWriter
+type decryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	in      []byte
+	plain   []byte
+	done    bool
+}
+
+// readEncryptionHeader reads the header of an encrypted file, returning the key ID,
+// wrapped data key and nonce prefix
+func readEncryptionHeader(src *bufio.Reader) (string, []byte, []byte, error) {
+	magic := make([]byte, len(encryptionMagic))
+	if _, err := io.ReadFull(src, magic); err != nil || string(magic) != encryptionMagic {
+		return "", nil, nil, ErrCorruptCiphertext
+	}
+
+	idLen, err := src.ReadByte()
+	if err != nil {
+		return "", nil, nil, ErrCorruptCiphertext
+	}
+	keyID := make([]byte, idLen)
+	if _, err := io.ReadFull(src, keyID); err != nil {
+		return "", nil, nil, ErrCorruptCiphertext
+	}
+
+	var wrappedLen uint16
+	if err := binary.Read(src, binary.BigEndian, &wrappedLen); err != nil {
+		return "", nil, nil, ErrCorruptCiphertext
+	}
+	wrapped := make([]byte, wrappedLen)
+	if _, err := io.ReadFull(src, wrapped); err != nil {
+		return "", nil, nil, ErrCorruptCiphertext
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(src, prefix); err != nil {
+		return "", nil, nil, ErrCorruptCiphertext
+	}
+
+	return string(keyID), wrapped, prefix, nil
+}
+
+// newDecryptReader reads the header from src and returns a reader yielding the plaintext
+func newDecryptReader(src *bufio.Reader, keys KeyWrapper) (*decryptReader, error) {
+	keyID, wrapped, prefix, err := readEncryptionHeader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := keys.UnwrapKey(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		src:    src,
+		aead:   aead,
+		prefix: prefix,
+		in:     make([]byte, segmentSize+aead.Overhead()),
+	}, nil
+}
+
+// Read returns decrypted data, authenticating one segment at a time
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next segment
+func (r *decryptReader) open() error {
+	n, err := io.ReadFull(r.src, r.in)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return ErrCorruptCiphertext
+	}
+
+	// The final segment is the one not followed by any further data
+	last := err == io.ErrUnexpectedEOF
+	if !last {
+		if _, peekErr := r.src.Peek(1); peekErr == io.EOF {
+			last = true
+		}
+	}
+
+	plain, err := r.aead.Open(r.in[:0], segmentNonce(r.prefix, r.counter, last), r.in[:n], nil)
+	if err != nil {
+		return ErrCorruptCiphertext
+	}
+	r.plain = plain
+	r.counter++
+	r.done = last
+	return nil
+}
+
+// segmentNonce builds the nonce of a segment
+func segmentNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, noncePrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if last {
+		nonce[noncePrefixSize+4] = 1
+	}
+	return nonce
+}
+
+// isEncrypted reports whether the stream starts with the encryption header
+func isEncrypted(src *bufio.Reader) bool {
+	magic, err := src.Peek(len(encryptionMagic))
+	return err == nil && string(magic) == encryptionMagic
+}
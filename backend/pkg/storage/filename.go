@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxFilenameLength is the maximum length in bytes of a normalized filename
+const MaxFilenameLength = 255
+
+// MaxSourceFilenameLength is the maximum length in bytes of a filename kept
+// as uploaded
+const MaxSourceFilenameLength = 1024
+
+// maxStoredExtLength is the longest extension kept in the generated names of
+// stored files
+const maxStoredExtLength = 16
+
+// defaultFilename replaces names that normalize to nothing
+const defaultFilename = "document"
+
+// reservedNames are device names Windows refuses as filenames, with or without an extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// NormalizeFilename turns a client-supplied filename into a name that is safe to
+// display, send in Content-Disposition and write into archives on any platform.
+// Directory components, control and invisible formatting characters (such as
+// bidi overrides) are removed, characters reserved on common filesystems are
+// replaced, unicode is NFC-normalized and the result is capped at
+// MaxFilenameLength bytes while keeping the extension.
+func NormalizeFilename(name string) string {
+	// Keep only the last path component, whichever separator the client used
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = norm.NFC.String(strings.ToValidUTF8(name, ""))
+
+	var b strings.Builder
+	lastSpace := false
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			// Collapse runs of whitespace, including tabs and newlines, into one space
+			if !lastSpace {
+				b.WriteByte(' ')
+			}
+			lastSpace = true
+			continue
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r), r == utf8.RuneError:
+			continue
+		case strings.ContainsRune(`<>:"|?*`, r):
+			b.WriteByte('_')
+		default:
+			b.WriteRune(r)
+		}
+		lastSpace = false
+	}
+
+	name = strings.Trim(b.String(), " .")
+	if name == "" {
+		return defaultFilename
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimRight(strings.TrimSuffix(name, ext), " .")
+	if base == "" {
+		// A dotfile such as ".pdf" has no extension of its own
+		base, ext = strings.TrimLeft(ext, "."), ""
+	}
+	if reservedNames[strings.ToUpper(base)] {
+		base = "_" + base
+	}
+
+	return truncateFilename(base, ext)
+}
+
+// SourceFilename returns a client-supplied filename as it was sent, only made
+// valid UTF-8, stripped of control and invisible formatting characters and
+// capped at MaxSourceFilenameLength bytes, so it can be kept as metadata for
+// display. It must never be used to build a path.
+func SourceFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(name, "\uFFFD"))
+
+	if len(name) > MaxSourceFilenameLength {
+		cut := MaxSourceFilenameLength
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = name[:cut]
+	}
+	return name
+}
+
+// storedExtension returns the extension of a filename for the generated name
+// of its stored file: lowercased, and empty unless it is made of ASCII letters
+// and digits only, so no part of a client-supplied name reaches a path
+func storedExtension(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(ext) < 2 || len(ext) > maxStoredExtLength {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// DisambiguateFilename returns the first variant of a normalized name, such as
+// "report (2).pdf", that is not taken. It is used to keep names unique within a
+// user's documents and inside archives.
+func DisambiguateFilename(name string, taken func(string) bool) string {
+	if !taken(name) {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate := truncateFilename(base, suffix+ext)
+		if !taken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// truncateFilename joins base and suffix, shortening base on a rune boundary so
+// the result fits in MaxFilenameLength bytes
+func truncateFilename(base, suffix string) string {
+	if len(suffix) > MaxFilenameLength/2 {
+		// An absurdly long extension is not worth keeping
+		suffix = ""
+	}
+
+	limit := MaxFilenameLength - len(suffix)
+	if len(base) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(base[cut]) {
+			cut--
+		}
+		base = strings.TrimRight(base[:cut], " .")
+	}
+	if base == "" {
+		base = defaultFilename
+	}
+
+	return base + suffix
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is the number of leading bytes inspected to detect the content type
+const sniffLen = 512
+
+// pdfTailLen is the number of trailing bytes searched for the end-of-file marker
+const pdfTailLen = 1024
+
+var (
+	// ErrContentMismatch is returned when a file's content does not match its extension
+	ErrContentMismatch = errors.New("file content does not match its extension")
+	// ErrEncryptedPDF is returned for password-protected or encrypted PDFs
+	ErrEncryptedPDF = errors.New("encrypted PDFs are not supported")
+	// ErrCorruptPDF is returned for PDFs missing their header or trailer
+	ErrCorruptPDF = errors.New("PDF file is corrupted or truncated")
+)
+
+// DetectContentType sniffs the MIME type from the first bytes of a file.
+// It extends http.DetectContentType with TIFF, which the standard library does not recognize.
+func DetectContentType(header []byte) string {
+	if bytes.HasPrefix(header, []byte("II*\x00")) || bytes.HasPrefix(header, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+
+	contentType := http.DetectContentType(header)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType
+}
+
+// ValidateContent checks that an uploaded file's content matches the MIME type
+// implied by its extension and, for PDFs, that the document is neither encrypted
+// nor truncated. It returns the detected MIME type.
+func ValidateContent(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	return ValidateFileContent(src, file.Filename, file.Size)
+}
+
+// ValidateFileContent is ValidateContent for a file read from elsewhere than a
+// multipart upload, such as a temporary file
+func ValidateFileContent(src multipart.File, filename string, size int64) (string, error) {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(src, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	header = header[:n]
+
+	detected := DetectContentType(header)
+	if detected != GetMimeType(filename) {
+		return detected, ErrContentMismatch
+	}
+
+	if detected == "application/pdf" {
+		if err := inspectPDF(src, size); err != nil {
+			return detected, err
+		}
+	}
+
+	return detected, nil
+}
+
+// inspectPDF looks for the end-of-file marker and an encryption dictionary.
+// The trailer dictionary and cross-reference stream dictionaries are never
+// compressed, so a plain scan for the /Encrypt key finds them.
+func inspectPDF(src multipart.File, size int64) error {
+	tailStart := max(size-pdfTailLen, 0)
+	tail := make([]byte, size-tailStart)
+	if _, err := src.ReadAt(tail, tailStart); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) || !bytes.Contains(tail, []byte("startxref")) {
+		return ErrCorruptPDF
+	}
+
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+
+	encrypted, err := containsToken(src, []byte("/Encrypt"))
+	if err != nil {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if encrypted {
+		return ErrEncryptedPDF
+	}
+
+	return nil
+}
+
+// containsToken reports whether r contains token, reading in chunks and keeping
+// an overlap so matches spanning two chunks are found
+func containsToken(r io.Reader, token []byte) (bool, error) {
+	buf := make([]byte, 64*1024)
+	carry := 0
+	for {
+		n, err := r.Read(buf[carry:])
+		window := buf[:carry+n]
+		if bytes.Contains(window, token) {
+			return true, nil
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		carry = min(len(token)-1, len(window))
+		copy(buf, window[len(window)-carry:])
+	}
+}
+
+// ContentInspector validates a file's content as it is written to it, as
+// ValidateFileContent does, for files received as a stream. Writes fail with
+// ErrContentMismatch as soon as the leading bytes do not match the extension,
+// so a transfer can be aborted early; the PDF checks need the whole file and
+// are made by Result.
+type ContentInspector struct {
+	expected  string
+	detected  string
+	header    []byte
+	tail      []byte
+	encrypted bool
+}
+
+// NewContentInspector creates an inspector for a file of the given name
+func NewContentInspector(filename string) *ContentInspector {
+	return &ContentInspector{
+		expected: GetMimeType(filename),
+		header:   make([]byte, 0, sniffLen),
+		tail:     make([]byte, 0, pdfTailLen),
+	}
+}
+
+// Write inspects the next bytes of the file
+func (i *ContentInspector) Write(p []byte) (int, error) {
+	if len(i.header) < sniffLen {
+		i.header = append(i.header, p[:min(sniffLen-len(i.header), len(p))]...)
+		if len(i.header) == sniffLen {
+			if err := i.checkType(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	// Look for /Encrypt in the bytes written and across their boundary with
+	// the previous ones, which end the tail
+	token := []byte("/Encrypt")
+	if !i.encrypted {
+		overlap := len(token) - 1
+		boundary := append(bytes.Clone(i.tail[max(len(i.tail)-overlap, 0):]), p[:min(overlap, len(p))]...)
+		i.encrypted = bytes.Contains(boundary, token) || bytes.Contains(p, token)
+	}
+
+	if len(p) >= pdfTailLen {
+		i.tail = append(i.tail[:0], p[len(p)-pdfTailLen:]...)
+	} else {
+		keep := min(len(i.tail), pdfTailLen-len(p))
+		i.tail = append(i.tail[:copy(i.tail, i.tail[len(i.tail)-keep:])], p...)
+	}
+
+	return len(p), nil
+}
+
+// Result returns the detected MIME type once the whole file was written, with
+// the error ValidateFileContent would return for it
+func (i *ContentInspector) Result() (string, error) {
+	if i.detected == "" {
+		if err := i.checkType(); err != nil {
+			return i.detected, err
+		}
+	}
+
+	if i.detected == "application/pdf" {
+		if !bytes.Contains(i.tail, []byte("%%EOF")) || !bytes.Contains(i.tail, []byte("startxref")) {
+			return i.detected, ErrCorruptPDF
+		}
+		if i.encrypted {
+			return i.detected, ErrEncryptedPDF
+		}
+	}
+
+	return i.detected, nil
+}
+
+// checkType detects the content type from the header
+func (i *ContentInspector) checkType() error {
+	i.detected = DetectContentType(i.header)
+	if i.detected != i.expected {
+		return ErrContentMismatch
+	}
+	return nil
+}
@@ -1,20 +1,55 @@
 package storage
 
 import (
+	"bufio"
+	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"mime/multipart"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// DocumentsDir is the directory below the storage root holding uploaded documents
+const DocumentsDir = "documents"
+
+// ImportsDir is the directory below the storage root holding reports of imports
+const ImportsDir = "imports"
+
+// ReportsDir is the directory below the storage root holding generated usage reports
+const ReportsDir = "reports"
+
+// ExportsDir is the directory below the storage root holding users' data exports
+const ExportsDir = "exports"
+
+// QuarantineDir is the directory below the storage root holding files rejected by the malware scanner
+const QuarantineDir = "quarantine"
+
+// ContentDir is the directory below DocumentsDir holding content-addressed
+// documents, sharded by the first bytes of their hash
+const ContentDir = "content"
+
+// contentReuseGrace is how long Release keeps a content-addressed file after an
+// upload reused it, as the upload's document may not have been created yet
+const contentReuseGrace = time.Hour
+
+// ReferenceChecker returns the subset of the given file paths that documents
+// refer to
+type ReferenceChecker func(ctx context.Context, paths []string) (map[string]bool, error)
+
 // Storage handles file storage operations
 type Storage struct {
-	basePath string
+	basePath         string
+	keys             KeyWrapper
+	contentAddressed bool
+	references       ReferenceChecker
 }
 
 // NewStorage creates a new storage instance
@@ -30,6 +65,26 @@ func NewStorage(basePath string) (*Storage, error) {
 	}, nil
 }
 
+// EnableEncryption encrypts documents saved from now on with keys protected by the
+// given wrapper. Files stored earlier in plaintext stay readable.
+func (s *Storage) EnableEncryption(keys KeyWrapper) {
+	s.keys = keys
+}
+
+// EnableContentAddressing stores documents saved from now on once per content,
+// at a path derived from their hash, whichever user uploads them. Documents
+// with the same content then share one file, which Release deletes once no
+// document refers to it any more. Files stored earlier keep their paths.
+func (s *Storage) EnableContentAddressing() {
+	s.contentAddressed = true
+}
+
+// UseReferenceChecker has Release keep files that documents still refer to,
+// as references tells
+func (s *Storage) UseReferenceChecker(references ReferenceChecker) {
+	s.references = references
+}
+
 // SaveFile saves an uploaded file to storage
 func (s *Storage) SaveFile(file *multipart.FileHeader, userID uuid.UUID) (filePath string, fileHash string, err error) {
 	// Open uploaded file
@@ -39,59 +94,198 @@ func (s *Storage) SaveFile(file *multipart.FileHeader, userID uuid.UUID) (filePa
 	}
 	defer src.Close()
 
+	return s.SaveReader(src, file.Filename, userID)
+}
+
+// SaveReader saves the contents of src as a document of the user under a
+// generated name keeping only filename's sanitized extension, and returns its
+// path and SHA-256 hash. With
+// content addressing enabled, the path of a file with the same content and
+// extension stored earlier is returned instead of storing it again.
+func (s *Storage) SaveReader(src io.Reader, filename string, userID uuid.UUID) (filePath string, fileHash string, err error) {
 	// Generate unique filename
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
+	ext := storedExtension(filename)
+	storedName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
 
-	// Create user directory
-	userDir := filepath.Join(s.basePath, "documents", userID.String())
+	// Create user directory, or write content-addressed files to a temporary
+	// name until their hash is known
+	userDir := filepath.Join(s.basePath, DocumentsDir, userID.String())
+	if s.contentAddressed {
+		userDir = filepath.Join(s.basePath, DocumentsDir, ContentDir)
+		storedName = ".upload-" + storedName
+	}
 	err = os.MkdirAll(userDir, 0755)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create user directory: %w", err)
 	}
 
 	// Create destination file
-	destPath := filepath.Join(userDir, filename)
+	destPath := filepath.Join(userDir, storedName)
 	dst, err := os.Create(destPath)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create destination file: %w", err)
 	}
 	defer dst.Close()
 
-	// Calculate hash while copying
+	// Encrypt at rest when enabled
+	var out io.Writer = dst
+	var encrypter *encryptWriter
+	if s.keys != nil {
+		encrypter, err = newEncryptWriter(dst, s.keys)
+		if err != nil {
+			os.Remove(destPath)
+			return "", "", err
+		}
+		out = encrypter
+	}
+
+	// Calculate hash of the plaintext while copying
 	hash := sha256.New()
-	multiWriter := io.MultiWriter(dst, hash)
+	multiWriter := io.MultiWriter(out, hash)
 
 	// Copy file
 	_, err = io.Copy(multiWriter, src)
+	if err == nil && encrypter != nil {
+		err = encrypter.Close()
+	}
 	if err != nil {
 		os.Remove(destPath) // Clean up on error
 		return "", "", fmt.Errorf("failed to save file: %w", err)
 	}
 
 	fileHash = fmt.Sprintf("%x", hash.Sum(nil))
+	if s.contentAddressed {
+		filePath, err = s.storeContent(destPath, fileHash, ext)
+		if err != nil {
+			os.Remove(destPath)
+			return "", "", err
+		}
+		return filePath, fileHash, nil
+	}
 	return destPath, fileHash, nil
 }
 
-// DeleteFile deletes a file from storage
-func (s *Storage) DeleteFile(filePath string) error {
-	// Verify file is within basePath (security check)
-	absPath, err := filepath.Abs(filePath)
+// storeContent moves a file written to a temporary path to the path its hash
+// addresses, unless a file with the same content is stored there already
+func (s *Storage) storeContent(tmpPath, fileHash, ext string) (string, error) {
+	destPath := s.ContentPath(fileHash, ext)
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create content directory: %w", err)
+	}
+
+	if _, err := os.Stat(destPath); err == nil {
+		os.Remove(tmpPath)
+		// Mark the file as reused, so Release keeps it until the upload's
+		// document exists
+		now := time.Now()
+		if err := os.Chtimes(destPath, now, now); err != nil {
+			return "", fmt.Errorf("failed to reuse stored file: %w", err)
+		}
+		return destPath, nil
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		return "", fmt.Errorf("failed to store file: %w", err)
+	}
+	return destPath, nil
+}
+
+// ContentPath returns the path of the content-addressed file with the given
+// SHA-256 hash and extension, sharded by the hash's first two bytes so no
+// directory grows too large
+func (s *Storage) ContentPath(fileHash, ext string) string {
+	return filepath.Join(s.basePath, DocumentsDir, ContentDir, fileHash[:2], fileHash[2:4], fileHash+ext)
+}
+
+// IsContentAddressed reports whether a stored file is content-addressed, and
+// so possibly shared by the documents of several users
+func (s *Storage) IsContentAddressed(filePath string) bool {
+	rel, err := filepath.Rel(filepath.Join(s.basePath, DocumentsDir, ContentDir), filePath)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// WriteFile writes the contents of r to a path relative to the storage root,
+// creating parent directories as needed, and returns the full path
+func (s *Storage) WriteFile(relativePath string, r io.Reader) (string, error) {
+	return s.writeFile(relativePath, r, false)
+}
+
+// WriteEncryptedFile writes a file like WriteFile, encrypting it at rest like
+// documents when encryption is enabled. Files derived from users' data, such
+// as archives and reports, are written with it and read back with Open.
+func (s *Storage) WriteEncryptedFile(relativePath string, r io.Reader) (string, error) {
+	return s.writeFile(relativePath, r, s.keys != nil)
+}
+
+// writeFile writes a file through a temporary file, optionally encrypting it
+func (s *Storage) writeFile(relativePath string, r io.Reader, encrypt bool) (string, error) {
+	destPath := filepath.Join(s.basePath, filepath.Clean("/"+relativePath))
+
+	err := os.MkdirAll(filepath.Dir(destPath), 0755)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
+		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	absBasePath, err := filepath.Abs(s.basePath)
+	// Write to a temporary file first so readers never see a partial file
+	tmpPath := destPath + ".tmp"
+	dst, err := os.Create(tmpPath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute base path: %w", err)
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+
+	var out io.Writer = dst
+	var encrypter *encryptWriter
+	if encrypt {
+		encrypter, err = newEncryptWriter(dst, s.keys)
+		if err != nil {
+			dst.Close()
+			os.Remove(tmpPath)
+			return "", err
+		}
+		out = encrypter
 	}
 
-	if !strings.HasPrefix(absPath, absBasePath) {
-		return fmt.Errorf("file path outside storage directory")
+	_, err = io.Copy(out, r)
+	if err == nil && encrypter != nil {
+		err = encrypter.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, destPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to finalize file: %w", err)
+	}
+
+	return destPath, nil
+}
+
+// CheckWritable verifies files can be written to the storage root by writing
+// and removing a probe file
+func (s *Storage) CheckWritable() error {
+	probe, err := s.WriteFile(".healthz-"+uuid.NewString(), strings.NewReader("ok"))
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(probe); err != nil {
+		return fmt.Errorf("failed to remove probe file: %w", err)
+	}
+	return nil
+}
+
+// DeleteFile deletes a file from storage, refusing paths outside of the
+// storage root
+func (s *Storage) DeleteFile(filePath string) error {
+	if _, err := s.RelativePath(filePath); err != nil {
+		return err
 	}
 
-	// Delete file
-	err = os.Remove(filePath)
+	err := os.Remove(filePath)
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
@@ -99,15 +293,240 @@ func (s *Storage) DeleteFile(filePath string) error {
 	return nil
 }
 
+// Release deletes a document file once documents no longer refer to it. A
+// content-addressed file reused by an upload within the last hour is kept, as
+// the upload's document may not exist yet; the janitor removes it later if no
+// document ever comes to refer to it.
+func (s *Storage) Release(ctx context.Context, filePath string) error {
+	if s.references != nil {
+		referenced, err := s.references(ctx, []string{filePath})
+		if err != nil {
+			return err
+		}
+		if referenced[filePath] {
+			return nil
+		}
+	}
+
+	if s.IsContentAddressed(filePath) {
+		info, err := os.Stat(filePath)
+		if err == nil && time.Since(info.ModTime()) < contentReuseGrace {
+			return nil
+		}
+	}
+
+	return s.DeleteFile(filePath)
+}
+
+// Quarantine moves a stored file into the quarantine directory, out of reach of
+// the document cleanup, and returns its new path
+func (s *Storage) Quarantine(filePath string) (string, error) {
+	if _, err := s.RelativePath(filePath); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.basePath, QuarantineDir, time.Now().UTC().Format("2006-01-02"))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	destPath := filepath.Join(dir, filepath.Base(filePath))
+	if err := os.Rename(filePath, destPath); err != nil {
+		return "", fmt.Errorf("failed to quarantine file: %w", err)
+	}
+
+	// Strip permissions so the file cannot be served or executed by accident
+	_ = os.Chmod(destPath, 0400)
+
+	return destPath, nil
+}
+
+// Open opens a stored file for reading, transparently decrypting encrypted files
+func (s *Storage) Open(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	src := bufio.NewReaderSize(file, segmentSize)
+	if !isEncrypted(src) {
+		return readCloser{src, file}, nil
+	}
+	if s.keys == nil {
+		file.Close()
+		return nil, ErrNoEncryptionKey
+	}
+
+	plain, err := newDecryptReader(src, s.keys)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return readCloser{plain, file}, nil
+}
+
+// Reencrypt rewrites a stored file encrypted under a fresh data key wrapped with
+// the current key. Files already encrypted with the current key are skipped
+// unless force is set. It reports whether the file was rewritten.
+func (s *Storage) Reencrypt(filePath string, force bool) (bool, error) {
+	if s.keys == nil {
+		return false, ErrNoEncryptionKey
+	}
+
+	if !force {
+		keyID, err := s.encryptionKeyID(filePath)
+		if err != nil {
+			return false, err
+		}
+		if keyID == s.keys.CurrentKeyID() {
+			return false, nil
+		}
+	}
+
+	src, err := s.Open(filePath)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	tmpPath := filePath + ".rekey"
+	dst, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return false, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	encrypter, err := newEncryptWriter(dst, s.keys)
+	if err == nil {
+		_, err = io.Copy(encrypter, src)
+	}
+	if err == nil {
+		err = encrypter.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return false, fmt.Errorf("failed to re-encrypt file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return false, fmt.Errorf("failed to replace file: %w", err)
+	}
+
+	return true, nil
+}
+
+// encryptionKeyID returns the ID of the key a file is encrypted with, or "" for plaintext files
+func (s *Storage) encryptionKeyID(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	src := bufio.NewReader(file)
+	if !isEncrypted(src) {
+		return "", nil
+	}
+	keyID, _, _, err := readEncryptionHeader(src)
+	return keyID, err
+}
+
+// readCloser pairs a reader with the file backing it
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ListFiles returns the full paths of the regular files below a directory relative
+// to the storage root that were last modified before the given time
+func (s *Storage) ListFiles(relativeDir string, modifiedBefore time.Time) ([]string, error) {
+	var files []string
+	err := s.WalkFiles(relativeDir, func(path string, info fs.FileInfo) error {
+		if info.ModTime().Before(modifiedBefore) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// WalkFiles calls fn with the full path and file info of every regular file
+// below a directory relative to the storage root, stopping at the first error
+// fn returns. Files removed during the walk are skipped.
+func (s *Storage) WalkFiles(relativeDir string, fn func(path string, info fs.FileInfo) error) error {
+	root := filepath.Join(s.basePath, filepath.Clean("/"+relativeDir))
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		return fn(path, info)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+
+	return nil
+}
+
 // FileExists checks if a file exists
 func (s *Storage) FileExists(filePath string) bool {
 	_, err := os.Stat(filePath)
 	return err == nil
 }
 
-// GetFilePath returns the full path for a file
+// RelativePath returns the path of a stored file relative to the storage
+// root, with forward slashes, failing for paths outside of it
+func (s *Storage) RelativePath(filePath string) (string, error) {
+	absBasePath, err := filepath.Abs(s.basePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve storage root: %w", err)
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve stored file path: %w", err)
+	}
+
+	rel, err := filepath.Rel(absBasePath, absPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve stored file path: %w", err)
+	}
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("stored file %s is outside of the storage root", filePath)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// GetFilePath returns the full path for a file, which stays below the
+// storage root whatever relativePath holds
 func (s *Storage) GetFilePath(relativePath string) string {
-	return filepath.Join(s.basePath, relativePath)
+	return filepath.Join(s.basePath, filepath.Clean("/"+relativePath))
 }
 
 // ValidateFileType checks if the file type is allowed
@@ -142,3 +561,37 @@ func GetMimeType(filename string) string {
 	}
 	return mimeType
 }
+
+// ErrFileTooLarge is returned by readers from LimitReader past their limit
+var ErrFileTooLarge = errors.New("file exceeds the maximum allowed size")
+
+// LimitReader returns a reader reading at most n bytes from r, which fails
+// with ErrFileTooLarge when r holds more instead of ending like
+// io.LimitReader
+func LimitReader(r io.Reader, n int64) io.Reader {
+	return &limitReader{r: r, remaining: n}
+}
+
+type limitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// Read one byte past the limit to tell whether r holds more
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		n = int(l.remaining)
+		l.remaining = 0
+		return n, ErrFileTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeleteFileStaysInsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStorage(filepath.Join(dir, "storage"))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+
+	// A sibling directory sharing the root's name as a prefix is outside of it
+	sibling := filepath.Join(dir, "storage-evil", "x")
+	outside := filepath.Join(dir, "outside")
+	for _, path := range []string{sibling, outside} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("keep"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, path := range []string{
+		sibling,
+		outside,
+		filepath.Join(dir, "storage", "..", "outside"),
+		filepath.Join(dir, "storage"),
+	} {
+		if err := store.DeleteFile(path); err == nil {
+			t.Errorf("DeleteFile(%s) succeeded, want it refused", path)
+		}
+	}
+	for _, path := range []string{sibling, outside} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+
+	stored, err := store.WriteFile("exports/a.zip", strings.NewReader("zip"))
+	if err == nil {
+		err = store.DeleteFile(stored)
+	}
+	if err != nil {
+		t.Fatalf("deleting a stored file: %v", err)
+	}
+	if _, err := os.Stat(stored); !os.IsNotExist(err) {
+		t.Errorf("stored file still exists: %v", err)
+	}
+}
+
+func TestWriteEncryptedFile(t *testing.T) {
+	store, err := NewStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	content := "Invoice 42, ACME & Co, total 10.00"
+
+	// Without encryption at rest, files are written as they are
+	plainPath, err := store.WriteEncryptedFile("exports/plain.zip", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("WriteEncryptedFile: %v", err)
+	}
+	if raw, _ := os.ReadFile(plainPath); string(raw) != content {
+		t.Errorf("file without encryption = %q, want %q", raw, content)
+	}
+
+	keys, err := ParseKeyring("k1:"+base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)), "k1")
+	if err != nil {
+		t.Fatalf("ParseKeyring: %v", err)
+	}
+	store.EnableEncryption(keys)
+
+	sealedPath, err := store.WriteEncryptedFile("exports/sealed.zip", strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("WriteEncryptedFile: %v", err)
+	}
+	raw, err := os.ReadFile(sealedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("Invoice 42")) {
+		t.Error("encrypted file holds the plaintext")
+	}
+
+	for _, path := range []string{plainPath, sealedPath} {
+		file, err := store.Open(path)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		read, err := io.ReadAll(file)
+		file.Close()
+		if err != nil || string(read) != content {
+			t.Errorf("Open(%s) = %q, %v; want %q", path, read, err, content)
+		}
+	}
+}
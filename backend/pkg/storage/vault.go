@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultTransit is a KeyWrapper delegating data key encryption to the
+// HashiCorp Vault transit secrets engine, so the key encryption key never
+// leaves Vault. Key versions are tracked by Vault inside the ciphertext.
+type VaultTransit struct {
+	addr       string
+	token      string
+	keyName    string
+	httpClient *http.Client
+}
+
+// NewVaultTransit creates a key wrapper for a transit key
+func NewVaultTransit(addr, token, keyName string, timeout time.Duration) (*VaultTransit, error) {
+	if addr == "" || token == "" || keyName == "" {
+		return nil, fmt.Errorf("vault address, token and transit key are required")
+	}
+	return &VaultTransit{
+		addr:       strings.TrimRight(addr, "/"),
+		token:      token,
+		keyName:    keyName,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// CurrentKeyID returns the key ID recorded in files encrypted through Vault
+func (v *VaultTransit) CurrentKeyID() string {
+	return "vault:" + v.keyName
+}
+
+// WrapKey encrypts a data key with the latest version of the transit key
+func (v *VaultTransit) WrapKey(dataKey []byte) (string, []byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	err := v.call("encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}, &resp)
+	if err != nil {
+		return "", nil, err
+	}
+	return v.CurrentKeyID(), []byte(resp.Data.Ciphertext), nil
+}
+
+// UnwrapKey decrypts a data key wrapped by Vault
+func (v *VaultTransit) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != v.CurrentKeyID() {
+		return nil, fmt.Errorf("file was encrypted with key %q, not %q", keyID, v.CurrentKeyID())
+	}
+
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.call("decrypt", map[string]string{"ciphertext": string(wrapped)}, &resp); err != nil {
+		return nil, err
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key returned by vault: %w", err)
+	}
+	return dataKey, nil
+}
+
+// call invokes a transit operation on the configured key
+func (v *VaultTransit) call(operation string, payload any, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/transit/%s/%s", v.addr, operation, url.PathEscape(v.keyName))
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault %s failed: %w", operation, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault %s failed with status %d", operation, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	return nil
+}
@@ -1,7 +1,9 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -12,13 +14,57 @@ type Validator struct {
 	validate *validator.Validate
 }
 
-// New creates a new validator instance
+// New creates a new validator instance. Fields are named by their form tag,
+// or else their JSON tag, as clients know them.
 func New() *Validator {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, key := range []string{"form", "json"} {
+			name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
 	return &Validator{
-		validate: validator.New(),
+		validate: validate,
 	}
 }
 
+// FieldError is a failed validation of one field
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Errors is returned by Validate for a struct with invalid fields
+type Errors struct {
+	Fields []FieldError
+}
+
+func (e *Errors) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return fmt.Sprintf("validation error: %s", strings.Join(messages, "; "))
+}
+
+// FieldErrors returns the failed fields of an error returned by Validate, or
+// nil for errors of other kinds
+func FieldErrors(err error) []FieldError {
+	var validationErrs *Errors
+	if errors.As(err, &validationErrs) {
+		return validationErrs.Fields
+	}
+	return nil
+}
+
 // Validate validates a struct
 func (v *Validator) Validate(data interface{}) error {
 	err := v.validate.Struct(data)
@@ -28,33 +74,46 @@ func (v *Validator) Validate(data interface{}) error {
 
 	// Convert validation errors to custom format
 	if validationErrors, ok := err.(validator.ValidationErrors); ok {
-		var errors []string
+		fields := make([]FieldError, 0, len(validationErrors))
 		for _, e := range validationErrors {
-			errors = append(errors, formatValidationError(e))
+			fields = append(fields, FieldError{
+				Field:   strings.ToLower(e.Field()),
+				Message: formatValidationError(e),
+			})
 		}
-		return fmt.Errorf("validation error: %s", strings.Join(errors, "; "))
+		return &Errors{Fields: fields}
 	}
 
 	return err
 }
 
-// formatValidationError formats a validation error into a human-readable message
+// formatValidationError formats a validation error into a human-readable
+// message, to follow the field's name
 func formatValidationError(e validator.FieldError) string {
-	field := strings.ToLower(e.Field())
+	// Bounds of numbers are values; those of strings and lists are lengths
+	unit := " characters"
+	switch e.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		unit = ""
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
 
 	switch e.Tag() {
 	case "required":
-		return fmt.Sprintf("%s is required", field)
+		return "is required"
 	case "email":
-		return fmt.Sprintf("%s must be a valid email address", field)
+		return "must be a valid email address"
 	case "min":
-		return fmt.Sprintf("%s must be at least %s characters", field, e.Param())
+		return fmt.Sprintf("must be at least %s%s", e.Param(), unit)
 	case "max":
-		return fmt.Sprintf("%s must be at most %s characters", field, e.Param())
+		return fmt.Sprintf("must be at most %s%s", e.Param(), unit)
 	case "oneof":
-		return fmt.Sprintf("%s must be one of: %s", field, e.Param())
+		return fmt.Sprintf("must be one of: %s", e.Param())
 	default:
-		return fmt.Sprintf("%s failed validation (%s)", field, e.Tag())
+		return fmt.Sprintf("failed validation (%s)", e.Tag())
 	}
 }
 
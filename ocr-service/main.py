@@ -47,6 +47,14 @@ async def health_check():
         "model": settings.MODEL_PATH
     }
 
+@app.get("/version")
+async def version():
+    """Response schema versions of /api/v1/ocr/process, most preferred first"""
+    return {
+        "version": "1.0.0",
+        "schema_versions": ["2"]
+    }
+
 @app.on_event("startup")
 async def startup_event():
     """Initialize service on startup"""